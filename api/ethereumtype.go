@@ -4,7 +4,7 @@ import (
 	"sync"
 
 	"github.com/golang/glog"
-	"github.com/linxGnu/grocksdb"
+	"github.com/juju/errors"
 	"github.com/trezor/blockbook/bchain"
 	"github.com/trezor/blockbook/db"
 )
@@ -29,21 +29,31 @@ func (w *Worker) RefetchInternalData() error {
 	return nil
 }
 
+// RefetchInternalDataSync refetches the internal data in the calling goroutine,
+// it is used to serialize the refetch with the synchronization of the index
+func (w *Worker) RefetchInternalDataSync() error {
+	refetchInternalDataMux.Lock()
+	if refetchingInternalData {
+		refetchInternalDataMux.Unlock()
+		return errors.New("Refetch of internal data is already running")
+	}
+	refetchingInternalData = true
+	refetchInternalDataMux.Unlock()
+	w.RefetchInternalDataRoutine()
+	return nil
+}
+
 const maxNumberOfRetires = 25
 
 func (w *Worker) incrementRefetchInternalDataRetryCount(ie *db.BlockInternalDataError) {
-	wb := grocksdb.NewWriteBatch()
-	defer wb.Destroy()
-	err := w.db.StoreBlockInternalDataErrorEthereumType(wb, &bchain.Block{
+	err := w.db.UpdateBlockInternalDataErrorEthereumType(&bchain.Block{
 		BlockHeader: bchain.BlockHeader{
 			Hash:   ie.Hash,
 			Height: ie.Height,
 		},
 	}, ie.ErrorMessage, ie.Retries+1)
 	if err != nil {
-		glog.Errorf("StoreBlockInternalDataErrorEthereumType %d %s, error %v", ie.Height, ie.Hash, err)
-	} else {
-		w.db.WriteBatch(wb)
+		glog.Errorf("UpdateBlockInternalDataErrorEthereumType %d %s, error %v", ie.Height, ie.Hash, err)
 	}
 }
 
@@ -0,0 +1,172 @@
+//go:build unittest
+
+package api
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/trezor/blockbook/bchain"
+	"github.com/trezor/blockbook/bchain/coins/eth"
+	"github.com/trezor/blockbook/db"
+	"github.com/trezor/blockbook/tests/dbtestdata"
+)
+
+func Test_getParsedEthereumInputData(t *testing.T) {
+	m := db.NewMemoryStore(eth.NewEthereumParser(1, true), false)
+	w := &Worker{db: m}
+	m.StoreFourByteSignature(0x23b872dd, &bchain.FourByteSignature{
+		Name:       "transferFrom",
+		Parameters: []string{"address", "address", "uint256"},
+	})
+	contractCd, _ := hex.DecodeString(dbtestdata.EthAddrContractCd)
+	contract4a, _ := hex.DecodeString(dbtestdata.EthAddrContract4a)
+	if _, err := m.StoreContractABI(contractCd, []byte(`[{"type":"function","name":"transferFrom","inputs":[{"name":"from","type":"address"},{"name":"to","type":"address"},{"name":"tokenId","type":"uint256"}],"outputs":[]}]`)); err != nil {
+		t.Fatal(err)
+	}
+	input := "0x23b872dd000000000000000000000000837e3f699d85a4b0b99894567e9233dfb1dcb0810000000000000000000000007b62eb7fe80350dc7ec945c0b73242cb9877fb1b0000000000000000000000000000000000000000000000000000000000000001"
+	tests := []struct {
+		name       string
+		contract   bchain.AddressDescriptor
+		wantParams []string
+	}{
+		{name: "verified ABI", contract: contractCd, wantParams: []string{"from", "to", "tokenId"}},
+		{name: "four byte signature", contract: contract4a, wantParams: []string{"", "", ""}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := w.getParsedEthereumInputData(tt.contract, input)
+			if got == nil || got.Name != "Transfer From" || len(got.Params) != len(tt.wantParams) {
+				t.Fatalf("getParsedEthereumInputData() = %+v", got)
+			}
+			for i := range got.Params {
+				if got.Params[i].Name != tt.wantParams[i] {
+					t.Errorf("getParsedEthereumInputData() param %d name = %q, want %q", i, got.Params[i].Name, tt.wantParams[i])
+				}
+			}
+			if got.Params[2].Values[0] != "1" {
+				t.Errorf("getParsedEthereumInputData() tokenId = %v, want [1]", got.Params[2].Values)
+			}
+		})
+	}
+}
+
+// allowanceChain returns the allowances of the spenders, the other methods are not used by the tests
+type allowanceChain struct {
+	bchain.BlockChain
+	allowances map[string]int64
+}
+
+func (c *allowanceChain) EthereumTypeGetErc20ContractAllowance(ownerDesc, spenderDesc, contractDesc bchain.AddressDescriptor) (*big.Int, error) {
+	return big.NewInt(c.allowances[string(spenderDesc)]), nil
+}
+
+func (c *allowanceChain) GetContractInfo(contractDesc bchain.AddressDescriptor) (*bchain.ContractInfo, error) {
+	return nil, nil
+}
+
+func Test_GetTokenApprovals(t *testing.T) {
+	const approval = "0x8c5be1e5ebec7d5bd14f71427d1e84f3dd0314c0f7b2291e5b200ac8c7c3b925"
+	const approvalForAll = "0x17307eab39ab6107e8899845ad3d59bd9653f200f220920489ca2b5937696c31"
+	parser := eth.NewEthereumParser(1, false)
+	m := db.NewMemoryStore(parser, false)
+	addrDesc := func(a string) bchain.AddressDescriptor {
+		b, err := hex.DecodeString(a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	chain := &allowanceChain{allowances: map[string]int64{
+		string(addrDesc(dbtestdata.EthAddr55)): 400,
+		string(addrDesc(dbtestdata.EthAddr9f)): 0,
+	}}
+	w := &Worker{db: m, chain: chain, chainParser: parser, chainType: bchain.ChainEthereumType}
+	addLog := func(tx *bchain.Tx, signature, contract, spender string, value int64) {
+		csd := tx.CoinSpecificData.(bchain.EthereumSpecificData)
+		csd.Receipt.Logs = append(csd.Receipt.Logs, &bchain.RpcLog{
+			Address: "0x" + contract,
+			Topics: []string{
+				signature,
+				"0x000000000000000000000000" + dbtestdata.EthAddr3e,
+				"0x000000000000000000000000" + spender,
+			},
+			Data: fmt.Sprintf("0x%064x", value),
+		})
+	}
+	// the allowance of EthAddr55 was partly spent, the allowance of EthAddr9f fully by transferFrom without Approval events
+	block := dbtestdata.GetTestEthereumTypeBlock1(parser)
+	addLog(&block.Txs[0], approval, dbtestdata.EthAddrContract4a, dbtestdata.EthAddr55, 1000)
+	addLog(&block.Txs[0], approval, dbtestdata.EthAddrContract4a, dbtestdata.EthAddr9f, 2000)
+	addLog(&block.Txs[1], approvalForAll, dbtestdata.EthAddrContractCd, dbtestdata.EthAddr20, 1)
+	if err := m.ConnectBlock(block); err != nil {
+		t.Fatal(err)
+	}
+	got, err := w.GetTokenApprovals(dbtestdata.EthAddr3e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	spenders := make(map[string]string)
+	for _, a := range got.Approvals {
+		v := ""
+		if a.ValueSat != nil {
+			v = a.ValueSat.String()
+		}
+		spenders[a.Spender] = fmt.Sprintf("%v %v %v", a.ForAll, v, a.Height)
+	}
+	want := map[string]string{
+		eth.EIP55Address(addrDesc(dbtestdata.EthAddr55)): "false 400 4321000",
+		eth.EIP55Address(addrDesc(dbtestdata.EthAddr20)): "true  4321000",
+	}
+	if !reflect.DeepEqual(spenders, want) {
+		t.Errorf("GetTokenApprovals() = %v, want %v", spenders, want)
+	}
+}
+
+func Test_DecodeReturnData(t *testing.T) {
+	parser := eth.NewEthereumParser(1, false)
+	m := db.NewMemoryStore(parser, false)
+	w := &Worker{db: m, chainParser: parser, chainType: bchain.ChainEthereumType}
+	contract4a, _ := hex.DecodeString(dbtestdata.EthAddrContract4a)
+	if _, err := m.StoreContractABI(contract4a, []byte(`[{"type":"function","name":"balanceOf","inputs":[{"name":"owner","type":"address"}],"outputs":[{"name":"balance","type":"uint256"}]}]`)); err != nil {
+		t.Fatal(err)
+	}
+	input := "0x70a08231000000000000000000000000" + dbtestdata.EthAddr3e
+	output := fmt.Sprintf("0x%064x", 12345)
+	tests := []struct {
+		name     string
+		contract string
+		input    string
+		output   string
+		want     string
+		wantErr  string
+	}{
+		{name: "decoded", contract: dbtestdata.EthAddrContract4a, input: input, output: output, want: "balance uint256 [12345]"},
+		{name: "unknown ABI", contract: dbtestdata.EthAddrContractCd, input: input, output: output, wantErr: "The verified ABI of the contract is not known"},
+		{name: "unknown method", contract: dbtestdata.EthAddrContract4a, input: "0x23b872dd", output: output, wantErr: "The input data cannot be decoded by the ABI of the contract"},
+		{name: "truncated output", contract: dbtestdata.EthAddrContract4a, input: input, output: "0x3039", wantErr: "The return data cannot be decoded by the ABI of the contract"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := w.DecodeReturnData(tt.contract, tt.input, tt.output)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("DecodeReturnData() error = %v, want %v", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Contract != eth.EIP55AddressFromAddress(tt.contract) || got.ParsedData.Name != "Balance Of" || len(got.Outputs) != 1 {
+				t.Fatalf("DecodeReturnData() = %+v", got)
+			}
+			if o := got.Outputs[0]; fmt.Sprintf("%v %v %v", o.Name, o.Type, o.Values) != tt.want {
+				t.Errorf("DecodeReturnData() outputs = %+v, want %v", got.Outputs, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,356 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+	"github.com/trezor/blockbook/bchain"
+	"github.com/trezor/blockbook/bchain/coins/eth"
+	"github.com/trezor/blockbook/db"
+)
+
+// number of rows for which the fiat rates are fetched at once
+const exportFiatRatesBatch = 1000
+
+// number of blocks, for which the txids of the exported addresses are loaded at once
+const exportHeightWindow = 10000
+
+type exportTxid struct {
+	txid   string
+	height uint32
+}
+
+// Export is a transaction history of an address or xpub prepared for streaming, it reads from a snapshot of the database
+// pinned to a single best block so that the rows are consistent from the first to the last one.
+// The snapshot must be released by Close.
+type Export struct {
+	Height       uint32
+	Hash         string
+	w            *Worker
+	release      func()
+	addrDescs    []bchain.AddressDescriptor
+	selfAddrDesc map[string]struct{}
+	currency     string
+}
+
+// newExport creates the export reading from a new snapshot of the database
+func (w *Worker) newExport(currency string) (*Export, error) {
+	snapshot, release := w.db.Snapshot()
+	height, hash, err := snapshot.GetBestBlock()
+	if err != nil {
+		release()
+		return nil, errors.Annotatef(err, "GetBestBlock")
+	}
+	sw := *w
+	sw.db = snapshot
+	currency = strings.ToLower(currency)
+	if currency == "" {
+		currency = "usd"
+	}
+	return &Export{
+		Height:   height,
+		Hash:     hash,
+		w:        &sw,
+		release:  release,
+		currency: currency,
+	}, nil
+}
+
+// PrepareAddressExport prepares the export of the confirmed transactions of the address up to the current best block
+func (w *Worker) PrepareAddressExport(address string, currency string) (*Export, error) {
+	addrDesc, _, err := w.getAddrDescAndNormalizeAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	e, err := w.newExport(currency)
+	if err != nil {
+		return nil, err
+	}
+	e.setAddrDescs([]bchain.AddressDescriptor{addrDesc})
+	if err = e.checkPrunedHistory(); err != nil {
+		e.Close()
+		return nil, err
+	}
+	return e, nil
+}
+
+// PrepareXpubExport prepares the export of the confirmed transactions of all used addresses of the xpub up to the current best block
+func (w *Worker) PrepareXpubExport(xpub string, gap int, currency string) (*Export, error) {
+	xd, err := w.chainParser.ParseXpub(xpub)
+	if err != nil {
+		return nil, err
+	}
+	e, err := w.newExport(currency)
+	if err != nil {
+		return nil, err
+	}
+	data, _, _, err := e.w.getXpubData(xd, 0, 1, AccountDetailsBasic, &AddressFilter{
+		Vout:          AddressFilterVoutOff,
+		OnlyConfirmed: true,
+	}, gap)
+	if err != nil {
+		e.Close()
+		return nil, err
+	}
+	addrDescs := make([]bchain.AddressDescriptor, 0)
+	for _, da := range data.addresses {
+		for i := range da {
+			if da[i].balance != nil {
+				addrDescs = append(addrDescs, da[i].addrDesc)
+			}
+		}
+	}
+	e.setAddrDescs(addrDescs)
+	if err = e.checkPrunedHistory(); err != nil {
+		e.Close()
+		return nil, err
+	}
+	return e, nil
+}
+
+func (e *Export) setAddrDescs(addrDescs []bchain.AddressDescriptor) {
+	e.addrDescs = addrDescs
+	e.selfAddrDesc = make(map[string]struct{}, len(addrDescs))
+	for _, addrDesc := range addrDescs {
+		e.selfAddrDesc[string(addrDesc)] = struct{}{}
+	}
+}
+
+// checkPrunedHistory rejects the export if some transactions of the addresses are pruned from the index,
+// the rows and the running balance would not be complete
+func (e *Export) checkPrunedHistory() error {
+	prunedHeight := e.w.is.GetPrunedHeight()
+	if prunedHeight == 0 {
+		return nil
+	}
+	for _, addrDesc := range e.addrDescs {
+		ba, err := e.w.db.GetAddrDescBalance(addrDesc, db.AddressBalanceDetailNoUTXO)
+		if err != nil {
+			return err
+		}
+		if ba == nil {
+			continue
+		}
+		txs := 0
+		err = e.w.db.GetAddrDescTransactions(addrDesc, 0, e.Height, func(txid string, height uint32, indexes []int32) error {
+			txs++
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		if txs < int(ba.Txs) {
+			return NewAPIError(fmt.Sprintf("The history below height %d is pruned, the export is not available", prunedHeight), true)
+		}
+	}
+	return nil
+}
+
+// Close releases the snapshot of the database
+func (e *Export) Close() {
+	if e.release != nil {
+		e.release()
+		e.release = nil
+	}
+}
+
+// exportGetTxids returns unique txids of the addresses in the blocks lower-higher sorted by height in the ascending order,
+// the transactions of one address in the same block are kept in the order of the block
+func (w *Worker) exportGetTxids(addrDescs []bchain.AddressDescriptor, lower, higher uint32) ([]exportTxid, error) {
+	txids := make([]exportTxid, 0)
+	for _, addrDesc := range addrDescs {
+		l := len(txids)
+		err := w.db.GetAddrDescTransactions(addrDesc, lower, higher, func(txid string, height uint32, indexes []int32) error {
+			txids = append(txids, exportTxid{txid, height})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		// db returns the transactions from the newest to the oldest
+		for i, j := l, len(txids)-1; i < j; i, j = i+1, j-1 {
+			txids[i], txids[j] = txids[j], txids[i]
+		}
+	}
+	sort.SliceStable(txids, func(i, j int) bool { return txids[i].height < txids[j].height })
+	unique := make(map[string]struct{}, len(txids))
+	i := 0
+	for _, t := range txids {
+		if _, found := unique[t.txid]; !found {
+			unique[t.txid] = struct{}{}
+			txids[i] = t
+			i++
+		}
+	}
+	return txids[:i], nil
+}
+
+// Run computes the rows of the export in the blockchain order and passes them to the function fn,
+// the txids are loaded from the snapshot by windows of blocks so that the whole history is not held in memory
+func (e *Export) Run(fn func(*ExportTx) error) error {
+	start := time.Now()
+	var balance big.Int
+	count := 0
+	rows := make([]ExportTx, 0, exportFiatRatesBatch)
+	flush := func() error {
+		e.w.setFiatValuesToExportTxs(rows, e.currency)
+		for i := range rows {
+			if err := fn(&rows[i]); err != nil {
+				return err
+			}
+		}
+		count += len(rows)
+		rows = rows[:0]
+		return nil
+	}
+	for lower := uint32(0); ; lower += exportHeightWindow {
+		higher := e.Height
+		if e.Height-lower >= exportHeightWindow {
+			higher = lower + exportHeightWindow - 1
+		}
+		txids, err := e.w.exportGetTxids(e.addrDescs, lower, higher)
+		if err != nil {
+			return err
+		}
+		for _, t := range txids {
+			row, err := e.w.exportTx(t, e.addrDescs, e.selfAddrDesc)
+			if err != nil {
+				return err
+			}
+			if row == nil {
+				continue
+			}
+			balance.Add(&balance, (*big.Int)(row.BalanceDelta))
+			row.Balance = (*Amount)(new(big.Int).Set(&balance))
+			rows = append(rows, *row)
+			if len(rows) == exportFiatRatesBatch {
+				if err := flush(); err != nil {
+					return err
+				}
+			}
+		}
+		if higher == e.Height {
+			break
+		}
+	}
+	if err := flush(); err != nil {
+		return err
+	}
+	glog.Info("export ", len(e.addrDescs), " addresses, height ", e.Height, ", count ", count, ", ", time.Since(start))
+	return nil
+}
+
+func (w *Worker) exportTx(t exportTxid, addrDescs []bchain.AddressDescriptor, selfAddrDesc map[string]struct{}) (*ExportTx, error) {
+	var delta, feesSat big.Int
+	if w.chainType == bchain.ChainBitcoinType {
+		ta, err := w.db.GetTxAddresses(t.txid)
+		if err != nil {
+			return nil, err
+		}
+		if ta == nil {
+			glog.Warning("DB inconsistency:  tx ", t.txid, ": not found in txAddresses")
+			return nil, nil
+		}
+		var valInSat, valOutSat big.Int
+		ownInput := false
+		for i := range ta.Inputs {
+			tai := &ta.Inputs[i]
+			valInSat.Add(&valInSat, &tai.ValueSat)
+			if _, found := selfAddrDesc[string(tai.AddrDesc)]; found {
+				delta.Sub(&delta, &tai.ValueSat)
+				ownInput = true
+			}
+		}
+		for i := range ta.Outputs {
+			tao := &ta.Outputs[i]
+			valOutSat.Add(&valOutSat, &tao.ValueSat)
+			if _, found := selfAddrDesc[string(tao.AddrDesc)]; found {
+				delta.Add(&delta, &tao.ValueSat)
+			}
+		}
+		// the fee is reported only if it was paid by the exported address or xpub, for coinbase transactions valIn is 0
+		if ownInput {
+			feesSat.Sub(&valInSat, &valOutSat)
+			if feesSat.Sign() == -1 {
+				feesSat.SetUint64(0)
+			}
+		}
+	} else if w.chainType == bchain.ChainEthereumType {
+		// export of ethereum type coins is supported only for a single address
+		addrDesc := addrDescs[0]
+		bchainTx, _, err := w.txCache.GetTransaction(t.txid)
+		if err != nil {
+			return nil, err
+		}
+		if bchainTx == nil {
+			glog.Warning("Inconsistency:  tx ", t.txid, ": not found in the blockchain")
+			return nil, nil
+		}
+		if len(bchainTx.Vin) > 0 && len(bchainTx.Vin[0].Addresses) > 0 {
+			txAddrDesc, err := w.chainParser.GetAddrDescFromAddress(bchainTx.Vin[0].Addresses[0])
+			if err != nil {
+				return nil, err
+			}
+			ethTxData := eth.GetEthereumTxData(bchainTx)
+			if bytes.Equal(addrDesc, txAddrDesc) && ethTxData.GasUsed != nil && ethTxData.PaidGasPrice() != nil {
+				feesSat.Mul(ethTxData.PaidGasPrice(), ethTxData.GasUsed)
+			}
+		}
+		// the balance history of the transaction contains the fees in the sent amount
+		bh, err := w.balanceHistoryForTxid(addrDesc, t.txid, 0, maxUint32, selfAddrDesc)
+		if err != nil {
+			return nil, err
+		}
+		if bh == nil {
+			return nil, nil
+		}
+		delta.Sub((*big.Int)(bh.ReceivedSat), (*big.Int)(bh.SentSat))
+	}
+	return &ExportTx{
+		Txid:         t.txid,
+		Blockheight:  t.height,
+		Blocktime:    int64(w.is.GetBlockTime(t.height)),
+		BalanceDelta: (*Amount)(&delta),
+		FeesSat:      (*Amount)(&feesSat),
+	}, nil
+}
+
+func (w *Worker) setFiatValuesToExportTxs(rows []ExportTx, currency string) {
+	if len(rows) == 0 {
+		return
+	}
+	timestamps := make([]int64, len(rows))
+	for i := range rows {
+		timestamps[i] = rows[i].Blocktime
+	}
+	tickers, err := w.fiatRates.GetTickersForTimestamps(timestamps, "", "")
+	if err != nil {
+		glog.Errorf("Error finding tickers for export. Error: %v", err)
+		return
+	}
+	if tickers == nil || len(*tickers) != len(rows) {
+		return
+	}
+	d := w.chainParser.AmountDecimals()
+	for i := range rows {
+		ticker := (*tickers)[i]
+		if ticker == nil {
+			continue
+		}
+		if rate, found := ticker.Rates[currency]; found {
+			row := &rows[i]
+			row.FiatRate = float64(rate)
+			delta, err := strconv.ParseFloat(row.BalanceDelta.DecimalString(d), 64)
+			if err == nil {
+				row.FiatValue = delta * row.FiatRate
+			}
+		}
+	}
+}
@@ -0,0 +1,329 @@
+package api
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+	"github.com/trezor/blockbook/bchain"
+)
+
+const (
+	// maximum number of transactions loaded to a mempool package, Bitcoin Core limits the ancestors and descendants to 25 transactions
+	maxMempoolPackageTxs = 100
+	// virtual size of a child transaction spending one P2WPKH output to one P2WPKH output
+	cpfpChildVSize = 110
+	// default incremental relay fee of Bitcoin Core in sat/kB
+	incrementalRelayFeePerKb = 1000
+)
+
+// packageTx is a transaction in the graph of a mempool package
+type packageTx struct {
+	txid     string
+	fee      int64
+	vsize    int
+	rbf      bool
+	inputs   []bchain.Outpoint
+	outputs  int
+	parents  []*packageTx
+	children []*packageTx
+}
+
+// mempoolPackageGraph contains the transaction, its unconfirmed ancestors, descendants and the ancestors of the descendants
+type mempoolPackageGraph struct {
+	w     *Worker
+	txs   map[string]*packageTx
+	order []*packageTx
+}
+
+func (g *mempoolPackageGraph) load(txid string) (*packageTx, error) {
+	if t, found := g.txs[txid]; found {
+		return t, nil
+	}
+	bchainTx, height, err := g.w.txCache.GetTransaction(txid)
+	if err != nil {
+		if err == bchain.ErrTxNotFound {
+			return nil, NewAPIError(fmt.Sprintf("Transaction '%v' not found", txid), true)
+		}
+		return nil, NewAPIError(fmt.Sprintf("Transaction '%v' not found (%v)", txid, err), true)
+	}
+	return g.add(bchainTx, height)
+}
+
+func (g *mempoolPackageGraph) add(bchainTx *bchain.Tx, height int) (*packageTx, error) {
+	txid := bchainTx.Txid
+	if len(g.txs) >= maxMempoolPackageTxs {
+		return nil, NewAPIError(fmt.Sprintf("Mempool package has more than %d transactions", maxMempoolPackageTxs), true)
+	}
+	tx, err := g.w.getTransactionFromBchainTx(bchainTx, height, false, false, nil)
+	if err != nil {
+		return nil, err
+	}
+	t := &packageTx{
+		txid:    txid,
+		vsize:   tx.VSize,
+		rbf:     tx.Rbf,
+		inputs:  make([]bchain.Outpoint, 0, len(tx.Vin)),
+		outputs: len(tx.Vout),
+	}
+	if t.vsize == 0 {
+		t.vsize = tx.Size
+	}
+	if tx.FeesSat != nil {
+		t.fee = tx.FeesSat.AsInt64()
+	}
+	for i := range tx.Vin {
+		if tx.Vin[i].Txid != "" {
+			t.inputs = append(t.inputs, bchain.Outpoint{Txid: tx.Vin[i].Txid, Vout: int32(tx.Vin[i].Vout)})
+		}
+	}
+	g.txs[txid] = t
+	g.order = append(g.order, t)
+	return t, nil
+}
+
+func (g *mempoolPackageGraph) inMempool(txid string) bool {
+	return g.w.mempool.GetTransactionTime(txid) != 0
+}
+
+// loadAncestors loads the unconfirmed ancestors of the transaction
+func (g *mempoolPackageGraph) loadAncestors(t *packageTx) error {
+	queue := []*packageTx{t}
+	for len(queue) > 0 {
+		t = queue[0]
+		queue = queue[1:]
+		for _, o := range t.inputs {
+			if _, found := g.txs[o.Txid]; found || !g.inMempool(o.Txid) {
+				continue
+			}
+			p, err := g.load(o.Txid)
+			if err != nil {
+				return err
+			}
+			queue = append(queue, p)
+		}
+	}
+	return nil
+}
+
+// loadDescendants loads the mempool transactions spending the outputs of the transaction, recursively,
+// the spending transactions are found by the outpoints in the mempool
+func (g *mempoolPackageGraph) loadDescendants(t *packageTx) ([]*packageTx, error) {
+	var descendants []*packageTx
+	queue := []*packageTx{t}
+	for len(queue) > 0 {
+		t = queue[0]
+		queue = queue[1:]
+		for i := 0; i < t.outputs; i++ {
+			spender, err := g.w.mempool.GetOutpointSpender(bchain.Outpoint{Txid: t.txid, Vout: int32(i)})
+			if err != nil {
+				return nil, err
+			}
+			if spender == "" {
+				continue
+			}
+			if _, found := g.txs[spender]; found {
+				continue
+			}
+			bchainTx, height, err := g.w.txCache.GetTransaction(spender)
+			if err != nil {
+				glog.Warning("GetTransaction ", spender, ": ", err)
+				continue
+			}
+			c, err := g.add(bchainTx, height)
+			if err != nil {
+				return nil, err
+			}
+			descendants = append(descendants, c)
+			queue = append(queue, c)
+		}
+	}
+	return descendants, nil
+}
+
+// link connects the transactions in the graph to their parents and children
+func (g *mempoolPackageGraph) link() {
+	for _, t := range g.order {
+		t.parents = nil
+		t.children = nil
+	}
+	for _, t := range g.order {
+		for _, o := range t.inputs {
+			if p, found := g.txs[o.Txid]; found && !containsPackageTx(t.parents, p) {
+				t.parents = append(t.parents, p)
+				p.children = append(p.children, t)
+			}
+		}
+	}
+}
+
+func containsPackageTx(a []*packageTx, t *packageTx) bool {
+	for _, v := range a {
+		if v == t {
+			return true
+		}
+	}
+	return false
+}
+
+// packageSet returns the transaction and its ancestors (or descendants) in the order of their discovery
+func packageSet(t *packageTx, ancestors bool) []*packageTx {
+	visited := map[*packageTx]struct{}{t: {}}
+	rv := []*packageTx{t}
+	for i := 0; i < len(rv); i++ {
+		next := rv[i].children
+		if ancestors {
+			next = rv[i].parents
+		}
+		for _, n := range next {
+			if _, found := visited[n]; !found {
+				visited[n] = struct{}{}
+				rv = append(rv, n)
+			}
+		}
+	}
+	return rv
+}
+
+func packageFeeAndSize(txs []*packageTx) (int64, int) {
+	var fee int64
+	var vsize int
+	for _, t := range txs {
+		fee += t.fee
+		vsize += t.vsize
+	}
+	return fee, vsize
+}
+
+func feePerKb(fee int64, vsize int) int64 {
+	if vsize <= 0 {
+		return 0
+	}
+	return fee * 1000 / int64(vsize)
+}
+
+// feeForSize returns the fee for the size at the fee rate, rounded up
+func feeForSize(feePerKb int64, vsize int) int64 {
+	return (feePerKb*int64(vsize) + 999) / 1000
+}
+
+func maxFee(a int64, b ...int64) int64 {
+	for _, v := range b {
+		if v > a {
+			a = v
+		}
+	}
+	return a
+}
+
+func toMempoolPackageTxs(txs []*packageTx) []MempoolPackageTx {
+	if len(txs) == 0 {
+		return nil
+	}
+	rv := make([]MempoolPackageTx, len(txs))
+	for i, t := range txs {
+		rv[i] = MempoolPackageTx{
+			Txid:     t.txid,
+			FeesSat:  (*Amount)(big.NewInt(t.fee)),
+			VSize:    t.vsize,
+			FeePerKb: feePerKb(t.fee, t.vsize),
+		}
+		for _, p := range t.parents {
+			rv[i].Depends = append(rv[i].Depends, p.txid)
+		}
+	}
+	return rv
+}
+
+// computeMempoolPackage computes the package of the transaction t in a linked graph
+func computeMempoolPackage(t *packageTx, targetFeePerKb int64) *MempoolPackage {
+	ancestors := packageSet(t, true)
+	descendants := packageSet(t, false)
+	ancestorFee, ancestorSize := packageFeeAndSize(ancestors)
+	descendantFee, descendantSize := packageFeeAndSize(descendants)
+	r := &MempoolPackage{
+		Txid:              t.txid,
+		FeesSat:           (*Amount)(big.NewInt(t.fee)),
+		VSize:             t.vsize,
+		FeePerKb:          feePerKb(t.fee, t.vsize),
+		AncestorCount:     len(ancestors),
+		AncestorSize:      ancestorSize,
+		AncestorFeesSat:   (*Amount)(big.NewInt(ancestorFee)),
+		AncestorFeePerKb:  feePerKb(ancestorFee, ancestorSize),
+		DescendantCount:   len(descendants),
+		DescendantSize:    descendantSize,
+		DescendantFeesSat: (*Amount)(big.NewInt(descendantFee)),
+		TargetFeePerKb:    targetFeePerKb,
+		CpfpVSize:         cpfpChildVSize,
+		CpfpFeeSat:        &Amount{},
+		RbfFeeSat:         &Amount{},
+		Ancestors:         toMempoolPackageTxs(ancestors[1:]),
+		Descendants:       toMempoolPackageTxs(descendants[1:]),
+	}
+	for _, a := range ancestors {
+		r.Rbf = r.Rbf || a.rbf
+	}
+	// the transaction is mined together with the best package of itself or of any of its descendants with their ancestors
+	r.EffectiveFeePerKb = r.AncestorFeePerKb
+	for _, d := range descendants[1:] {
+		fee, size := packageFeeAndSize(packageSet(d, true))
+		r.EffectiveFeePerKb = maxFee(r.EffectiveFeePerKb, feePerKb(fee, size))
+	}
+	if r.EffectiveFeePerKb >= targetFeePerKb {
+		return r
+	}
+	// a new child must pay for the whole ancestor package and for itself
+	cpfp := maxFee(feeForSize(targetFeePerKb, ancestorSize+cpfpChildVSize)-ancestorFee, feeForSize(incrementalRelayFeePerKb, cpfpChildVSize))
+	// the replacement of the same size must pay for the replaced transaction with its descendants (BIP125 rules 3 and 4),
+	// must have the target fee rate and must get the package with the unconfirmed ancestors to the target fee rate
+	rbf := maxFee(
+		feeForSize(targetFeePerKb, t.vsize),
+		descendantFee+feeForSize(incrementalRelayFeePerKb, t.vsize),
+		feeForSize(targetFeePerKb, ancestorSize)-(ancestorFee-t.fee),
+	)
+	r.CpfpFeeSat = (*Amount)(big.NewInt(cpfp))
+	r.RbfFeeSat = (*Amount)(big.NewInt(rbf))
+	return r
+}
+
+// GetMempoolPackage returns the unconfirmed ancestors and descendants of the mempool transaction,
+// the package fee rates and the fees needed to bump the transaction to the target fee rate using CPFP or RBF,
+// if targetFeePerKb is not positive, the estimated fee for the next block is used
+func (w *Worker) GetMempoolPackage(txid string, targetFeePerKb int64) (*MempoolPackage, error) {
+	if w.chainType != bchain.ChainBitcoinType {
+		return nil, NewAPIError("Not supported", true)
+	}
+	g := &mempoolPackageGraph{
+		w:   w,
+		txs: make(map[string]*packageTx),
+	}
+	if !g.inMempool(txid) {
+		return nil, NewAPIError(fmt.Sprintf("Transaction '%v' is not in mempool", txid), true)
+	}
+	t, err := g.load(txid)
+	if err != nil {
+		return nil, err
+	}
+	if err = g.loadAncestors(t); err != nil {
+		return nil, err
+	}
+	descendants, err := g.loadDescendants(t)
+	if err != nil {
+		return nil, err
+	}
+	for _, d := range descendants {
+		if err = g.loadAncestors(d); err != nil {
+			return nil, err
+		}
+	}
+	g.link()
+	if targetFeePerKb <= 0 {
+		fee, err := w.cachedEstimateFee(1, true)
+		if err != nil {
+			return nil, errors.Annotatef(err, "cachedEstimateFee")
+		}
+		targetFeePerKb = fee.Int64()
+	}
+	return computeMempoolPackage(t, targetFeePerKb), nil
+}
@@ -0,0 +1,54 @@
+//go:build unittest
+
+package api
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/trezor/blockbook/bchain"
+)
+
+func Test_computeMempoolPackage(t *testing.T) {
+	newGraph := func() (*mempoolPackageGraph, *packageTx) {
+		g := &mempoolPackageGraph{txs: make(map[string]*packageTx)}
+		for _, tx := range []*packageTx{
+			{txid: "parent", fee: 200, vsize: 200, inputs: []bchain.Outpoint{{Txid: "confirmed", Vout: 0}}},
+			{txid: "tx", fee: 300, vsize: 300, inputs: []bchain.Outpoint{{Txid: "parent", Vout: 0}}},
+			{txid: "child", fee: 5000, vsize: 200, inputs: []bchain.Outpoint{{Txid: "tx", Vout: 1}}},
+		} {
+			g.txs[tx.txid] = tx
+			g.order = append(g.order, tx)
+		}
+		g.link()
+		return g, g.txs["tx"]
+	}
+	tests := []struct {
+		name           string
+		targetFeePerKb int64
+		want           string
+	}{
+		{
+			name:           "bump needed",
+			targetFeePerKb: 10000,
+			want:           `{"txid":"tx","fees":"300","vsize":300,"feePerKb":1000,"ancestorCount":2,"ancestorSize":500,"ancestorFees":"500","ancestorFeePerKb":1000,"descendantCount":2,"descendantSize":500,"descendantFees":"5300","effectiveFeePerKb":7857,"targetFeePerKb":10000,"cpfpVSize":110,"cpfpFee":"5600","rbfFee":"5600","ancestors":[{"txid":"parent","fees":"200","vsize":200,"feePerKb":1000}],"descendants":[{"txid":"child","fees":"5000","vsize":200,"feePerKb":25000,"depends":["tx"]}]}`,
+		},
+		{
+			name:           "bumped by child",
+			targetFeePerKb: 5000,
+			want:           `{"txid":"tx","fees":"300","vsize":300,"feePerKb":1000,"ancestorCount":2,"ancestorSize":500,"ancestorFees":"500","ancestorFeePerKb":1000,"descendantCount":2,"descendantSize":500,"descendantFees":"5300","effectiveFeePerKb":7857,"targetFeePerKb":5000,"cpfpVSize":110,"cpfpFee":"0","rbfFee":"0","ancestors":[{"txid":"parent","fees":"200","vsize":200,"feePerKb":1000}],"descendants":[{"txid":"child","fees":"5000","vsize":200,"feePerKb":25000,"depends":["tx"]}]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, tx := newGraph()
+			b, err := json.Marshal(computeMempoolPackage(tx, tt.targetFeePerKb))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != tt.want {
+				t.Errorf("computeMempoolPackage() = %v, want %v", string(b), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,325 @@
+package api
+
+import (
+	"fmt"
+	"math/big"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+	"github.com/trezor/blockbook/bchain"
+	"github.com/trezor/blockbook/db"
+)
+
+// addrDescState is the state of an address descriptor at a block height rebuilt from the index
+type addrDescState struct {
+	received big.Int
+	sent     big.Int
+	txs      int
+	utxos    Utxos
+	// the older transactions of the address are pruned from the index
+	truncated bool
+}
+
+func outpointKey(txid string, vout uint32) string {
+	return txid + ":" + strconv.Itoa(int(vout))
+}
+
+// GetBlockHeightAtTime returns the height of the last block with the time lower or equal to the timestamp
+func (w *Worker) GetBlockHeightAtTime(timestamp int64) (uint32, error) {
+	bestHeight, _, err := w.db.GetBestBlock()
+	if err != nil {
+		return 0, errors.Annotatef(err, "GetBestBlock")
+	}
+	if timestamp < 0 || timestamp >= int64(maxUint32) {
+		return 0, NewAPIError("Invalid timestamp", true)
+	}
+	height := w.is.GetBlockHeightOfTime(uint32(timestamp) + 1)
+	if height == 0 {
+		return 0, NewAPIError("No block before the timestamp", true)
+	}
+	if height == maxUint32 || height > bestHeight+1 {
+		return bestHeight, nil
+	}
+	return height - 1, nil
+}
+
+// newAddressState checks the height and returns AddressState with the block info filled
+func (w *Worker) newAddressState(height uint32) (*AddressState, error) {
+	bestHeight, _, err := w.db.GetBestBlock()
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetBestBlock")
+	}
+	if height > bestHeight {
+		return nil, NewAPIError(fmt.Sprintf("Height %d is higher than the best block %d", height, bestHeight), true)
+	}
+	if err := w.checkPrunedHeight(height); err != nil {
+		return nil, err
+	}
+	bi, err := w.db.GetBlockInfo(height)
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetBlockInfo %v", height)
+	}
+	if bi == nil {
+		return nil, NewAPIError(fmt.Sprintf("Block %d not found", height), true)
+	}
+	return &AddressState{
+		Height:    height,
+		Hash:      bi.Hash,
+		Blocktime: bi.Time,
+	}, nil
+}
+
+// spentOutpoints returns the outpoints spent by the inputs of the transaction
+func (w *Worker) spentOutpoints(txid string, inputs []int32) ([]string, error) {
+	outpoints := make([]string, 0, len(inputs))
+	if w.db.HasExtendedIndex() {
+		ta, err := w.db.GetTxAddresses(txid)
+		if err != nil {
+			return nil, err
+		}
+		if ta == nil {
+			return nil, errors.Errorf("DB inconsistency: tx %v not found in txAddresses", txid)
+		}
+		for _, i := range inputs {
+			if int(i) < len(ta.Inputs) {
+				outpoints = append(outpoints, outpointKey(ta.Inputs[i].Txid, ta.Inputs[i].Vout))
+			}
+		}
+		return outpoints, nil
+	}
+	bchainTx, _, err := w.txCache.GetTransaction(txid)
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetTransaction %v", txid)
+	}
+	for _, i := range inputs {
+		if int(i) < len(bchainTx.Vin) {
+			outpoints = append(outpoints, outpointKey(bchainTx.Vin[i].Txid, bchainTx.Vin[i].Vout))
+		}
+	}
+	return outpoints, nil
+}
+
+// getAddrDescStateAt rebuilds the balance and the unspent outputs of the address descriptor at the height
+// from the addresses and txAddresses columns, the txids of the address are added to the map txids.
+// In the pruned index the state is marked as truncated if some transactions of the address are missing.
+func (w *Worker) getAddrDescStateAt(addrDesc bchain.AddressDescriptor, height uint32, txids map[string]struct{}) (*addrDescState, error) {
+	type txInputs struct {
+		txid   string
+		inputs []int32
+	}
+	// the outputs spent by transactions after the height were unspent at the height
+	spending := make([]txInputs, 0)
+	txsAfter := 0
+	if height < maxUint32 {
+		err := w.db.GetAddrDescTransactions(addrDesc, height+1, maxUint32, func(txid string, height uint32, indexes []int32) error {
+			txsAfter++
+			var inputs []int32
+			for _, index := range indexes {
+				if index < 0 {
+					inputs = append(inputs, ^index)
+				}
+			}
+			if len(inputs) > 0 {
+				spending = append(spending, txInputs{txid, inputs})
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	spentAfter := make(map[string]struct{})
+	for _, s := range spending {
+		outpoints, err := w.spentOutpoints(s.txid, s.inputs)
+		if err != nil {
+			return nil, err
+		}
+		for _, o := range outpoints {
+			spentAfter[o] = struct{}{}
+		}
+	}
+	s := &addrDescState{utxos: make(Utxos, 0)}
+	err := w.db.GetAddrDescTransactions(addrDesc, 0, height, func(txid string, txHeight uint32, indexes []int32) error {
+		ta, err := w.db.GetTxAddresses(txid)
+		if err != nil {
+			return err
+		}
+		if ta == nil {
+			glog.Warning("DB inconsistency:  tx ", txid, ": not found in txAddresses")
+			return nil
+		}
+		s.txs++
+		if txids != nil {
+			txids[txid] = struct{}{}
+		}
+		for _, index := range indexes {
+			if index < 0 {
+				index = ^index
+				if int(index) < len(ta.Inputs) {
+					s.sent.Add(&s.sent, &ta.Inputs[index].ValueSat)
+				}
+				continue
+			}
+			if int(index) >= len(ta.Outputs) {
+				continue
+			}
+			tao := &ta.Outputs[index]
+			s.received.Add(&s.received, &tao.ValueSat)
+			if _, found := spentAfter[outpointKey(txid, uint32(index))]; !tao.Spent || found {
+				confirmations := int(height-txHeight) + 1
+				coinbase := false
+				if confirmations < w.chainParser.MinimumCoinbaseConfirmations() {
+					if len(ta.Inputs) == 1 && len(ta.Inputs[0].AddrDesc) == 0 && IsZeroBigInt(&ta.Inputs[0].ValueSat) {
+						coinbase = true
+					}
+				}
+				s.utxos = append(s.utxos, Utxo{
+					Txid:          txid,
+					Vout:          index,
+					AmountSat:     (*Amount)(new(big.Int).Set(&tao.ValueSat)),
+					Height:        int(txHeight),
+					Confirmations: confirmations,
+					Coinbase:      coinbase,
+				})
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if w.is.GetPrunedHeight() > 0 {
+		ba, err := w.db.GetAddrDescBalance(addrDesc, db.AddressBalanceDetailNoUTXO)
+		if err != nil {
+			return nil, err
+		}
+		if ba != nil && int(ba.Txs) > txsAfter+s.txs {
+			// the state is incomplete, the checksum cannot be verified
+			s.truncated = true
+			return s, nil
+		}
+	}
+	var checksum big.Int
+	checksum.Sub(&s.received, &s.sent)
+	for i := range s.utxos {
+		checksum.Sub(&checksum, (*big.Int)(s.utxos[i].AmountSat))
+	}
+	if checksum.Sign() != 0 {
+		glog.Warning("DB inconsistency:  ", addrDesc, ": state at height ", height, " checksum is not zero, checksum=", checksum.String())
+	}
+	return s, nil
+}
+
+// GetAddressStateAt returns the balance and the unspent outputs of the address as they were at the block height
+func (w *Worker) GetAddressStateAt(address string, height uint32) (*AddressState, error) {
+	if w.chainType != bchain.ChainBitcoinType {
+		return nil, NewAPIError("Not supported", true)
+	}
+	start := time.Now()
+	r, err := w.newAddressState(height)
+	if err != nil {
+		return nil, err
+	}
+	addrDesc, address, err := w.getAddrDescAndNormalizeAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	s, err := w.getAddrDescStateAt(addrDesc, height, nil)
+	if err != nil {
+		return nil, err
+	}
+	var balance big.Int
+	balance.Sub(&s.received, &s.sent)
+	sort.Stable(s.utxos)
+	r.Address = &Address{
+		AddrStr:               address,
+		BalanceSat:            (*Amount)(&balance),
+		TotalReceivedSat:      (*Amount)(&s.received),
+		TotalSentSat:          (*Amount)(&s.sent),
+		UnconfirmedBalanceSat: &Amount{},
+		Txs:                   s.txs,
+		HistoryTruncated:      s.truncated,
+	}
+	r.Utxos = s.utxos
+	glog.Info("GetAddressStateAt ", address, ", height ", height, ", ", len(r.Utxos), " utxos, ", time.Since(start))
+	return r, nil
+}
+
+// GetXpubStateAt returns the balance, the used addresses and the unspent outputs of the xpub as they were at the block height
+func (w *Worker) GetXpubStateAt(xpub string, height uint32, gap int) (*AddressState, error) {
+	start := time.Now()
+	r, err := w.newAddressState(height)
+	if err != nil {
+		return nil, err
+	}
+	xd, err := w.chainParser.ParseXpub(xpub)
+	if err != nil {
+		return nil, err
+	}
+	data, _, inCache, err := w.getXpubData(xd, 0, 1, AccountDetailsBasic, &AddressFilter{
+		Vout:          AddressFilterVoutOff,
+		OnlyConfirmed: true,
+	}, gap)
+	if err != nil {
+		return nil, err
+	}
+	var balance, received, sent big.Int
+	txids := make(map[string]struct{})
+	tokens := make([]Token, 0, 4)
+	utxos := make(Utxos, 0, 8)
+	addrTxCount := 0
+	truncated := false
+	for ci, da := range data.addresses {
+		for i := range da {
+			ad := &da[i]
+			// the address not used now was not used at the height either
+			if ad.balance == nil {
+				continue
+			}
+			s, err := w.getAddrDescStateAt(ad.addrDesc, height, txids)
+			if err != nil {
+				return nil, err
+			}
+			truncated = truncated || s.truncated
+			if s.txs == 0 {
+				continue
+			}
+			var b big.Int
+			b.Sub(&s.received, &s.sent)
+			balance.Add(&balance, &b)
+			received.Add(&received, &s.received)
+			sent.Add(&sent, &s.sent)
+			addrTxCount += s.txs
+			t := w.tokenFromXpubAddress(data, ad, int(xd.ChangeIndexes[ci]), i, AccountDetailsTokens)
+			t.BalanceSat = (*Amount)(&b)
+			t.TotalReceivedSat = (*Amount)(&s.received)
+			t.TotalSentSat = (*Amount)(&s.sent)
+			t.Transfers = s.txs
+			tokens = append(tokens, t)
+			for j := range s.utxos {
+				s.utxos[j].Address = t.Name
+				s.utxos[j].Path = t.Path
+			}
+			utxos = append(utxos, s.utxos...)
+		}
+	}
+	sort.Stable(utxos)
+	r.Address = &Address{
+		AddrStr:               xpub,
+		BalanceSat:            (*Amount)(&balance),
+		TotalReceivedSat:      (*Amount)(&received),
+		TotalSentSat:          (*Amount)(&sent),
+		UnconfirmedBalanceSat: &Amount{},
+		Txs:                   len(txids),
+		AddrTxCount:           addrTxCount,
+		HistoryTruncated:      truncated,
+		UsedTokens:            len(tokens),
+		Tokens:                tokens,
+	}
+	r.Utxos = utxos
+	glog.Info("GetXpubStateAt ", xpub[:xpubLogPrefix], ", height ", height, ", cache ", inCache, ", ", len(r.Utxos), " utxos, ", time.Since(start))
+	return r, nil
+}
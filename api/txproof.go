@@ -0,0 +1,166 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/juju/errors"
+	"github.com/martinboehm/btcd/chaincfg/chainhash"
+	"github.com/martinboehm/btcd/wire"
+	"github.com/trezor/blockbook/bchain"
+)
+
+// merkleBranch returns the hashes needed to compute the Merkle root from the transaction at the index and the Merkle root,
+// the hashes are in the same byte order as txids
+func merkleBranch(txids []string, index int) ([]string, string, error) {
+	if index < 0 || index >= len(txids) {
+		return nil, "", errors.Errorf("Index %v out of range", index)
+	}
+	level := make([]chainhash.Hash, len(txids))
+	for i, txid := range txids {
+		h, err := chainhash.NewHashFromStr(txid)
+		if err != nil {
+			return nil, "", errors.Annotatef(err, "txid %v", txid)
+		}
+		level[i] = *h
+	}
+	branch := make([]string, 0, 16)
+	for len(level) > 1 {
+		if len(level)%2 == 1 {
+			level = append(level, level[len(level)-1])
+		}
+		branch = append(branch, level[index^1].String())
+		next := make([]chainhash.Hash, len(level)/2)
+		var buf [chainhash.HashSize * 2]byte
+		for i := range next {
+			copy(buf[:chainhash.HashSize], level[2*i][:])
+			copy(buf[chainhash.HashSize:], level[2*i+1][:])
+			next[i] = chainhash.DoubleHashH(buf[:])
+		}
+		level = next
+		index /= 2
+	}
+	return branch, level[0].String(), nil
+}
+
+// bitcoinTypeHeader serializes the 80 byte block header from the block info,
+// returns empty string if the serialized header does not hash to the block hash (coins with a different header format)
+func bitcoinTypeHeader(bi *bchain.BlockInfo) string {
+	version, err := bi.Version.Int64()
+	if err != nil {
+		return ""
+	}
+	nonce, err := strconv.ParseUint(string(bi.Nonce), 10, 32)
+	if err != nil {
+		return ""
+	}
+	bits, err := strconv.ParseUint(bi.Bits, 16, 32)
+	if err != nil {
+		return ""
+	}
+	prev, err := chainhash.NewHashFromStr(bi.Prev)
+	if err != nil {
+		return ""
+	}
+	merkleRoot, err := chainhash.NewHashFromStr(bi.MerkleRoot)
+	if err != nil {
+		return ""
+	}
+	h := wire.BlockHeader{
+		Version:    int32(version),
+		PrevBlock:  *prev,
+		MerkleRoot: *merkleRoot,
+		Timestamp:  time.Unix(bi.Time, 0),
+		Bits:       uint32(bits),
+		Nonce:      uint32(nonce),
+	}
+	if h.BlockHash().String() != bi.Hash {
+		return ""
+	}
+	var buf bytes.Buffer
+	if err = h.Serialize(&buf); err != nil {
+		return ""
+	}
+	return fmt.Sprintf("%x", buf.Bytes())
+}
+
+func (w *Worker) getBitcoinTypeTransactionProof(txid string) (*TxProof, error) {
+	bchainTx, height, err := w.txCache.GetTransaction(txid)
+	if err != nil {
+		if err == bchain.ErrTxNotFound {
+			return nil, NewAPIError(fmt.Sprintf("Transaction '%v' not found", txid), true)
+		}
+		return nil, NewAPIError(fmt.Sprintf("Transaction '%v' not found (%v)", txid, err), true)
+	}
+	if bchainTx.Confirmations == 0 || height < 0 {
+		return nil, NewAPIError(fmt.Sprintf("Transaction '%v' is not confirmed", txid), true)
+	}
+	hash, err := w.db.GetBlockHash(uint32(height))
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetBlockHash %v", height)
+	}
+	bi, err := w.chain.GetBlockInfo(hash)
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetBlockInfo %v", hash)
+	}
+	index := -1
+	for i := range bi.Txids {
+		if bi.Txids[i] == txid {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return nil, errors.Errorf("Transaction %v not found in block %v", txid, hash)
+	}
+	merkle, root, err := merkleBranch(bi.Txids, index)
+	if err != nil {
+		return nil, err
+	}
+	if root != bi.MerkleRoot {
+		return nil, NewAPIError("Merkle proof is not supported for the coin", true)
+	}
+	return &TxProof{
+		Txid:        txid,
+		BlockHash:   hash,
+		BlockHeight: uint32(height),
+		Header:      bitcoinTypeHeader(bi),
+		Index:       index,
+		MerkleRoot:  root,
+		Merkle:      merkle,
+	}, nil
+}
+
+func (w *Worker) getEthereumTypeTransactionProof(txid string) (*TxProof, error) {
+	p, err := w.chain.EthereumTypeGetReceiptProof(txid)
+	if err != nil {
+		if err == bchain.ErrTxNotFound {
+			return nil, NewAPIError(fmt.Sprintf("Transaction '%v' not found or not confirmed", txid), true)
+		}
+		return nil, errors.Annotatef(err, "EthereumTypeGetReceiptProof %v", txid)
+	}
+	return &TxProof{
+		Txid:         txid,
+		BlockHash:    p.BlockHash,
+		BlockHeight:  p.BlockNumber,
+		Header:       p.Header,
+		Index:        int(p.TxIndex),
+		ReceiptsRoot: p.ReceiptsRoot,
+		Key:          p.Key,
+		Receipt:      p.Receipt,
+		Proof:        p.Proof,
+	}, nil
+}
+
+// GetTransactionProof returns the proof of inclusion of the confirmed transaction in its block
+func (w *Worker) GetTransactionProof(txid string) (*TxProof, error) {
+	switch w.chainType {
+	case bchain.ChainBitcoinType:
+		return w.getBitcoinTypeTransactionProof(txid)
+	case bchain.ChainEthereumType:
+		return w.getEthereumTypeTransactionProof(txid)
+	}
+	return nil, NewAPIError("Not supported", true)
+}
@@ -0,0 +1,106 @@
+//go:build unittest
+
+package api
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/trezor/blockbook/bchain"
+	"github.com/trezor/blockbook/common"
+)
+
+// block 100000 of Bitcoin mainnet
+var testProofBlockInfo = bchain.BlockInfo{
+	BlockHeader: bchain.BlockHeader{
+		Hash:   "000000000003ba27aa200b1cecaad478d2b00432346c3f1f3986da1afd33e506",
+		Prev:   "000000000002d01c1fccc21636b607dfd930d31d01c3a62104612a1719011250",
+		Height: 100000,
+		Time:   1293623863,
+	},
+	Version:    common.JSONNumber("1"),
+	MerkleRoot: "f3e94742aca4b5ef85488dc37c06c3282295ffec960994b2c0d5ac2a25a95766",
+	Nonce:      common.JSONNumber("274148111"),
+	Bits:       "1b04864c",
+	Txids: []string{
+		"8c14f0db3df150123e6f3dbbf30f8b955a8249b62ac1d1ff16284aefa3d06d87",
+		"fff2525b8931402dd09222c50775608f75787bd2b87e56995a7bdd30f79702c4",
+		"6359f0868171b1d194cbee1af2f16ea598ae8fad666d9b012c8ed2b79a236ec4",
+		"e9a66845e05d5abc0ad04ec80f774a7e585c6e8db975962d069a522137b80c1d",
+	},
+}
+
+func Test_merkleBranch(t *testing.T) {
+	tests := []struct {
+		name     string
+		txids    []string
+		index    int
+		want     []string
+		wantRoot string
+		wantErr  bool
+	}{
+		{
+			name:     "first tx",
+			txids:    testProofBlockInfo.Txids,
+			index:    0,
+			want:     []string{"fff2525b8931402dd09222c50775608f75787bd2b87e56995a7bdd30f79702c4", "8e30899078ca1813be036a073bbf80b86cdddde1c96e9e9c99e9e3782df4ae49"},
+			wantRoot: testProofBlockInfo.MerkleRoot,
+		},
+		{
+			name:     "last tx",
+			txids:    testProofBlockInfo.Txids,
+			index:    3,
+			want:     []string{"6359f0868171b1d194cbee1af2f16ea598ae8fad666d9b012c8ed2b79a236ec4", "ccdafb73d8dcd0173d5d5c3c9a0770d0b3953db889dab99ef05b1907518cb815"},
+			wantRoot: testProofBlockInfo.MerkleRoot,
+		},
+		{
+			name:     "odd number of txs",
+			txids:    testProofBlockInfo.Txids[:3],
+			index:    2,
+			want:     []string{"6359f0868171b1d194cbee1af2f16ea598ae8fad666d9b012c8ed2b79a236ec4", "ccdafb73d8dcd0173d5d5c3c9a0770d0b3953db889dab99ef05b1907518cb815"},
+			wantRoot: "",
+		},
+		{
+			name:     "single tx",
+			txids:    testProofBlockInfo.Txids[:1],
+			index:    0,
+			want:     []string{},
+			wantRoot: "8c14f0db3df150123e6f3dbbf30f8b955a8249b62ac1d1ff16284aefa3d06d87",
+		},
+		{
+			name:    "index out of range",
+			txids:   testProofBlockInfo.Txids,
+			index:   4,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, root, err := merkleBranch(tt.txids, tt.index)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("merkleBranch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("merkleBranch() = %v, want %v", got, tt.want)
+			}
+			if tt.wantRoot != "" && root != tt.wantRoot {
+				t.Errorf("merkleBranch() root = %v, want %v", root, tt.wantRoot)
+			}
+		})
+	}
+}
+
+func Test_bitcoinTypeHeader(t *testing.T) {
+	want := "0100000050120119172a610421a6c3011dd330d9df07b63616c2cc1f1cd00200000000006657a9252aacd5c0b2940996ecff952228c3067cc38d4885efb5a4ac4247e9f337221b4d4c86041b0f2b5710"
+	if got := bitcoinTypeHeader(&testProofBlockInfo); got != want {
+		t.Errorf("bitcoinTypeHeader() = %v, want %v", got, want)
+	}
+	bi := testProofBlockInfo
+	bi.Nonce = "1"
+	if got := bitcoinTypeHeader(&bi); got != "" {
+		t.Errorf("bitcoinTypeHeader() with a wrong nonce = %v, want empty", got)
+	}
+}
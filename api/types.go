@@ -1,6 +1,8 @@
 package api
 
 import (
+	"encoding/base64"
+	"encoding/binary"
 	"encoding/json"
 	"errors"
 	"math/big"
@@ -224,17 +226,44 @@ type EthereumInternalTransfer struct {
 
 // EthereumSpecific contains ethereum specific transaction data
 type EthereumSpecific struct {
-	Type              bchain.EthereumInternalTransactionType `json:"type,omitempty"`
-	CreatedContract   string                                 `json:"createdContract,omitempty"`
-	Status            eth.TxStatus                           `json:"status"` // 1 OK, 0 Fail, -1 pending
-	Error             string                                 `json:"error,omitempty"`
-	Nonce             uint64                                 `json:"nonce"`
-	GasLimit          *big.Int                               `json:"gasLimit"`
-	GasUsed           *big.Int                               `json:"gasUsed,omitempty"`
-	GasPrice          *Amount                                `json:"gasPrice,omitempty"`
-	Data              string                                 `json:"data,omitempty"`
-	ParsedData        *bchain.EthereumParsedInputData        `json:"parsedData,omitempty"`
-	InternalTransfers []EthereumInternalTransfer             `json:"internalTransfers,omitempty"`
+	Type                 bchain.EthereumInternalTransactionType `json:"type,omitempty"`
+	CreatedContract      string                                 `json:"createdContract,omitempty"`
+	Status               eth.TxStatus                           `json:"status"` // 1 OK, 0 Fail, -1 pending
+	Error                string                                 `json:"error,omitempty"`
+	Nonce                uint64                                 `json:"nonce"`
+	GasLimit             *big.Int                               `json:"gasLimit"`
+	GasUsed              *big.Int                               `json:"gasUsed,omitempty"`
+	GasPrice             *Amount                                `json:"gasPrice,omitempty"`
+	MaxFeePerGas         *Amount                                `json:"maxFeePerGas,omitempty"`
+	MaxPriorityFeePerGas *Amount                                `json:"maxPriorityFeePerGas,omitempty"`
+	EffectiveGasPrice    *Amount                                `json:"effectiveGasPrice,omitempty"`
+	BaseFeePerGas        *Amount                                `json:"baseFeePerGas,omitempty"`
+	Data                 string                                 `json:"data,omitempty"`
+	ParsedData           *bchain.EthereumParsedInputData        `json:"parsedData,omitempty"`
+	InternalTransfers    []EthereumInternalTransfer             `json:"internalTransfers,omitempty"`
+	Logs                 []EthereumLog                          `json:"logs,omitempty"`
+}
+
+// EthereumLog contains a log of the transaction and the event decoded from it, if its signature is known
+type EthereumLog struct {
+	Address    string                        `json:"address"`
+	Topics     []string                      `json:"topics"`
+	Data       string                        `json:"data,omitempty"`
+	ParsedData *bchain.EthereumParsedLogData `json:"parsedData,omitempty"`
+}
+
+// Eip1559Fee is the max fee and max priority fee of the EIP-1559 transaction
+type Eip1559Fee struct {
+	MaxFeePerGas         *Amount `json:"maxFeePerGas"`
+	MaxPriorityFeePerGas *Amount `json:"maxPriorityFeePerGas"`
+}
+
+// Eip1559Fees contains the base fee of the next block and the slow, normal and fast EIP-1559 fee estimation
+type Eip1559Fees struct {
+	BaseFeePerGas *Amount     `json:"baseFeePerGas"`
+	Slow          *Eip1559Fee `json:"slow"`
+	Normal        *Eip1559Fee `json:"normal"`
+	Fast          *Eip1559Fee `json:"fast"`
 }
 
 type AddressAlias struct {
@@ -263,6 +292,8 @@ type Tx struct {
 	FeesSat                *Amount           `json:"fees,omitempty"`
 	Hex                    string            `json:"hex,omitempty"`
 	Rbf                    bool              `json:"rbf,omitempty"`
+	ReplacedBy             string            `json:"replacedBy,omitempty"`
+	Replaces               []string          `json:"replaces,omitempty"`
 	CoinSpecificData       json.RawMessage   `json:"coinSpecificData,omitempty" ts_type:"any"`
 	TokenTransfers         []TokenTransfer   `json:"tokenTransfers,omitempty"`
 	EthereumSpecific       *EthereumSpecific `json:"ethereumSpecific,omitempty"`
@@ -279,9 +310,40 @@ type FeeStats struct {
 
 // Paging contains information about paging for address, blocks and block
 type Paging struct {
-	Page        int `json:"page,omitempty"`
-	TotalPages  int `json:"totalPages,omitempty"`
-	ItemsOnPage int `json:"itemsOnPage,omitempty"`
+	Page        int    `json:"page,omitempty"`
+	TotalPages  int    `json:"totalPages,omitempty"`
+	ItemsOnPage int    `json:"itemsOnPage,omitempty"`
+	NextCursor  string `json:"nextCursor,omitempty"`
+}
+
+// TxCursor is a position in the confirmed transaction history of an address or xpub,
+// it points to the Index-th transaction of the history in the block at the Height
+type TxCursor struct {
+	Height uint32
+	Index  uint32
+}
+
+// String returns the opaque representation of the cursor
+func (c *TxCursor) String() string {
+	if c == nil {
+		return ""
+	}
+	b := make([]byte, 8)
+	binary.BigEndian.PutUint32(b, c.Height)
+	binary.BigEndian.PutUint32(b[4:], c.Index)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// ParseTxCursor parses the cursor from its opaque representation returned in Paging.NextCursor
+func ParseTxCursor(s string) (*TxCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || len(b) != 8 {
+		return nil, NewAPIError("Invalid cursor", true)
+	}
+	return &TxCursor{
+		Height: binary.BigEndian.Uint32(b),
+		Index:  binary.BigEndian.Uint32(b[4:]),
+	}, nil
 }
 
 // TokensToReturn specifies what tokens are returned by GetAddress and GetXpubAddress
@@ -314,6 +376,9 @@ type AddressFilter struct {
 	TokensToReturn TokensToReturn
 	// OnlyConfirmed set to true will ignore mempool transactions; mempool is also ignored if FromHeight/ToHeight filter is specified
 	OnlyConfirmed bool
+	// Cursor continues the listing of confirmed transactions at the position returned in Paging.NextCursor,
+	// the page number is ignored and mempool transactions are not listed if Cursor is specified
+	Cursor *TxCursor
 }
 
 // StakingPool holds data about address participation in a staking pool contract
@@ -339,6 +404,7 @@ type Address struct {
 	UnconfirmedBalanceSat *Amount              `json:"unconfirmedBalance"`
 	UnconfirmedTxs        int                  `json:"unconfirmedTxs"`
 	Txs                   int                  `json:"txs"`
+	HistoryTruncated      bool                 `json:"historyTruncated,omitempty"` // the older transactions of the address are pruned from the index
 	AddrTxCount           int                  `json:"addrTxCount,omitempty"`
 	NonTokenTxs           int                  `json:"nonTokenTxs,omitempty"`
 	InternalTxs           int                  `json:"internalTxs,omitempty"`
@@ -377,6 +443,15 @@ type Utxo struct {
 // Utxos is array of Utxo
 type Utxos []Utxo
 
+// AddressState is the balance and the unspent outputs of an address or xpub at a block height
+type AddressState struct {
+	Height    uint32   `json:"height"`
+	Hash      string   `json:"hash"`
+	Blocktime int64    `json:"blockTime"`
+	Address   *Address `json:"address"`
+	Utxos     Utxos    `json:"utxos"`
+}
+
 func (a Utxos) Len() int      { return len(a) }
 func (a Utxos) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
 func (a Utxos) Less(i, j int) bool {
@@ -459,6 +534,18 @@ func (a BalanceHistories) SortAndAggregate(groupByTime uint32) BalanceHistories
 	return bhs
 }
 
+// ExportTx is one row of the transaction history export of an address or xpub
+type ExportTx struct {
+	Txid         string  `json:"txid"`
+	Blockheight  uint32  `json:"blockHeight"`
+	Blocktime    int64   `json:"blockTime"`
+	BalanceDelta *Amount `json:"balanceDelta"`
+	Balance      *Amount `json:"balance"`
+	FeesSat      *Amount `json:"fees"`
+	FiatRate     float64 `json:"fiatRate,omitempty"`
+	FiatValue    float64 `json:"fiatValue,omitempty"`
+}
+
 // Blocks is list of blocks with paging information
 type Blocks struct {
 	Paging
@@ -496,6 +583,22 @@ type BlockRaw struct {
 	Hex string `json:"hex"`
 }
 
+// TxProof contains the proof of inclusion of a transaction in a block,
+// the Merkle branch for Bitcoin-type coins, the receipts trie proof for Ethereum-type coins
+type TxProof struct {
+	Txid         string   `json:"txid"`
+	BlockHash    string   `json:"blockHash"`
+	BlockHeight  uint32   `json:"blockHeight"`
+	Header       string   `json:"header,omitempty"`
+	Index        int      `json:"index"`
+	MerkleRoot   string   `json:"merkleRoot,omitempty"`
+	Merkle       []string `json:"merkle,omitempty"`
+	ReceiptsRoot string   `json:"receiptsRoot,omitempty"`
+	Key          string   `json:"key,omitempty"`
+	Receipt      string   `json:"receipt,omitempty"`
+	Proof        []string `json:"proof,omitempty"`
+}
+
 // BlockbookInfo contains information about the running blockbook instance
 type BlockbookInfo struct {
 	Coin                         string                       `json:"coin"`
@@ -513,6 +616,7 @@ type BlockbookInfo struct {
 	MempoolSize                  int                          `json:"mempoolSize"`
 	Decimals                     int                          `json:"decimals"`
 	DbSize                       int64                        `json:"dbSize"`
+	PrunedHeight                 uint32                       `json:"prunedHeight,omitempty"`
 	HasFiatRates                 bool                         `json:"hasFiatRates,omitempty"`
 	HasTokenFiatRates            bool                         `json:"hasTokenFiatRates,omitempty"`
 	CurrentFiatRatesTime         *time.Time                   `json:"currentFiatRatesTime,omitempty"`
@@ -543,6 +647,95 @@ type MempoolTxids struct {
 	MempoolSize int           `json:"mempoolSize"`
 }
 
+// MempoolConflicts contains the replacements of a transaction and the mempool transactions spending the same outpoints
+type MempoolConflicts struct {
+	Txid             string   `json:"txid"`
+	ReplacedBy       string   `json:"replacedBy,omitempty"`
+	Replaces         []string `json:"replaces,omitempty"`
+	Conflicts        []string `json:"conflicts,omitempty"`
+	ReplacementChain []string `json:"replacementChain,omitempty"`
+}
+
+// MempoolPackageTx is an unconfirmed ancestor or descendant of a transaction in a mempool package
+type MempoolPackageTx struct {
+	Txid     string   `json:"txid"`
+	FeesSat  *Amount  `json:"fees"`
+	VSize    int      `json:"vsize"`
+	FeePerKb int64    `json:"feePerKb"`
+	Depends  []string `json:"depends,omitempty"`
+}
+
+// MempoolPackage contains the unconfirmed ancestors and descendants of a mempool transaction
+// and the fees needed to bump the transaction to the target fee rate,
+// the ancestor and descendant counts, sizes and fees include the transaction itself
+type MempoolPackage struct {
+	Txid              string             `json:"txid"`
+	FeesSat           *Amount            `json:"fees"`
+	VSize             int                `json:"vsize"`
+	FeePerKb          int64              `json:"feePerKb"`
+	Rbf               bool               `json:"rbf,omitempty"`
+	AncestorCount     int                `json:"ancestorCount"`
+	AncestorSize      int                `json:"ancestorSize"`
+	AncestorFeesSat   *Amount            `json:"ancestorFees"`
+	AncestorFeePerKb  int64              `json:"ancestorFeePerKb"`
+	DescendantCount   int                `json:"descendantCount"`
+	DescendantSize    int                `json:"descendantSize"`
+	DescendantFeesSat *Amount            `json:"descendantFees"`
+	EffectiveFeePerKb int64              `json:"effectiveFeePerKb"`
+	TargetFeePerKb    int64              `json:"targetFeePerKb"`
+	CpfpVSize         int                `json:"cpfpVSize"`
+	CpfpFeeSat        *Amount            `json:"cpfpFee"`
+	RbfFeeSat         *Amount            `json:"rbfFee"`
+	Ancestors         []MempoolPackageTx `json:"ancestors,omitempty"`
+	Descendants       []MempoolPackageTx `json:"descendants,omitempty"`
+}
+
+// TokenApproval is the current approval of the spender to transfer the tokens of the owner,
+// the value is the current allowance of the ERC20 token, the ERC721 and ERC1155 tokens are approved for all.
+// Height is the block height of the last Approval event of the spender.
+type TokenApproval struct {
+	Type     bchain.TokenTypeName `json:"type" ts_type:"'' | 'ERC20' | 'ERC721' | 'ERC1155'"`
+	Contract string               `json:"contract"`
+	Name     string               `json:"name,omitempty"`
+	Symbol   string               `json:"symbol,omitempty"`
+	Decimals int                  `json:"decimals,omitempty"`
+	Spender  string               `json:"spender"`
+	ForAll   bool                 `json:"forAll,omitempty"`
+	ValueSat *Amount              `json:"value,omitempty"`
+	Height   uint32               `json:"height"`
+}
+
+// TokenApprovals contains the current approvals given by the address
+type TokenApprovals struct {
+	Address   string          `json:"address"`
+	Approvals []TokenApproval `json:"approvals"`
+}
+
+// ContractLog is a log of the contract stored in the contract log index, the topics contain also the topic0
+type ContractLog struct {
+	Txid        string   `json:"txid"`
+	BlockHeight uint32   `json:"blockHeight"`
+	LogIndex    uint32   `json:"logIndex"`
+	Topics      []string `json:"topics"`
+	Data        string   `json:"data"`
+}
+
+// ContractLogs contains the logs of the contract with the topic0 in the requested range of blocks
+type ContractLogs struct {
+	Paging
+	Contract string        `json:"contract"`
+	Topic0   string        `json:"topic0"`
+	Logs     []ContractLog `json:"logs"`
+}
+
+// ReturnData is the data returned by the call of the contract decoded by the verified ABI of the contract,
+// ParsedData is the decoded input data of the call
+type ReturnData struct {
+	Contract   string                            `json:"contract"`
+	ParsedData *bchain.EthereumParsedInputData   `json:"parsedData"`
+	Outputs    []bchain.EthereumParsedInputParam `json:"outputs"`
+}
+
 // FiatTicker contains formatted CurrencyRatesTicker data
 type FiatTicker struct {
 	Timestamp int64              `json:"ts,omitempty"`
@@ -321,3 +321,52 @@ func TestTokens_Sort(t *testing.T) {
 		})
 	}
 }
+
+func TestTxCursor(t *testing.T) {
+	tests := []struct {
+		name    string
+		cursor  *TxCursor
+		want    string
+		wantErr bool
+	}{
+		{
+			name:   "nil",
+			cursor: nil,
+			want:   "",
+		},
+		{
+			name:   "height 225493 index 0",
+			cursor: &TxCursor{Height: 225493, Index: 0},
+			want:   "AANw1QAAAAA",
+		},
+		{
+			name:   "height 225494 index 1",
+			cursor: &TxCursor{Height: 225494, Index: 1},
+			want:   "AANw1gAAAAE",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.cursor.String()
+			if got != tt.want {
+				t.Errorf("TxCursor.String() = %v, want %v", got, tt.want)
+			}
+			if tt.cursor == nil {
+				return
+			}
+			parsed, err := ParseTxCursor(got)
+			if err != nil {
+				t.Errorf("ParseTxCursor() error = %v", err)
+				return
+			}
+			if !reflect.DeepEqual(parsed, tt.cursor) {
+				t.Errorf("ParseTxCursor() = %+v, want %+v", parsed, tt.cursor)
+			}
+		})
+	}
+	for _, s := range []string{"1234", "AANw1QAAAAAA", "!!!!!!!!!!!"} {
+		if _, err := ParseTxCursor(s); err == nil {
+			t.Errorf("ParseTxCursor(%v) expected error", s)
+		}
+	}
+}
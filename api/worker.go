@@ -2,6 +2,7 @@ package api
 
 import (
 	"bytes"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"math"
@@ -24,7 +25,7 @@ import (
 
 // Worker is handle to api worker
 type Worker struct {
-	db                *db.RocksDB
+	db                db.Store
 	txCache           *db.TxCache
 	chain             bchain.BlockChain
 	chainParser       bchain.BlockChainParser
@@ -40,7 +41,7 @@ type Worker struct {
 type contractInfoCache = map[string]*bchain.ContractInfo
 
 // NewWorker creates new api worker
-func NewWorker(db *db.RocksDB, chain bchain.BlockChain, mempool bchain.Mempool, txCache *db.TxCache, metrics *common.Metrics, is *common.InternalState, fiatRates *fiat.FiatRates) (*Worker, error) {
+func NewWorker(db db.Store, chain bchain.BlockChain, mempool bchain.Mempool, txCache *db.TxCache, metrics *common.Metrics, is *common.InternalState, fiatRates *fiat.FiatRates) (*Worker, error) {
 	w := &Worker{
 		db:                db,
 		txCache:           txCache,
@@ -187,30 +188,111 @@ func (w *Worker) getAddressAliases(addresses map[string]struct{}) AddressAliases
 	return nil
 }
 
-// GetTransaction reads transaction data from txid
+// GetTransaction reads transaction data from txid, for Ethereum type coins including the decoded logs
 func (w *Worker) GetTransaction(txid string, spendingTxs bool, specificJSON bool) (*Tx, error) {
 	addresses := w.newAddressesMapForAliases()
-	tx, err := w.getTransaction(txid, spendingTxs, specificJSON, addresses)
+	bchainTx, height, err := w.getBchainTransaction(txid)
 	if err != nil {
 		return nil, err
 	}
+	tx, err := w.getTransactionFromBchainTx(bchainTx, height, spendingTxs, specificJSON, addresses)
+	if err != nil {
+		return nil, err
+	}
+	if tx.EthereumSpecific != nil {
+		tx.EthereumSpecific.Logs = w.getEthereumLogs(bchainTx, addresses)
+	}
 	tx.AddressAliases = w.getAddressAliases(addresses)
 	return tx, nil
 }
 
 // getTransaction reads transaction data from txid
 func (w *Worker) getTransaction(txid string, spendingTxs bool, specificJSON bool, addresses map[string]struct{}) (*Tx, error) {
+	bchainTx, height, err := w.getBchainTransaction(txid)
+	if err != nil {
+		return nil, err
+	}
+	return w.getTransactionFromBchainTx(bchainTx, height, spendingTxs, specificJSON, addresses)
+}
+
+func (w *Worker) getBchainTransaction(txid string) (*bchain.Tx, int, error) {
 	bchainTx, height, err := w.txCache.GetTransaction(txid)
 	if err != nil {
 		if err == bchain.ErrTxNotFound {
-			return nil, NewAPIError(fmt.Sprintf("Transaction '%v' not found", txid), true)
+			return nil, 0, NewAPIError(fmt.Sprintf("Transaction '%v' not found", txid), true)
 		}
-		return nil, NewAPIError(fmt.Sprintf("Transaction '%v' not found (%v)", txid, err), true)
+		return nil, 0, NewAPIError(fmt.Sprintf("Transaction '%v' not found (%v)", txid, err), true)
 	}
-	return w.getTransactionFromBchainTx(bchainTx, height, spendingTxs, specificJSON, addresses)
+	return bchainTx, height, nil
+}
+
+// getEthereumLogs returns the logs of the transaction decoded by the known event signatures
+func (w *Worker) getEthereumLogs(bchainTx *bchain.Tx, addresses map[string]struct{}) []EthereumLog {
+	csd, ok := bchainTx.CoinSpecificData.(bchain.EthereumSpecificData)
+	if !ok || csd.Receipt == nil || len(csd.Receipt.Logs) == 0 {
+		return nil
+	}
+	logs := make([]EthereumLog, len(csd.Receipt.Logs))
+	for i, l := range csd.Receipt.Logs {
+		logs[i] = EthereumLog{
+			Address: eth.EIP55AddressFromAddress(l.Address),
+			Topics:  l.Topics,
+			Data:    l.Data,
+		}
+		aggregateAddress(addresses, logs[i].Address)
+		if len(l.Topics) > 0 {
+			contract, err := w.chainParser.GetAddrDescFromAddress(l.Address)
+			if err != nil {
+				glog.Errorf("GetAddrDescFromAddress error %v, tx %v, log address %v", err, bchainTx.Txid, l.Address)
+			}
+			logs[i].ParsedData = w.getParsedEthereumLogData(contract, l.Topics, l.Data)
+		}
+	}
+	return logs
+}
+
+// getContractABI returns the verified ABI of the contract or nil if it is not known
+func (w *Worker) getContractABI(contract bchain.AddressDescriptor) *eth.ContractABI {
+	if len(contract) == 0 {
+		return nil
+	}
+	a, err := w.db.GetContractABI(contract)
+	if err != nil {
+		glog.Errorf("GetContractABI(%v) error %v", contract, err)
+		return nil
+	}
+	return a
 }
 
-func (w *Worker) getParsedEthereumInputData(data string) *bchain.EthereumParsedInputData {
+// getParsedEthereumLogData decodes the log using the verified ABI of the contract, if it is not known, using the event signatures
+func (w *Worker) getParsedEthereumLogData(contract bchain.AddressDescriptor, topics []string, data string) *bchain.EthereumParsedLogData {
+	if a := w.getContractABI(contract); a != nil {
+		if parsed := a.ParseLogData(topics, data); parsed != nil {
+			return parsed
+		}
+	}
+	topic0, err := hex.DecodeString(strings.TrimPrefix(topics[0], "0x"))
+	if err != nil || len(topic0) != 32 {
+		return nil
+	}
+	signatures, err := w.db.GetEventSignatures(topic0)
+	if err != nil {
+		glog.Errorf("GetEventSignatures(%v) error %v", topics[0], err)
+		return nil
+	}
+	if signatures == nil || len(*signatures) == 0 {
+		return nil
+	}
+	return eth.ParseLogData(signatures, topics, data)
+}
+
+// getParsedEthereumInputData decodes the input data using the verified ABI of the called contract, if it is not known, using the four byte signatures
+func (w *Worker) getParsedEthereumInputData(contract bchain.AddressDescriptor, data string) *bchain.EthereumParsedInputData {
+	if a := w.getContractABI(contract); a != nil {
+		if parsed := a.ParseInputData(data); parsed != nil {
+			return parsed
+		}
+	}
 	var err error
 	var signatures *[]bchain.FourByteSignature
 	fourBytes := eth.GetSignatureFromData(data)
@@ -237,6 +319,9 @@ func (w *Worker) getConfirmationETA(tx *Tx) (int64, uint32) {
 		// and the tx is most probably going to be confirmed in the first block
 		if mempoolSize < 32 {
 			etaBlocks = 1
+		} else if b := w.templateTxBlock(tx.Txid); b > 0 {
+			// the block template accounts also for the ancestors and descendants of the tx
+			etaBlocks = uint32(b)
 		} else {
 			var txFeePerKB int64
 			if tx.VSize > 0 {
@@ -270,6 +355,17 @@ func (w *Worker) getConfirmationETA(tx *Tx) (int64, uint32) {
 	return etaSeconds, etaBlocks
 }
 
+// templateTxBlock returns the projected block of the tx from the mempool block template, 0 if not available
+func (w *Worker) templateTxBlock(txid string) int {
+	if w.mempool == nil {
+		return 0
+	}
+	if t := w.mempool.GetBlockTemplate(); t != nil {
+		return t.TxBlock(txid)
+	}
+	return 0
+}
+
 // getTransactionFromBchainTx reads transaction data from txid
 func (w *Worker) getTransactionFromBchainTx(bchainTx *bchain.Tx, height int, spendingTxs bool, specificJSON bool, addresses map[string]struct{}) (*Tx, error) {
 	var err error
@@ -332,7 +428,8 @@ func (w *Worker) getTransactionFromBchainTx(bchainTx *bchain.Tx, height int, spe
 					}
 					// mempool transactions are not in TxAddresses but confirmed should be there, log a problem
 					// ignore when Confirmations==1, it may be just a timing problem
-					if bchainTx.Confirmations > 1 {
+					// the spent transactions are removed from the pruned index
+					if bchainTx.Confirmations > 1 && w.is.GetPrunedHeight() == 0 {
 						glog.Warning("DB inconsistency:  tx ", bchainVin.Txid, ": not found in txAddresses, confirmations ", bchainTx.Confirmations)
 					}
 					if len(otx.Vout) > int(vin.Vout) {
@@ -424,23 +521,31 @@ func (w *Worker) getTransactionFromBchainTx(bchainTx *bchain.Tx, height int, spe
 			}
 		}
 
-		parsedInputData := w.getParsedEthereumInputData(ethTxData.Data)
+		var contract bchain.AddressDescriptor
+		if len(vouts) > 0 {
+			contract = vouts[0].AddrDesc
+		}
+		parsedInputData := w.getParsedEthereumInputData(contract, ethTxData.Data)
 
 		// mempool txs do not have fees yet
 		if ethTxData.GasUsed != nil {
-			feesSat.Mul(ethTxData.GasPrice, ethTxData.GasUsed)
+			feesSat.Mul(ethTxData.PaidGasPrice(), ethTxData.GasUsed)
 		}
 		if len(bchainTx.Vout) > 0 {
 			valOutSat = bchainTx.Vout[0].ValueSat
 		}
 		ethSpecific = &EthereumSpecific{
-			GasLimit:   ethTxData.GasLimit,
-			GasPrice:   (*Amount)(ethTxData.GasPrice),
-			GasUsed:    ethTxData.GasUsed,
-			Nonce:      ethTxData.Nonce,
-			Status:     ethTxData.Status,
-			Data:       ethTxData.Data,
-			ParsedData: parsedInputData,
+			GasLimit:             ethTxData.GasLimit,
+			GasPrice:             (*Amount)(ethTxData.GasPrice),
+			GasUsed:              ethTxData.GasUsed,
+			Nonce:                ethTxData.Nonce,
+			Status:               ethTxData.Status,
+			Data:                 ethTxData.Data,
+			ParsedData:           parsedInputData,
+			MaxFeePerGas:         (*Amount)(ethTxData.MaxFeePerGas),
+			MaxPriorityFeePerGas: (*Amount)(ethTxData.MaxPriorityFeePerGas),
+			EffectiveGasPrice:    (*Amount)(ethTxData.EffectiveGasPrice),
+			BaseFeePerGas:        (*Amount)(ethTxData.BaseFeePerGas),
 		}
 		if internalData != nil {
 			ethSpecific.Type = internalData.Type
@@ -493,6 +598,7 @@ func (w *Worker) getTransactionFromBchainTx(bchainTx *bchain.Tx, height int, spe
 		r.Blocktime = int64(w.mempool.GetTransactionTime(bchainTx.Txid))
 		r.ConfirmationETASeconds, r.ConfirmationETABlocks = w.getConfirmationETA(r)
 	}
+	w.setTxReplacements(r)
 	return r, nil
 }
 
@@ -571,12 +677,14 @@ func (w *Worker) GetTransactionFromMempoolTx(mempoolTx *bchain.MempoolTx) (*Tx,
 		tokens = w.getEthereumTokensTransfers(mempoolTx.TokenTransfers, addresses)
 		ethTxData := eth.GetEthereumTxDataFromSpecificData(mempoolTx.CoinSpecificData)
 		ethSpecific = &EthereumSpecific{
-			GasLimit: ethTxData.GasLimit,
-			GasPrice: (*Amount)(ethTxData.GasPrice),
-			GasUsed:  ethTxData.GasUsed,
-			Nonce:    ethTxData.Nonce,
-			Status:   ethTxData.Status,
-			Data:     ethTxData.Data,
+			GasLimit:             ethTxData.GasLimit,
+			GasPrice:             (*Amount)(ethTxData.GasPrice),
+			GasUsed:              ethTxData.GasUsed,
+			Nonce:                ethTxData.Nonce,
+			Status:               ethTxData.Status,
+			Data:                 ethTxData.Data,
+			MaxFeePerGas:         (*Amount)(ethTxData.MaxFeePerGas),
+			MaxPriorityFeePerGas: (*Amount)(ethTxData.MaxPriorityFeePerGas),
 		}
 	}
 	r := &Tx{
@@ -598,6 +706,7 @@ func (w *Worker) GetTransactionFromMempoolTx(mempoolTx *bchain.MempoolTx) (*Tx,
 		AddressAliases:   w.getAddressAliases(addresses),
 	}
 	r.ConfirmationETASeconds, r.ConfirmationETABlocks = w.getConfirmationETA(r)
+	w.setTxReplacements(r)
 	return r, nil
 }
 
@@ -737,20 +846,50 @@ func (w *Worker) GetEthereumTokenURI(contract string, id string) (string, *bchai
 	return uri, ci, nil
 }
 
-func (w *Worker) getAddressTxids(addrDesc bchain.AddressDescriptor, mempool bool, filter *AddressFilter, maxResults int) ([]string, error) {
+// getAddressTxids returns txids of the address, up to maxResults; for confirmed transactions it returns also the cursor
+// pointing to the next transaction of the history, if there are more transactions than maxResults
+func (w *Worker) getAddressTxids(addrDesc bchain.AddressDescriptor, mempool bool, filter *AddressFilter, maxResults int) ([]string, *TxCursor, error) {
 	var err error
+	var next *TxCursor
 	txids := make([]string, 0, 4)
+	// position of the currently processed confirmed transaction, the index is counted within the block
+	var position TxCursor
+	started := false
+	skip := func(height uint32) bool {
+		if mempool {
+			return false
+		}
+		if started && position.Height == height {
+			position.Index++
+		} else {
+			position = TxCursor{Height: height}
+			started = true
+		}
+		return filter.Cursor != nil && height == filter.Cursor.Height && position.Index < filter.Cursor.Index
+	}
+	add := func(txid string) error {
+		if len(txids) >= maxResults {
+			if !mempool {
+				next = &TxCursor{Height: position.Height, Index: position.Index}
+			}
+			return &db.StopIteration{}
+		}
+		txids = append(txids, txid)
+		return nil
+	}
 	var callback db.GetTransactionsCallback
 	if filter.Vout == AddressFilterVoutOff {
 		callback = func(txid string, height uint32, indexes []int32) error {
-			txids = append(txids, txid)
-			if len(txids) >= maxResults {
-				return &db.StopIteration{}
+			if skip(height) {
+				return nil
 			}
-			return nil
+			return add(txid)
 		}
 	} else {
 		callback = func(txid string, height uint32, indexes []int32) error {
+			if skip(height) {
+				return nil
+			}
 			for _, index := range indexes {
 				vout := index
 				if vout < 0 {
@@ -759,11 +898,7 @@ func (w *Worker) getAddressTxids(addrDesc bchain.AddressDescriptor, mempool bool
 				if (filter.Vout == AddressFilterVoutInputs && index < 0) ||
 					(filter.Vout == AddressFilterVoutOutputs && index >= 0) ||
 					(vout == int32(filter.Vout)) {
-					txids = append(txids, txid)
-					if len(txids) >= maxResults {
-						return &db.StopIteration{}
-					}
-					break
+					return add(txid)
 				}
 			}
 			return nil
@@ -773,7 +908,7 @@ func (w *Worker) getAddressTxids(addrDesc bchain.AddressDescriptor, mempool bool
 		uniqueTxs := make(map[string]struct{})
 		o, err := w.mempool.GetAddrDescTransactions(addrDesc)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 		for _, m := range o {
 			if _, found := uniqueTxs[m.Txid]; !found {
@@ -789,12 +924,16 @@ func (w *Worker) getAddressTxids(addrDesc bchain.AddressDescriptor, mempool bool
 		if to == 0 {
 			to = maxUint32
 		}
+		// the iteration seeks directly to the block of the cursor
+		if filter.Cursor != nil && filter.Cursor.Height < to {
+			to = filter.Cursor.Height
+		}
 		err = w.db.GetAddrDescTransactions(addrDesc, filter.FromHeight, to, callback)
 		if err != nil {
-			return nil, err
+			return nil, nil, err
 		}
 	}
-	return txids, nil
+	return txids, next, nil
 }
 
 func (t *Tx) getAddrVoutValue(addrDesc bchain.AddressDescriptor) *big.Int {
@@ -1292,8 +1431,10 @@ func (w *Worker) GetAddress(address string, page int, txsOnPage int, option Acco
 		totalReceived, totalSent *big.Int
 		unconfirmedTxs           int
 		totalResults             int
+		historyTruncated         bool
 	)
 	ed := &ethereumTypeAddressData{}
+	noFilter := filter.Vout == AddressFilterVoutOff && filter.FromHeight == 0 && filter.ToHeight == 0
 	addrDesc, address, err := w.getAddrDescAndNormalizeAddress(address)
 	if err != nil {
 		return nil, err
@@ -1311,8 +1452,12 @@ func (w *Worker) GetAddress(address string, page int, txsOnPage int, option Acco
 			return nil, NewAPIError(fmt.Sprintf("Address not found, %v", err), true)
 		}
 		if ba != nil {
-			// totalResults is known only if there is no filter
-			if filter.Vout == AddressFilterVoutOff && filter.FromHeight == 0 && filter.ToHeight == 0 {
+			// totalResults is known only if there is no filter and the history is not pruned
+			if w.is.GetPrunedHeight() > 0 {
+				// the number of the transactions remaining in the index is not known without reading the whole history
+				historyTruncated = ba.Txs > 0
+				totalResults = -1
+			} else if noFilter {
 				totalResults = int(ba.Txs)
 			} else {
 				totalResults = -1
@@ -1327,7 +1472,7 @@ func (w *Worker) GetAddress(address string, page int, txsOnPage int, option Acco
 	addresses := w.newAddressesMapForAliases()
 	// process mempool, only if toHeight is not specified
 	if filter.ToHeight == 0 && !filter.OnlyConfirmed {
-		txm, err = w.getAddressTxids(addrDesc, true, filter, maxInt)
+		txm, _, err = w.getAddressTxids(addrDesc, true, filter, maxInt)
 		if err != nil {
 			return nil, errors.Annotatef(err, "getAddressTxids %v true", addrDesc)
 		}
@@ -1347,7 +1492,7 @@ func (w *Worker) GetAddress(address string, page int, txsOnPage int, option Acco
 					} else {
 						uBalSat.Sub(&uBalSat, tx.getAddrVinValue(addrDesc))
 					}
-					if page == 0 {
+					if page == 0 && filter.Cursor == nil {
 						if option == AccountDetailsTxidHistory {
 							txids = append(txids, tx.Txid)
 						} else if option >= AccountDetailsTxHistoryLight {
@@ -1361,23 +1506,37 @@ func (w *Worker) GetAddress(address string, page int, txsOnPage int, option Acco
 	}
 	// get tx history if requested by option or check mempool if there are some transactions for a new address
 	if option >= AccountDetailsTxidHistory && filter.Vout != AddressFilterVoutQueryNotNecessary {
-		txc, err := w.getAddressTxids(addrDesc, false, filter, (page+1)*txsOnPage)
+		maxResults := (page + 1) * txsOnPage
+		if filter.Cursor != nil {
+			maxResults = txsOnPage
+		}
+		txc, next, err := w.getAddressTxids(addrDesc, false, filter, maxResults)
 		if err != nil {
 			return nil, errors.Annotatef(err, "getAddressTxids %v false", addrDesc)
 		}
+		if historyTruncated && noFilter && filter.Cursor == nil && len(txc) < maxResults {
+			// the whole remaining history was read, it is truncated only if some transactions are missing
+			historyTruncated = len(txc) < int(ba.Txs)
+		}
 		bestheight, _, err := w.db.GetBestBlock()
 		if err != nil {
 			return nil, errors.Annotatef(err, "GetBestBlock")
 		}
 		var from, to int
-		pg, from, to, page = computePaging(len(txc), page, txsOnPage)
-		if len(txc) >= txsOnPage {
-			if totalResults < 0 {
-				pg.TotalPages = -1
-			} else {
-				pg, _, _, _ = computePaging(totalResults, page, txsOnPage)
+		if filter.Cursor != nil {
+			// page numbers are not known when listing from a cursor
+			pg, from, to = Paging{ItemsOnPage: txsOnPage}, 0, len(txc)
+		} else {
+			pg, from, to, page = computePaging(len(txc), page, txsOnPage)
+			if len(txc) >= txsOnPage {
+				if totalResults < 0 {
+					pg.TotalPages = -1
+				} else {
+					pg, _, _, _ = computePaging(totalResults, page, txsOnPage)
+				}
 			}
 		}
+		pg.NextCursor = next.String()
 		for i := from; i < to; i++ {
 			txid := txc[i]
 			if option == AccountDetailsTxidHistory {
@@ -1419,6 +1578,7 @@ func (w *Worker) GetAddress(address string, page int, txsOnPage int, option Acco
 		TotalReceivedSat:      (*Amount)(totalReceived),
 		TotalSentSat:          (*Amount)(totalSent),
 		Txs:                   int(ba.Txs),
+		HistoryTruncated:      historyTruncated,
 		NonTokenTxs:           ed.nonContractTxs,
 		InternalTxs:           ed.internalTxs,
 		UnconfirmedBalanceSat: (*Amount)(&uBalSat),
@@ -1444,6 +1604,14 @@ func (w *Worker) GetAddress(address string, page int, txsOnPage int, option Acco
 	return r, nil
 }
 
+// checkPrunedHeight rejects the requests of the history below the pruned height of the index
+func (w *Worker) checkPrunedHeight(height uint32) error {
+	if prunedHeight := w.is.GetPrunedHeight(); height < prunedHeight {
+		return NewAPIError(fmt.Sprintf("The history below height %d is pruned", prunedHeight), true)
+	}
+	return nil
+}
+
 func (w *Worker) balanceHistoryHeightsFromTo(fromTimestamp, toTimestamp int64) (uint32, uint32, uint32, uint32) {
 	fromUnix := uint32(0)
 	toUnix := maxUint32
@@ -1601,7 +1769,7 @@ func (w *Worker) balanceHistoryForTxid(addrDesc bchain.AddressDescriptor, txid s
 					var feesSat big.Int
 					// mempool txs do not have fees yet
 					if ethTxData.GasUsed != nil {
-						feesSat.Mul(ethTxData.GasPrice, ethTxData.GasUsed)
+						feesSat.Mul(ethTxData.PaidGasPrice(), ethTxData.GasUsed)
 					}
 					(*big.Int)(bh.SentSat).Add((*big.Int)(bh.SentSat), &feesSat)
 				}
@@ -1665,7 +1833,10 @@ func (w *Worker) GetBalanceHistory(address string, fromTimestamp, toTimestamp in
 	if fromHeight >= toHeight {
 		return bhs, nil
 	}
-	txs, err := w.getAddressTxids(addrDesc, false, &AddressFilter{Vout: AddressFilterVoutOff, FromHeight: fromHeight, ToHeight: toHeight}, maxInt)
+	if err := w.checkPrunedHeight(fromHeight); err != nil {
+		return nil, err
+	}
+	txs, _, err := w.getAddressTxids(addrDesc, false, &AddressFilter{Vout: AddressFilterVoutOff, FromHeight: fromHeight, ToHeight: toHeight}, maxInt)
 	if err != nil {
 		return nil, err
 	}
@@ -1709,7 +1880,7 @@ func (w *Worker) getAddrDescUtxo(addrDesc bchain.AddressDescriptor, ba *db.AddrB
 	spentInMempool := make(map[string]struct{})
 	if !onlyConfirmed {
 		// get utxo from mempool
-		txm, err := w.getAddressTxids(addrDesc, true, &AddressFilter{Vout: AddressFilterVoutOff}, maxInt)
+		txm, _, err := w.getAddressTxids(addrDesc, true, &AddressFilter{Vout: AddressFilterVoutOff}, maxInt)
 		if err != nil {
 			return nil, err
 		}
@@ -2426,6 +2597,7 @@ func (w *Worker) GetSystemInfo(internal bool) (*SystemInfo, error) {
 		HistoricalTokenFiatRatesTime: nonZeroTime(w.is.HistoricalTokenFiatRatesTime),
 		SupportedStakingPools:        w.chain.EthereumTypeGetSupportedStakingPools(),
 		DbSize:                       w.db.DatabaseSizeOnDisk(),
+		PrunedHeight:                 w.is.GetPrunedHeight(),
 		DbSizeFromColumns:            internalDBSize,
 		DbColumns:                    columnStats,
 		About:                        Text.BlockbookAbout,
@@ -2474,6 +2646,217 @@ func (w *Worker) GetMempool(page int, itemsOnPage int) (*MempoolTxids, error) {
 	return r, nil
 }
 
+// setTxReplacements sets the replacements of the unconfirmed transaction recorded by the mempool
+func (w *Worker) setTxReplacements(tx *Tx) {
+	if w.chainType != bchain.ChainBitcoinType || tx.Confirmations != 0 {
+		return
+	}
+	c, err := w.mempool.GetTxConflicts(tx.Txid)
+	if err != nil {
+		glog.Error("GetTxConflicts error ", err, " for ", tx.Txid)
+		return
+	}
+	tx.ReplacedBy = c.ReplacedBy
+	tx.Replaces = c.Replaces
+}
+
+// GetTokenApprovals returns the current non zero token approvals given by the address with the block height in which they were set,
+// the allowances of the ERC20 tokens are read from the contracts as the transfers by the spender decrease them without an Approval event
+func (w *Worker) GetTokenApprovals(address string) (*TokenApprovals, error) {
+	if w.chainType != bchain.ChainEthereumType {
+		return nil, NewAPIError("Not supported", true)
+	}
+	addrDesc, address, err := w.getAddrDescAndNormalizeAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	approvals, err := w.db.GetAddrDescApprovals(addrDesc)
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetAddrDescApprovals %v", addrDesc)
+	}
+	r := &TokenApprovals{
+		Address:   address,
+		Approvals: make([]TokenApproval, 0, len(approvals)),
+	}
+	contractCache := make(contractInfoCache)
+	for i := range approvals {
+		a := &approvals[i]
+		var allowance *big.Int
+		if !a.ForAll {
+			allowance, err = w.chain.EthereumTypeGetErc20ContractAllowance(addrDesc, a.Spender, a.Contract)
+			if err != nil {
+				return nil, errors.Annotatef(err, "EthereumTypeGetErc20ContractAllowance %v %v", a.Contract, a.Spender)
+			}
+			if allowance.Sign() == 0 {
+				continue
+			}
+		}
+		ci, found := contractCache[string(a.Contract)]
+		if !found {
+			typeFromContext := bchain.ERC20TokenType
+			if a.ForAll {
+				typeFromContext = bchain.UnknownTokenType
+			}
+			ci, _, err = w.getContractDescriptorInfo(a.Contract, typeFromContext)
+			if err != nil {
+				return nil, errors.Annotatef(err, "getContractDescriptorInfo %v", a.Contract)
+			}
+			contractCache[string(a.Contract)] = ci
+		}
+		r.Approvals = append(r.Approvals, TokenApproval{})
+		ra := &r.Approvals[len(r.Approvals)-1]
+		ra.Type = ci.Type
+		ra.Contract = ci.Contract
+		ra.Name = ci.Name
+		ra.Symbol = ci.Symbol
+		ra.Decimals = ci.Decimals
+		ra.ForAll = a.ForAll
+		ra.Height = a.Height
+		if !a.ForAll {
+			ra.ValueSat = (*Amount)(allowance)
+		}
+		if spenders, _, err := w.chainParser.GetAddressesFromAddrDesc(a.Spender); err == nil && len(spenders) == 1 {
+			ra.Spender = spenders[0]
+		}
+	}
+	return r, nil
+}
+
+// DecodeReturnData decodes the data returned by the call of the contract with the input data by the verified ABI of the contract
+func (w *Worker) DecodeReturnData(contract, input, output string) (*ReturnData, error) {
+	if w.chainType != bchain.ChainEthereumType {
+		return nil, NewAPIError("Not supported", true)
+	}
+	contractDesc, err := w.chainParser.GetAddrDescFromAddress(contract)
+	if err != nil {
+		return nil, NewAPIError(fmt.Sprintf("Invalid contract, %v", err), true)
+	}
+	a := w.getContractABI(contractDesc)
+	if a == nil {
+		return nil, NewAPIError("The verified ABI of the contract is not known", true)
+	}
+	parsed := a.ParseInputData(input)
+	if parsed == nil {
+		return nil, NewAPIError("The input data cannot be decoded by the ABI of the contract", true)
+	}
+	outputs := a.ParseReturnData(input, output)
+	if outputs == nil {
+		return nil, NewAPIError("The return data cannot be decoded by the ABI of the contract", true)
+	}
+	r := &ReturnData{
+		Contract:   contract,
+		ParsedData: parsed,
+		Outputs:    outputs,
+	}
+	if contracts, _, err := w.chainParser.GetAddressesFromAddrDesc(contractDesc); err == nil && len(contracts) == 1 {
+		r.Contract = contracts[0]
+	}
+	return r, nil
+}
+
+// GetContractLogs returns the logs of the contract with the topic0 in the blocks from-to stored in the contract log index,
+// the logs are ordered by the block height and the log index
+func (w *Worker) GetContractLogs(contract, topic0 string, from, to, page, logsOnPage int) (*ContractLogs, error) {
+	if w.chainType != bchain.ChainEthereumType || !w.chainParser.UseContractLogs() {
+		return nil, NewAPIError("Not supported", true)
+	}
+	contractDesc, err := w.chainParser.GetAddrDescFromAddress(contract)
+	if err != nil {
+		return nil, NewAPIError(fmt.Sprintf("Invalid contract, %v", err), true)
+	}
+	topic, err := hex.DecodeString(strings.TrimPrefix(strings.ToLower(topic0), "0x"))
+	if err != nil || len(topic) != 32 {
+		return nil, NewAPIError("Invalid topic0", true)
+	}
+	page--
+	if page < 0 {
+		page = 0
+	}
+	if from < 0 {
+		from = 0
+	}
+	lower, higher := uint32(from), maxUint32
+	if to > 0 {
+		higher = uint32(to)
+	}
+	r := &ContractLogs{
+		Contract: contract,
+		Topic0:   "0x" + hex.EncodeToString(topic),
+		Logs:     []ContractLog{},
+	}
+	if contracts, _, err := w.chainParser.GetAddressesFromAddrDesc(contractDesc); err == nil && len(contracts) == 1 {
+		r.Contract = contracts[0]
+	}
+	skip := page * logsOnPage
+	count := 0
+	err = w.db.GetContractLogs(contractDesc, topic, lower, higher, func(l *db.ContractLog) error {
+		if count >= skip+logsOnPage {
+			// there are more logs than fit to the requested page, the total number is not counted
+			count++
+			return &db.StopIteration{}
+		}
+		if count >= skip {
+			cl := ContractLog{
+				Txid:        l.Txid,
+				BlockHeight: l.Height,
+				LogIndex:    l.LogIndex,
+				Topics:      make([]string, len(l.Topics)),
+				Data:        "0x" + hex.EncodeToString(l.Data),
+			}
+			for i := range l.Topics {
+				cl.Topics[i] = "0x" + hex.EncodeToString(l.Topics[i])
+			}
+			r.Logs = append(r.Logs, cl)
+		}
+		count++
+		return nil
+	})
+	if err != nil {
+		return nil, errors.Annotatef(err, "GetContractLogs %v %v", contract, topic0)
+	}
+	r.Paging = Paging{Page: page + 1, ItemsOnPage: logsOnPage}
+	if count > skip+logsOnPage {
+		r.Paging.TotalPages = -1
+	} else if count > 0 {
+		r.Paging.TotalPages = (count-1)/logsOnPage + 1
+	} else {
+		r.Paging.TotalPages = 1
+	}
+	return r, nil
+}
+
+// GetMempoolConflicts returns the replacements of the transaction and the mempool transactions spending the same outpoints
+func (w *Worker) GetMempoolConflicts(txid string) (*MempoolConflicts, error) {
+	if w.chainType != bchain.ChainBitcoinType {
+		return nil, NewAPIError("Not supported", true)
+	}
+	c, err := w.mempool.GetTxConflicts(txid)
+	if err != nil {
+		return nil, err
+	}
+	r := &MempoolConflicts{
+		Txid:       txid,
+		ReplacedBy: c.ReplacedBy,
+		Replaces:   c.Replaces,
+		Conflicts:  c.Conflicts,
+	}
+	// follow the chain of replacements to the latest known transaction
+	seen := map[string]struct{}{txid: {}}
+	for next := c.ReplacedBy; next != ""; {
+		if _, found := seen[next]; found {
+			break
+		}
+		seen[next] = struct{}{}
+		r.ReplacementChain = append(r.ReplacementChain, next)
+		nc, err := w.mempool.GetTxConflicts(next)
+		if err != nil {
+			return nil, err
+		}
+		next = nc.ReplacedBy
+	}
+	return r, nil
+}
+
 type bitcoinTypeEstimatedFee struct {
 	timestamp int64
 	fee       big.Int
@@ -2522,3 +2905,41 @@ func (w *Worker) EstimateFee(blocks int, conservative bool) (big.Int, error) {
 	}
 	return w.cachedEstimateFee(blocks, conservative)
 }
+
+type ethereumTypeEstimatedEip1559Fees struct {
+	timestamp int64
+	fees      *Eip1559Fees
+	lock      sync.Mutex
+}
+
+var estimatedEip1559FeesCache ethereumTypeEstimatedEip1559Fees
+
+// EstimateEip1559Fees returns the slow, normal and fast EIP-1559 fee estimation
+// it uses 10 second cache to reduce calls to the backend
+func (w *Worker) EstimateEip1559Fees() (*Eip1559Fees, error) {
+	s := &estimatedEip1559FeesCache
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	threshold := time.Now().Unix() - 10
+	if s.timestamp >= threshold {
+		return s.fees, nil
+	}
+	f, err := w.chain.EthereumTypeGetEip1559Fees()
+	if err != nil {
+		return nil, err
+	}
+	eip1559Fee := func(f *bchain.Eip1559Fee) *Eip1559Fee {
+		return &Eip1559Fee{
+			MaxFeePerGas:         (*Amount)(f.MaxFeePerGas),
+			MaxPriorityFeePerGas: (*Amount)(f.MaxPriorityFeePerGas),
+		}
+	}
+	s.fees = &Eip1559Fees{
+		BaseFeePerGas: (*Amount)(f.BaseFeePerGas),
+		Slow:          eip1559Fee(f.Slow),
+		Normal:        eip1559Fee(f.Normal),
+		Fast:          eip1559Fee(f.Fast),
+	}
+	s.timestamp = time.Now().Unix()
+	return s.fees, nil
+}
@@ -47,6 +47,86 @@ func (a xpubTxids) Less(i, j int) bool {
 	return hi > hj
 }
 
+// cursorIndex returns the index of the transaction to which the cursor points, the txids must be sorted
+func (a xpubTxids) cursorIndex(c *TxCursor) int {
+	i := sort.Search(len(a), func(i int) bool { return a[i].height <= c.Height })
+	for j := uint32(0); j < c.Index && i < len(a) && a[i].height == c.Height; j++ {
+		i++
+	}
+	return i
+}
+
+// cursor returns the cursor pointing to the i-th transaction, the index is counted within the block
+func (a xpubTxids) cursor(i int) *TxCursor {
+	if i >= len(a) {
+		return nil
+	}
+	c := TxCursor{Height: a[i].height}
+	for j := i; j > 0 && a[j-1].height == c.Height; j-- {
+		c.Index++
+	}
+	return &c
+}
+
+// xpubTxidsFromCursor returns up to txsOnPage confirmed transactions of the xpub starting at the cursor and the cursor of the next transaction;
+// the history of each used address is read from the block of the cursor downwards, only as many transactions as are needed for the page,
+// the order of the transactions is the same as of the complete history, so that the cursor index within the block is consistent
+func (w *Worker) xpubTxidsFromCursor(data *xpubData, filter *AddressFilter, txidFilter func(txid *xpubTxid, ad *xpubAddress) bool, txsOnPage int) (xpubTxids, *TxCursor, error) {
+	toHeight := filter.Cursor.Height
+	if filter.ToHeight != 0 && filter.ToHeight < toHeight {
+		toHeight = filter.ToHeight
+	}
+	// the transactions skipped in the block of the cursor, the page and one more transaction for the next cursor
+	needed := int(filter.Cursor.Index) + txsOnPage + 1
+	for maxResults := needed; ; maxResults *= 2 {
+		// the history read from all addresses is complete above the safeHeight, the block at the safeHeight
+		// may be shared with the transactions of other addresses, which were not read yet
+		complete := true
+		var safeHeight uint32
+		txcMap := make(map[string]bool)
+		txc := make(xpubTxids, 0, needed)
+		for _, da := range data.addresses {
+			for i := range da {
+				ad := &da[i]
+				if ad.balance == nil {
+					continue
+				}
+				adTxids, adComplete, err := w.xpubGetAddressTxids(ad.addrDesc, false, filter.FromHeight, toHeight, maxResults)
+				if err != nil {
+					return nil, nil, err
+				}
+				if !adComplete {
+					complete = false
+					if h := adTxids[len(adTxids)-1].height; h > safeHeight {
+						safeHeight = h
+					}
+				}
+				for j := range adTxids {
+					txid := &adTxids[j]
+					if _, found := txcMap[txid.txid]; !found {
+						add := txidFilter == nil || txidFilter(txid, ad)
+						txcMap[txid.txid] = add
+						if add {
+							txc = append(txc, *txid)
+						}
+					}
+				}
+			}
+		}
+		sort.Stable(txc)
+		// with a filter, the complete part of the read history may not contain enough transactions
+		if !complete && (len(txc) < needed || txc[needed-1].height <= safeHeight) {
+			continue
+		}
+		from := txc.cursorIndex(filter.Cursor)
+		to := from + txsOnPage
+		if to > len(txc) {
+			to = len(txc)
+		}
+		return txc[from:to], txc.cursor(to), nil
+	}
+}
+
 type xpubAddress struct {
 	addrDesc  bchain.AddressDescriptor
 	balance   *db.AddrBalance
@@ -370,7 +450,8 @@ func (w *Worker) getXpubData(xd *bchain.XpubDescriptor, page int, txsOnPage int,
 				}
 			}
 		}
-		if option >= AccountDetailsTxidHistory {
+		// listing from a cursor reads only the needed part of the history, see xpubTxidsFromCursor
+		if option >= AccountDetailsTxidHistory && filter.Cursor == nil {
 			for _, da := range data.addresses {
 				for i := range da {
 					if err = w.xpubCheckAndLoadTxids(&da[i], filter, bestheight, (page+1)*txsOnPage); err != nil {
@@ -470,7 +551,7 @@ func (w *Worker) GetXpubAddress(xpub string, page int, txsOnPage int, option Acc
 						uBalSat.Add(&uBalSat, tx.getAddrVoutValue(ad.addrDesc))
 						uBalSat.Sub(&uBalSat, tx.getAddrVinValue(ad.addrDesc))
 						// mempool txs are returned only on the first page, uniquely and filtered
-						if page == 0 && !foundTx && (txidFilter == nil || txidFilter(&txid, ad)) {
+						if page == 0 && filter.Cursor == nil && !foundTx && (txidFilter == nil || txidFilter(&txid, ad)) {
 							mempoolEntries = append(mempoolEntries, bchain.MempoolTxidEntry{Txid: txid.txid, Time: uint32(tx.Blocktime)})
 						}
 					}
@@ -487,7 +568,27 @@ func (w *Worker) GetXpubAddress(xpub string, page int, txsOnPage int, option Acc
 			}
 		}
 	}
-	if option >= AccountDetailsTxidHistory {
+	if option >= AccountDetailsTxidHistory && filter.Cursor != nil {
+		// page numbers and the exact number of transactions are not known when listing from a cursor
+		txCount = int(data.txCountEstimate)
+		var next *TxCursor
+		txc, next, err = w.xpubTxidsFromCursor(data, filter, txidFilter, txsOnPage)
+		if err != nil {
+			return nil, err
+		}
+		pg = Paging{ItemsOnPage: txsOnPage, NextCursor: next.String()}
+		for i := range txc {
+			if option == AccountDetailsTxidHistory {
+				txids = append(txids, txc[i].txid)
+			} else {
+				tx, err := w.txFromTxid(txc[i].txid, bestheight, option, nil, addresses)
+				if err != nil {
+					return nil, err
+				}
+				txs = append(txs, tx)
+			}
+		}
+	} else if option >= AccountDetailsTxidHistory {
 		txcMap := make(map[string]bool)
 		txc = make(xpubTxids, 0, 32)
 		for _, da := range data.addresses {
@@ -525,6 +626,7 @@ func (w *Worker) GetXpubAddress(xpub string, page int, txsOnPage int, option Acc
 				pg, _, _, _ = computePaging(totalResults, page, txsOnPage)
 			}
 		}
+		pg.NextCursor = txc.cursor(to).String()
 		// get confirmed transactions
 		for i := from; i < to; i++ {
 			xpubTxid := &txc[i]
@@ -658,6 +760,9 @@ func (w *Worker) GetXpubBalanceHistory(xpub string, fromTimestamp, toTimestamp i
 	if fromHeight >= toHeight {
 		return bhs, nil
 	}
+	if err := w.checkPrunedHeight(fromHeight); err != nil {
+		return nil, err
+	}
 	xd, err := w.chainParser.ParseXpub(xpub)
 	if err != nil {
 		return nil, err
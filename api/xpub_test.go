@@ -0,0 +1,96 @@
+//go:build unittest
+
+package api
+
+import (
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/trezor/blockbook/bchain"
+	"github.com/trezor/blockbook/bchain/coins/btc"
+	"github.com/trezor/blockbook/db"
+	"github.com/trezor/blockbook/tests/dbtestdata"
+)
+
+func Test_xpubTxidsFromCursor(t *testing.T) {
+	parser := btc.NewBitcoinParser(btc.GetChainParams("test"), &btc.Configuration{BlockAddressesToKeep: 100})
+	m := db.NewMemoryStore(parser, false)
+	w := &Worker{db: m}
+	// the addresses of the xpub share the blocks 2 and 4
+	blocks := [][][]string{
+		{{dbtestdata.Addr1}, {dbtestdata.Addr2}},
+		{{dbtestdata.Addr1, dbtestdata.Addr2}, {dbtestdata.Addr1}, {dbtestdata.Addr2}, {dbtestdata.Addr1}},
+		{{dbtestdata.Addr1}},
+		{{dbtestdata.Addr2}, {dbtestdata.Addr1, dbtestdata.Addr2}},
+	}
+	for i, txs := range blocks {
+		height := uint32(i + 1)
+		block := &bchain.Block{BlockHeader: bchain.BlockHeader{Height: height, Hash: fmt.Sprintf("%064x", height)}}
+		for j, addresses := range txs {
+			tx := bchain.Tx{
+				Txid: fmt.Sprintf("%060x%04x", height, j),
+				Vin:  []bchain.Vin{{Coinbase: "00"}},
+			}
+			for k, a := range addresses {
+				tx.Vout = append(tx.Vout, bchain.Vout{
+					N:            uint32(k),
+					ScriptPubKey: bchain.ScriptPubKey{Hex: dbtestdata.AddressToPubKeyHex(a, parser)},
+					ValueSat:     *big.NewInt(1000),
+				})
+			}
+			block.Txs = append(block.Txs, tx)
+		}
+		if err := m.ConnectBlock(block); err != nil {
+			t.Fatal(err)
+		}
+	}
+	data := &xpubData{addresses: [][]xpubAddress{make([]xpubAddress, 2)}}
+	for i, a := range []string{dbtestdata.Addr1, dbtestdata.Addr2} {
+		addrDesc, err := parser.GetAddrDescFromAddress(a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data.addresses[0][i] = xpubAddress{addrDesc: addrDesc, balance: &db.AddrBalance{}}
+	}
+	all, next, err := w.xpubTxidsFromCursor(data, &AddressFilter{Cursor: &TxCursor{Height: maxUint32}}, nil, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 9 || next != nil {
+		t.Fatalf("xpubTxidsFromCursor() returned %d txs, next %+v", len(all), next)
+	}
+	for _, txsOnPage := range []int{1, 2, 3, 4} {
+		t.Run(fmt.Sprint("page ", txsOnPage), func(t *testing.T) {
+			got := make(xpubTxids, 0, len(all))
+			insideBlock := false
+			c := &TxCursor{Height: maxUint32}
+			for c != nil {
+				txc, next, err := w.xpubTxidsFromCursor(data, &AddressFilter{Cursor: c}, nil, txsOnPage)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if len(txc) == 0 || len(txc) > txsOnPage {
+					t.Fatalf("xpubTxidsFromCursor(%+v) returned %d txs", c, len(txc))
+				}
+				got = append(got, txc...)
+				// the page boundary inside the block 2 shared by both addresses
+				if next != nil && next.Height == 2 && next.Index > 0 {
+					insideBlock = true
+				}
+				c = next
+			}
+			if len(got) != len(all) {
+				t.Fatalf("paged %d txs, want %d", len(got), len(all))
+			}
+			for i := range all {
+				if got[i] != all[i] {
+					t.Errorf("tx %d = %+v, want %+v", i, got[i], all[i])
+				}
+			}
+			if !insideBlock {
+				t.Error("no page boundary inside the shared block")
+			}
+		})
+	}
+}
@@ -54,6 +54,11 @@ func (b *BaseChain) EthereumTypeEstimateGas(params map[string]interface{}) (uint
 	return 0, errors.New("not supported")
 }
 
+// EthereumTypeGetEip1559Fees is not supported
+func (b *BaseChain) EthereumTypeGetEip1559Fees() (*Eip1559Fees, error) {
+	return nil, errors.New("not supported")
+}
+
 // GetContractInfo is not supported
 func (b *BaseChain) GetContractInfo(contractDesc AddressDescriptor) (*ContractInfo, error) {
 	return nil, errors.New("not supported")
@@ -64,6 +69,11 @@ func (b *BaseChain) EthereumTypeGetErc20ContractBalance(addrDesc, contractDesc A
 	return nil, errors.New("not supported")
 }
 
+// EthereumTypeGetErc20ContractAllowance is not supported
+func (b *BaseChain) EthereumTypeGetErc20ContractAllowance(ownerDesc, spenderDesc, contractDesc AddressDescriptor) (*big.Int, error) {
+	return nil, errors.New("not supported")
+}
+
 // GetContractInfo returns URI of non fungible or multi token defined by token id
 func (p *BaseChain) GetTokenURI(contractDesc AddressDescriptor, tokenID *big.Int) (string, error) {
 	return "", errors.New("not supported")
@@ -76,3 +86,33 @@ func (b *BaseChain) EthereumTypeGetSupportedStakingPools() []string {
 func (b *BaseChain) EthereumTypeGetStakingPoolsData(addrDesc AddressDescriptor) ([]StakingPoolData, error) {
 	return nil, errors.New("not supported")
 }
+
+// EthereumTypeGetReceiptProof is not supported
+func (b *BaseChain) EthereumTypeGetReceiptProof(txid string) (*EthereumReceiptProof, error) {
+	return nil, errors.New("not supported")
+}
+
+// EthereumTypeGetBlockBodies is not supported
+func (b *BaseChain) EthereumTypeGetBlockBodies(lower, higher uint32) ([]*EthereumStagedBlock, error) {
+	return nil, errors.New("not supported")
+}
+
+// EthereumTypeGetBlockLogs is not supported
+func (b *BaseChain) EthereumTypeGetBlockLogs(blocks []*EthereumStagedBlock) error {
+	return errors.New("not supported")
+}
+
+// EthereumTypeGetBlockInternalData is not supported
+func (b *BaseChain) EthereumTypeGetBlockInternalData(blocks []*EthereumStagedBlock) error {
+	return errors.New("not supported")
+}
+
+// EthereumTypeAssembleBlock is not supported
+func (b *BaseChain) EthereumTypeAssembleBlock(block *EthereumStagedBlock) (*Block, error) {
+	return nil, errors.New("not supported")
+}
+
+// EthereumTypeGetAddressAliasRecords is not supported
+func (b *BaseChain) EthereumTypeGetAddressAliasRecords(lower, higher uint32) ([]AddressAliasRecord, error) {
+	return nil, errors.New("not supported")
+}
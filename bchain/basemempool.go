@@ -21,6 +21,8 @@ type txidio struct {
 	txid   string
 	io     []addrIndex
 	filter string
+	inputs []Outpoint
+	fee    *mempoolTxFee
 }
 
 // BaseMempool is mempool base handle
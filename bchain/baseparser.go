@@ -109,6 +109,11 @@ func (p *BaseParser) UseAddressAliases() bool {
 	return p.AddressAliases
 }
 
+// UseContractLogs returns true if the contract log index is enabled
+func (p *BaseParser) UseContractLogs() bool {
+	return false
+}
+
 // ParseTxFromJson parses JSON message containing transaction and returns Tx struct
 func (p *BaseParser) ParseTxFromJson(msg json.RawMessage) (*Tx, error) {
 	var tx Tx
@@ -318,6 +323,16 @@ func (p *BaseParser) EthereumTypeGetTokenTransfersFromTx(tx *Tx) (TokenTransfers
 	return nil, errors.New("Not supported")
 }
 
+// EthereumTypeGetTokenApprovalsFromTx is unsupported
+func (p *BaseParser) EthereumTypeGetTokenApprovalsFromTx(tx *Tx) (TokenApprovals, error) {
+	return nil, errors.New("Not supported")
+}
+
+// EthereumTypeGetContractLogsFromBlock is unsupported
+func (p *BaseParser) EthereumTypeGetContractLogsFromBlock(block *Block) (ContractLogs, error) {
+	return nil, errors.New("Not supported")
+}
+
 // FormatAddressAlias makes possible to do coin specific formatting to an address alias
 func (p *BaseParser) FormatAddressAlias(address string, name string) string {
 	return name
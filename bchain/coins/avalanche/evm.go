@@ -70,6 +70,23 @@ func (c *AvalancheRPCClient) CallContext(ctx context.Context, result interface{}
 	return nil
 }
 
+// BatchCallContext sends the calls in one batched JSON-RPC request, unfinalized data errors are ignored as in CallContext
+func (c *AvalancheRPCClient) BatchCallContext(ctx context.Context, b []bchain.EVMBatchElem) error {
+	elems := make([]rpc.BatchElem, len(b))
+	for i := range b {
+		elems[i] = rpc.BatchElem{Method: b[i].Method, Args: b[i].Args, Result: b[i].Result}
+	}
+	if err := c.Client.BatchCallContext(ctx, elems); err != nil {
+		return err
+	}
+	for i := range elems {
+		if elems[i].Error != nil && !strings.Contains(elems[i].Error.Error(), "cannot query unfinalized data") {
+			b[i].Error = elems[i].Error
+		}
+	}
+	return nil
+}
+
 // AvalancheHeader wraps a block header to implement the EVMHeader interface
 type AvalancheHeader struct {
 	*types.Header
@@ -316,6 +316,11 @@ func (c *blockChainWithMetrics) EthereumTypeEstimateGas(params map[string]interf
 	return c.b.EthereumTypeEstimateGas(params)
 }
 
+func (c *blockChainWithMetrics) EthereumTypeGetEip1559Fees() (v *bchain.Eip1559Fees, err error) {
+	defer func(s time.Time) { c.observeRPCLatency("EthereumTypeGetEip1559Fees", s, err) }(time.Now())
+	return c.b.EthereumTypeGetEip1559Fees()
+}
+
 func (c *blockChainWithMetrics) GetContractInfo(contractDesc bchain.AddressDescriptor) (v *bchain.ContractInfo, err error) {
 	defer func(s time.Time) { c.observeRPCLatency("GetContractInfo", s, err) }(time.Now())
 	return c.b.GetContractInfo(contractDesc)
@@ -326,6 +331,11 @@ func (c *blockChainWithMetrics) EthereumTypeGetErc20ContractBalance(addrDesc, co
 	return c.b.EthereumTypeGetErc20ContractBalance(addrDesc, contractDesc)
 }
 
+func (c *blockChainWithMetrics) EthereumTypeGetErc20ContractAllowance(ownerDesc, spenderDesc, contractDesc bchain.AddressDescriptor) (v *big.Int, err error) {
+	defer func(s time.Time) { c.observeRPCLatency("EthereumTypeGetErc20ContractAllowance", s, err) }(time.Now())
+	return c.b.EthereumTypeGetErc20ContractAllowance(ownerDesc, spenderDesc, contractDesc)
+}
+
 // GetContractInfo returns URI of non fungible or multi token defined by token id
 func (c *blockChainWithMetrics) GetTokenURI(contractDesc bchain.AddressDescriptor, tokenID *big.Int) (v string, err error) {
 	defer func(s time.Time) { c.observeRPCLatency("GetTokenURI", s, err) }(time.Now())
@@ -341,6 +351,35 @@ func (c *blockChainWithMetrics) EthereumTypeGetStakingPoolsData(addrDesc bchain.
 	return c.b.EthereumTypeGetStakingPoolsData(addrDesc)
 }
 
+func (c *blockChainWithMetrics) EthereumTypeGetReceiptProof(txid string) (v *bchain.EthereumReceiptProof, err error) {
+	defer func(s time.Time) { c.observeRPCLatency("EthereumTypeGetReceiptProof", s, err) }(time.Now())
+	return c.b.EthereumTypeGetReceiptProof(txid)
+}
+
+func (c *blockChainWithMetrics) EthereumTypeGetBlockBodies(lower, higher uint32) (v []*bchain.EthereumStagedBlock, err error) {
+	defer func(s time.Time) { c.observeRPCLatency("EthereumTypeGetBlockBodies", s, err) }(time.Now())
+	return c.b.EthereumTypeGetBlockBodies(lower, higher)
+}
+
+func (c *blockChainWithMetrics) EthereumTypeGetBlockLogs(blocks []*bchain.EthereumStagedBlock) (err error) {
+	defer func(s time.Time) { c.observeRPCLatency("EthereumTypeGetBlockLogs", s, err) }(time.Now())
+	return c.b.EthereumTypeGetBlockLogs(blocks)
+}
+
+func (c *blockChainWithMetrics) EthereumTypeGetBlockInternalData(blocks []*bchain.EthereumStagedBlock) (err error) {
+	defer func(s time.Time) { c.observeRPCLatency("EthereumTypeGetBlockInternalData", s, err) }(time.Now())
+	return c.b.EthereumTypeGetBlockInternalData(blocks)
+}
+
+func (c *blockChainWithMetrics) EthereumTypeAssembleBlock(block *bchain.EthereumStagedBlock) (*bchain.Block, error) {
+	return c.b.EthereumTypeAssembleBlock(block)
+}
+
+func (c *blockChainWithMetrics) EthereumTypeGetAddressAliasRecords(lower, higher uint32) (v []bchain.AddressAliasRecord, err error) {
+	defer func(s time.Time) { c.observeRPCLatency("EthereumTypeGetAddressAliasRecords", s, err) }(time.Now())
+	return c.b.EthereumTypeGetAddressAliasRecords(lower, higher)
+}
+
 type mempoolWithMetrics struct {
 	mempool bchain.Mempool
 	m       *common.Metrics
@@ -385,3 +424,15 @@ func (c *mempoolWithMetrics) GetTransactionTime(txid string) uint32 {
 func (c *mempoolWithMetrics) GetTxidFilterEntries(filterScripts string, fromTimestamp uint32) (bchain.MempoolTxidFilterEntries, error) {
 	return c.mempool.GetTxidFilterEntries(filterScripts, fromTimestamp)
 }
+
+func (c *mempoolWithMetrics) GetTxConflicts(txid string) (bchain.MempoolTxConflicts, error) {
+	return c.mempool.GetTxConflicts(txid)
+}
+
+func (c *mempoolWithMetrics) GetOutpointSpender(outpoint bchain.Outpoint) (string, error) {
+	return c.mempool.GetOutpointSpender(outpoint)
+}
+
+func (c *mempoolWithMetrics) GetBlockTemplate() *bchain.MempoolBlockTemplate {
+	return c.mempool.GetBlockTemplate()
+}
@@ -6,7 +6,6 @@ import (
 	"encoding/binary"
 	"encoding/hex"
 	"math/big"
-	"regexp"
 	"strconv"
 	"strings"
 	"unicode/utf8"
@@ -429,101 +428,19 @@ func (p *BitcoinLikeParser) xpubDescriptorFromXpub(xpub string) (*bchain.XpubDes
 	return &descriptor, nil
 }
 
-var (
-	xpubDesriptorRegex     *regexp.Regexp
-	typeSubexpIndex        int
-	bipSubexpIndex         int
-	xpubSubexpIndex        int
-	changeSubexpIndex      int
-	changeList1SubexpIndex int
-	changeList2SubexpIndex int
-)
-
-func init() {
-	xpubDesriptorRegex, _ = regexp.Compile(`^(?P<type>(sh\(wpkh|wpkh|pk|pkh|wpkh|wsh|tr))\((\[\w+/(?P<bip>\d+)'/\d+'?/\d+'?\])?(?P<xpub>\w+)(/(({(?P<changelist1>\d+(,\d+)*)})|(<(?P<changelist2>\d+(;\d+)*)>)|(?P<change>\d+))/\*)?\)+`)
-	typeSubexpIndex = xpubDesriptorRegex.SubexpIndex("type")
-	bipSubexpIndex = xpubDesriptorRegex.SubexpIndex("bip")
-	xpubSubexpIndex = xpubDesriptorRegex.SubexpIndex("xpub")
-	changeList1SubexpIndex = xpubDesriptorRegex.SubexpIndex("changelist1")
-	changeList2SubexpIndex = xpubDesriptorRegex.SubexpIndex("changelist2")
-	changeSubexpIndex = xpubDesriptorRegex.SubexpIndex("change")
-	if changeSubexpIndex < 0 {
-		panic("Invalid bitcoinparser xpubDesriptorRegex")
-	}
-}
-
-// ParseXpub parses xpub (or xpub descriptor) and returns XpubDescriptor
+// ParseXpub parses xpub (or output descriptor) and returns XpubDescriptor
 func (p *BitcoinLikeParser) ParseXpub(xpub string) (*bchain.XpubDescriptor, error) {
-	match := xpubDesriptorRegex.FindStringSubmatch(xpub)
-	if len(match) > changeSubexpIndex {
-		var descriptor bchain.XpubDescriptor
-		descriptor.XpubDescriptor = xpub
-		m := match[typeSubexpIndex]
-		switch m {
-		case "pkh":
-			descriptor.Type = bchain.P2PKH
-			descriptor.Bip = "44"
-		case "sh(wpkh":
-			descriptor.Type = bchain.P2SHWPKH
-			descriptor.Bip = "49"
-		case "wpkh":
-			descriptor.Type = bchain.P2WPKH
-			descriptor.Bip = "84"
-		case "tr":
-			descriptor.Type = bchain.P2TR
-			descriptor.Bip = "86"
-		default:
-			return nil, errors.Errorf("Xpub descriptor %s is not supported", m)
-		}
-		if len(match[bipSubexpIndex]) > 0 {
-			descriptor.Bip = match[bipSubexpIndex]
-		}
-		descriptor.Xpub = match[xpubSubexpIndex]
-		extKey, err := hdkeychain.NewKeyFromString(descriptor.Xpub, p.Params.Base58CksumHasher)
-		if err != nil {
-			return nil, err
-		}
-		descriptor.ExtKey = extKey
-		if len(match[changeSubexpIndex]) > 0 {
-			change, err := strconv.ParseUint(match[changeSubexpIndex], 10, 32)
-			if err != nil {
-				return nil, err
-			}
-			descriptor.ChangeIndexes = []uint32{uint32(change)}
-		} else {
-			if len(match[changeList1SubexpIndex]) > 0 || len(match[changeList2SubexpIndex]) > 0 {
-				var changes []string
-				if len(match[changeList1SubexpIndex]) > 0 {
-					changes = strings.Split(match[changeList1SubexpIndex], ",")
-				} else {
-					changes = strings.Split(match[changeList2SubexpIndex], ";")
-				}
-				if len(changes) == 0 {
-					return nil, errors.New("Invalid xpub descriptor, cannot parse change")
-				}
-				descriptor.ChangeIndexes = make([]uint32, len(changes))
-				for i, ch := range changes {
-					change, err := strconv.ParseUint(ch, 10, 32)
-					if err != nil {
-						return nil, err
-					}
-					descriptor.ChangeIndexes[i] = uint32(change)
-
-				}
-			} else {
-				// default to {0,1}
-				descriptor.ChangeIndexes = []uint32{0, 1}
-			}
-
-		}
-		return &descriptor, nil
+	if strings.ContainsAny(xpub, "(#") {
+		return p.parseDescriptor(xpub)
 	}
 	return p.xpubDescriptorFromXpub(xpub)
-
 }
 
 // DeriveAddressDescriptors derives address descriptors from given xpub for listed indexes
 func (p *BitcoinLikeParser) DeriveAddressDescriptors(descriptor *bchain.XpubDescriptor, change uint32, indexes []uint32) ([]bchain.AddressDescriptor, error) {
+	if descriptor.ScriptTemplate != nil {
+		return p.deriveScriptAddressDescriptors(descriptor, change, indexes)
+	}
 	ad := make([]bchain.AddressDescriptor, len(indexes))
 	changeExtKey, err := descriptor.ExtKey.(*hdkeychain.ExtendedKey).Derive(change)
 	if err != nil {
@@ -547,6 +464,13 @@ func (p *BitcoinLikeParser) DeriveAddressDescriptorsFromTo(descriptor *bchain.Xp
 	if toIndex <= fromIndex {
 		return nil, errors.New("toIndex<=fromIndex")
 	}
+	if descriptor.ScriptTemplate != nil {
+		indexes := make([]uint32, toIndex-fromIndex)
+		for i := range indexes {
+			indexes[i] = fromIndex + uint32(i)
+		}
+		return p.deriveScriptAddressDescriptors(descriptor, change, indexes)
+	}
 	changeExtKey, err := descriptor.ExtKey.(*hdkeychain.ExtendedKey).Derive(change)
 	if err != nil {
 		return nil, err
@@ -575,6 +499,18 @@ func (p *BitcoinLikeParser) DerivationBasePath(descriptor *bchain.XpubDescriptor
 		c = "'"
 	}
 	c = strconv.Itoa(int(cn)) + c
+	if len(descriptor.Keys) > 0 {
+		// script descriptors use the origin of the first xpub, the depth of the xpub differs by the standard (BIP45, BIP48)
+		for _, key := range descriptor.Keys {
+			if key.ExtKey != nil {
+				if i := strings.IndexByte(key.Origin, '/'); i >= 0 {
+					return "m" + key.Origin[i:], nil
+				}
+				break
+			}
+		}
+		return "unknown/" + c, nil
+	}
 	if extKey.Depth() != 3 {
 		return "unknown/" + c, nil
 	}
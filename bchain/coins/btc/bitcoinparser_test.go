@@ -7,6 +7,7 @@ import (
 	"math/big"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/martinboehm/btcutil/chaincfg"
@@ -759,11 +760,11 @@ func TestParseXpubDescriptors(t *testing.T) {
 			},
 		},
 		{
-			name:   "tr([5c9e228d/86'/1'/0']tpubD/{0,1,2}/*)#4rqwxvej",
-			xpub:   "tr([5c9e228d/86'/1'/0']tpubDC88gkaZi5HvJGxGDNLADkvtdpni3mLmx6vr2KnXmWMG8zfkBRggsxHVBkUpgcwPe2KKpkyvTJCdXHb1UHEWE64vczyyPQfHr1skBcsRedN/{0,1,2}/*)#4rqwxvej",
+			name:   "tr([5c9e228d/86'/1'/0']tpubD/{0,1,2}/*)#dzq5m3rf",
+			xpub:   "tr([5c9e228d/86'/1'/0']tpubDC88gkaZi5HvJGxGDNLADkvtdpni3mLmx6vr2KnXmWMG8zfkBRggsxHVBkUpgcwPe2KKpkyvTJCdXHb1UHEWE64vczyyPQfHr1skBcsRedN/{0,1,2}/*)#dzq5m3rf",
 			parser: btcTestnetParser,
 			want: &bchain.XpubDescriptor{
-				XpubDescriptor: "tr([5c9e228d/86'/1'/0']tpubDC88gkaZi5HvJGxGDNLADkvtdpni3mLmx6vr2KnXmWMG8zfkBRggsxHVBkUpgcwPe2KKpkyvTJCdXHb1UHEWE64vczyyPQfHr1skBcsRedN/{0,1,2}/*)#4rqwxvej",
+				XpubDescriptor: "tr([5c9e228d/86'/1'/0']tpubDC88gkaZi5HvJGxGDNLADkvtdpni3mLmx6vr2KnXmWMG8zfkBRggsxHVBkUpgcwPe2KKpkyvTJCdXHb1UHEWE64vczyyPQfHr1skBcsRedN/{0,1,2}/*)#dzq5m3rf",
 				Xpub:           "tpubDC88gkaZi5HvJGxGDNLADkvtdpni3mLmx6vr2KnXmWMG8zfkBRggsxHVBkUpgcwPe2KKpkyvTJCdXHb1UHEWE64vczyyPQfHr1skBcsRedN",
 				Type:           bchain.P2TR,
 				Bip:            "86",
@@ -771,11 +772,11 @@ func TestParseXpubDescriptors(t *testing.T) {
 			},
 		},
 		{
-			name:   "tr([5c9e228d/86'/1'/0']tpubD/<0;1;2>/*)#4rqwxvej",
-			xpub:   "tr([5c9e228d/86'/1'/0']tpubDC88gkaZi5HvJGxGDNLADkvtdpni3mLmx6vr2KnXmWMG8zfkBRggsxHVBkUpgcwPe2KKpkyvTJCdXHb1UHEWE64vczyyPQfHr1skBcsRedN/<0;1;2>/*)#4rqwxvej",
+			name:   "tr([5c9e228d/86'/1'/0']tpubD/<0;1;2>/*)#xum0es6f",
+			xpub:   "tr([5c9e228d/86'/1'/0']tpubDC88gkaZi5HvJGxGDNLADkvtdpni3mLmx6vr2KnXmWMG8zfkBRggsxHVBkUpgcwPe2KKpkyvTJCdXHb1UHEWE64vczyyPQfHr1skBcsRedN/<0;1;2>/*)#xum0es6f",
 			parser: btcTestnetParser,
 			want: &bchain.XpubDescriptor{
-				XpubDescriptor: "tr([5c9e228d/86'/1'/0']tpubDC88gkaZi5HvJGxGDNLADkvtdpni3mLmx6vr2KnXmWMG8zfkBRggsxHVBkUpgcwPe2KKpkyvTJCdXHb1UHEWE64vczyyPQfHr1skBcsRedN/<0;1;2>/*)#4rqwxvej",
+				XpubDescriptor: "tr([5c9e228d/86'/1'/0']tpubDC88gkaZi5HvJGxGDNLADkvtdpni3mLmx6vr2KnXmWMG8zfkBRggsxHVBkUpgcwPe2KKpkyvTJCdXHb1UHEWE64vczyyPQfHr1skBcsRedN/<0;1;2>/*)#xum0es6f",
 				Xpub:           "tpubDC88gkaZi5HvJGxGDNLADkvtdpni3mLmx6vr2KnXmWMG8zfkBRggsxHVBkUpgcwPe2KKpkyvTJCdXHb1UHEWE64vczyyPQfHr1skBcsRedN",
 				Type:           bchain.P2TR,
 				Bip:            "86",
@@ -783,11 +784,11 @@ func TestParseXpubDescriptors(t *testing.T) {
 			},
 		},
 		{
-			name:   "tr([5c9e228d/86'/1'/0']tpubD/3/*)#4rqwxvej",
-			xpub:   "tr([5c9e228d/86'/1'/0']tpubDC88gkaZi5HvJGxGDNLADkvtdpni3mLmx6vr2KnXmWMG8zfkBRggsxHVBkUpgcwPe2KKpkyvTJCdXHb1UHEWE64vczyyPQfHr1skBcsRedN/3/*)#4rqwxvej",
+			name:   "tr([5c9e228d/86'/1'/0']tpubD/3/*)#0k0dg6qn",
+			xpub:   "tr([5c9e228d/86'/1'/0']tpubDC88gkaZi5HvJGxGDNLADkvtdpni3mLmx6vr2KnXmWMG8zfkBRggsxHVBkUpgcwPe2KKpkyvTJCdXHb1UHEWE64vczyyPQfHr1skBcsRedN/3/*)#0k0dg6qn",
 			parser: btcTestnetParser,
 			want: &bchain.XpubDescriptor{
-				XpubDescriptor: "tr([5c9e228d/86'/1'/0']tpubDC88gkaZi5HvJGxGDNLADkvtdpni3mLmx6vr2KnXmWMG8zfkBRggsxHVBkUpgcwPe2KKpkyvTJCdXHb1UHEWE64vczyyPQfHr1skBcsRedN/3/*)#4rqwxvej",
+				XpubDescriptor: "tr([5c9e228d/86'/1'/0']tpubDC88gkaZi5HvJGxGDNLADkvtdpni3mLmx6vr2KnXmWMG8zfkBRggsxHVBkUpgcwPe2KKpkyvTJCdXHb1UHEWE64vczyyPQfHr1skBcsRedN/3/*)#0k0dg6qn",
 				Xpub:           "tpubDC88gkaZi5HvJGxGDNLADkvtdpni3mLmx6vr2KnXmWMG8zfkBRggsxHVBkUpgcwPe2KKpkyvTJCdXHb1UHEWE64vczyyPQfHr1skBcsRedN",
 				Type:           bchain.P2TR,
 				Bip:            "86",
@@ -890,6 +891,69 @@ func TestParseXpubDescriptors(t *testing.T) {
 				ChangeIndexes:  []uint32{0, 1},
 			},
 		},
+		{
+			name:   "wsh(sortedmulti(2,[5c9e228d/48'/0'/0'/2']xpub/<0;1>/*,...))#a7kr72aj",
+			xpub:   "wsh(sortedmulti(2,[5c9e228d/48'/0'/0'/2']xpub6BosfCnifzxcFwrSzQiqu2DBVTshkCXacvNsWGYJVVhhawA7d4R5WSWGFNbi8Aw6ZRc1brxMyWMzG3DSSSSoekkudhUd9yLb6qx39T9nMdj/<0;1>/*,[d1e2f3a4/48'/0'/0'/2']xpub6BgBgsespWvERF3LHQu6CnqdvfEvtMcQjYrcRzx53QJjSxarj2afYWcLteoGVky7D3UKDP9QyrLprQ3VCECoY49yfdDEHGCtMMj92pReUsQ/<0;1>/*,[00112233/48'/0'/0'/2']zpub6rFR7y4Q2AijBEqTUquhVz398htDFrtymD9xYYfG1m4wAcvPhXNfE3EfH1r1ADqtfSdVCToUG868RvUUkgDKf31mGDtKsAYz2oz2AGutZYs/<0;1>/*))#a7kr72aj",
+			parser: btcMainParser,
+			want: &bchain.XpubDescriptor{
+				XpubDescriptor: "wsh(sortedmulti(2,[5c9e228d/48'/0'/0'/2']xpub6BosfCnifzxcFwrSzQiqu2DBVTshkCXacvNsWGYJVVhhawA7d4R5WSWGFNbi8Aw6ZRc1brxMyWMzG3DSSSSoekkudhUd9yLb6qx39T9nMdj/<0;1>/*,[d1e2f3a4/48'/0'/0'/2']xpub6BgBgsespWvERF3LHQu6CnqdvfEvtMcQjYrcRzx53QJjSxarj2afYWcLteoGVky7D3UKDP9QyrLprQ3VCECoY49yfdDEHGCtMMj92pReUsQ/<0;1>/*,[00112233/48'/0'/0'/2']zpub6rFR7y4Q2AijBEqTUquhVz398htDFrtymD9xYYfG1m4wAcvPhXNfE3EfH1r1ADqtfSdVCToUG868RvUUkgDKf31mGDtKsAYz2oz2AGutZYs/<0;1>/*))#a7kr72aj",
+				Xpub:           "xpub6BosfCnifzxcFwrSzQiqu2DBVTshkCXacvNsWGYJVVhhawA7d4R5WSWGFNbi8Aw6ZRc1brxMyWMzG3DSSSSoekkudhUd9yLb6qx39T9nMdj",
+				Type:           bchain.P2WSH,
+				Bip:            "48",
+				ChangeIndexes:  []uint32{0, 1},
+				Keys: []bchain.XpubDescriptorKey{
+					{Xpub: "xpub6BosfCnifzxcFwrSzQiqu2DBVTshkCXacvNsWGYJVVhhawA7d4R5WSWGFNbi8Aw6ZRc1brxMyWMzG3DSSSSoekkudhUd9yLb6qx39T9nMdj", Origin: "5c9e228d/48'/0'/0'/2'"},
+					{Xpub: "xpub6BgBgsespWvERF3LHQu6CnqdvfEvtMcQjYrcRzx53QJjSxarj2afYWcLteoGVky7D3UKDP9QyrLprQ3VCECoY49yfdDEHGCtMMj92pReUsQ", Origin: "d1e2f3a4/48'/0'/0'/2'"},
+					{Xpub: "zpub6rFR7y4Q2AijBEqTUquhVz398htDFrtymD9xYYfG1m4wAcvPhXNfE3EfH1r1ADqtfSdVCToUG868RvUUkgDKf31mGDtKsAYz2oz2AGutZYs", Origin: "00112233/48'/0'/0'/2'"},
+				},
+			},
+		},
+		{
+			name:   "sh(wsh(multi(1,xpub,pubkey)))",
+			xpub:   "sh(wsh(multi(1,xpub6BosfCnifzxcFwrSzQiqu2DBVTshkCXacvNsWGYJVVhhawA7d4R5WSWGFNbi8Aw6ZRc1brxMyWMzG3DSSSSoekkudhUd9yLb6qx39T9nMdj/3/*,02c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac09b95c709ee5)))",
+			parser: btcMainParser,
+			want: &bchain.XpubDescriptor{
+				XpubDescriptor: "sh(wsh(multi(1,xpub6BosfCnifzxcFwrSzQiqu2DBVTshkCXacvNsWGYJVVhhawA7d4R5WSWGFNbi8Aw6ZRc1brxMyWMzG3DSSSSoekkudhUd9yLb6qx39T9nMdj/3/*,02c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac09b95c709ee5)))",
+				Xpub:           "xpub6BosfCnifzxcFwrSzQiqu2DBVTshkCXacvNsWGYJVVhhawA7d4R5WSWGFNbi8Aw6ZRc1brxMyWMzG3DSSSSoekkudhUd9yLb6qx39T9nMdj",
+				Type:           bchain.P2SHWSH,
+				Bip:            "48",
+				ChangeIndexes:  []uint32{3},
+				Keys: []bchain.XpubDescriptorKey{
+					{Xpub: "xpub6BosfCnifzxcFwrSzQiqu2DBVTshkCXacvNsWGYJVVhhawA7d4R5WSWGFNbi8Aw6ZRc1brxMyWMzG3DSSSSoekkudhUd9yLb6qx39T9nMdj"},
+					{PubKey: []byte{0x02, 0xc6, 0x04, 0x7f, 0x94, 0x41, 0xed, 0x7d, 0x6d, 0x30, 0x45, 0x40, 0x6e, 0x95, 0xc0, 0x7c, 0xd8, 0x5c, 0x77, 0x8e, 0x4b, 0x8c, 0xef, 0x3c, 0xa7, 0xab, 0xac, 0x09, 0xb9, 0x5c, 0x70, 0x9e, 0xe5}},
+				},
+			},
+		},
+		{
+			name:    "wsh(sortedmulti(2,[5c9e228d/48'/0'/0'/2']xpub/<0;1>/*,...))#a7kr72ax error - invalid checksum",
+			xpub:    "wsh(sortedmulti(2,[5c9e228d/48'/0'/0'/2']xpub6BosfCnifzxcFwrSzQiqu2DBVTshkCXacvNsWGYJVVhhawA7d4R5WSWGFNbi8Aw6ZRc1brxMyWMzG3DSSSSoekkudhUd9yLb6qx39T9nMdj/<0;1>/*,[d1e2f3a4/48'/0'/0'/2']xpub6BgBgsespWvERF3LHQu6CnqdvfEvtMcQjYrcRzx53QJjSxarj2afYWcLteoGVky7D3UKDP9QyrLprQ3VCECoY49yfdDEHGCtMMj92pReUsQ/<0;1>/*,[00112233/48'/0'/0'/2']zpub6rFR7y4Q2AijBEqTUquhVz398htDFrtymD9xYYfG1m4wAcvPhXNfE3EfH1r1ADqtfSdVCToUG868RvUUkgDKf31mGDtKsAYz2oz2AGutZYs/<0;1>/*))#a7kr72ax",
+			parser:  btcMainParser,
+			wantErr: true,
+		},
+		{
+			name:    "wsh(multi(2,xpub/0/*,xpub/1/*)) error - different change indexes",
+			xpub:    "wsh(multi(2,xpub6BosfCnifzxcFwrSzQiqu2DBVTshkCXacvNsWGYJVVhhawA7d4R5WSWGFNbi8Aw6ZRc1brxMyWMzG3DSSSSoekkudhUd9yLb6qx39T9nMdj/0/*,xpub6BgBgsespWvERF3LHQu6CnqdvfEvtMcQjYrcRzx53QJjSxarj2afYWcLteoGVky7D3UKDP9QyrLprQ3VCECoY49yfdDEHGCtMMj92pReUsQ/1/*))",
+			parser:  btcMainParser,
+			wantErr: true,
+		},
+		{
+			name:    "wsh(multi(3,xpub,xpub)) error - threshold greater than number of keys",
+			xpub:    "wsh(multi(3,xpub6BosfCnifzxcFwrSzQiqu2DBVTshkCXacvNsWGYJVVhhawA7d4R5WSWGFNbi8Aw6ZRc1brxMyWMzG3DSSSSoekkudhUd9yLb6qx39T9nMdj,xpub6BgBgsespWvERF3LHQu6CnqdvfEvtMcQjYrcRzx53QJjSxarj2afYWcLteoGVky7D3UKDP9QyrLprQ3VCECoY49yfdDEHGCtMMj92pReUsQ))",
+			parser:  btcMainParser,
+			wantErr: true,
+		},
+		{
+			name:    "wsh(and_x(pk(xpub),older(10))) error - unknown miniscript fragment",
+			xpub:    "wsh(and_x(pk(xpub6BosfCnifzxcFwrSzQiqu2DBVTshkCXacvNsWGYJVVhhawA7d4R5WSWGFNbi8Aw6ZRc1brxMyWMzG3DSSSSoekkudhUd9yLb6qx39T9nMdj),older(10)))",
+			parser:  btcMainParser,
+			wantErr: true,
+		},
+		{
+			name:    "wsh(pk(pubkey)) error - no xpub",
+			xpub:    "wsh(pk(02c6047f9441ed7d6d3045406e95c07cd85c778e4b8cef3ca7abac09b95c709ee5))",
+			parser:  btcMainParser,
+			wantErr: true,
+		},
 		{
 			name:    "xxx(xpub) error - unknown output script",
 			xpub:    "xxx(xpub6BgBgsespWvERF3LHQu6CnqdvfEvtMcQjYrcRzx53QJjSxarj2afYWcLteoGVky7D3UKDP9QyrLprQ3VCECoY49yfdDEHGCtMMj92pReUsQ)",
@@ -922,6 +986,18 @@ func TestParseXpubDescriptors(t *testing.T) {
 					return
 				}
 				got.ExtKey = nil
+				for i := range got.Keys {
+					if (got.Keys[i].ExtKey == nil) != (got.Keys[i].PubKey != nil) {
+						t.Errorf("ParseXpub() got invalid key %d", i)
+						return
+					}
+					got.Keys[i].ExtKey = nil
+				}
+				if (got.ScriptTemplate == nil) != (got.Keys == nil) {
+					t.Errorf("ParseXpub() got invalid ScriptTemplate")
+					return
+				}
+				got.ScriptTemplate = nil
 				if !reflect.DeepEqual(got, tt.want) {
 					t.Errorf("ParseXpub() = %+v, want %+v", got, tt.want)
 				}
@@ -930,6 +1006,47 @@ func TestParseXpubDescriptors(t *testing.T) {
 	}
 }
 
+// test vectors of https://github.com/bitcoin/bips/blob/master/bip-0380.mediawiki#test-vectors
+func TestDescriptorChecksum(t *testing.T) {
+	const pkh = "pkh([d34db33f/44'/0'/0']xpub6ERApfZwUNrhLCkDtcHTcxd75RbzS1ed54G1LkBUHQVHQKqhMkhgbmJbZRkrgZw4koxb5JaHWkY4ALHY2grBGRjaDMzQLcgJvLJuZZvRcEL/1/*)"
+	for _, tt := range []struct {
+		desc string
+		want string
+	}{
+		{"raw(deadbeef)", "89f8spxm"},
+		{pkh, "ml40v0wf"},
+	} {
+		if got, err := descriptorChecksum(tt.desc); err != nil || got != tt.want {
+			t.Errorf("descriptorChecksum(%s) = %v, %v, want %v", tt.desc, got, err, tt.want)
+		}
+	}
+	if _, err := descriptorChecksum("raw(\u00dc)"); err == nil {
+		t.Error("descriptorChecksum() invalid character, expected error")
+	}
+
+	parser := NewBitcoinParser(GetChainParams("main"), &Configuration{XPubMagic: 76067358, XPubMagicSegwitP2sh: 77429938, XPubMagicSegwitNative: 78792518})
+	for _, tt := range []struct {
+		name    string
+		xpub    string
+		wantErr bool
+	}{
+		{name: "valid checksum", xpub: pkh + "#ml40v0wf"},
+		{name: "no checksum", xpub: pkh},
+		{name: "missing checksum", xpub: pkh + "#", wantErr: true},
+		{name: "too long checksum", xpub: pkh + "#ml40v0wff", wantErr: true},
+		{name: "too short checksum", xpub: pkh + "#ml40v0w", wantErr: true},
+		{name: "error in payload", xpub: strings.Replace(pkh, "/1/*", "/2/*", 1) + "#ml40v0wf", wantErr: true},
+		{name: "error in checksum", xpub: pkh + "#ml40v0wg", wantErr: true},
+		{name: "invalid character in checksum", xpub: pkh + "##l40v0wf", wantErr: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := parser.ParseXpub(tt.xpub); (err != nil) != tt.wantErr {
+				t.Errorf("ParseXpub() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
 func TestDeriveAddressDescriptors(t *testing.T) {
 	btcMainParser := NewBitcoinParser(GetChainParams("main"), &Configuration{XPubMagic: 76067358, XPubMagicSegwitP2sh: 77429938, XPubMagicSegwitNative: 78792518})
 	btcTestnetParser := NewBitcoinParser(GetChainParams("test"), &Configuration{XPubMagic: 70617039, XPubMagicSegwitP2sh: 71979618, XPubMagicSegwitNative: 73342198})
@@ -958,7 +1075,7 @@ func TestDeriveAddressDescriptors(t *testing.T) {
 		{
 			name: "m/86'/0'/0'",
 			args: args{
-				xpub:    "tr([5c9e228d/86'/0'/0']xpub6BgBgsespWvERF3LHQu6CnqdvfEvtMcQjYrcRzx53QJjSxarj2afYWcLteoGVky7D3UKDP9QyrLprQ3VCECoY49yfdDEHGCtMMj92pReUsQ/0/*)#d8jj22qr",
+				xpub:    "tr([5c9e228d/86'/0'/0']xpub6BgBgsespWvERF3LHQu6CnqdvfEvtMcQjYrcRzx53QJjSxarj2afYWcLteoGVky7D3UKDP9QyrLprQ3VCECoY49yfdDEHGCtMMj92pReUsQ/0/*)#80c3709y",
 				change:  0,
 				indexes: []uint32{0, 1},
 				parser:  btcMainParser,
@@ -968,7 +1085,7 @@ func TestDeriveAddressDescriptors(t *testing.T) {
 		{
 			name: "m/86'/0'/0'/1",
 			args: args{
-				xpub:    "tr([5c9e228d/86'/0'/0']xpub6BgBgsespWvERF3LHQu6CnqdvfEvtMcQjYrcRzx53QJjSxarj2afYWcLteoGVky7D3UKDP9QyrLprQ3VCECoY49yfdDEHGCtMMj92pReUsQ/0/*)#d8jj22qr",
+				xpub:    "tr([5c9e228d/86'/0'/0']xpub6BgBgsespWvERF3LHQu6CnqdvfEvtMcQjYrcRzx53QJjSxarj2afYWcLteoGVky7D3UKDP9QyrLprQ3VCECoY49yfdDEHGCtMMj92pReUsQ/0/*)#80c3709y",
 				change:  1,
 				indexes: []uint32{0},
 				parser:  btcMainParser,
@@ -1086,7 +1203,7 @@ func TestDeriveAddressDescriptorsFromTo(t *testing.T) {
 		{
 			name: "m/86'/0'/0'",
 			args: args{
-				xpub:      "tr([5c9e228d/86'/0'/0']xpub6BgBgsespWvERF3LHQu6CnqdvfEvtMcQjYrcRzx53QJjSxarj2afYWcLteoGVky7D3UKDP9QyrLprQ3VCECoY49yfdDEHGCtMMj92pReUsQ/0/*)#d8jj22qr",
+				xpub:      "tr([5c9e228d/86'/0'/0']xpub6BgBgsespWvERF3LHQu6CnqdvfEvtMcQjYrcRzx53QJjSxarj2afYWcLteoGVky7D3UKDP9QyrLprQ3VCECoY49yfdDEHGCtMMj92pReUsQ/0/*)#80c3709y",
 				change:    0,
 				fromIndex: 0,
 				toIndex:   1,
@@ -1105,6 +1222,50 @@ func TestDeriveAddressDescriptorsFromTo(t *testing.T) {
 			},
 			want: []string{"2N4Q5FhU2497BryFfUgbqkAJE87aKHUhXMp", "2Mt7P2BAfE922zmfXrdcYTLyR7GUvbwSEns", "2N6aUMgQk8y1zvoq6FeWFyotyj75WY9BGsu", "2NA7tbZWM9BcRwBuebKSQe2xbhhF1paJwBM", "2N8RZMzvrUUnpLmvACX9ysmJ2MX3GK5jcQM", "2MvUUSiQZDSqyeSdofKX9KrSCio1nANPDTe", "2NBXaWu1HazjoUVgrXgcKNoBLhtkkD9Gmet", "2N791Ttf89tMVw2maj86E1Y3VgxD9Mc7PU7", "2NCJmwEq8GJm8t8GWWyBXAfpw7F2qZEVP5Y", "2NEgW71hWKer2XCSA8ZCC2VnWpB77L6bk68"},
 		},
+		{
+			name: "wsh(sortedmulti(2,xpub,xpub,zpub))",
+			args: args{
+				xpub:      "wsh(sortedmulti(2,[5c9e228d/48'/0'/0'/2']xpub6BosfCnifzxcFwrSzQiqu2DBVTshkCXacvNsWGYJVVhhawA7d4R5WSWGFNbi8Aw6ZRc1brxMyWMzG3DSSSSoekkudhUd9yLb6qx39T9nMdj/<0;1>/*,[d1e2f3a4/48'/0'/0'/2']xpub6BgBgsespWvERF3LHQu6CnqdvfEvtMcQjYrcRzx53QJjSxarj2afYWcLteoGVky7D3UKDP9QyrLprQ3VCECoY49yfdDEHGCtMMj92pReUsQ/<0;1>/*,[00112233/48'/0'/0'/2']zpub6rFR7y4Q2AijBEqTUquhVz398htDFrtymD9xYYfG1m4wAcvPhXNfE3EfH1r1ADqtfSdVCToUG868RvUUkgDKf31mGDtKsAYz2oz2AGutZYs/<0;1>/*))#a7kr72aj",
+				change:    0,
+				fromIndex: 0,
+				toIndex:   2,
+				parser:    btcMainParser,
+			},
+			want: []string{"bc1q3gmlsxvcql8ejhajun9q2qlyh5g6ccdqtfpmkxp2g7kkaz7rq8vsdmxkun", "bc1q5zsalx30v9grxxk9jcdrvm6atz3k24fjnyv6cfxw4crtx2feszaq3ht22s"},
+		},
+		{
+			name: "sh(wsh(sortedmulti(2,zpub,xpub,xpub)))",
+			args: args{
+				xpub:      "sh(wsh(sortedmulti(2,zpub6rFR7y4Q2AijBEqTUquhVz398htDFrtymD9xYYfG1m4wAcvPhXNfE3EfH1r1ADqtfSdVCToUG868RvUUkgDKf31mGDtKsAYz2oz2AGutZYs,xpub6BgBgsespWvERF3LHQu6CnqdvfEvtMcQjYrcRzx53QJjSxarj2afYWcLteoGVky7D3UKDP9QyrLprQ3VCECoY49yfdDEHGCtMMj92pReUsQ,xpub6BosfCnifzxcFwrSzQiqu2DBVTshkCXacvNsWGYJVVhhawA7d4R5WSWGFNbi8Aw6ZRc1brxMyWMzG3DSSSSoekkudhUd9yLb6qx39T9nMdj)))",
+				change:    1,
+				fromIndex: 5,
+				toIndex:   6,
+				parser:    btcMainParser,
+			},
+			want: []string{"39enUxu7gHRajD3U1nKJ88Px2TNbPePE3J"},
+		},
+		{
+			name: "sh(sortedmulti(2,xpub,xpub,zpub))",
+			args: args{
+				xpub:      "sh(sortedmulti(2,xpub6BgBgsespWvERF3LHQu6CnqdvfEvtMcQjYrcRzx53QJjSxarj2afYWcLteoGVky7D3UKDP9QyrLprQ3VCECoY49yfdDEHGCtMMj92pReUsQ/{0,1}/*,xpub6BosfCnifzxcFwrSzQiqu2DBVTshkCXacvNsWGYJVVhhawA7d4R5WSWGFNbi8Aw6ZRc1brxMyWMzG3DSSSSoekkudhUd9yLb6qx39T9nMdj/{0,1}/*,zpub6rFR7y4Q2AijBEqTUquhVz398htDFrtymD9xYYfG1m4wAcvPhXNfE3EfH1r1ADqtfSdVCToUG868RvUUkgDKf31mGDtKsAYz2oz2AGutZYs/{0,1}/*))",
+				change:    1,
+				fromIndex: 0,
+				toIndex:   2,
+				parser:    btcMainParser,
+			},
+			want: []string{"3HEPCLQpLBmiN4BtfPcHGV3nV7S5qQMaKs", "39VZixx485TtyrTmtDFPxn49XkpnGw5NNw"},
+		},
+		{
+			name: "wsh(and_v(v:pk(xpub),or_d(pk(xpub),older(144))))",
+			args: args{
+				xpub:      "wsh(and_v(v:pk(xpub6BosfCnifzxcFwrSzQiqu2DBVTshkCXacvNsWGYJVVhhawA7d4R5WSWGFNbi8Aw6ZRc1brxMyWMzG3DSSSSoekkudhUd9yLb6qx39T9nMdj),or_d(pk(xpub6BgBgsespWvERF3LHQu6CnqdvfEvtMcQjYrcRzx53QJjSxarj2afYWcLteoGVky7D3UKDP9QyrLprQ3VCECoY49yfdDEHGCtMMj92pReUsQ),older(144))))",
+				change:    0,
+				fromIndex: 0,
+				toIndex:   2,
+				parser:    btcMainParser,
+			},
+			want: []string{"bc1qp7d0vt77kzvvxew6yr645fg4hj0y5m8v2kjayz52q7c44df2h5yq3mah8p", "bc1q7d8gujem6qs0hsaln8r7m6e5hsq77epq6emgv280ezlgqjh9u2wq9mk3gc"},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -1183,7 +1344,7 @@ func TestBitcoinParser_DerivationBasePath(t *testing.T) {
 		{
 			name: "m/86'/0'/0'",
 			args: args{
-				xpub:   "tr([5c9e228d/86'/0'/0']xpub6BgBgsespWvERF3LHQu6CnqdvfEvtMcQjYrcRzx53QJjSxarj2afYWcLteoGVky7D3UKDP9QyrLprQ3VCECoY49yfdDEHGCtMMj92pReUsQ/0/*)#d8jj22qr",
+				xpub:   "tr([5c9e228d/86'/0'/0']xpub6BgBgsespWvERF3LHQu6CnqdvfEvtMcQjYrcRzx53QJjSxarj2afYWcLteoGVky7D3UKDP9QyrLprQ3VCECoY49yfdDEHGCtMMj92pReUsQ/0/*)#80c3709y",
 				parser: btcMainParser,
 			},
 			want: "m/86'/0'/0'",
@@ -1220,6 +1381,14 @@ func TestBitcoinParser_DerivationBasePath(t *testing.T) {
 			},
 			want: "m/49'/1'/0'",
 		},
+		{
+			name: "m/48'/0'/0'/2'",
+			args: args{
+				xpub:   "wsh(sortedmulti(2,[5c9e228d/48'/0'/0'/2']xpub6BosfCnifzxcFwrSzQiqu2DBVTshkCXacvNsWGYJVVhhawA7d4R5WSWGFNbi8Aw6ZRc1brxMyWMzG3DSSSSoekkudhUd9yLb6qx39T9nMdj/<0;1>/*,[d1e2f3a4/48'/0'/0'/2']xpub6BgBgsespWvERF3LHQu6CnqdvfEvtMcQjYrcRzx53QJjSxarj2afYWcLteoGVky7D3UKDP9QyrLprQ3VCECoY49yfdDEHGCtMMj92pReUsQ/<0;1>/*,[00112233/48'/0'/0'/2']zpub6rFR7y4Q2AijBEqTUquhVz398htDFrtymD9xYYfG1m4wAcvPhXNfE3EfH1r1ADqtfSdVCToUG868RvUUkgDKf31mGDtKsAYz2oz2AGutZYs/<0;1>/*))#a7kr72aj",
+				parser: btcMainParser,
+			},
+			want: "m/48'/0'/0'/2'",
+		},
 		{
 			name: "m/44'/133'/12'",
 			args: args{
@@ -157,6 +157,12 @@ func (b *BitcoinRPC) Initialize() error {
 			// disable AlternativeEstimateFee logic
 			b.alternativeFeeProvider = nil
 		}
+	} else if b.ChainConfig.AlternativeEstimateFee == "localmempool" {
+		if b.alternativeFeeProvider, err = NewLocalMempoolFee(b, b.ChainConfig.AlternativeEstimateFeeParams); err != nil {
+			glog.Error("NewLocalMempoolFee error ", err, " Reverting to default estimateFee functionality")
+			// disable AlternativeEstimateFee logic
+			b.alternativeFeeProvider = nil
+		}
 	}
 
 	return nil
@@ -166,6 +172,9 @@ func (b *BitcoinRPC) Initialize() error {
 func (b *BitcoinRPC) CreateMempool(chain bchain.BlockChain) (bchain.Mempool, error) {
 	if b.Mempool == nil {
 		b.Mempool = bchain.NewMempoolBitcoinType(chain, b.ChainConfig.MempoolWorkers, b.ChainConfig.MempoolSubWorkers, b.mempoolGolombFilterP, b.mempoolFilterScripts, b.mempoolUseZeroedKey)
+		if p, ok := b.alternativeFeeProvider.(*localMempoolFeeProvider); ok {
+			p.run(b.Mempool)
+		}
 	}
 	return b.Mempool, nil
 }
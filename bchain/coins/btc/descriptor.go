@@ -0,0 +1,768 @@
+package btc
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/juju/errors"
+	"github.com/martinboehm/btcutil"
+	"github.com/martinboehm/btcutil/hdkeychain"
+	"github.com/martinboehm/btcutil/txscript"
+	"github.com/trezor/blockbook/bchain"
+)
+
+// output descriptor checksum according to https://github.com/bitcoin/bips/blob/master/bip-0380.mediawiki
+const descriptorInputCharset = "0123456789()[],'/*abcdefgh@:$%{}IJKLMNOPQRSTUVWXYZ&+-.;<=>?!^_|~ijklmnopqrstuvwxyzABCDEFGH`#\"\\ "
+const descriptorChecksumCharset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+var descriptorChecksumGenerator = [5]uint64{0xf5dee51989, 0xa9fdca3312, 0x1bab10e32d, 0x3706b1677a, 0x644d626ffd}
+
+func descriptorPolymod(c uint64, v int) uint64 {
+	top := c >> 35
+	c = (c&0x7ffffffff)<<5 ^ uint64(v)
+	for i := 0; i < 5; i++ {
+		if (top>>uint(i))&1 != 0 {
+			c ^= descriptorChecksumGenerator[i]
+		}
+	}
+	return c
+}
+
+// descriptorChecksum computes the 8 character checksum of the descriptor (without the # separator)
+func descriptorChecksum(desc string) (string, error) {
+	c := uint64(1)
+	cls := 0
+	clsCount := 0
+	for _, ch := range desc {
+		pos := strings.IndexRune(descriptorInputCharset, ch)
+		if pos < 0 {
+			return "", errors.Errorf("Invalid character '%c' in descriptor", ch)
+		}
+		c = descriptorPolymod(c, pos&31)
+		cls = cls*3 + (pos >> 5)
+		clsCount++
+		if clsCount == 3 {
+			c = descriptorPolymod(c, cls)
+			cls = 0
+			clsCount = 0
+		}
+	}
+	if clsCount > 0 {
+		c = descriptorPolymod(c, cls)
+	}
+	for i := 0; i < 8; i++ {
+		c = descriptorPolymod(c, 0)
+	}
+	c ^= 1
+	var b strings.Builder
+	for i := 0; i < 8; i++ {
+		b.WriteByte(descriptorChecksumCharset[(c>>(5*(7-uint(i))))&31])
+	}
+	return b.String(), nil
+}
+
+// descriptorExpr is a node of the parsed descriptor, e.g. name "wsh" with a single argument "sortedmulti(...)"
+// arguments which are not expressions (keys, numbers, hashes) are stored as nodes without args
+type descriptorExpr struct {
+	name string
+	args []*descriptorExpr
+	leaf bool
+}
+
+func parseDescriptorExpr(s string) (*descriptorExpr, error) {
+	i := strings.IndexByte(s, '(')
+	if i < 0 {
+		if strings.ContainsAny(s, ")") {
+			return nil, errors.Errorf("Invalid descriptor expression %s", s)
+		}
+		return &descriptorExpr{name: s, leaf: true}, nil
+	}
+	if s[len(s)-1] != ')' {
+		return nil, errors.Errorf("Invalid descriptor expression %s", s)
+	}
+	e := &descriptorExpr{name: s[:i]}
+	inner := s[i+1 : len(s)-1]
+	depth := 0
+	start := 0
+	for j := 0; j < len(inner); j++ {
+		switch inner[j] {
+		case '(', '[', '{', '<':
+			depth++
+		case ')', ']', '}', '>':
+			depth--
+			if depth < 0 {
+				return nil, errors.Errorf("Invalid descriptor expression %s", s)
+			}
+		case ',':
+			if depth == 0 {
+				a, err := parseDescriptorExpr(inner[start:j])
+				if err != nil {
+					return nil, err
+				}
+				e.args = append(e.args, a)
+				start = j + 1
+			}
+		}
+	}
+	if depth != 0 {
+		return nil, errors.Errorf("Invalid descriptor expression %s", s)
+	}
+	a, err := parseDescriptorExpr(inner[start:])
+	if err != nil {
+		return nil, err
+	}
+	e.args = append(e.args, a)
+	return e, nil
+}
+
+// descriptorKey is a parsed key expression of the descriptor
+type descriptorKey struct {
+	bchain.XpubDescriptorKey
+	changeIndexes []uint32
+}
+
+// parseDescriptorKey parses key expression in the form [origin]xpub/change/* or [origin]pubkey
+// the change can be a single index or a list of indexes in the form {0,1} or <0;1>
+func (p *BitcoinLikeParser) parseDescriptorKey(s string) (*descriptorKey, error) {
+	var key descriptorKey
+	if strings.HasPrefix(s, "[") {
+		i := strings.IndexByte(s, ']')
+		if i < 0 {
+			return nil, errors.Errorf("Invalid key origin in %s", s)
+		}
+		origin := strings.Split(s[1:i], "/")
+		if len(origin[0]) != 8 {
+			return nil, errors.Errorf("Invalid key origin fingerprint in %s", s)
+		}
+		if _, err := hex.DecodeString(origin[0]); err != nil {
+			return nil, errors.Errorf("Invalid key origin fingerprint in %s", s)
+		}
+		for j := 1; j < len(origin); j++ {
+			e := strings.TrimRight(origin[j], "'h")
+			if len(origin[j])-len(e) > 1 {
+				return nil, errors.Errorf("Invalid key origin path in %s", s)
+			}
+			if _, err := strconv.ParseUint(e, 10, 31); err != nil {
+				return nil, errors.Errorf("Invalid key origin path in %s", s)
+			}
+			if e != origin[j] {
+				origin[j] = e + "'"
+			}
+		}
+		key.Origin = strings.Join(origin, "/")
+		s = s[i+1:]
+	}
+	path := strings.Split(s, "/")
+	if len(path[0]) == 66 {
+		pubKey, err := hex.DecodeString(path[0])
+		if err == nil {
+			if len(path) > 1 {
+				return nil, errors.Errorf("Derivation path is not allowed for public key %s", path[0])
+			}
+			key.PubKey = pubKey
+			return &key, nil
+		}
+	}
+	extKey, err := hdkeychain.NewKeyFromString(path[0], p.Params.Base58CksumHasher)
+	if err != nil {
+		return nil, err
+	}
+	key.Xpub = path[0]
+	key.ExtKey = extKey
+	switch len(path) {
+	case 1:
+		// default to {0,1}
+		key.changeIndexes = []uint32{0, 1}
+	case 3:
+		if path[2] != "*" {
+			return nil, errors.Errorf("Invalid xpub descriptor %s, the path must end with /*", s)
+		}
+		var changes []string
+		c := path[1]
+		if len(c) > 2 && ((c[0] == '{' && c[len(c)-1] == '}') || (c[0] == '<' && c[len(c)-1] == '>')) {
+			if c[0] == '{' {
+				changes = strings.Split(c[1:len(c)-1], ",")
+			} else {
+				changes = strings.Split(c[1:len(c)-1], ";")
+			}
+		} else {
+			changes = []string{c}
+		}
+		key.changeIndexes = make([]uint32, len(changes))
+		for i, ch := range changes {
+			change, err := strconv.ParseUint(ch, 10, 31)
+			if err != nil {
+				return nil, errors.Errorf("Invalid xpub descriptor %s, cannot parse change", s)
+			}
+			key.changeIndexes[i] = uint32(change)
+		}
+	default:
+		return nil, errors.Errorf("Invalid xpub descriptor %s, unsupported derivation path", s)
+	}
+	return &key, nil
+}
+
+// bipFromOrigin returns the purpose part of the key origin path, empty string if the origin is not specified
+func bipFromOrigin(origin string) string {
+	path := strings.Split(origin, "/")
+	if len(path) > 1 {
+		return strings.TrimRight(path[1], "'")
+	}
+	return ""
+}
+
+// parseDescriptor parses output descriptor with checksum validation
+func (p *BitcoinLikeParser) parseDescriptor(xpub string) (*bchain.XpubDescriptor, error) {
+	desc := xpub
+	if i := strings.LastIndexByte(xpub, '#'); i >= 0 {
+		desc = xpub[:i]
+		checksum, err := descriptorChecksum(desc)
+		if err != nil {
+			return nil, err
+		}
+		if checksum != xpub[i+1:] {
+			return nil, errors.Errorf("Invalid descriptor checksum %s, expected %s", xpub[i+1:], checksum)
+		}
+	}
+	expr, err := parseDescriptorExpr(desc)
+	if err != nil {
+		return nil, err
+	}
+	if expr.leaf {
+		return nil, errors.Errorf("Invalid xpub descriptor %s", xpub)
+	}
+	var descriptor bchain.XpubDescriptor
+	descriptor.XpubDescriptor = xpub
+	var script *descriptorExpr
+	switch expr.name {
+	case "pkh":
+		descriptor.Type = bchain.P2PKH
+		descriptor.Bip = "44"
+	case "wpkh":
+		descriptor.Type = bchain.P2WPKH
+		descriptor.Bip = "84"
+	case "tr":
+		if len(expr.args) != 1 {
+			return nil, errors.New("Taproot descriptors with script tree are not supported")
+		}
+		descriptor.Type = bchain.P2TR
+		descriptor.Bip = "86"
+	case "sh":
+		if len(expr.args) != 1 {
+			return nil, errors.Errorf("Invalid xpub descriptor %s", xpub)
+		}
+		switch expr.args[0].name {
+		case "wpkh":
+			descriptor.Type = bchain.P2SHWPKH
+			descriptor.Bip = "49"
+			expr = expr.args[0]
+		case "wsh":
+			descriptor.Type = bchain.P2SHWSH
+			descriptor.Bip = "48"
+			script = expr.args[0]
+		default:
+			descriptor.Type = bchain.P2SH
+			descriptor.Bip = "45"
+			script = expr
+		}
+	case "wsh":
+		descriptor.Type = bchain.P2WSH
+		descriptor.Bip = "48"
+		script = expr
+	default:
+		return nil, errors.Errorf("Xpub descriptor %s is not supported", expr.name)
+	}
+	if script != nil {
+		if len(script.args) != 1 || script.args[0].leaf {
+			return nil, errors.Errorf("Invalid xpub descriptor %s", xpub)
+		}
+		if err = p.parseScriptDescriptor(&descriptor, script.args[0]); err != nil {
+			return nil, err
+		}
+		return &descriptor, nil
+	}
+	if len(expr.args) != 1 || !expr.args[0].leaf {
+		return nil, errors.Errorf("Invalid xpub descriptor %s", xpub)
+	}
+	key, err := p.parseDescriptorKey(expr.args[0].name)
+	if err != nil {
+		return nil, err
+	}
+	if key.ExtKey == nil {
+		return nil, errors.Errorf("Xpub descriptor %s does not contain xpub", xpub)
+	}
+	if bip := bipFromOrigin(key.Origin); bip != "" {
+		descriptor.Bip = bip
+	}
+	descriptor.Xpub = key.Xpub
+	descriptor.ExtKey = key.ExtKey
+	descriptor.ChangeIndexes = key.changeIndexes
+	return &descriptor, nil
+}
+
+// parseScriptDescriptor parses the script part of sh and wsh descriptors and collects its keys
+func (p *BitcoinLikeParser) parseScriptDescriptor(descriptor *bchain.XpubDescriptor, expr *descriptorExpr) error {
+	var keys []*descriptorKey
+	root, err := p.parseScriptNode(expr, &keys)
+	if err != nil {
+		return err
+	}
+	descriptor.Keys = make([]bchain.XpubDescriptorKey, len(keys))
+	for i, key := range keys {
+		descriptor.Keys[i] = key.XpubDescriptorKey
+		if key.ExtKey == nil {
+			continue
+		}
+		if descriptor.ExtKey == nil {
+			descriptor.Xpub = key.Xpub
+			descriptor.ExtKey = key.ExtKey
+			descriptor.ChangeIndexes = key.changeIndexes
+			if bip := bipFromOrigin(key.Origin); bip != "" {
+				descriptor.Bip = bip
+			}
+		} else if !equalChangeIndexes(descriptor.ChangeIndexes, key.changeIndexes) {
+			return errors.New("All xpubs of the descriptor must have the same change indexes")
+		}
+	}
+	if descriptor.ExtKey == nil {
+		return errors.Errorf("Xpub descriptor %s does not contain xpub", descriptor.XpubDescriptor)
+	}
+	descriptor.ScriptTemplate = &scriptTemplate{root: root}
+	return nil
+}
+
+func equalChangeIndexes(a, b []uint32) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// scriptTemplate is a compiled script of a script descriptor,
+// it generates the script for the keys derived at given change and index
+type scriptTemplate struct {
+	root *scriptNode
+}
+
+// scriptNode is a miniscript fragment (or multi/sortedmulti) with its wrappers
+type scriptNode struct {
+	fragment string
+	wrappers string
+	k        int64
+	keys     []int
+	hash     []byte
+	sub      []*scriptNode
+}
+
+// miniscript hash fragments with the expected hash length and the hashing opcode
+var miniscriptHashFragments = map[string]struct {
+	size int
+	op   byte
+}{
+	"sha256":    {32, txscript.OP_SHA256},
+	"hash256":   {32, txscript.OP_HASH256},
+	"ripemd160": {20, txscript.OP_RIPEMD160},
+	"hash160":   {20, txscript.OP_HASH160},
+}
+
+// number of sub expressions of miniscript fragments
+var miniscriptSubFragments = map[string]int{
+	"andor":  3,
+	"and_v":  2,
+	"and_b":  2,
+	"and_n":  2,
+	"or_b":   2,
+	"or_c":   2,
+	"or_d":   2,
+	"or_i":   2,
+	"thresh": -1,
+}
+
+const maxMultisigKeys = 20
+
+func (p *BitcoinLikeParser) parseScriptNode(expr *descriptorExpr, keys *[]*descriptorKey) (*scriptNode, error) {
+	n := &scriptNode{fragment: expr.name}
+	if i := strings.IndexByte(expr.name, ':'); i >= 0 {
+		n.wrappers = expr.name[:i]
+		n.fragment = expr.name[i+1:]
+		for _, w := range n.wrappers {
+			if !strings.ContainsRune("asctdvjnlu", w) {
+				return nil, errors.Errorf("Unknown miniscript wrapper %c", w)
+			}
+		}
+	}
+	if expr.leaf {
+		if n.fragment != "0" && n.fragment != "1" {
+			return nil, errors.Errorf("Unknown miniscript fragment %s", n.fragment)
+		}
+		return n, nil
+	}
+	addKey := func(a *descriptorExpr) error {
+		if !a.leaf {
+			return errors.Errorf("Invalid key in %s", expr.name)
+		}
+		key, err := p.parseDescriptorKey(a.name)
+		if err != nil {
+			return err
+		}
+		n.keys = append(n.keys, len(*keys))
+		*keys = append(*keys, key)
+		return nil
+	}
+	parseK := func(a *descriptorExpr) error {
+		var err error
+		if !a.leaf {
+			return errors.Errorf("Invalid number in %s", expr.name)
+		}
+		if n.k, err = strconv.ParseInt(a.name, 10, 32); err != nil {
+			return errors.Errorf("Invalid number in %s", expr.name)
+		}
+		return nil
+	}
+	switch n.fragment {
+	case "pk", "pkh", "pk_k", "pk_h":
+		if len(expr.args) != 1 {
+			return nil, errors.Errorf("Invalid number of arguments of %s", expr.name)
+		}
+		if err := addKey(expr.args[0]); err != nil {
+			return nil, err
+		}
+	case "older", "after":
+		if len(expr.args) != 1 {
+			return nil, errors.Errorf("Invalid number of arguments of %s", expr.name)
+		}
+		if err := parseK(expr.args[0]); err != nil {
+			return nil, err
+		}
+		if n.k < 1 {
+			return nil, errors.Errorf("Invalid value of %s", expr.name)
+		}
+	case "multi", "sortedmulti":
+		if len(expr.args) < 2 || len(expr.args) > maxMultisigKeys+1 {
+			return nil, errors.Errorf("Invalid number of arguments of %s", expr.name)
+		}
+		if err := parseK(expr.args[0]); err != nil {
+			return nil, err
+		}
+		if n.k < 1 || n.k > int64(len(expr.args)-1) {
+			return nil, errors.Errorf("Invalid threshold of %s", expr.name)
+		}
+		for _, a := range expr.args[1:] {
+			if err := addKey(a); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		if h, ok := miniscriptHashFragments[n.fragment]; ok {
+			if len(expr.args) != 1 || !expr.args[0].leaf {
+				return nil, errors.Errorf("Invalid number of arguments of %s", expr.name)
+			}
+			hash, err := hex.DecodeString(expr.args[0].name)
+			if err != nil || len(hash) != h.size {
+				return nil, errors.Errorf("Invalid hash in %s", expr.name)
+			}
+			n.hash = hash
+			break
+		}
+		count, ok := miniscriptSubFragments[n.fragment]
+		if !ok {
+			return nil, errors.Errorf("Unknown miniscript fragment %s", n.fragment)
+		}
+		args := expr.args
+		if count < 0 {
+			// thresh(k,X1,...,Xn)
+			if len(args) < 2 {
+				return nil, errors.Errorf("Invalid number of arguments of %s", expr.name)
+			}
+			if err := parseK(args[0]); err != nil {
+				return nil, err
+			}
+			args = args[1:]
+			if n.k < 1 || n.k > int64(len(args)) {
+				return nil, errors.Errorf("Invalid threshold of %s", expr.name)
+			}
+		} else if len(args) != count {
+			return nil, errors.Errorf("Invalid number of arguments of %s", expr.name)
+		}
+		for _, a := range args {
+			s, err := p.parseScriptNode(a, keys)
+			if err != nil {
+				return nil, err
+			}
+			n.sub = append(n.sub, s)
+		}
+	}
+	return n, nil
+}
+
+// scriptWriter builds the script, keeping track of the last opcode for the verify wrapper
+type scriptWriter struct {
+	script []byte
+	lastOp int
+}
+
+func (w *scriptWriter) op(ops ...byte) {
+	w.script = append(w.script, ops...)
+	w.lastOp = int(ops[len(ops)-1])
+}
+
+func (w *scriptWriter) data(d []byte) {
+	s, _ := txscript.NewScriptBuilder().AddData(d).Script()
+	w.script = append(w.script, s...)
+	w.lastOp = -1
+}
+
+func (w *scriptWriter) number(n int64) {
+	s, _ := txscript.NewScriptBuilder().AddInt64(n).Script()
+	w.script = append(w.script, s...)
+	w.lastOp = -1
+}
+
+// verify converts the last opcode to its VERIFY variant if possible, otherwise appends OP_VERIFY
+func (w *scriptWriter) verify() {
+	var v byte
+	switch w.lastOp {
+	case txscript.OP_EQUAL:
+		v = txscript.OP_EQUALVERIFY
+	case txscript.OP_NUMEQUAL:
+		v = txscript.OP_NUMEQUALVERIFY
+	case txscript.OP_CHECKSIG:
+		v = txscript.OP_CHECKSIGVERIFY
+	case txscript.OP_CHECKMULTISIG:
+		v = txscript.OP_CHECKMULTISIGVERIFY
+	default:
+		w.op(txscript.OP_VERIFY)
+		return
+	}
+	w.script[len(w.script)-1] = v
+	w.lastOp = int(v)
+}
+
+// script returns the script of the template for the given public keys
+func (t *scriptTemplate) script(pubKeys [][]byte) []byte {
+	var w scriptWriter
+	t.root.write(&w, pubKeys, 0)
+	return w.script
+}
+
+// write outputs the script of the node starting from the wrapper at index wrapper, the first wrapper is the outermost one
+func (n *scriptNode) write(w *scriptWriter, pubKeys [][]byte, wrapper int) {
+	if wrapper == len(n.wrappers) {
+		n.writeFragment(w, pubKeys)
+		return
+	}
+	inner := func() { n.write(w, pubKeys, wrapper+1) }
+	switch n.wrappers[wrapper] {
+	case 'a':
+		w.op(txscript.OP_TOALTSTACK)
+		inner()
+		w.op(txscript.OP_FROMALTSTACK)
+	case 's':
+		w.op(txscript.OP_SWAP)
+		inner()
+	case 'c':
+		inner()
+		w.op(txscript.OP_CHECKSIG)
+	case 't':
+		inner()
+		w.op(txscript.OP_1)
+	case 'd':
+		w.op(txscript.OP_DUP, txscript.OP_IF)
+		inner()
+		w.op(txscript.OP_ENDIF)
+	case 'v':
+		inner()
+		w.verify()
+	case 'j':
+		w.op(txscript.OP_SIZE, txscript.OP_0NOTEQUAL, txscript.OP_IF)
+		inner()
+		w.op(txscript.OP_ENDIF)
+	case 'n':
+		inner()
+		w.op(txscript.OP_0NOTEQUAL)
+	case 'l':
+		w.op(txscript.OP_IF, txscript.OP_0, txscript.OP_ELSE)
+		inner()
+		w.op(txscript.OP_ENDIF)
+	case 'u':
+		w.op(txscript.OP_IF)
+		inner()
+		w.op(txscript.OP_ELSE, txscript.OP_0, txscript.OP_ENDIF)
+	}
+}
+
+func (n *scriptNode) writeSub(w *scriptWriter, pubKeys [][]byte, i int) {
+	n.sub[i].write(w, pubKeys, 0)
+}
+
+func (n *scriptNode) writeFragment(w *scriptWriter, pubKeys [][]byte) {
+	switch n.fragment {
+	case "0":
+		w.op(txscript.OP_0)
+	case "1":
+		w.op(txscript.OP_1)
+	case "pk_k":
+		w.data(pubKeys[n.keys[0]])
+	case "pk":
+		w.data(pubKeys[n.keys[0]])
+		w.op(txscript.OP_CHECKSIG)
+	case "pk_h", "pkh":
+		w.op(txscript.OP_DUP, txscript.OP_HASH160)
+		w.data(btcutil.Hash160(pubKeys[n.keys[0]]))
+		w.op(txscript.OP_EQUALVERIFY)
+		if n.fragment == "pkh" {
+			w.op(txscript.OP_CHECKSIG)
+		}
+	case "older":
+		w.number(n.k)
+		w.op(txscript.OP_CHECKSEQUENCEVERIFY)
+	case "after":
+		w.number(n.k)
+		w.op(txscript.OP_CHECKLOCKTIMEVERIFY)
+	case "multi", "sortedmulti":
+		keys := make([][]byte, len(n.keys))
+		for i, k := range n.keys {
+			keys[i] = pubKeys[k]
+		}
+		if n.fragment == "sortedmulti" {
+			sort.Slice(keys, func(i, j int) bool { return bytes.Compare(keys[i], keys[j]) < 0 })
+		}
+		w.number(n.k)
+		for _, k := range keys {
+			w.data(k)
+		}
+		w.number(int64(len(keys)))
+		w.op(txscript.OP_CHECKMULTISIG)
+	case "andor":
+		n.writeSub(w, pubKeys, 0)
+		w.op(txscript.OP_NOTIF)
+		n.writeSub(w, pubKeys, 2)
+		w.op(txscript.OP_ELSE)
+		n.writeSub(w, pubKeys, 1)
+		w.op(txscript.OP_ENDIF)
+	case "and_v":
+		n.writeSub(w, pubKeys, 0)
+		n.writeSub(w, pubKeys, 1)
+	case "and_b":
+		n.writeSub(w, pubKeys, 0)
+		n.writeSub(w, pubKeys, 1)
+		w.op(txscript.OP_BOOLAND)
+	case "and_n":
+		n.writeSub(w, pubKeys, 0)
+		w.op(txscript.OP_NOTIF, txscript.OP_0, txscript.OP_ELSE)
+		n.writeSub(w, pubKeys, 1)
+		w.op(txscript.OP_ENDIF)
+	case "or_b":
+		n.writeSub(w, pubKeys, 0)
+		n.writeSub(w, pubKeys, 1)
+		w.op(txscript.OP_BOOLOR)
+	case "or_c":
+		n.writeSub(w, pubKeys, 0)
+		w.op(txscript.OP_NOTIF)
+		n.writeSub(w, pubKeys, 1)
+		w.op(txscript.OP_ENDIF)
+	case "or_d":
+		n.writeSub(w, pubKeys, 0)
+		w.op(txscript.OP_IFDUP, txscript.OP_NOTIF)
+		n.writeSub(w, pubKeys, 1)
+		w.op(txscript.OP_ENDIF)
+	case "or_i":
+		w.op(txscript.OP_IF)
+		n.writeSub(w, pubKeys, 0)
+		w.op(txscript.OP_ELSE)
+		n.writeSub(w, pubKeys, 1)
+		w.op(txscript.OP_ENDIF)
+	case "thresh":
+		for i := range n.sub {
+			n.writeSub(w, pubKeys, i)
+			if i > 0 {
+				w.op(txscript.OP_ADD)
+			}
+		}
+		w.number(n.k)
+		w.op(txscript.OP_EQUAL)
+	default:
+		if h, ok := miniscriptHashFragments[n.fragment]; ok {
+			w.op(txscript.OP_SIZE)
+			w.number(32)
+			w.op(txscript.OP_EQUALVERIFY, h.op)
+			w.data(n.hash)
+			w.op(txscript.OP_EQUAL)
+		}
+	}
+}
+
+// deriveScriptAddressDescriptors derives address descriptors of a script descriptor for listed indexes
+func (p *BitcoinLikeParser) deriveScriptAddressDescriptors(descriptor *bchain.XpubDescriptor, change uint32, indexes []uint32) ([]bchain.AddressDescriptor, error) {
+	template, ok := descriptor.ScriptTemplate.(*scriptTemplate)
+	if !ok {
+		return nil, errors.New("Invalid script descriptor template")
+	}
+	changeExtKeys := make([]*hdkeychain.ExtendedKey, len(descriptor.Keys))
+	pubKeys := make([][]byte, len(descriptor.Keys))
+	for i := range descriptor.Keys {
+		if descriptor.Keys[i].ExtKey == nil {
+			pubKeys[i] = descriptor.Keys[i].PubKey
+			continue
+		}
+		var err error
+		changeExtKeys[i], err = descriptor.Keys[i].ExtKey.(*hdkeychain.ExtendedKey).Derive(change)
+		if err != nil {
+			return nil, err
+		}
+	}
+	ad := make([]bchain.AddressDescriptor, len(indexes))
+	for i, index := range indexes {
+		for j, changeExtKey := range changeExtKeys {
+			if changeExtKey == nil {
+				continue
+			}
+			indexExtKey, err := changeExtKey.Derive(index)
+			if err != nil {
+				return nil, err
+			}
+			pubKeys[j] = indexExtKey.PubKeyBytes()
+		}
+		var err error
+		ad[i], err = p.addrDescFromScript(template.script(pubKeys), descriptor)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return ad, nil
+}
+
+func (p *BitcoinLikeParser) addrDescFromScript(script []byte, descriptor *bchain.XpubDescriptor) (bchain.AddressDescriptor, error) {
+	var a btcutil.Address
+	var err error
+	switch descriptor.Type {
+	case bchain.P2SH:
+		a, err = btcutil.NewAddressScriptHash(script, p.Params)
+	case bchain.P2WSH:
+		hash := sha256.Sum256(script)
+		a, err = btcutil.NewAddressWitnessScriptHash(hash[:], p.Params)
+	case bchain.P2SHWSH:
+		// redeemScript <witness version: OP_0><len scriptHash: 32><32-byte-scriptHash>
+		hash := sha256.Sum256(script)
+		redeemScript := make([]byte, len(hash)+2)
+		redeemScript[0] = 0
+		redeemScript[1] = byte(len(hash))
+		copy(redeemScript[2:], hash[:])
+		a, err = btcutil.NewAddressScriptHash(redeemScript, p.Params)
+	default:
+		return nil, errors.New("Unsupported xpub descriptor type")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return txscript.PayToAddrScript(a)
+}
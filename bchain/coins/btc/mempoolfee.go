@@ -0,0 +1,101 @@
+package btc
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+	"github.com/trezor/blockbook/bchain"
+)
+
+// the fee estimation from the local mempool projects the mempool transactions to the next blocks,
+// the transactions are selected by the fee rate of their package (the transaction with its unconfirmed ancestors)
+// so that the child pays for parent transactions are accounted for
+
+const (
+	defaultLocalMempoolFeePeriodSeconds = 60
+	// maximum block weight divided by the witness scale factor, with a reserve for the coinbase transaction
+	defaultLocalMempoolFeeBlockVSize  = 1000000 - 4000
+	defaultLocalMempoolFeeMinFeePerKB = 1000
+)
+
+type localMempoolFeeParams struct {
+	PeriodSeconds int   `json:"periodSeconds"`
+	BlockVSize    int64 `json:"blockVSize"`
+	MinFeePerKB   int64 `json:"minFeePerKB"`
+}
+
+type localMempoolFeeProvider struct {
+	*alternativeFeeProvider
+	params localMempoolFeeParams
+}
+
+// NewLocalMempoolFee initializes the fee estimation from the local mempool
+func NewLocalMempoolFee(chain bchain.BlockChain, params string) (alternativeFeeProviderInterface, error) {
+	p := &localMempoolFeeProvider{alternativeFeeProvider: &alternativeFeeProvider{}}
+	if params != "" {
+		err := json.Unmarshal([]byte(params), &p.params)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if p.params.PeriodSeconds == 0 {
+		p.params.PeriodSeconds = defaultLocalMempoolFeePeriodSeconds
+	}
+	if p.params.BlockVSize == 0 {
+		p.params.BlockVSize = defaultLocalMempoolFeeBlockVSize
+	}
+	if p.params.MinFeePerKB == 0 {
+		p.params.MinFeePerKB = defaultLocalMempoolFeeMinFeePerKB
+	}
+	if p.params.PeriodSeconds < 0 || p.params.BlockVSize < 0 || p.params.MinFeePerKB < 0 {
+		return nil, errors.New("NewLocalMempoolFee: Invalid parameters")
+	}
+	if _, ok := chain.(*BitcoinRPC); !ok {
+		return nil, errors.New("NewLocalMempoolFee: Unsupported chain")
+	}
+	p.chain = chain
+	return p, nil
+}
+
+// run starts the periodic estimation from the mempool, the mempool is created after the initialization of the chain
+func (p *localMempoolFeeProvider) run(mempool *bchain.MempoolBitcoinType) {
+	go p.localMempoolFeeUpdater(mempool)
+}
+
+func (p *localMempoolFeeProvider) localMempoolFeeUpdater(mempool *bchain.MempoolBitcoinType) {
+	period := time.Duration(p.params.PeriodSeconds) * time.Second
+	timer := time.NewTimer(period)
+	counter := 0
+	for {
+		t := mempool.UpdateBlockTemplate(p.params.BlockVSize, p.params.MinFeePerKB)
+		if p.localMempoolFeeProcessTemplate(t) {
+			if counter%60 == 0 {
+				p.compareToDefault()
+			}
+			counter++
+		}
+		<-timer.C
+		timer.Reset(period)
+	}
+}
+
+func (p *localMempoolFeeProvider) localMempoolFeeProcessTemplate(t *bchain.MempoolBlockTemplate) bool {
+	// empty mempool is most probably not synchronized yet, let the default estimator be used
+	if t.Txs == 0 {
+		return false
+	}
+	p.mux.Lock()
+	defer p.mux.Unlock()
+	p.fees = make([]alternativeFeeProviderFee, len(t.FeePerKB))
+	for i, fee := range t.FeePerKB {
+		p.fees[i] = alternativeFeeProviderFee{
+			blocks:   i + 1,
+			feePerKB: int(fee),
+		}
+	}
+	p.lastSync = t.Time
+	glog.V(1).Info("localMempoolFee: ", t.Txs, " txs, ", t.VSize, " vbytes, fees ", t.FeePerKB)
+	return true
+}
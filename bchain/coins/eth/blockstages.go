@@ -0,0 +1,209 @@
+package eth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+	"github.com/trezor/blockbook/bchain"
+)
+
+// stagedBlockData is the chain specific data of bchain.EthereumStagedBlock
+type stagedBlockData struct {
+	head   rpcHeader
+	body   rpcBlockTransactions
+	header *bchain.BlockHeader
+	// filled by the logs stage
+	logs map[string][]*bchain.RpcLog
+	ens  []bchain.AddressAliasRecord
+	// filled by the internal data stage, the error does not stop the block processing
+	internalData    []bchain.EthereumInternalData
+	contracts       []bchain.ContractInfo
+	internalDataErr error
+}
+
+func (b *EthereumRPC) newStagedBlock(raw json.RawMessage) (*bchain.EthereumStagedBlock, error) {
+	d := &stagedBlockData{}
+	if err := json.Unmarshal(raw, &d.head); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(raw, &d.body); err != nil {
+		return nil, err
+	}
+	var err error
+	if d.header, err = b.ethHeaderToBlockHeader(&d.head); err != nil {
+		return nil, err
+	}
+	return &bchain.EthereumStagedBlock{
+		Height: d.header.Height,
+		Hash:   d.header.Hash,
+		Data:   d,
+	}, nil
+}
+
+// batchTimeout returns the timeout of a batch request with n calls
+func (b *EthereumRPC) batchTimeout(n int) time.Duration {
+	return b.Timeout * time.Duration(n)
+}
+
+// EthereumTypeGetBlockBodies fetches the headers and transactions of the blocks in the range lower-higher in one batch request
+func (b *EthereumRPC) EthereumTypeGetBlockBodies(lower, higher uint32) ([]*bchain.EthereumStagedBlock, error) {
+	if higher < lower {
+		return nil, errors.Errorf("Invalid block range %v-%v", lower, higher)
+	}
+	n := int(higher-lower) + 1
+	raws := make([]json.RawMessage, n)
+	elems := make([]bchain.EVMBatchElem, n)
+	for i := range elems {
+		elems[i] = bchain.EVMBatchElem{
+			Method: "eth_getBlockByNumber",
+			Args:   []interface{}{fmt.Sprintf("%#x", lower+uint32(i)), true},
+			Result: &raws[i],
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), b.batchTimeout(n))
+	defer cancel()
+	if err := b.RPC.BatchCallContext(ctx, elems); err != nil {
+		return nil, errors.Annotatef(err, "eth_getBlockByNumber %v-%v", lower, higher)
+	}
+	blocks := make([]*bchain.EthereumStagedBlock, n)
+	for i := range elems {
+		height := lower + uint32(i)
+		raw := raws[i]
+		if elems[i].Error != nil {
+			// the backend may refuse part of the batch, for example if the response is too large, fetch the block alone
+			var err error
+			if raw, err = b.getBlockRaw("", height, true); err != nil {
+				return nil, err
+			}
+		} else if len(raw) == 0 || (len(raw) == 4 && string(raw) == "null") {
+			return nil, errors.Annotatef(bchain.ErrBlockNotFound, "height %v", height)
+		}
+		sb, err := b.newStagedBlock(raw)
+		if err != nil {
+			return nil, errors.Annotatef(err, "height %v", height)
+		}
+		if sb.Height != height {
+			return nil, errors.Errorf("Unexpected block %v %v, expected height %v", sb.Height, sb.Hash, height)
+		}
+		blocks[i] = sb
+	}
+	return blocks, nil
+}
+
+// EthereumTypeGetBlockLogs fetches the logs of the blocks in one batch request
+func (b *EthereumRPC) EthereumTypeGetBlockLogs(blocks []*bchain.EthereumStagedBlock) error {
+	logs := make([][]rpcLogWithTxHash, len(blocks))
+	elems := make([]bchain.EVMBatchElem, len(blocks))
+	for i, sb := range blocks {
+		d := sb.Data.(*stagedBlockData)
+		elems[i] = bchain.EVMBatchElem{
+			Method: "eth_getLogs",
+			Args: []interface{}{map[string]interface{}{
+				"fromBlock": d.head.Number,
+				"toBlock":   d.head.Number,
+			}},
+			Result: &logs[i],
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), b.batchTimeout(len(elems)))
+	defer cancel()
+	if err := b.RPC.BatchCallContext(ctx, elems); err != nil {
+		return errors.Annotatef(err, "eth_getLogs batch of %v blocks", len(elems))
+	}
+	for i, sb := range blocks {
+		d := sb.Data.(*stagedBlockData)
+		if elems[i].Error != nil {
+			var err error
+			if d.logs, d.ens, err = b.processEventsForBlock(d.head.Number); err != nil {
+				return err
+			}
+		} else {
+			d.logs, d.ens = processEvents(logs[i])
+		}
+	}
+	return nil
+}
+
+// EthereumTypeGetBlockInternalData fetches the internal data of the blocks in one batch request
+func (b *EthereumRPC) EthereumTypeGetBlockInternalData(blocks []*bchain.EthereumStagedBlock) error {
+	if !ProcessInternalTransactions {
+		for _, sb := range blocks {
+			d := sb.Data.(*stagedBlockData)
+			d.internalData = make([]bchain.EthereumInternalData, len(d.body.Transactions))
+			d.contracts = make([]bchain.ContractInfo, 0)
+		}
+		return nil
+	}
+	traces := make([][]rpcTraceResult, len(blocks))
+	elems := make([]bchain.EVMBatchElem, len(blocks))
+	for i, sb := range blocks {
+		elems[i] = bchain.EVMBatchElem{
+			Method: "debug_traceBlockByHash",
+			Args:   []interface{}{sb.Hash, traceBlockOptions},
+			Result: &traces[i],
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), b.batchTimeout(len(elems)))
+	defer cancel()
+	if err := b.RPC.BatchCallContext(ctx, elems); err != nil {
+		return errors.Annotatef(err, "debug_traceBlockByHash batch of %v blocks", len(elems))
+	}
+	for i, sb := range blocks {
+		d := sb.Data.(*stagedBlockData)
+		if elems[i].Error != nil {
+			// retry the block alone, the error of the single call is stored with the block as in GetBlock
+			glog.Warning("debug_traceBlockByHash block ", sb.Hash, " in batch, error ", elems[i].Error, ", retrying")
+			d.internalData, d.contracts, d.internalDataErr = b.getInternalDataForBlock(sb.Hash, sb.Height, d.body.Transactions)
+		} else {
+			d.internalData, d.contracts, d.internalDataErr = b.processInternalDataTrace(sb.Hash, sb.Height, d.body.Transactions, traces[i])
+		}
+	}
+	return nil
+}
+
+// EthereumTypeAssembleBlock creates the block from the data fetched by all the stages
+func (b *EthereumRPC) EthereumTypeAssembleBlock(sb *bchain.EthereumStagedBlock) (*bchain.Block, error) {
+	return b.assembleBlock(sb)
+}
+
+func (b *EthereumRPC) assembleBlock(sb *bchain.EthereumStagedBlock) (*bchain.Block, error) {
+	d, ok := sb.Data.(*stagedBlockData)
+	if !ok || d.internalData == nil {
+		return nil, errors.Errorf("Block %v %v is not fetched completely", sb.Height, sb.Hash)
+	}
+	// pass internalData error and ENS records in blockSpecificData to be stored
+	var blockSpecificData *bchain.EthereumBlockSpecificData
+	if d.internalDataErr != nil || len(d.ens) > 0 || len(d.contracts) > 0 {
+		blockSpecificData = &bchain.EthereumBlockSpecificData{}
+		if d.internalDataErr != nil {
+			blockSpecificData.InternalDataError = d.internalDataErr.Error()
+		}
+		if len(d.ens) > 0 {
+			blockSpecificData.AddressAliasRecords = d.ens
+		}
+		if len(d.contracts) > 0 {
+			blockSpecificData.Contracts = d.contracts
+		}
+	}
+	btxs := make([]bchain.Tx, len(d.body.Transactions))
+	for i := range d.body.Transactions {
+		tx := &d.body.Transactions[i]
+		btx, err := b.Parser.ethTxToTx(tx, &bchain.RpcReceipt{Logs: d.logs[tx.Hash]}, &d.internalData[i], d.head.BaseFeePerGas, d.header.Time, uint32(d.header.Confirmations), true)
+		if err != nil {
+			return nil, errors.Annotatef(err, "hash %v, height %v, txid %v", sb.Hash, sb.Height, tx.Hash)
+		}
+		btxs[i] = *btx
+		if b.mempoolInitialized {
+			b.Mempool.RemoveTransactionFromMempool(tx.Hash)
+		}
+	}
+	return &bchain.Block{
+		BlockHeader:      *d.header,
+		Txs:              btxs,
+		CoinSpecificData: blockSpecificData,
+	}, nil
+}
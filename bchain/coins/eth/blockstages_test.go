@@ -0,0 +1,168 @@
+//go:build unittest
+
+package eth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/juju/errors"
+	"github.com/trezor/blockbook/bchain"
+)
+
+// testStagesRPC serves the block data of heights 0x10 and 0x11, the calls for the heights in failBatch fail in batches,
+// the calls for the heights in failAlways fail also when called alone
+type testStagesRPC struct {
+	failBatch  map[string]bool
+	failAlways map[string]bool
+	batches    int
+	calls      int
+}
+
+const testStagesAddrA = "0x1111111111111111111111111111111111111111"
+const testStagesAddrB = "0x2222222222222222222222222222222222222222"
+
+func testStagesHash(number string) string {
+	return fmt.Sprintf("0x%064s", number[2:])
+}
+
+func testStagesTxHash(number string) string {
+	return fmt.Sprintf("0x%063sf", number[2:])
+}
+
+// testStagesResponse returns the response to the call and the number of the block it belongs to
+func testStagesResponse(method string, args []interface{}) (string, string) {
+	switch method {
+	case "eth_getBlockByNumber":
+		n := args[0].(string)
+		return fmt.Sprintf(`{"hash":"%s","parentHash":"0x00","number":"%s","timestamp":"0x5f000000","size":"0x100","transactions":[`+
+			`{"nonce":"0x0","gasPrice":"0x1","gas":"0x5208","to":"%s","value":"0x1","input":"0x","hash":"%s","blockNumber":"%s","from":"%s","transactionIndex":"0x0"}]}`,
+			testStagesHash(n), n, testStagesAddrB, testStagesTxHash(n), n, testStagesAddrA), n
+	case "eth_getLogs":
+		n := args[0].(map[string]interface{})["fromBlock"].(string)
+		return fmt.Sprintf(`[{"address":"%s","topics":["0x%064x"],"data":"0x","transactionHash":"%s"}]`, testStagesAddrB, 1, testStagesTxHash(n)), n
+	case "debug_traceBlockByHash":
+		h := args[0].(string)
+		n := "0x" + h[len(h)-2:]
+		return fmt.Sprintf(`[{"result":{"type":"CALL","from":"%s","to":"%s","value":"0x1","calls":[{"type":"CALL","from":"%s","to":"%s","value":"0x5"}]}}]`,
+			testStagesAddrA, testStagesAddrB, testStagesAddrB, testStagesAddrA), n
+	}
+	return "", ""
+}
+
+func (r *testStagesRPC) EthSubscribe(ctx context.Context, channel interface{}, args ...interface{}) (bchain.EVMClientSubscription, error) {
+	return nil, errors.New("not supported")
+}
+
+func (r *testStagesRPC) CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error {
+	r.calls++
+	resp, n := testStagesResponse(method, args)
+	if resp == "" {
+		return errors.Errorf("unexpected method %v", method)
+	}
+	if r.failAlways[n] {
+		return errors.Errorf("%v %v failed", method, n)
+	}
+	return json.Unmarshal([]byte(resp), result)
+}
+
+func (r *testStagesRPC) BatchCallContext(ctx context.Context, b []bchain.EVMBatchElem) error {
+	r.batches++
+	for i := range b {
+		resp, n := testStagesResponse(b[i].Method, b[i].Args)
+		if resp == "" {
+			return errors.Errorf("unexpected method %v", b[i].Method)
+		}
+		if r.failBatch[n] || r.failAlways[n] {
+			b[i].Error = errors.Errorf("%v %v failed", b[i].Method, n)
+		} else if err := json.Unmarshal([]byte(resp), b[i].Result); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *testStagesRPC) Close() {}
+
+func TestEthereumRPC_StagedBlocks(t *testing.T) {
+	defer func(p bool) { ProcessInternalTransactions = p }(ProcessInternalTransactions)
+	ProcessInternalTransactions = true
+	rpc := &testStagesRPC{
+		failBatch:  map[string]bool{"0x11": true},
+		failAlways: map[string]bool{},
+	}
+	b := &EthereumRPC{
+		RPC:         rpc,
+		Timeout:     time.Second,
+		Parser:      NewEthereumParser(1, false),
+		ChainConfig: &Configuration{},
+		bestHeader:  &EthereumHeader{Header: &types.Header{Number: big.NewInt(0x20)}},
+	}
+
+	blocks, err := b.EthereumTypeGetBlockBodies(0x10, 0x11)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(blocks) != 2 || blocks[0].Height != 0x10 || blocks[1].Height != 0x11 || blocks[1].Hash != testStagesHash("0x11") {
+		t.Fatalf("EthereumTypeGetBlockBodies() = %+v", blocks)
+	}
+	// the block 0x11 failed in the batch and was fetched alone
+	if rpc.batches != 1 || rpc.calls != 1 {
+		t.Errorf("EthereumTypeGetBlockBodies() batches %v, calls %v, want 1, 1", rpc.batches, rpc.calls)
+	}
+	if _, err = b.EthereumTypeAssembleBlock(blocks[0]); err == nil {
+		t.Error("EthereumTypeAssembleBlock() before all stages, expected error")
+	}
+
+	if err = b.EthereumTypeGetBlockLogs(blocks); err != nil {
+		t.Fatal(err)
+	}
+	rpc.failAlways["0x11"] = true
+	if err = b.EthereumTypeGetBlockInternalData(blocks); err != nil {
+		t.Fatal(err)
+	}
+	if rpc.batches != 3 || rpc.calls != 3 {
+		t.Errorf("stages batches %v, calls %v, want 3, 3", rpc.batches, rpc.calls)
+	}
+
+	for i, sb := range blocks {
+		block, err := b.EthereumTypeAssembleBlock(sb)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if block.Height != sb.Height || block.Hash != sb.Hash || block.Confirmations != int(0x20-sb.Height+1) {
+			t.Errorf("block %d: header %+v", i, block.BlockHeader)
+		}
+		if len(block.Txs) != 1 {
+			t.Fatalf("block %d: got %d txs, want 1", i, len(block.Txs))
+		}
+		csd, ok := block.Txs[0].CoinSpecificData.(bchain.EthereumSpecificData)
+		if !ok {
+			t.Fatalf("block %d: unexpected tx CoinSpecificData %T", i, block.Txs[0].CoinSpecificData)
+		}
+		if len(csd.Receipt.Logs) != 1 {
+			t.Errorf("block %d: got %d logs, want 1", i, len(csd.Receipt.Logs))
+		}
+		bsd, _ := block.CoinSpecificData.(*bchain.EthereumBlockSpecificData)
+		if i == 0 {
+			if csd.InternalData == nil || len(csd.InternalData.Transfers) != 1 || csd.InternalData.Transfers[0].From != testStagesAddrB {
+				t.Errorf("block %d: internal data %+v", i, csd.InternalData)
+			}
+			if bsd != nil {
+				t.Errorf("block %d: unexpected block specific data %+v", i, bsd)
+			}
+		} else {
+			if csd.InternalData != nil {
+				t.Errorf("block %d: unexpected internal data %+v", i, csd.InternalData)
+			}
+			if bsd == nil || bsd.InternalDataError == "" {
+				t.Errorf("block %d: missing internal data error", i)
+			}
+		}
+	}
+}
@@ -21,6 +21,8 @@ const erc1155URIMethodSignature = "0x0e89341c"                     // uri(uint25
 const tokenTransferEventSignature = "0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"
 const tokenERC1155TransferSingleEventSignature = "0xc3d58168c5ae7397731d063d5bbf3d657854427343f4c083240f7aacaa2d0f62"
 const tokenERC1155TransferBatchEventSignature = "0x4a39dc06d4c0dbc64b70af90fd698a233a518aa5d07e595d983b8c0526c8f7fb"
+const tokenApprovalEventSignature = "0x8c5be1e5ebec7d5bd14f71427d1e84f3dd0314c0f7b2291e5b200ac8c7c3b925"
+const tokenApprovalForAllEventSignature = "0x17307eab39ab6107e8899845ad3d59bd9653f200f220920489ca2b5937696c31"
 
 const nameRegisteredEventSignature = "0xca6abbe9d7f11422cb6ca7629fbf6fe9efb1c621f71ce8f02b9f2a230097404f"
 
@@ -28,6 +30,7 @@ const contractNameSignature = "0x06fdde03"
 const contractSymbolSignature = "0x95d89b41"
 const contractDecimalsSignature = "0x313ce567"
 const contractBalanceOfSignature = "0x70a08231"
+const contractAllowanceSignature = "0xdd62ed3e" // allowance(address,address)
 
 func addressFromPaddedHex(s string) (string, error) {
 	var t big.Int
@@ -226,6 +229,79 @@ func contractGetTransfersFromLog(logs []*bchain.RpcLog) (bchain.TokenTransfers,
 	return r, nil
 }
 
+// processApprovalEvent processes the ERC20 Approval event, the ERC721 Approval of a single token
+// (with the token id as the fourth topic) is ignored
+func processApprovalEvent(l *bchain.RpcLog) (approval *bchain.TokenApproval, err error) {
+	if len(l.Topics) != 3 {
+		return nil, nil
+	}
+	var value big.Int
+	_, ok := value.SetString(l.Data, 0)
+	if !ok {
+		return nil, errors.New("ERC20 Approval log Data is not a number")
+	}
+	return newTokenApproval(l, false, &value)
+}
+
+// processApprovalForAllEvent processes the ERC721 and ERC1155 ApprovalForAll event
+func processApprovalForAllEvent(l *bchain.RpcLog) (approval *bchain.TokenApproval, err error) {
+	if len(l.Topics) != 3 {
+		return nil, nil
+	}
+	var value big.Int
+	_, ok := value.SetString(l.Data, 0)
+	if !ok {
+		return nil, errors.New("ApprovalForAll log Data is not a number")
+	}
+	if value.Sign() != 0 {
+		value.SetInt64(1)
+	}
+	return newTokenApproval(l, true, &value)
+}
+
+func newTokenApproval(l *bchain.RpcLog, forAll bool, value *big.Int) (*bchain.TokenApproval, error) {
+	owner, err := addressFromPaddedHex(l.Topics[1])
+	if err != nil {
+		return nil, err
+	}
+	spender, err := addressFromPaddedHex(l.Topics[2])
+	if err != nil {
+		return nil, err
+	}
+	return &bchain.TokenApproval{
+		ForAll:   forAll,
+		Contract: EIP55AddressFromAddress(l.Address),
+		Owner:    EIP55AddressFromAddress(owner),
+		Spender:  EIP55AddressFromAddress(spender),
+		Value:    *value,
+	}, nil
+}
+
+func contractGetApprovalsFromLog(logs []*bchain.RpcLog) (bchain.TokenApprovals, error) {
+	var r bchain.TokenApprovals
+	var ta *bchain.TokenApproval
+	var err error
+	for _, l := range logs {
+		if len(l.Topics) > 0 {
+			signature := l.Topics[0]
+			if signature == tokenApprovalEventSignature {
+				ta, err = processApprovalEvent(l)
+			} else if signature == tokenApprovalForAllEventSignature {
+				ta, err = processApprovalForAllEvent(l)
+			} else {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			if ta != nil {
+				r = append(r, ta)
+			}
+		}
+	}
+	return r, nil
+}
+
 func contractGetTransfersFromTx(tx *bchain.RpcTransaction) (bchain.TokenTransfers, error) {
 	var r bchain.TokenTransfers
 	if len(tx.Payload) == 10+128 && strings.HasPrefix(tx.Payload, erc20TransferMethodSignature) {
@@ -351,6 +427,24 @@ func (b *EthereumRPC) EthereumTypeGetErc20ContractBalance(addrDesc, contractDesc
 	return r, nil
 }
 
+// EthereumTypeGetErc20ContractAllowance returns the amount of the ERC20 token, which the spender can currently transfer from the owner
+func (b *EthereumRPC) EthereumTypeGetErc20ContractAllowance(ownerDesc, spenderDesc, contractDesc bchain.AddressDescriptor) (*big.Int, error) {
+	owner := hexutil.Encode(ownerDesc)[2:]
+	spender := hexutil.Encode(spenderDesc)[2:]
+	contract := hexutil.Encode(contractDesc)
+	req := contractAllowanceSignature + "0000000000000000000000000000000000000000000000000000000000000000"[len(owner):] + owner +
+		"0000000000000000000000000000000000000000000000000000000000000000"[len(spender):] + spender
+	data, err := b.ethCall(req, contract)
+	if err != nil {
+		return nil, err
+	}
+	r := parseSimpleNumericProperty(data)
+	if r == nil {
+		return nil, errors.New("Invalid allowance")
+	}
+	return r, nil
+}
+
 // GetContractInfo returns URI of non fungible or multi token defined by token id
 func (b *EthereumRPC) GetTokenURI(contractDesc bchain.AddressDescriptor, tokenID *big.Int) (string, error) {
 	address := hexutil.Encode(contractDesc)
@@ -227,6 +227,106 @@ func Test_contractGetTransfersFromLog(t *testing.T) {
 	}
 }
 
+func Test_contractGetApprovalsFromLog(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []*bchain.RpcLog
+		want    bchain.TokenApprovals
+		wantErr bool
+	}{
+		{
+			name: "ERC20 approval and ApprovalForAll",
+			args: []*bchain.RpcLog{
+				{ // Approval
+					Address: "0x76a45e8976499ab9ae223cc584019341d5a84e96",
+					Topics: []string{
+						"0x8c5be1e5ebec7d5bd14f71427d1e84f3dd0314c0f7b2291e5b200ac8c7c3b925",
+						"0x0000000000000000000000002aacf811ac1a60081ea39f7783c0d26c500871a8",
+						"0x000000000000000000000000e9a5216ff992cfa01594d43501a56e12769eb9d2",
+					},
+					Data: "0xffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff",
+				},
+				{ // Transfer
+					Address: "0x76a45e8976499ab9ae223cc584019341d5a84e96",
+					Topics: []string{
+						"0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef",
+						"0x0000000000000000000000002aacf811ac1a60081ea39f7783c0d26c500871a8",
+						"0x000000000000000000000000e9a5216ff992cfa01594d43501a56e12769eb9d2",
+					},
+					Data: "0x0000000000000000000000000000000000000000000000000000000000000123",
+				},
+				{ // ERC721 Approval of a single token, ignored
+					Address: "0x5689b918d34c038901870105a6c7fc24744d31eb",
+					Topics: []string{
+						"0x8c5be1e5ebec7d5bd14f71427d1e84f3dd0314c0f7b2291e5b200ac8c7c3b925",
+						"0x0000000000000000000000002aacf811ac1a60081ea39f7783c0d26c500871a8",
+						"0x000000000000000000000000e9a5216ff992cfa01594d43501a56e12769eb9d2",
+						"0x0000000000000000000000000000000000000000000000000000000000000001",
+					},
+					Data: "0x",
+				},
+				{ // ApprovalForAll
+					Address: "0x6fd712e3a5b556654044608f9129040a4839e36c",
+					Topics: []string{
+						"0x17307eab39ab6107e8899845ad3d59bd9653f200f220920489ca2b5937696c31",
+						"0x0000000000000000000000002aacf811ac1a60081ea39f7783c0d26c500871a8",
+						"0x0000000000000000000000001e0049783f008a0085193e00003d00cd54003c71",
+					},
+					Data: "0x0000000000000000000000000000000000000000000000000000000000000001",
+				},
+			},
+			want: bchain.TokenApprovals{
+				{
+					Contract: "0x76a45e8976499ab9ae223cc584019341d5a84e96",
+					Owner:    "0x2aacf811ac1a60081ea39f7783c0d26c500871a8",
+					Spender:  "0xe9a5216ff992cfa01594d43501a56e12769eb9d2",
+					Value:    *new(big.Int).Sub(new(big.Int).Lsh(big.NewInt(1), 256), big.NewInt(1)),
+				},
+				{
+					ForAll:   true,
+					Contract: "0x6fd712e3a5b556654044608f9129040a4839e36c",
+					Owner:    "0x2aacf811ac1a60081ea39f7783c0d26c500871a8",
+					Spender:  "0x1e0049783f008a0085193e00003d00cd54003c71",
+					Value:    *big.NewInt(1),
+				},
+			},
+		},
+		{
+			name: "invalid data",
+			args: []*bchain.RpcLog{
+				{
+					Address: "0x76a45e8976499ab9ae223cc584019341d5a84e96",
+					Topics: []string{
+						"0x8c5be1e5ebec7d5bd14f71427d1e84f3dd0314c0f7b2291e5b200ac8c7c3b925",
+						"0x0000000000000000000000002aacf811ac1a60081ea39f7783c0d26c500871a8",
+						"0x000000000000000000000000e9a5216ff992cfa01594d43501a56e12769eb9d2",
+					},
+					Data: "0xzz",
+				},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := contractGetApprovalsFromLog(tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("contractGetApprovalsFromLog error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if len(got) != len(tt.want) {
+				t.Errorf("contractGetApprovalsFromLog len not same, %+v, want %+v", got, tt.want)
+			}
+			for i := range got {
+				// the addresses could have different case
+				if strings.ToLower(fmt.Sprint(got[i])) != strings.ToLower(fmt.Sprint(tt.want[i])) {
+					t.Errorf("contractGetApprovalsFromLog %d = %+v, want %+v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
+
 func Test_contractGetTransfersFromTx(t *testing.T) {
 	p := NewEthereumParser(1, false)
 	b1 := dbtestdata.GetTestEthereumTypeBlock1(p)
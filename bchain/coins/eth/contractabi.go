@@ -0,0 +1,296 @@
+package eth
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"math/big"
+	"reflect"
+	"runtime/debug"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/accounts/abi"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+	"github.com/trezor/blockbook/bchain"
+)
+
+// ContractABI is a verified ABI of a contract uploaded by the operator, it is used to decode exactly
+// the input data, the return data and the logs of the contract
+type ContractABI struct {
+	ABI abi.ABI
+	// Data is the ABI in the JSON format as it is stored in the database
+	Data []byte
+}
+
+// ParseContractABI parses the ABI in the JSON format, it accepts the ABI array, an object with the abi field
+// (compiler artifacts) or the ABI array encoded as JSON string (as returned by the block explorers)
+func ParseContractABI(data []byte) (*ContractABI, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] == '{' {
+		var artifact struct {
+			ABI json.RawMessage `json:"abi"`
+		}
+		if err := json.Unmarshal(data, &artifact); err != nil {
+			return nil, errors.Annotatef(err, "Invalid contract ABI")
+		}
+		data = bytes.TrimSpace(artifact.ABI)
+	}
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return nil, errors.Annotatef(err, "Invalid contract ABI")
+		}
+		data = bytes.TrimSpace([]byte(s))
+	}
+	if len(data) == 0 || data[0] != '[' {
+		return nil, errors.New("Invalid contract ABI, expecting JSON array")
+	}
+	a, err := abi.JSON(bytes.NewReader(data))
+	if err != nil {
+		return nil, errors.Annotatef(err, "Invalid contract ABI")
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, data); err != nil {
+		return nil, errors.Annotatef(err, "Invalid contract ABI")
+	}
+	return &ContractABI{ABI: a, Data: compact.Bytes()}, nil
+}
+
+func decodeHexData(data string) ([]byte, bool) {
+	if has0xPrefix(data) {
+		data = data[2:]
+	}
+	b, err := hex.DecodeString(data)
+	return b, err == nil
+}
+
+func abiSignature(name string, args abi.Arguments) string {
+	types := make([]string, len(args))
+	for i := range args {
+		types[i] = args[i].Type.String()
+	}
+	return name + "(" + strings.Join(types, ", ") + ")"
+}
+
+// formatABIValue formats the value unpacked by the abi package, the arrays and tuples are returned as slices of their formatted elements
+func formatABIValue(t *abi.Type, v reflect.Value) interface{} {
+	switch t.T {
+	case abi.IntTy, abi.UintTy:
+		switch v.Kind() {
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			return strconv.FormatInt(v.Int(), 10)
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			return strconv.FormatUint(v.Uint(), 10)
+		}
+		if n, ok := v.Interface().(*big.Int); ok && n != nil {
+			return n.String()
+		}
+	case abi.BoolTy:
+		return strconv.FormatBool(v.Bool())
+	case abi.AddressTy:
+		if a, ok := v.Interface().(common.Address); ok {
+			return EIP55Address(a.Bytes())
+		}
+	case abi.StringTy:
+		return v.String()
+	case abi.BytesTy:
+		if v.Len() == 0 {
+			return ""
+		}
+		return hexutil.Encode(v.Bytes())
+	case abi.FixedBytesTy, abi.FunctionTy, abi.HashTy:
+		b := make([]byte, v.Len())
+		reflect.Copy(reflect.ValueOf(b), v)
+		return hexutil.Encode(b)
+	case abi.ArrayTy, abi.SliceTy:
+		r := make([]interface{}, v.Len())
+		for i := range r {
+			r[i] = formatABIValue(t.Elem, v.Index(i))
+		}
+		return r
+	case abi.TupleTy:
+		r := make([]interface{}, len(t.TupleElems))
+		for i := range r {
+			r[i] = formatABIValue(t.TupleElems[i], v.Field(i))
+		}
+		return r
+	}
+	return ""
+}
+
+// formatABIParamValues returns the values of the parameter in the same form as the values parsed from the four byte signatures,
+// the elements of the arrays are the values, the nested arrays and tuples are encoded as JSON arrays
+func formatABIParamValues(t *abi.Type, v interface{}) []string {
+	var values []interface{}
+	f := formatABIValue(t, reflect.ValueOf(v))
+	if t.T == abi.ArrayTy || t.T == abi.SliceTy {
+		values = f.([]interface{})
+	} else {
+		values = []interface{}{f}
+	}
+	r := make([]string, len(values))
+	for i, value := range values {
+		if s, ok := value.(string); ok {
+			r[i] = s
+		} else {
+			b, err := json.Marshal(value)
+			if err != nil {
+				return nil
+			}
+			r[i] = string(b)
+		}
+	}
+	return r
+}
+
+func unpackABIParams(args abi.Arguments, data []byte) ([]bchain.EthereumParsedInputParam, bool) {
+	values, err := args.UnpackValues(data)
+	if err != nil || len(values) != len(args) {
+		return nil, false
+	}
+	params := make([]bchain.EthereumParsedInputParam, len(args))
+	for i := range args {
+		params[i] = bchain.EthereumParsedInputParam{
+			Name:   args[i].Name,
+			Type:   args[i].Type.String(),
+			Values: formatABIParamValues(&args[i].Type, values[i]),
+		}
+	}
+	return params, true
+}
+
+// ParseInputData decodes the transaction input data by the method of the contract ABI,
+// returns nil if the ABI does not contain the method
+func (a *ContractABI) ParseInputData(data string) (parsed *bchain.EthereumParsedInputData) {
+	if len(data) < 10 {
+		return nil
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			glog.Error("ContractABI.ParseInputData recovered from panic: ", r, ", ", data)
+			debug.PrintStack()
+			parsed = nil
+		}
+	}()
+	b, ok := decodeHexData(data)
+	if !ok {
+		return nil
+	}
+	method, err := a.ABI.MethodById(b)
+	if err != nil {
+		return nil
+	}
+	params, ok := unpackABIParams(method.Inputs, b[4:])
+	if !ok {
+		return nil
+	}
+	return &bchain.EthereumParsedInputData{
+		MethodId: data[:10],
+		Name:     decamel(method.RawName),
+		Function: abiSignature(method.RawName, method.Inputs),
+		Params:   params,
+	}
+}
+
+// ParseReturnData decodes the data returned by the call of the contract with the input data by the outputs of the method of the contract ABI,
+// returns nil if the ABI does not contain the method
+func (a *ContractABI) ParseReturnData(input, output string) (parsed []bchain.EthereumParsedInputParam) {
+	defer func() {
+		if r := recover(); r != nil {
+			glog.Error("ContractABI.ParseReturnData recovered from panic: ", r, ", ", input, ", ", output)
+			debug.PrintStack()
+			parsed = nil
+		}
+	}()
+	in, ok := decodeHexData(input)
+	if !ok || len(in) < 4 {
+		return nil
+	}
+	out, ok := decodeHexData(output)
+	if !ok {
+		return nil
+	}
+	method, err := a.ABI.MethodById(in)
+	if err != nil {
+		return nil
+	}
+	params, ok := unpackABIParams(method.Outputs, out)
+	if !ok {
+		return nil
+	}
+	return params
+}
+
+// ParseLogData decodes the log by the event of the contract ABI identified by the first topic,
+// the indexed parameters of dynamic types, arrays and tuples are stored in the topics only as their keccak hash, which is returned;
+// returns nil if the ABI does not contain the event
+func (a *ContractABI) ParseLogData(topics []string, data string) (parsed *bchain.EthereumParsedLogData) {
+	if len(topics) == 0 {
+		return nil
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			glog.Error("ContractABI.ParseLogData recovered from panic: ", r, ", ", topics, ", ", data)
+			debug.PrintStack()
+			parsed = nil
+		}
+	}()
+	topicBytes := make([][]byte, len(topics))
+	for i, t := range topics {
+		b, ok := decodeHexData(t)
+		if !ok || len(b) != common.HashLength {
+			return nil
+		}
+		topicBytes[i] = b
+	}
+	event, err := a.ABI.EventByID(common.BytesToHash(topicBytes[0]))
+	if err != nil {
+		return nil
+	}
+	b, ok := decodeHexData(data)
+	if !ok {
+		return nil
+	}
+	nonIndexed := event.Inputs.NonIndexed()
+	if len(event.Inputs)-len(nonIndexed) != len(topics)-1 {
+		return nil
+	}
+	dataParams, ok := unpackABIParams(nonIndexed, b)
+	if !ok {
+		return nil
+	}
+	params := make([]bchain.EthereumParsedLogParam, len(event.Inputs))
+	next, nextData := 1, 0
+	for i := range event.Inputs {
+		arg := &event.Inputs[i]
+		if !arg.Indexed {
+			p := &dataParams[nextData]
+			params[i] = bchain.EthereumParsedLogParam{Name: p.Name, Type: p.Type, Values: p.Values}
+			nextData++
+			continue
+		}
+		topic := topicBytes[next]
+		next++
+		var values []string
+		switch arg.Type.T {
+		case abi.StringTy, abi.BytesTy, abi.SliceTy, abi.ArrayTy, abi.TupleTy:
+			values = []string{hexutil.Encode(topic)}
+		default:
+			topicParams, ok := unpackABIParams(abi.Arguments{{Type: arg.Type}}, topic)
+			if !ok {
+				return nil
+			}
+			values = topicParams[0].Values
+		}
+		params[i] = bchain.EthereumParsedLogParam{Name: arg.Name, Type: arg.Type.String(), Indexed: true, Values: values}
+	}
+	return &bchain.EthereumParsedLogData{
+		Name:   decamel(event.RawName),
+		Event:  abiSignature(event.RawName, event.Inputs),
+		Params: params,
+	}
+}
@@ -0,0 +1,157 @@
+//go:build unittest
+
+package eth
+
+import (
+	"encoding/hex"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/trezor/blockbook/bchain"
+)
+
+const testContractABI = `[
+	{"type":"function","name":"swapExactTokens","stateMutability":"nonpayable",
+		"inputs":[
+			{"name":"order","type":"tuple","components":[{"name":"maker","type":"address"},{"name":"amounts","type":"uint256[]"}]},
+			{"name":"path","type":"address[2][]"},
+			{"name":"deadline","type":"int64"}
+		],
+		"outputs":[{"name":"amountOut","type":"uint256"},{"name":"note","type":"string"}]},
+	{"type":"event","name":"OrderFilled","anonymous":false,
+		"inputs":[
+			{"name":"maker","type":"address","indexed":true},
+			{"name":"tag","type":"string","indexed":true},
+			{"name":"fills","type":"tuple[]","indexed":false,"components":[{"name":"id","type":"uint8"},{"name":"data","type":"bytes"}]}
+		]}
+]`
+
+func TestParseContractABI(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{name: "array", data: testContractABI},
+		{name: "artifact", data: `{"contractName":"Test","abi":` + testContractABI + `}`},
+		{name: "string", data: `"[{\"type\":\"function\",\"name\":\"f\",\"inputs\":[]}]"`},
+		{name: "empty", data: "", wantErr: true},
+		{name: "object without abi", data: `{"contractName":"Test"}`, wantErr: true},
+		{name: "invalid type", data: `[{"type":"function","name":"f","inputs":[{"name":"a","type":"foo"}]}]`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := ParseContractABI([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseContractABI() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && len(a.ABI.Methods) == 0 {
+				t.Errorf("ParseContractABI() no methods parsed")
+			}
+		})
+	}
+}
+
+func TestContractABI_ParseData(t *testing.T) {
+	a, err := ParseContractABI([]byte(testContractABI))
+	if err != nil {
+		t.Fatal(err)
+	}
+	maker := common.HexToAddress("0x5689b918d34c038901870105a6c7fc24744d31eb")
+	token1 := common.HexToAddress("0x76a45e8976499ab9ae223cc584019341d5a84e96")
+	token2 := common.HexToAddress("0x4af4114f73d1c1c903ac9e0361b379d1291808a2")
+	type order struct {
+		Maker   common.Address
+		Amounts []*big.Int
+	}
+	input, err := a.ABI.Pack("swapExactTokens",
+		order{Maker: maker, Amounts: []*big.Int{big.NewInt(1), new(big.Int).Lsh(big.NewInt(1), 200)}},
+		[][2]common.Address{{token1, token2}, {token2, token1}},
+		int64(-5),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	inputHex := "0x" + hex.EncodeToString(input)
+	wantInput := &bchain.EthereumParsedInputData{
+		MethodId: inputHex[:10],
+		Name:     "Swap Exact Tokens",
+		Function: "swapExactTokens((address,uint256[]), address[2][], int64)",
+		Params: []bchain.EthereumParsedInputParam{
+			{
+				Name:   "order",
+				Type:   "(address,uint256[])",
+				Values: []string{`["0x5689b918D34C038901870105A6C7fc24744D31eB",["1","1606938044258990275541962092341162602522202993782792835301376"]]`},
+			},
+			{
+				Name: "path",
+				Type: "address[2][]",
+				Values: []string{
+					`["0x76A45e8976499ab9aE223cc584019341d5a84e96","0x4af4114F73d1c1C903aC9E0361b379D1291808A2"]`,
+					`["0x4af4114F73d1c1C903aC9E0361b379D1291808A2","0x76A45e8976499ab9aE223cc584019341d5a84e96"]`,
+				},
+			},
+			{
+				Name:   "deadline",
+				Type:   "int64",
+				Values: []string{"-5"},
+			},
+		},
+	}
+	if got := a.ParseInputData(inputHex); !reflect.DeepEqual(got, wantInput) {
+		t.Errorf("ParseInputData() = %+v, want %+v", got, wantInput)
+	}
+	if got := a.ParseInputData("0x12345678" + inputHex[10:]); got != nil {
+		t.Errorf("ParseInputData() unknown method = %+v, want nil", got)
+	}
+	if got := a.ParseInputData(inputHex[:100]); got != nil {
+		t.Errorf("ParseInputData() truncated = %+v, want nil", got)
+	}
+
+	output, err := a.ABI.Methods["swapExactTokens"].Outputs.Pack(big.NewInt(12345), "done")
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantOutput := []bchain.EthereumParsedInputParam{
+		{Name: "amountOut", Type: "uint256", Values: []string{"12345"}},
+		{Name: "note", Type: "string", Values: []string{"done"}},
+	}
+	if got := a.ParseReturnData(inputHex, "0x"+hex.EncodeToString(output)); !reflect.DeepEqual(got, wantOutput) {
+		t.Errorf("ParseReturnData() = %+v, want %+v", got, wantOutput)
+	}
+
+	type fill struct {
+		Id   uint8
+		Data []byte
+	}
+	event := a.ABI.Events["OrderFilled"]
+	data, err := event.Inputs.NonIndexed().Pack([]fill{{Id: 7, Data: []byte{1, 2, 3}}, {Id: 8}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	topics := []string{
+		event.ID.Hex(),
+		"0x0000000000000000000000005689b918d34c038901870105a6c7fc24744d31eb",
+		"0x1c8aff950685c2ed4bc3174f3472287b56d9517b9c948127319a09a7a36deac8",
+	}
+	wantLog := &bchain.EthereumParsedLogData{
+		Name:  "Order Filled",
+		Event: "OrderFilled(address, string, (uint8,bytes)[])",
+		Params: []bchain.EthereumParsedLogParam{
+			{Name: "maker", Type: "address", Indexed: true, Values: []string{"0x5689b918D34C038901870105A6C7fc24744D31eB"}},
+			{Name: "tag", Type: "string", Indexed: true, Values: []string{"0x1c8aff950685c2ed4bc3174f3472287b56d9517b9c948127319a09a7a36deac8"}},
+			{Name: "fills", Type: "(uint8,bytes)[]", Values: []string{`["7","0x010203"]`, `["8",""]`}},
+		},
+	}
+	if got := a.ParseLogData(topics, "0x"+hex.EncodeToString(data)); !reflect.DeepEqual(got, wantLog) {
+		t.Errorf("ParseLogData() = %+v, want %+v", got, wantLog)
+	}
+	if got := a.ParseLogData(topics[:2], "0x"+hex.EncodeToString(data)); got != nil {
+		t.Errorf("ParseLogData() missing topic = %+v, want nil", got)
+	}
+	if got := a.ParseLogData([]string{"0x" + hex.EncodeToString(make([]byte, 32))}, "0x"); got != nil {
+		t.Errorf("ParseLogData() unknown event = %+v, want nil", got)
+	}
+}
@@ -0,0 +1,107 @@
+package eth
+
+import (
+	"strings"
+
+	"github.com/juju/errors"
+	"github.com/trezor/blockbook/bchain"
+)
+
+// ContractLogFilter selects the logs of the contract which are stored in the contract log index,
+// the logs are selected by their first topic (topic0), if Topics is empty, all logs of the contract with a topic are selected
+type ContractLogFilter struct {
+	Contract string   `json:"contract"`
+	Topics   []string `json:"topics,omitempty"`
+}
+
+func normalizeLogHex(s string) string {
+	s = strings.ToLower(s)
+	if has0xPrefix(s) {
+		s = s[2:]
+	}
+	return s
+}
+
+// SetContractLogFilters sets the filters selecting the indexed contract logs
+func (p *EthereumParser) SetContractLogFilters(filters []ContractLogFilter) error {
+	if len(filters) == 0 {
+		p.contractLogFilters = nil
+		return nil
+	}
+	m := make(map[string]map[string]struct{}, len(filters))
+	for i := range filters {
+		f := &filters[i]
+		contract := normalizeLogHex(f.Contract)
+		if len(contract) != EthereumTypeAddressDescriptorLen*2 {
+			return errors.Errorf("Invalid contract %v in contract log filter", f.Contract)
+		}
+		topics, found := m[contract]
+		if !found || topics != nil {
+			if len(f.Topics) == 0 {
+				// all topics of the contract
+				topics = nil
+			} else {
+				if topics == nil {
+					topics = make(map[string]struct{})
+				}
+				for _, t := range f.Topics {
+					topic := normalizeLogHex(t)
+					if len(topic) != 64 {
+						return errors.Errorf("Invalid topic %v of contract %v in contract log filter", t, f.Contract)
+					}
+					topics[topic] = struct{}{}
+				}
+			}
+			m[contract] = topics
+		}
+	}
+	p.contractLogFilters = m
+	return nil
+}
+
+// UseContractLogs returns true if the contract log filters are configured
+func (p *EthereumParser) UseContractLogs() bool {
+	return len(p.contractLogFilters) > 0
+}
+
+func (p *EthereumParser) isContractLogSelected(l *bchain.RpcLog) bool {
+	if len(l.Topics) == 0 {
+		return false
+	}
+	topics, found := p.contractLogFilters[normalizeLogHex(l.Address)]
+	if !found {
+		return false
+	}
+	if topics == nil {
+		return true
+	}
+	_, found = topics[normalizeLogHex(l.Topics[0])]
+	return found
+}
+
+// EthereumTypeGetContractLogsFromBlock returns the logs of the block selected by the contract log filters,
+// the index of the log is counted from the first log of the block in the same way as the logIndex of the backend
+func (p *EthereumParser) EthereumTypeGetContractLogsFromBlock(block *bchain.Block) (bchain.ContractLogs, error) {
+	if len(p.contractLogFilters) == 0 {
+		return nil, nil
+	}
+	var r bchain.ContractLogs
+	var logIndex uint32
+	for i := range block.Txs {
+		csd, ok := block.Txs[i].CoinSpecificData.(bchain.EthereumSpecificData)
+		if !ok || csd.Receipt == nil {
+			continue
+		}
+		for _, l := range csd.Receipt.Logs {
+			if p.isContractLogSelected(l) {
+				r = append(r, &bchain.ContractLog{
+					TxIndex:  i,
+					LogIndex: logIndex,
+					Log:      l,
+				})
+			}
+			logIndex++
+		}
+	}
+	return r, nil
+}
@@ -0,0 +1,101 @@
+//go:build unittest
+
+package eth
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/trezor/blockbook/bchain"
+)
+
+func Test_EthereumTypeGetContractLogsFromBlock(t *testing.T) {
+	const (
+		contract1 = "0x76a45e8976499ab9ae223cc584019341d5a84e96"
+		contract2 = "0x5689B918D34C038901870105A6C7FC24744D31EB"
+		contract3 = "0x4af4114f73d1c1c903ac9e0361b379d1291808a2"
+		transfer  = "0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"
+		approval  = "0x8c5be1e5ebec7d5bd14f71427d1e84f3dd0314c0f7b2291e5b200ac8c7c3b925"
+	)
+	logs := []*bchain.RpcLog{
+		{Address: contract1, Topics: []string{transfer}, Data: "0x01"},
+		{Address: contract1, Topics: []string{approval}, Data: "0x02"},
+		{Address: "0x5689b918d34c038901870105a6c7fc24744d31eb", Topics: []string{approval}},
+		{Address: contract3, Topics: []string{transfer}},
+		{Address: contract2}, // anonymous log without topics
+	}
+	block := &bchain.Block{
+		Txs: []bchain.Tx{
+			{CoinSpecificData: bchain.EthereumSpecificData{Receipt: &bchain.RpcReceipt{Logs: logs[:2]}}},
+			{CoinSpecificData: bchain.EthereumSpecificData{}},
+			{CoinSpecificData: bchain.EthereumSpecificData{Receipt: &bchain.RpcReceipt{Logs: logs[2:]}}},
+		},
+	}
+	tests := []struct {
+		name    string
+		filters []ContractLogFilter
+		want    bchain.ContractLogs
+		wantErr bool
+	}{
+		{
+			name: "no filters",
+		},
+		{
+			name: "topics of contract",
+			filters: []ContractLogFilter{
+				{Contract: contract1, Topics: []string{"0xDDF252AD1BE2C89B69C2B068FC378DAA952BA7F163C4A11628F55A4DF523B3EF"}},
+				{Contract: contract2, Topics: []string{transfer}},
+				{Contract: contract2, Topics: []string{approval}},
+			},
+			want: bchain.ContractLogs{
+				{TxIndex: 0, LogIndex: 0, Log: logs[0]},
+				{TxIndex: 2, LogIndex: 2, Log: logs[2]},
+			},
+		},
+		{
+			name: "all topics of contract",
+			filters: []ContractLogFilter{
+				{Contract: contract1, Topics: []string{approval}},
+				{Contract: contract1},
+				{Contract: contract2},
+			},
+			want: bchain.ContractLogs{
+				{TxIndex: 0, LogIndex: 0, Log: logs[0]},
+				{TxIndex: 0, LogIndex: 1, Log: logs[1]},
+				{TxIndex: 2, LogIndex: 2, Log: logs[2]},
+			},
+		},
+		{
+			name:    "invalid contract",
+			filters: []ContractLogFilter{{Contract: "0x1234"}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid topic",
+			filters: []ContractLogFilter{{Contract: contract1, Topics: []string{"0x1234"}}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewEthereumParser(1, false)
+			err := p.SetContractLogFilters(tt.filters)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SetContractLogFilters() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if p.UseContractLogs() != (len(tt.filters) > 0) {
+				t.Errorf("UseContractLogs() = %v", p.UseContractLogs())
+			}
+			got, err := p.EthereumTypeGetContractLogsFromBlock(block)
+			if err != nil {
+				t.Fatalf("EthereumTypeGetContractLogsFromBlock() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("EthereumTypeGetContractLogsFromBlock() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
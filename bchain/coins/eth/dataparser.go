@@ -239,9 +239,33 @@ func tryParseParams(data string, params []string, parsedParams []abi.Type) []bch
 	return parsed
 }
 
+// prepareSignature sets DecamelName and Function and parses parameter types from string to abi.Type,
+// if not yet done, the signatures are stored in cache
+func prepareSignature(s *bchain.FourByteSignature) {
+	if s.DecamelName == "" {
+		s.DecamelName = decamel(s.Name)
+		s.Function = s.Name + "(" + strings.Join(s.Parameters, ", ") + ")"
+		s.ParsedParameters = make([]abi.Type, len(s.Parameters))
+		for j := range s.Parameters {
+			var t abi.Type
+			if len(s.Parameters[j]) > 0 && s.Parameters[j][0] == '(' {
+				// Tuple type is not supported for now
+				t = abi.Type{T: abi.TupleTy}
+			} else {
+				var err error
+				t, err = abi.NewType(s.Parameters[j], "", nil)
+				if err != nil {
+					t = abi.Type{T: ErrorTy}
+				}
+			}
+			s.ParsedParameters[j] = t
+		}
+	}
+}
+
 // ParseInputData tries to parse transaction input data from known FourByteSignatures
 // as there may be multiple signatures for the same four bytes, it tries to match the input to the known parameters
-// it does not parse tuples for now
+// it does not parse tuples, they are decoded only using the ContractABI of the contract
 func ParseInputData(signatures *[]bchain.FourByteSignature, data string) *bchain.EthereumParsedInputData {
 	if len(data) <= 2 { // data is empty or 0x
 		return &bchain.EthereumParsedInputData{Name: "Transfer"}
@@ -262,27 +286,7 @@ func ParseInputData(signatures *[]bchain.FourByteSignature, data string) *bchain
 		data = data[10:]
 		for i := range *signatures {
 			s := &(*signatures)[i]
-			// if not yet done, set DecamelName and Function and parse parameter types from string to abi.Type
-			// the signatures are stored in cache
-			if s.DecamelName == "" {
-				s.DecamelName = decamel(s.Name)
-				s.Function = s.Name + "(" + strings.Join(s.Parameters, ", ") + ")"
-				s.ParsedParameters = make([]abi.Type, len(s.Parameters))
-				for j := range s.Parameters {
-					var t abi.Type
-					if len(s.Parameters[j]) > 0 && s.Parameters[j][0] == '(' {
-						// Tuple type is not supported for now
-						t = abi.Type{T: abi.TupleTy}
-					} else {
-						var err error
-						t, err = abi.NewType(s.Parameters[j], "", nil)
-						if err != nil {
-							t = abi.Type{T: ErrorTy}
-						}
-					}
-					s.ParsedParameters[j] = t
-				}
-			}
+			prepareSignature(s)
 			parsedParams := tryParseParams(data, s.Parameters, s.ParsedParameters)
 			if parsedParams != nil {
 				parsed.Name = s.DecamelName
@@ -295,6 +299,133 @@ func ParseInputData(signatures *[]bchain.FourByteSignature, data string) *bchain
 	return &parsed
 }
 
+// parseTopicParam returns the value of the indexed event parameter from the topic, the values of the dynamic types
+// are not stored in the topic, only their keccak hash, which is returned
+func parseTopicParam(topic string, t *abi.Type) ([]string, bool) {
+	if len(topic) != 64 {
+		return nil, false
+	}
+	switch t.T {
+	case abi.IntTy, abi.UintTy, abi.BoolTy, abi.FixedBytesTy:
+		values, _, ok := processParam(topic, 0, 0, t, make([]bool, 1))
+		return values, ok
+	case abi.AddressTy:
+		// the address is padded by zeros
+		if strings.TrimLeft(topic[:24], "0") != "" {
+			return nil, false
+		}
+		values, _, ok := processParam(topic, 0, 0, t, make([]bool, 1))
+		return values, ok
+	case abi.StringTy, abi.BytesTy, abi.SliceTy, abi.ArrayTy, abi.TupleTy:
+		return []string{"0x" + topic}, true
+	}
+	return nil, false
+}
+
+// tryParseLogParams tries to parse the event parameters with the parameters at the indexes taken from the topics
+func tryParseLogParams(topics []string, data string, params []string, parsedParams []abi.Type, indexes []int) []bchain.EthereumParsedLogParam {
+	parsed := make([]bchain.EthereumParsedLogParam, len(params))
+	var dataParams []string
+	var dataParsedParams []abi.Type
+	var dataIndexes []int
+	next := 0
+	for i := range params {
+		if next < len(indexes) && indexes[next] == i {
+			values, ok := parseTopicParam(strings.ToLower(topics[next][2:]), &parsedParams[i])
+			if !ok {
+				return nil
+			}
+			parsed[i] = bchain.EthereumParsedLogParam{Type: params[i], Indexed: true, Values: values}
+			next++
+		} else {
+			dataParams = append(dataParams, params[i])
+			dataParsedParams = append(dataParsedParams, parsedParams[i])
+			dataIndexes = append(dataIndexes, i)
+		}
+	}
+	dataParsed := tryParseParams(data, dataParams, dataParsedParams)
+	if dataParsed == nil {
+		return nil
+	}
+	for j, i := range dataIndexes {
+		parsed[i] = bchain.EthereumParsedLogParam{Type: dataParsed[j].Type, Values: dataParsed[j].Values}
+	}
+	return parsed
+}
+
+// ParseLogData tries to decode the log from known event signatures of its first topic. The event signatures do not specify,
+// which parameters are indexed; all assignments of the other topics to the parameters are tried and from the assignments,
+// for which the topics and the data of the log are fully parsed, the one with the most indexed addresses is used,
+// as the addresses are the usually indexed parameters; the first such assignment in the order of the parameters wins.
+// It does not parse tuples, they are decoded only using the ContractABI of the contract
+func ParseLogData(signatures *[]bchain.FourByteSignature, topics []string, data string) *bchain.EthereumParsedLogData {
+	if signatures == nil || len(topics) == 0 {
+		return nil
+	}
+	for _, t := range topics {
+		if len(t) != 66 || !has0xPrefix(t) {
+			return nil
+		}
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			glog.Error("ParseLogData recovered from panic: ", r, ", ", topics, ", ", data, ",signatures ", signatures)
+			debug.PrintStack()
+		}
+	}()
+	if has0xPrefix(data) {
+		data = data[2:]
+	}
+	topics = topics[1:]
+	for i := range *signatures {
+		s := &(*signatures)[i]
+		prepareSignature(s)
+		if len(s.Parameters) < len(topics) {
+			continue
+		}
+		// indexes of the indexed parameters, starting with the first combination 0, 1, ...
+		indexes := make([]int, len(topics))
+		for j := range indexes {
+			indexes[j] = j
+		}
+		var best []bchain.EthereumParsedLogParam
+		bestAddresses := -1
+		for {
+			if parsedParams := tryParseLogParams(topics, data, s.Parameters, s.ParsedParameters, indexes); parsedParams != nil {
+				addresses := 0
+				for _, j := range indexes {
+					if s.ParsedParameters[j].T == abi.AddressTy {
+						addresses++
+					}
+				}
+				if addresses > bestAddresses {
+					best, bestAddresses = parsedParams, addresses
+				}
+			}
+			// next combination of the indexes
+			j := len(indexes) - 1
+			for j >= 0 && indexes[j] == len(s.Parameters)-len(indexes)+j {
+				j--
+			}
+			if j < 0 {
+				break
+			}
+			indexes[j]++
+			for k := j + 1; k < len(indexes); k++ {
+				indexes[k] = indexes[k-1] + 1
+			}
+		}
+		if best != nil {
+			return &bchain.EthereumParsedLogData{
+				Name:   s.DecamelName,
+				Event:  s.Function,
+				Params: best,
+			}
+		}
+	}
+	return nil
+}
+
 // getEnsRecord processes transaction log entry and tries to parse ENS record from it
 func getEnsRecord(l *rpcLogWithTxHash) *bchain.AddressAliasRecord {
 	if len(l.Topics) == 3 && l.Topics[0] == nameRegisteredEventSignature && len(l.Data) >= 322 {
@@ -428,6 +428,123 @@ func TestParseInputData(t *testing.T) {
 	}
 }
 
+func TestParseLogData(t *testing.T) {
+	signatures := []bchain.FourByteSignature{
+		{
+			Name:       "Transfer",
+			Parameters: []string{"address", "address", "uint256"},
+		},
+		{
+			Name:       "Swap",
+			Parameters: []string{"address", "uint256", "uint256", "uint256", "uint256", "address"},
+		},
+		{
+			Name:       "NameRegistered",
+			Parameters: []string{"string", "address"},
+		},
+	}
+	tests := []struct {
+		name       string
+		signatures *[]bchain.FourByteSignature
+		topics     []string
+		data       string
+		want       *bchain.EthereumParsedLogData
+	}{
+		{
+			name:       "ERC20 Transfer",
+			signatures: &[]bchain.FourByteSignature{signatures[0]},
+			topics: []string{
+				"0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef",
+				"0x0000000000000000000000002aacf811ac1a60081ea39f7783c0d26c500871a8",
+				"0x000000000000000000000000e9a5216ff992cfa01594d43501a56e12769eb9d2",
+			},
+			data: "0x0000000000000000000000000000000000000000000000000000000000000123",
+			want: &bchain.EthereumParsedLogData{
+				Name:  "Transfer",
+				Event: "Transfer(address, address, uint256)",
+				Params: []bchain.EthereumParsedLogParam{
+					{Type: "address", Indexed: true, Values: []string{"0x2aaCF811aC1A60081EA39F7783c0D26c500871a8"}},
+					{Type: "address", Indexed: true, Values: []string{"0xe9a5216fF992Cfa01594d43501a56E12769eB9d2"}},
+					{Type: "uint256", Values: []string{"291"}},
+				},
+			},
+		},
+		{
+			name:       "ERC721 Transfer does not match the signature",
+			signatures: &[]bchain.FourByteSignature{signatures[0]},
+			topics: []string{
+				"0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef",
+				"0x0000000000000000000000002aacf811ac1a60081ea39f7783c0d26c500871a8",
+				"0x000000000000000000000000e9a5216ff992cfa01594d43501a56e12769eb9d2",
+				"0x0000000000000000000000000000000000000000000000000000000000000123",
+			},
+			data: "0x0000000000000000000000000000000000000000000000000000000000000123",
+			want: nil,
+		},
+		{
+			name:       "Swap with indexed addresses at the start and at the end",
+			signatures: &[]bchain.FourByteSignature{signatures[1]},
+			topics: []string{
+				"0xd78ad95fa46c994b6551d0da85fc275fe613ce37657fb8d5e3d130840159d822",
+				"0x0000000000000000000000007a250d5630b4cf539739df2c5dacb4c659f2488d",
+				"0x000000000000000000000000e9a5216ff992cfa01594d43501a56e12769eb9d2",
+			},
+			data: "0x" +
+				"0000000000000000000000000000000000000000000000000de0b6b3a7640000" +
+				"0000000000000000000000000000000000000000000000000000000000000000" +
+				"0000000000000000000000000000000000000000000000000000000000000000" +
+				"00000000000000000000000000000000000000000000000000000000000f4240",
+			want: &bchain.EthereumParsedLogData{
+				Name:  "Swap",
+				Event: "Swap(address, uint256, uint256, uint256, uint256, address)",
+				Params: []bchain.EthereumParsedLogParam{
+					{Type: "address", Indexed: true, Values: []string{"0x7a250d5630B4cF539739dF2C5dAcb4c659F2488D"}},
+					{Type: "uint256", Values: []string{"1000000000000000000"}},
+					{Type: "uint256", Values: []string{"0"}},
+					{Type: "uint256", Values: []string{"0"}},
+					{Type: "uint256", Values: []string{"1000000"}},
+					{Type: "address", Indexed: true, Values: []string{"0xe9a5216fF992Cfa01594d43501a56E12769eB9d2"}},
+				},
+			},
+		},
+		{
+			name:       "indexed string is returned as hash",
+			signatures: &signatures,
+			topics: []string{
+				"0x5b03bfed1c14a02bdeceb5fa582eb1a5765fc0bc64ca0e6af4c20afc9487f081",
+				"0x40ce2aa8cd9ee9fef4bf3a68abab7fbcceb6bac89370518caf6a602cefe836bd",
+			},
+			data: "0x0000000000000000000000002c630b16aa53ae0189880e15c23323688acb607c",
+			want: &bchain.EthereumParsedLogData{
+				Name:  "Name Registered",
+				Event: "NameRegistered(string, address)",
+				Params: []bchain.EthereumParsedLogParam{
+					{Type: "string", Indexed: true, Values: []string{"0x40ce2aa8cd9ee9fef4bf3a68abab7fbcceb6bac89370518caf6a602cefe836bd"}},
+					{Type: "address", Values: []string{"0x2C630b16Aa53ae0189880e15C23323688acb607c"}},
+				},
+			},
+		},
+		{
+			name:       "no signatures",
+			signatures: nil,
+			topics:     []string{"0xddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef"},
+			want:       nil,
+		},
+		{
+			name:       "anonymous log",
+			signatures: &signatures,
+			want:       nil,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ParseLogData(tt.signatures, tt.topics, tt.data); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseLogData() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
 func Test_getEnsRecord(t *testing.T) {
 	tests := []struct {
 		name string
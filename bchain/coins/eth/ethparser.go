@@ -25,11 +25,13 @@ const EtherAmountDecimalPoint = 18
 // EthereumParser handle
 type EthereumParser struct {
 	*bchain.BaseParser
+	// contract -> set of selected topics, nil set selects all topics of the contract
+	contractLogFilters map[string]map[string]struct{}
 }
 
 // NewEthereumParser returns new EthereumParser instance
 func NewEthereumParser(b int, addressAliases bool) *EthereumParser {
-	return &EthereumParser{&bchain.BaseParser{
+	return &EthereumParser{BaseParser: &bchain.BaseParser{
 		BlockAddressesToKeep: b,
 		AmountDecimalPoint:   EtherAmountDecimalPoint,
 		AddressAliases:       addressAliases,
@@ -44,6 +46,8 @@ type rpcHeader struct {
 	Time       string `json:"timestamp"`
 	Size       string `json:"size"`
 	Nonce      string `json:"nonce"`
+	// empty for the blocks before EIP-1559
+	BaseFeePerGas string `json:"baseFeePerGas"`
 }
 
 type rpcLogWithTxHash struct {
@@ -66,7 +70,7 @@ func ethNumber(n string) (int64, error) {
 	return 0, errors.Errorf("Not a number: '%v'", n)
 }
 
-func (p *EthereumParser) ethTxToTx(tx *bchain.RpcTransaction, receipt *bchain.RpcReceipt, internalData *bchain.EthereumInternalData, blocktime int64, confirmations uint32, fixEIP55 bool) (*bchain.Tx, error) {
+func (p *EthereumParser) ethTxToTx(tx *bchain.RpcTransaction, receipt *bchain.RpcReceipt, internalData *bchain.EthereumInternalData, baseFeePerGas string, blocktime int64, confirmations uint32, fixEIP55 bool) (*bchain.Tx, error) {
 	txid := tx.Hash
 	var (
 		fa, ta []string
@@ -106,9 +110,10 @@ func (p *EthereumParser) ethTxToTx(tx *bchain.RpcTransaction, receipt *bchain.Rp
 		}
 	}
 	ct := bchain.EthereumSpecificData{
-		Tx:           tx,
-		InternalData: internalData,
-		Receipt:      receipt,
+		Tx:            tx,
+		InternalData:  internalData,
+		Receipt:       receipt,
+		BaseFeePerGas: baseFeePerGas,
 	}
 	vs, err := hexutil.DecodeBig(tx.Value)
 	if err != nil {
@@ -292,11 +297,32 @@ func (p *EthereumParser) PackTx(tx *bchain.Tx, height uint32, blockTime int64) (
 	if pt.Tx.Value, err = hexDecodeBig(r.Tx.Value); err != nil {
 		return nil, errors.Annotatef(err, "Value %v", r.Tx.Value)
 	}
+	// the EIP-1559 fields are optional, the max fee is never zero and marks the presence of the fields
+	if r.Tx.MaxFeePerGas != "" {
+		if pt.Tx.MaxFeePerGas, err = hexDecodeBig(r.Tx.MaxFeePerGas); err != nil {
+			return nil, errors.Annotatef(err, "MaxFeePerGas %v", r.Tx.MaxFeePerGas)
+		}
+		if r.Tx.MaxPriorityFeePerGas != "" {
+			if pt.Tx.MaxPriorityFeePerGas, err = hexDecodeBig(r.Tx.MaxPriorityFeePerGas); err != nil {
+				return nil, errors.Annotatef(err, "MaxPriorityFeePerGas %v", r.Tx.MaxPriorityFeePerGas)
+			}
+		}
+	}
+	if r.BaseFeePerGas != "" {
+		if pt.BaseFeePerGas, err = hexDecodeBig(r.BaseFeePerGas); err != nil {
+			return nil, errors.Annotatef(err, "BaseFeePerGas %v", r.BaseFeePerGas)
+		}
+	}
 	if r.Receipt != nil {
 		pt.Receipt = &ProtoCompleteTransaction_ReceiptType{}
 		if pt.Receipt.GasUsed, err = hexDecodeBig(r.Receipt.GasUsed); err != nil {
 			return nil, errors.Annotatef(err, "GasUsed %v", r.Receipt.GasUsed)
 		}
+		if r.Receipt.EffectiveGasPrice != "" {
+			if pt.Receipt.EffectiveGasPrice, err = hexDecodeBig(r.Receipt.EffectiveGasPrice); err != nil {
+				return nil, errors.Annotatef(err, "EffectiveGasPrice %v", r.Receipt.EffectiveGasPrice)
+			}
+		}
 		if r.Receipt.Status != "" {
 			if pt.Receipt.Status, err = hexDecodeBig(r.Receipt.Status); err != nil {
 				return nil, errors.Annotatef(err, "Status %v", r.Receipt.Status)
@@ -357,6 +383,14 @@ func (p *EthereumParser) UnpackTx(buf []byte) (*bchain.Tx, uint32, error) {
 		TransactionIndex: hexutil.EncodeUint64(uint64(pt.Tx.TransactionIndex)),
 		Value:            hexEncodeBig(pt.Tx.Value),
 	}
+	if len(pt.Tx.MaxFeePerGas) > 0 {
+		rt.MaxFeePerGas = hexEncodeBig(pt.Tx.MaxFeePerGas)
+		rt.MaxPriorityFeePerGas = hexEncodeBig(pt.Tx.MaxPriorityFeePerGas)
+	}
+	var baseFeePerGas string
+	if len(pt.BaseFeePerGas) > 0 {
+		baseFeePerGas = hexEncodeBig(pt.BaseFeePerGas)
+	}
 	var rr *bchain.RpcReceipt
 	if pt.Receipt != nil {
 		logs := make([]*bchain.RpcLog, len(pt.Receipt.Log))
@@ -381,9 +415,12 @@ func (p *EthereumParser) UnpackTx(buf []byte) (*bchain.Tx, uint32, error) {
 			Status:  status,
 			Logs:    logs,
 		}
+		if len(pt.Receipt.EffectiveGasPrice) > 0 {
+			rr.EffectiveGasPrice = hexEncodeBig(pt.Receipt.EffectiveGasPrice)
+		}
 	}
 	// TODO handle internal transactions
-	tx, err := p.ethTxToTx(&rt, rr, nil, int64(pt.BlockTime), 0, false)
+	tx, err := p.ethTxToTx(&rt, rr, nil, baseFeePerGas, int64(pt.BlockTime), 0, false)
 	if err != nil {
 		return nil, 0, err
 	}
@@ -459,6 +496,16 @@ func (p *EthereumParser) EthereumTypeGetTokenTransfersFromTx(tx *bchain.Tx) (bch
 	return r, nil
 }
 
+// EthereumTypeGetTokenApprovalsFromTx returns token approvals from the logs of bchain.Tx,
+// the transactions without receipt (mempool transactions) do not have approvals
+func (p *EthereumParser) EthereumTypeGetTokenApprovalsFromTx(tx *bchain.Tx) (bchain.TokenApprovals, error) {
+	csd, ok := tx.CoinSpecificData.(bchain.EthereumSpecificData)
+	if !ok || csd.Receipt == nil {
+		return nil, nil
+	}
+	return contractGetApprovalsFromLog(csd.Receipt.Logs)
+}
+
 // FormatAddressAlias adds .eth to a name alias
 func (p *EthereumParser) FormatAddressAlias(address string, name string) string {
 	return name + ".eth"
@@ -483,6 +530,19 @@ type EthereumTxData struct {
 	GasUsed  *big.Int `json:"gasused"`
 	GasPrice *big.Int `json:"gasprice"`
 	Data     string   `json:"data"`
+	// EIP-1559 fields, nil if not known
+	MaxFeePerGas         *big.Int `json:"maxfeepergas,omitempty"`
+	MaxPriorityFeePerGas *big.Int `json:"maxpriorityfeepergas,omitempty"`
+	EffectiveGasPrice    *big.Int `json:"effectivegasprice,omitempty"`
+	BaseFeePerGas        *big.Int `json:"basefeepergas,omitempty"`
+}
+
+// PaidGasPrice returns the price per gas paid by the transaction, the effective gas price if known
+func (etd *EthereumTxData) PaidGasPrice() *big.Int {
+	if etd.EffectiveGasPrice != nil {
+		return etd.EffectiveGasPrice
+	}
+	return etd.GasPrice
 }
 
 // GetEthereumTxData returns EthereumTxData from bchain.Tx
@@ -500,6 +560,13 @@ func GetEthereumTxDataFromSpecificData(coinSpecificData interface{}) *EthereumTx
 			etd.GasLimit, _ = hexutil.DecodeBig(csd.Tx.GasLimit)
 			etd.GasPrice, _ = hexutil.DecodeBig(csd.Tx.GasPrice)
 			etd.Data = csd.Tx.Payload
+			if csd.Tx.MaxFeePerGas != "" {
+				etd.MaxFeePerGas, _ = hexutil.DecodeBig(csd.Tx.MaxFeePerGas)
+				etd.MaxPriorityFeePerGas, _ = hexutil.DecodeBig(csd.Tx.MaxPriorityFeePerGas)
+			}
+		}
+		if csd.BaseFeePerGas != "" {
+			etd.BaseFeePerGas, _ = hexutil.DecodeBig(csd.BaseFeePerGas)
 		}
 		if csd.Receipt != nil {
 			switch csd.Receipt.Status {
@@ -511,6 +578,16 @@ func GetEthereumTxDataFromSpecificData(coinSpecificData interface{}) *EthereumTx
 				etd.Status = TxStatusFailure
 			}
 			etd.GasUsed, _ = hexutil.DecodeBig(csd.Receipt.GasUsed)
+			if csd.Receipt.EffectiveGasPrice != "" {
+				etd.EffectiveGasPrice, _ = hexutil.DecodeBig(csd.Receipt.EffectiveGasPrice)
+			}
+		}
+		// the effective gas price of the included transaction is min(maxFeePerGas, baseFeePerGas+maxPriorityFeePerGas)
+		if etd.EffectiveGasPrice == nil && etd.MaxFeePerGas != nil && etd.MaxPriorityFeePerGas != nil && etd.BaseFeePerGas != nil {
+			etd.EffectiveGasPrice = new(big.Int).Add(etd.BaseFeePerGas, etd.MaxPriorityFeePerGas)
+			if etd.EffectiveGasPrice.Cmp(etd.MaxFeePerGas) > 0 {
+				etd.EffectiveGasPrice.Set(etd.MaxFeePerGas)
+			}
 		}
 	}
 	return &etd
@@ -494,3 +494,50 @@ func TestEthereumParser_PackInternalTransactionError_UnpackInternalTransactionEr
 		})
 	}
 }
+
+func TestEthereumParser_PackTx_UnpackTx_Eip1559(t *testing.T) {
+	tx := testTx1
+	csd := testTx1.CoinSpecificData.(bchain.EthereumSpecificData)
+	rt := *csd.Tx
+	rt.MaxFeePerGas = "0x4a817c800"
+	rt.MaxPriorityFeePerGas = "0x0"
+	rr := *csd.Receipt
+	rr.EffectiveGasPrice = "0x3b9aca00"
+	csd.Tx = &rt
+	csd.Receipt = &rr
+	csd.BaseFeePerGas = "0x3b9aca00"
+	tx.CoinSpecificData = csd
+	p := NewEthereumParser(1, false)
+	b, err := p.PackTx(&tx, 4321000, 1534858022)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, _, err := p.UnpackTx(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gs := got.CoinSpecificData.(bchain.EthereumSpecificData)
+	if !reflect.DeepEqual(gs.Tx, csd.Tx) {
+		t.Errorf("EthereumParser.UnpackTx() gs.Tx got = %+v, want %+v", gs.Tx, csd.Tx)
+	}
+	if !reflect.DeepEqual(gs.Receipt, csd.Receipt) {
+		t.Errorf("EthereumParser.UnpackTx() gs.Receipt got = %+v, want %+v", gs.Receipt, csd.Receipt)
+	}
+	if gs.BaseFeePerGas != csd.BaseFeePerGas {
+		t.Errorf("EthereumParser.UnpackTx() gs.BaseFeePerGas got = %v, want %v", gs.BaseFeePerGas, csd.BaseFeePerGas)
+	}
+
+	// without the receipt, the effective gas price is derived from the base fee and the priority fee
+	gs.Receipt = nil
+	gs.Tx.MaxPriorityFeePerGas = "0x77359400"
+	got.CoinSpecificData = gs
+	etd := GetEthereumTxData(got)
+	if etd.EffectiveGasPrice == nil || etd.EffectiveGasPrice.Cmp(big.NewInt(3000000000)) != 0 {
+		t.Errorf("GetEthereumTxData() EffectiveGasPrice = %v, want 3000000000", etd.EffectiveGasPrice)
+	}
+	gs.Tx.MaxPriorityFeePerGas = "0x12a05f2000"
+	etd = GetEthereumTxData(got)
+	if etd.EffectiveGasPrice == nil || etd.EffectiveGasPrice.Cmp(big.NewInt(20000000000)) != 0 {
+		t.Errorf("GetEthereumTxData() EffectiveGasPrice = %v, want 20000000000", etd.EffectiveGasPrice)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"io/ioutil"
 	"math/big"
 	"net/http"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -49,6 +50,9 @@ type Configuration struct {
 	ProcessInternalTransactions     bool   `json:"processInternalTransactions"`
 	ProcessZeroInternalTransactions bool   `json:"processZeroInternalTransactions"`
 	ConsensusNodeVersionURL         string `json:"consensusNodeVersion"`
+
+	// ContractLogs selects the logs stored in the contract log index
+	ContractLogs []ContractLogFilter `json:"contract_logs,omitempty"`
 }
 
 // EthereumRPC is an interface to JSON-RPC eth service.
@@ -101,6 +105,9 @@ func NewEthereumRPC(config json.RawMessage, pushHandler func(bchain.Notification
 
 	// always create parser
 	s.Parser = NewEthereumParser(c.BlockAddressesToKeep, c.AddressAliases)
+	if err = s.Parser.SetContractLogFilters(c.ContractLogs); err != nil {
+		return nil, errors.Annotatef(err, "Invalid configuration file")
+	}
 	s.Timeout = time.Duration(c.RPCTimeout) * time.Second
 	s.PushHandler = pushHandler
 
@@ -569,7 +576,6 @@ func (b *EthereumRPC) processEventsForBlock(blockNumber string) (map[string][]*b
 	ctx, cancel := context.WithTimeout(context.Background(), b.Timeout)
 	defer cancel()
 	var logs []rpcLogWithTxHash
-	var ensRecords []bchain.AddressAliasRecord
 	err := b.RPC.CallContext(ctx, &logs, "eth_getLogs", map[string]interface{}{
 		"fromBlock": blockNumber,
 		"toBlock":   blockNumber,
@@ -577,6 +583,30 @@ func (b *EthereumRPC) processEventsForBlock(blockNumber string) (map[string][]*b
 	if err != nil {
 		return nil, nil, errors.Annotatef(err, "eth_getLogs blockNumber %v", blockNumber)
 	}
+	r, ensRecords := processEvents(logs)
+	return r, ensRecords, nil
+}
+
+// EthereumTypeGetAddressAliasRecords returns the ENS records registered in the blocks in the range lower-higher
+func (b *EthereumRPC) EthereumTypeGetAddressAliasRecords(lower, higher uint32) ([]bchain.AddressAliasRecord, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), b.Timeout)
+	defer cancel()
+	var logs []rpcLogWithTxHash
+	err := b.RPC.CallContext(ctx, &logs, "eth_getLogs", map[string]interface{}{
+		"fromBlock": fmt.Sprintf("%#x", lower),
+		"toBlock":   fmt.Sprintf("%#x", higher),
+		"topics":    []interface{}{nameRegisteredEventSignature},
+	})
+	if err != nil {
+		return nil, errors.Annotatef(err, "eth_getLogs blocks %v-%v", lower, higher)
+	}
+	_, ensRecords := processEvents(logs)
+	return ensRecords, nil
+}
+
+// processEvents groups the logs by transactions and extracts the ENS records
+func processEvents(logs []rpcLogWithTxHash) (map[string][]*bchain.RpcLog, []bchain.AddressAliasRecord) {
+	var ensRecords []bchain.AddressAliasRecord
 	r := make(map[string][]*bchain.RpcLog)
 	for i := range logs {
 		l := &logs[i]
@@ -586,7 +616,7 @@ func (b *EthereumRPC) processEventsForBlock(blockNumber string) (map[string][]*b
 			ensRecords = append(ensRecords, *ens)
 		}
 	}
-	return r, ensRecords, nil
+	return r, ensRecords
 }
 
 type rpcCallTrace struct {
@@ -653,77 +683,85 @@ func (b *EthereumRPC) processCallTrace(call *rpcCallTrace, d *bchain.EthereumInt
 	return contracts
 }
 
+var traceBlockOptions = map[string]interface{}{"tracer": "callTracer"}
+
 // getInternalDataForBlock fetches debug trace using callTracer, extracts internal transfers and creations and destructions of contracts
 func (b *EthereumRPC) getInternalDataForBlock(blockHash string, blockHeight uint32, transactions []bchain.RpcTransaction) ([]bchain.EthereumInternalData, []bchain.ContractInfo, error) {
+	if !ProcessInternalTransactions {
+		return make([]bchain.EthereumInternalData, len(transactions)), make([]bchain.ContractInfo, 0), nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), b.Timeout)
+	defer cancel()
+	var trace []rpcTraceResult
+	err := b.RPC.CallContext(ctx, &trace, "debug_traceBlockByHash", blockHash, traceBlockOptions)
+	if err != nil {
+		glog.Error("debug_traceBlockByHash block ", blockHash, ", error ", err)
+		return make([]bchain.EthereumInternalData, len(transactions)), make([]bchain.ContractInfo, 0), err
+	}
+	return b.processInternalDataTrace(blockHash, blockHeight, transactions, trace)
+}
+
+// processInternalDataTrace extracts internal transfers and creations and destructions of contracts from the callTracer trace of the block
+func (b *EthereumRPC) processInternalDataTrace(blockHash string, blockHeight uint32, transactions []bchain.RpcTransaction, trace []rpcTraceResult) ([]bchain.EthereumInternalData, []bchain.ContractInfo, error) {
 	data := make([]bchain.EthereumInternalData, len(transactions))
 	contracts := make([]bchain.ContractInfo, 0)
-	if ProcessInternalTransactions {
-		ctx, cancel := context.WithTimeout(context.Background(), b.Timeout)
-		defer cancel()
-		var trace []rpcTraceResult
-		err := b.RPC.CallContext(ctx, &trace, "debug_traceBlockByHash", blockHash, map[string]interface{}{"tracer": "callTracer"})
-		if err != nil {
-			glog.Error("debug_traceBlockByHash block ", blockHash, ", error ", err)
-			return data, contracts, err
-		}
-		if len(trace) != len(data) {
-			if len(trace) < len(data) {
-				for i := range transactions {
-					tx := &transactions[i]
-					// bridging transactions in Polygon do not create trace and cause mismatch between the trace size and block size, it is necessary to adjust the trace size
-					// bridging transaction that from and to zero address
-					if tx.To == "0x0000000000000000000000000000000000000000" && tx.From == "0x0000000000000000000000000000000000000000" {
-						if i >= len(trace) {
-							trace = append(trace, rpcTraceResult{})
-						} else {
-							trace = append(trace[:i+1], trace[i:]...)
-							trace[i] = rpcTraceResult{}
-						}
+	if len(trace) != len(data) {
+		if len(trace) < len(data) {
+			for i := range transactions {
+				tx := &transactions[i]
+				// bridging transactions in Polygon do not create trace and cause mismatch between the trace size and block size, it is necessary to adjust the trace size
+				// bridging transaction that from and to zero address
+				if tx.To == "0x0000000000000000000000000000000000000000" && tx.From == "0x0000000000000000000000000000000000000000" {
+					if i >= len(trace) {
+						trace = append(trace, rpcTraceResult{})
+					} else {
+						trace = append(trace[:i+1], trace[i:]...)
+						trace[i] = rpcTraceResult{}
 					}
 				}
 			}
-			if len(trace) != len(data) {
-				e := fmt.Sprint("trace length does not match block length ", len(trace), "!=", len(data))
-				glog.Error("debug_traceBlockByHash block ", blockHash, ", error: ", e)
-				return data, contracts, errors.New(e)
-			} else {
-				glog.Warning("debug_traceBlockByHash block ", blockHash, ", trace adjusted to match the number of transactions in block")
-			}
 		}
-		for i, result := range trace {
-			r := &result.Result
-			d := &data[i]
-			if r.Type == "CREATE" || r.Type == "CREATE2" {
-				d.Type = bchain.CREATE
-				d.Contract = r.To
-				contracts = append(contracts, *b.getCreationContractInfo(d.Contract, blockHeight))
-			} else if r.Type == "SELFDESTRUCT" {
-				d.Type = bchain.SELFDESTRUCT
-			}
-			for j := range r.Calls {
-				contracts = b.processCallTrace(&r.Calls[j], d, contracts, blockHeight)
+		if len(trace) != len(data) {
+			e := fmt.Sprint("trace length does not match block length ", len(trace), "!=", len(data))
+			glog.Error("debug_traceBlockByHash block ", blockHash, ", error: ", e)
+			return data, contracts, errors.New(e)
+		} else {
+			glog.Warning("debug_traceBlockByHash block ", blockHash, ", trace adjusted to match the number of transactions in block")
+		}
+	}
+	for i, result := range trace {
+		r := &result.Result
+		d := &data[i]
+		if r.Type == "CREATE" || r.Type == "CREATE2" {
+			d.Type = bchain.CREATE
+			d.Contract = r.To
+			contracts = append(contracts, *b.getCreationContractInfo(d.Contract, blockHeight))
+		} else if r.Type == "SELFDESTRUCT" {
+			d.Type = bchain.SELFDESTRUCT
+		}
+		for j := range r.Calls {
+			contracts = b.processCallTrace(&r.Calls[j], d, contracts, blockHeight)
+		}
+		if r.Error != "" {
+			baseError := PackInternalTransactionError(r.Error)
+			if len(baseError) > 1 {
+				// n, _ := ethNumber(transactions[i].BlockNumber)
+				// glog.Infof("Internal Data Error %d %s: unknown base error %s", n, transactions[i].Hash, baseError)
+				baseError = strings.ToUpper(baseError[:1]) + baseError[1:] + ". "
 			}
-			if r.Error != "" {
-				baseError := PackInternalTransactionError(r.Error)
-				if len(baseError) > 1 {
-					// n, _ := ethNumber(transactions[i].BlockNumber)
-					// glog.Infof("Internal Data Error %d %s: unknown base error %s", n, transactions[i].Hash, baseError)
-					baseError = strings.ToUpper(baseError[:1]) + baseError[1:] + ". "
-				}
-				outputError := ParseErrorFromOutput(r.Output)
-				if len(outputError) > 0 {
-					d.Error = baseError + strings.ToUpper(outputError[:1]) + outputError[1:]
+			outputError := ParseErrorFromOutput(r.Output)
+			if len(outputError) > 0 {
+				d.Error = baseError + strings.ToUpper(outputError[:1]) + outputError[1:]
+			} else {
+				traceError := PackInternalTransactionError(d.Error)
+				if traceError == baseError {
+					d.Error = baseError
 				} else {
-					traceError := PackInternalTransactionError(d.Error)
-					if traceError == baseError {
-						d.Error = baseError
-					} else {
-						d.Error = baseError + traceError
-					}
+					d.Error = baseError + traceError
 				}
-				// n, _ := ethNumber(transactions[i].BlockNumber)
-				// glog.Infof("Internal Data Error %d %s: %s", n, transactions[i].Hash, UnpackInternalTransactionError([]byte(d.Error)))
 			}
+			// n, _ := ethNumber(transactions[i].BlockNumber)
+			// glog.Infof("Internal Data Error %d %s: %s", n, transactions[i].Hash, UnpackInternalTransactionError([]byte(d.Error)))
 		}
 	}
 	return data, contracts, nil
@@ -735,62 +773,24 @@ func (b *EthereumRPC) GetBlock(hash string, height uint32) (*bchain.Block, error
 	if err != nil {
 		return nil, err
 	}
-	var head rpcHeader
-	if err := json.Unmarshal(raw, &head); err != nil {
-		return nil, errors.Annotatef(err, "hash %v, height %v", hash, height)
-	}
-	var body rpcBlockTransactions
-	if err := json.Unmarshal(raw, &body); err != nil {
-		return nil, errors.Annotatef(err, "hash %v, height %v", hash, height)
-	}
-	bbh, err := b.ethHeaderToBlockHeader(&head)
+	sb, err := b.newStagedBlock(raw)
 	if err != nil {
 		return nil, errors.Annotatef(err, "hash %v, height %v", hash, height)
 	}
-	// get block events
-	// TODO - could be possibly done in parallel to getInternalDataForBlock
-	logs, ens, err := b.processEventsForBlock(head.Number)
+	d := sb.Data.(*stagedBlockData)
+	// get block events in parallel to the internal data
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		d.internalData, d.contracts, d.internalDataErr = b.getInternalDataForBlock(d.head.Hash, sb.Height, d.body.Transactions)
+	}()
+	d.logs, d.ens, err = b.processEventsForBlock(d.head.Number)
+	wg.Wait()
 	if err != nil {
 		return nil, err
 	}
-	// error fetching internal data does not stop the block processing
-	var blockSpecificData *bchain.EthereumBlockSpecificData
-	internalData, contracts, err := b.getInternalDataForBlock(head.Hash, bbh.Height, body.Transactions)
-	// pass internalData error and ENS records in blockSpecificData to be stored
-	if err != nil || len(ens) > 0 || len(contracts) > 0 {
-		blockSpecificData = &bchain.EthereumBlockSpecificData{}
-		if err != nil {
-			blockSpecificData.InternalDataError = err.Error()
-			// glog.Info("InternalDataError ", bbh.Height, ": ", err.Error())
-		}
-		if len(ens) > 0 {
-			blockSpecificData.AddressAliasRecords = ens
-			// glog.Info("ENS", ens)
-		}
-		if len(contracts) > 0 {
-			blockSpecificData.Contracts = contracts
-			// glog.Info("Contracts", contracts)
-		}
-	}
-
-	btxs := make([]bchain.Tx, len(body.Transactions))
-	for i := range body.Transactions {
-		tx := &body.Transactions[i]
-		btx, err := b.Parser.ethTxToTx(tx, &bchain.RpcReceipt{Logs: logs[tx.Hash]}, &internalData[i], bbh.Time, uint32(bbh.Confirmations), true)
-		if err != nil {
-			return nil, errors.Annotatef(err, "hash %v, height %v, txid %v", hash, height, tx.Hash)
-		}
-		btxs[i] = *btx
-		if b.mempoolInitialized {
-			b.Mempool.RemoveTransactionFromMempool(tx.Hash)
-		}
-	}
-	bbk := bchain.Block{
-		BlockHeader:      *bbh,
-		Txs:              btxs,
-		CoinSpecificData: blockSpecificData,
-	}
-	return &bbk, nil
+	return b.assembleBlock(sb)
 }
 
 // GetBlockInfo returns extended header (more info than in bchain.BlockHeader) with a list of txids
@@ -843,7 +843,7 @@ func (b *EthereumRPC) GetTransaction(txid string) (*bchain.Tx, error) {
 	var btx *bchain.Tx
 	if tx.BlockNumber == "" {
 		// mempool tx
-		btx, err = b.Parser.ethTxToTx(tx, nil, nil, 0, 0, true)
+		btx, err = b.Parser.ethTxToTx(tx, nil, nil, "", 0, 0, true)
 		if err != nil {
 			return nil, errors.Annotatef(err, "txid %v", txid)
 		}
@@ -854,7 +854,8 @@ func (b *EthereumRPC) GetTransaction(txid string) (*bchain.Tx, error) {
 			return nil, err
 		}
 		var ht struct {
-			Time string `json:"timestamp"`
+			Time          string `json:"timestamp"`
+			BaseFeePerGas string `json:"baseFeePerGas"`
 		}
 		if err := json.Unmarshal(raw, &ht); err != nil {
 			return nil, errors.Annotatef(err, "hash %v", hash)
@@ -876,7 +877,7 @@ func (b *EthereumRPC) GetTransaction(txid string) (*bchain.Tx, error) {
 		if err != nil {
 			return nil, errors.Annotatef(err, "txid %v", txid)
 		}
-		btx, err = b.Parser.ethTxToTx(tx, &receipt, nil, time, confirmations, true)
+		btx, err = b.Parser.ethTxToTx(tx, &receipt, nil, ht.BaseFeePerGas, time, confirmations, true)
 		if err != nil {
 			return nil, errors.Annotatef(err, "txid %v", txid)
 		}
@@ -937,6 +938,77 @@ func (b *EthereumRPC) EstimateSmartFee(blocks int, conservative bool) (big.Int,
 	return r, err
 }
 
+const (
+	// number of the last blocks used by the EIP-1559 fee estimation
+	eip1559FeeHistoryBlocks = 20
+)
+
+// reward percentiles of the slow, normal and fast EIP-1559 fee estimation
+var eip1559RewardPercentiles = []float64{10, 50, 90}
+
+type rpcFeeHistory struct {
+	OldestBlock   string     `json:"oldestBlock"`
+	BaseFeePerGas []string   `json:"baseFeePerGas"`
+	GasUsedRatio  []float64  `json:"gasUsedRatio"`
+	Reward        [][]string `json:"reward"`
+}
+
+// EthereumTypeGetEip1559Fees returns the slow, normal and fast EIP-1559 fee estimation
+// based on the fee history of the last blocks
+func (b *EthereumRPC) EthereumTypeGetEip1559Fees() (*bchain.Eip1559Fees, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), b.Timeout)
+	defer cancel()
+	var h rpcFeeHistory
+	err := b.RPC.CallContext(ctx, &h, "eth_feeHistory", hexutil.EncodeUint64(eip1559FeeHistoryBlocks), "latest", eip1559RewardPercentiles)
+	if err != nil {
+		return nil, err
+	}
+	return eip1559FeesFromHistory(&h)
+}
+
+// eip1559FeesFromHistory computes the fee estimation from the fee history, the base fee of the next block
+// is the last item of baseFeePerGas, the priority fee is the median of the rewards of the blocks in the given percentile
+func eip1559FeesFromHistory(h *rpcFeeHistory) (*bchain.Eip1559Fees, error) {
+	if len(h.BaseFeePerGas) == 0 {
+		return nil, errors.New("eth_feeHistory: missing baseFeePerGas")
+	}
+	baseFee, err := hexutil.DecodeBig(h.BaseFeePerGas[len(h.BaseFeePerGas)-1])
+	if err != nil {
+		return nil, errors.Annotatef(err, "baseFeePerGas %v", h.BaseFeePerGas[len(h.BaseFeePerGas)-1])
+	}
+	fees := make([]*bchain.Eip1559Fee, len(eip1559RewardPercentiles))
+	for i := range eip1559RewardPercentiles {
+		rewards := make([]*big.Int, 0, len(h.Reward))
+		for _, r := range h.Reward {
+			if i >= len(r) {
+				continue
+			}
+			v, err := hexutil.DecodeBig(r[i])
+			if err != nil {
+				return nil, errors.Annotatef(err, "reward %v", r[i])
+			}
+			rewards = append(rewards, v)
+		}
+		priorityFee := new(big.Int)
+		if len(rewards) > 0 {
+			sort.Slice(rewards, func(i, j int) bool { return rewards[i].Cmp(rewards[j]) < 0 })
+			priorityFee.Set(rewards[len(rewards)/2])
+		}
+		// the max fee covers the growth of the base fee in the next blocks
+		maxFee := new(big.Int).Mul(baseFee, big.NewInt(2))
+		fees[i] = &bchain.Eip1559Fee{
+			MaxFeePerGas:         maxFee.Add(maxFee, priorityFee),
+			MaxPriorityFeePerGas: priorityFee,
+		}
+	}
+	return &bchain.Eip1559Fees{
+		BaseFeePerGas: baseFee,
+		Slow:          fees[0],
+		Normal:        fees[1],
+		Fast:          fees[2],
+	}, nil
+}
+
 // GetStringFromMap attempts to return the value for a specific key in a map as a string if valid,
 // otherwise returns an empty string with false indicating there was no key found, or the value was not a string
 func GetStringFromMap(p string, params map[string]interface{}) (string, bool) {
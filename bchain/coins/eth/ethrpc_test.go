@@ -0,0 +1,49 @@
+//go:build unittest
+
+package eth
+
+import (
+	"math/big"
+	"testing"
+)
+
+func Test_eip1559FeesFromHistory(t *testing.T) {
+	h := rpcFeeHistory{
+		OldestBlock:   "0x10",
+		BaseFeePerGas: []string{"0x3b9aca00", "0x3b9aca00", "0x77359400", "0x4a817c800"},
+		GasUsedRatio:  []float64{0.5, 0.9, 0.99},
+		Reward: [][]string{
+			{"0x1", "0x5", "0x64"},
+			{"0x3", "0x4", "0xc8"},
+			{"0x2", "0x6", "0x12c"},
+		},
+	}
+	got, err := eip1559FeesFromHistory(&h)
+	if err != nil {
+		t.Fatal(err)
+	}
+	baseFee := int64(20000000000)
+	if got.BaseFeePerGas.Cmp(big.NewInt(baseFee)) != 0 {
+		t.Errorf("BaseFeePerGas = %v, want %v", got.BaseFeePerGas, baseFee)
+	}
+	for _, tt := range []struct {
+		name     string
+		priority int64
+		fee      func() *big.Int
+		prio     func() *big.Int
+	}{
+		{"slow", 2, func() *big.Int { return got.Slow.MaxFeePerGas }, func() *big.Int { return got.Slow.MaxPriorityFeePerGas }},
+		{"normal", 5, func() *big.Int { return got.Normal.MaxFeePerGas }, func() *big.Int { return got.Normal.MaxPriorityFeePerGas }},
+		{"fast", 200, func() *big.Int { return got.Fast.MaxFeePerGas }, func() *big.Int { return got.Fast.MaxPriorityFeePerGas }},
+	} {
+		if tt.prio().Cmp(big.NewInt(tt.priority)) != 0 {
+			t.Errorf("%s MaxPriorityFeePerGas = %v, want %v", tt.name, tt.prio(), tt.priority)
+		}
+		if want := big.NewInt(2*baseFee + tt.priority); tt.fee().Cmp(want) != 0 {
+			t.Errorf("%s MaxFeePerGas = %v, want %v", tt.name, tt.fee(), want)
+		}
+	}
+	if _, err := eip1559FeesFromHistory(&rpcFeeHistory{}); err == nil {
+		t.Error("eip1559FeesFromHistory of empty history expected error")
+	}
+}
@@ -57,6 +57,21 @@ func (c *EthereumRPCClient) EthSubscribe(ctx context.Context, channel interface{
 	return &EthereumClientSubscription{ClientSubscription: sub}, nil
 }
 
+// BatchCallContext sends the calls in one batched JSON-RPC request
+func (c *EthereumRPCClient) BatchCallContext(ctx context.Context, b []bchain.EVMBatchElem) error {
+	elems := make([]rpc.BatchElem, len(b))
+	for i := range b {
+		elems[i] = rpc.BatchElem{Method: b[i].Method, Args: b[i].Args, Result: b[i].Result}
+	}
+	if err := c.Client.BatchCallContext(ctx, elems); err != nil {
+		return err
+	}
+	for i := range elems {
+		b[i].Error = elems[i].Error
+	}
+	return nil
+}
+
 // EthereumHeader wraps a block header to implement the EVMHeader interface
 type EthereumHeader struct {
 	*types.Header
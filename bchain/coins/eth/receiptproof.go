@@ -0,0 +1,256 @@
+package eth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"sort"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+	"github.com/trezor/blockbook/bchain"
+	"golang.org/x/crypto/sha3"
+)
+
+type rpcReceiptPosition struct {
+	BlockHash        string `json:"blockHash"`
+	BlockNumber      string `json:"blockNumber"`
+	TransactionIndex string `json:"transactionIndex"`
+}
+
+type rpcReceiptsHeader struct {
+	ReceiptsRoot string   `json:"receiptsRoot"`
+	Transactions []string `json:"transactions"`
+}
+
+// keyToNibbles converts the trie key to the path of nibbles in the trie
+func keyToNibbles(key []byte) []byte {
+	nibbles := make([]byte, len(key)*2)
+	for i, b := range key {
+		nibbles[i*2] = b >> 4
+		nibbles[i*2+1] = b & 0x0f
+	}
+	return nibbles
+}
+
+// compactNibbles encodes the path of the leaf or extension node using the hex prefix encoding
+func compactNibbles(nibbles []byte, leaf bool) []byte {
+	flag := byte(0)
+	if leaf {
+		flag = 2
+	}
+	b := make([]byte, len(nibbles)/2+1)
+	if len(nibbles)%2 == 1 {
+		b[0] = (flag+1)<<4 | nibbles[0]
+		nibbles = nibbles[1:]
+	} else {
+		b[0] = flag << 4
+	}
+	for i := 0; i < len(nibbles); i += 2 {
+		b[i/2+1] = nibbles[i]<<4 | nibbles[i+1]
+	}
+	return b
+}
+
+func keccak256(data []byte) []byte {
+	h := sha3.NewLegacyKeccak256()
+	h.Write(data)
+	return h.Sum(nil)
+}
+
+func rlpString(b []byte) rlp.RawValue {
+	e, _ := rlp.EncodeToBytes(b)
+	return e
+}
+
+// trieEntry is a key value pair of the trie, the key is converted to nibbles
+type trieEntry struct {
+	nibbles []byte
+	value   []byte
+}
+
+// trieProofBuilder computes the Merkle Patricia trie of the entries sorted by the key
+// and collects the nodes on the path to the proven key
+type trieProofBuilder struct {
+	proofNibbles []byte
+	proof        [][]byte
+}
+
+// node returns the RLP encoding of the node containing the entries sharing the first depth nibbles
+func (b *trieProofBuilder) node(entries []trieEntry, depth int) []byte {
+	var items []rlp.RawValue
+	if len(entries) == 1 {
+		items = []rlp.RawValue{rlpString(compactNibbles(entries[0].nibbles[depth:], true)), rlpString(entries[0].value)}
+	} else {
+		// the length of the common prefix of the keys, the sorted entries have the most different keys at the ends
+		first, last := entries[0].nibbles, entries[len(entries)-1].nibbles
+		prefix := 0
+		for depth+prefix < len(first) && depth+prefix < len(last) && first[depth+prefix] == last[depth+prefix] {
+			prefix++
+		}
+		if prefix > 0 {
+			items = []rlp.RawValue{rlpString(compactNibbles(first[depth:depth+prefix], false)), b.ref(entries, depth+prefix)}
+		} else {
+			items = make([]rlp.RawValue, 17)
+			items[16] = rlpString(nil)
+			for len(entries) > 0 && len(entries[0].nibbles) == depth {
+				items[16] = rlpString(entries[0].value)
+				entries = entries[1:]
+			}
+			for n := byte(0); n < 16; n++ {
+				i := 0
+				for i < len(entries) && entries[i].nibbles[depth] == n {
+					i++
+				}
+				if i == 0 {
+					items[n] = rlpString(nil)
+				} else {
+					items[n] = b.ref(entries[:i], depth+1)
+					entries = entries[i:]
+				}
+			}
+		}
+	}
+	enc, _ := rlp.EncodeToBytes(items)
+	return enc
+}
+
+// ref returns the reference to the node from its parent, the nodes shorter than 32 bytes are embedded in their parents,
+// the referenced nodes on the path to the proven key are added to the proof in the order from the leaf to the root
+func (b *trieProofBuilder) ref(entries []trieEntry, depth int) rlp.RawValue {
+	enc := b.node(entries, depth)
+	if len(enc) < 32 {
+		return enc
+	}
+	if bytes.HasPrefix(b.proofNibbles, entries[0].nibbles[:depth]) {
+		b.proof = append(b.proof, enc)
+	}
+	return rlpString(keccak256(enc))
+}
+
+// receiptTrieProof builds the receipts trie of the block and returns its root, the key and the value of the receipt at the index
+// and the trie nodes on the path from the root to the receipt
+func receiptTrieProof(receipts types.Receipts, index int) (ethcommon.Hash, []byte, []byte, [][]byte, error) {
+	if index < 0 || index >= len(receipts) {
+		return ethcommon.Hash{}, nil, nil, nil, errors.Errorf("Receipt index %v out of range", index)
+	}
+	entries := make([]trieEntry, len(receipts))
+	var buf bytes.Buffer
+	for i := range receipts {
+		buf.Reset()
+		receipts.EncodeIndex(i, &buf)
+		entries[i] = trieEntry{keyToNibbles(rlp.AppendUint64(nil, uint64(i))), ethcommon.CopyBytes(buf.Bytes())}
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return bytes.Compare(entries[i].nibbles, entries[j].nibbles) < 0
+	})
+	key := rlp.AppendUint64(nil, uint64(index))
+	var value []byte
+	b := trieProofBuilder{proofNibbles: keyToNibbles(key)}
+	for _, e := range entries {
+		if bytes.Equal(e.nibbles, b.proofNibbles) {
+			value = e.value
+			break
+		}
+	}
+	// the root is always hashed, even if it is shorter than 32 bytes
+	rootNode := b.node(entries, 0)
+	proof := make([][]byte, 0, len(b.proof)+1)
+	proof = append(proof, rootNode)
+	for i := len(b.proof) - 1; i >= 0; i-- {
+		proof = append(proof, b.proof[i])
+	}
+	return ethcommon.BytesToHash(keccak256(rootNode)), key, value, proof, nil
+}
+
+// getBlockReceipts returns the receipts of the block using eth_getBlockReceipts
+// or the receipts of the individual transactions if the backend does not support it
+func (b *EthereumRPC) getBlockReceipts(hash string, txids []string) (types.Receipts, error) {
+	var receipts types.Receipts
+	ctx, cancel := context.WithTimeout(context.Background(), b.Timeout)
+	err := b.RPC.CallContext(ctx, &receipts, "eth_getBlockReceipts", ethcommon.HexToHash(hash))
+	cancel()
+	if err == nil && len(receipts) == len(txids) {
+		return receipts, nil
+	}
+	glog.V(1).Info("eth_getBlockReceipts ", hash, " not available (", err, "), getting receipts of ", len(txids), " transactions")
+	receipts = make(types.Receipts, len(txids))
+	for i, txid := range txids {
+		ctx, cancel := context.WithTimeout(context.Background(), b.Timeout)
+		err = b.RPC.CallContext(ctx, &receipts[i], "eth_getTransactionReceipt", ethcommon.HexToHash(txid))
+		cancel()
+		if err != nil {
+			return nil, errors.Annotatef(err, "txid %v", txid)
+		}
+		if receipts[i] == nil {
+			return nil, errors.Errorf("Receipt of tx %v not found", txid)
+		}
+	}
+	return receipts, nil
+}
+
+// EthereumTypeGetReceiptProof returns the proof of the receipt of the confirmed transaction in the receipts trie of its block
+func (b *EthereumRPC) EthereumTypeGetReceiptProof(txid string) (*bchain.EthereumReceiptProof, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), b.Timeout)
+	defer cancel()
+	var position *rpcReceiptPosition
+	err := b.RPC.CallContext(ctx, &position, "eth_getTransactionReceipt", ethcommon.HexToHash(txid))
+	if err != nil {
+		return nil, errors.Annotatef(err, "txid %v", txid)
+	}
+	if position == nil || position.BlockHash == "" {
+		return nil, bchain.ErrTxNotFound
+	}
+	index, err := ethNumber(position.TransactionIndex)
+	if err != nil {
+		return nil, errors.Annotatef(err, "txid %v", txid)
+	}
+	number, err := ethNumber(position.BlockNumber)
+	if err != nil {
+		return nil, errors.Annotatef(err, "txid %v", txid)
+	}
+	raw, err := b.getBlockRaw(position.BlockHash, 0, false)
+	if err != nil {
+		return nil, err
+	}
+	var head rpcReceiptsHeader
+	if err = json.Unmarshal(raw, &head); err != nil {
+		return nil, errors.Annotatef(err, "hash %v", position.BlockHash)
+	}
+	receipts, err := b.getBlockReceipts(position.BlockHash, head.Transactions)
+	if err != nil {
+		return nil, err
+	}
+	root, key, value, proof, err := receiptTrieProof(receipts, int(index))
+	if err != nil {
+		return nil, errors.Annotatef(err, "txid %v", txid)
+	}
+	if root != ethcommon.HexToHash(head.ReceiptsRoot) {
+		return nil, errors.Errorf("Receipts root mismatch in block %v, computed %v, block %v", position.BlockHash, root.Hex(), head.ReceiptsRoot)
+	}
+	p := &bchain.EthereumReceiptProof{
+		BlockHash:    position.BlockHash,
+		BlockNumber:  uint32(number),
+		ReceiptsRoot: head.ReceiptsRoot,
+		TxIndex:      uint32(index),
+		Key:          hexutil.Encode(key),
+		Receipt:      hexutil.Encode(value),
+		Proof:        make([]string, len(proof)),
+	}
+	for i := range proof {
+		p.Proof[i] = hexutil.Encode(proof[i])
+	}
+	// return the RLP encoded header only if it hashes to the block hash,
+	// the headers of some chains contain fields unknown to go-ethereum
+	var header types.Header
+	if err = json.Unmarshal(raw, &header); err == nil && header.Hash() == ethcommon.HexToHash(position.BlockHash) {
+		if h, err := rlp.EncodeToBytes(&header); err == nil {
+			p.Header = hexutil.Encode(h)
+		}
+	}
+	return p, nil
+}
@@ -0,0 +1,160 @@
+//go:build unittest
+
+package eth
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	ethcommon "github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/rlp"
+	"github.com/juju/errors"
+)
+
+func testReceipts(n int) types.Receipts {
+	receipts := make(types.Receipts, n)
+	for i := range receipts {
+		r := &types.Receipt{
+			Type:              types.LegacyTxType,
+			Status:            types.ReceiptStatusSuccessful,
+			CumulativeGasUsed: uint64(21000 * (i + 1)),
+			Logs:              []*types.Log{},
+		}
+		if i%3 == 1 {
+			r.Type = types.DynamicFeeTxType
+			r.Logs = []*types.Log{{
+				Address: ethcommon.BigToAddress(big.NewInt(int64(i))),
+				Topics:  []ethcommon.Hash{ethcommon.BigToHash(big.NewInt(int64(i)))},
+				Data:    []byte{byte(i)},
+			}}
+		}
+		r.Bloom = types.CreateBloom(types.Receipts{r})
+		receipts[i] = r
+	}
+	return receipts
+}
+
+// verifyTrieProof returns the value of the key in the trie with the root, using only the nodes of the proof
+func verifyTrieProof(root ethcommon.Hash, key []byte, proof [][]byte) ([]byte, error) {
+	nodes := make(map[ethcommon.Hash][]byte)
+	for _, n := range proof {
+		nodes[ethcommon.BytesToHash(keccak256(n))] = n
+	}
+	nibbles := keyToNibbles(key)
+	node, found := nodes[root]
+	for {
+		if !found {
+			return nil, errors.New("missing node")
+		}
+		var items []rlp.RawValue
+		if err := rlp.DecodeBytes(node, &items); err != nil {
+			return nil, err
+		}
+		var child rlp.RawValue
+		switch len(items) {
+		case 17:
+			if len(nibbles) == 0 {
+				var value []byte
+				return value, rlp.DecodeBytes(items[16], &value)
+			}
+			child, nibbles = items[nibbles[0]], nibbles[1:]
+		case 2:
+			var compact []byte
+			if err := rlp.DecodeBytes(items[0], &compact); err != nil || len(compact) == 0 {
+				return nil, errors.New("invalid node path")
+			}
+			path := keyToNibbles(compact)
+			leaf := path[0] >= 2
+			if path[0]%2 == 1 {
+				path = path[1:]
+			} else {
+				path = path[2:]
+			}
+			if !bytes.HasPrefix(nibbles, path) {
+				return nil, errors.New("key not found")
+			}
+			nibbles = nibbles[len(path):]
+			if leaf {
+				if len(nibbles) != 0 {
+					return nil, errors.New("key not found")
+				}
+				var value []byte
+				return value, rlp.DecodeBytes(items[1], &value)
+			}
+			child = items[1]
+		default:
+			return nil, errors.New("invalid node")
+		}
+		// the nodes shorter than 32 bytes are embedded, the others are referenced by their hash
+		if kind, _, _, _ := rlp.Split(child); kind == rlp.List {
+			node, found = child, true
+		} else {
+			var hash []byte
+			if err := rlp.DecodeBytes(child, &hash); err != nil || len(hash) != 32 {
+				return nil, errors.New("key not found")
+			}
+			node, found = nodes[ethcommon.BytesToHash(hash)]
+		}
+	}
+}
+
+func Test_receiptTrieProof(t *testing.T) {
+	tests := []struct {
+		name     string
+		receipts int
+		indexes  []int
+		// the receipts root computed by go-ethereum types.DeriveSha
+		want string
+	}{
+		{
+			name:     "single receipt",
+			receipts: 1,
+			indexes:  []int{0},
+			want:     "0x056b23fbba480696b65fe5a59b8f2148a1299103c4f57df839233af2cf4ca2d2",
+		},
+		{
+			name:     "small block",
+			receipts: 3,
+			indexes:  []int{0, 1, 2},
+			want:     "0x62eaac9484c630ed3ce8690482a48f55ec4628f26e3e08fe4415b91fb64d846d",
+		},
+		{
+			name:     "block over 128 receipts",
+			receipts: 300,
+			indexes:  []int{0, 1, 15, 16, 127, 128, 255, 256, 299},
+			want:     "0x26be4b4c65e5042463fdf2ffa0e6867a1ac127f167a1dc5c5a796c8a5b6226c0",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			receipts := testReceipts(tt.receipts)
+			want := ethcommon.HexToHash(tt.want)
+			for _, index := range tt.indexes {
+				root, key, value, proof, err := receiptTrieProof(receipts, index)
+				if err != nil {
+					t.Fatalf("receiptTrieProof(%v) error %v", index, err)
+				}
+				if root != want {
+					t.Fatalf("receiptTrieProof(%v) root %v, want %v", index, root.Hex(), want.Hex())
+				}
+				var buf bytes.Buffer
+				receipts.EncodeIndex(index, &buf)
+				if !bytes.Equal(value, buf.Bytes()) {
+					t.Errorf("receiptTrieProof(%v) value %x, want %x", index, value, buf.Bytes())
+				}
+				got, err := verifyTrieProof(root, key, proof)
+				if err != nil {
+					t.Fatalf("verifyTrieProof(%v) error %v", index, err)
+				}
+				if !bytes.Equal(got, value) {
+					t.Errorf("verifyTrieProof(%v) = %x, want %x", index, got, value)
+				}
+			}
+		})
+	}
+	if _, _, _, _, err := receiptTrieProof(testReceipts(2), 2); err == nil {
+		t.Error("receiptTrieProof out of range index, expected error")
+	}
+}
@@ -19,9 +19,19 @@ type EVMClient interface {
 type EVMRPCClient interface {
 	EthSubscribe(ctx context.Context, channel interface{}, args ...interface{}) (EVMClientSubscription, error)
 	CallContext(ctx context.Context, result interface{}, method string, args ...interface{}) error
+	BatchCallContext(ctx context.Context, b []EVMBatchElem) error
 	Close()
 }
 
+// EVMBatchElem is one call of a batched JSON-RPC request,
+// Error is set if the call fails, the error returned by BatchCallContext means the whole batch failed
+type EVMBatchElem struct {
+	Method string
+	Args   []interface{}
+	Result interface{}
+	Error  error
+}
+
 // EVMHeader provides access to the necessary header data for evm chain sync
 type EVMHeader interface {
 	Hash() string
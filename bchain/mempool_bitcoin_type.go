@@ -9,11 +9,20 @@ import (
 	"github.com/juju/errors"
 )
 
+// replaced transactions are remembered for this period after the replacement
+const replacementExpiration = 24 * time.Hour
+
 type chanInputPayload struct {
 	tx    *MempoolTx
 	index int
 }
 
+type txReplacement struct {
+	replacedBy string
+	replaces   []string
+	time       time.Time
+}
+
 // MempoolBitcoinType is mempool handle.
 type MempoolBitcoinType struct {
 	BaseMempool
@@ -23,6 +32,11 @@ type MempoolBitcoinType struct {
 	golombFilterP       uint8
 	filterScripts       string
 	useZeroedKey        bool
+	spenders            map[Outpoint]string
+	txInputs            map[string][]Outpoint
+	replacements        map[string]*txReplacement
+	txFees              map[string]mempoolTxFee
+	blockTemplate       *MempoolBlockTemplate
 }
 
 // NewMempoolBitcoinType creates new mempool handler.
@@ -39,6 +53,10 @@ func NewMempoolBitcoinType(chain BlockChain, workers int, subworkers int, golomb
 		golombFilterP: golombFilterP,
 		filterScripts: filterScripts,
 		useZeroedKey:  useZeroedKey,
+		spenders:      make(map[Outpoint]string),
+		txInputs:      make(map[string][]Outpoint),
+		replacements:  make(map[string]*txReplacement),
+		txFees:        make(map[string]mempoolTxFee),
 	}
 	for i := 0; i < workers; i++ {
 		go func(i int) {
@@ -53,11 +71,11 @@ func NewMempoolBitcoinType(chain BlockChain, workers int, subworkers int, golomb
 				}(j)
 			}
 			for txid := range m.chanTxid {
-				io, golombFilter, ok := m.getTxAddrs(txid, chanInput, chanResult)
+				tio, ok := m.getTxAddrs(txid, chanInput, chanResult)
 				if !ok {
-					io = []addrIndex{}
+					tio = txidio{txid: txid, io: []addrIndex{}}
 				}
-				m.chanAddrIndex <- txidio{txid, io, golombFilter}
+				m.chanAddrIndex <- tio
 			}
 		}(i)
 	}
@@ -117,11 +135,11 @@ func (m *MempoolBitcoinType) computeGolombFilter(mtx *MempoolTx, tx *Tx) string
 	return hex.EncodeToString(fb)
 }
 
-func (m *MempoolBitcoinType) getTxAddrs(txid string, chanInput chan chanInputPayload, chanResult chan *addrIndex) ([]addrIndex, string, bool) {
+func (m *MempoolBitcoinType) getTxAddrs(txid string, chanInput chan chanInputPayload, chanResult chan *addrIndex) (txidio, bool) {
 	tx, err := m.chain.GetTransactionForMempool(txid)
 	if err != nil {
 		glog.Error("cannot get transaction ", txid, ": ", err)
-		return nil, "", false
+		return txidio{}, false
 	}
 	glog.V(2).Info("mempool: gettxaddrs ", txid, ", ", len(tx.Vin), " inputs")
 	mtx := m.txToMempoolTx(tx)
@@ -140,11 +158,13 @@ func (m *MempoolBitcoinType) getTxAddrs(txid string, chanInput chan chanInputPay
 		}
 	}
 	dispatched := 0
+	inputs := make([]Outpoint, 0, len(tx.Vin))
 	for i := range tx.Vin {
 		input := &tx.Vin[i]
 		if input.Coinbase != "" {
 			continue
 		}
+		inputs = append(inputs, Outpoint{input.Txid, int32(input.Vout)})
 		payload := chanInputPayload{mtx, i}
 	loop:
 		for {
@@ -175,7 +195,97 @@ func (m *MempoolBitcoinType) getTxAddrs(txid string, chanInput chan chanInputPay
 	if m.OnNewTx != nil {
 		m.OnNewTx(mtx)
 	}
-	return io, golombFilter, true
+	return txidio{txid: txid, io: io, filter: golombFilter, inputs: inputs, fee: m.getTxFee(mtx, tx)}, true
+}
+
+// getTxFee returns the fee and the virtual size of the transaction, nil if the values of all inputs are not known
+func (m *MempoolBitcoinType) getTxFee(mtx *MempoolTx, tx *Tx) *mempoolTxFee {
+	var in, out big.Int
+	for i := range mtx.Vin {
+		vin := &mtx.Vin[i]
+		if vin.Coinbase != "" {
+			continue
+		}
+		if vin.AddrDesc == nil {
+			return nil
+		}
+		in.Add(&in, &vin.ValueSat)
+	}
+	for i := range tx.Vout {
+		out.Add(&out, &tx.Vout[i].ValueSat)
+	}
+	in.Sub(&in, &out)
+	if in.Sign() < 0 || !in.IsInt64() {
+		return nil
+	}
+	vsize := tx.VSize
+	if vsize <= 0 {
+		vsize = int64(len(tx.Hex) >> 1)
+	}
+	if vsize <= 0 {
+		return nil
+	}
+	return &mempoolTxFee{vsize: vsize, fee: in.Int64()}
+}
+
+// addSpender records the outpoints spent by the mempool transaction,
+// the transactions spending the same outpoints are considered replaced by it. The caller is responsible for locking!
+func (m *MempoolBitcoinType) addSpender(txid string, inputs []Outpoint, now time.Time) {
+	if len(inputs) == 0 {
+		return
+	}
+	if _, found := m.txInputs[txid]; found {
+		return
+	}
+	m.txInputs[txid] = inputs
+	for _, o := range inputs {
+		if spender, found := m.spenders[o]; found && spender != txid {
+			m.addReplacement(spender, txid, now)
+		}
+		m.spenders[o] = txid
+	}
+}
+
+// addReplacement records the replacement of the transaction. The caller is responsible for locking!
+func (m *MempoolBitcoinType) addReplacement(replaced string, by string, now time.Time) {
+	r := m.replacements[replaced]
+	if r == nil {
+		r = &txReplacement{}
+		m.replacements[replaced] = r
+	}
+	if r.replacedBy == by {
+		return
+	}
+	r.replacedBy = by
+	r.time = now
+	rb := m.replacements[by]
+	if rb == nil {
+		rb = &txReplacement{}
+		m.replacements[by] = rb
+	}
+	rb.replaces = append(rb.replaces, replaced)
+	rb.time = now
+	glog.V(1).Info("mempool: tx ", replaced, " replaced by ", by)
+}
+
+// removeSpender removes the outpoints spent by the transaction removed from the mempool. The caller is responsible for locking!
+func (m *MempoolBitcoinType) removeSpender(txid string) {
+	for _, o := range m.txInputs[txid] {
+		if m.spenders[o] == txid {
+			delete(m.spenders, o)
+		}
+	}
+	delete(m.txInputs, txid)
+}
+
+// pruneReplacements removes expired replacement records. The caller is responsible for locking!
+func (m *MempoolBitcoinType) pruneReplacements(now time.Time) {
+	expired := now.Add(-replacementExpiration)
+	for txid, r := range m.replacements {
+		if r.time.Before(expired) {
+			delete(m.replacements, txid)
+		}
+	}
 }
 
 // Resync gets mempool transactions and maps outputs to transactions.
@@ -189,15 +299,20 @@ func (m *MempoolBitcoinType) Resync() (int, error) {
 		return 0, err
 	}
 	glog.V(2).Info("mempool: resync ", len(txs), " txs")
-	onNewEntry := func(txid string, entry txEntry) {
-		if len(entry.addrIndexes) > 0 {
-			m.mux.Lock()
-			m.txEntries[txid] = entry
-			for _, si := range entry.addrIndexes {
-				m.addrDescToTx[si.addrDesc] = append(m.addrDescToTx[si.addrDesc], Outpoint{txid, si.n})
+	now := time.Now()
+	onNewEntry := func(tio *txidio, txTime uint32) {
+		m.mux.Lock()
+		if len(tio.io) > 0 {
+			m.txEntries[tio.txid] = txEntry{tio.io, txTime, tio.filter}
+			for _, si := range tio.io {
+				m.addrDescToTx[si.addrDesc] = append(m.addrDescToTx[si.addrDesc], Outpoint{tio.txid, si.n})
 			}
-			m.mux.Unlock()
 		}
+		m.addSpender(tio.txid, tio.inputs, now)
+		if tio.fee != nil && len(tio.inputs) > 0 {
+			m.txFees[tio.txid] = *tio.fee
+		}
+		m.mux.Unlock()
 	}
 	txsMap := make(map[string]struct{}, len(txs))
 	dispatched := 0
@@ -212,7 +327,7 @@ func (m *MempoolBitcoinType) Resync() (int, error) {
 				select {
 				// store as many processed transactions as possible
 				case tio := <-m.chanAddrIndex:
-					onNewEntry(tio.txid, txEntry{tio.io, txTime, tio.filter})
+					onNewEntry(&tio, txTime)
 					dispatched--
 				// send transaction to be processed
 				case m.chanTxid <- txid:
@@ -224,7 +339,7 @@ func (m *MempoolBitcoinType) Resync() (int, error) {
 	}
 	for i := 0; i < dispatched; i++ {
 		tio := <-m.chanAddrIndex
-		onNewEntry(tio.txid, txEntry{tio.io, txTime, tio.filter})
+		onNewEntry(&tio, txTime)
 	}
 
 	for txid, entry := range m.txEntries {
@@ -234,6 +349,15 @@ func (m *MempoolBitcoinType) Resync() (int, error) {
 			m.mux.Unlock()
 		}
 	}
+	m.mux.Lock()
+	for txid := range m.txInputs {
+		if _, exists := txsMap[txid]; !exists {
+			m.removeSpender(txid)
+			delete(m.txFees, txid)
+		}
+	}
+	m.pruneReplacements(now)
+	m.mux.Unlock()
 	glog.Info("mempool: resync finished in ", time.Since(start), ", ", len(m.txEntries), " transactions in mempool")
 	return len(m.txEntries), nil
 }
@@ -253,3 +377,42 @@ func (m *MempoolBitcoinType) GetTxidFilterEntries(filterScripts string, fromTime
 	m.mux.Unlock()
 	return MempoolTxidFilterEntries{entries, m.useZeroedKey}, nil
 }
+
+// GetTxConflicts returns the replacements of the transaction and other mempool transactions spending the same outpoints
+func (m *MempoolBitcoinType) GetTxConflicts(txid string) (MempoolTxConflicts, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	var rv MempoolTxConflicts
+	if r := m.replacements[txid]; r != nil {
+		rv.ReplacedBy = r.replacedBy
+		rv.Replaces = append(rv.Replaces, r.replaces...)
+		// the replaced transactions remain in the mempool until the next resync
+		for _, replaced := range r.replaces {
+			if _, found := m.txInputs[replaced]; found {
+				rv.Conflicts = appendIfMissing(rv.Conflicts, replaced)
+			}
+		}
+	}
+	for _, o := range m.txInputs[txid] {
+		if spender, found := m.spenders[o]; found && spender != txid {
+			rv.Conflicts = appendIfMissing(rv.Conflicts, spender)
+		}
+	}
+	return rv, nil
+}
+
+// GetOutpointSpender returns the mempool transaction spending the outpoint or empty string if the outpoint is not spent in the mempool
+func (m *MempoolBitcoinType) GetOutpointSpender(outpoint Outpoint) (string, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	return m.spenders[outpoint], nil
+}
+
+func appendIfMissing(a []string, s string) []string {
+	for _, v := range a {
+		if v == s {
+			return a
+		}
+	}
+	return append(a, s)
+}
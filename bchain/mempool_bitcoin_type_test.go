@@ -2,7 +2,9 @@ package bchain
 
 import (
 	"encoding/hex"
+	"reflect"
 	"testing"
+	"time"
 
 	"github.com/martinboehm/btcutil/gcs"
 )
@@ -350,3 +352,72 @@ func TestMempoolBitcoinType_computeGolombFilter_taproot_noordinals(t *testing.T)
 		})
 	}
 }
+
+func TestMempoolBitcoinType_replacements(t *testing.T) {
+	m := &MempoolBitcoinType{
+		spenders:     make(map[Outpoint]string),
+		txInputs:     make(map[string][]Outpoint),
+		replacements: make(map[string]*txReplacement),
+	}
+	now := time.Unix(1700000000, 0)
+	m.addSpender("tx1", []Outpoint{{"prev1", 0}, {"prev2", 1}}, now)
+	m.addSpender("tx2", []Outpoint{{"prev3", 0}}, now)
+	// tx3 double spends an input of tx1
+	m.addSpender("tx3", []Outpoint{{"prev2", 1}, {"prev4", 0}}, now)
+	tests := []struct {
+		txid string
+		want MempoolTxConflicts
+	}{
+		{
+			txid: "tx1",
+			want: MempoolTxConflicts{ReplacedBy: "tx3", Conflicts: []string{"tx3"}},
+		},
+		{
+			txid: "tx2",
+			want: MempoolTxConflicts{},
+		},
+		{
+			txid: "tx3",
+			want: MempoolTxConflicts{Replaces: []string{"tx1"}, Conflicts: []string{"tx1"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.txid, func(t *testing.T) {
+			got, err := m.GetTxConflicts(tt.txid)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetTxConflicts() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+	// tx1 is removed from the mempool by the resync, the replacement is remembered
+	m.removeSpender("tx1")
+	if _, found := m.spenders[Outpoint{"prev1", 0}]; found {
+		t.Error("outpoint prev1:0 still has a spender")
+	}
+	if got, _ := m.GetOutpointSpender(Outpoint{"prev2", 1}); got != "tx3" {
+		t.Errorf("GetOutpointSpender(prev2:1) = %v, want tx3", got)
+	}
+	got, _ := m.GetTxConflicts("tx3")
+	want := MempoolTxConflicts{Replaces: []string{"tx1"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetTxConflicts() = %+v, want %+v", got, want)
+	}
+	// tx4 replaces tx3, which forms a replacement chain
+	m.addSpender("tx4", []Outpoint{{"prev4", 0}}, now.Add(time.Hour))
+	got, _ = m.GetTxConflicts("tx3")
+	want = MempoolTxConflicts{ReplacedBy: "tx4", Replaces: []string{"tx1"}, Conflicts: []string{"tx4"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GetTxConflicts() = %+v, want %+v", got, want)
+	}
+	// expired replacements are removed
+	m.pruneReplacements(now.Add(replacementExpiration + time.Minute))
+	if _, found := m.replacements["tx1"]; found {
+		t.Error("replacement of tx1 not pruned")
+	}
+	if _, found := m.replacements["tx4"]; !found {
+		t.Error("replacement of tx4 pruned")
+	}
+}
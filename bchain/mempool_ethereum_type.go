@@ -172,3 +172,18 @@ func (m *MempoolEthereumType) RemoveTransactionFromMempool(txid string) {
 func (m *MempoolEthereumType) GetTxidFilterEntries(filterScripts string, fromTimestamp uint32) (MempoolTxidFilterEntries, error) {
 	return MempoolTxidFilterEntries{}, errors.New("Not supported")
 }
+
+// GetTxConflicts is not supported for Ethereum type mempool
+func (m *MempoolEthereumType) GetTxConflicts(txid string) (MempoolTxConflicts, error) {
+	return MempoolTxConflicts{}, errors.New("Not supported")
+}
+
+// GetOutpointSpender is not supported for Ethereum type mempool
+func (m *MempoolEthereumType) GetOutpointSpender(outpoint Outpoint) (string, error) {
+	return "", errors.New("Not supported")
+}
+
+// GetBlockTemplate is not supported for Ethereum type mempool
+func (m *MempoolEthereumType) GetBlockTemplate() *MempoolBlockTemplate {
+	return nil
+}
@@ -0,0 +1,207 @@
+package bchain
+
+import (
+	"container/heap"
+	"time"
+)
+
+// mempoolTxFee is the fee and the virtual size of a mempool transaction
+type mempoolTxFee struct {
+	vsize int64
+	fee   int64
+}
+
+// MempoolBlockTemplate is a projection of the mempool transactions to the next blocks,
+// the transactions are selected by the feerate of their package (transaction with its unconfirmed ancestors)
+type MempoolBlockTemplate struct {
+	// FeePerKB contains for the n-th block (at index n-1) the fee rate in sat/kB needed to get to the block
+	FeePerKB []int64
+	// Txs is the number of transactions in the template
+	Txs int
+	// VSize is the total virtual size of the transactions in the template
+	VSize int64
+	// Time is the time when the template was created
+	Time     time.Time
+	txBlocks map[string]int
+}
+
+// TxBlock returns the projected block of the transaction (1 is the next block), 0 if the transaction is not in the template
+func (t *MempoolBlockTemplate) TxBlock(txid string) int {
+	return t.txBlocks[txid]
+}
+
+type templateTx struct {
+	txid     string
+	fee      mempoolTxFee
+	parents  []*templateTx
+	children []*templateTx
+	included bool
+	// the package of the transaction and its ancestors not yet included in the template
+	pkgFee   int64
+	pkgVSize int64
+	version  int
+}
+
+func (t *templateTx) feeRate() float64 {
+	return float64(t.pkgFee) / float64(t.pkgVSize)
+}
+
+// ancestors appends to rv the ancestors of the transaction not yet included in the template and then the transaction itself
+func (t *templateTx) ancestors(visited map[*templateTx]struct{}, rv []*templateTx) []*templateTx {
+	visited[t] = struct{}{}
+	for _, p := range t.parents {
+		if _, found := visited[p]; !found && !p.included {
+			rv = p.ancestors(visited, rv)
+		}
+	}
+	return append(rv, t)
+}
+
+// updatePackage recomputes the fee and the size of the package of the transaction
+func (t *templateTx) updatePackage() {
+	t.pkgFee = 0
+	t.pkgVSize = 0
+	for _, a := range t.ancestors(make(map[*templateTx]struct{}), nil) {
+		t.pkgFee += a.fee.fee
+		t.pkgVSize += a.fee.vsize
+	}
+	t.version++
+}
+
+type templateHeapEntry struct {
+	tx      *templateTx
+	feeRate float64
+	version int
+}
+
+type templateHeap []templateHeapEntry
+
+func (h templateHeap) Len() int { return len(h) }
+func (h templateHeap) Less(i, j int) bool {
+	if h[i].feeRate == h[j].feeRate {
+		return h[i].tx.txid < h[j].tx.txid
+	}
+	return h[i].feeRate > h[j].feeRate
+}
+func (h templateHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *templateHeap) Push(x interface{}) { *h = append(*h, x.(templateHeapEntry)) }
+func (h *templateHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	x := old[n-1]
+	*h = old[:n-1]
+	return x
+}
+
+// newMempoolBlockTemplate projects the transactions to blocks of the size blockVSize,
+// the fee rate of a block is the fee rate of the package which crosses its boundary,
+// the fee rate of the last, not full block is minFeePerKB
+func newMempoolBlockTemplate(fees map[string]mempoolTxFee, inputs map[string][]Outpoint, blockVSize int64, minFeePerKB int64) *MempoolBlockTemplate {
+	txs := make(map[string]*templateTx, len(fees))
+	for txid, fee := range fees {
+		txs[txid] = &templateTx{txid: txid, fee: fee}
+	}
+	for _, t := range txs {
+		for _, o := range inputs[t.txid] {
+			if p, found := txs[o.Txid]; found && !containsTemplateTx(t.parents, p) {
+				t.parents = append(t.parents, p)
+				p.children = append(p.children, t)
+			}
+		}
+	}
+	h := make(templateHeap, 0, len(txs))
+	for _, t := range txs {
+		t.updatePackage()
+		h = append(h, templateHeapEntry{t, t.feeRate(), t.version})
+	}
+	heap.Init(&h)
+	rv := &MempoolBlockTemplate{
+		FeePerKB: make([]int64, 0),
+		Time:     time.Now(),
+		txBlocks: make(map[string]int, len(txs)),
+	}
+	for h.Len() > 0 {
+		e := heap.Pop(&h).(templateHeapEntry)
+		if e.tx.included || e.version != e.tx.version {
+			continue
+		}
+		pkg := e.tx.ancestors(make(map[*templateTx]struct{}), nil)
+		vsize := rv.VSize
+		for _, t := range pkg {
+			t.included = true
+			rv.txBlocks[t.txid] = int(vsize/blockVSize) + 1
+			vsize += t.fee.vsize
+		}
+		// the package crosses one or more block boundaries
+		for int64(len(rv.FeePerKB)+1)*blockVSize <= vsize {
+			rv.FeePerKB = append(rv.FeePerKB, int64(e.feeRate*1000))
+		}
+		rv.VSize = vsize
+		rv.Txs += len(pkg)
+		// update the packages of the descendants of the included transactions
+		updated := make(map[*templateTx]struct{})
+		queue := make([]*templateTx, 0)
+		for _, t := range pkg {
+			queue = append(queue, t.children...)
+		}
+		for len(queue) > 0 {
+			t := queue[0]
+			queue = queue[1:]
+			if _, found := updated[t]; found || t.included {
+				continue
+			}
+			updated[t] = struct{}{}
+			t.updatePackage()
+			heap.Push(&h, templateHeapEntry{t, t.feeRate(), t.version})
+			queue = append(queue, t.children...)
+		}
+	}
+	if rv.VSize > int64(len(rv.FeePerKB))*blockVSize || len(rv.FeePerKB) == 0 {
+		rv.FeePerKB = append(rv.FeePerKB, minFeePerKB)
+	}
+	// the fee rates of the packages are not strictly decreasing, make the fee rates of the blocks non increasing
+	for i := range rv.FeePerKB {
+		if rv.FeePerKB[i] < minFeePerKB {
+			rv.FeePerKB[i] = minFeePerKB
+		}
+		if i > 0 && rv.FeePerKB[i] > rv.FeePerKB[i-1] {
+			rv.FeePerKB[i] = rv.FeePerKB[i-1]
+		}
+	}
+	return rv
+}
+
+func containsTemplateTx(a []*templateTx, t *templateTx) bool {
+	for _, v := range a {
+		if v == t {
+			return true
+		}
+	}
+	return false
+}
+
+// UpdateBlockTemplate creates a new block template from the current mempool transactions
+func (m *MempoolBitcoinType) UpdateBlockTemplate(blockVSize int64, minFeePerKB int64) *MempoolBlockTemplate {
+	m.mux.Lock()
+	fees := make(map[string]mempoolTxFee, len(m.txFees))
+	for txid, fee := range m.txFees {
+		fees[txid] = fee
+	}
+	inputs := make(map[string][]Outpoint, len(fees))
+	for txid := range fees {
+		inputs[txid] = m.txInputs[txid]
+	}
+	m.mux.Unlock()
+	t := newMempoolBlockTemplate(fees, inputs, blockVSize, minFeePerKB)
+	m.mux.Lock()
+	m.blockTemplate = t
+	m.mux.Unlock()
+	return t
+}
+
+// GetBlockTemplate returns the last block template created by UpdateBlockTemplate, nil if there is none
+func (m *MempoolBitcoinType) GetBlockTemplate() *MempoolBlockTemplate {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	return m.blockTemplate
+}
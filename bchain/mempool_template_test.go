@@ -0,0 +1,86 @@
+package bchain
+
+import (
+	"reflect"
+	"testing"
+)
+
+func Test_newMempoolBlockTemplate(t *testing.T) {
+	tests := []struct {
+		name         string
+		fees         map[string]mempoolTxFee
+		inputs       map[string][]Outpoint
+		wantFeePerKB []int64
+		wantTxs      int
+		wantVSize    int64
+		wantBlocks   map[string]int
+	}{
+		{
+			name:         "empty",
+			fees:         map[string]mempoolTxFee{},
+			inputs:       map[string][]Outpoint{},
+			wantFeePerKB: []int64{1000},
+			wantBlocks:   map[string]int{},
+		},
+		{
+			name: "child pays for parent",
+			fees: map[string]mempoolTxFee{
+				"a": {vsize: 500, fee: 500},
+				"b": {vsize: 500, fee: 9500},
+				"c": {vsize: 500, fee: 2500},
+				"d": {vsize: 500, fee: 1000},
+				"e": {vsize: 300, fee: 300},
+			},
+			inputs: map[string][]Outpoint{
+				"a": {{Txid: "confirmed", Vout: 0}},
+				"b": {{Txid: "a", Vout: 0}, {Txid: "a", Vout: 1}},
+				"c": {{Txid: "confirmed", Vout: 1}},
+				"d": {{Txid: "confirmed", Vout: 2}},
+				"e": {{Txid: "confirmed", Vout: 3}},
+			},
+			wantFeePerKB: []int64{10000, 2000, 1000},
+			wantTxs:      5,
+			wantVSize:    2300,
+			wantBlocks:   map[string]int{"a": 1, "b": 1, "c": 2, "d": 2, "e": 3},
+		},
+		{
+			name: "low fee child of high fee parent",
+			fees: map[string]mempoolTxFee{
+				"p": {vsize: 500, fee: 5000},
+				"q": {vsize: 500, fee: 500},
+				"r": {vsize: 500, fee: 4000},
+			},
+			inputs: map[string][]Outpoint{
+				"p": {{Txid: "confirmed", Vout: 0}},
+				"q": {{Txid: "p", Vout: 0}},
+				"r": {{Txid: "confirmed", Vout: 1}},
+			},
+			wantFeePerKB: []int64{8000, 1000},
+			wantTxs:      3,
+			wantVSize:    1500,
+			wantBlocks:   map[string]int{"p": 1, "r": 1, "q": 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newMempoolBlockTemplate(tt.fees, tt.inputs, 1000, 1000)
+			if !reflect.DeepEqual(got.FeePerKB, tt.wantFeePerKB) {
+				t.Errorf("FeePerKB = %v, want %v", got.FeePerKB, tt.wantFeePerKB)
+			}
+			if got.Txs != tt.wantTxs {
+				t.Errorf("Txs = %v, want %v", got.Txs, tt.wantTxs)
+			}
+			if got.VSize != tt.wantVSize {
+				t.Errorf("VSize = %v, want %v", got.VSize, tt.wantVSize)
+			}
+			for txid, want := range tt.wantBlocks {
+				if b := got.TxBlock(txid); b != want {
+					t.Errorf("TxBlock(%v) = %v, want %v", txid, b, want)
+				}
+			}
+			if b := got.TxBlock("unknown"); b != 0 {
+				t.Errorf("TxBlock(unknown) = %v, want 0", b)
+			}
+		})
+	}
+}
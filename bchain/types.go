@@ -258,16 +258,30 @@ const (
 	P2SHWPKH
 	P2WPKH
 	P2TR
+	P2SH
+	P2WSH
+	P2SHWSH
 )
 
+// XpubDescriptorKey is one of the keys of a script (multisig, miniscript) xpub descriptor
+type XpubDescriptorKey struct {
+	Xpub   string      // Xpub part of the key expression, empty for a fixed public key
+	Origin string      // key origin without the brackets, e.g. 5c9e228d/48'/0'/0'/2'
+	ExtKey interface{} // extended key parsed from xpub, nil for a fixed public key
+	PubKey []byte      // fixed public key, nil for an xpub key
+}
+
 // XpubDescriptor contains parsed data from xpub descriptor
 type XpubDescriptor struct {
 	XpubDescriptor string // The whole descriptor
-	Xpub           string // Xpub part of the descriptor
+	Xpub           string // Xpub part of the descriptor, the first xpub key in case of a script descriptor
 	Type           ScriptType
 	Bip            string
 	ChangeIndexes  []uint32
 	ExtKey         interface{} // extended key parsed from xpub, usually of type *hdkeychain.ExtendedKey
+	// Keys and ScriptTemplate are set only for script descriptors (types P2SH, P2WSH and P2SHWSH)
+	Keys           []XpubDescriptorKey
+	ScriptTemplate interface{} // parsed script expression, its type is specific to the chain parser
 }
 
 // MempoolTxidEntries is array of MempoolTxidEntry
@@ -280,9 +294,19 @@ type MempoolTxidFilterEntries struct {
 	UsedZeroedKey bool              `json:"usedZeroedKey,omitempty"`
 }
 
+// MempoolTxConflicts contains the replacements of a mempool transaction and other mempool transactions spending the same outpoints
+type MempoolTxConflicts struct {
+	ReplacedBy string
+	Replaces   []string
+	Conflicts  []string
+}
+
 // OnNewBlockFunc is used to send notification about a new block
 type OnNewBlockFunc func(hash string, height uint32)
 
+// OnDisconnectBlocksFunc is used to send notification about blocks disconnected because of a fork
+type OnDisconnectBlocksFunc func(lower uint32, higher uint32, hashes []string)
+
 // OnNewTxAddrFunc is used to send notification about a new transaction/address
 type OnNewTxAddrFunc func(tx *Tx, desc AddressDescriptor)
 
@@ -332,10 +356,19 @@ type BlockChain interface {
 	EthereumTypeGetBalance(addrDesc AddressDescriptor) (*big.Int, error)
 	EthereumTypeGetNonce(addrDesc AddressDescriptor) (uint64, error)
 	EthereumTypeEstimateGas(params map[string]interface{}) (uint64, error)
+	EthereumTypeGetEip1559Fees() (*Eip1559Fees, error)
 	EthereumTypeGetErc20ContractBalance(addrDesc, contractDesc AddressDescriptor) (*big.Int, error)
+	EthereumTypeGetErc20ContractAllowance(ownerDesc, spenderDesc, contractDesc AddressDescriptor) (*big.Int, error)
 	EthereumTypeGetSupportedStakingPools() []string
 	EthereumTypeGetStakingPoolsData(addrDesc AddressDescriptor) ([]StakingPoolData, error)
 	GetTokenURI(contractDesc AddressDescriptor, tokenID *big.Int) (string, error)
+	EthereumTypeGetReceiptProof(txid string) (*EthereumReceiptProof, error)
+	// EthereumType staged block fetching used by the parallel bulk sync
+	EthereumTypeGetBlockBodies(lower, higher uint32) ([]*EthereumStagedBlock, error)
+	EthereumTypeGetBlockLogs(blocks []*EthereumStagedBlock) error
+	EthereumTypeGetBlockInternalData(blocks []*EthereumStagedBlock) error
+	EthereumTypeAssembleBlock(block *EthereumStagedBlock) (*Block, error)
+	EthereumTypeGetAddressAliasRecords(lower, higher uint32) ([]AddressAliasRecord, error)
 }
 
 // BlockChainParser defines common interface to parsing and conversions of block chain data
@@ -349,6 +382,8 @@ type BlockChainParser interface {
 	AmountDecimals() int
 	// UseAddressAliases returns true if address aliases are enabled
 	UseAddressAliases() bool
+	// UseContractLogs returns true if the contract log index is enabled
+	UseContractLogs() bool
 	// MinimumCoinbaseConfirmations returns minimum number of confirmations a coinbase transaction must have before it can be spent
 	MinimumCoinbaseConfirmations() int
 	// SupportsVSize returns true if vsize of a transaction should be computed and returned by API
@@ -384,6 +419,8 @@ type BlockChainParser interface {
 	DeriveAddressDescriptorsFromTo(descriptor *XpubDescriptor, change uint32, fromIndex uint32, toIndex uint32) ([]AddressDescriptor, error)
 	// EthereumType specific
 	EthereumTypeGetTokenTransfersFromTx(tx *Tx) (TokenTransfers, error)
+	EthereumTypeGetTokenApprovalsFromTx(tx *Tx) (TokenApprovals, error)
+	EthereumTypeGetContractLogsFromBlock(block *Block) (ContractLogs, error)
 	// AddressAlias
 	FormatAddressAlias(address string, name string) string
 }
@@ -396,4 +433,7 @@ type Mempool interface {
 	GetAllEntries() MempoolTxidEntries
 	GetTransactionTime(txid string) uint32
 	GetTxidFilterEntries(filterScripts string, fromTimestamp uint32) (MempoolTxidFilterEntries, error)
+	GetTxConflicts(txid string) (MempoolTxConflicts, error)
+	GetOutpointSpender(outpoint Outpoint) (string, error)
+	GetBlockTemplate() *MempoolBlockTemplate
 }
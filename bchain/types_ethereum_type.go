@@ -14,7 +14,8 @@ type EthereumInternalTransfer struct {
 	Value big.Int                         `json:"value"`
 }
 
-// FourByteSignature contains data about about a contract function signature
+// FourByteSignature contains data about about a contract function signature,
+// the same structure is used for the contract event signatures
 type FourByteSignature struct {
 	// stored in DB
 	Name       string
@@ -25,8 +26,9 @@ type FourByteSignature struct {
 	ParsedParameters []abi.Type
 }
 
-// EthereumParsedInputParam contains data about a contract function parameter
+// EthereumParsedInputParam contains data about a contract function parameter, the name is known only from the contract ABI
 type EthereumParsedInputParam struct {
+	Name   string   `json:"name,omitempty"`
 	Type   string   `json:"type"`
 	Values []string `json:"values,omitempty"`
 }
@@ -39,6 +41,21 @@ type EthereumParsedInputData struct {
 	Params   []EthereumParsedInputParam `json:"params,omitempty"`
 }
 
+// EthereumParsedLogParam contains data about a parameter of a contract event, the indexed parameters are taken from the log topics
+type EthereumParsedLogParam struct {
+	Name    string   `json:"name,omitempty"`
+	Type    string   `json:"type"`
+	Indexed bool     `json:"indexed,omitempty"`
+	Values  []string `json:"values,omitempty"`
+}
+
+// EthereumParsedLogData contains the event decoded from a log
+type EthereumParsedLogData struct {
+	Name   string                   `json:"name"`
+	Event  string                   `json:"event"`
+	Params []EthereumParsedLogParam `json:"params,omitempty"`
+}
+
 // EthereumInternalTransactionType - type of ethereum transaction from internal data
 type EthereumInternalTransactionType int
 
@@ -94,6 +111,30 @@ type TokenTransfer struct {
 	MultiTokenValues []MultiTokenValue
 }
 
+// TokenApproval contains a single token approval. The ERC20 Approval event sets the approved amount in Value,
+// the ERC721 and ERC1155 ApprovalForAll event sets Value to 1 if the operator is approved and to 0 if it is revoked.
+type TokenApproval struct {
+	ForAll   bool
+	Contract string
+	Owner    string
+	Spender  string
+	Value    big.Int
+}
+
+// TokenApprovals is array of TokenApproval
+type TokenApprovals []*TokenApproval
+
+// ContractLog is a log of the block selected by the contract log filters of the coin configuration,
+// TxIndex is the position of the transaction in the block, LogIndex is the position of the log in the block
+type ContractLog struct {
+	TxIndex  int
+	LogIndex uint32
+	Log      *RpcLog
+}
+
+// ContractLogs is array of ContractLog
+type ContractLogs []*ContractLog
+
 // RpcTransaction is returned by eth_getTransactionByHash
 type RpcTransaction struct {
 	AccountNonce     string `json:"nonce"`
@@ -107,6 +148,9 @@ type RpcTransaction struct {
 	BlockHash        string `json:"blockHash,omitempty"`
 	From             string `json:"from"`
 	TransactionIndex string `json:"transactionIndex"`
+	// EIP-1559 fee parameters, set only for the transactions of type 2 and higher
+	MaxFeePerGas         string `json:"maxFeePerGas,omitempty"`
+	MaxPriorityFeePerGas string `json:"maxPriorityFeePerGas,omitempty"`
 	// Signature values - ignored
 	// V string `json:"v"`
 	// R string `json:"r"`
@@ -122,9 +166,22 @@ type RpcLog struct {
 
 // RpcLog is returned by eth_getTransactionReceipt
 type RpcReceipt struct {
-	GasUsed string    `json:"gasUsed"`
-	Status  string    `json:"status"`
-	Logs    []*RpcLog `json:"logs"`
+	GasUsed           string    `json:"gasUsed"`
+	Status            string    `json:"status"`
+	Logs              []*RpcLog `json:"logs"`
+	EffectiveGasPrice string    `json:"effectiveGasPrice,omitempty"`
+}
+
+// EthereumReceiptProof is a proof of a transaction receipt in the receipts trie of a block
+type EthereumReceiptProof struct {
+	BlockHash    string
+	BlockNumber  uint32
+	Header       string // RLP encoded block header, empty if it cannot be reconstructed from the backend data
+	ReceiptsRoot string
+	TxIndex      uint32
+	Key          string   // RLP encoded transaction index, the key in the receipts trie
+	Receipt      string   // consensus encoded receipt, the value in the receipts trie
+	Proof        []string // trie nodes on the path from the root to the receipt
 }
 
 // EthereumSpecificData contains data specific to Ethereum transactions
@@ -132,6 +189,23 @@ type EthereumSpecificData struct {
 	Tx           *RpcTransaction       `json:"tx"`
 	InternalData *EthereumInternalData `json:"internalData,omitempty"`
 	Receipt      *RpcReceipt           `json:"receipt,omitempty"`
+	// base fee of the block containing the transaction, empty for the blocks before EIP-1559
+	BaseFeePerGas string `json:"baseFeePerGas,omitempty"`
+}
+
+// Eip1559Fee contains the fee parameters of an EIP-1559 transaction
+type Eip1559Fee struct {
+	MaxFeePerGas         *big.Int
+	MaxPriorityFeePerGas *big.Int
+}
+
+// Eip1559Fees is the estimation of the EIP-1559 fee parameters for the slow, normal and fast inclusion of a transaction
+type Eip1559Fees struct {
+	// base fee of the next block
+	BaseFeePerGas *big.Int
+	Slow          *Eip1559Fee
+	Normal        *Eip1559Fee
+	Fast          *Eip1559Fee
 }
 
 // AddressAliasRecord maps address to ENS name
@@ -159,3 +233,12 @@ type StakingPoolData struct {
 	RestakedReward          big.Int `json:"restakedReward"`          // restakedRewardOf method
 	AutocompoundBalance     big.Int `json:"autocompoundBalance"`     // autocompoundBalanceOf method
 }
+
+// EthereumStagedBlock is a block fetched in independent stages by the parallel bulk sync.
+// The block body is fetched first, then the logs and the internal data stages fill their parts of the chain specific Data,
+// the stages of one block may run concurrently. The block is assembled after all stages are finished.
+type EthereumStagedBlock struct {
+	Height uint32
+	Hash   string
+	Data   interface{}
+}
@@ -15,6 +15,7 @@ export interface EthereumInternalTransfer {
     value: string;
 }
 export interface EthereumParsedInputParam {
+    name?: string;
     type: string;
     values?: string[];
 }
@@ -24,6 +25,23 @@ export interface EthereumParsedInputData {
     function?: string;
     params?: EthereumParsedInputParam[];
 }
+export interface EthereumParsedLogParam {
+    name?: string;
+    type: string;
+    indexed?: boolean;
+    values?: string[];
+}
+export interface EthereumParsedLogData {
+    name: string;
+    event: string;
+    params?: EthereumParsedLogParam[];
+}
+export interface EthereumLog {
+    address: string;
+    topics: string[];
+    data?: string;
+    parsedData?: EthereumParsedLogData;
+}
 export interface EthereumSpecific {
     type?: number;
     createdContract?: string;
@@ -33,9 +51,24 @@ export interface EthereumSpecific {
     gasLimit: number;
     gasUsed?: number;
     gasPrice?: string;
+    maxFeePerGas?: string;
+    maxPriorityFeePerGas?: string;
+    effectiveGasPrice?: string;
+    baseFeePerGas?: string;
     data?: string;
     parsedData?: EthereumParsedInputData;
     internalTransfers?: EthereumInternalTransfer[];
+    logs?: EthereumLog[];
+}
+export interface Eip1559Fee {
+    maxFeePerGas: string;
+    maxPriorityFeePerGas: string;
+}
+export interface Eip1559Fees {
+    baseFeePerGas: string;
+    slow: Eip1559Fee;
+    normal: Eip1559Fee;
+    fast: Eip1559Fee;
 }
 export interface MultiTokenValue {
     id?: string;
@@ -98,6 +131,8 @@ export interface Tx {
     fees?: string;
     hex?: string;
     rbf?: boolean;
+    replacedBy?: string;
+    replaces?: string[];
     coinSpecificData?: any;
     tokenTransfers?: TokenTransfer[];
     ethereumSpecific?: EthereumSpecific;
@@ -148,6 +183,7 @@ export interface Address {
     page?: number;
     totalPages?: number;
     itemsOnPage?: number;
+    nextCursor?: string;
     address: string;
     balance: string;
     totalReceived?: string;
@@ -155,6 +191,7 @@ export interface Address {
     unconfirmedBalance: string;
     unconfirmedTxs: number;
     txs: number;
+    historyTruncated?: boolean;
     addrTxCount?: number;
     nonTokenTxs?: number;
     internalTxs?: number;
@@ -184,6 +221,13 @@ export interface Utxo {
     lockTime?: number;
     coinbase?: boolean;
 }
+export interface AddressState {
+    height: number;
+    hash: string;
+    blockTime: number;
+    address: Address;
+    utxos: Utxo[];
+}
 export interface BalanceHistory {
     time: number;
     txs: number;
@@ -204,12 +248,14 @@ export interface Blocks {
     page?: number;
     totalPages?: number;
     itemsOnPage?: number;
+    nextCursor?: string;
     blocks: BlockInfo[];
 }
 export interface Block {
     page?: number;
     totalPages?: number;
     itemsOnPage?: number;
+    nextCursor?: string;
     hash: string;
     previousBlockHash?: string;
     nextBlockHash?: string;
@@ -230,6 +276,19 @@ export interface Block {
 export interface BlockRaw {
     hex: string;
 }
+export interface TxProof {
+    txid: string;
+    blockHash: string;
+    blockHeight: number;
+    header?: string;
+    index: number;
+    merkleRoot?: string;
+    merkle?: string[];
+    receiptsRoot?: string;
+    key?: string;
+    receipt?: string;
+    proof?: string[];
+}
 export interface BackendInfo {
     error?: string;
     chain?: string;
@@ -270,6 +329,7 @@ export interface BlockbookInfo {
     mempoolSize: number;
     decimals: number;
     dbSize: number;
+    prunedHeight?: number;
     hasFiatRates?: boolean;
     hasTokenFiatRates?: boolean;
     currentFiatRatesTime?: string;
@@ -284,6 +344,72 @@ export interface SystemInfo {
     blockbook: BlockbookInfo;
     backend: BackendInfo;
 }
+export interface MempoolConflicts {
+    txid: string;
+    replacedBy?: string;
+    replaces?: string[];
+    conflicts?: string[];
+    replacementChain?: string[];
+}
+export interface MempoolPackage {
+    txid: string;
+    fees: string;
+    vsize: number;
+    feePerKb: number;
+    rbf?: boolean;
+    ancestorCount: number;
+    ancestorSize: number;
+    ancestorFees: string;
+    ancestorFeePerKb: number;
+    descendantCount: number;
+    descendantSize: number;
+    descendantFees: string;
+    effectiveFeePerKb: number;
+    targetFeePerKb: number;
+    cpfpVSize: number;
+    cpfpFee: string;
+    rbfFee: string;
+    ancestors?: MempoolPackageTx[];
+    descendants?: MempoolPackageTx[];
+}
+export interface MempoolPackageTx {
+    txid: string;
+    fees: string;
+    vsize: number;
+    feePerKb: number;
+    depends?: string[];
+}
+export interface TokenApproval {
+    type: '' | 'ERC20' | 'ERC721' | 'ERC1155';
+    contract: string;
+    name?: string;
+    symbol?: string;
+    decimals?: number;
+    spender: string;
+    forAll?: boolean;
+    value?: string;
+    height: number;
+}
+export interface TokenApprovals {
+    address: string;
+    approvals: TokenApproval[];
+}
+export interface ContractLog {
+    txid: string;
+    blockHeight: number;
+    logIndex: number;
+    topics: string[];
+    data: string;
+}
+export interface ContractLogs {
+    page?: number;
+    totalPages?: number;
+    itemsOnPage?: number;
+    nextCursor?: string;
+    contract: string;
+    topic0: string;
+    logs: ContractLog[];
+}
 export interface FiatTicker {
     ts?: number;
     rates: { [key: string]: number };
@@ -340,6 +466,7 @@ export interface WsAccountInfoReq {
     contractFilter?: string;
     secondaryCurrency?: string;
     gap?: number;
+    cursor?: string;
 }
 export interface WsBackendInfo {
     version?: string;
@@ -383,6 +510,12 @@ export interface WsBlockFiltersBatchReq {
 export interface WsAccountUtxoReq {
     descriptor: string;
 }
+export interface WsAccountStateAtReq {
+    descriptor: string;
+    height?: number;
+    time?: number;
+    gap?: number;
+}
 export interface WsBalanceHistoryReq {
     descriptor: string;
     from?: number;
@@ -412,6 +545,7 @@ export interface WsEstimateFeeRes {
     feePerTx?: string;
     feePerUnit?: string;
     feeLimit?: string;
+    eip1559?: Eip1559Fees;
 }
 export interface WsSendTransactionReq {
     hex: string;
@@ -423,6 +557,19 @@ export interface WsSubscribeFiatRatesReq {
     currency?: string;
     tokens?: string[];
 }
+export interface WsSubscribeTxStatusReq {
+    txids?: string[];
+    addresses?: string[];
+    confirmations?: number;
+}
+export interface WsTxStatus {
+    event: 'confirmed' | 'confirmations' | 'replaced' | 'evicted' | 'reorged';
+    txid: string;
+    blockHeight?: number;
+    blockHash?: string;
+    confirmations?: number;
+    replacedBy?: string;
+}
 export interface WsCurrentFiatRatesReq {
     currencies?: string[];
     token?: string;
@@ -25,6 +25,7 @@ import (
 	"github.com/trezor/blockbook/fiat"
 	"github.com/trezor/blockbook/fourbyte"
 	"github.com/trezor/blockbook/server"
+	"github.com/trezor/blockbook/webhook"
 )
 
 // debounce too close requests for resync
@@ -36,6 +37,12 @@ const debounceResyncMempoolMs = 1009
 // store internal state about once every minute
 const storeInternalStatePeriodMs = 59699
 
+// prune the history of the index about once every 10 minutes, if not triggered by the resync of the index
+const pruneIndexPeriodMs = 600011
+
+// debounce too close requests for pruning
+const debouncePruneIndexMs = 10007
+
 // exit codes from the main function
 const exitCodeOK = 0
 const exitCodeFatal = 255
@@ -54,6 +61,14 @@ var (
 	synchronize = flag.Bool("sync", false, "synchronizes until tip, if together with zeromq, keeps index synchronized")
 	repair      = flag.Bool("repair", false, "repair the database")
 	fixUtxo     = flag.Bool("fixutxo", false, "check and fix utxo db and exit")
+	checkpoint  = flag.String("checkpoint", "", "create a checkpoint of the database with a manifest in the given directory and exit")
+	checkpoints = flag.String("checkpointsdir", "", "directory in which the checkpoints requested in the internal server are created (default checkpoints from the internal server disabled)")
+	verifyDb    = flag.Bool("verifydb", false, "verify the address balances in the database against the indexed transactions and exit")
+	repairDb    = flag.Bool("verifydbrepair", false, "repair the address balances found inconsistent by -verifydb")
+	migrate     = flag.Bool("migrate", false, "run the pending migrations of the database to the current data version and exit")
+	rebuild     = flag.String("rebuild", "", "rebuild the derived column (blockFilter, extendedIndex, addressContracts, addressAliases, approvals or contractLogs) of the database and exit, an interrupted rebuild is resumed by the next run")
+	replica     = flag.String("replica", "", "run as read only replica serving the public interface from the database in datadir, opened as RocksDB secondary instance keeping its files in the given directory")
+	bootstrap   = flag.String("bootstrap", "", "initialize the empty datadir from the database checkpoint in the given directory, the checkpoint is validated against the coin and the backend")
 	prof        = flag.String("prof", "", "http server binding [address]:port of the interface to profiling data /debug/pprof/ (default no profiling)")
 
 	syncChunk   = flag.Int("chunk", 100, "block chunk size for processing in bulk mode")
@@ -63,6 +78,7 @@ var (
 	debugMode = flag.Bool("debug", false, "debug mode, return more verbose errors, reload templates on each request")
 
 	internalBinding = flag.String("internal", "", "internal http server binding [address]:port, (default no internal server)")
+	adminAuthFile   = flag.String("adminauth", "", "path to file containing user:password for the basic authentication of the admin actions controlling the synchronization in the internal server and of the replica relay (default admin actions disabled)")
 
 	publicBinding = flag.String("public", "", "public http server binding [address]:port[/path] (default no public server)")
 
@@ -70,7 +86,8 @@ var (
 
 	explorerURL = flag.String("explorer", "", "address of blockchain explorer")
 
-	noTxCache = flag.Bool("notxcache", false, "disable tx cache")
+	noTxCache     = flag.Bool("notxcache", false, "disable tx cache")
+	txCacheMemory = flag.Int("txcachememory", 1<<26, "size limit in bytes of the in-memory LRU layer of the tx cache, 0 disables the in-memory layer")
 
 	enableSubNewTx = flag.Bool("enablesubnewtx", false, "enable support for subscribing to all new transactions")
 
@@ -85,6 +102,14 @@ var (
 	resyncMempoolPeriodMs = flag.Int("resyncmempoolperiod", 60017, "resync mempool period in milliseconds")
 
 	extendedIndex = flag.Bool("extendedindex", false, "if true, create index of input txids and spending transactions")
+
+	pruneDepth = flag.Int("prunedepth", 0, "keep the complete address history only for the given number of the last blocks, the older history is pruned in background (default 0 no pruning)")
+
+	enableWebhooks = flag.Bool("webhooks", false, "enable webhook notifications about transactions of subscribed addresses and xpubs, managed in the internal server")
+
+	contractABIsDir = flag.String("contractabis", "", "directory with the verified contract ABIs in the files <contract address>.json imported on the start, Ethereum type coins only (the ABIs can be managed also in the internal server)")
+
+	replicaPrimary = flag.String("replicaprimary", "", "url of the internal server of the primary Blockbook, from which the replica receives the notifications about new blocks and mempool transactions, authenticated by the credentials from -adminauth")
 )
 
 var (
@@ -94,6 +119,8 @@ var (
 	chanSyncIndexDone             = make(chan struct{})
 	chanSyncMempoolDone           = make(chan struct{})
 	chanStoreInternalStateDone    = make(chan struct{})
+	chanPruneIndex                = make(chan struct{})
+	chanPruneIndexDone            = make(chan struct{})
 	chain                         bchain.BlockChain
 	mempool                       bchain.Mempool
 	index                         *db.RocksDB
@@ -102,7 +129,12 @@ var (
 	syncWorker                    *db.SyncWorker
 	internalState                 *common.InternalState
 	fiatRates                     *fiat.FiatRates
+	webhooks                      *webhook.Dispatcher
+	syncControl                   *db.SyncControl
+	replicaRelay                  *server.ReplicaRelay
+	replicaWorker                 *db.ReplicaWorker
 	callbacksOnNewBlock           []bchain.OnNewBlockFunc
+	callbacksOnDisconnectBlocks   []bchain.OnDisconnectBlocksFunc
 	callbacksOnNewTxAddr          []bchain.OnNewTxAddrFunc
 	callbacksOnNewTx              []bchain.OnNewTxFunc
 	callbacksOnNewFiatRatesTicker []fiat.OnNewFiatRatesTicker
@@ -169,18 +201,89 @@ func mainWithExitCode() int {
 		return exitCodeFatal
 	}
 
-	index, err = db.NewRocksDB(*dbPath, *dbCache, *dbMaxOpenFiles, chain.GetChainParser(), metrics, *extendedIndex)
+	if *bootstrap != "" {
+		if err = bootstrapFromCheckpoint(*bootstrap, config.CoinName); err != nil {
+			glog.Error("bootstrap: ", err)
+			return exitCodeFatal
+		}
+	}
+
+	if *replica != "" {
+		index, err = db.NewRocksDBSecondary(*dbPath, *replica, *dbCache, chain.GetChainParser(), metrics, *extendedIndex)
+	} else {
+		index, err = db.NewRocksDB(*dbPath, *dbCache, *dbMaxOpenFiles, chain.GetChainParser(), metrics, *extendedIndex)
+	}
 	if err != nil {
 		glog.Error("rocksDB: ", err)
 		return exitCodeFatal
 	}
 	defer index.Close()
 
+	if *rebuild != "" {
+		if err = index.SetRebuild(*rebuild); err != nil {
+			glog.Error("rebuild: ", err)
+			return exitCodeFatal
+		}
+	}
+
+	if *pruneDepth > 0 && *replica == "" {
+		if err = index.SetPruneDepth(uint32(*pruneDepth)); err != nil {
+			glog.Error("prune: ", err)
+			return exitCodeFatal
+		}
+	}
+
 	internalState, err = newInternalState(config, index, *enableSubNewTx)
 	if err != nil {
 		glog.Error("internalState: ", err)
 		return exitCodeFatal
 	}
+	index.SetInternalState(internalState)
+
+	if *replica != "" {
+		return runReplica(config)
+	}
+
+	// upgrade the database to the current data version, the long running migrations only on demand
+	migrations, err := index.PendingMigrations()
+	if err != nil {
+		glog.Error("migration: ", err)
+		return exitCodeFatal
+	}
+	if len(migrations) > 0 {
+		if !*migrate && !db.QuickMigrations(migrations) {
+			glog.Error("migration: the database requires migration to the current data version, run Blockbook with the -migrate flag")
+			return exitCodeFatal
+		}
+		if err = index.Migrate(chanOsSignal); err != nil {
+			if err == db.ErrOperationInterrupted {
+				glog.Info("migration: interrupted, it will be resumed by the next run")
+				return exitCodeOK
+			}
+			glog.Error("migration: ", err)
+			return exitCodeFatal
+		}
+	}
+	if *migrate {
+		return exitCodeOK
+	}
+
+	// regenerate the derived column, the database cannot be used until an interrupted rebuild is finished
+	if *rebuild != "" {
+		if err = index.Rebuild(chain, chanOsSignal); err != nil {
+			if err == db.ErrOperationInterrupted {
+				glog.Info("rebuild: interrupted, it will be resumed by the next run with -rebuild=", *rebuild)
+				return exitCodeOK
+			}
+			glog.Error("rebuild: ", err)
+			return exitCodeFatal
+		}
+		return exitCodeOK
+	}
+	if internalState.Rebuild != nil {
+		glog.Error("rebuild: the rebuild of ", internalState.Rebuild.Name, " is not finished, run Blockbook with the -rebuild=", internalState.Rebuild.Name, " flag")
+		return exitCodeFatal
+	}
 
 	// fix possible inconsistencies in the UTXO index
 	if *fixUtxo || !internalState.UtxoChecked {
@@ -202,7 +305,6 @@ func mainWithExitCode() int {
 		internalState.SortedAddressContracts = true
 	}
 
-	index.SetInternalState(internalState)
 	if *fixUtxo {
 		err = index.StoreInternalState(internalState)
 		if err != nil {
@@ -241,6 +343,22 @@ func mainWithExitCode() int {
 		return exitCodeOK
 	}
 
+	if *verifyDb {
+		if _, err = index.VerifyIndex(chanOsSignal, *repairDb, 0); err != nil && err != db.ErrOperationInterrupted {
+			glog.Error("verifyDb: ", err)
+			return exitCodeFatal
+		}
+		return exitCodeOK
+	}
+
+	if *checkpoint != "" {
+		if _, err = index.CreateCheckpoint(*checkpoint); err != nil {
+			glog.Error("checkpoint: ", err)
+			return exitCodeFatal
+		}
+		return exitCodeOK
+	}
+
 	syncWorker, err = db.NewSyncWorker(index, chain, *syncWorkers, *syncChunk, *blockFrom, *dryRun, chanOsSignal, metrics, internalState)
 	if err != nil {
 		glog.Errorf("NewSyncWorker %v", err)
@@ -263,10 +381,11 @@ func mainWithExitCode() int {
 		return exitCodeOK
 	}
 
-	if txCache, err = db.NewTxCache(index, chain, metrics, internalState, !*noTxCache); err != nil {
+	if txCache, err = db.NewTxCache(index, chain, metrics, internalState, !*noTxCache, *txCacheMemory); err != nil {
 		glog.Error("txCache ", err)
 		return exitCodeFatal
 	}
+	callbacksOnDisconnectBlocks = append(callbacksOnDisconnectBlocks, txCache.OnDisconnectBlocks)
 
 	if fiatRates, err = fiat.NewFiatRates(index, config, metrics, onNewFiatRatesTicker); err != nil {
 		glog.Error("fiatRates ", err)
@@ -278,6 +397,36 @@ func mainWithExitCode() int {
 		glog.Error("blockbookAppInfoMetric ", err)
 	}
 
+	if *contractABIsDir != "" && chain.GetChainParser().GetChainType() == bchain.ChainEthereumType {
+		n, err := index.ImportContractABIs(*contractABIsDir)
+		if err != nil {
+			glog.Error("contractABIs: ", err)
+			return exitCodeFatal
+		}
+		glog.Info("contractABIs: imported ", n, " contract ABIs from ", *contractABIsDir)
+	}
+
+	if *enableWebhooks {
+		if webhooks, err = webhook.NewDispatcher(index, chain, mempool, txCache, metrics, internalState, fiatRates); err != nil {
+			glog.Error("webhooks ", err)
+			return exitCodeFatal
+		}
+		callbacksOnNewBlock = append(callbacksOnNewBlock, webhooks.OnNewBlock)
+		callbacksOnNewTxAddr = append(callbacksOnNewTxAddr, webhooks.OnNewTxAddr)
+		go webhooks.Run()
+	}
+
+	if *synchronize {
+		syncControl = db.NewSyncControl(triggerSyncIndex, triggerStoreInternalState)
+		if *internalBinding != "" {
+			replicaRelay = server.NewReplicaRelay()
+			callbacksOnNewBlock = append(callbacksOnNewBlock, replicaRelay.OnNewBlock)
+			callbacksOnDisconnectBlocks = append(callbacksOnDisconnectBlocks, replicaRelay.OnDisconnectBlocks)
+			callbacksOnNewTxAddr = append(callbacksOnNewTxAddr, replicaRelay.OnNewTxAddr)
+			callbacksOnNewTx = append(callbacksOnNewTx, replicaRelay.OnNewTx)
+		}
+	}
+
 	var internalServer *server.InternalServer
 	if *internalBinding != "" {
 		internalServer, err = startInternalServer()
@@ -299,7 +448,7 @@ func mainWithExitCode() int {
 	if *synchronize {
 		internalState.SyncMode = true
 		internalState.InitialSync = true
-		if err := syncWorker.ResyncIndex(nil, true); err != nil {
+		if err := syncWorker.ResyncIndex(nil, nil, true); err != nil {
 			if err != db.ErrOperationInterrupted {
 				glog.Error("resyncIndex ", err)
 				return exitCodeFatal
@@ -307,23 +456,14 @@ func mainWithExitCode() int {
 			return exitCodeOK
 		}
 		// initialize mempool after the initial sync is complete
-		var addrDescForOutpoint bchain.AddrDescForOutpointFunc
-		if chain.GetChainParser().GetChainType() == bchain.ChainBitcoinType {
-			addrDescForOutpoint = index.AddrDescForOutpoint
-		}
-		err = chain.InitializeMempool(addrDescForOutpoint, onNewTxAddr, onNewTx)
-		if err != nil {
-			glog.Error("initializeMempool ", err)
+		if err = initializeMempool(); err != nil {
 			return exitCodeFatal
 		}
-		var mempoolCount int
-		if mempoolCount, err = mempool.Resync(); err != nil {
-			glog.Error("resyncMempool ", err)
-			return exitCodeFatal
-		}
-		internalState.FinishedMempoolSync(mempoolCount)
 		go syncIndexLoop()
 		go syncMempoolLoop()
+		if index.PruneDepth() > 0 {
+			go pruneIndexLoop()
+		}
 		internalState.InitialSync = false
 	}
 	go storeInternalStateLoop()
@@ -331,6 +471,7 @@ func mainWithExitCode() int {
 	if publicServer != nil {
 		// start full public interface
 		callbacksOnNewBlock = append(callbacksOnNewBlock, publicServer.OnNewBlock)
+		callbacksOnDisconnectBlocks = append(callbacksOnDisconnectBlocks, publicServer.OnDisconnectBlocks)
 		callbacksOnNewTxAddr = append(callbacksOnNewTxAddr, publicServer.OnNewTxAddr)
 		callbacksOnNewTx = append(callbacksOnNewTx, publicServer.OnNewTx)
 		callbacksOnNewFiatRatesTicker = append(callbacksOnNewFiatRatesTicker, publicServer.OnNewFiatRatesTicker)
@@ -368,10 +509,128 @@ func mainWithExitCode() int {
 		<-chanSyncIndexDone
 		<-chanSyncMempoolDone
 		<-chanStoreInternalStateDone
+		if index.PruneDepth() > 0 {
+			// syncIndexLoop, which triggers the pruning, is already stopped
+			close(chanPruneIndex)
+			<-chanPruneIndexDone
+		}
 	}
 	return exitCodeOK
 }
 
+// runReplica serves the public interface from the secondary instance of the database of the primary Blockbook,
+// the index is not synchronized, it catches up with the primary on its notifications or periodically
+func runReplica(config *common.Config) int {
+	migrations, err := index.PendingMigrations()
+	if err != nil {
+		glog.Error("migration: ", err)
+		return exitCodeFatal
+	}
+	if len(migrations) > 0 {
+		glog.Error("replica: the database requires migration, it must be migrated by the primary Blockbook")
+		return exitCodeFatal
+	}
+	if internalState.Rebuild != nil {
+		glog.Error("replica: the rebuild of ", internalState.Rebuild.Name, " is not finished by the primary Blockbook")
+		return exitCodeFatal
+	}
+	if *enableWebhooks {
+		glog.Error("replica: webhooks are not supported by the replica")
+		return exitCodeFatal
+	}
+
+	if replicaWorker, err = db.NewReplicaWorker(index, metrics, internalState); err != nil {
+		glog.Error("replica: ", err)
+		return exitCodeFatal
+	}
+	if txCache, err = db.NewTxCache(index, chain, metrics, internalState, !*noTxCache, *txCacheMemory); err != nil {
+		glog.Error("txCache ", err)
+		return exitCodeFatal
+	}
+	callbacksOnDisconnectBlocks = append(callbacksOnDisconnectBlocks, txCache.OnDisconnectBlocks)
+	if fiatRates, err = fiat.NewFiatRates(index, config, metrics, onNewFiatRatesTicker); err != nil {
+		glog.Error("fiatRates ", err)
+		return exitCodeFatal
+	}
+	if err = replicaWorker.CatchUp(nil, nil); err != nil {
+		glog.Error("replica: ", err)
+		return exitCodeFatal
+	}
+	if err = blockbookAppInfoMetric(index, chain, txCache, internalState, metrics); err != nil {
+		glog.Error("blockbookAppInfoMetric ", err)
+	}
+
+	var internalServer *server.InternalServer
+	if *internalBinding != "" {
+		internalServer, err = startInternalServer()
+		if err != nil {
+			glog.Error("internal server: ", err)
+			return exitCodeFatal
+		}
+	}
+	var publicServer *server.PublicServer
+	if *publicBinding != "" {
+		publicServer, err = startPublicServer()
+		if err != nil {
+			glog.Error("public server: ", err)
+			return exitCodeFatal
+		}
+	}
+
+	// the replica does not synchronize its own mempool, the new mempool transactions are relayed from the primary
+	go replicaSyncLoop()
+	go storeInternalStateLoop()
+
+	if publicServer != nil {
+		callbacksOnNewBlock = append(callbacksOnNewBlock, publicServer.OnNewBlock)
+		callbacksOnDisconnectBlocks = append(callbacksOnDisconnectBlocks, publicServer.OnDisconnectBlocks)
+		callbacksOnNewTxAddr = append(callbacksOnNewTxAddr, publicServer.OnNewTxAddr)
+		callbacksOnNewTx = append(callbacksOnNewTx, publicServer.OnNewTx)
+		callbacksOnNewFiatRatesTicker = append(callbacksOnNewFiatRatesTicker, publicServer.OnNewFiatRatesTicker)
+		publicServer.ConnectFullPublicInterface()
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	if *replicaPrimary != "" {
+		adminAuth, err := readAdminAuth()
+		if err != nil {
+			glog.Error("adminauth: ", err)
+			cancel()
+			return exitCodeFatal
+		}
+		go server.RunReplicaClient(ctx, strings.TrimSuffix(*replicaPrimary, "/")+"/replica/notifications", adminAuth, pushSynchronizationHandler, onNewTxAddr, onNewTx)
+	}
+
+	waitForSignalAndShutdown(internalServer, publicServer, chain, 10*time.Second)
+
+	cancel()
+	close(chanSyncIndex)
+	close(chanStoreInternalState)
+	<-chanSyncIndexDone
+	<-chanStoreInternalStateDone
+	return exitCodeOK
+}
+
+// initializeMempool initializes the mempool and synchronizes it for the first time
+func initializeMempool() error {
+	var addrDescForOutpoint bchain.AddrDescForOutpointFunc
+	if chain.GetChainParser().GetChainType() == bchain.ChainBitcoinType {
+		addrDescForOutpoint = index.AddrDescForOutpoint
+	}
+	err := chain.InitializeMempool(addrDescForOutpoint, onNewTxAddr, onNewTx)
+	if err != nil {
+		glog.Error("initializeMempool ", err)
+		return err
+	}
+	mempoolCount, err := mempool.Resync()
+	if err != nil {
+		glog.Error("resyncMempool ", err)
+		return err
+	}
+	internalState.FinishedMempoolSync(mempoolCount)
+	return nil
+}
+
 func getBlockChainWithRetry(coin string, configFile string, pushHandler func(bchain.NotificationType), metrics *common.Metrics, seconds int) (bchain.BlockChain, bchain.Mempool, error) {
 	var chain bchain.BlockChain
 	var mempool bchain.Mempool
@@ -396,8 +655,24 @@ func getBlockChainWithRetry(coin string, configFile string, pushHandler func(bch
 	}
 }
 
+// readAdminAuth reads the credentials in the format user:password from the file given by the -adminauth parameter
+func readAdminAuth() (string, error) {
+	if *adminAuthFile == "" {
+		return "", nil
+	}
+	b, err := os.ReadFile(*adminAuthFile)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(b)), nil
+}
+
 func startInternalServer() (*server.InternalServer, error) {
-	internalServer, err := server.NewInternalServer(*internalBinding, *certFiles, index, chain, mempool, txCache, metrics, internalState, fiatRates)
+	adminAuth, err := readAdminAuth()
+	if err != nil {
+		return nil, err
+	}
+	internalServer, err := server.NewInternalServer(*internalBinding, *certFiles, index, chain, mempool, txCache, metrics, internalState, fiatRates, webhooks, syncControl, adminAuth, replicaRelay, *checkpoints)
 	if err != nil {
 		return nil, err
 	}
@@ -435,29 +710,36 @@ func startPublicServer() (*server.PublicServer, error) {
 	return publicServer, err
 }
 
-func performRollback() error {
-	bestHeight, bestHash, err := index.GetBestBlock()
+// bootstrapFromCheckpoint validates the checkpoint against the coin and the backend and restores it to the datadir,
+// the synchronization then continues from the best block of the checkpoint
+func bootstrapFromCheckpoint(dir string, coin string) error {
+	m, err := db.ReadCheckpointManifest(dir)
 	if err != nil {
-		glog.Error("rollbackHeight: ", err)
 		return err
 	}
-	if uint32(*rollbackHeight) > bestHeight {
-		glog.Infof("nothing to rollback, rollbackHeight %d, bestHeight: %d", *rollbackHeight, bestHeight)
-	} else {
-		hashes := []string{bestHash}
-		for height := bestHeight - 1; height >= uint32(*rollbackHeight); height-- {
-			hash, err := index.GetBlockHash(height)
-			if err != nil {
-				glog.Error("rollbackHeight: ", err)
-				return err
-			}
-			hashes = append(hashes, hash)
-		}
-		err = syncWorker.DisconnectBlocks(uint32(*rollbackHeight), bestHeight, hashes)
-		if err != nil {
-			glog.Error("rollbackHeight: ", err)
-			return err
-		}
+	// extended index is used only by bitcoin type coins
+	ei := *extendedIndex && chain.GetChainParser().GetChainType() == bchain.ChainBitcoinType
+	if err = m.Validate(coin, ei); err != nil {
+		return err
+	}
+	hash, err := chain.GetBlockHash(m.BestHeight)
+	if err != nil {
+		return errors.Annotatef(err, "backend block %d", m.BestHeight)
+	}
+	if hash != m.BestHash {
+		return errors.Errorf("Checkpoint block %d %s is not in the backend chain, backend block hash %s", m.BestHeight, m.BestHash, hash)
+	}
+	if err = db.RestoreCheckpoint(dir, *dbPath); err != nil {
+		return err
+	}
+	glog.Infof("bootstrap: datadir %s initialized from checkpoint %s, best block %d %s", *dbPath, dir, m.BestHeight, m.BestHash)
+	return nil
+}
+
+func performRollback() error {
+	if err := syncWorker.Rollback(uint32(*rollbackHeight), nil); err != nil {
+		glog.Error("rollbackHeight: ", err)
+		return err
 	}
 	return nil
 }
@@ -518,20 +800,118 @@ func newInternalState(config *common.Config, d *db.RocksDB, enableSubNewTx bool)
 func syncIndexLoop() {
 	defer close(chanSyncIndexDone)
 	glog.Info("syncIndexLoop starting")
+	apiWorker, err := api.NewWorker(index, chain, mempool, txCache, metrics, internalState, fiatRates)
+	if err != nil {
+		glog.Error("syncIndexLoop ", err)
+	}
 	// resync index about every 15 minutes if there are no chanSyncIndex requests, with debounce 1 second
 	common.TickAndDebounce(time.Duration(*resyncIndexPeriodMs)*time.Millisecond, debounceResyncIndexMs*time.Millisecond, chanSyncIndex, func() {
-		if err := syncWorker.ResyncIndex(onNewBlockHash, false); err != nil {
+		syncControlActions(apiWorker)
+		if syncControl.IsPaused() {
+			glog.V(1).Info("syncIndexLoop paused")
+			return
+		}
+		if err := syncWorker.ResyncIndex(onNewBlockHash, onDisconnectBlocks, false); err != nil {
 			glog.Error("syncIndexLoop ", errors.ErrorStack(err), ", will retry...")
 			// retry once in case of random network error, after a slight delay
 			time.Sleep(time.Millisecond * 2500)
-			if err := syncWorker.ResyncIndex(onNewBlockHash, false); err != nil {
+			if err := syncWorker.ResyncIndex(onNewBlockHash, onDisconnectBlocks, false); err != nil {
 				glog.Error("syncIndexLoop ", errors.ErrorStack(err))
 			}
 		}
+		// the actions requested during the resync
+		syncControlActions(apiWorker)
+		if index.PruneDepth() > 0 {
+			triggerPruneIndex()
+		}
 	})
 	glog.Info("syncIndexLoop stopped")
 }
 
+func replicaSyncLoop() {
+	defer close(chanSyncIndexDone)
+	glog.Info("replicaSyncLoop starting")
+	// catch up with the primary on its notifications or at least every resyncIndexPeriodMs, with debounce 1 second
+	common.TickAndDebounce(time.Duration(*resyncIndexPeriodMs)*time.Millisecond, debounceResyncIndexMs*time.Millisecond, chanSyncIndex, func() {
+		if err := replicaWorker.CatchUp(onNewBlockHash, onDisconnectBlocks); err != nil {
+			glog.Error("replicaSyncLoop ", errors.ErrorStack(err))
+		}
+	})
+	glog.Info("replicaSyncLoop stopped")
+}
+
+// syncControlActions executes the admin actions requested in the internal server, serialized with the resync of the index
+func syncControlActions(apiWorker *api.Worker) {
+	for a := syncControl.Next(db.SyncActionRollback, db.SyncActionRefetchInternalData); a != nil; a = syncControl.Next(db.SyncActionRollback, db.SyncActionRefetchInternalData) {
+		glog.Info("syncIndexLoop action ", a.Name)
+		var err error
+		switch a.Name {
+		case db.SyncActionRollback:
+			err = syncWorker.Rollback(a.Height, onDisconnectBlocks)
+		case db.SyncActionRefetchInternalData:
+			if apiWorker == nil {
+				err = errors.New("Missing api worker")
+			} else {
+				err = apiWorker.RefetchInternalDataSync()
+			}
+		}
+		if err != nil {
+			glog.Error("syncIndexLoop action ", a.Name, " ", errors.ErrorStack(err))
+		}
+		syncControl.Finish(a, err)
+	}
+}
+
+// triggerSyncIndex wakes up syncIndexLoop, if the loop is busy, the actions are executed after the running resync
+func triggerSyncIndex() {
+	if common.IsInShutdown() {
+		return
+	}
+	select {
+	case chanSyncIndex <- struct{}{}:
+	default:
+	}
+}
+
+// triggerStoreInternalState wakes up storeInternalStateLoop
+func triggerStoreInternalState() {
+	if common.IsInShutdown() {
+		return
+	}
+	select {
+	case chanStoreInternalState <- struct{}{}:
+	default:
+	}
+}
+
+// triggerPruneIndex wakes up pruneIndexLoop
+func triggerPruneIndex() {
+	if common.IsInShutdown() {
+		return
+	}
+	select {
+	case chanPruneIndex <- struct{}{}:
+	default:
+	}
+}
+
+// pruneIndexLoop removes the history of the index older than the prune depth, the pruning is interrupted by the shutdown
+func pruneIndexLoop() {
+	stopPrune := make(chan os.Signal, 1)
+	defer func() {
+		signal.Stop(stopPrune)
+		close(chanPruneIndexDone)
+	}()
+	signal.Notify(stopPrune, syscall.SIGHUP, syscall.SIGINT, syscall.SIGQUIT, syscall.SIGTERM)
+	glog.Info("pruneIndexLoop starting with prune depth ", index.PruneDepth())
+	common.TickAndDebounce(pruneIndexPeriodMs*time.Millisecond, debouncePruneIndexMs*time.Millisecond, chanPruneIndex, func() {
+		if err := index.Prune(chain, stopPrune); err != nil && err != db.ErrOperationInterrupted {
+			glog.Error("pruneIndexLoop ", errors.ErrorStack(err))
+		}
+	})
+	glog.Info("pruneIndexLoop stopped")
+}
+
 func onNewBlockHash(hash string, height uint32) {
 	defer func() {
 		if r := recover(); r != nil {
@@ -543,6 +923,17 @@ func onNewBlockHash(hash string, height uint32) {
 	}
 }
 
+func onDisconnectBlocks(lower uint32, higher uint32, hashes []string) {
+	defer func() {
+		if r := recover(); r != nil {
+			glog.Error("onDisconnectBlocks recovered from panic: ", r)
+		}
+	}()
+	for _, c := range callbacksOnDisconnectBlocks {
+		c(lower, higher, hashes)
+	}
+}
+
 func onNewFiatRatesTicker(ticker *common.CurrencyRatesTicker) {
 	defer func() {
 		if r := recover(); r != nil {
@@ -565,7 +956,6 @@ func syncMempoolLoop() {
 			glog.Error("syncMempoolLoop ", errors.ErrorStack(err))
 		} else {
 			internalState.FinishedMempoolSync(count)
-
 		}
 	})
 	glog.Info("syncMempoolLoop stopped")
@@ -589,17 +979,29 @@ func storeInternalStateLoop() {
 	} else {
 		glog.Info("storeInternalStateLoop starting with db stats compute disabled")
 	}
+	computeColumnStats := func(a *db.SyncAction) {
+		computeRunning = true
+		go func() {
+			err := index.ComputeInternalStateColumnStats(stopCompute)
+			if err != nil {
+				glog.Error("computeInternalStateColumnStats error: ", err)
+			}
+			if a != nil {
+				syncControl.Finish(a, err)
+			}
+			lastCompute = time.Now()
+			computeRunning = false
+		}()
+	}
 	common.TickAndDebounce(storeInternalStatePeriodMs*time.Millisecond, (storeInternalStatePeriodMs-1)*time.Millisecond, chanStoreInternalState, func() {
+		if syncControl != nil && !computeRunning {
+			// column stats requested in the internal server
+			if a := syncControl.Next(db.SyncActionComputeColumnStats); a != nil {
+				computeColumnStats(a)
+			}
+		}
 		if (*dbStatsPeriodHours) > 0 && !computeRunning && lastCompute.Add(computePeriod).Before(time.Now()) {
-			computeRunning = true
-			go func() {
-				err := index.ComputeInternalStateColumnStats(stopCompute)
-				if err != nil {
-					glog.Error("computeInternalStateColumnStats error: ", err)
-				}
-				lastCompute = time.Now()
-				computeRunning = false
-			}()
+			computeColumnStats(nil)
 		}
 		if err := index.StoreInternalState(internalState); err != nil {
 			glog.Error("storeInternalStateLoop ", errors.ErrorStack(err))
@@ -709,4 +1111,14 @@ func initDownloaders(db *db.RocksDB, chain bchain.BlockChain, config *common.Con
 
 	}
 
+	if config.EventSignatures != "" && chain.GetChainParser().GetChainType() == bchain.ChainEthereumType {
+		esd, err := fourbyte.NewEventSignaturesDownloader(db, config.EventSignatures)
+		if err != nil {
+			glog.Errorf("NewEventSignaturesDownloader Init error: %v", err)
+		} else {
+			glog.Infof("Starting EventSignatures downloader...")
+			go esd.Run()
+		}
+	}
+
 }
@@ -28,11 +28,15 @@ func main() {
 	t.Add(api.FeeStats{})
 	t.Add(api.Address{})
 	t.Add(api.Utxo{})
+	t.Add(api.AddressState{})
 	t.Add(api.BalanceHistory{})
 	t.Add(api.Blocks{})
 	t.Add(api.Block{})
 	t.Add(api.BlockRaw{})
+	t.Add(api.TxProof{})
 	t.Add(api.SystemInfo{})
+	t.Add(api.MempoolConflicts{})
+	t.Add(api.MempoolPackage{})
 	t.Add(api.FiatTicker{})
 	t.Add(api.FiatTickers{})
 	t.Add(api.AvailableVsCurrencies{})
@@ -48,6 +52,7 @@ func main() {
 	t.Add(server.WsBlockFilterReq{})
 	t.Add(server.WsBlockFiltersBatchReq{})
 	t.Add(server.WsAccountUtxoReq{})
+	t.Add(server.WsAccountStateAtReq{})
 	t.Add(server.WsBalanceHistoryReq{})
 	t.Add(server.WsTransactionReq{})
 	t.Add(server.WsTransactionSpecificReq{})
@@ -56,6 +61,8 @@ func main() {
 	t.Add(server.WsSendTransactionReq{})
 	t.Add(server.WsSubscribeAddressesReq{})
 	t.Add(server.WsSubscribeFiatRatesReq{})
+	t.Add(server.WsSubscribeTxStatusReq{})
+	t.Add(server.WsTxStatus{})
 	t.Add(server.WsCurrentFiatRatesReq{})
 	t.Add(server.WsFiatRatesForTimestampsReq{})
 	t.Add(server.WsFiatRatesTickersListReq{})
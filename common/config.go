@@ -13,6 +13,7 @@ type Config struct {
 	CoinShortcut            string `json:"coin_shortcut"`
 	CoinLabel               string `json:"coin_label"`
 	FourByteSignatures      string `json:"fourByteSignatures"`
+	EventSignatures         string `json:"eventSignatures"`
 	FiatRates               string `json:"fiat_rates"`
 	FiatRatesParams         string `json:"fiat_rates_params"`
 	FiatRatesVsCurrencies   string `json:"fiat_rates_vs_currencies"`
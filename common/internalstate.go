@@ -31,6 +31,14 @@ type InternalStateColumn struct {
 	Updated    time.Time `json:"updated"`
 }
 
+// MigrationProgress contains the progress of the running database migration or column rebuild, it allows to resume the interrupted operation
+type MigrationProgress struct {
+	Name    string `json:"name"`
+	Column  string `json:"column,omitempty"`
+	LastKey []byte `json:"lastKey,omitempty"`
+	Rows    int64  `json:"rows"`
+}
+
 // BackendInfo is used to get information about blockchain
 type BackendInfo struct {
 	BackendError     string      `json:"error,omitempty"`
@@ -90,8 +98,14 @@ type InternalState struct {
 	BackendInfo BackendInfo `json:"-"`
 
 	// database migrations
-	UtxoChecked            bool `json:"utxoChecked"`
-	SortedAddressContracts bool `json:"sortedAddressContracts"`
+	UtxoChecked            bool               `json:"utxoChecked"`
+	SortedAddressContracts bool               `json:"sortedAddressContracts"`
+	Migration              *MigrationProgress `json:"migration,omitempty"`
+	Rebuild                *MigrationProgress `json:"rebuild,omitempty"`
+
+	// pruning of the history, the index of the blocks below PrunedHeight is removed
+	PruneDepth   uint32 `json:"pruneDepth,omitempty"`
+	PrunedHeight uint32 `json:"prunedHeight,omitempty"`
 
 	// golomb filter settings
 	BlockGolombFilterP      uint8  `json:"block_golomb_filter_p"`
@@ -142,6 +156,20 @@ func (is *InternalState) GetSyncState() (bool, uint32, time.Time, time.Time) {
 	return is.IsSynchronized, is.BestHeight, is.LastSync, is.StartSync
 }
 
+// GetPrunedHeight returns the lowest height with the complete history in the index, 0 if the index is not pruned
+func (is *InternalState) GetPrunedHeight() uint32 {
+	is.mux.Lock()
+	defer is.mux.Unlock()
+	return is.PrunedHeight
+}
+
+// SetPrunedHeight sets the lowest height with the complete history in the index
+func (is *InternalState) SetPrunedHeight(height uint32) {
+	is.mux.Lock()
+	defer is.mux.Unlock()
+	is.PrunedHeight = height
+}
+
 // StartedMempoolSync signals start of mempool synchronization
 func (is *InternalState) StartedMempoolSync() {
 	is.mux.Lock()
@@ -19,6 +19,8 @@ type Metrics struct {
 	IndexResyncDuration      prometheus.Histogram
 	MempoolResyncDuration    prometheus.Histogram
 	TxCacheEfficiency        *prometheus.CounterVec
+	TxCacheSourceEfficiency  *prometheus.CounterVec
+	TxCacheMemorySize        prometheus.Gauge
 	RPCLatency               *prometheus.HistogramVec
 	IndexResyncErrors        *prometheus.CounterVec
 	IndexDBSize              prometheus.Gauge
@@ -36,6 +38,7 @@ type Metrics struct {
 	SocketIOPendingRequests  *prometheus.GaugeVec
 	XPubCacheSize            prometheus.Gauge
 	CoingeckoRequests        *prometheus.CounterVec
+	WebhookDeliveries        *prometheus.CounterVec
 }
 
 // Labels represents a collection of label name -> value mappings.
@@ -133,6 +136,21 @@ func GetMetrics(coin string) (*Metrics, error) {
 		},
 		[]string{"status"},
 	)
+	metrics.TxCacheSourceEfficiency = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name:        "blockbook_txcache_source_efficiency",
+			Help:        "Efficiency of txCache by source (memory, db, backend) and status",
+			ConstLabels: Labels{"coin": coin},
+		},
+		[]string{"source", "status"},
+	)
+	metrics.TxCacheMemorySize = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name:        "blockbook_txcache_memory_size",
+			Help:        "Estimated size of the in-memory txCache (in bytes)",
+			ConstLabels: Labels{"coin": coin},
+		},
+	)
 	metrics.RPCLatency = prometheus.NewHistogramVec(
 		prometheus.HistogramOpts{
 			Name:        "blockbook_rpc_latency",
@@ -264,6 +282,14 @@ func GetMetrics(coin string) (*Metrics, error) {
 		},
 		[]string{"endpoint", "status"},
 	)
+	metrics.WebhookDeliveries = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name:        "blockbook_webhook_deliveries",
+			Help:        "Total number of webhook delivery attempts by event and status",
+			ConstLabels: Labels{"coin": coin},
+		},
+		[]string{"event", "status"},
+	)
 
 	v := reflect.ValueOf(metrics)
 	for i := 0; i < v.NumField(); i++ {
@@ -0,0 +1,178 @@
+package db
+
+import (
+	"bytes"
+	"math/big"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+	"github.com/linxGnu/grocksdb"
+	"github.com/trezor/blockbook/bchain"
+	"github.com/trezor/blockbook/bchain/coins/eth"
+)
+
+// The approvals column keeps the history of the token approvals under the key owner+contract+spender+kind+^height,
+// the first row of the owner, contract, spender and kind is therefore the last approval.
+// The blockApprovals column keeps the keys of the approvals set in the block for the blocks that can be disconnected.
+
+const (
+	approvalKindAllowance = byte(0)
+	approvalKindForAll    = byte(1)
+	packedApprovalKeyLen  = 3*eth.EthereumTypeAddressDescriptorLen + 1 + packedHeightBytes
+)
+
+// TokenApproval is the approval of the spender to transfer the tokens of the owner in the contract, set at Height.
+// Value is the amount of the ERC20 token approved at Height, the transfers made by the spender do not change it.
+// The ForAll approvals of ERC721 and ERC1155 tokens have Value 1.
+type TokenApproval struct {
+	Contract bchain.AddressDescriptor
+	Spender  bchain.AddressDescriptor
+	ForAll   bool
+	Value    big.Int
+	Height   uint32
+}
+
+type ethBlockTxApproval struct {
+	owner, contract, spender bchain.AddressDescriptor
+	forAll                   bool
+	value                    big.Int
+	height                   uint32
+}
+
+func packApprovalKey(a *ethBlockTxApproval) []byte {
+	key := make([]byte, 0, packedApprovalKeyLen)
+	key = appendAddress(key, a.owner)
+	key = appendAddress(key, a.contract)
+	key = appendAddress(key, a.spender)
+	if a.forAll {
+		key = append(key, approvalKindForAll)
+	} else {
+		key = append(key, approvalKindAllowance)
+	}
+	return append(key, packUint(^a.height)...)
+}
+
+func unpackApprovalKey(key []byte) (*TokenApproval, error) {
+	if len(key) != packedApprovalKeyLen {
+		return nil, errors.New("Invalid approval key")
+	}
+	l := eth.EthereumTypeAddressDescriptorLen
+	return &TokenApproval{
+		Contract: append(bchain.AddressDescriptor(nil), key[l:2*l]...),
+		Spender:  append(bchain.AddressDescriptor(nil), key[2*l:3*l]...),
+		ForAll:   key[3*l] == approvalKindForAll,
+		Height:   ^unpackUint(key[3*l+1:]),
+	}, nil
+}
+
+func (ei *ethereumTypeIndex) processApprovals(blockTx *ethBlockTx, tx *bchain.Tx, height uint32) {
+	approvals, err := ei.chainParser.EthereumTypeGetTokenApprovalsFromTx(tx)
+	if err != nil {
+		glog.Warningf("rocksdb: processApprovals %v, tx %v", err, tx.Txid)
+		return
+	}
+	for _, a := range approvals {
+		var owner, contract, spender bchain.AddressDescriptor
+		contract, err = ei.chainParser.GetAddrDescFromAddress(a.Contract)
+		if err == nil {
+			owner, err = ei.chainParser.GetAddrDescFromAddress(a.Owner)
+			if err == nil {
+				spender, err = ei.chainParser.GetAddrDescFromAddress(a.Spender)
+			}
+		}
+		if err != nil {
+			glog.Warningf("rocksdb: processApprovals %v, tx %v, approval %v", err, tx.Txid, a)
+			continue
+		}
+		blockTx.approvals = append(blockTx.approvals, ethBlockTxApproval{
+			owner:    owner,
+			contract: contract,
+			spender:  spender,
+			forAll:   a.ForAll,
+			value:    a.Value,
+			height:   height,
+		})
+	}
+}
+
+func (d *RocksDB) storeApprovalsEthereumType(wb *grocksdb.WriteBatch, blockTxs []ethBlockTx) {
+	buf := make([]byte, maxPackedBigintBytes)
+	for i := range blockTxs {
+		for j := range blockTxs[i].approvals {
+			a := &blockTxs[i].approvals[j]
+			l := packBigint(&a.value, buf)
+			wb.PutCF(d.cfh[cfApprovals], packApprovalKey(a), buf[:l])
+		}
+	}
+}
+
+// storeAndCleanupBlockApprovals stores the keys of the approvals set in the block, so that they can be removed
+// on the disconnection of the block, and removes the keys of the block, which can be no more disconnected
+func (d *RocksDB) storeAndCleanupBlockApprovals(wb *grocksdb.WriteBatch, height uint32, blockTxs []ethBlockTx) {
+	var buf []byte
+	for i := range blockTxs {
+		for j := range blockTxs[i].approvals {
+			buf = append(buf, packApprovalKey(&blockTxs[i].approvals[j])...)
+		}
+	}
+	if len(buf) > 0 {
+		wb.PutCF(d.cfh[cfBlockApprovals], packUint(height), buf)
+	}
+	keep := uint32(d.chainParser.KeepBlockAddresses())
+	if keep > 0 && height > keep {
+		wb.DeleteCF(d.cfh[cfBlockApprovals], packUint(height-keep))
+	}
+}
+
+func (d *RocksDB) disconnectApprovals(wb *grocksdb.WriteBatch, height uint32) error {
+	key := packUint(height)
+	val, err := d.db.GetCF(d.ro, d.cfh[cfBlockApprovals], key)
+	if err != nil {
+		return err
+	}
+	defer val.Free()
+	buf := val.Data()
+	for len(buf) >= packedApprovalKeyLen {
+		wb.DeleteCF(d.cfh[cfApprovals], buf[:packedApprovalKeyLen])
+		buf = buf[packedApprovalKeyLen:]
+	}
+	wb.DeleteCF(d.cfh[cfBlockApprovals], key)
+	return nil
+}
+
+// GetAddrDescApprovals returns the last approvals with non zero value given by the owner
+func (d *RocksDB) GetAddrDescApprovals(owner bchain.AddressDescriptor) ([]TokenApproval, error) {
+	if d.chainParser.GetChainType() != bchain.ChainEthereumType {
+		return nil, errors.New("Unsupported chain type")
+	}
+	if len(owner) != eth.EthereumTypeAddressDescriptorLen {
+		return nil, nil
+	}
+	r := []TokenApproval{}
+	var last []byte
+	it := d.db.NewIteratorCF(d.ro, d.cfh[cfApprovals])
+	defer it.Close()
+	for it.Seek(owner); it.Valid(); it.Next() {
+		key := it.Key().Data()
+		if !bytes.HasPrefix(key, owner) {
+			break
+		}
+		// the rows of the same contract, spender and kind are ordered from the latest
+		tuple := key[:len(key)-packedHeightBytes]
+		if last != nil && bytes.Equal(tuple, last) {
+			continue
+		}
+		last = append(last[:0], tuple...)
+		value, _ := unpackBigint(it.Value().Data())
+		if value.Sign() == 0 {
+			continue
+		}
+		a, err := unpackApprovalKey(key)
+		if err != nil {
+			return nil, err
+		}
+		a.Value = value
+		r = append(r, *a)
+	}
+	return r, nil
+}
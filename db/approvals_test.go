@@ -0,0 +1,120 @@
+//go:build unittest
+
+package db
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/trezor/blockbook/bchain"
+	"github.com/trezor/blockbook/tests/dbtestdata"
+)
+
+func addApprovalLog(tx *bchain.Tx, signature, contract, owner, spender string, value int64) {
+	csd := tx.CoinSpecificData.(bchain.EthereumSpecificData)
+	csd.Receipt.Logs = append(csd.Receipt.Logs, &bchain.RpcLog{
+		Address: "0x" + contract,
+		Topics: []string{
+			signature,
+			"0x000000000000000000000000" + owner,
+			"0x000000000000000000000000" + spender,
+		},
+		Data: fmt.Sprintf("0x%064x", value),
+	})
+}
+
+func TestRocksDB_Approvals_EthereumType(t *testing.T) {
+	const approval = "0x8c5be1e5ebec7d5bd14f71427d1e84f3dd0314c0f7b2291e5b200ac8c7c3b925"
+	const approvalForAll = "0x17307eab39ab6107e8899845ad3d59bd9653f200f220920489ca2b5937696c31"
+	d := setupRocksDB(t, &testEthereumParser{
+		EthereumParser: ethereumTestnetParser(),
+	})
+	defer closeAndDestroyRocksDB(t, d)
+
+	addrDesc := func(a string) bchain.AddressDescriptor {
+		b, err := hex.DecodeString(a)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	verifyApprovals := func(want []TokenApproval) {
+		t.Helper()
+		got, err := d.GetAddrDescApprovals(addrDesc(dbtestdata.EthAddr3e))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("GetAddrDescApprovals() = %+v, want %+v", got, want)
+		}
+	}
+	allowance := TokenApproval{
+		Contract: addrDesc(dbtestdata.EthAddrContract4a),
+		Spender:  addrDesc(dbtestdata.EthAddr55),
+		Value:    *big.NewInt(1000),
+		Height:   4321000,
+	}
+	forAll := TokenApproval{
+		Contract: addrDesc(dbtestdata.EthAddrContractCd),
+		Spender:  addrDesc(dbtestdata.EthAddr20),
+		ForAll:   true,
+		Value:    *big.NewInt(1),
+		Height:   4321001,
+	}
+
+	// the first approval of the allowance is replaced in the same block
+	block1 := dbtestdata.GetTestEthereumTypeBlock1(d.chainParser)
+	addApprovalLog(&block1.Txs[0], approval, dbtestdata.EthAddrContract4a, dbtestdata.EthAddr3e, dbtestdata.EthAddr55, 500)
+	addApprovalLog(&block1.Txs[0], approval, dbtestdata.EthAddrContract4a, dbtestdata.EthAddr3e, dbtestdata.EthAddr55, 1000)
+	if err := d.ConnectBlock(block1); err != nil {
+		t.Fatal(err)
+	}
+	verifyApprovals([]TokenApproval{allowance})
+
+	// the allowance is revoked, approval for all is given
+	block2 := dbtestdata.GetTestEthereumTypeBlock2(d.chainParser)
+	addApprovalLog(&block2.Txs[0], approval, dbtestdata.EthAddrContract4a, dbtestdata.EthAddr3e, dbtestdata.EthAddr55, 0)
+	addApprovalLog(&block2.Txs[1], approvalForAll, dbtestdata.EthAddrContractCd, dbtestdata.EthAddr3e, dbtestdata.EthAddr20, 1)
+	if err := d.ConnectBlock(block2); err != nil {
+		t.Fatal(err)
+	}
+	verifyApprovals([]TokenApproval{forAll})
+	// the keys of the approvals of the first block cannot be disconnected anymore and are removed
+	if err := checkColumn(d, cfBlockApprovals, []keyPair{
+		{
+			"0041eee9",
+			dbtestdata.EthAddr3e + dbtestdata.EthAddrContract4a + dbtestdata.EthAddr55 + "00" + uintToHex(^uint32(4321001)) +
+				dbtestdata.EthAddr3e + dbtestdata.EthAddrContractCd + dbtestdata.EthAddr20 + "01" + uintToHex(^uint32(4321001)),
+			nil,
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the disconnection of the second block restores the allowance
+	if err := d.DisconnectBlockRangeEthereumType(4321001, 4321001); err != nil {
+		t.Fatal(err)
+	}
+	verifyApprovals([]TokenApproval{allowance})
+	if err := checkColumn(d, cfBlockApprovals, []keyPair{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkColumn(d, cfApprovals, []keyPair{
+		{
+			dbtestdata.EthAddr3e + dbtestdata.EthAddrContract4a + dbtestdata.EthAddr55 + "00" + uintToHex(^uint32(4321000)),
+			bigintToHex(big.NewInt(1000)),
+			nil,
+		},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the approvals of other addresses are not returned
+	got, err := d.GetAddrDescApprovals(addrDesc(dbtestdata.EthAddr55))
+	if err != nil || len(got) != 0 {
+		t.Errorf("GetAddrDescApprovals(EthAddr55) = %+v, %v, want empty", got, err)
+	}
+}
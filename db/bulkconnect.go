@@ -192,7 +192,7 @@ func (b *BulkConnect) connectBlockBitcoinType(block *bchain.Block, storeBlockTxs
 	} else if gf != nil && !gf.Enabled {
 		gf = nil
 	}
-	if err := b.d.processAddressesBitcoinType(block, addresses, b.txAddressesMap, b.balances, gf); err != nil {
+	if err := b.d.bitcoinTypeIndex().processAddressesBitcoinType(block, addresses, b.txAddressesMap, b.balances, gf); err != nil {
 		return err
 	}
 	var storeAddressesChan, storeBalancesChan chan error
@@ -305,7 +305,7 @@ func (b *BulkConnect) parallelStoreAddressContracts(c chan error, all bool) {
 
 func (b *BulkConnect) connectBlockEthereumType(block *bchain.Block, storeBlockTxs bool) error {
 	addresses := make(addressesMap)
-	blockTxs, err := b.d.processAddressesEthereumType(block, addresses, b.addressContracts)
+	blockTxs, err := b.d.ethereumTypeIndex().processAddressesEthereumType(block, addresses, b.addressContracts)
 	if err != nil {
 		return err
 	}
@@ -342,6 +342,8 @@ func (b *BulkConnect) connectBlockEthereumType(block *bchain.Block, storeBlockTx
 		if err = b.d.storeInternalDataEthereumType(wb, b.ethBlockTxs); err != nil {
 			return err
 		}
+		b.d.storeApprovalsEthereumType(wb, b.ethBlockTxs)
+		b.d.storeContractLogsEthereumType(wb, b.ethBlockTxs)
 		b.ethBlockTxs = b.ethBlockTxs[:0]
 		if err = b.d.storeBlockSpecificDataEthereumType(wb, block); err != nil {
 			return err
@@ -411,6 +413,8 @@ func (b *BulkConnect) Close() error {
 	if err := b.d.storeInternalDataEthereumType(wb, b.ethBlockTxs); err != nil {
 		return err
 	}
+	b.d.storeApprovalsEthereumType(wb, b.ethBlockTxs)
+	b.d.storeContractLogsEthereumType(wb, b.ethBlockTxs)
 	b.ethBlockTxs = b.ethBlockTxs[:0]
 	bac := b.bulkAddressesCount
 	if err := b.storeBulkAddresses(wb); err != nil {
@@ -0,0 +1,223 @@
+package db
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+	"github.com/linxGnu/grocksdb"
+	"github.com/trezor/blockbook/common"
+)
+
+// CheckpointManifestFile is the name of the file with the manifest stored in the checkpoint directory
+const CheckpointManifestFile = "blockbook-checkpoint.json"
+
+// CheckpointManifest describes the content of a db checkpoint
+type CheckpointManifest struct {
+	Coin          string    `json:"coin"`
+	BestHeight    uint32    `json:"bestHeight"`
+	BestHash      string    `json:"bestHash"`
+	DbVersion     int       `json:"dbVersion"`
+	ExtendedIndex bool      `json:"extendedIndex"`
+	Created       time.Time `json:"created"`
+}
+
+var checkpointMux sync.Mutex
+
+// CreateCheckpoint creates a consistent copy of the database in the directory, which must not exist.
+// The copy is done while the db is open, SST files are hard-linked if the directory is on the same filesystem.
+// The internal state is stored in the checkpoint in closed state and the manifest is written to the directory.
+func (d *RocksDB) CreateCheckpoint(dir string) (*CheckpointManifest, error) {
+	checkpointMux.Lock()
+	defer checkpointMux.Unlock()
+	if d.is == nil {
+		return nil, errors.New("Internal state not created")
+	}
+	if _, err := os.Stat(dir); err == nil || !os.IsNotExist(err) {
+		return nil, errors.Errorf("Checkpoint directory %v already exists", dir)
+	}
+	glog.Info("rocksdb: creating checkpoint in ", dir)
+	cp, err := d.db.NewCheckpoint()
+	if err != nil {
+		return nil, err
+	}
+	defer cp.Destroy()
+	if err = cp.CreateCheckpoint(dir, 0); err != nil {
+		return nil, errors.Annotatef(err, "CreateCheckpoint %v", dir)
+	}
+	m, err := d.finishCheckpoint(dir)
+	if err != nil {
+		// do not leave behind an unusable checkpoint
+		os.RemoveAll(dir)
+		return nil, err
+	}
+	glog.Infof("rocksdb: checkpoint %v created, best block %v %v", dir, m.BestHeight, m.BestHash)
+	return m, nil
+}
+
+// finishCheckpoint opens the checkpoint, stores the internal state to it and writes the manifest
+func (d *RocksDB) finishCheckpoint(dir string) (*CheckpointManifest, error) {
+	c := grocksdb.NewLRUCache(1 << 20)
+	defer c.Destroy()
+	db, cfh, err := openDB(dir, "", c, d.maxOpenFiles)
+	if err != nil {
+		return nil, errors.Annotatef(err, "open checkpoint %v", dir)
+	}
+	cd := &RocksDB{
+		path:          dir,
+		db:            db,
+		wo:            grocksdb.NewDefaultWriteOptions(),
+		ro:            grocksdb.NewDefaultReadOptions(),
+		cfh:           cfh,
+		chainParser:   d.chainParser,
+		cache:         c,
+		maxOpenFiles:  d.maxOpenFiles,
+		extendedIndex: d.extendedIndex,
+	}
+	defer func() {
+		cd.closeDB()
+		cd.wo.Destroy()
+		cd.ro.Destroy()
+	}()
+	// the state stored in the checkpoint shows if the db was consistent at the moment of the checkpoint,
+	// it is not during the bulk import of the initial synchronization
+	val, err := cd.db.GetCF(cd.ro, cd.cfh[cfDefault], []byte(internalStateKey))
+	if err != nil {
+		return nil, err
+	}
+	data := val.Data()
+	if len(data) > 0 {
+		stored, err := common.UnpackInternalState(data)
+		if err != nil {
+			val.Free()
+			return nil, err
+		}
+		if stored.DbState == common.DbStateInconsistent {
+			val.Free()
+			return nil, errors.New("Database is in inconsistent state, checkpoint cannot be created")
+		}
+	}
+	val.Free()
+	bestHeight, bestHash, err := cd.GetBestBlock()
+	if err != nil {
+		return nil, err
+	}
+	// copy the current internal state, the column stats may slightly differ from the content of the checkpoint
+	buf, err := d.is.Pack()
+	if err != nil {
+		return nil, err
+	}
+	is, err := common.UnpackInternalState(buf)
+	if err != nil {
+		return nil, err
+	}
+	is.DbState = common.DbStateClosed
+	is.BestHeight = bestHeight
+	if err = cd.storeState(is); err != nil {
+		return nil, err
+	}
+	m := &CheckpointManifest{
+		Coin:          is.Coin,
+		BestHeight:    bestHeight,
+		BestHash:      bestHash,
+		DbVersion:     dbVersion,
+		ExtendedIndex: d.extendedIndex,
+		Created:       time.Now().UTC(),
+	}
+	buf, err = json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err = os.WriteFile(filepath.Join(dir, CheckpointManifestFile), buf, 0644); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// ReadCheckpointManifest reads the manifest of the checkpoint in the directory
+func ReadCheckpointManifest(dir string) (*CheckpointManifest, error) {
+	buf, err := os.ReadFile(filepath.Join(dir, CheckpointManifestFile))
+	if err != nil {
+		return nil, errors.Annotatef(err, "checkpoint %v", dir)
+	}
+	var m CheckpointManifest
+	if err = json.Unmarshal(buf, &m); err != nil {
+		return nil, errors.Annotatef(err, "checkpoint %v manifest", dir)
+	}
+	return &m, nil
+}
+
+// Validate checks that the checkpoint can be used for the coin and the index settings
+func (m *CheckpointManifest) Validate(coin string, extendedIndex bool) error {
+	if m.Coin != coin {
+		return errors.Errorf("Coins do not match. Checkpoint coin %v, RPC coin %v", m.Coin, coin)
+	}
+	if m.DbVersion != dbVersion {
+		return errors.Errorf("DB versions do not match. Checkpoint version %v, required version %v", m.DbVersion, dbVersion)
+	}
+	if m.ExtendedIndex != extendedIndex {
+		return errors.Errorf("ExtendedIndex setting does not match. Checkpoint extendedIndex %v, extendedIndex in options %v", m.ExtendedIndex, extendedIndex)
+	}
+	if m.BestHash == "" {
+		return errors.New("Checkpoint does not contain any block")
+	}
+	return nil
+}
+
+// RestoreCheckpoint copies the db files of the checkpoint to the path, which must be empty or not exist.
+// The immutable SST files are hard-linked if possible, the other files are copied.
+func RestoreCheckpoint(dir, path string) error {
+	entries, err := os.ReadDir(path)
+	if err == nil && len(entries) > 0 {
+		return errors.Errorf("Directory %v is not empty", path)
+	} else if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err = os.MkdirAll(path, 0755); err != nil {
+		return err
+	}
+	entries, err = os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+	glog.Infof("rocksdb: restoring checkpoint %v to %v", dir, path)
+	for _, e := range entries {
+		if e.IsDir() || e.Name() == CheckpointManifestFile {
+			continue
+		}
+		src := filepath.Join(dir, e.Name())
+		dst := filepath.Join(path, e.Name())
+		if strings.HasSuffix(e.Name(), ".sst") {
+			if err = os.Link(src, dst); err == nil {
+				continue
+			}
+		}
+		if err = copyFile(src, dst); err != nil {
+			return errors.Annotatef(err, "copy %v", src)
+		}
+	}
+	return nil
+}
+
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
@@ -0,0 +1,104 @@
+//go:build unittest
+
+package db
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/trezor/blockbook/common"
+	"github.com/trezor/blockbook/tests/dbtestdata"
+)
+
+func TestRocksDB_CreateCheckpoint(t *testing.T) {
+	d := setupRocksDB(t, &testBitcoinParser{
+		BitcoinParser: bitcoinTestnetParser(),
+	})
+	defer closeAndDestroyRocksDB(t, d)
+
+	if err := d.ConnectBlock(dbtestdata.GetTestBitcoinTypeBlock1(d.chainParser)); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.ConnectBlock(dbtestdata.GetTestBitcoinTypeBlock2(d.chainParser)); err != nil {
+		t.Fatal(err)
+	}
+	d.is.DbState = common.DbStateOpen
+
+	tmp, err := os.MkdirTemp("", "testcheckpoint")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	dir := filepath.Join(tmp, "checkpoint")
+
+	m, err := d.CreateCheckpoint(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := CheckpointManifest{
+		Coin:       "coin-unittest",
+		BestHeight: 225494,
+		BestHash:   "00000000eb0443fd7dc4a1ed5c686a8e995057805f9a161d9a5a77a95e72b7b6",
+		DbVersion:  dbVersion,
+		Created:    m.Created,
+	}
+	if *m != want {
+		t.Errorf("CreateCheckpoint() = %+v, want %+v", *m, want)
+	}
+	if _, err = d.CreateCheckpoint(dir); err == nil {
+		t.Error("CreateCheckpoint() to existing directory, expected error")
+	}
+
+	rm, err := ReadCheckpointManifest(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rm.Created.Equal(m.Created) {
+		t.Errorf("ReadCheckpointManifest() created %v, want %v", rm.Created, m.Created)
+	}
+	rm.Created = m.Created
+	if *rm != *m {
+		t.Errorf("ReadCheckpointManifest() = %+v, want %+v", *rm, *m)
+	}
+	if err = rm.Validate("coin-unittest", false); err != nil {
+		t.Errorf("Validate() error %v", err)
+	}
+	if err = rm.Validate("other-coin", false); err == nil {
+		t.Error("Validate() other coin, expected error")
+	}
+	if err = rm.Validate("coin-unittest", true); err == nil {
+		t.Error("Validate() extended index, expected error")
+	}
+
+	path := filepath.Join(tmp, "data")
+	if err = RestoreCheckpoint(dir, path); err != nil {
+		t.Fatal(err)
+	}
+	if err = RestoreCheckpoint(dir, path); err == nil {
+		t.Error("RestoreCheckpoint() to not empty directory, expected error")
+	}
+	if _, err = os.Stat(filepath.Join(path, CheckpointManifestFile)); !os.IsNotExist(err) {
+		t.Error("RestoreCheckpoint() copied the manifest")
+	}
+
+	r, err := NewRocksDB(path, 100000, -1, d.chainParser, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	is, err := r.LoadInternalState(&common.Config{CoinName: "coin-unittest"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if is.DbState != common.DbStateClosed {
+		t.Errorf("restored DbState = %v, want %v", is.DbState, common.DbStateClosed)
+	}
+	height, hash, err := r.GetBestBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if height != m.BestHeight || hash != m.BestHash {
+		t.Errorf("restored GetBestBlock() = %v %v, want %v %v", height, hash, m.BestHeight, m.BestHash)
+	}
+}
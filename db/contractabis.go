@@ -0,0 +1,132 @@
+package db
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+	"github.com/trezor/blockbook/bchain"
+	"github.com/trezor/blockbook/bchain/coins/eth"
+)
+
+// The contractABIs column keeps the verified ABIs of the contracts uploaded by the operator
+// under the key contract address descriptor, the value is the ABI in the JSON format.
+
+// cachedContractABIs contains only the stored ABIs, cachedContractABIsGeneration is incremented by each change
+// of the stored ABIs so that an ABI read before the change is not put to the cache after it
+var cachedContractABIs = make(map[string]*eth.ContractABI)
+var cachedContractABIsGeneration uint64
+var cachedContractABIsMux sync.Mutex
+
+func (d *RocksDB) checkContractABIContract(contract bchain.AddressDescriptor) error {
+	if d.chainParser.GetChainType() != bchain.ChainEthereumType {
+		return errors.New("Unsupported chain type")
+	}
+	if len(contract) != eth.EthereumTypeAddressDescriptorLen {
+		return errors.New("Invalid contract")
+	}
+	return nil
+}
+
+// GetContractABI gets the verified ABI of the contract, returns nil if the ABI of the contract is not stored
+func (d *RocksDB) GetContractABI(contract bchain.AddressDescriptor) (*eth.ContractABI, error) {
+	if err := d.checkContractABIContract(contract); err != nil {
+		return nil, err
+	}
+	cacheKey := string(contract)
+	cachedContractABIsMux.Lock()
+	a, found := cachedContractABIs[cacheKey]
+	generation := cachedContractABIsGeneration
+	cachedContractABIsMux.Unlock()
+	if found {
+		return a, nil
+	}
+	val, err := d.db.GetCF(d.ro, d.cfh[cfContractABIs], contract)
+	if err != nil {
+		return nil, err
+	}
+	defer val.Free()
+	buf := val.Data()
+	if len(buf) == 0 {
+		return nil, nil
+	}
+	if a, err = eth.ParseContractABI(buf); err != nil {
+		return nil, err
+	}
+	cachedContractABIsMux.Lock()
+	if generation == cachedContractABIsGeneration {
+		cachedContractABIs[cacheKey] = a
+	}
+	cachedContractABIsMux.Unlock()
+	return a, nil
+}
+
+// uncacheContractABI removes the changed ABI of the contract from the cache
+func uncacheContractABI(contract bchain.AddressDescriptor) {
+	cachedContractABIsMux.Lock()
+	delete(cachedContractABIs, string(contract))
+	cachedContractABIsGeneration++
+	cachedContractABIsMux.Unlock()
+}
+
+// StoreContractABI validates and stores the verified ABI of the contract, replacing the previously stored ABI
+func (d *RocksDB) StoreContractABI(contract bchain.AddressDescriptor, data []byte) (*eth.ContractABI, error) {
+	if err := d.checkContractABIContract(contract); err != nil {
+		return nil, err
+	}
+	a, err := eth.ParseContractABI(data)
+	if err != nil {
+		return nil, err
+	}
+	if err = d.db.PutCF(d.wo, d.cfh[cfContractABIs], contract, a.Data); err != nil {
+		return nil, err
+	}
+	uncacheContractABI(contract)
+	return a, nil
+}
+
+// DeleteContractABI removes the verified ABI of the contract
+func (d *RocksDB) DeleteContractABI(contract bchain.AddressDescriptor) error {
+	if err := d.checkContractABIContract(contract); err != nil {
+		return err
+	}
+	if err := d.db.DeleteCF(d.wo, d.cfh[cfContractABIs], contract); err != nil {
+		return err
+	}
+	uncacheContractABI(contract)
+	return nil
+}
+
+// ImportContractABIs stores the verified ABIs from the files <contract address>.json in the directory,
+// the files which cannot be imported are logged and skipped, returns the number of the imported ABIs
+func (d *RocksDB) ImportContractABIs(dir string) (int, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return 0, err
+	}
+	imported := 0
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.EqualFold(filepath.Ext(name), ".json") {
+			continue
+		}
+		contract, err := d.chainParser.GetAddrDescFromAddress(strings.TrimSuffix(name, filepath.Ext(name)))
+		if err != nil {
+			glog.Warning("contractABIs: skipping ", name, ", the file name is not a contract address: ", err)
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if err == nil {
+			_, err = d.StoreContractABI(contract, data)
+		}
+		if err != nil {
+			glog.Warning("contractABIs: skipping ", name, ": ", err)
+			continue
+		}
+		imported++
+	}
+	return imported, nil
+}
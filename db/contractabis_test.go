@@ -0,0 +1,107 @@
+//go:build unittest
+
+package db
+
+import (
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/trezor/blockbook/tests/dbtestdata"
+)
+
+func TestRocksDB_ContractABIs_EthereumType(t *testing.T) {
+	d := setupRocksDB(t, &testEthereumParser{
+		EthereumParser: ethereumTestnetParser(),
+	})
+	defer closeAndDestroyRocksDB(t, d)
+
+	const (
+		transferABI = `[{"type":"function","name":"transfer","inputs":[{"name":"to","type":"address"},{"name":"value","type":"uint256"}],"outputs":[{"name":"","type":"bool"}]}]`
+		approveABI  = `[{"type":"function","name":"approve","inputs":[{"name":"spender","type":"address"},{"name":"value","type":"uint256"}],"outputs":[{"name":"","type":"bool"}]}]`
+	)
+	contract4a := hexToBytes(dbtestdata.EthAddrContract4a)
+	contractCd := hexToBytes(dbtestdata.EthAddrContractCd)
+
+	getMethods := func(c []byte) []string {
+		t.Helper()
+		a, err := d.GetContractABI(c)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if a == nil {
+			return nil
+		}
+		r := []string{}
+		for name := range a.ABI.Methods {
+			r = append(r, name)
+		}
+		return r
+	}
+
+	if got := getMethods(contract4a); got != nil {
+		t.Fatalf("GetContractABI() = %v, want nil", got)
+	}
+	// the ABI is stored compacted, the missing ABI is not cached
+	if _, err := d.StoreContractABI(contract4a, []byte("{\n\"abi\": "+transferABI+"\n}")); err != nil {
+		t.Fatal(err)
+	}
+	if got := getMethods(contract4a); len(got) != 1 || got[0] != "transfer" {
+		t.Errorf("GetContractABI() = %v, want [transfer]", got)
+	}
+	if err := checkColumn(d, cfContractABIs, []keyPair{{dbtestdata.EthAddrContract4a, hex.EncodeToString([]byte(transferABI)), nil}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := d.StoreContractABI(contract4a, []byte("[")); err == nil {
+		t.Error("StoreContractABI() invalid ABI, expected error")
+	}
+	if _, err := d.StoreContractABI(contract4a, []byte(approveABI)); err != nil {
+		t.Fatal(err)
+	}
+	if got := getMethods(contract4a); len(got) != 1 || got[0] != "approve" {
+		t.Errorf("GetContractABI() = %v, want [approve]", got)
+	}
+	if err := d.DeleteContractABI(contract4a); err != nil {
+		t.Fatal(err)
+	}
+	if got := getMethods(contract4a); got != nil {
+		t.Errorf("GetContractABI() after delete = %v, want nil", got)
+	}
+	cachedContractABIsMux.Lock()
+	if _, found := cachedContractABIs[string(contract4a)]; found {
+		t.Error("GetContractABI() cached the missing ABI")
+	}
+	cachedContractABIsMux.Unlock()
+
+	// import from the directory skips the files with invalid name or content
+	dir := t.TempDir()
+	files := map[string]string{
+		"0x" + dbtestdata.EthAddrContract4a + ".json": transferABI,
+		"0x" + dbtestdata.EthAddrContractCd + ".JSON": approveABI,
+		"0x" + dbtestdata.EthAddr3e + ".json":         "invalid",
+		"readme.json":                                 transferABI,
+		"0x" + dbtestdata.EthAddr55 + ".txt":          transferABI,
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	n, err := d.ImportContractABIs(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 {
+		t.Errorf("ImportContractABIs() = %v, want 2", n)
+	}
+	if got := getMethods(contract4a); len(got) != 1 || got[0] != "transfer" {
+		t.Errorf("GetContractABI() imported = %v, want [transfer]", got)
+	}
+	if got := getMethods(contractCd); len(got) != 1 || got[0] != "approve" {
+		t.Errorf("GetContractABI() imported = %v, want [approve]", got)
+	}
+	if got := getMethods(hexToBytes(dbtestdata.EthAddr55)); got != nil {
+		t.Errorf("GetContractABI() not imported = %v, want nil", got)
+	}
+}
@@ -0,0 +1,225 @@
+package db
+
+import (
+	"bytes"
+	"encoding/hex"
+
+	vlq "github.com/bsm/go-vlq"
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+	"github.com/linxGnu/grocksdb"
+	"github.com/trezor/blockbook/bchain"
+	"github.com/trezor/blockbook/bchain/coins/eth"
+)
+
+// The contractLogs column keeps the logs selected by the contract log filters of the coin configuration
+// under the key contract+topic0+height+logIndex, the value is txid+varuint number of other topics+other topics+data.
+// The blockContractLogs column keeps the keys of the logs of the block for the blocks that can be disconnected.
+
+const (
+	contractLogTopicLen     = 32
+	packedContractLogKeyLen = eth.EthereumTypeAddressDescriptorLen + contractLogTopicLen + 2*packedHeightBytes
+)
+
+// ContractLog is a log of the contract stored in the contract log index, Topics contain also the topic0
+type ContractLog struct {
+	Txid     string
+	Height   uint32
+	LogIndex uint32
+	Topics   [][]byte
+	Data     []byte
+}
+
+// GetContractLogsCallback is called by GetContractLogs for each log, it can stop the iteration by returning StopIteration
+type GetContractLogsCallback func(log *ContractLog) error
+
+type ethBlockTxLog struct {
+	key   []byte
+	value []byte
+}
+
+func decodeLogHex(s string) ([]byte, error) {
+	if len(s) >= 2 && s[0] == '0' && (s[1]|32) == 'x' {
+		s = s[2:]
+	}
+	return hex.DecodeString(s)
+}
+
+func packContractLogKey(contract bchain.AddressDescriptor, topic0 []byte, height, logIndex uint32) []byte {
+	key := make([]byte, 0, packedContractLogKeyLen)
+	key = append(key, contract...)
+	key = append(key, topic0...)
+	key = append(key, packUint(height)...)
+	return append(key, packUint(logIndex)...)
+}
+
+func packContractLog(btxID []byte, l *bchain.RpcLog) ([]byte, error) {
+	data, err := decodeLogHex(l.Data)
+	if err != nil {
+		return nil, err
+	}
+	varBuf := make([]byte, vlq.MaxLen64)
+	vl := packVaruint(uint(len(l.Topics)-1), varBuf)
+	buf := make([]byte, 0, len(btxID)+vl+contractLogTopicLen*(len(l.Topics)-1)+len(data))
+	buf = append(buf, btxID...)
+	buf = append(buf, varBuf[:vl]...)
+	for _, t := range l.Topics[1:] {
+		topic, err := decodeLogHex(t)
+		if err != nil {
+			return nil, err
+		}
+		if len(topic) != contractLogTopicLen {
+			return nil, errors.Errorf("Invalid topic %v", t)
+		}
+		buf = append(buf, topic...)
+	}
+	return append(buf, data...), nil
+}
+
+func (ei *ethereumTypeIndex) unpackContractLog(key, value []byte) (*ContractLog, error) {
+	if len(key) != packedContractLogKeyLen {
+		return nil, errors.New("Invalid contract log key")
+	}
+	pl := ei.chainParser.PackedTxidLen()
+	if len(value) < pl+1 {
+		return nil, errors.New("Invalid contract log value")
+	}
+	txid, err := ei.chainParser.UnpackTxid(value[:pl])
+	if err != nil {
+		return nil, err
+	}
+	l := eth.EthereumTypeAddressDescriptorLen
+	r := &ContractLog{
+		Txid:     txid,
+		Height:   unpackUint(key[l+contractLogTopicLen:]),
+		LogIndex: unpackUint(key[l+contractLogTopicLen+packedHeightBytes:]),
+		Topics:   [][]byte{append([]byte(nil), key[l:l+contractLogTopicLen]...)},
+	}
+	value = value[pl:]
+	topics, ll := unpackVaruint(value)
+	value = value[ll:]
+	if len(value) < int(topics)*contractLogTopicLen {
+		return nil, errors.New("Invalid contract log value")
+	}
+	for i := uint(0); i < topics; i++ {
+		r.Topics = append(r.Topics, append([]byte(nil), value[:contractLogTopicLen]...))
+		value = value[contractLogTopicLen:]
+	}
+	r.Data = append([]byte(nil), value...)
+	return r, nil
+}
+
+// processContractLogs assigns the logs of the block selected by the contract log filters to the transactions of the block
+func (ei *ethereumTypeIndex) processContractLogs(blockTxs []ethBlockTx, block *bchain.Block) {
+	if !ei.chainParser.UseContractLogs() {
+		return
+	}
+	logs, err := ei.chainParser.EthereumTypeGetContractLogsFromBlock(block)
+	if err != nil {
+		glog.Warningf("rocksdb: processContractLogs %v, block %v", err, block.Height)
+		return
+	}
+	for _, cl := range logs {
+		if cl.TxIndex >= len(blockTxs) {
+			continue
+		}
+		blockTx := &blockTxs[cl.TxIndex]
+		contract, err := ei.chainParser.GetAddrDescFromAddress(cl.Log.Address)
+		var topic0, value []byte
+		if err == nil {
+			topic0, err = decodeLogHex(cl.Log.Topics[0])
+			if err == nil && len(topic0) != contractLogTopicLen {
+				err = errors.Errorf("Invalid topic %v", cl.Log.Topics[0])
+			}
+			if err == nil {
+				value, err = packContractLog(blockTx.btxID, cl.Log)
+			}
+		}
+		if err != nil {
+			glog.Warningf("rocksdb: processContractLogs %v, tx %v, log %v", err, block.Txs[cl.TxIndex].Txid, cl.LogIndex)
+			continue
+		}
+		blockTx.logs = append(blockTx.logs, ethBlockTxLog{
+			key:   packContractLogKey(contract, topic0, block.Height, cl.LogIndex),
+			value: value,
+		})
+	}
+}
+
+func (d *RocksDB) storeContractLogsEthereumType(wb *grocksdb.WriteBatch, blockTxs []ethBlockTx) {
+	for i := range blockTxs {
+		for j := range blockTxs[i].logs {
+			l := &blockTxs[i].logs[j]
+			wb.PutCF(d.cfh[cfContractLogs], l.key, l.value)
+		}
+	}
+}
+
+// storeAndCleanupBlockContractLogs stores the keys of the logs of the block, so that they can be removed
+// on the disconnection of the block, and removes the keys of the block, which can be no more disconnected
+func (d *RocksDB) storeAndCleanupBlockContractLogs(wb *grocksdb.WriteBatch, height uint32, blockTxs []ethBlockTx) {
+	var buf []byte
+	for i := range blockTxs {
+		for j := range blockTxs[i].logs {
+			buf = append(buf, blockTxs[i].logs[j].key...)
+		}
+	}
+	if len(buf) > 0 {
+		wb.PutCF(d.cfh[cfBlockContractLogs], packUint(height), buf)
+	}
+	keep := uint32(d.chainParser.KeepBlockAddresses())
+	if keep > 0 && height > keep {
+		wb.DeleteCF(d.cfh[cfBlockContractLogs], packUint(height-keep))
+	}
+}
+
+func (d *RocksDB) disconnectContractLogs(wb *grocksdb.WriteBatch, height uint32) error {
+	key := packUint(height)
+	val, err := d.db.GetCF(d.ro, d.cfh[cfBlockContractLogs], key)
+	if err != nil {
+		return err
+	}
+	defer val.Free()
+	buf := val.Data()
+	for len(buf) >= packedContractLogKeyLen {
+		wb.DeleteCF(d.cfh[cfContractLogs], buf[:packedContractLogKeyLen])
+		buf = buf[packedContractLogKeyLen:]
+	}
+	wb.DeleteCF(d.cfh[cfBlockContractLogs], key)
+	return nil
+}
+
+// GetContractLogs calls fn for the logs of the contract with the topic0 in the blocks lower-higher, ordered by height and log index
+func (d *RocksDB) GetContractLogs(contract bchain.AddressDescriptor, topic0 []byte, lower, higher uint32, fn GetContractLogsCallback) error {
+	if d.chainParser.GetChainType() != bchain.ChainEthereumType {
+		return errors.New("Unsupported chain type")
+	}
+	if len(contract) != eth.EthereumTypeAddressDescriptorLen || len(topic0) != contractLogTopicLen {
+		return nil
+	}
+	prefix := make([]byte, 0, packedContractLogKeyLen)
+	prefix = append(prefix, contract...)
+	prefix = append(prefix, topic0...)
+	startKey := append(append([]byte(nil), prefix...), packUint(lower)...)
+	stopKey := append(append([]byte(nil), prefix...), packUint(higher)...)
+	ei := d.ethereumTypeIndex()
+	it := d.db.NewIteratorCF(d.ro, d.cfh[cfContractLogs])
+	defer it.Close()
+	for it.Seek(startKey); it.Valid(); it.Next() {
+		key := it.Key().Data()
+		if bytes.Compare(key[:len(stopKey)], stopKey) > 0 {
+			break
+		}
+		l, err := ei.unpackContractLog(key, it.Value().Data())
+		if err != nil {
+			return err
+		}
+		if err := fn(l); err != nil {
+			if _, ok := err.(*StopIteration); ok {
+				return nil
+			}
+			return err
+		}
+	}
+	return nil
+}
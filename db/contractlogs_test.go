@@ -0,0 +1,122 @@
+//go:build unittest
+
+package db
+
+import (
+	"encoding/hex"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/trezor/blockbook/bchain"
+	"github.com/trezor/blockbook/bchain/coins/eth"
+	"github.com/trezor/blockbook/tests/dbtestdata"
+)
+
+// addContractLog appends the log to the i-th transaction of the block and returns the index of the log in the block
+func addContractLog(block *bchain.Block, i int, contract string, topics []string, data string) uint32 {
+	var logIndex uint32
+	for j := 0; j <= i; j++ {
+		csd := block.Txs[j].CoinSpecificData.(bchain.EthereumSpecificData)
+		if csd.Receipt != nil {
+			logIndex += uint32(len(csd.Receipt.Logs))
+		}
+	}
+	csd := block.Txs[i].CoinSpecificData.(bchain.EthereumSpecificData)
+	csd.Receipt.Logs = append(csd.Receipt.Logs, &bchain.RpcLog{
+		Address: "0x" + contract,
+		Topics:  topics,
+		Data:    data,
+	})
+	return logIndex
+}
+
+func TestRocksDB_ContractLogs_EthereumType(t *testing.T) {
+	topic0 := "0x" + strings.Repeat("a1", 32)
+	otherTopic := "0x" + strings.Repeat("b2", 32)
+	topic1 := "0x000000000000000000000000" + dbtestdata.EthAddr3e
+	parser := ethereumTestnetParser()
+	if err := parser.SetContractLogFilters([]eth.ContractLogFilter{
+		{Contract: "0x" + dbtestdata.EthAddrContract4a, Topics: []string{topic0}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	d := setupRocksDB(t, &testEthereumParser{
+		EthereumParser: parser,
+	})
+	defer closeAndDestroyRocksDB(t, d)
+
+	hexToBytes := func(s string) []byte {
+		b, err := hex.DecodeString(strings.TrimPrefix(s, "0x"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return b
+	}
+	contract := hexToBytes(dbtestdata.EthAddrContract4a)
+	getLogs := func(lower, higher uint32, max int) []ContractLog {
+		t.Helper()
+		r := []ContractLog{}
+		if err := d.GetContractLogs(contract, hexToBytes(topic0), lower, higher, func(l *ContractLog) error {
+			r = append(r, *l)
+			if len(r) == max {
+				return &StopIteration{}
+			}
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		return r
+	}
+
+	block1 := dbtestdata.GetTestEthereumTypeBlock1(d.chainParser)
+	// the log of other contract and the log with other topic are not indexed
+	addContractLog(block1, 0, dbtestdata.EthAddrContractCd, []string{topic0}, "0x")
+	log1 := ContractLog{
+		Txid:     block1.Txs[1].Txid,
+		Height:   4321000,
+		LogIndex: addContractLog(block1, 1, dbtestdata.EthAddrContract4a, []string{topic0, topic1}, "0x0102"),
+		Topics:   [][]byte{hexToBytes(topic0), hexToBytes(topic1)},
+		Data:     []byte{1, 2},
+	}
+	addContractLog(block1, 1, dbtestdata.EthAddrContract4a, []string{otherTopic}, "0x")
+	if err := d.ConnectBlock(block1); err != nil {
+		t.Fatal(err)
+	}
+	block2 := dbtestdata.GetTestEthereumTypeBlock2(d.chainParser)
+	log2 := ContractLog{
+		Txid:     block2.Txs[0].Txid,
+		Height:   4321001,
+		LogIndex: addContractLog(block2, 0, dbtestdata.EthAddrContract4a, []string{topic0}, "0x"),
+		Topics:   [][]byte{hexToBytes(topic0)},
+	}
+	if err := d.ConnectBlock(block2); err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := getLogs(0, ^uint32(0), 0), []ContractLog{log1, log2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GetContractLogs() = %+v, want %+v", got, want)
+	}
+	if got, want := getLogs(4321001, 4321001, 0), []ContractLog{log2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GetContractLogs(4321001, 4321001) = %+v, want %+v", got, want)
+	}
+	if got, want := getLogs(0, ^uint32(0), 1), []ContractLog{log1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GetContractLogs() stopped = %+v, want %+v", got, want)
+	}
+	// the keys of the logs of the first block cannot be disconnected anymore and are removed
+	key2 := dbtestdata.EthAddrContract4a + strings.Repeat("a1", 32) + uintToHex(4321001) + uintToHex(log2.LogIndex)
+	if err := checkColumn(d, cfBlockContractLogs, []keyPair{{"0041eee9", key2, nil}}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the disconnection of the second block removes its logs
+	if err := d.DisconnectBlockRangeEthereumType(4321001, 4321001); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := getLogs(0, ^uint32(0), 0), []ContractLog{log1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("GetContractLogs() after disconnect = %+v, want %+v", got, want)
+	}
+	if err := checkColumn(d, cfBlockContractLogs, []keyPair{}); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,1009 @@
+package db
+
+import (
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+	"github.com/trezor/blockbook/bchain"
+	"github.com/trezor/blockbook/bchain/coins/eth"
+	"github.com/trezor/blockbook/common"
+)
+
+// MemoryStore is an implementation of Store keeping the index in memory, it is intended for tests.
+// The changes of the index are computed by the same code as in RocksDB, the stored values
+// are copied on read and write so that the callers cannot modify the index.
+type MemoryStore struct {
+	mux           sync.Mutex
+	chainParser   bchain.BlockChainParser
+	is            *common.InternalState
+	extendedIndex bool
+	cbs           connectBlockStats
+	blocks        map[uint32]*BlockInfo
+	// addresses maps the address descriptor to the heights and the transactions with indexes in the blocks
+	addresses   map[string]map[uint32][]txIndexes
+	balances    map[string]*AddrBalance
+	txAddresses map[string]*TxAddresses
+	blockTxs    map[uint32][]blockTxs
+	blockFilter map[string]string
+	txs         map[string][]byte
+	// EthereumType data, the data of the transactions, approvals and logs are kept packed as in RocksDB
+	addressContracts   map[string]*AddrContracts
+	ethBlockTxs        map[uint32][]byte
+	approvals          map[string][]byte
+	blockApprovals     map[uint32][]byte
+	contractLogs       map[string][]byte
+	blockContractLogs  map[uint32][]byte
+	contracts          map[string]*bchain.ContractInfo
+	internalData       map[string][]byte
+	internalDataErrors map[uint32]BlockInternalDataError
+	// addressAliases maps the address to the name of the alias
+	addressAliases       map[string]string
+	fourByteSignatures   map[uint32][]bchain.FourByteSignature
+	eventSignatures      map[string][]bchain.FourByteSignature
+	contractABIs         map[string]*eth.ContractABI
+	fiatRatesLastTickers []common.CurrencyRatesTicker
+}
+
+// NewMemoryStore creates an empty MemoryStore for the chain of the parser
+func NewMemoryStore(parser bchain.BlockChainParser, extendedIndex bool) *MemoryStore {
+	if parser.GetChainType() != bchain.ChainBitcoinType {
+		extendedIndex = false
+	}
+	return &MemoryStore{
+		chainParser:        parser,
+		extendedIndex:      extendedIndex,
+		blocks:             make(map[uint32]*BlockInfo),
+		addresses:          make(map[string]map[uint32][]txIndexes),
+		balances:           make(map[string]*AddrBalance),
+		txAddresses:        make(map[string]*TxAddresses),
+		blockTxs:           make(map[uint32][]blockTxs),
+		blockFilter:        make(map[string]string),
+		txs:                make(map[string][]byte),
+		addressContracts:   make(map[string]*AddrContracts),
+		ethBlockTxs:        make(map[uint32][]byte),
+		approvals:          make(map[string][]byte),
+		blockApprovals:     make(map[uint32][]byte),
+		contractLogs:       make(map[string][]byte),
+		blockContractLogs:  make(map[uint32][]byte),
+		contracts:          make(map[string]*bchain.ContractInfo),
+		internalData:       make(map[string][]byte),
+		internalDataErrors: make(map[uint32]BlockInternalDataError),
+		addressAliases:     make(map[string]string),
+		fourByteSignatures: make(map[uint32][]bchain.FourByteSignature),
+		eventSignatures:    make(map[string][]bchain.FourByteSignature),
+		contractABIs:       make(map[string]*eth.ContractABI),
+	}
+}
+
+// SetInternalState sets the internal state, it is optional, the block times and the block filters are maintained only if it is set
+func (m *MemoryStore) SetInternalState(is *common.InternalState) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.is = is
+}
+
+func copyAddrBalance(ab *AddrBalance, detail AddressBalanceDetail) *AddrBalance {
+	r := &AddrBalance{Txs: ab.Txs}
+	r.SentSat.Set(&ab.SentSat)
+	r.BalanceSat.Set(&ab.BalanceSat)
+	if detail != AddressBalanceDetailNoUTXO {
+		r.Utxos = make([]Utxo, 0, len(ab.Utxos))
+		for i := range ab.Utxos {
+			u := &ab.Utxos[i]
+			// utxos with Vout < 0 are marked as spent
+			if u.Vout < 0 {
+				continue
+			}
+			c := Utxo{
+				BtxID:  append([]byte(nil), u.BtxID...),
+				Vout:   u.Vout,
+				Height: u.Height,
+			}
+			c.ValueSat.Set(&u.ValueSat)
+			if detail == AddressBalanceDetailUTXO {
+				r.Utxos = append(r.Utxos, c)
+			} else {
+				r.addUtxo(&c)
+			}
+		}
+	}
+	return r
+}
+
+func (m *MemoryStore) copyTxAddresses(ta *TxAddresses) *TxAddresses {
+	r := &TxAddresses{
+		Height:  ta.Height,
+		Inputs:  make([]TxInput, len(ta.Inputs)),
+		Outputs: make([]TxOutput, len(ta.Outputs)),
+	}
+	if m.extendedIndex {
+		r.VSize = ta.VSize
+	}
+	for i := range ta.Inputs {
+		s, d := &ta.Inputs[i], &r.Inputs[i]
+		d.AddrDesc = append(bchain.AddressDescriptor(nil), s.AddrDesc...)
+		d.ValueSat.Set(&s.ValueSat)
+		if m.extendedIndex {
+			d.Txid = s.Txid
+			d.Vout = s.Vout
+		}
+	}
+	for i := range ta.Outputs {
+		s, d := &ta.Outputs[i], &r.Outputs[i]
+		d.AddrDesc = append(bchain.AddressDescriptor(nil), s.AddrDesc...)
+		d.Spent = s.Spent
+		d.ValueSat.Set(&s.ValueSat)
+		if m.extendedIndex && s.Spent {
+			d.SpentTxid = s.SpentTxid
+			d.SpentIndex = s.SpentIndex
+			d.SpentHeight = s.SpentHeight
+		}
+	}
+	return r
+}
+
+func (m *MemoryStore) bitcoinTypeIndex() *bitcoinTypeIndex {
+	return &bitcoinTypeIndex{
+		chainParser:        m.chainParser,
+		extendedIndex:      m.extendedIndex,
+		cbs:                &m.cbs,
+		getAddrDescBalance: m.getAddrDescBalance,
+		getTxAddresses:     m.getTxAddresses,
+	}
+}
+
+func (m *MemoryStore) ethereumTypeIndex() *ethereumTypeIndex {
+	return &ethereumTypeIndex{
+		chainParser:                    m.chainParser,
+		cbs:                            &m.cbs,
+		getAddrDescContracts:           m.getAddrDescContracts,
+		getTxIndexesForAddressAndBlock: m.getTxIndexesForAddressAndBlock,
+		getEthereumInternalData:        m.getEthereumInternalData,
+	}
+}
+
+// GetBestBlock returns the block hash of the block with highest height in the store
+func (m *MemoryStore) GetBestBlock() (uint32, string, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	var bestHeight uint32
+	var bestHash string
+	for h, bi := range m.blocks {
+		if bestHash == "" || h > bestHeight {
+			bestHeight, bestHash = h, bi.Hash
+		}
+	}
+	return bestHeight, bestHash, nil
+}
+
+// GetBlockHash returns block hash at given height or empty string if not found
+func (m *MemoryStore) GetBlockHash(height uint32) (string, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	if bi, found := m.blocks[height]; found {
+		return bi.Hash, nil
+	}
+	return "", nil
+}
+
+// GetBlockInfo returns block info stored in the store
+func (m *MemoryStore) GetBlockInfo(height uint32) (*BlockInfo, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	if bi, found := m.blocks[height]; found {
+		r := *bi
+		return &r, nil
+	}
+	return nil, nil
+}
+
+// ConnectBlock indexes addresses in the block and stores them in the store
+func (m *MemoryStore) ConnectBlock(block *bchain.Block) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	switch m.chainParser.GetChainType() {
+	case bchain.ChainBitcoinType:
+		return m.connectBlockBitcoinType(block)
+	case bchain.ChainEthereumType:
+		return m.connectBlockEthereumType(block)
+	}
+	return errors.New("Unknown chain type")
+}
+
+func (m *MemoryStore) connectBlockBitcoinType(block *bchain.Block) error {
+	addresses := make(addressesMap)
+	txAddressesMap := make(map[string]*TxAddresses)
+	balances := make(map[string]*AddrBalance)
+	var gf *bchain.GolombFilter
+	if m.is != nil {
+		var err error
+		gf, err = bchain.NewGolombFilter(m.is.BlockGolombFilterP, m.is.BlockFilterScripts, block.BlockHeader.Hash, m.is.BlockFilterUseZeroedKey)
+		if err != nil {
+			glog.Error("ConnectBlock golomb filter error ", err)
+			gf = nil
+		} else if gf != nil && !gf.Enabled {
+			gf = nil
+		}
+	}
+	if err := m.bitcoinTypeIndex().processAddressesBitcoinType(block, addresses, txAddressesMap, balances, gf); err != nil {
+		return err
+	}
+	bt := make([]blockTxs, len(block.Txs))
+	zeroTx := make([]byte, m.chainParser.PackedTxidLen())
+	for i := range block.Txs {
+		tx := &block.Txs[i]
+		btxID, err := m.chainParser.PackTxid(tx.Txid)
+		if err != nil {
+			return err
+		}
+		bt[i].btxID = btxID
+		bt[i].inputs = make([]outpoint, len(tx.Vin))
+		for v := range tx.Vin {
+			vin := &tx.Vin[v]
+			o := &bt[i].inputs[v]
+			if o.btxID, err = m.chainParser.PackTxid(vin.Txid); err != nil {
+				// do not process inputs without input txid
+				if err != bchain.ErrTxidMissing {
+					return err
+				}
+				o.btxID = zeroTx
+			}
+			o.index = int32(vin.Vout)
+		}
+	}
+	for k, ta := range txAddressesMap {
+		m.txAddresses[k] = m.copyTxAddresses(ta)
+	}
+	m.storeBalances(balances)
+	m.storeAddresses(block.Height, addresses)
+	m.blockTxs[block.Height] = bt
+	// keep the blockTxs only for the last blocks as RocksDB does
+	if keep := uint32(m.chainParser.KeepBlockAddresses()); block.Height > keep {
+		for h := range m.blockTxs {
+			if h <= block.Height-keep {
+				delete(m.blockTxs, h)
+			}
+		}
+	}
+	if gf != nil {
+		m.blockFilter[block.Hash] = hex.EncodeToString(gf.Compute())
+	}
+	m.storeBlock(block)
+	return nil
+}
+
+func (m *MemoryStore) connectBlockEthereumType(block *bchain.Block) error {
+	addresses := make(addressesMap)
+	addressContracts := make(map[string]*AddrContracts)
+	ei := m.ethereumTypeIndex()
+	blockTxs, err := ei.processAddressesEthereumType(block, addresses, addressContracts)
+	if err != nil {
+		return err
+	}
+	m.storeAddressContracts(addressContracts)
+	m.storeInternalData(blockTxs)
+	var approvalKeys, logKeys []byte
+	buf := make([]byte, maxPackedBigintBytes)
+	for i := range blockTxs {
+		for j := range blockTxs[i].approvals {
+			a := &blockTxs[i].approvals[j]
+			key := packApprovalKey(a)
+			l := packBigint(&a.value, buf)
+			m.approvals[string(key)] = append([]byte(nil), buf[:l]...)
+			approvalKeys = append(approvalKeys, key...)
+		}
+		for j := range blockTxs[i].logs {
+			l := &blockTxs[i].logs[j]
+			m.contractLogs[string(l.key)] = append([]byte(nil), l.value...)
+			logKeys = append(logKeys, l.key...)
+		}
+	}
+	if err := m.storeBlockSpecificDataEthereumType(block); err != nil {
+		return err
+	}
+	// keep the blockTxs, the approvals and the logs of the block only for the last blocks as RocksDB does
+	m.ethBlockTxs[block.Height] = packBlockTxsEthereumType(blockTxs)
+	if len(approvalKeys) > 0 {
+		m.blockApprovals[block.Height] = approvalKeys
+	}
+	if len(logKeys) > 0 {
+		m.blockContractLogs[block.Height] = logKeys
+	}
+	if keep := uint32(m.chainParser.KeepBlockAddresses()); keep > 0 && block.Height > keep {
+		delete(m.blockApprovals, block.Height-keep)
+		delete(m.blockContractLogs, block.Height-keep)
+		for h := range m.ethBlockTxs {
+			if h <= block.Height-keep {
+				delete(m.ethBlockTxs, h)
+			}
+		}
+	}
+	m.storeAddresses(block.Height, addresses)
+	m.storeBlock(block)
+	return nil
+}
+
+func (m *MemoryStore) storeBlock(block *bchain.Block) {
+	m.blocks[block.Height] = &BlockInfo{
+		Hash:   block.Hash,
+		Time:   block.Time,
+		Txs:    uint32(len(block.Txs)),
+		Size:   uint32(block.Size),
+		Height: block.Height,
+	}
+	if m.is != nil {
+		m.is.UpdateBestHeight(block.Height)
+		m.is.AppendBlockTime(uint32(block.Time))
+	}
+}
+
+func (m *MemoryStore) storeAddresses(height uint32, addresses addressesMap) {
+	for a, txi := range addresses {
+		ah, found := m.addresses[a]
+		if !found {
+			ah = make(map[uint32][]txIndexes)
+			m.addresses[a] = ah
+		}
+		ah[height] = txi
+	}
+}
+
+func (m *MemoryStore) storeAddressContracts(acm map[string]*AddrContracts) {
+	for addrDesc, acs := range acm {
+		// address with 0 contracts is removed - happens on disconnect
+		if acs == nil || (acs.NonContractTxs == 0 && acs.InternalTxs == 0 && len(acs.Contracts) == 0) {
+			delete(m.addressContracts, addrDesc)
+		} else {
+			m.addressContracts[addrDesc], _ = unpackAddrContracts(packAddrContracts(acs), bchain.AddressDescriptor(addrDesc))
+		}
+	}
+}
+
+func (m *MemoryStore) storeInternalData(blockTxs []ethBlockTx) {
+	for i := range blockTxs {
+		blockTx := &blockTxs[i]
+		if blockTx.internalData != nil {
+			m.internalData[string(blockTx.btxID)] = packEthInternalData(blockTx.internalData)
+		}
+	}
+}
+
+func (m *MemoryStore) storeBlockSpecificDataEthereumType(block *bchain.Block) error {
+	blockSpecificData, _ := block.CoinSpecificData.(*bchain.EthereumBlockSpecificData)
+	if blockSpecificData == nil {
+		return nil
+	}
+	if blockSpecificData.InternalDataError != "" {
+		m.internalDataErrors[block.Height] = BlockInternalDataError{
+			Height:       block.Height,
+			Hash:         block.Hash,
+			ErrorMessage: blockSpecificData.InternalDataError,
+		}
+	}
+	if m.chainParser.UseAddressAliases() {
+		for i := range blockSpecificData.AddressAliasRecords {
+			r := &blockSpecificData.AddressAliasRecords[i]
+			if len(r.Name) > 0 {
+				m.addressAliases[r.Address] = r.Name
+			}
+		}
+	}
+	for i := range blockSpecificData.Contracts {
+		if err := m.storeContractInfo(&blockSpecificData.Contracts[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) storeBalances(balances map[string]*AddrBalance) {
+	for a, ab := range balances {
+		// balance with 0 transactions is removed - happens on disconnect
+		if ab == nil || ab.Txs <= 0 {
+			delete(m.balances, a)
+		} else {
+			m.balances[a] = copyAddrBalance(ab, AddressBalanceDetailUTXO)
+		}
+	}
+}
+
+// DisconnectBlockRangeBitcoinType removes all data belonging to blocks in range lower-higher
+func (m *MemoryStore) DisconnectBlockRangeBitcoinType(lower uint32, higher uint32) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	for height := lower; height <= higher; height++ {
+		if len(m.blockTxs[height]) == 0 {
+			return errors.Errorf("Cannot disconnect blocks with height %v and lower. It is necessary to rebuild index.", height)
+		}
+	}
+	for height := higher; height >= lower; height-- {
+		r, err := m.bitcoinTypeIndex().disconnectBlockTxs(m.blockTxs[height])
+		if err != nil {
+			return err
+		}
+		for a := range r.blockAddressesTxs {
+			m.deleteAddressHeight(a, height)
+		}
+		for k, ta := range r.txAddressesToUpdate {
+			m.txAddresses[k] = m.copyTxAddresses(ta)
+		}
+		m.storeBalances(r.balances)
+		for k := range r.txsToDelete {
+			delete(m.txAddresses, k)
+			delete(m.txs, k)
+		}
+		if bi, found := m.blocks[height]; found {
+			delete(m.blockFilter, bi.Hash)
+		}
+		delete(m.blocks, height)
+		delete(m.blockTxs, height)
+		if m.is != nil {
+			m.is.UpdateBestHeight(height - 1)
+		}
+		if height == 0 {
+			break
+		}
+	}
+	if m.is != nil {
+		m.is.RemoveLastBlockTimes(int(higher-lower) + 1)
+	}
+	return nil
+}
+
+func (m *MemoryStore) deleteAddressHeight(addrDesc string, height uint32) {
+	if ah, found := m.addresses[addrDesc]; found {
+		delete(ah, height)
+		if len(ah) == 0 {
+			delete(m.addresses, addrDesc)
+		}
+	}
+}
+
+// DisconnectBlockRangeEthereumType removes all data belonging to blocks in range lower-higher
+// it is able to disconnect only the last blocks, for which the blockTxs are kept
+func (m *MemoryStore) DisconnectBlockRangeEthereumType(lower uint32, higher uint32) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	blocks := make([][]ethBlockTx, higher-lower+1)
+	for height := lower; height <= higher; height++ {
+		buf, found := m.ethBlockTxs[height]
+		if !found {
+			return errors.Errorf("Cannot disconnect blocks with height %v and lower. It is necessary to rebuild index.", height)
+		}
+		blockTxs, err := unpackBlockTxsEthereumType(buf)
+		if err != nil {
+			return err
+		}
+		blocks[height-lower] = blockTxs
+	}
+	ei := m.ethereumTypeIndex()
+	contracts := make(map[string]*AddrContracts)
+	for height := higher; height >= lower; height-- {
+		blockTxs := blocks[height-lower]
+		addresses, err := ei.disconnectBlockTxs(height, blockTxs, contracts)
+		if err != nil {
+			return err
+		}
+		for i := range blockTxs {
+			delete(m.txs, string(blockTxs[i].btxID))
+			delete(m.internalData, string(blockTxs[i].btxID))
+		}
+		for a := range addresses {
+			m.deleteAddressHeight(a, height)
+		}
+		for buf := m.blockApprovals[height]; len(buf) >= packedApprovalKeyLen; buf = buf[packedApprovalKeyLen:] {
+			delete(m.approvals, string(buf[:packedApprovalKeyLen]))
+		}
+		for buf := m.blockContractLogs[height]; len(buf) >= packedContractLogKeyLen; buf = buf[packedContractLogKeyLen:] {
+			delete(m.contractLogs, string(buf[:packedContractLogKeyLen]))
+		}
+		delete(m.blockApprovals, height)
+		delete(m.blockContractLogs, height)
+		delete(m.ethBlockTxs, height)
+		delete(m.blocks, height)
+		delete(m.internalDataErrors, height)
+		if m.is != nil {
+			m.is.UpdateBestHeight(height - 1)
+		}
+		if height == 0 {
+			break
+		}
+	}
+	m.storeAddressContracts(contracts)
+	if m.is != nil {
+		m.is.RemoveLastBlockTimes(int(higher-lower) + 1)
+	}
+	return nil
+}
+
+// GetTransactions finds all input/output transactions for address
+func (m *MemoryStore) GetTransactions(address string, lower uint32, higher uint32, fn GetTransactionsCallback) error {
+	addrDesc, err := m.chainParser.GetAddrDescFromAddress(address)
+	if err != nil {
+		return err
+	}
+	return m.GetAddrDescTransactions(addrDesc, lower, higher, fn)
+}
+
+// GetAddrDescTransactions finds all input/output transactions for address descriptor
+// Transaction are passed to callback function in the order from newest block to the oldest
+func (m *MemoryStore) GetAddrDescTransactions(addrDesc bchain.AddressDescriptor, lower uint32, higher uint32, fn GetTransactionsCallback) error {
+	type heightTxs struct {
+		height uint32
+		txs    []txIndexes
+	}
+	// copy the data so that the callback is called without the lock
+	m.mux.Lock()
+	var found []heightTxs
+	for h, txi := range m.addresses[string(addrDesc)] {
+		if h >= lower && h <= higher {
+			found = append(found, heightTxs{h, txi})
+		}
+	}
+	m.mux.Unlock()
+	sort.Slice(found, func(i, j int) bool {
+		return found[i].height > found[j].height
+	})
+	for _, f := range found {
+		// the txs in the block are passed from newest to oldest as in RocksDB
+		for j := len(f.txs) - 1; j >= 0; j-- {
+			t := &f.txs[j]
+			txid, err := m.chainParser.UnpackTxid(t.btxID)
+			if err != nil {
+				return err
+			}
+			if err := fn(txid, f.height, append([]int32(nil), t.indexes...)); err != nil {
+				if _, ok := err.(*StopIteration); ok {
+					return nil
+				}
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (m *MemoryStore) getTxIndexesForAddressAndBlock(addrDesc bchain.AddressDescriptor, height uint32) ([]txIndexes, error) {
+	txi := m.addresses[string(addrDesc)][height]
+	if txi == nil {
+		return nil, nil
+	}
+	r := make([]txIndexes, len(txi))
+	for i := range txi {
+		r[i].btxID = append([]byte(nil), txi[i].btxID...)
+		r[i].indexes = append([]int32(nil), txi[i].indexes...)
+	}
+	return r, nil
+}
+
+func (m *MemoryStore) getAddrDescBalance(addrDesc bchain.AddressDescriptor, detail AddressBalanceDetail) (*AddrBalance, error) {
+	if ab, found := m.balances[string(addrDesc)]; found {
+		return copyAddrBalance(ab, detail), nil
+	}
+	return nil, nil
+}
+
+// GetAddrDescBalance returns AddrBalance for given addrDesc
+func (m *MemoryStore) GetAddrDescBalance(addrDesc bchain.AddressDescriptor, detail AddressBalanceDetail) (*AddrBalance, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	return m.getAddrDescBalance(addrDesc, detail)
+}
+
+// GetAddressBalance returns address balance for an address or nil if address not found
+func (m *MemoryStore) GetAddressBalance(address string, detail AddressBalanceDetail) (*AddrBalance, error) {
+	addrDesc, err := m.chainParser.GetAddrDescFromAddress(address)
+	if err != nil {
+		return nil, err
+	}
+	return m.GetAddrDescBalance(addrDesc, detail)
+}
+
+func (m *MemoryStore) getTxAddresses(btxID []byte) (*TxAddresses, error) {
+	if ta, found := m.txAddresses[string(btxID)]; found {
+		return m.copyTxAddresses(ta), nil
+	}
+	return nil, nil
+}
+
+// GetTxAddresses returns TxAddresses for given txid or nil if not found
+func (m *MemoryStore) GetTxAddresses(txid string) (*TxAddresses, error) {
+	btxID, err := m.chainParser.PackTxid(txid)
+	if err != nil {
+		return nil, err
+	}
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	return m.getTxAddresses(btxID)
+}
+
+// GetTx returns transaction stored in the store
+func (m *MemoryStore) GetTx(txid string) (*bchain.Tx, uint32, error) {
+	key, err := m.chainParser.PackTxid(txid)
+	if err != nil {
+		return nil, 0, err
+	}
+	m.mux.Lock()
+	data := m.txs[string(key)]
+	m.mux.Unlock()
+	if len(data) > 4 {
+		return m.chainParser.UnpackTx(data)
+	}
+	return nil, 0, nil
+}
+
+// PutTx stores transaction in the store
+func (m *MemoryStore) PutTx(tx *bchain.Tx, height uint32, blockTime int64) error {
+	key, err := m.chainParser.PackTxid(tx.Txid)
+	if err != nil {
+		return nil
+	}
+	buf, err := m.chainParser.PackTx(tx, height, blockTime)
+	if err != nil {
+		return err
+	}
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.txs[string(key)] = buf
+	return nil
+}
+
+// GetBlockFilter returns the block filter of the block or empty string if not found
+func (m *MemoryStore) GetBlockFilter(blockHash string) (string, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	return m.blockFilter[blockHash], nil
+}
+
+// HasExtendedIndex returns true if the store indexes input txids and spending data
+func (m *MemoryStore) HasExtendedIndex() bool {
+	return m.extendedIndex
+}
+
+// Snapshot returns the MemoryStore itself, the in-memory index does not support isolated read views
+func (m *MemoryStore) Snapshot() (Store, func()) {
+	return m, func() {}
+}
+
+func (m *MemoryStore) getAddrDescContracts(addrDesc bchain.AddressDescriptor) (*AddrContracts, error) {
+	acs := m.addressContracts[string(addrDesc)]
+	if acs == nil {
+		return nil, nil
+	}
+	// return a copy using the same serialization as RocksDB
+	return unpackAddrContracts(packAddrContracts(acs), addrDesc)
+}
+
+// GetAddrDescContracts returns AddrContracts for given addrDesc
+func (m *MemoryStore) GetAddrDescContracts(addrDesc bchain.AddressDescriptor) (*AddrContracts, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	return m.getAddrDescContracts(addrDesc)
+}
+
+// sortedKeysWithPrefix returns the sorted keys of the map starting with the prefix
+func sortedKeysWithPrefix(data map[string][]byte, prefix []byte) []string {
+	var keys []string
+	for k := range data {
+		if strings.HasPrefix(k, string(prefix)) {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// GetAddrDescApprovals returns the last approvals with non zero value given by the owner
+func (m *MemoryStore) GetAddrDescApprovals(owner bchain.AddressDescriptor) ([]TokenApproval, error) {
+	if m.chainParser.GetChainType() != bchain.ChainEthereumType {
+		return nil, errors.New("Unsupported chain type")
+	}
+	if len(owner) != eth.EthereumTypeAddressDescriptorLen {
+		return nil, nil
+	}
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	r := []TokenApproval{}
+	var last string
+	for _, key := range sortedKeysWithPrefix(m.approvals, owner) {
+		// the rows of the same contract, spender and kind are ordered from the latest
+		tuple := key[:len(key)-packedHeightBytes]
+		if tuple == last {
+			continue
+		}
+		last = tuple
+		value, _ := unpackBigint(m.approvals[key])
+		if value.Sign() == 0 {
+			continue
+		}
+		a, err := unpackApprovalKey([]byte(key))
+		if err != nil {
+			return nil, err
+		}
+		a.Value = value
+		r = append(r, *a)
+	}
+	return r, nil
+}
+
+// GetContractLogs calls fn for the logs of the contract with the topic0 in the blocks lower-higher, ordered by height and log index
+func (m *MemoryStore) GetContractLogs(contract bchain.AddressDescriptor, topic0 []byte, lower, higher uint32, fn GetContractLogsCallback) error {
+	if m.chainParser.GetChainType() != bchain.ChainEthereumType {
+		return errors.New("Unsupported chain type")
+	}
+	if len(contract) != eth.EthereumTypeAddressDescriptorLen || len(topic0) != contractLogTopicLen {
+		return nil
+	}
+	prefix := append(append([]byte(nil), contract...), topic0...)
+	// unpack the logs so that the callback is called without the lock
+	ei := m.ethereumTypeIndex()
+	var logs []*ContractLog
+	m.mux.Lock()
+	for _, key := range sortedKeysWithPrefix(m.contractLogs, prefix) {
+		height := unpackUint([]byte(key[len(prefix):]))
+		if height < lower || height > higher {
+			continue
+		}
+		l, err := ei.unpackContractLog([]byte(key), m.contractLogs[key])
+		if err != nil {
+			m.mux.Unlock()
+			return err
+		}
+		logs = append(logs, l)
+	}
+	m.mux.Unlock()
+	for _, l := range logs {
+		if err := fn(l); err != nil {
+			if _, ok := err.(*StopIteration); ok {
+				return nil
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// GetContractInfo gets contract from the store
+func (m *MemoryStore) GetContractInfo(contract bchain.AddressDescriptor, typeFromContext bchain.TokenTypeName) (*bchain.ContractInfo, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	ci := m.contracts[string(contract)]
+	if ci == nil {
+		return nil, nil
+	}
+	if typeFromContext != bchain.UnknownTokenType && ci.Type == bchain.UnknownTokenType {
+		ci.Type = typeFromContext
+	}
+	r := *ci
+	return &r, nil
+}
+
+// GetContractInfoForAddress gets contract from the store
+func (m *MemoryStore) GetContractInfoForAddress(address string) (*bchain.ContractInfo, error) {
+	contract, err := m.chainParser.GetAddrDescFromAddress(address)
+	if err != nil || contract == nil {
+		return nil, err
+	}
+	return m.GetContractInfo(contract, bchain.UnknownTokenType)
+}
+
+// StoreContractInfo stores contractInfo in the store
+// if CreatedInBlock==0 and DestructedInBlock!=0, it is evaluated as a destruction of a contract, the contract info is updated
+func (m *MemoryStore) StoreContractInfo(contractInfo *bchain.ContractInfo) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	return m.storeContractInfo(contractInfo)
+}
+
+func (m *MemoryStore) storeContractInfo(contractInfo *bchain.ContractInfo) error {
+	if contractInfo.Contract == "" {
+		return nil
+	}
+	key, err := m.chainParser.GetAddrDescFromAddress(contractInfo.Contract)
+	if err != nil {
+		return err
+	}
+	if contractInfo.CreatedInBlock == 0 && contractInfo.DestructedInBlock != 0 {
+		if ci := m.contracts[string(key)]; ci != nil {
+			ci.DestructedInBlock = contractInfo.DestructedInBlock
+		}
+		return nil
+	}
+	ci := *contractInfo
+	m.contracts[string(key)] = &ci
+	return nil
+}
+
+func (m *MemoryStore) getEthereumInternalData(btxID []byte) (*bchain.EthereumInternalData, error) {
+	buf := m.internalData[string(btxID)]
+	if len(buf) == 0 {
+		return nil, nil
+	}
+	return m.ethereumTypeIndex().unpackEthInternalData(buf)
+}
+
+// GetEthereumInternalData gets transaction internal data from the store
+func (m *MemoryStore) GetEthereumInternalData(txid string) (*bchain.EthereumInternalData, error) {
+	btxID, err := m.chainParser.PackTxid(txid)
+	if err != nil {
+		return nil, err
+	}
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	return m.getEthereumInternalData(btxID)
+}
+
+// StoreFourByteSignature stores the function signature
+func (m *MemoryStore) StoreFourByteSignature(fourBytes uint32, signature *bchain.FourByteSignature) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.fourByteSignatures[fourBytes] = append(m.fourByteSignatures[fourBytes], *signature)
+}
+
+// GetFourByteSignatures gets all the function signatures with the given four bytes
+func (m *MemoryStore) GetFourByteSignatures(fourBytes uint32) (*[]bchain.FourByteSignature, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	signatures, found := m.fourByteSignatures[fourBytes]
+	if !found {
+		return nil, nil
+	}
+	r := append([]bchain.FourByteSignature(nil), signatures...)
+	return &r, nil
+}
+
+// StoreEventSignature stores the event signature
+func (m *MemoryStore) StoreEventSignature(topic0 []byte, signature *bchain.FourByteSignature) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.eventSignatures[string(topic0)] = append(m.eventSignatures[string(topic0)], *signature)
+}
+
+// GetEventSignatures gets all the event signatures with the given topic0
+func (m *MemoryStore) GetEventSignatures(topic0 []byte) (*[]bchain.FourByteSignature, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	signatures, found := m.eventSignatures[string(topic0)]
+	if !found {
+		return nil, nil
+	}
+	r := append([]bchain.FourByteSignature(nil), signatures...)
+	return &r, nil
+}
+
+// StoreContractABI validates and stores the verified ABI of the contract
+func (m *MemoryStore) StoreContractABI(contract bchain.AddressDescriptor, data []byte) (*eth.ContractABI, error) {
+	a, err := eth.ParseContractABI(data)
+	if err != nil {
+		return nil, err
+	}
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.contractABIs[string(contract)] = a
+	return a, nil
+}
+
+// GetContractABI gets the verified ABI of the contract, returns nil if the ABI of the contract is not stored
+func (m *MemoryStore) GetContractABI(contract bchain.AddressDescriptor) (*eth.ContractABI, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	return m.contractABIs[string(contract)], nil
+}
+
+// GetAddressAlias returns the alias of the address or empty string
+func (m *MemoryStore) GetAddressAlias(address string) string {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	name, found := m.addressAliases[address]
+	if !found {
+		return ""
+	}
+	return m.chainParser.FormatAddressAlias(address, name)
+}
+
+// GetBlockInternalDataErrorsEthereumType returns the blocks with internal data errors
+func (m *MemoryStore) GetBlockInternalDataErrorsEthereumType() ([]BlockInternalDataError, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	r := make([]BlockInternalDataError, 0, len(m.internalDataErrors))
+	for _, e := range m.internalDataErrors {
+		r = append(r, e)
+	}
+	sort.Slice(r, func(i, j int) bool {
+		return r[i].Height < r[j].Height
+	})
+	return r, nil
+}
+
+// UpdateBlockInternalDataErrorEthereumType stores the internal data error of the block with the number of retries
+func (m *MemoryStore) UpdateBlockInternalDataErrorEthereumType(block *bchain.Block, message string, retryCount uint8) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.internalDataErrors[block.Height] = BlockInternalDataError{
+		Height:       block.Height,
+		Hash:         block.Hash,
+		Retries:      retryCount,
+		ErrorMessage: message,
+	}
+	return nil
+}
+
+// ReconnectInternalDataToBlockEthereumType adds missing internal data to the block and stores them in the store
+func (m *MemoryStore) ReconnectInternalDataToBlockEthereumType(block *bchain.Block) error {
+	if m.chainParser.GetChainType() != bchain.ChainEthereumType {
+		return errors.New("Unsupported chain type")
+	}
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	addresses := make(addressesMap)
+	addressContracts := make(map[string]*AddrContracts)
+	blockTxs, err := m.ethereumTypeIndex().reconnectInternalData(block, addresses, addressContracts)
+	if err != nil {
+		return err
+	}
+	m.storeAddressContracts(addressContracts)
+	m.storeInternalData(blockTxs)
+	m.storeAddresses(block.Height, addresses)
+	// remove the block from the internal errors
+	delete(m.internalDataErrors, block.Height)
+	return nil
+}
+
+// StoreFiatRatesTicker stores the ticker returned by FiatRatesFindLastTicker
+func (m *MemoryStore) StoreFiatRatesTicker(ticker *common.CurrencyRatesTicker) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.fiatRatesLastTickers = append(m.fiatRatesLastTickers, *ticker)
+	sort.SliceStable(m.fiatRatesLastTickers, func(i, j int) bool {
+		return m.fiatRatesLastTickers[i].Timestamp.Before(m.fiatRatesLastTickers[j].Timestamp)
+	})
+}
+
+// FiatRatesFindLastTicker gets the last stored ticker of the base currency, vsCurrency or the token if specified
+func (m *MemoryStore) FiatRatesFindLastTicker(vsCurrency string, token string) (*common.CurrencyRatesTicker, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	for i := len(m.fiatRatesLastTickers) - 1; i >= 0; i-- {
+		t := &m.fiatRatesLastTickers[i]
+		if vsCurrency != "" {
+			if _, found := t.Rates[vsCurrency]; !found {
+				continue
+			}
+		}
+		if token != "" {
+			if _, found := t.TokenRates[token]; !found {
+				continue
+			}
+		}
+		r := *t
+		return &r, nil
+	}
+	return nil, nil
+}
+
+// DatabaseSizeOnDisk returns 0, MemoryStore does not use disk
+func (m *MemoryStore) DatabaseSizeOnDisk() int64 {
+	return 0
+}
+
+// GetMemoryStats returns the number of stored items
+func (m *MemoryStore) GetMemoryStats() string {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	return fmt.Sprintf("MemoryStore: blocks %d, addresses %d, balances %d, txAddresses %d, txs %d",
+		len(m.blocks), len(m.addresses), len(m.balances), len(m.txAddresses), len(m.txs))
+}
+
+// GetAndResetConnectBlockStats gets statistics about cache usage in connect blocks and resets the counters
+func (m *MemoryStore) GetAndResetConnectBlockStats() string {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	s := fmt.Sprintf("%+v", m.cbs)
+	m.cbs = connectBlockStats{}
+	return s
+}
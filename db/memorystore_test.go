@@ -0,0 +1,255 @@
+//go:build unittest
+
+package db
+
+import (
+	"encoding/hex"
+	"reflect"
+	"testing"
+
+	"github.com/trezor/blockbook/bchain"
+	"github.com/trezor/blockbook/common"
+	"github.com/trezor/blockbook/tests/dbtestdata"
+)
+
+// memoryStoreMirror applies the changes of the index to RocksDB and to MemoryStore
+// and checks that MemoryStore contains the same data as the columns of RocksDB
+type memoryStoreMirror struct {
+	t *testing.T
+	d *RocksDB
+	m *MemoryStore
+}
+
+func newMemoryStoreMirror(t *testing.T, d *RocksDB) *memoryStoreMirror {
+	m := NewMemoryStore(d.chainParser, d.extendedIndex)
+	m.SetInternalState(&common.InternalState{
+		BlockGolombFilterP:      d.is.BlockGolombFilterP,
+		BlockFilterScripts:      d.is.BlockFilterScripts,
+		BlockFilterUseZeroedKey: d.is.BlockFilterUseZeroedKey,
+	})
+	return &memoryStoreMirror{t: t, d: d, m: m}
+}
+
+// result checks that both stores returned the same error and the same data, it returns the error of RocksDB
+func (s *memoryStoreMirror) result(errD, errM error) error {
+	s.t.Helper()
+	if (errD == nil) != (errM == nil) || (errD != nil && errD.Error() != errM.Error()) {
+		s.t.Errorf("MemoryStore error %v, RocksDB error %v", errM, errD)
+	}
+	s.check()
+	return errD
+}
+
+func (s *memoryStoreMirror) ConnectBlock(block *bchain.Block) error {
+	s.t.Helper()
+	return s.result(s.d.ConnectBlock(block), s.m.ConnectBlock(block))
+}
+
+func (s *memoryStoreMirror) DisconnectBlockRangeBitcoinType(lower uint32, higher uint32) error {
+	s.t.Helper()
+	return s.result(s.d.DisconnectBlockRangeBitcoinType(lower, higher), s.m.DisconnectBlockRangeBitcoinType(lower, higher))
+}
+
+func (s *memoryStoreMirror) DisconnectBlockRangeEthereumType(lower uint32, higher uint32) error {
+	s.t.Helper()
+	return s.result(s.d.DisconnectBlockRangeEthereumType(lower, higher), s.m.DisconnectBlockRangeEthereumType(lower, higher))
+}
+
+func (s *memoryStoreMirror) PutTx(tx *bchain.Tx, height uint32, blockTime int64) error {
+	s.t.Helper()
+	return s.result(s.d.PutTx(tx, height, blockTime), s.m.PutTx(tx, height, blockTime))
+}
+
+func bytesKeyPairs(data map[string][]byte) []keyPair {
+	kp := make([]keyPair, 0, len(data))
+	for k, v := range data {
+		kp = append(kp, keyPair{hex.EncodeToString([]byte(k)), hex.EncodeToString(v), nil})
+	}
+	return kp
+}
+
+func heightKeyPairs(data map[uint32][]byte) []keyPair {
+	kp := make([]keyPair, 0, len(data))
+	for h, v := range data {
+		kp = append(kp, keyPair{hex.EncodeToString(packUint(h)), hex.EncodeToString(v), nil})
+	}
+	return kp
+}
+
+// columns renders the content of MemoryStore as the columns of RocksDB
+func (s *memoryStoreMirror) columns() (map[int][]keyPair, error) {
+	d, m := s.d, s.m
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	c := make(map[int][]keyPair)
+	c[cfHeight] = []keyPair{}
+	for h, bi := range m.blocks {
+		v, err := d.packBlockInfo(bi)
+		if err != nil {
+			return nil, err
+		}
+		c[cfHeight] = append(c[cfHeight], keyPair{hex.EncodeToString(packUint(h)), hex.EncodeToString(v), nil})
+	}
+	c[cfAddresses] = []keyPair{}
+	for a, ah := range m.addresses {
+		for h, txi := range ah {
+			key := packAddressKey(bchain.AddressDescriptor(a), h)
+			c[cfAddresses] = append(c[cfAddresses], keyPair{hex.EncodeToString(key), hex.EncodeToString(d.packTxIndexes(txi)), nil})
+		}
+	}
+	c[cfTransactions] = bytesKeyPairs(m.txs)
+	varBuf := make([]byte, maxPackedBigintBytes)
+	switch d.chainParser.GetChainType() {
+	case bchain.ChainBitcoinType:
+		c[cfTxAddresses] = []keyPair{}
+		for k, ta := range m.txAddresses {
+			v := d.packTxAddresses(ta, nil, varBuf)
+			c[cfTxAddresses] = append(c[cfTxAddresses], keyPair{hex.EncodeToString([]byte(k)), hex.EncodeToString(v), nil})
+		}
+		c[cfAddressBalance] = []keyPair{}
+		for k, ab := range m.balances {
+			v := packAddrBalance(ab, nil, varBuf)
+			c[cfAddressBalance] = append(c[cfAddressBalance], keyPair{hex.EncodeToString([]byte(k)), hex.EncodeToString(v), nil})
+		}
+		c[cfBlockTxs] = []keyPair{}
+		for h, bt := range m.blockTxs {
+			var v []byte
+			for i := range bt {
+				v = append(v, bt[i].btxID...)
+				l := packVaruint(uint(len(bt[i].inputs)), varBuf)
+				v = append(v, varBuf[:l]...)
+				v = append(v, d.packOutpoints(bt[i].inputs)...)
+			}
+			c[cfBlockTxs] = append(c[cfBlockTxs], keyPair{hex.EncodeToString(packUint(h)), hex.EncodeToString(v), nil})
+		}
+		c[cfBlockFilter] = []keyPair{}
+		for k, v := range m.blockFilter {
+			c[cfBlockFilter] = append(c[cfBlockFilter], keyPair{k, v, nil})
+		}
+	case bchain.ChainEthereumType:
+		c[cfAddressContracts] = []keyPair{}
+		for k, acs := range m.addressContracts {
+			c[cfAddressContracts] = append(c[cfAddressContracts], keyPair{hex.EncodeToString([]byte(k)), hex.EncodeToString(packAddrContracts(acs)), nil})
+		}
+		c[cfContracts] = []keyPair{}
+		for k, ci := range m.contracts {
+			c[cfContracts] = append(c[cfContracts], keyPair{hex.EncodeToString([]byte(k)), hex.EncodeToString(packContractInfo(ci)), nil})
+		}
+		c[cfInternalData] = bytesKeyPairs(m.internalData)
+		c[cfBlockTxs] = heightKeyPairs(m.ethBlockTxs)
+		c[cfApprovals] = bytesKeyPairs(m.approvals)
+		c[cfBlockApprovals] = heightKeyPairs(m.blockApprovals)
+		c[cfContractLogs] = bytesKeyPairs(m.contractLogs)
+		c[cfBlockContractLogs] = heightKeyPairs(m.blockContractLogs)
+		c[cfAddressAliases] = []keyPair{}
+		for a, name := range m.addressAliases {
+			c[cfAddressAliases] = append(c[cfAddressAliases], keyPair{hex.EncodeToString([]byte(a)), hex.EncodeToString([]byte(name)), nil})
+		}
+		c[cfBlockInternalDataErrors] = []keyPair{}
+		for h, e := range m.internalDataErrors {
+			v, err := d.chainParser.PackTxid(e.Hash)
+			if err != nil {
+				return nil, err
+			}
+			v = append(v, e.Retries)
+			v = append(v, []byte(e.ErrorMessage)...)
+			c[cfBlockInternalDataErrors] = append(c[cfBlockInternalDataErrors], keyPair{hex.EncodeToString(packUint(h)), hex.EncodeToString(v), nil})
+		}
+	}
+	return c, nil
+}
+
+func (s *memoryStoreMirror) check() {
+	s.t.Helper()
+	columns, err := s.columns()
+	if err != nil {
+		s.t.Fatal(err)
+	}
+	for col, kp := range columns {
+		if err := checkColumn(s.d, col, kp); err != nil {
+			s.t.Errorf("MemoryStore differs from RocksDB: %v", err)
+		}
+	}
+	// the transactions of the addresses are returned in the same order
+	for a := range s.m.addresses {
+		got, want := s.addrDescTransactions(s.m, a), s.addrDescTransactions(s.d, a)
+		if !reflect.DeepEqual(got, want) {
+			s.t.Errorf("MemoryStore GetAddrDescTransactions(%x) = %v, RocksDB %v", a, got, want)
+		}
+	}
+}
+
+func (s *memoryStoreMirror) addrDescTransactions(store Store, addrDesc string) []txidIndex {
+	s.t.Helper()
+	r := []txidIndex{}
+	if err := store.GetAddrDescTransactions(bchain.AddressDescriptor(addrDesc), 0, ^uint32(0), func(txid string, height uint32, indexes []int32) error {
+		for _, index := range indexes {
+			r = append(r, txidIndex{txid, index})
+		}
+		return nil
+	}); err != nil {
+		s.t.Fatal(err)
+	}
+	return r
+}
+
+func TestMemoryStore_Copies(t *testing.T) {
+	parser := &testBitcoinParser{
+		BitcoinParser: bitcoinTestnetParser(),
+	}
+	m := NewMemoryStore(parser, false)
+	for _, block := range []*bchain.Block{dbtestdata.GetTestBitcoinTypeBlock1(parser), dbtestdata.GetTestBitcoinTypeBlock2(parser)} {
+		if err := m.ConnectBlock(block); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// the returned data are copies, modification does not change the store
+	ta, err := m.GetTxAddresses(dbtestdata.TxidB2T1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ta.Outputs[0].Spent = false
+	if ta, _ = m.GetTxAddresses(dbtestdata.TxidB2T1); !ta.Outputs[0].Spent {
+		t.Error("GetTxAddresses() returned data shared with the store")
+	}
+	ab, err := m.GetAddressBalance(dbtestdata.Addr5, AddressBalanceDetailUTXO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ab.Utxos[0].BtxID[0]++
+	if ab, _ = m.GetAddressBalance(dbtestdata.Addr5, AddressBalanceDetailUTXO); !reflect.DeepEqual(ab.Utxos[0].BtxID, hexToBytes(dbtestdata.TxidB2T3)) {
+		t.Error("GetAddressBalance() returned data shared with the store")
+	}
+}
+
+func TestMemoryStore_EthereumType(t *testing.T) {
+	parser := ethereumTestnetParser()
+	m := NewMemoryStore(parser, true)
+	if m.HasExtendedIndex() {
+		t.Error("HasExtendedIndex() = true for EthereumType")
+	}
+	ci := &bchain.ContractInfo{
+		Contract:       dbtestdata.EthAddrContract4a,
+		Type:           bchain.ERC20TokenType,
+		Name:           "Contract 74",
+		Symbol:         "S74",
+		Decimals:       12,
+		CreatedInBlock: 44,
+	}
+	if err := m.StoreContractInfo(ci); err != nil {
+		t.Fatal(err)
+	}
+	got, err := m.GetContractInfoForAddress(dbtestdata.EthAddrContract4a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, ci) {
+		t.Errorf("GetContractInfoForAddress() = %+v, want %+v", got, ci)
+	}
+	if err := m.StoreContractInfo(&bchain.ContractInfo{Contract: dbtestdata.EthAddrContract4a, DestructedInBlock: 55}); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ = m.GetContractInfoForAddress(dbtestdata.EthAddrContract4a); got.DestructedInBlock != 55 || got.CreatedInBlock != 44 {
+		t.Errorf("GetContractInfoForAddress() after destruction = %+v", got)
+	}
+}
@@ -0,0 +1,245 @@
+package db
+
+import (
+	"bytes"
+	"os"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+	"github.com/linxGnu/grocksdb"
+	"github.com/trezor/blockbook/bchain"
+	"github.com/trezor/blockbook/common"
+)
+
+// number of rows processed by a migration in one write batch
+var migrationBatchSize = 10000
+
+// Migration upgrades the data of the database from the version From to the version From+1.
+// The rows of the Columns are processed by the Row function in batches. The progress is written
+// together with each batch to the internal state, an interrupted migration continues from the last written batch.
+type Migration struct {
+	Name      string
+	From      uint32
+	ChainType bchain.ChainType
+	// Quick migrations run automatically on startup, the others must be started by the -migrate flag
+	Quick bool
+	// Start is called once before the rows are processed, it must be idempotent,
+	// it is called again if Blockbook stops before the start of the migration is recorded
+	Start func(d *RocksDB) error
+	// Columns are the names of the columns processed by the Row function, in the given order
+	Columns []string
+	// Row rewrites the row of the column, all changes must be done in the write batch
+	Row func(d *RocksDB, wb *grocksdb.WriteBatch, column string, key, value []byte) error
+}
+
+// migrations is the ordered registry of the database migrations,
+// there is one migration for each supported version upgrade of each chain type
+var migrations = []*Migration{
+	{
+		// columns transactions and fiatRates of BitcoinType coins are not compatible with v6 and must be cleared
+		Name:      "v6-clear-transactions-fiatrates",
+		From:      5,
+		ChainType: bchain.ChainBitcoinType,
+		Quick:     true,
+		Start: func(d *RocksDB) error {
+			if err := d.clearColumn(cfTransactions); err != nil {
+				return err
+			}
+			return d.clearColumn(cfFiatRates)
+		},
+	},
+}
+
+func (d *RocksDB) clearColumn(cf int) error {
+	return d.db.DeleteRangeCF(d.wo, d.cfh[cf], []byte{0}, []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff})
+}
+
+func columnIndex(name string) int {
+	for i, n := range cfNames {
+		if n == name {
+			return i
+		}
+	}
+	return -1
+}
+
+// migrationsFrom returns the migrations upgrading the database of the chain type from the version to the current dbVersion
+func migrationsFrom(version uint32, chainType bchain.ChainType) ([]*Migration, error) {
+	if version > dbVersion {
+		return nil, errors.Errorf("DB version %v is newer than the supported version %v", version, dbVersion)
+	}
+	var ms []*Migration
+	for v := version; v < dbVersion; v++ {
+		var found *Migration
+		for _, m := range migrations {
+			if m.From == v && m.ChainType == chainType {
+				found = m
+				break
+			}
+		}
+		if found == nil {
+			return nil, errors.Errorf("Migration from DB version %v to %v is not supported", v, v+1)
+		}
+		ms = append(ms, found)
+	}
+	return ms, nil
+}
+
+// QuickMigrations returns true if all the migrations run automatically on startup
+func QuickMigrations(ms []*Migration) bool {
+	for _, m := range ms {
+		if !m.Quick {
+			return false
+		}
+	}
+	return true
+}
+
+// PendingMigrations returns the migrations needed to upgrade the database to the current dbVersion
+func (d *RocksDB) PendingMigrations() ([]*Migration, error) {
+	if d.is == nil {
+		return nil, errors.New("Internal state not created")
+	}
+	version := uint32(dbVersion)
+	for _, c := range d.is.DbColumns {
+		if c.Version < version {
+			version = c.Version
+		}
+	}
+	return migrationsFrom(version, d.chainParser.GetChainType())
+}
+
+// Migrate runs the pending migrations in order, it can be interrupted and resumed by the next call
+func (d *RocksDB) Migrate(stop chan os.Signal) error {
+	ms, err := d.PendingMigrations()
+	if err != nil {
+		return err
+	}
+	for _, m := range ms {
+		if err = d.runMigration(m, stop); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *RocksDB) runMigration(m *Migration, stop chan os.Signal) error {
+	p := d.is.Migration
+	if p == nil || p.Name != m.Name {
+		if p != nil {
+			glog.Warningf("migration %v: discarding progress of unknown migration %v", m.Name, p.Name)
+		}
+		glog.Infof("migration %v: starting upgrade from v%d to v%d", m.Name, m.From, m.From+1)
+		if m.Start != nil {
+			if err := m.Start(d); err != nil {
+				return errors.Annotatef(err, "migration %v", m.Name)
+			}
+		}
+		p = &common.MigrationProgress{Name: m.Name}
+		d.is.Migration = p
+		if err := d.storeState(d.is); err != nil {
+			return err
+		}
+	} else {
+		glog.Infof("migration %v: resuming in column %v after %d rows", m.Name, p.Column, p.Rows)
+	}
+	start := 0
+	for i, column := range m.Columns {
+		if column == p.Column {
+			start = i
+			break
+		}
+	}
+	for i := start; i < len(m.Columns); i++ {
+		if p.Column != m.Columns[i] {
+			p.Column = m.Columns[i]
+			p.LastKey = nil
+		}
+		if err := d.migrateColumn(m, p, stop); err != nil {
+			return err
+		}
+	}
+	for i := range d.is.DbColumns {
+		if d.is.DbColumns[i].Version == m.From {
+			d.is.DbColumns[i].Version = m.From + 1
+		}
+	}
+	d.is.Migration = nil
+	if err := d.storeState(d.is); err != nil {
+		return err
+	}
+	glog.Infof("migration %v: finished, processed %d rows", m.Name, p.Rows)
+	return nil
+}
+
+// migrateColumn processes the rows of the column in batches, starting after the last processed key
+func (d *RocksDB) migrateColumn(m *Migration, p *common.MigrationProgress, stop chan os.Signal) error {
+	cf := columnIndex(p.Column)
+	if cf < 0 {
+		return errors.Errorf("migration %v: unknown column %v", m.Name, p.Column)
+	}
+	// do not use cache
+	ro := grocksdb.NewDefaultReadOptions()
+	ro.SetFillCache(false)
+	defer ro.Destroy()
+	for batch := 1; ; batch++ {
+		select {
+		case <-stop:
+			return ErrOperationInterrupted
+		default:
+		}
+		done, err := d.migrateBatch(m, p, cf, ro)
+		if err != nil {
+			return errors.Annotatef(err, "migration %v, column %v", m.Name, p.Column)
+		}
+		if done {
+			return nil
+		}
+		if batch%100 == 0 {
+			glog.Infof("migration %v: column %v, processed %d rows", m.Name, p.Column, p.Rows)
+		}
+	}
+}
+
+// migrateBatch processes one batch of rows and writes it together with the progress, returns true if the column is finished
+func (d *RocksDB) migrateBatch(m *Migration, p *common.MigrationProgress, cf int, ro *grocksdb.ReadOptions) (bool, error) {
+	wb := grocksdb.NewWriteBatch()
+	defer wb.Destroy()
+	it := d.db.NewIteratorCF(ro, d.cfh[cf])
+	defer it.Close()
+	if p.LastKey == nil {
+		it.SeekToFirst()
+	} else {
+		it.Seek(p.LastKey)
+		if it.Valid() && bytes.Equal(it.Key().Data(), p.LastKey) {
+			it.Next()
+		}
+	}
+	count := 0
+	var lastKey []byte
+	for ; it.Valid() && count < migrationBatchSize; it.Next() {
+		key := it.Key().Data()
+		if err := m.Row(d, wb, p.Column, key, it.Value().Data()); err != nil {
+			return false, errors.Annotatef(err, "key %x", key)
+		}
+		lastKey = append(lastKey[:0], key...)
+		count++
+	}
+	if err := it.Err(); err != nil {
+		return false, err
+	}
+	if count > 0 {
+		p.LastKey = lastKey
+		p.Rows += int64(count)
+	}
+	// store the progress atomically with the changes
+	buf, err := d.is.Pack()
+	if err != nil {
+		return false, err
+	}
+	wb.PutCF(d.cfh[cfDefault], []byte(internalStateKey), buf)
+	if err = d.WriteBatch(wb); err != nil {
+		return false, err
+	}
+	return count < migrationBatchSize, nil
+}
@@ -0,0 +1,134 @@
+//go:build unittest
+
+package db
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/juju/errors"
+	"github.com/linxGnu/grocksdb"
+	"github.com/trezor/blockbook/bchain"
+	"github.com/trezor/blockbook/common"
+	"github.com/trezor/blockbook/tests/dbtestdata"
+)
+
+func TestRocksDB_Migrate(t *testing.T) {
+	d := setupRocksDB(t, &testBitcoinParser{
+		BitcoinParser: bitcoinTestnetParser(),
+	})
+	defer closeAndDestroyRocksDB(t, d)
+
+	if err := d.ConnectBlock(dbtestdata.GetTestBitcoinTypeBlock1(d.chainParser)); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.ConnectBlock(dbtestdata.GetTestBitcoinTypeBlock2(d.chainParser)); err != nil {
+		t.Fatal(err)
+	}
+
+	var processed [][]byte
+	fail := true
+	m := &Migration{
+		Name:      "test",
+		From:      dbVersion - 1,
+		ChainType: bchain.ChainBitcoinType,
+		Columns:   []string{"height"},
+		Row: func(d *RocksDB, wb *grocksdb.WriteBatch, column string, key, value []byte) error {
+			if fail && len(processed) == 1 {
+				return errors.New("test failure")
+			}
+			processed = append(processed, append([]byte{}, key...))
+			wb.PutCF(d.cfh[cfHeight], key, value)
+			return nil
+		},
+	}
+	defer func(ms []*Migration, size int) {
+		migrations = ms
+		migrationBatchSize = size
+	}(migrations, migrationBatchSize)
+	migrations = []*Migration{m}
+	migrationBatchSize = 1
+
+	if _, err := migrationsFrom(dbVersion-2, bchain.ChainBitcoinType); err == nil {
+		t.Error("migrationsFrom(dbVersion-2) expected error")
+	}
+	if _, err := migrationsFrom(dbVersion+1, bchain.ChainBitcoinType); err == nil {
+		t.Error("migrationsFrom(dbVersion+1) expected error")
+	}
+	if _, err := migrationsFrom(dbVersion-1, bchain.ChainEthereumType); err == nil {
+		t.Error("migrationsFrom(dbVersion-1) of ethereum type expected error")
+	}
+
+	ms, err := d.PendingMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ms) != 0 {
+		t.Fatalf("PendingMigrations() = %v, want none", ms)
+	}
+	for i := range d.is.DbColumns {
+		d.is.DbColumns[i].Version = dbVersion - 1
+	}
+	if err = d.StoreInternalState(d.is); err != nil {
+		t.Fatal(err)
+	}
+	ms, err = d.PendingMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(ms, []*Migration{m}) {
+		t.Fatalf("PendingMigrations() = %v, want %v", ms, []*Migration{m})
+	}
+	if QuickMigrations(ms) {
+		t.Error("QuickMigrations() = true, want false")
+	}
+
+	// the migration fails in the second batch, the progress of the first batch is stored
+	if err = d.Migrate(nil); err == nil {
+		t.Fatal("Migrate() expected error")
+	}
+	is, err := d.LoadInternalState(&common.Config{CoinName: "coin-unittest"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantProgress := &common.MigrationProgress{Name: "test", Column: "height", LastKey: packUint(225493), Rows: 1}
+	if !reflect.DeepEqual(is.Migration, wantProgress) {
+		t.Errorf("stored Migration = %+v, want %+v", is.Migration, wantProgress)
+	}
+	for _, c := range is.DbColumns {
+		if c.Version != dbVersion-1 {
+			t.Errorf("column %v version %v, want %v", c.Name, c.Version, dbVersion-1)
+		}
+	}
+	d.SetInternalState(is)
+
+	// the migration is resumed after the last stored batch
+	fail = false
+	if err = d.Migrate(nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(processed) != 2 || !bytes.Equal(processed[0], packUint(225493)) || !bytes.Equal(processed[1], packUint(225494)) {
+		t.Errorf("processed keys %x", processed)
+	}
+	is, err = d.LoadInternalState(&common.Config{CoinName: "coin-unittest"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if is.Migration != nil {
+		t.Errorf("stored Migration = %+v, want nil", is.Migration)
+	}
+	for _, c := range is.DbColumns {
+		if c.Version != dbVersion {
+			t.Errorf("column %v version %v, want %v", c.Name, c.Version, dbVersion)
+		}
+	}
+	d.SetInternalState(is)
+	ms, err = d.PendingMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ms) != 0 {
+		t.Errorf("PendingMigrations() after Migrate = %v, want none", ms)
+	}
+}
@@ -0,0 +1,281 @@
+package db
+
+import (
+	"os"
+	"sync"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+	"github.com/linxGnu/grocksdb"
+	"github.com/trezor/blockbook/bchain"
+)
+
+// number of blocks pruned in one write batch, variable so that it can be changed by the tests
+var pruneBlocksBatchSize = 10
+
+// pruneMux serializes the pruning with the disconnection of the blocks
+var pruneMux sync.Mutex
+
+// SetPruneDepth enables the pruning of the history of the blocks older than depth,
+// it must be called before LoadInternalState
+func (d *RocksDB) SetPruneDepth(depth uint32) error {
+	if d.chainParser.GetChainType() != bchain.ChainBitcoinType {
+		return errors.New("Pruning is supported only by BitcoinType coins")
+	}
+	if keep := d.chainParser.KeepBlockAddresses(); depth < uint32(keep) {
+		return errors.Errorf("Prune depth %v is lower than the number of blocks kept for the disconnection of blocks %v", depth, keep)
+	}
+	d.pruneDepth = depth
+	return nil
+}
+
+// PruneDepth returns the number of the last blocks with the complete history, 0 if the pruning is disabled
+func (d *RocksDB) PruneDepth() uint32 {
+	return d.pruneDepth
+}
+
+// Prune removes the addresses and txAddresses data of the blocks older than the prune depth,
+// the address balances are not changed. The blocks are pruned in batches, the pruned height is stored
+// atomically with each batch, so that the interrupted pruning continues by the next call.
+func (d *RocksDB) Prune(chain bchain.BlockChain, stop chan os.Signal) error {
+	if d.pruneDepth == 0 {
+		return nil
+	}
+	for {
+		select {
+		case <-stop:
+			return ErrOperationInterrupted
+		default:
+		}
+		done, err := d.pruneBatch(chain)
+		if err != nil || done {
+			return err
+		}
+	}
+}
+
+// pruneBatch prunes the next batch of blocks, it returns true if there is nothing more to prune
+func (d *RocksDB) pruneBatch(chain bchain.BlockChain) (bool, error) {
+	pruneMux.Lock()
+	defer pruneMux.Unlock()
+	bestHeight, _, err := d.GetBestBlock()
+	if err != nil {
+		return false, err
+	}
+	if bestHeight < d.pruneDepth {
+		return true, nil
+	}
+	target := bestHeight - d.pruneDepth
+	prunedHeight := d.is.GetPrunedHeight()
+	lower := prunedHeight
+	if lower == 0 {
+		// the index does not have to start at the genesis block
+		lower, _ = d.firstBlockHeight()
+	}
+	if lower > target {
+		return true, nil
+	}
+	higher := target
+	if target-lower >= uint32(pruneBlocksBatchSize) {
+		higher = lower + uint32(pruneBlocksBatchSize) - 1
+	}
+	wb := grocksdb.NewWriteBatch()
+	defer wb.Destroy()
+	p := &pruner{
+		d:       d,
+		chain:   chain,
+		wb:      wb,
+		deleted: make(map[string]struct{}),
+	}
+	for height := lower; height <= higher; height++ {
+		if err := p.pruneBlock(height); err != nil {
+			return false, errors.Annotatef(err, "prune block %v", height)
+		}
+	}
+	// store the pruned height atomically with the changes
+	d.is.SetPrunedHeight(higher + 1)
+	buf, err := d.is.Pack()
+	if err == nil {
+		wb.PutCF(d.cfh[cfDefault], []byte(internalStateKey), buf)
+		err = d.WriteBatch(wb)
+	}
+	if err != nil {
+		d.is.SetPrunedHeight(prunedHeight)
+		return false, err
+	}
+	glog.Infof("prune: pruned blocks %d-%d, removed %d txAddresses", lower, higher, len(p.deleted))
+	return higher == target, nil
+}
+
+// pruner holds the state of one batch of pruning
+type pruner struct {
+	d     *RocksDB
+	chain bchain.BlockChain
+	wb    *grocksdb.WriteBatch
+	// txAddresses removed in the batch
+	deleted map[string]struct{}
+	// block txs read by the check of the spent outputs
+	blocks map[uint32][]blockTxs
+}
+
+// pruneBlock removes the addresses of the block and the txAddresses of the transactions of the block and of the
+// transactions spent by the block, which have all outputs spent by the pruned blocks
+func (p *pruner) pruneBlock(height uint32) error {
+	d := p.d
+	bt, err := d.getBlockTxs(height)
+	if err != nil {
+		return err
+	}
+	if len(bt) == 0 {
+		// the block txs of the blocks connected by the initial synchronization are not stored
+		if bt, err = p.blockTxsFromBackend(height); err != nil {
+			return err
+		}
+	}
+	p.blocks = make(map[uint32][]blockTxs)
+	addrDescs := make(map[string]struct{})
+	addAddrDesc := func(addrDesc bchain.AddressDescriptor) {
+		if len(addrDesc) > 0 && d.chainParser.IsAddrDescIndexable(addrDesc) {
+			addrDescs[string(addrDesc)] = struct{}{}
+		}
+	}
+	candidates := make([][]byte, 0, len(bt))
+	for i := range bt {
+		ta, err := d.getTxAddresses(bt[i].btxID)
+		if err != nil {
+			return err
+		}
+		if ta != nil {
+			for j := range ta.Inputs {
+				addAddrDesc(ta.Inputs[j].AddrDesc)
+			}
+			for j := range ta.Outputs {
+				addAddrDesc(ta.Outputs[j].AddrDesc)
+			}
+		}
+		candidates = append(candidates, bt[i].btxID)
+		for j := range bt[i].inputs {
+			candidates = append(candidates, bt[i].inputs[j].btxID)
+		}
+	}
+	for a := range addrDescs {
+		p.wb.DeleteCF(d.cfh[cfAddresses], packAddressKey(bchain.AddressDescriptor(a), height))
+	}
+	for _, btxID := range candidates {
+		if _, found := p.deleted[string(btxID)]; found {
+			continue
+		}
+		prunable, err := p.isTxPrunable(btxID, height)
+		if err != nil {
+			return err
+		}
+		if prunable {
+			p.wb.DeleteCF(d.cfh[cfTxAddresses], btxID)
+			p.deleted[string(btxID)] = struct{}{}
+		}
+	}
+	p.wb.DeleteCF(d.cfh[cfBlockTxs], packUint(height))
+	return nil
+}
+
+// isTxPrunable returns true if all indexed outputs of the tx are spent by the blocks up to the pruned height,
+// the txs spent by the later blocks are necessary for their disconnection
+func (p *pruner) isTxPrunable(btxID []byte, height uint32) (bool, error) {
+	ta, err := p.d.getTxAddresses(btxID)
+	if err != nil || ta == nil {
+		return false, err
+	}
+	for i := range ta.Outputs {
+		o := &ta.Outputs[i]
+		if len(o.AddrDesc) == 0 || !p.d.chainParser.IsAddrDescIndexable(o.AddrDesc) {
+			continue
+		}
+		if !o.Spent {
+			return false, nil
+		}
+		spentLater, err := p.isSpentAfter(btxID, int32(i), o, height)
+		if err != nil || spentLater {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// isSpentAfter returns true if the output is spent in a block higher than height
+func (p *pruner) isSpentAfter(btxID []byte, vout int32, o *TxOutput, height uint32) (bool, error) {
+	if p.d.extendedIndex {
+		return o.SpentHeight > height, nil
+	}
+	// find the spending input in the block txs of the blocks in which the address of the output appears as input
+	spent := false
+	err := p.d.GetAddrDescTransactions(o.AddrDesc, height+1, ^uint32(0), func(txid string, h uint32, indexes []int32) error {
+		bt, err := p.getBlockTxs(h)
+		if err != nil {
+			return err
+		}
+		spendingBtxID, err := p.d.chainParser.PackTxid(txid)
+		if err != nil {
+			return err
+		}
+		for i := range bt {
+			if string(bt[i].btxID) != string(spendingBtxID) {
+				continue
+			}
+			for _, index := range indexes {
+				if index >= 0 || int(^index) >= len(bt[i].inputs) {
+					continue
+				}
+				in := &bt[i].inputs[^index]
+				if in.index == vout && string(in.btxID) == string(btxID) {
+					spent = true
+					return &StopIteration{}
+				}
+			}
+		}
+		return nil
+	})
+	return spent, err
+}
+
+func (p *pruner) getBlockTxs(height uint32) ([]blockTxs, error) {
+	bt, found := p.blocks[height]
+	if !found {
+		var err error
+		if bt, err = p.d.getBlockTxs(height); err != nil {
+			return nil, err
+		}
+		p.blocks[height] = bt
+	}
+	return bt, nil
+}
+
+// blockTxsFromBackend gets the txids and inputs of the block from the backend
+func (p *pruner) blockTxsFromBackend(height uint32) ([]blockTxs, error) {
+	if p.chain == nil {
+		return nil, errors.Errorf("Missing block txs of block %v", height)
+	}
+	block, err := p.d.getIndexedBlock(p.chain, height)
+	if err != nil {
+		return nil, err
+	}
+	pl := p.d.chainParser.PackedTxidLen()
+	bt := make([]blockTxs, len(block.Txs))
+	for i := range block.Txs {
+		tx := &block.Txs[i]
+		if bt[i].btxID, err = p.d.chainParser.PackTxid(tx.Txid); err != nil {
+			return nil, err
+		}
+		bt[i].inputs = make([]outpoint, len(tx.Vin))
+		for j := range tx.Vin {
+			btxID, err := p.d.chainParser.PackTxid(tx.Vin[j].Txid)
+			if err != nil {
+				if err != bchain.ErrTxidMissing {
+					return nil, err
+				}
+				btxID = make([]byte, pl)
+			}
+			bt[i].inputs[j] = outpoint{btxID: btxID, index: int32(tx.Vin[j].Vout)}
+		}
+	}
+	return bt, nil
+}
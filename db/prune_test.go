@@ -0,0 +1,145 @@
+//go:build unittest
+
+package db
+
+import (
+	"math/big"
+	"os"
+	"testing"
+
+	"github.com/trezor/blockbook/bchain"
+	"github.com/trezor/blockbook/tests/dbtestdata"
+)
+
+func TestRocksDB_Prune_BitcoinType(t *testing.T) {
+	defer func(s int) { pruneBlocksBatchSize = s }(pruneBlocksBatchSize)
+	pruneBlocksBatchSize = 1
+	parser := &testBitcoinParser{
+		BitcoinParser: bitcoinTestnetParser(),
+	}
+	d := setupRocksDB(t, parser)
+	defer closeAndDestroyRocksDB(t, d)
+	if err := d.SetPruneDepth(1); err != nil {
+		t.Fatal(err)
+	}
+	block3 := &bchain.Block{
+		BlockHeader: bchain.BlockHeader{
+			Height: 225495,
+			Hash:   "000000001b5d0fc1b3a2b5b1e4c2e3bd0d5e3f3b2c6a4b1d8e9f0a1b2c3d4e5f",
+			Time:   1521595700,
+		},
+		Txs: []bchain.Tx{
+			{
+				Txid: "2a3a4c4a3e6d1a9a4b5b4a0f8c2b9e0c5d6f7e8a9b0c1d2e3f4a5b6c7d8e9f0a",
+				Vin:  []bchain.Vin{{Coinbase: "03c01e15"}},
+				Vout: []bchain.Vout{
+					{
+						N:            0,
+						ScriptPubKey: bchain.ScriptPubKey{Hex: dbtestdata.AddressToPubKeyHex(dbtestdata.Addr1, parser)},
+						ValueSat:     *big.NewInt(1000),
+					},
+				},
+			},
+		},
+	}
+	for _, b := range []*bchain.Block{dbtestdata.GetTestBitcoinTypeBlock1(parser), dbtestdata.GetTestBitcoinTypeBlock2(parser)} {
+		if err := d.ConnectBlock(b); err != nil {
+			t.Fatal(err)
+		}
+	}
+	addressTxs := func(address string) int {
+		addrDesc, err := parser.GetAddrDescFromAddress(address)
+		if err != nil {
+			t.Fatal(err)
+		}
+		count := 0
+		if err := d.GetAddrDescTransactions(addrDesc, 0, ^uint32(0), func(txid string, height uint32, indexes []int32) error {
+			count++
+			return nil
+		}); err != nil {
+			t.Fatal(err)
+		}
+		return count
+	}
+	hasTxAddresses := func(txid string) bool {
+		ta, err := d.GetTxAddresses(txid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ta != nil
+	}
+
+	// the first block is pruned, its txs are spent by the second block, which can be still disconnected
+	if err := d.Prune(nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := d.is.GetPrunedHeight(); got != 225494 {
+		t.Fatalf("PrunedHeight = %v, want 225494", got)
+	}
+	if got := addressTxs(dbtestdata.Addr1); got != 0 {
+		t.Errorf("Addr1 indexed txs = %v, want 0", got)
+	}
+	if got := addressTxs(dbtestdata.Addr3); got != 1 {
+		t.Errorf("Addr3 indexed txs = %v, want 1", got)
+	}
+	ab, err := d.GetAddressBalance(dbtestdata.Addr1, AddressBalanceDetailNoUTXO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ab == nil || ab.Txs != 1 || ab.BalanceSat.Cmp(dbtestdata.SatB1T1A1) != 0 {
+		t.Errorf("Addr1 balance %+v", ab)
+	}
+	for _, txid := range []string{dbtestdata.TxidB1T1, dbtestdata.TxidB1T2} {
+		if !hasTxAddresses(txid) {
+			t.Errorf("txAddresses of %v removed", txid)
+		}
+	}
+	if err := d.DisconnectBlockRangeBitcoinType(225493, 225494); err == nil {
+		t.Error("DisconnectBlockRangeBitcoinType of a pruned block expected error")
+	}
+
+	// the interrupted pruning does not change the index
+	if err := d.ConnectBlock(block3); err != nil {
+		t.Fatal(err)
+	}
+	stop := make(chan os.Signal, 1)
+	stop <- os.Interrupt
+	if err := d.Prune(nil, stop); err != ErrOperationInterrupted {
+		t.Fatalf("Prune() = %v, want ErrOperationInterrupted", err)
+	}
+	if got := d.is.GetPrunedHeight(); got != 225494 {
+		t.Fatalf("PrunedHeight = %v, want 225494", got)
+	}
+
+	// the second block is pruned, the fully spent txs are removed
+	if err := d.Prune(nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	if got := d.is.GetPrunedHeight(); got != 225495 {
+		t.Fatalf("PrunedHeight = %v, want 225495", got)
+	}
+	if got := addressTxs(dbtestdata.Addr3); got != 0 {
+		t.Errorf("Addr3 indexed txs = %v, want 0", got)
+	}
+	if got := addressTxs(dbtestdata.Addr1); got != 1 {
+		t.Errorf("Addr1 indexed txs = %v, want 1", got)
+	}
+	if hasTxAddresses(dbtestdata.TxidB1T2) {
+		t.Errorf("txAddresses of spent tx %v not removed", dbtestdata.TxidB1T2)
+	}
+	for _, txid := range []string{dbtestdata.TxidB1T1, dbtestdata.TxidB2T1, dbtestdata.TxidB2T2} {
+		if !hasTxAddresses(txid) {
+			t.Errorf("txAddresses of tx with unspent outputs %v removed", txid)
+		}
+	}
+	if err := d.DisconnectBlockRangeBitcoinType(225495, 225495); err != nil {
+		t.Fatal(err)
+	}
+	ab, err = d.GetAddressBalance(dbtestdata.Addr1, AddressBalanceDetailNoUTXO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ab == nil || ab.Txs != 1 || ab.BalanceSat.Cmp(dbtestdata.SatB1T1A1) != 0 {
+		t.Errorf("Addr1 balance after disconnect %+v", ab)
+	}
+}
@@ -0,0 +1,499 @@
+package db
+
+import (
+	"os"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+	"github.com/linxGnu/grocksdb"
+	"github.com/trezor/blockbook/bchain"
+	"github.com/trezor/blockbook/common"
+)
+
+// targets of the column rebuild
+const (
+	RebuildBlockFilter      = "blockFilter"
+	RebuildExtendedIndex    = "extendedIndex"
+	RebuildAddressContracts = "addressContracts"
+	RebuildAddressAliases   = "addressAliases"
+	RebuildApprovals        = "approvals"
+	RebuildContractLogs     = "contractLogs"
+)
+
+// number of blocks processed by a rebuild in one write batch
+var rebuildBlocksBatchSize = 100
+
+// rebuildTarget regenerates a derived column. The targets with the column set rewrite the rows of the column
+// in the same way as the migrations, the other targets process the indexed blocks in batches of batchBlocks heights.
+// The progress is stored together with each batch in the internal state, an interrupted rebuild continues from the last written batch.
+type rebuildTarget struct {
+	chainType bchain.ChainType
+	// start is called before the first batch, it must be idempotent
+	start func(d *RocksDB) error
+	// column and row are used by the targets rewriting the rows of the column
+	column string
+	row    func(d *RocksDB, wb *grocksdb.WriteBatch, column string, key, value []byte) error
+	// blocks processes the blocks in the range lower-higher, all changes must be done in the write batch
+	blocks func(d *RocksDB, chain bchain.BlockChain, wb *grocksdb.WriteBatch, lower, higher uint32) error
+	// number of blocks in a batch, if zero, rebuildBlocksBatchSize is used
+	batchBlocks uint32
+	// finish is called after the last batch
+	finish func(d *RocksDB) error
+}
+
+var rebuildTargets = map[string]*rebuildTarget{
+	RebuildBlockFilter: {
+		chainType: bchain.ChainBitcoinType,
+		start: func(d *RocksDB) error {
+			return d.clearColumn(cfBlockFilter)
+		},
+		blocks: rebuildBlockFilters,
+	},
+	RebuildExtendedIndex: {
+		chainType: bchain.ChainBitcoinType,
+		blocks:    rebuildExtendedIndex,
+	},
+	RebuildAddressContracts: {
+		chainType: bchain.ChainEthereumType,
+		column:    "addressContracts",
+		row:       rebuildAddressContractsRow,
+		finish: func(d *RocksDB) error {
+			d.is.SortedAddressContracts = true
+			return nil
+		},
+	},
+	RebuildAddressAliases: {
+		chainType: bchain.ChainEthereumType,
+		start: func(d *RocksDB) error {
+			cachedAddressAliasRecordsMux.Lock()
+			cachedAddressAliasRecords = make(map[string]string)
+			cachedAddressAliasRecordsMux.Unlock()
+			return d.clearColumn(cfAddressAliases)
+		},
+		blocks: func(d *RocksDB, chain bchain.BlockChain, wb *grocksdb.WriteBatch, lower, higher uint32) error {
+			records, err := chain.EthereumTypeGetAddressAliasRecords(lower, higher)
+			if err != nil {
+				return err
+			}
+			return d.storeAddressAliasRecords(wb, records)
+		},
+		// the records are fetched by one request for the whole batch
+		batchBlocks: 1000,
+		finish: func(d *RocksDB) error {
+			count, err := d.InitAddressAliasRecords()
+			if err == nil {
+				glog.Info("rebuild ", RebuildAddressAliases, ": loaded ", count, " address alias records")
+			}
+			return err
+		},
+	},
+	RebuildApprovals: {
+		chainType: bchain.ChainEthereumType,
+		start: func(d *RocksDB) error {
+			if err := d.clearColumn(cfApprovals); err != nil {
+				return err
+			}
+			return d.clearColumn(cfBlockApprovals)
+		},
+		blocks: rebuildApprovals,
+	},
+	RebuildContractLogs: {
+		chainType: bchain.ChainEthereumType,
+		start: func(d *RocksDB) error {
+			if err := d.clearColumn(cfContractLogs); err != nil {
+				return err
+			}
+			return d.clearColumn(cfBlockContractLogs)
+		},
+		blocks: rebuildContractLogs,
+	},
+}
+
+// SetRebuild sets the column to be rebuilt, it must be called before LoadInternalState,
+// which then accepts the changed settings related to the rebuilt column
+func (d *RocksDB) SetRebuild(target string) error {
+	t, found := rebuildTargets[target]
+	if !found {
+		return errors.Errorf("Unknown rebuild target %v, supported targets are %v, %v, %v, %v, %v and %v", target,
+			RebuildBlockFilter, RebuildExtendedIndex, RebuildAddressContracts, RebuildAddressAliases, RebuildApprovals, RebuildContractLogs)
+	}
+	if t.chainType != d.chainParser.GetChainType() {
+		return errors.Errorf("Rebuild target %v is not supported by the coin", target)
+	}
+	if target == RebuildExtendedIndex && !d.extendedIndex {
+		return errors.Errorf("Rebuild target %v requires the -extendedindex flag", target)
+	}
+	if target == RebuildAddressAliases && !d.chainParser.UseAddressAliases() {
+		return errors.Errorf("Rebuild target %v requires address aliases enabled in the coin configuration", target)
+	}
+	if target == RebuildContractLogs && !d.chainParser.UseContractLogs() {
+		return errors.Errorf("Rebuild target %v requires contract logs configured in the coin configuration", target)
+	}
+	d.rebuild = target
+	return nil
+}
+
+// Rebuild regenerates the column set by SetRebuild, it can be interrupted and resumed by the next call
+func (d *RocksDB) Rebuild(chain bchain.BlockChain, stop chan os.Signal) error {
+	t := rebuildTargets[d.rebuild]
+	if t == nil {
+		return errors.New("Rebuild target not set")
+	}
+	p := d.is.Rebuild
+	if p == nil || p.Name != d.rebuild {
+		if p != nil {
+			return errors.Errorf("Rebuild of %v is not finished, it must be finished first", p.Name)
+		}
+		glog.Info("rebuild ", d.rebuild, ": starting")
+		if t.start != nil {
+			if err := t.start(d); err != nil {
+				return errors.Annotatef(err, "rebuild %v", d.rebuild)
+			}
+		}
+		p = &common.MigrationProgress{Name: d.rebuild, Column: t.column}
+		d.is.Rebuild = p
+		if err := d.storeState(d.is); err != nil {
+			return err
+		}
+	} else {
+		glog.Info("rebuild ", d.rebuild, ": resuming after ", p.Rows, " rows")
+	}
+	var err error
+	if t.column != "" {
+		err = d.rebuildColumn(t, p, stop)
+	} else {
+		err = d.rebuildBlocks(t, p, chain, stop)
+	}
+	if err != nil {
+		return err
+	}
+	if t.finish != nil {
+		if err = t.finish(d); err != nil {
+			return errors.Annotatef(err, "rebuild %v", d.rebuild)
+		}
+	}
+	d.is.Rebuild = nil
+	if err = d.storeState(d.is); err != nil {
+		return err
+	}
+	glog.Info("rebuild ", p.Name, ": finished, processed ", p.Rows, " rows")
+	return nil
+}
+
+// rebuildColumn rewrites the rows of the column in batches using the migration batches
+func (d *RocksDB) rebuildColumn(t *rebuildTarget, p *common.MigrationProgress, stop chan os.Signal) error {
+	m := &Migration{Name: "rebuild-" + p.Name, Columns: []string{t.column}, Row: t.row}
+	cf := columnIndex(t.column)
+	// do not use cache
+	ro := grocksdb.NewDefaultReadOptions()
+	ro.SetFillCache(false)
+	defer ro.Destroy()
+	for batch := 1; ; batch++ {
+		select {
+		case <-stop:
+			return ErrOperationInterrupted
+		default:
+		}
+		done, err := d.migrateBatch(m, p, cf, ro)
+		if err != nil {
+			return errors.Annotatef(err, "rebuild %v", p.Name)
+		}
+		if done {
+			return nil
+		}
+		if batch%100 == 0 {
+			glog.Info("rebuild ", p.Name, ": processed ", p.Rows, " rows")
+		}
+	}
+}
+
+// firstBlockHeight returns the lowest height stored in the height column
+func (d *RocksDB) firstBlockHeight() (uint32, bool) {
+	it := d.db.NewIteratorCF(d.ro, d.cfh[cfHeight])
+	defer it.Close()
+	it.SeekToFirst()
+	if !it.Valid() {
+		return 0, false
+	}
+	return unpackUint(it.Key().Data()), true
+}
+
+// rebuildBlocks processes the indexed blocks in batches, the last processed height is stored in the progress as LastKey
+func (d *RocksDB) rebuildBlocks(t *rebuildTarget, p *common.MigrationProgress, chain bchain.BlockChain, stop chan os.Signal) error {
+	bestHeight, _, err := d.GetBestBlock()
+	if err != nil {
+		return err
+	}
+	var lower uint32
+	if p.LastKey != nil {
+		lower = unpackUint(p.LastKey) + 1
+	} else {
+		var found bool
+		if lower, found = d.firstBlockHeight(); !found {
+			return nil
+		}
+	}
+	batchBlocks := t.batchBlocks
+	if batchBlocks == 0 {
+		batchBlocks = uint32(rebuildBlocksBatchSize)
+	}
+	first := lower
+	for batch := 1; lower <= bestHeight; batch++ {
+		select {
+		case <-stop:
+			return ErrOperationInterrupted
+		default:
+		}
+		higher := bestHeight
+		if bestHeight-lower >= batchBlocks {
+			higher = lower + batchBlocks - 1
+		}
+		if err := d.rebuildBlocksBatch(t, p, chain, lower, higher); err != nil {
+			return errors.Annotatef(err, "rebuild %v, blocks %v-%v", p.Name, lower, higher)
+		}
+		if batch%10 == 0 || higher == bestHeight {
+			glog.Infof("rebuild %v: processed blocks up to %d, %.2f%% done", p.Name, higher, float64(higher-first+1)*100/float64(bestHeight-first+1))
+		}
+		lower = higher + 1
+	}
+	return nil
+}
+
+func (d *RocksDB) rebuildBlocksBatch(t *rebuildTarget, p *common.MigrationProgress, chain bchain.BlockChain, lower, higher uint32) error {
+	wb := grocksdb.NewWriteBatch()
+	defer wb.Destroy()
+	if err := t.blocks(d, chain, wb, lower, higher); err != nil {
+		return err
+	}
+	p.LastKey = packUint(higher)
+	p.Rows += int64(higher - lower + 1)
+	// store the progress atomically with the changes
+	buf, err := d.is.Pack()
+	if err != nil {
+		return err
+	}
+	wb.PutCF(d.cfh[cfDefault], []byte(internalStateKey), buf)
+	return d.WriteBatch(wb)
+}
+
+// getIndexedBlock gets from the backend the block indexed at the height
+func (d *RocksDB) getIndexedBlock(chain bchain.BlockChain, height uint32) (*bchain.Block, error) {
+	hash, err := d.GetBlockHash(height)
+	if err != nil {
+		return nil, err
+	}
+	if hash == "" {
+		return nil, errors.Errorf("Block %v is not indexed", height)
+	}
+	return chain.GetBlock(hash, height)
+}
+
+// rebuildBlockFilters computes the block filters from the outputs of the block and the input addresses stored in txAddresses
+func rebuildBlockFilters(d *RocksDB, chain bchain.BlockChain, wb *grocksdb.WriteBatch, lower, higher uint32) error {
+	if d.is.BlockGolombFilterP == 0 {
+		// the filters are disabled, the column was cleared by start
+		return nil
+	}
+	for height := lower; height <= higher; height++ {
+		block, err := d.getIndexedBlock(chain, height)
+		if err != nil {
+			return err
+		}
+		gf, err := bchain.NewGolombFilter(d.is.BlockGolombFilterP, d.is.BlockFilterScripts, block.BlockHeader.Hash, d.is.BlockFilterUseZeroedKey)
+		if err != nil {
+			return err
+		}
+		// add the data in the same order as ConnectBlock, first the outputs of all txs, then the inputs
+		for i := range block.Txs {
+			tx := &block.Txs[i]
+			for j := range tx.Vout {
+				addrDesc, err := d.chainParser.GetAddrDescFromVout(&tx.Vout[j])
+				if err != nil || len(addrDesc) == 0 || len(addrDesc) > maxAddrDescLen {
+					continue
+				}
+				gf.AddAddrDesc(addrDesc, tx)
+			}
+		}
+		for i := range block.Txs {
+			tx := &block.Txs[i]
+			ta, err := d.GetTxAddresses(tx.Txid)
+			if err != nil {
+				return err
+			}
+			if ta == nil {
+				glog.Warning("rebuild ", RebuildBlockFilter, ": height ", height, ", tx ", tx.Txid, " not found in txAddresses")
+				continue
+			}
+			for j := range ta.Inputs {
+				gf.AddAddrDesc(ta.Inputs[j].AddrDesc, tx)
+			}
+		}
+		if err := d.storeBlockFilter(wb, block.BlockHeader.Hash, gf.Compute()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// rebuildExtendedIndex converts the txAddresses of the transactions in the blocks to the format with the extended index
+// and fills in the spending data of the outputs spent in the blocks. The txAddresses of the transactions in the already
+// processed blocks are stored in the extended format, the others are read in the original format.
+func rebuildExtendedIndex(d *RocksDB, chain bchain.BlockChain, wb *grocksdb.WriteBatch, lower, higher uint32) error {
+	original := *d
+	original.extendedIndex = false
+	txAddressesMap := make(map[string]*TxAddresses)
+	getTxAddresses := func(btxID []byte) (*TxAddresses, error) {
+		if ta, found := txAddressesMap[string(btxID)]; found {
+			return ta, nil
+		}
+		val, err := d.db.GetCF(d.ro, d.cfh[cfTxAddresses], btxID)
+		if err != nil {
+			return nil, err
+		}
+		defer val.Free()
+		buf := val.Data()
+		if len(buf) < 3 {
+			return nil, nil
+		}
+		var ta *TxAddresses
+		if height, _ := unpackVaruint(buf); uint32(height) < lower {
+			ta, err = d.unpackTxAddresses(buf)
+		} else {
+			ta, err = original.unpackTxAddresses(buf)
+		}
+		if err != nil {
+			return nil, err
+		}
+		txAddressesMap[string(btxID)] = ta
+		return ta, nil
+	}
+	for height := lower; height <= higher; height++ {
+		block, err := d.getIndexedBlock(chain, height)
+		if err != nil {
+			return err
+		}
+		for i := range block.Txs {
+			tx := &block.Txs[i]
+			btxID, err := d.chainParser.PackTxid(tx.Txid)
+			if err != nil {
+				return err
+			}
+			ta, err := getTxAddresses(btxID)
+			if err != nil {
+				return err
+			}
+			if ta == nil {
+				glog.Warning("rebuild ", RebuildExtendedIndex, ": height ", height, ", tx ", tx.Txid, " not found in txAddresses")
+				continue
+			}
+			if tx.VSize > 0 {
+				ta.VSize = uint32(tx.VSize)
+			} else {
+				ta.VSize = uint32(len(tx.Hex))
+			}
+			for j := range tx.Vin {
+				input := &tx.Vin[j]
+				if j >= len(ta.Inputs) {
+					break
+				}
+				ibtxID, err := d.chainParser.PackTxid(input.Txid)
+				if err != nil {
+					// coinbase inputs do not have input txid
+					if err == bchain.ErrTxidMissing {
+						continue
+					}
+					return err
+				}
+				ita, err := getTxAddresses(ibtxID)
+				if err != nil {
+					return err
+				}
+				if ita == nil || len(ita.Outputs) <= int(input.Vout) {
+					continue
+				}
+				ta.Inputs[j].Txid = input.Txid
+				ta.Inputs[j].Vout = input.Vout
+				spentOutput := &ita.Outputs[input.Vout]
+				spentOutput.SpentTxid = tx.Txid
+				spentOutput.SpentIndex = uint32(j)
+				spentOutput.SpentHeight = height
+			}
+		}
+	}
+	return d.storeTxAddresses(wb, txAddressesMap)
+}
+
+// rebuildAddressContractsRow sorts the token ids and multi token values of the contracts of the address
+func rebuildAddressContractsRow(d *RocksDB, wb *grocksdb.WriteBatch, column string, key, value []byte) error {
+	if len(value) == 0 {
+		return nil
+	}
+	ca, err := unpackAddrContracts(value, key)
+	if err != nil {
+		return err
+	}
+	update := false
+	for i := range ca.Contracts {
+		c := &ca.Contracts[i]
+		if c.Ids.sort() {
+			update = true
+		}
+		if c.MultiTokenValues.sort() {
+			update = true
+		}
+	}
+	if update {
+		wb.PutCF(d.cfh[cfAddressContracts], key, packAddrContracts(ca))
+	}
+	return nil
+}
+
+// rebuildApprovals indexes the token approvals of the blocks fetched from the backend,
+// the keys of the approvals are stored also for the blocks which can be disconnected
+func rebuildApprovals(d *RocksDB, chain bchain.BlockChain, wb *grocksdb.WriteBatch, lower, higher uint32) error {
+	for height := lower; height <= higher; height++ {
+		block, err := d.getIndexedBlock(chain, height)
+		if err != nil {
+			return err
+		}
+		blockTxs := make([]ethBlockTx, len(block.Txs))
+		ei := d.ethereumTypeIndex()
+		for i := range block.Txs {
+			ei.processApprovals(&blockTxs[i], &block.Txs[i], height)
+		}
+		d.storeApprovalsEthereumType(wb, blockTxs)
+		bt, err := d.getBlockTxsEthereumType(height)
+		if err != nil {
+			return err
+		}
+		if bt != nil {
+			d.storeAndCleanupBlockApprovals(wb, height, blockTxs)
+		}
+	}
+	return nil
+}
+
+func rebuildContractLogs(d *RocksDB, chain bchain.BlockChain, wb *grocksdb.WriteBatch, lower, higher uint32) error {
+	for height := lower; height <= higher; height++ {
+		block, err := d.getIndexedBlock(chain, height)
+		if err != nil {
+			return err
+		}
+		blockTxs := make([]ethBlockTx, len(block.Txs))
+		for i := range block.Txs {
+			if blockTxs[i].btxID, err = d.chainParser.PackTxid(block.Txs[i].Txid); err != nil {
+				return err
+			}
+		}
+		d.ethereumTypeIndex().processContractLogs(blockTxs, block)
+		d.storeContractLogsEthereumType(wb, blockTxs)
+		bt, err := d.getBlockTxsEthereumType(height)
+		if err != nil {
+			return err
+		}
+		if bt != nil {
+			d.storeAndCleanupBlockContractLogs(wb, height, blockTxs)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+//go:build unittest
+
+package db
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/trezor/blockbook/bchain"
+	"github.com/trezor/blockbook/tests/dbtestdata"
+)
+
+func TestRocksDB_Rebuild_BitcoinType(t *testing.T) {
+	defer func(s int) { rebuildBlocksBatchSize = s }(rebuildBlocksBatchSize)
+	rebuildBlocksBatchSize = 1
+	parser := &testBitcoinParser{
+		BitcoinParser: bitcoinTestnetParser(),
+	}
+	chain, err := dbtestdata.NewFakeBlockChain(parser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	block1 := dbtestdata.GetTestBitcoinTypeBlock1(parser)
+	block2 := dbtestdata.GetTestBitcoinTypeBlock2(parser)
+	connect := func(d *RocksDB) {
+		for _, b := range []*bchain.Block{block1, block2} {
+			if err := d.ConnectBlock(b); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	// the reference database is indexed with the extended index and the block filters
+	r := setupRocksDB(t, parser)
+	defer closeAndDestroyRocksDB(t, r)
+	r.extendedIndex = true
+	r.is.BlockGolombFilterP = 20
+	connect(r)
+
+	d := setupRocksDB(t, parser)
+	defer closeAndDestroyRocksDB(t, d)
+	connect(d)
+
+	// rebuild the extended index, the first run is interrupted before the first batch
+	d.extendedIndex = true
+	if err := d.SetRebuild(RebuildExtendedIndex); err != nil {
+		t.Fatal(err)
+	}
+	stop := make(chan os.Signal, 1)
+	stop <- os.Interrupt
+	if err := d.Rebuild(chain, stop); err != ErrOperationInterrupted {
+		t.Fatalf("Rebuild() = %v, want ErrOperationInterrupted", err)
+	}
+	if d.is.Rebuild == nil || d.is.Rebuild.Name != RebuildExtendedIndex {
+		t.Fatalf("Rebuild progress %+v", d.is.Rebuild)
+	}
+	if err := d.Rebuild(chain, nil); err != nil {
+		t.Fatal(err)
+	}
+	if d.is.Rebuild != nil {
+		t.Fatalf("Rebuild progress not cleared %+v", d.is.Rebuild)
+	}
+	for _, b := range []*bchain.Block{block1, block2} {
+		for i := range b.Txs {
+			got, err := d.GetTxAddresses(b.Txs[i].Txid)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want, err := r.GetTxAddresses(b.Txs[i].Txid)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("GetTxAddresses(%v) = %+v, want %+v", b.Txs[i].Txid, got, want)
+			}
+		}
+	}
+
+	// rebuild the block filters with the changed settings
+	d.is.BlockGolombFilterP = 20
+	if err := d.SetRebuild(RebuildBlockFilter); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Rebuild(chain, nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range []*bchain.Block{block1, block2} {
+		got, err := d.GetBlockFilter(b.Hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		want, err := r.GetBlockFilter(b.Hash)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got == "" || got != want {
+			t.Errorf("GetBlockFilter(%v) = %v, want %v", b.Hash, got, want)
+		}
+	}
+
+	if err := d.SetRebuild(RebuildAddressAliases); err == nil {
+		t.Error("SetRebuild(addressAliases) expected error for BitcoinType coin")
+	}
+}
@@ -0,0 +1,140 @@
+package db
+
+import (
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+	"github.com/trezor/blockbook/bchain"
+	"github.com/trezor/blockbook/common"
+)
+
+// number of the last block hashes kept by the replica to find the blocks disconnected by the primary
+const replicaBlockHashes = 100
+
+// ReplicaWorker keeps the secondary instance of the database up to date with the primary instance
+// and reports the blocks connected and disconnected by the primary
+type ReplicaWorker struct {
+	db      *RocksDB
+	metrics *common.Metrics
+	is      *common.InternalState
+	// hashes of the last blocks, the last one is the block at bestHeight
+	bestHeight uint32
+	hashes     []string
+}
+
+// NewReplicaWorker creates the ReplicaWorker for the database opened by NewRocksDBSecondary
+func NewReplicaWorker(db *RocksDB, metrics *common.Metrics, is *common.InternalState) (*ReplicaWorker, error) {
+	if !db.IsSecondary() {
+		return nil, errors.New("ReplicaWorker requires a secondary instance of the database")
+	}
+	w := &ReplicaWorker{
+		db:      db,
+		metrics: metrics,
+		is:      is,
+	}
+	bestHeight, bestHash, err := db.GetBestBlock()
+	if err != nil {
+		return nil, err
+	}
+	if bestHash != "" {
+		var lower uint32
+		if bestHeight >= replicaBlockHashes {
+			lower = bestHeight - replicaBlockHashes + 1
+		}
+		for h := lower; h <= bestHeight; h++ {
+			hash, err := db.GetBlockHash(h)
+			if err != nil {
+				return nil, err
+			}
+			w.hashes = append(w.hashes, hash)
+		}
+		w.bestHeight = bestHeight
+	}
+	return w, nil
+}
+
+func (w *ReplicaWorker) heightOf(i int) uint32 {
+	return w.bestHeight - uint32(len(w.hashes)-1-i)
+}
+
+// CatchUp makes visible the changes written by the primary instance and updates the internal state,
+// onDisconnectBlocks is called for the blocks disconnected and onNewBlock for the blocks connected by the primary since the last call
+func (w *ReplicaWorker) CatchUp(onNewBlock bchain.OnNewBlockFunc, onDisconnectBlocks bchain.OnDisconnectBlocksFunc) error {
+	start := time.Now()
+	w.is.StartedSync()
+	if err := w.db.TryCatchUpWithPrimary(); err != nil {
+		return err
+	}
+	bestHeight, bestHash, err := w.db.GetBestBlock()
+	if err != nil {
+		return err
+	}
+	if bestHash == "" || (len(w.hashes) > 0 && w.bestHeight == bestHeight && w.hashes[len(w.hashes)-1] == bestHash) {
+		w.is.FinishedSyncNoChange()
+		return nil
+	}
+	// find the last block which was not disconnected by the primary
+	i := len(w.hashes) - 1
+	for ; i >= 0; i-- {
+		h := w.heightOf(i)
+		if h > bestHeight {
+			continue
+		}
+		hash, err := w.db.GetBlockHash(h)
+		if err != nil {
+			return err
+		}
+		if hash == w.hashes[i] {
+			break
+		}
+	}
+	var lower uint32
+	if i >= 0 {
+		lower = w.heightOf(i) + 1
+	} else if len(w.hashes) > 0 {
+		glog.Warning("replica: disconnected more than ", len(w.hashes), " blocks")
+		lower = w.heightOf(0)
+	}
+	if i < len(w.hashes)-1 {
+		hashes := make([]string, 0, len(w.hashes)-1-i)
+		for j := len(w.hashes) - 1; j > i; j-- {
+			hashes = append(hashes, w.hashes[j])
+		}
+		glog.Infof("replica: disconnected blocks %d-%d", lower, w.bestHeight)
+		w.is.RemoveLastBlockTimes(len(hashes))
+		higher := w.bestHeight
+		w.hashes = w.hashes[:i+1]
+		w.bestHeight = lower - 1
+		if onDisconnectBlocks != nil {
+			onDisconnectBlocks(lower, higher, hashes)
+		}
+	}
+	for h := lower; h <= bestHeight; h++ {
+		bi, err := w.db.GetBlockInfo(h)
+		if err != nil {
+			return err
+		}
+		if bi == nil {
+			return errors.Errorf("Missing block info for height %d", h)
+		}
+		avg := w.is.AppendBlockTime(uint32(bi.Time))
+		if w.metrics != nil {
+			w.metrics.AvgBlockPeriod.Set(float64(avg))
+		}
+		w.hashes = append(w.hashes, bi.Hash)
+		w.bestHeight = h
+		if onNewBlock != nil {
+			onNewBlock(bi.Hash, h)
+		}
+	}
+	if len(w.hashes) > replicaBlockHashes {
+		w.hashes = w.hashes[len(w.hashes)-replicaBlockHashes:]
+	}
+	w.is.FinishedSync(bestHeight)
+	if w.metrics != nil {
+		w.metrics.BlockbookBestHeight.Set(float64(bestHeight))
+	}
+	glog.Info("replica: caught up to height ", bestHeight, " in ", time.Since(start))
+	return nil
+}
@@ -0,0 +1,119 @@
+//go:build unittest
+
+package db
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/trezor/blockbook/common"
+	"github.com/trezor/blockbook/tests/dbtestdata"
+)
+
+func TestReplicaWorker_CatchUp(t *testing.T) {
+	parser := &testBitcoinParser{
+		BitcoinParser: bitcoinTestnetParser(),
+	}
+	d := setupRocksDB(t, parser)
+	defer closeAndDestroyRocksDB(t, d)
+	block1 := dbtestdata.GetTestBitcoinTypeBlock1(parser)
+	block2 := dbtestdata.GetTestBitcoinTypeBlock2(parser)
+	if err := d.ConnectBlock(block1); err != nil {
+		t.Fatal(err)
+	}
+
+	tmp, err := os.MkdirTemp("", "testdbsecondary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmp)
+	s, err := NewRocksDBSecondary(d.path, tmp, 100000, parser, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	is, err := s.LoadInternalState(&common.Config{CoinName: "coin-unittest"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.SetInternalState(is)
+	w, err := NewReplicaWorker(s, nil, is)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = NewReplicaWorker(d, nil, is); err == nil {
+		t.Error("NewReplicaWorker() expected error for primary instance")
+	}
+	if err = s.PutTx(&block1.Txs[0], block1.Height, block1.Txs[0].Blocktime); err != nil {
+		t.Fatal(err)
+	}
+
+	type disconnected struct {
+		lower, higher uint32
+		hashes        []string
+	}
+	var newBlocks []string
+	var disconnects []disconnected
+	onNewBlock := func(hash string, height uint32) {
+		newBlocks = append(newBlocks, hash)
+	}
+	onDisconnectBlocks := func(lower uint32, higher uint32, hashes []string) {
+		disconnects = append(disconnects, disconnected{lower, higher, hashes})
+	}
+
+	// the block connected by the primary becomes visible after the catch up
+	if err = d.ConnectBlock(block2); err != nil {
+		t.Fatal(err)
+	}
+	if ta, err := s.GetTxAddresses(dbtestdata.TxidB2T1); err != nil || ta != nil {
+		t.Fatalf("GetTxAddresses before catch up: got %v, error %v", ta, err)
+	}
+	if err = w.CatchUp(onNewBlock, onDisconnectBlocks); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(newBlocks, []string{block2.Hash}) || len(disconnects) != 0 {
+		t.Fatalf("CatchUp() new blocks %v, disconnected %v", newBlocks, disconnects)
+	}
+	if ta, err := s.GetTxAddresses(dbtestdata.TxidB2T1); err != nil || ta == nil || ta.Height != block2.Height {
+		t.Fatalf("GetTxAddresses after catch up: got %v, error %v", ta, err)
+	}
+	if _, bestHeight, _, _ := is.GetSyncState(); bestHeight != block2.Height || len(is.BlockTimes) != int(block2.Height)+1 {
+		t.Fatalf("internal state best height %d, block times %d", bestHeight, len(is.BlockTimes))
+	}
+
+	// nothing changed
+	newBlocks = nil
+	if err = w.CatchUp(onNewBlock, onDisconnectBlocks); err != nil {
+		t.Fatal(err)
+	}
+	if len(newBlocks) != 0 || len(disconnects) != 0 {
+		t.Fatalf("CatchUp() new blocks %v, disconnected %v", newBlocks, disconnects)
+	}
+
+	// the block disconnected by the primary is reported
+	if err = d.DisconnectBlockRangeBitcoinType(block2.Height, block2.Height); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.CatchUp(onNewBlock, onDisconnectBlocks); err != nil {
+		t.Fatal(err)
+	}
+	if want := []disconnected{{block2.Height, block2.Height, []string{block2.Hash}}}; len(newBlocks) != 0 || !reflect.DeepEqual(disconnects, want) {
+		t.Fatalf("CatchUp() new blocks %v, disconnected %+v, want %+v", newBlocks, disconnects, want)
+	}
+	if _, bestHeight, _, _ := is.GetSyncState(); bestHeight != block1.Height || len(is.BlockTimes) != int(block1.Height)+1 {
+		t.Fatalf("internal state best height %d, block times %d", bestHeight, len(is.BlockTimes))
+	}
+
+	// and connected again
+	disconnects = nil
+	if err = d.ConnectBlock(block2); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.CatchUp(onNewBlock, onDisconnectBlocks); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(newBlocks, []string{block2.Hash}) || len(disconnects) != 0 {
+		t.Fatalf("CatchUp() new blocks %v, disconnected %v", newBlocks, disconnects)
+	}
+}
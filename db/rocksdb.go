@@ -71,6 +71,12 @@ type RocksDB struct {
 	maxOpenFiles  int
 	cbs           connectBlockStats
 	extendedIndex bool
+	// column being rebuilt, set by SetRebuild
+	rebuild string
+	// directory of the secondary instance, empty for the primary instance
+	secondaryPath string
+	// number of the last blocks with the complete history, 0 if the pruning is disabled, set by SetPruneDepth
+	pruneDepth uint32
 }
 
 const (
@@ -96,6 +102,12 @@ const (
 
 	// TODO move to common section
 	cfAddressAliases
+	cfApprovals
+	cfBlockApprovals
+	cfContractLogs
+	cfBlockContractLogs
+	cfEventSignatures
+	cfContractABIs
 )
 
 // common columns
@@ -104,9 +116,17 @@ var cfBaseNames = []string{"default", "height", "addresses", "blockTxs", "transa
 
 // type specific columns
 var cfNamesBitcoinType = []string{"addressBalance", "txAddresses", "blockFilter"}
-var cfNamesEthereumType = []string{"addressContracts", "internalData", "contracts", "functionSignatures", "blockInternalDataErrors", "addressAliases"}
+var cfNamesEthereumType = []string{"addressContracts", "internalData", "contracts", "functionSignatures", "blockInternalDataErrors", "addressAliases", "approvals", "blockApprovals", "contractLogs", "blockContractLogs", "eventSignatures", "contractABIs"}
 
-func openDB(path string, c *grocksdb.Cache, openFiles int) (*grocksdb.DB, []*grocksdb.ColumnFamilyHandle, error) {
+// common columns appended after the type specific columns, their indexes are set in NewRocksDB
+var cfNamesWebhooks = []string{"webhooks", "webhookDeliveries"}
+var cfWebhooks, cfWebhookDeliveries int
+
+func openDB(path, secondaryPath string, c *grocksdb.Cache, openFiles int) (*grocksdb.DB, []*grocksdb.ColumnFamilyHandle, error) {
+	if secondaryPath != "" {
+		// the secondary instance must keep all the files of the primary instance open
+		openFiles = -1
+	}
 	// opts with bloom filter
 	opts := createAndSetDBOptions(10, c, openFiles)
 	// opts for addresses without bloom filter
@@ -119,7 +139,14 @@ func openDB(path string, c *grocksdb.Cache, openFiles int) (*grocksdb.DB, []*gro
 	for i := 0; i < count; i++ {
 		cfOptions = append(cfOptions, opts)
 	}
-	db, cfh, err := grocksdb.OpenDbColumnFamilies(opts, path, cfNames, cfOptions)
+	var db *grocksdb.DB
+	var cfh []*grocksdb.ColumnFamilyHandle
+	var err error
+	if secondaryPath != "" {
+		db, cfh, err = grocksdb.OpenDbAsSecondaryColumnFamilies(opts, path, secondaryPath, cfNames, cfOptions)
+	} else {
+		db, cfh, err = grocksdb.OpenDbColumnFamilies(opts, path, cfNames, cfOptions)
+	}
 	if err != nil {
 		return nil, nil, err
 	}
@@ -130,7 +157,17 @@ func openDB(path string, c *grocksdb.Cache, openFiles int) (*grocksdb.DB, []*gro
 // needs to be called to release it.
 func NewRocksDB(path string, cacheSize, maxOpenFiles int, parser bchain.BlockChainParser, metrics *common.Metrics, extendedIndex bool) (d *RocksDB, err error) {
 	glog.Infof("rocksdb: opening %s, required data version %v, cache size %v, max open files %v", path, dbVersion, cacheSize, maxOpenFiles)
+	return newRocksDB(path, "", cacheSize, maxOpenFiles, parser, metrics, extendedIndex)
+}
 
+// NewRocksDBSecondary opens the database in path as a read only secondary instance, which keeps its own files in secondaryPath.
+// The data written by the primary instance become visible after the call of TryCatchUpWithPrimary.
+func NewRocksDBSecondary(path, secondaryPath string, cacheSize int, parser bchain.BlockChainParser, metrics *common.Metrics, extendedIndex bool) (d *RocksDB, err error) {
+	glog.Infof("rocksdb: opening %s as secondary instance in %s, required data version %v, cache size %v", path, secondaryPath, dbVersion, cacheSize)
+	return newRocksDB(path, secondaryPath, cacheSize, -1, parser, metrics, extendedIndex)
+}
+
+func newRocksDB(path, secondaryPath string, cacheSize, maxOpenFiles int, parser bchain.BlockChainParser, metrics *common.Metrics, extendedIndex bool) (d *RocksDB, err error) {
 	cfNames = append([]string{}, cfBaseNames...)
 	chainType := parser.GetChainType()
 	if chainType == bchain.ChainBitcoinType {
@@ -141,15 +178,18 @@ func NewRocksDB(path string, cacheSize, maxOpenFiles int, parser bchain.BlockCha
 	} else {
 		return nil, errors.New("Unknown chain type")
 	}
+	cfWebhooks = len(cfNames)
+	cfWebhookDeliveries = cfWebhooks + 1
+	cfNames = append(cfNames, cfNamesWebhooks...)
 
 	c := grocksdb.NewLRUCache(uint64(cacheSize))
-	db, cfh, err := openDB(path, c, maxOpenFiles)
+	db, cfh, err := openDB(path, secondaryPath, c, maxOpenFiles)
 	if err != nil {
 		return nil, err
 	}
 	wo := grocksdb.NewDefaultWriteOptions()
 	ro := grocksdb.NewDefaultReadOptions()
-	return &RocksDB{path, db, wo, ro, cfh, parser, nil, metrics, c, maxOpenFiles, connectBlockStats{}, extendedIndex}, nil
+	return &RocksDB{path, db, wo, ro, cfh, parser, nil, metrics, c, maxOpenFiles, connectBlockStats{}, extendedIndex, "", secondaryPath, 0}, nil
 }
 
 func (d *RocksDB) closeDB() error {
@@ -187,7 +227,7 @@ func (d *RocksDB) Reopen() error {
 		return err
 	}
 	d.db = nil
-	db, cfh, err := openDB(d.path, d.cache, d.maxOpenFiles)
+	db, cfh, err := openDB(d.path, d.secondaryPath, d.cache, d.maxOpenFiles)
 	if err != nil {
 		return err
 	}
@@ -207,6 +247,19 @@ func (d *RocksDB) WriteBatch(wb *grocksdb.WriteBatch) error {
 	return d.db.Write(d.wo, wb)
 }
 
+// IsSecondary returns true if the database is opened as a read only secondary instance
+func (d *RocksDB) IsSecondary() bool {
+	return d.secondaryPath != ""
+}
+
+// TryCatchUpWithPrimary makes visible in the secondary instance the data written by the primary instance
+func (d *RocksDB) TryCatchUpWithPrimary() error {
+	if d.secondaryPath == "" {
+		return errors.New("Not a secondary instance")
+	}
+	return d.db.TryCatchUpWithPrimary()
+}
+
 // HasExtendedIndex returns true if the DB indexes input txids and spending data
 func (d *RocksDB) HasExtendedIndex() bool {
 	return d.extendedIndex
@@ -356,7 +409,7 @@ func (d *RocksDB) ConnectBlock(block *bchain.Block) error {
 		} else if gf != nil && !gf.Enabled {
 			gf = nil
 		}
-		if err := d.processAddressesBitcoinType(block, addresses, txAddressesMap, balances, gf); err != nil {
+		if err := d.bitcoinTypeIndex().processAddressesBitcoinType(block, addresses, txAddressesMap, balances, gf); err != nil {
 			return err
 		}
 		if err := d.storeTxAddresses(wb, txAddressesMap); err != nil {
@@ -376,7 +429,7 @@ func (d *RocksDB) ConnectBlock(block *bchain.Block) error {
 		}
 	} else if chainType == bchain.ChainEthereumType {
 		addressContracts := make(map[string]*AddrContracts)
-		blockTxs, err := d.processAddressesEthereumType(block, addresses, addressContracts)
+		blockTxs, err := d.ethereumTypeIndex().processAddressesEthereumType(block, addresses, addressContracts)
 		if err != nil {
 			return err
 		}
@@ -386,6 +439,8 @@ func (d *RocksDB) ConnectBlock(block *bchain.Block) error {
 		if err := d.storeInternalDataEthereumType(wb, blockTxs); err != nil {
 			return err
 		}
+		d.storeApprovalsEthereumType(wb, blockTxs)
+		d.storeContractLogsEthereumType(wb, blockTxs)
 		if err = d.storeBlockSpecificDataEthereumType(wb, block); err != nil {
 			return err
 		}
@@ -587,8 +642,28 @@ type blockTxs struct {
 	inputs []outpoint
 }
 
-func (d *RocksDB) resetValueSatToZero(valueSat *big.Int, addrDesc bchain.AddressDescriptor, logText string) {
-	ad, _, err := d.chainParser.GetAddressesFromAddrDesc(addrDesc)
+// bitcoinTypeIndex computes the changes of the BitcoinType index in connected and disconnected blocks,
+// the stored data are read by the functions so that the computation is shared by RocksDB and MemoryStore
+type bitcoinTypeIndex struct {
+	chainParser        bchain.BlockChainParser
+	extendedIndex      bool
+	cbs                *connectBlockStats
+	getAddrDescBalance func(addrDesc bchain.AddressDescriptor, detail AddressBalanceDetail) (*AddrBalance, error)
+	getTxAddresses     func(btxID []byte) (*TxAddresses, error)
+}
+
+func (d *RocksDB) bitcoinTypeIndex() *bitcoinTypeIndex {
+	return &bitcoinTypeIndex{
+		chainParser:        d.chainParser,
+		extendedIndex:      d.extendedIndex,
+		cbs:                &d.cbs,
+		getAddrDescBalance: d.GetAddrDescBalance,
+		getTxAddresses:     d.getTxAddresses,
+	}
+}
+
+func (bi *bitcoinTypeIndex) resetValueSatToZero(valueSat *big.Int, addrDesc bchain.AddressDescriptor, logText string) {
+	ad, _, err := bi.chainParser.GetAddressesFromAddrDesc(addrDesc)
 	if err != nil {
 		glog.Warningf("rocksdb: unparsable address hex '%v' reached negative %s %v, resetting to 0. Parser error %v", addrDesc, logText, valueSat.String(), err)
 	} else {
@@ -604,19 +679,19 @@ func (d *RocksDB) GetAndResetConnectBlockStats() string {
 	return s
 }
 
-func (d *RocksDB) processAddressesBitcoinType(block *bchain.Block, addresses addressesMap, txAddressesMap map[string]*TxAddresses, balances map[string]*AddrBalance, gf *bchain.GolombFilter) error {
+func (bi *bitcoinTypeIndex) processAddressesBitcoinType(block *bchain.Block, addresses addressesMap, txAddressesMap map[string]*TxAddresses, balances map[string]*AddrBalance, gf *bchain.GolombFilter) error {
 	blockTxIDs := make([][]byte, len(block.Txs))
 	blockTxAddresses := make([]*TxAddresses, len(block.Txs))
 	// first process all outputs so that inputs can refer to txs in this block
 	for txi := range block.Txs {
 		tx := &block.Txs[txi]
-		btxID, err := d.chainParser.PackTxid(tx.Txid)
+		btxID, err := bi.chainParser.PackTxid(tx.Txid)
 		if err != nil {
 			return err
 		}
 		blockTxIDs[txi] = btxID
 		ta := TxAddresses{Height: block.Height}
-		if d.extendedIndex {
+		if bi.extendedIndex {
 			if tx.VSize > 0 {
 				ta.VSize = uint32(tx.VSize)
 			} else {
@@ -630,7 +705,7 @@ func (d *RocksDB) processAddressesBitcoinType(block *bchain.Block, addresses add
 			output := &tx.Vout[i]
 			tao := &ta.Outputs[i]
 			tao.ValueSat = output.ValueSat
-			addrDesc, err := d.chainParser.GetAddrDescFromVout(output)
+			addrDesc, err := bi.chainParser.GetAddrDescFromVout(output)
 			if err != nil || len(addrDesc) == 0 || len(addrDesc) > maxAddrDescLen {
 				if err != nil {
 					// do not log ErrAddressMissing, transactions can be without to address (for example eth contracts)
@@ -646,11 +721,11 @@ func (d *RocksDB) processAddressesBitcoinType(block *bchain.Block, addresses add
 				gf.AddAddrDesc(addrDesc, tx)
 			}
 			tao.AddrDesc = addrDesc
-			if d.chainParser.IsAddrDescIndexable(addrDesc) {
+			if bi.chainParser.IsAddrDescIndexable(addrDesc) {
 				strAddrDesc := string(addrDesc)
 				balance, e := balances[strAddrDesc]
 				if !e {
-					balance, err = d.GetAddrDescBalance(addrDesc, addressBalanceDetailUTXOIndexed)
+					balance, err = bi.getAddrDescBalance(addrDesc, addressBalanceDetailUTXOIndexed)
 					if err != nil {
 						return err
 					}
@@ -658,9 +733,9 @@ func (d *RocksDB) processAddressesBitcoinType(block *bchain.Block, addresses add
 						balance = &AddrBalance{}
 					}
 					balances[strAddrDesc] = balance
-					d.cbs.balancesMiss++
+					bi.cbs.balancesMiss++
 				} else {
-					d.cbs.balancesHit++
+					bi.cbs.balancesHit++
 				}
 				balance.BalanceSat.Add(&balance.BalanceSat, &output.ValueSat)
 				balance.addUtxo(&Utxo{
@@ -686,7 +761,7 @@ func (d *RocksDB) processAddressesBitcoinType(block *bchain.Block, addresses add
 		for i := range tx.Vin {
 			input := &tx.Vin[i]
 			tai := &ta.Inputs[i]
-			btxID, err := d.chainParser.PackTxid(input.Txid)
+			btxID, err := bi.chainParser.PackTxid(input.Txid)
 			if err != nil {
 				// do not process inputs without input txid
 				if err == bchain.ErrTxidMissing {
@@ -697,19 +772,19 @@ func (d *RocksDB) processAddressesBitcoinType(block *bchain.Block, addresses add
 			stxID := string(btxID)
 			ita, e := txAddressesMap[stxID]
 			if !e {
-				ita, err = d.getTxAddresses(btxID)
+				ita, err = bi.getTxAddresses(btxID)
 				if err != nil {
 					return err
 				}
 				if ita == nil {
 					// allow parser to process unknown input, some coins may implement special handling, default is to log warning
-					tai.AddrDesc = d.chainParser.GetAddrDescForUnknownInput(tx, i)
+					tai.AddrDesc = bi.chainParser.GetAddrDescForUnknownInput(tx, i)
 					continue
 				}
 				txAddressesMap[stxID] = ita
-				d.cbs.txAddressesMiss++
+				bi.cbs.txAddressesMiss++
 			} else {
-				d.cbs.txAddressesHit++
+				bi.cbs.txAddressesHit++
 			}
 			if len(ita.Outputs) <= int(input.Vout) {
 				glog.Warningf("rocksdb: height %d, tx %v, input tx %v vout %v is out of bounds of stored tx", block.Height, tx.Txid, input.Txid, input.Vout)
@@ -726,7 +801,7 @@ func (d *RocksDB) processAddressesBitcoinType(block *bchain.Block, addresses add
 			tai.ValueSat = spentOutput.ValueSat
 			// mark the output as spent in tx
 			spentOutput.Spent = true
-			if d.extendedIndex {
+			if bi.extendedIndex {
 				spentOutput.SpentTxid = tx.Txid
 				spentOutput.SpentIndex = uint32(i)
 				spentOutput.SpentHeight = block.Height
@@ -740,11 +815,11 @@ func (d *RocksDB) processAddressesBitcoinType(block *bchain.Block, addresses add
 				}
 				continue
 			}
-			if d.chainParser.IsAddrDescIndexable(spentOutput.AddrDesc) {
+			if bi.chainParser.IsAddrDescIndexable(spentOutput.AddrDesc) {
 				strAddrDesc := string(spentOutput.AddrDesc)
 				balance, e := balances[strAddrDesc]
 				if !e {
-					balance, err = d.GetAddrDescBalance(spentOutput.AddrDesc, addressBalanceDetailUTXOIndexed)
+					balance, err = bi.getAddrDescBalance(spentOutput.AddrDesc, addressBalanceDetailUTXOIndexed)
 					if err != nil {
 						return err
 					}
@@ -752,9 +827,9 @@ func (d *RocksDB) processAddressesBitcoinType(block *bchain.Block, addresses add
 						balance = &AddrBalance{}
 					}
 					balances[strAddrDesc] = balance
-					d.cbs.balancesMiss++
+					bi.cbs.balancesMiss++
 				} else {
-					d.cbs.balancesHit++
+					bi.cbs.balancesHit++
 				}
 				counted := addToAddressesMap(addresses, strAddrDesc, spendingTxid, ^int32(i))
 				if !counted {
@@ -763,7 +838,7 @@ func (d *RocksDB) processAddressesBitcoinType(block *bchain.Block, addresses add
 				balance.BalanceSat.Sub(&balance.BalanceSat, &spentOutput.ValueSat)
 				balance.markUtxoAsSpent(btxID, int32(input.Vout))
 				if balance.BalanceSat.Sign() < 0 {
-					d.resetValueSatToZero(&balance.BalanceSat, spentOutput.AddrDesc, "balance")
+					bi.resetValueSatToZero(&balance.BalanceSat, spentOutput.AddrDesc, "balance")
 				}
 				balance.SentSat.Add(&balance.SentSat, &spentOutput.ValueSat)
 			}
@@ -850,6 +925,10 @@ func (d *RocksDB) storeBalances(wb *grocksdb.WriteBatch, abm map[string]*AddrBal
 }
 
 func (d *RocksDB) cleanupBlockTxs(wb *grocksdb.WriteBatch, block *bchain.Block) error {
+	// in the pruned index, the block txs are removed by the pruning of the block
+	if d.pruneDepth > 0 {
+		return nil
+	}
 	keep := d.chainParser.KeepBlockAddresses()
 	// cleanup old block address
 	if block.Height > uint32(keep) {
@@ -1362,6 +1441,20 @@ func (d *RocksDB) unpackBlockInfo(buf []byte) (*BlockInfo, error) {
 	}, nil
 }
 
+// Snapshot returns a read only view of the database pinned to its current state,
+// the view must be released by the returned function and must not be used for writes
+func (d *RocksDB) Snapshot() (Store, func()) {
+	snapshot := d.db.NewSnapshot()
+	ro := grocksdb.NewDefaultReadOptions()
+	ro.SetSnapshot(snapshot)
+	sd := *d
+	sd.ro = ro
+	return &sd, func() {
+		ro.Destroy()
+		d.db.ReleaseSnapshot(snapshot)
+	}
+}
+
 // GetBestBlock returns the block hash of the block with highest height in the db
 func (d *RocksDB) GetBestBlock() (uint32, string, error) {
 	it := d.db.NewIteratorCF(d.ro, d.cfh[cfHeight])
@@ -1483,7 +1576,7 @@ func (d *RocksDB) storeAddressAliasRecords(wb *grocksdb.WriteBatch, records []bc
 
 // Disconnect blocks
 
-func (d *RocksDB) disconnectTxAddressesInputs(wb *grocksdb.WriteBatch, btxID []byte, inputs []outpoint, txa *TxAddresses, txAddressesToUpdate map[string]*TxAddresses,
+func (bi *bitcoinTypeIndex) disconnectTxAddressesInputs(btxID []byte, inputs []outpoint, txa *TxAddresses, txAddressesToUpdate map[string]*TxAddresses,
 	getAddressBalance func(addrDesc bchain.AddressDescriptor) (*AddrBalance, error),
 	addressFoundInTx func(addrDesc bchain.AddressDescriptor, btxID []byte) bool) error {
 	var err error
@@ -1495,7 +1588,7 @@ func (d *RocksDB) disconnectTxAddressesInputs(wb *grocksdb.WriteBatch, btxID []b
 			s := string(input.btxID)
 			sa, found := txAddressesToUpdate[s]
 			if !found {
-				sa, err = d.getTxAddresses(input.btxID)
+				sa, err = bi.getTxAddresses(input.btxID)
 				if err != nil {
 					return err
 				}
@@ -1508,7 +1601,7 @@ func (d *RocksDB) disconnectTxAddressesInputs(wb *grocksdb.WriteBatch, btxID []b
 				sa.Outputs[input.index].Spent = false
 				inputHeight = sa.Height
 			}
-			if d.chainParser.IsAddrDescIndexable(t.AddrDesc) {
+			if bi.chainParser.IsAddrDescIndexable(t.AddrDesc) {
 				balance, err = getAddressBalance(t.AddrDesc)
 				if err != nil {
 					return err
@@ -1520,7 +1613,7 @@ func (d *RocksDB) disconnectTxAddressesInputs(wb *grocksdb.WriteBatch, btxID []b
 					}
 					balance.SentSat.Sub(&balance.SentSat, &t.ValueSat)
 					if balance.SentSat.Sign() < 0 {
-						d.resetValueSatToZero(&balance.SentSat, t.AddrDesc, "sent amount")
+						bi.resetValueSatToZero(&balance.SentSat, t.AddrDesc, "sent amount")
 					}
 					balance.BalanceSat.Add(&balance.BalanceSat, &t.ValueSat)
 					balance.addUtxoInDisconnect(&Utxo{
@@ -1530,7 +1623,7 @@ func (d *RocksDB) disconnectTxAddressesInputs(wb *grocksdb.WriteBatch, btxID []b
 						ValueSat: t.ValueSat,
 					})
 				} else {
-					ad, _, _ := d.chainParser.GetAddressesFromAddrDesc(t.AddrDesc)
+					ad, _, _ := bi.chainParser.GetAddressesFromAddrDesc(t.AddrDesc)
 					glog.Warningf("Balance for address %s (%s) not found", ad, t.AddrDesc)
 				}
 			}
@@ -1539,13 +1632,13 @@ func (d *RocksDB) disconnectTxAddressesInputs(wb *grocksdb.WriteBatch, btxID []b
 	return nil
 }
 
-func (d *RocksDB) disconnectTxAddressesOutputs(wb *grocksdb.WriteBatch, btxID []byte, txa *TxAddresses,
+func (bi *bitcoinTypeIndex) disconnectTxAddressesOutputs(btxID []byte, txa *TxAddresses,
 	getAddressBalance func(addrDesc bchain.AddressDescriptor) (*AddrBalance, error),
 	addressFoundInTx func(addrDesc bchain.AddressDescriptor, btxID []byte) bool) error {
 	for i, t := range txa.Outputs {
 		if len(t.AddrDesc) > 0 {
 			exist := addressFoundInTx(t.AddrDesc, btxID)
-			if d.chainParser.IsAddrDescIndexable(t.AddrDesc) {
+			if bi.chainParser.IsAddrDescIndexable(t.AddrDesc) {
 				balance, err := getAddressBalance(t.AddrDesc)
 				if err != nil {
 					return err
@@ -1557,11 +1650,11 @@ func (d *RocksDB) disconnectTxAddressesOutputs(wb *grocksdb.WriteBatch, btxID []
 					}
 					balance.BalanceSat.Sub(&balance.BalanceSat, &t.ValueSat)
 					if balance.BalanceSat.Sign() < 0 {
-						d.resetValueSatToZero(&balance.BalanceSat, t.AddrDesc, "balance")
+						bi.resetValueSatToZero(&balance.BalanceSat, t.AddrDesc, "balance")
 					}
 					balance.markUtxoAsSpent(btxID, int32(i))
 				} else {
-					ad, _, _ := d.chainParser.GetAddressesFromAddrDesc(t.AddrDesc)
+					ad, _, _ := bi.chainParser.GetAddressesFromAddrDesc(t.AddrDesc)
 					glog.Warningf("Balance for address %s (%s) not found", ad, t.AddrDesc)
 				}
 			}
@@ -1583,37 +1676,45 @@ func (d *RocksDB) disconnectBlockFilter(wb *grocksdb.WriteBatch, height uint32)
 	return nil
 }
 
-func (d *RocksDB) disconnectBlock(height uint32, blockTxs []blockTxs) error {
-	wb := grocksdb.NewWriteBatch()
-	defer wb.Destroy()
-	txAddressesToUpdate := make(map[string]*TxAddresses)
-	txAddresses := make([]*TxAddresses, len(blockTxs))
-	txsToDelete := make(map[string]struct{})
+// bitcoinTypeDisconnect holds the changes of the index reverting the transactions of a disconnected block
+type bitcoinTypeDisconnect struct {
+	txAddressesToUpdate map[string]*TxAddresses
+	balances            map[string]*AddrBalance
+	// all addresses in the block, together with a map of transactions where they appear
+	blockAddressesTxs map[string]map[string]struct{}
+	txsToDelete       map[string]struct{}
+}
 
-	balances := make(map[string]*AddrBalance)
+// disconnectBlockTxs reverts the changes made by the transactions of the block,
+// the spent utxos are removed from the returned balances
+func (bi *bitcoinTypeIndex) disconnectBlockTxs(blockTxs []blockTxs) (*bitcoinTypeDisconnect, error) {
+	r := &bitcoinTypeDisconnect{
+		txAddressesToUpdate: make(map[string]*TxAddresses),
+		balances:            make(map[string]*AddrBalance),
+		blockAddressesTxs:   make(map[string]map[string]struct{}),
+		txsToDelete:         make(map[string]struct{}),
+	}
+	txAddresses := make([]*TxAddresses, len(blockTxs))
 	getAddressBalance := func(addrDesc bchain.AddressDescriptor) (*AddrBalance, error) {
 		var err error
 		s := string(addrDesc)
-		b, fb := balances[s]
+		b, fb := r.balances[s]
 		if !fb {
-			b, err = d.GetAddrDescBalance(addrDesc, addressBalanceDetailUTXOIndexed)
+			b, err = bi.getAddrDescBalance(addrDesc, addressBalanceDetailUTXOIndexed)
 			if err != nil {
 				return nil, err
 			}
-			balances[s] = b
+			r.balances[s] = b
 		}
 		return b, nil
 	}
-
-	// all addresses in the block are stored in blockAddressesTxs, together with a map of transactions where they appear
-	blockAddressesTxs := make(map[string]map[string]struct{})
 	// addressFoundInTx handles updates of the blockAddressesTxs map and returns true if the address+tx was already encountered
 	addressFoundInTx := func(addrDesc bchain.AddressDescriptor, btxID []byte) bool {
 		sAddrDesc := string(addrDesc)
 		sBtxID := string(btxID)
-		a, exist := blockAddressesTxs[sAddrDesc]
+		a, exist := r.blockAddressesTxs[sAddrDesc]
 		if !exist {
-			blockAddressesTxs[sAddrDesc] = map[string]struct{}{sBtxID: {}}
+			r.blockAddressesTxs[sAddrDesc] = map[string]struct{}{sBtxID: {}}
 		} else {
 			_, exist = a[sBtxID]
 			if !exist {
@@ -1622,26 +1723,24 @@ func (d *RocksDB) disconnectBlock(height uint32, blockTxs []blockTxs) error {
 		}
 		return exist
 	}
-
-	glog.Info("Disconnecting block ", height, " containing ", len(blockTxs), " transactions")
 	// when connecting block, outputs are processed first
 	// when disconnecting, inputs must be reversed first
 	for i := range blockTxs {
 		btxID := blockTxs[i].btxID
 		s := string(btxID)
-		txsToDelete[s] = struct{}{}
-		txa, err := d.getTxAddresses(btxID)
+		r.txsToDelete[s] = struct{}{}
+		txa, err := bi.getTxAddresses(btxID)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		if txa == nil {
-			ut, _ := d.chainParser.UnpackTxid(btxID)
+			ut, _ := bi.chainParser.UnpackTxid(btxID)
 			glog.Warning("TxAddress for txid ", ut, " not found")
 			continue
 		}
 		txAddresses[i] = txa
-		if err := d.disconnectTxAddressesInputs(wb, btxID, blockTxs[i].inputs, txa, txAddressesToUpdate, getAddressBalance, addressFoundInTx); err != nil {
-			return err
+		if err := bi.disconnectTxAddressesInputs(btxID, blockTxs[i].inputs, txa, r.txAddressesToUpdate, getAddressBalance, addressFoundInTx); err != nil {
+			return nil, err
 		}
 	}
 	for i := range blockTxs {
@@ -1650,20 +1749,48 @@ func (d *RocksDB) disconnectBlock(height uint32, blockTxs []blockTxs) error {
 		if txa == nil {
 			continue
 		}
-		if err := d.disconnectTxAddressesOutputs(wb, btxID, txa, getAddressBalance, addressFoundInTx); err != nil {
-			return err
+		if err := bi.disconnectTxAddressesOutputs(btxID, txa, getAddressBalance, addressFoundInTx); err != nil {
+			return nil, err
 		}
 	}
-	for a := range blockAddressesTxs {
+	for _, b := range r.balances {
+		if b != nil {
+			// remove spent utxos
+			us := make([]Utxo, 0, len(b.Utxos))
+			for _, u := range b.Utxos {
+				// remove utxos marked as spent
+				if u.Vout >= 0 {
+					us = append(us, u)
+				}
+			}
+			b.Utxos = us
+			// sort utxos by height
+			sort.SliceStable(b.Utxos, func(i, j int) bool {
+				return b.Utxos[i].Height < b.Utxos[j].Height
+			})
+		}
+	}
+	return r, nil
+}
+
+func (d *RocksDB) disconnectBlock(height uint32, blockTxs []blockTxs) error {
+	wb := grocksdb.NewWriteBatch()
+	defer wb.Destroy()
+	glog.Info("Disconnecting block ", height, " containing ", len(blockTxs), " transactions")
+	r, err := d.bitcoinTypeIndex().disconnectBlockTxs(blockTxs)
+	if err != nil {
+		return err
+	}
+	for a := range r.blockAddressesTxs {
 		key := packAddressKey([]byte(a), height)
 		wb.DeleteCF(d.cfh[cfAddresses], key)
 	}
 	key := packUint(height)
 	wb.DeleteCF(d.cfh[cfBlockTxs], key)
 	wb.DeleteCF(d.cfh[cfHeight], key)
-	d.storeTxAddresses(wb, txAddressesToUpdate)
-	d.storeBalancesDisconnect(wb, balances)
-	for s := range txsToDelete {
+	d.storeTxAddresses(wb, r.txAddressesToUpdate)
+	d.storeBalances(wb, r.balances)
+	for s := range r.txsToDelete {
 		b := []byte(s)
 		wb.DeleteCF(d.cfh[cfTransactions], b)
 		wb.DeleteCF(d.cfh[cfTxAddresses], b)
@@ -1677,6 +1804,12 @@ func (d *RocksDB) disconnectBlock(height uint32, blockTxs []blockTxs) error {
 // DisconnectBlockRangeBitcoinType removes all data belonging to blocks in range lower-higher
 // it is able to disconnect only blocks for which there are data in the blockTxs column
 func (d *RocksDB) DisconnectBlockRangeBitcoinType(lower uint32, higher uint32) error {
+	// the pruning must not remove the data of the disconnected blocks
+	pruneMux.Lock()
+	defer pruneMux.Unlock()
+	if prunedHeight := d.is.GetPrunedHeight(); lower < prunedHeight {
+		return errors.Errorf("Cannot disconnect blocks with height %v and lower, the history below height %v is pruned. It is necessary to rebuild index.", lower, prunedHeight)
+	}
 	blocks := make([][]blockTxs, higher-lower+1)
 	for height := lower; height <= higher; height++ {
 		blockTxs, err := d.getBlockTxs(height)
@@ -1699,27 +1832,6 @@ func (d *RocksDB) DisconnectBlockRangeBitcoinType(lower uint32, higher uint32) e
 	return nil
 }
 
-func (d *RocksDB) storeBalancesDisconnect(wb *grocksdb.WriteBatch, balances map[string]*AddrBalance) {
-	for _, b := range balances {
-		if b != nil {
-			// remove spent utxos
-			us := make([]Utxo, 0, len(b.Utxos))
-			for _, u := range b.Utxos {
-				// remove utxos marked as spent
-				if u.Vout >= 0 {
-					us = append(us, u)
-				}
-			}
-			b.Utxos = us
-			// sort utxos by height
-			sort.SliceStable(b.Utxos, func(i, j int) bool {
-				return b.Utxos[i].Height < b.Utxos[j].Height
-			})
-		}
-	}
-	d.storeBalances(wb, balances)
-
-}
 func dirSize(path string) (int64, error) {
 	var size int64
 	err := filepath.Walk(path, func(_ string, info os.FileInfo, err error) error {
@@ -1776,6 +1888,10 @@ func (d *RocksDB) GetTx(txid string) (*bchain.Tx, uint32, error) {
 
 // PutTx stores transactions in db
 func (d *RocksDB) PutTx(tx *bchain.Tx, height uint32, blockTime int64) error {
+	// the secondary instance is read only, the transactions are cached only in memory
+	if d.secondaryPath != "" {
+		return nil
+	}
 	key, err := d.chainParser.PackTxid(tx.Txid)
 	if err != nil {
 		return nil
@@ -1857,20 +1973,13 @@ func (d *RocksDB) checkColumns(is *common.InternalState) ([]common.InternalState
 		nc[i].Version = dbVersion
 		for j := 0; j < len(sc); j++ {
 			if sc[j].Name == nc[i].Name {
-				// check the version of the column, if it does not match, the db is not compatible
+				// check the version of the column, if it does not match, the db must be upgraded by migrations
 				if sc[j].Version != dbVersion {
-					// upgrade of DB 5 to 6 for BitcoinType coins is possible
-					// columns transactions and fiatRates must be cleared as they are not compatible
-					if sc[j].Version == 5 && dbVersion == 6 && d.chainParser.GetChainType() == bchain.ChainBitcoinType {
-						if nc[i].Name == "transactions" {
-							d.db.DeleteRangeCF(d.wo, d.cfh[cfTransactions], []byte{0}, []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff})
-						} else if nc[i].Name == "fiatRates" {
-							d.db.DeleteRangeCF(d.wo, d.cfh[cfFiatRates], []byte{0}, []byte{0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff})
-						}
-						glog.Infof("Column %s upgraded from v%d to v%d", nc[i].Name, sc[j].Version, dbVersion)
-					} else {
-						return nil, errors.Errorf("DB version %v of column '%v' does not match the required version %v. DB is not compatible.", sc[j].Version, sc[j].Name, dbVersion)
+					if _, err := migrationsFrom(sc[j].Version, d.chainParser.GetChainType()); err != nil {
+						return nil, errors.Errorf("DB version %v of column '%v' does not match the required version %v. DB is not compatible: %v", sc[j].Version, sc[j].Name, dbVersion, err)
 					}
+					// the version is updated by the migrations
+					nc[i].Version = sc[j].Version
 				}
 				nc[i].Rows = sc[j].Rows
 				nc[i].KeyBytes = sc[j].KeyBytes
@@ -1901,6 +2010,7 @@ func (d *RocksDB) LoadInternalState(config *common.Config) (*common.InternalStat
 			BlockGolombFilterP:      config.BlockGolombFilterP,
 			BlockFilterScripts:      config.BlockFilterScripts,
 			BlockFilterUseZeroedKey: config.BlockFilterUseZeroedKey,
+			PruneDepth:              d.pruneDepth,
 		}
 	} else {
 		is, err = common.UnpackInternalState(data)
@@ -1914,9 +2024,26 @@ func (d *RocksDB) LoadInternalState(config *common.Config) (*common.InternalStat
 		} else if is.Coin != config.CoinName {
 			return nil, errors.Errorf("Coins do not match. DB coin %v, RPC coin %v", is.Coin, config.CoinName)
 		}
+		if d.rebuild == RebuildExtendedIndex && !is.ExtendedIndex {
+			glog.Info("rebuild: enabling extendedIndex")
+			is.ExtendedIndex = true
+		}
+		if d.rebuild == RebuildBlockFilter {
+			glog.Infof("rebuild: block filter settings p %v, scripts %v, zeroed key %v", config.BlockGolombFilterP, config.BlockFilterScripts, config.BlockFilterUseZeroedKey)
+			is.BlockGolombFilterP = config.BlockGolombFilterP
+			is.BlockFilterScripts = config.BlockFilterScripts
+			is.BlockFilterUseZeroedKey = config.BlockFilterUseZeroedKey
+		}
 		if is.ExtendedIndex != d.extendedIndex {
 			return nil, errors.Errorf("ExtendedIndex setting does not match. DB extendedIndex %v, extendedIndex in options %v", is.ExtendedIndex, d.extendedIndex)
 		}
+		if d.secondaryPath != "" {
+			// the pruning is done by the primary instance
+			d.pruneDepth = is.PruneDepth
+		} else if is.PrunedHeight > 0 && d.pruneDepth == 0 {
+			return nil, errors.Errorf("The history below height %v is pruned, the database must be used with the prunedepth option", is.PrunedHeight)
+		}
+		is.PruneDepth = d.pruneDepth
 		if is.BlockGolombFilterP != config.BlockGolombFilterP {
 			return nil, errors.Errorf("BlockGolombFilterP does not match. DB BlockGolombFilterP %v, config BlockGolombFilterP %v", is.BlockGolombFilterP, config.BlockGolombFilterP)
 		}
@@ -2003,6 +2130,10 @@ func (d *RocksDB) StoreInternalState(is *common.InternalState) error {
 }
 
 func (d *RocksDB) storeState(is *common.InternalState) error {
+	// the secondary instance is read only, the state is stored by the primary instance
+	if d.secondaryPath != "" {
+		return nil
+	}
 	buf, err := is.Pack()
 	if err != nil {
 		return err
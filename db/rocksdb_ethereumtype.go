@@ -335,12 +335,32 @@ func addToContract(c *AddrContract, contractIndex int, index int32, contract bch
 	return index
 }
 
-func (d *RocksDB) addToAddressesAndContractsEthereumType(addrDesc bchain.AddressDescriptor, btxID []byte, index int32, contract bchain.AddressDescriptor, transfer *bchain.TokenTransfer, addTxCount bool, addresses addressesMap, addressContracts map[string]*AddrContracts) error {
+// ethereumTypeIndex computes the changes of the EthereumType index in connected and disconnected blocks,
+// the stored data are read by the functions so that the computation is shared by RocksDB and MemoryStore
+type ethereumTypeIndex struct {
+	chainParser                    bchain.BlockChainParser
+	cbs                            *connectBlockStats
+	getAddrDescContracts           func(addrDesc bchain.AddressDescriptor) (*AddrContracts, error)
+	getTxIndexesForAddressAndBlock func(addrDesc bchain.AddressDescriptor, height uint32) ([]txIndexes, error)
+	getEthereumInternalData        func(btxID []byte) (*bchain.EthereumInternalData, error)
+}
+
+func (d *RocksDB) ethereumTypeIndex() *ethereumTypeIndex {
+	return &ethereumTypeIndex{
+		chainParser:                    d.chainParser,
+		cbs:                            &d.cbs,
+		getAddrDescContracts:           d.GetAddrDescContracts,
+		getTxIndexesForAddressAndBlock: d.getTxIndexesForAddressAndBlock,
+		getEthereumInternalData:        d.getEthereumInternalData,
+	}
+}
+
+func (ei *ethereumTypeIndex) addToAddressesAndContractsEthereumType(addrDesc bchain.AddressDescriptor, btxID []byte, index int32, contract bchain.AddressDescriptor, transfer *bchain.TokenTransfer, addTxCount bool, addresses addressesMap, addressContracts map[string]*AddrContracts) error {
 	var err error
 	strAddrDesc := string(addrDesc)
 	ac, e := addressContracts[strAddrDesc]
 	if !e {
-		ac, err = d.GetAddrDescContracts(addrDesc)
+		ac, err = ei.getAddrDescContracts(addrDesc)
 		if err != nil {
 			return err
 		}
@@ -348,9 +368,9 @@ func (d *RocksDB) addToAddressesAndContractsEthereumType(addrDesc bchain.Address
 			ac = &AddrContracts{}
 		}
 		addressContracts[strAddrDesc] = ac
-		d.cbs.balancesMiss++
+		ei.cbs.balancesMiss++
 	} else {
-		d.cbs.balancesHit++
+		ei.cbs.balancesHit++
 	}
 	if contract == nil {
 		if addTxCount {
@@ -414,21 +434,25 @@ type ethBlockTx struct {
 	from, to     bchain.AddressDescriptor
 	contracts    []ethBlockTxContract
 	internalData *ethInternalData
+	// approvals are not stored in blockTx, the keys of the approvals of the block are stored in the cfBlockApprovals column
+	approvals []ethBlockTxApproval
+	// logs are not stored in blockTx, the keys of the logs of the block are stored in the cfBlockContractLogs column
+	logs []ethBlockTxLog
 }
 
-func (d *RocksDB) processBaseTxData(blockTx *ethBlockTx, tx *bchain.Tx, addresses addressesMap, addressContracts map[string]*AddrContracts) error {
+func (ei *ethereumTypeIndex) processBaseTxData(blockTx *ethBlockTx, tx *bchain.Tx, addresses addressesMap, addressContracts map[string]*AddrContracts) error {
 	var from, to bchain.AddressDescriptor
 	var err error
 	// there is only one output address in EthereumType transaction, store it in format txid 0
 	if len(tx.Vout) == 1 && len(tx.Vout[0].ScriptPubKey.Addresses) == 1 {
-		to, err = d.chainParser.GetAddrDescFromAddress(tx.Vout[0].ScriptPubKey.Addresses[0])
+		to, err = ei.chainParser.GetAddrDescFromAddress(tx.Vout[0].ScriptPubKey.Addresses[0])
 		if err != nil {
 			// do not log ErrAddressMissing, transactions can be without to address (for example eth contracts)
 			if err != bchain.ErrAddressMissing {
 				glog.Warningf("rocksdb: processBaseTxData: %v, tx %v, output", err, tx.Txid)
 			}
 		} else {
-			if err = d.addToAddressesAndContractsEthereumType(to, blockTx.btxID, transferTo, nil, nil, true, addresses, addressContracts); err != nil {
+			if err = ei.addToAddressesAndContractsEthereumType(to, blockTx.btxID, transferTo, nil, nil, true, addresses, addressContracts); err != nil {
 				return err
 			}
 			blockTx.to = to
@@ -436,13 +460,13 @@ func (d *RocksDB) processBaseTxData(blockTx *ethBlockTx, tx *bchain.Tx, addresse
 	}
 	// there is only one input address in EthereumType transaction, store it in format txid ^0
 	if len(tx.Vin) == 1 && len(tx.Vin[0].Addresses) == 1 {
-		from, err = d.chainParser.GetAddrDescFromAddress(tx.Vin[0].Addresses[0])
+		from, err = ei.chainParser.GetAddrDescFromAddress(tx.Vin[0].Addresses[0])
 		if err != nil {
 			if err != bchain.ErrAddressMissing {
 				glog.Warningf("rocksdb: processBaseTxData: %v, tx %v, input", err, tx.Txid)
 			}
 		} else {
-			if err = d.addToAddressesAndContractsEthereumType(from, blockTx.btxID, transferFrom, nil, nil, !bytes.Equal(from, to), addresses, addressContracts); err != nil {
+			if err = ei.addToAddressesAndContractsEthereumType(from, blockTx.btxID, transferFrom, nil, nil, !bytes.Equal(from, to), addresses, addressContracts); err != nil {
 				return err
 			}
 			blockTx.from = from
@@ -451,11 +475,11 @@ func (d *RocksDB) processBaseTxData(blockTx *ethBlockTx, tx *bchain.Tx, addresse
 	return nil
 }
 
-func (d *RocksDB) setAddressTxIndexesToAddressMap(addrDesc bchain.AddressDescriptor, height uint32, addresses addressesMap) error {
+func (ei *ethereumTypeIndex) setAddressTxIndexesToAddressMap(addrDesc bchain.AddressDescriptor, height uint32, addresses addressesMap) error {
 	strAddrDesc := string(addrDesc)
 	_, found := addresses[strAddrDesc]
 	if !found {
-		txIndexes, err := d.getTxIndexesForAddressAndBlock(addrDesc, height)
+		txIndexes, err := ei.getTxIndexesForAddressAndBlock(addrDesc, height)
 		if err != nil {
 			return err
 		}
@@ -467,14 +491,14 @@ func (d *RocksDB) setAddressTxIndexesToAddressMap(addrDesc bchain.AddressDescrip
 }
 
 // existingBlock signals that internal data are reconnected to already indexed block after they failed during standard sync
-func (d *RocksDB) processInternalData(blockTx *ethBlockTx, tx *bchain.Tx, id *bchain.EthereumInternalData, addresses addressesMap, addressContracts map[string]*AddrContracts, existingBlock bool) error {
+func (ei *ethereumTypeIndex) processInternalData(blockTx *ethBlockTx, tx *bchain.Tx, id *bchain.EthereumInternalData, addresses addressesMap, addressContracts map[string]*AddrContracts, existingBlock bool) error {
 	blockTx.internalData = &ethInternalData{
 		internalType: id.Type,
 		errorMsg:     id.Error,
 	}
 	// index contract creation
 	if id.Type == bchain.CREATE {
-		to, err := d.chainParser.GetAddrDescFromAddress(id.Contract)
+		to, err := ei.chainParser.GetAddrDescFromAddress(id.Contract)
 		if err != nil {
 			if err != bchain.ErrAddressMissing {
 				glog.Warningf("rocksdb: processInternalData: %v, tx %v, create contract", err, tx.Txid)
@@ -484,11 +508,11 @@ func (d *RocksDB) processInternalData(blockTx *ethBlockTx, tx *bchain.Tx, id *bc
 		} else {
 			blockTx.internalData.contract = to
 			if existingBlock {
-				if err = d.setAddressTxIndexesToAddressMap(to, tx.BlockHeight, addresses); err != nil {
+				if err = ei.setAddressTxIndexesToAddressMap(to, tx.BlockHeight, addresses); err != nil {
 					return err
 				}
 			}
-			if err = d.addToAddressesAndContractsEthereumType(to, blockTx.btxID, internalTransferTo, nil, nil, true, addresses, addressContracts); err != nil {
+			if err = ei.addToAddressesAndContractsEthereumType(to, blockTx.btxID, internalTransferTo, nil, nil, true, addresses, addressContracts); err != nil {
 				return err
 			}
 		}
@@ -499,7 +523,7 @@ func (d *RocksDB) processInternalData(blockTx *ethBlockTx, tx *bchain.Tx, id *bc
 		for i := range id.Transfers {
 			iti := &id.Transfers[i]
 			ito := &blockTx.internalData.transfers[i]
-			to, err := d.chainParser.GetAddrDescFromAddress(iti.To)
+			to, err := ei.chainParser.GetAddrDescFromAddress(iti.To)
 			if err != nil {
 				// do not log ErrAddressMissing, transactions can be without to address (for example eth contracts)
 				if err != bchain.ErrAddressMissing {
@@ -507,27 +531,27 @@ func (d *RocksDB) processInternalData(blockTx *ethBlockTx, tx *bchain.Tx, id *bc
 				}
 			} else {
 				if existingBlock {
-					if err = d.setAddressTxIndexesToAddressMap(to, tx.BlockHeight, addresses); err != nil {
+					if err = ei.setAddressTxIndexesToAddressMap(to, tx.BlockHeight, addresses); err != nil {
 						return err
 					}
 				}
-				if err = d.addToAddressesAndContractsEthereumType(to, blockTx.btxID, internalTransferTo, nil, nil, true, addresses, addressContracts); err != nil {
+				if err = ei.addToAddressesAndContractsEthereumType(to, blockTx.btxID, internalTransferTo, nil, nil, true, addresses, addressContracts); err != nil {
 					return err
 				}
 				ito.to = to
 			}
-			from, err := d.chainParser.GetAddrDescFromAddress(iti.From)
+			from, err := ei.chainParser.GetAddrDescFromAddress(iti.From)
 			if err != nil {
 				if err != bchain.ErrAddressMissing {
 					glog.Warningf("rocksdb: processInternalData: %v, tx %v, internal transfer %d from", err, tx.Txid, i)
 				}
 			} else {
 				if existingBlock {
-					if err = d.setAddressTxIndexesToAddressMap(from, tx.BlockHeight, addresses); err != nil {
+					if err = ei.setAddressTxIndexesToAddressMap(from, tx.BlockHeight, addresses); err != nil {
 						return err
 					}
 				}
-				if err = d.addToAddressesAndContractsEthereumType(from, blockTx.btxID, internalTransferFrom, nil, nil, !bytes.Equal(from, to), addresses, addressContracts); err != nil {
+				if err = ei.addToAddressesAndContractsEthereumType(from, blockTx.btxID, internalTransferFrom, nil, nil, !bytes.Equal(from, to), addresses, addressContracts); err != nil {
 					return err
 				}
 				ito.from = from
@@ -539,30 +563,30 @@ func (d *RocksDB) processInternalData(blockTx *ethBlockTx, tx *bchain.Tx, id *bc
 	return nil
 }
 
-func (d *RocksDB) processContractTransfers(blockTx *ethBlockTx, tx *bchain.Tx, addresses addressesMap, addressContracts map[string]*AddrContracts) error {
-	tokenTransfers, err := d.chainParser.EthereumTypeGetTokenTransfersFromTx(tx)
+func (ei *ethereumTypeIndex) processContractTransfers(blockTx *ethBlockTx, tx *bchain.Tx, addresses addressesMap, addressContracts map[string]*AddrContracts) error {
+	tokenTransfers, err := ei.chainParser.EthereumTypeGetTokenTransfersFromTx(tx)
 	if err != nil {
 		glog.Warningf("rocksdb: processContractTransfers %v, tx %v", err, tx.Txid)
 	}
 	blockTx.contracts = make([]ethBlockTxContract, len(tokenTransfers))
 	for i, t := range tokenTransfers {
 		var contract, from, to bchain.AddressDescriptor
-		contract, err = d.chainParser.GetAddrDescFromAddress(t.Contract)
+		contract, err = ei.chainParser.GetAddrDescFromAddress(t.Contract)
 		if err == nil {
-			from, err = d.chainParser.GetAddrDescFromAddress(t.From)
+			from, err = ei.chainParser.GetAddrDescFromAddress(t.From)
 			if err == nil {
-				to, err = d.chainParser.GetAddrDescFromAddress(t.To)
+				to, err = ei.chainParser.GetAddrDescFromAddress(t.To)
 			}
 		}
 		if err != nil {
 			glog.Warningf("rocksdb: processContractTransfers %v, tx %v, transfer %v", err, tx.Txid, t)
 			continue
 		}
-		if err = d.addToAddressesAndContractsEthereumType(to, blockTx.btxID, int32(i), contract, t, true, addresses, addressContracts); err != nil {
+		if err = ei.addToAddressesAndContractsEthereumType(to, blockTx.btxID, int32(i), contract, t, true, addresses, addressContracts); err != nil {
 			return err
 		}
 		eq := bytes.Equal(from, to)
-		if err = d.addToAddressesAndContractsEthereumType(from, blockTx.btxID, ^int32(i), contract, t, !eq, addresses, addressContracts); err != nil {
+		if err = ei.addToAddressesAndContractsEthereumType(from, blockTx.btxID, ^int32(i), contract, t, !eq, addresses, addressContracts); err != nil {
 			return err
 		}
 		bc := &blockTx.contracts[i]
@@ -576,63 +600,74 @@ func (d *RocksDB) processContractTransfers(blockTx *ethBlockTx, tx *bchain.Tx, a
 	return nil
 }
 
-func (d *RocksDB) processAddressesEthereumType(block *bchain.Block, addresses addressesMap, addressContracts map[string]*AddrContracts) ([]ethBlockTx, error) {
+func (ei *ethereumTypeIndex) processAddressesEthereumType(block *bchain.Block, addresses addressesMap, addressContracts map[string]*AddrContracts) ([]ethBlockTx, error) {
 	blockTxs := make([]ethBlockTx, len(block.Txs))
 	for txi := range block.Txs {
 		tx := &block.Txs[txi]
-		btxID, err := d.chainParser.PackTxid(tx.Txid)
+		btxID, err := ei.chainParser.PackTxid(tx.Txid)
 		if err != nil {
 			return nil, err
 		}
 		blockTx := &blockTxs[txi]
 		blockTx.btxID = btxID
-		if err = d.processBaseTxData(blockTx, tx, addresses, addressContracts); err != nil {
+		if err = ei.processBaseTxData(blockTx, tx, addresses, addressContracts); err != nil {
 			return nil, err
 		}
 		// process internal data
 		eid, _ := tx.CoinSpecificData.(bchain.EthereumSpecificData)
 		if eid.InternalData != nil {
-			if err = d.processInternalData(blockTx, tx, eid.InternalData, addresses, addressContracts, false); err != nil {
+			if err = ei.processInternalData(blockTx, tx, eid.InternalData, addresses, addressContracts, false); err != nil {
 				return nil, err
 			}
 		}
 		// store contract transfers
-		if err = d.processContractTransfers(blockTx, tx, addresses, addressContracts); err != nil {
+		if err = ei.processContractTransfers(blockTx, tx, addresses, addressContracts); err != nil {
 			return nil, err
 		}
+		ei.processApprovals(blockTx, tx, block.Height)
 	}
+	ei.processContractLogs(blockTxs, block)
 	return blockTxs, nil
 }
 
-// ReconnectInternalDataToBlockEthereumType adds missing internal data to the block and stores them in db
-func (d *RocksDB) ReconnectInternalDataToBlockEthereumType(block *bchain.Block) error {
-	wb := grocksdb.NewWriteBatch()
-	defer wb.Destroy()
-	if d.chainParser.GetChainType() != bchain.ChainEthereumType {
-		return errors.New("Unsupported chain type")
-	}
-
-	addresses := make(addressesMap)
-	addressContracts := make(map[string]*AddrContracts)
-
-	// process internal data
+// reconnectInternalData processes the internal data of the transactions of the already indexed block
+func (ei *ethereumTypeIndex) reconnectInternalData(block *bchain.Block, addresses addressesMap, addressContracts map[string]*AddrContracts) ([]ethBlockTx, error) {
 	blockTxs := make([]ethBlockTx, len(block.Txs))
 	for txi := range block.Txs {
 		tx := &block.Txs[txi]
 		eid, _ := tx.CoinSpecificData.(bchain.EthereumSpecificData)
 		if eid.InternalData != nil {
-			btxID, err := d.chainParser.PackTxid(tx.Txid)
+			btxID, err := ei.chainParser.PackTxid(tx.Txid)
 			if err != nil {
-				return err
+				return nil, err
 			}
 			blockTx := &blockTxs[txi]
 			blockTx.btxID = btxID
 			tx.BlockHeight = block.Height
-			if err = d.processInternalData(blockTx, tx, eid.InternalData, addresses, addressContracts, true); err != nil {
-				return err
+			if err = ei.processInternalData(blockTx, tx, eid.InternalData, addresses, addressContracts, true); err != nil {
+				return nil, err
 			}
 		}
 	}
+	return blockTxs, nil
+}
+
+// ReconnectInternalDataToBlockEthereumType adds missing internal data to the block and stores them in db
+func (d *RocksDB) ReconnectInternalDataToBlockEthereumType(block *bchain.Block) error {
+	wb := grocksdb.NewWriteBatch()
+	defer wb.Destroy()
+	if d.chainParser.GetChainType() != bchain.ChainEthereumType {
+		return errors.New("Unsupported chain type")
+	}
+
+	addresses := make(addressesMap)
+	addressContracts := make(map[string]*AddrContracts)
+
+	// process internal data
+	blockTxs, err := d.ethereumTypeIndex().reconnectInternalData(block, addresses, addressContracts)
+	if err != nil {
+		return err
+	}
 
 	if err := d.storeAddressContracts(wb, addressContracts); err != nil {
 		return err
@@ -688,12 +723,16 @@ func packEthInternalData(data *ethInternalData) []byte {
 }
 
 func (d *RocksDB) unpackEthInternalData(buf []byte) (*bchain.EthereumInternalData, error) {
+	return d.ethereumTypeIndex().unpackEthInternalData(buf)
+}
+
+func (ei *ethereumTypeIndex) unpackEthInternalData(buf []byte) (*bchain.EthereumInternalData, error) {
 	id := bchain.EthereumInternalData{}
 	v, l := unpackVaruint(buf)
 	id.Type = bchain.EthereumInternalTransactionType(v & 1)
 	id.Transfers = make([]bchain.EthereumInternalTransfer, v>>1)
 	if id.Type == bchain.CREATE {
-		addresses, _, _ := d.chainParser.GetAddressesFromAddrDesc(buf[l : l+eth.EthereumTypeAddressDescriptorLen])
+		addresses, _, _ := ei.chainParser.GetAddressesFromAddrDesc(buf[l : l+eth.EthereumTypeAddressDescriptorLen])
 		l += eth.EthereumTypeAddressDescriptorLen
 		if len(addresses) > 0 {
 			id.Contract = addresses[0]
@@ -704,12 +743,12 @@ func (d *RocksDB) unpackEthInternalData(buf []byte) (*bchain.EthereumInternalDat
 		t := &id.Transfers[i]
 		t.Type = bchain.EthereumInternalTransactionType(buf[l])
 		l++
-		addresses, _, _ := d.chainParser.GetAddressesFromAddrDesc(buf[l : l+eth.EthereumTypeAddressDescriptorLen])
+		addresses, _, _ := ei.chainParser.GetAddressesFromAddrDesc(buf[l : l+eth.EthereumTypeAddressDescriptorLen])
 		l += eth.EthereumTypeAddressDescriptorLen
 		if len(addresses) > 0 {
 			t.From = addresses[0]
 		}
-		addresses, _, _ = d.chainParser.GetAddressesFromAddrDesc(buf[l : l+eth.EthereumTypeAddressDescriptorLen])
+		addresses, _, _ = ei.chainParser.GetAddressesFromAddrDesc(buf[l : l+eth.EthereumTypeAddressDescriptorLen])
 		l += eth.EthereumTypeAddressDescriptorLen
 		if len(addresses) > 0 {
 			t.To = addresses[0]
@@ -809,6 +848,76 @@ func (d *RocksDB) StoreFourByteSignature(wb *grocksdb.WriteBatch, fourBytes uint
 	return nil
 }
 
+// event signatures are stored in the same format as the 4byte signatures, under the key topic0+id
+func packEventSignatureKey(topic0 []byte, id uint32) []byte {
+	key := make([]byte, 0, len(topic0)+4)
+	key = append(key, topic0...)
+	key = append(key, packUint(id)...)
+	return key
+}
+
+// GetEventSignature gets the event signature of given topic0 and id
+func (d *RocksDB) GetEventSignature(topic0 []byte, id uint32) (*bchain.FourByteSignature, error) {
+	key := packEventSignatureKey(topic0, id)
+	val, err := d.db.GetCF(d.ro, d.cfh[cfEventSignatures], key)
+	if err != nil {
+		return nil, err
+	}
+	defer val.Free()
+	buf := val.Data()
+	if len(buf) == 0 {
+		return nil, nil
+	}
+	return unpackFourByteSignature(buf)
+}
+
+var cachedEventSignatures = make(map[string][]bchain.FourByteSignature)
+var cachedEventSignaturesMux sync.Mutex
+
+// GetEventSignatures gets all event signatures of given topic0
+// (there may be more signatures with the same hash in the signature database),
+// the returned signatures are a copy of the cached ones, they can be prepared for parsing by the caller
+func (d *RocksDB) GetEventSignatures(topic0 []byte) (*[]bchain.FourByteSignature, error) {
+	cachedEventSignaturesMux.Lock()
+	signatures, found := cachedEventSignatures[string(topic0)]
+	cachedEventSignaturesMux.Unlock()
+	if !found {
+		signatures = []bchain.FourByteSignature{}
+		it := d.db.NewIteratorCF(d.ro, d.cfh[cfEventSignatures])
+		defer it.Close()
+		for it.Seek(topic0); it.Valid(); it.Next() {
+			if !bytes.HasPrefix(it.Key().Data(), topic0) {
+				break
+			}
+			signature, err := unpackFourByteSignature(it.Value().Data())
+			if err != nil {
+				return nil, err
+			}
+			signatures = append(signatures, *signature)
+		}
+		cachedEventSignaturesMux.Lock()
+		cachedEventSignatures[string(topic0)] = signatures
+		cachedEventSignaturesMux.Unlock()
+	}
+	retval := append([]bchain.FourByteSignature{}, signatures...)
+	return &retval, nil
+}
+
+// StoreEventSignature stores the event signature to the write batch,
+// ClearCachedEventSignatures must be called after the write batch is written
+func (d *RocksDB) StoreEventSignature(wb *grocksdb.WriteBatch, topic0 []byte, id uint32, signature *bchain.FourByteSignature) error {
+	key := packEventSignatureKey(topic0, id)
+	wb.PutCF(d.cfh[cfEventSignatures], key, packFourByteSignature(signature))
+	return nil
+}
+
+// ClearCachedEventSignatures clears the cache of the event signatures, so that the stored signatures are read from DB
+func (d *RocksDB) ClearCachedEventSignatures() {
+	cachedEventSignaturesMux.Lock()
+	cachedEventSignatures = make(map[string][]bchain.FourByteSignature)
+	cachedEventSignaturesMux.Unlock()
+}
+
 // GetEthereumInternalData gets transaction internal data from DB
 func (d *RocksDB) GetEthereumInternalData(txid string) (*bchain.EthereumInternalData, error) {
 	btxID, err := d.chainParser.PackTxid(txid)
@@ -997,14 +1106,19 @@ func packBlockTx(buf []byte, blockTx *ethBlockTx) []byte {
 	return buf
 }
 
-func (d *RocksDB) storeAndCleanupBlockTxsEthereumType(wb *grocksdb.WriteBatch, block *bchain.Block, blockTxs []ethBlockTx) error {
-	pl := d.chainParser.PackedTxidLen()
-	buf := make([]byte, 0, (pl+2*eth.EthereumTypeAddressDescriptorLen)*len(blockTxs))
+func packBlockTxsEthereumType(blockTxs []ethBlockTx) []byte {
+	buf := make([]byte, 0, (eth.EthereumTypeTxidLen+2*eth.EthereumTypeAddressDescriptorLen)*len(blockTxs))
 	for i := range blockTxs {
 		buf = packBlockTx(buf, &blockTxs[i])
 	}
+	return buf
+}
+
+func (d *RocksDB) storeAndCleanupBlockTxsEthereumType(wb *grocksdb.WriteBatch, block *bchain.Block, blockTxs []ethBlockTx) error {
 	key := packUint(block.Height)
-	wb.PutCF(d.cfh[cfBlockTxs], key, buf)
+	wb.PutCF(d.cfh[cfBlockTxs], key, packBlockTxsEthereumType(blockTxs))
+	d.storeAndCleanupBlockApprovals(wb, block.Height, blockTxs)
+	d.storeAndCleanupBlockContractLogs(wb, block.Height, blockTxs)
 	return d.cleanupBlockTxs(wb, block)
 }
 
@@ -1025,6 +1139,16 @@ func (d *RocksDB) StoreBlockInternalDataErrorEthereumType(wb *grocksdb.WriteBatc
 	return nil
 }
 
+// UpdateBlockInternalDataErrorEthereumType stores the internal data error of the block with the number of retries
+func (d *RocksDB) UpdateBlockInternalDataErrorEthereumType(block *bchain.Block, message string, retryCount uint8) error {
+	wb := grocksdb.NewWriteBatch()
+	defer wb.Destroy()
+	if err := d.StoreBlockInternalDataErrorEthereumType(wb, block, message, retryCount); err != nil {
+		return err
+	}
+	return d.WriteBatch(wb)
+}
+
 type BlockInternalDataError struct {
 	Height       uint32
 	Hash         string
@@ -1178,9 +1302,14 @@ func (d *RocksDB) getBlockTxsEthereumType(height uint32) ([]ethBlockTx, error) {
 	if buf == nil {
 		return nil, nil
 	}
+	return unpackBlockTxsEthereumType(buf)
+}
+
+func unpackBlockTxsEthereumType(buf []byte) ([]ethBlockTx, error) {
 	// buf can be empty slice, this means the block did not contain any transactions
 	bt := make([]ethBlockTx, 0, 16)
 	var btx *ethBlockTx
+	var err error
 	for i := 0; i < len(buf); {
 		btx, i, err = unpackBlockTx(buf, i)
 		if err != nil {
@@ -1191,7 +1320,7 @@ func (d *RocksDB) getBlockTxsEthereumType(height uint32) ([]ethBlockTx, error) {
 	return bt, nil
 }
 
-func (d *RocksDB) disconnectAddress(btxID []byte, internal bool, addrDesc bchain.AddressDescriptor, btxContract *ethBlockTxContract, addresses map[string]map[string]struct{}, contracts map[string]*AddrContracts) error {
+func (ei *ethereumTypeIndex) disconnectAddress(btxID []byte, internal bool, addrDesc bchain.AddressDescriptor, btxContract *ethBlockTxContract, addresses map[string]map[string]struct{}, contracts map[string]*AddrContracts) error {
 	var err error
 	// do not process empty address
 	if len(addrDesc) == 0 {
@@ -1213,7 +1342,7 @@ func (d *RocksDB) disconnectAddress(btxID []byte, internal bool, addrDesc bchain
 	}
 	addrContracts, fc := contracts[s]
 	if !fc {
-		addrContracts, err = d.GetAddrDescContracts(addrDesc)
+		addrContracts, err = ei.getAddrDescContracts(addrDesc)
 		if err != nil {
 			return err
 		}
@@ -1279,37 +1408,37 @@ func (d *RocksDB) disconnectAddress(btxID []byte, internal bool, addrDesc bchain
 	return nil
 }
 
-func (d *RocksDB) disconnectInternalData(btxID []byte, addresses map[string]map[string]struct{}, contracts map[string]*AddrContracts) error {
-	internalData, err := d.getEthereumInternalData(btxID)
+func (ei *ethereumTypeIndex) disconnectInternalData(btxID []byte, addresses map[string]map[string]struct{}, contracts map[string]*AddrContracts) error {
+	internalData, err := ei.getEthereumInternalData(btxID)
 	if err != nil {
 		return err
 	}
 	if internalData != nil {
 		if internalData.Type == bchain.CREATE {
-			contract, err := d.chainParser.GetAddrDescFromAddress(internalData.Contract)
+			contract, err := ei.chainParser.GetAddrDescFromAddress(internalData.Contract)
 			if err != nil {
 				return err
 			}
-			if err := d.disconnectAddress(btxID, true, contract, nil, addresses, contracts); err != nil {
+			if err := ei.disconnectAddress(btxID, true, contract, nil, addresses, contracts); err != nil {
 				return err
 			}
 		}
 		for j := range internalData.Transfers {
 			t := &internalData.Transfers[j]
 			var from, to bchain.AddressDescriptor
-			from, err = d.chainParser.GetAddrDescFromAddress(t.From)
+			from, err = ei.chainParser.GetAddrDescFromAddress(t.From)
 			if err == nil {
-				to, err = d.chainParser.GetAddrDescFromAddress(t.To)
+				to, err = ei.chainParser.GetAddrDescFromAddress(t.To)
 			}
 			if err != nil {
 				return err
 			}
-			if err := d.disconnectAddress(btxID, true, from, nil, addresses, contracts); err != nil {
+			if err := ei.disconnectAddress(btxID, true, from, nil, addresses, contracts); err != nil {
 				return err
 			}
 			// if from==to, tx is counted only once and does not have to be disconnected again
 			if !bytes.Equal(from, to) {
-				if err := d.disconnectAddress(btxID, true, to, nil, addresses, contracts); err != nil {
+				if err := ei.disconnectAddress(btxID, true, to, nil, addresses, contracts); err != nil {
 					return err
 				}
 			}
@@ -1318,46 +1447,61 @@ func (d *RocksDB) disconnectInternalData(btxID []byte, addresses map[string]map[
 	return nil
 }
 
-func (d *RocksDB) disconnectBlockTxsEthereumType(wb *grocksdb.WriteBatch, height uint32, blockTxs []ethBlockTx, contracts map[string]*AddrContracts) error {
+// disconnectBlockTxs reverts the changes of the address contracts by the transactions of the block,
+// it returns the addresses of the block with the disconnected transactions
+func (ei *ethereumTypeIndex) disconnectBlockTxs(height uint32, blockTxs []ethBlockTx, contracts map[string]*AddrContracts) (map[string]map[string]struct{}, error) {
 	glog.Info("Disconnecting block ", height, " containing ", len(blockTxs), " transactions")
 	addresses := make(map[string]map[string]struct{})
 	for i := range blockTxs {
 		blockTx := &blockTxs[i]
-		if err := d.disconnectAddress(blockTx.btxID, false, blockTx.from, nil, addresses, contracts); err != nil {
-			return err
+		if err := ei.disconnectAddress(blockTx.btxID, false, blockTx.from, nil, addresses, contracts); err != nil {
+			return nil, err
 		}
 		// if from==to, tx is counted only once and does not have to be disconnected again
 		if !bytes.Equal(blockTx.from, blockTx.to) {
-			if err := d.disconnectAddress(blockTx.btxID, false, blockTx.to, nil, addresses, contracts); err != nil {
-				return err
+			if err := ei.disconnectAddress(blockTx.btxID, false, blockTx.to, nil, addresses, contracts); err != nil {
+				return nil, err
 			}
 		}
 		// internal data
-		err := d.disconnectInternalData(blockTx.btxID, addresses, contracts)
+		err := ei.disconnectInternalData(blockTx.btxID, addresses, contracts)
 		if err != nil {
-			return err
+			return nil, err
 
 		}
 		// contracts
 		for j := range blockTx.contracts {
 			c := &blockTx.contracts[j]
-			if err := d.disconnectAddress(blockTx.btxID, false, c.from, c, addresses, contracts); err != nil {
-				return err
+			if err := ei.disconnectAddress(blockTx.btxID, false, c.from, c, addresses, contracts); err != nil {
+				return nil, err
 			}
 			if !bytes.Equal(c.from, c.to) {
-				if err := d.disconnectAddress(blockTx.btxID, false, c.to, c, addresses, contracts); err != nil {
-					return err
+				if err := ei.disconnectAddress(blockTx.btxID, false, c.to, c, addresses, contracts); err != nil {
+					return nil, err
 				}
 			}
 		}
-		wb.DeleteCF(d.cfh[cfTransactions], blockTx.btxID)
-		wb.DeleteCF(d.cfh[cfInternalData], blockTx.btxID)
+	}
+	return addresses, nil
+}
+
+func (d *RocksDB) disconnectBlockTxsEthereumType(wb *grocksdb.WriteBatch, height uint32, blockTxs []ethBlockTx, contracts map[string]*AddrContracts) error {
+	addresses, err := d.ethereumTypeIndex().disconnectBlockTxs(height, blockTxs, contracts)
+	if err != nil {
+		return err
+	}
+	for i := range blockTxs {
+		wb.DeleteCF(d.cfh[cfTransactions], blockTxs[i].btxID)
+		wb.DeleteCF(d.cfh[cfInternalData], blockTxs[i].btxID)
 	}
 	for a := range addresses {
 		key := packAddressKey([]byte(a), height)
 		wb.DeleteCF(d.cfh[cfAddresses], key)
 	}
-	return nil
+	if err := d.disconnectApprovals(wb, height); err != nil {
+		return err
+	}
+	return d.disconnectContractLogs(wb, height)
 }
 
 // DisconnectBlockRangeEthereumType removes all data belonging to blocks in range lower-higher
@@ -383,6 +383,64 @@ func testFourByteSignature(t *testing.T, d *RocksDB) {
 	}
 }
 
+func testEventSignature(t *testing.T, d *RocksDB) {
+	topic0, _ := hex.DecodeString("ddf252ad1be2c89b69c2b068fc378daa952ba7f163c4a11628f55a4df523b3ef")
+	id := uint32(1)
+	signature := bchain.FourByteSignature{
+		Name:       "Transfer",
+		Parameters: []string{"address", "address", "uint256"},
+	}
+	// the signatures are cached, the stored signature is returned after the cache is cleared
+	gotSlice, err := d.GetEventSignatures(topic0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(*gotSlice) != 0 {
+		t.Errorf("testEventSignature: before store got %+v", *gotSlice)
+	}
+	wb := grocksdb.NewWriteBatch()
+	defer wb.Destroy()
+	if err := d.StoreEventSignature(wb, topic0, id, &signature); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.WriteBatch(wb); err != nil {
+		t.Fatal(err)
+	}
+	if gotSlice, _ = d.GetEventSignatures(topic0); len(*gotSlice) != 0 {
+		t.Errorf("testEventSignature: before the cache is cleared got %+v", *gotSlice)
+	}
+	d.ClearCachedEventSignatures()
+	got, err := d.GetEventSignature(topic0, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(*got, signature) {
+		t.Errorf("testEventSignature: got %+v, want %+v", got, signature)
+	}
+	gotSlice, err = d.GetEventSignatures(topic0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(*gotSlice, []bchain.FourByteSignature{signature}) {
+		t.Errorf("testEventSignature: got %+v, want %+v", *gotSlice, []bchain.FourByteSignature{signature})
+	}
+	// the returned signatures are a copy, their preparation for parsing does not change the cache
+	(*gotSlice)[0].DecamelName = "transfer"
+	if gotSlice, _ = d.GetEventSignatures(topic0); (*gotSlice)[0].DecamelName != "" {
+		t.Errorf("testEventSignature: cached signature modified %+v", *gotSlice)
+	}
+	// the signatures of other topics are not returned
+	other := append([]byte(nil), topic0...)
+	other[31]++
+	gotSlice, err = d.GetEventSignatures(other)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(*gotSlice) != 0 {
+		t.Errorf("testEventSignature: other topic got %+v", *gotSlice)
+	}
+}
+
 // TestRocksDB_Index_EthereumType is an integration test probing the whole indexing functionality for EthereumType chains
 // It does the following:
 // 1) Connect two blocks (inputs from 2nd block are spending some outputs from the 1st block)
@@ -397,6 +455,8 @@ func TestRocksDB_Index_EthereumType(t *testing.T) {
 		EthereumParser: ethereumTestnetParser(),
 	})
 	defer closeAndDestroyRocksDB(t, d)
+	// the same changes are applied to MemoryStore, which must contain the same data
+	s := newMemoryStoreMirror(t, d)
 
 	if len(d.is.BlockTimes) != 0 {
 		t.Fatal("Expecting is.BlockTimes 0, got ", len(d.is.BlockTimes))
@@ -404,7 +464,7 @@ func TestRocksDB_Index_EthereumType(t *testing.T) {
 
 	// connect 1st block
 	block1 := dbtestdata.GetTestEthereumTypeBlock1(d.chainParser)
-	if err := d.ConnectBlock(block1); err != nil {
+	if err := s.ConnectBlock(block1); err != nil {
 		t.Fatal(err)
 	}
 	verifyAfterEthereumTypeBlock1(t, d, false)
@@ -415,7 +475,7 @@ func TestRocksDB_Index_EthereumType(t *testing.T) {
 
 	// connect 2nd block, simulate InternalDataError and AddressAlias
 	block2 := dbtestdata.GetTestEthereumTypeBlock2(d.chainParser)
-	if err := d.ConnectBlock(block2); err != nil {
+	if err := s.ConnectBlock(block2); err != nil {
 		t.Fatal(err)
 	}
 	verifyAfterEthereumTypeBlock2(t, d, true)
@@ -504,6 +564,7 @@ func TestRocksDB_Index_EthereumType(t *testing.T) {
 
 	// Test to store and get FourByteSignature
 	testFourByteSignature(t, d)
+	testEventSignature(t, d)
 
 	// Test tx caching functionality, leave one tx in db to test cleanup in DisconnectBlock
 	testTxCache(t, d, block1, &block1.Txs[0])
@@ -516,10 +577,10 @@ func TestRocksDB_Index_EthereumType(t *testing.T) {
 	// restore InternalData
 	esd.InternalData = eid
 	block2.Txs[0].CoinSpecificData = esd
-	if err = d.PutTx(&block2.Txs[1], block2.Height, block2.Txs[1].Blocktime); err != nil {
+	if err = s.PutTx(&block2.Txs[1], block2.Height, block2.Txs[1].Blocktime); err != nil {
 		t.Fatal(err)
 	}
-	if err = d.PutTx(&block2.Txs[1], block2.Height, block2.Txs[1].Blocktime); err != nil {
+	if err = s.PutTx(&block2.Txs[1], block2.Height, block2.Txs[1].Blocktime); err != nil {
 		t.Fatal(err)
 	}
 	// check that there is only the last tx in the cache
@@ -532,7 +593,7 @@ func TestRocksDB_Index_EthereumType(t *testing.T) {
 		}
 	}
 	// try to disconnect both blocks, however only the last one is kept, it is not possible
-	err = d.DisconnectBlockRangeEthereumType(4321000, 4321001)
+	err = s.DisconnectBlockRangeEthereumType(4321000, 4321001)
 	if err == nil || err.Error() != "Cannot disconnect blocks with height 4321000 and lower. It is necessary to rebuild index." {
 		t.Fatal(err)
 	}
@@ -540,7 +601,7 @@ func TestRocksDB_Index_EthereumType(t *testing.T) {
 
 	// disconnect the 2nd block, verify that the db contains only data from the 1st block with restored unspentTxs
 	// and that the cached tx is removed
-	err = d.DisconnectBlockRangeEthereumType(4321001, 4321001)
+	err = s.DisconnectBlockRangeEthereumType(4321001, 4321001)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -556,7 +617,7 @@ func TestRocksDB_Index_EthereumType(t *testing.T) {
 	}
 
 	// connect block again and verify the state of db
-	if err := d.ConnectBlock(block2); err != nil {
+	if err := s.ConnectBlock(block2); err != nil {
 		t.Fatal(err)
 	}
 	verifyAfterEthereumTypeBlock2(t, d, false)
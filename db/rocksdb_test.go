@@ -535,6 +535,8 @@ func TestRocksDB_Index_BitcoinType(t *testing.T) {
 		BitcoinParser: bitcoinTestnetParser(),
 	})
 	defer closeAndDestroyRocksDB(t, d)
+	// the same changes are applied to MemoryStore, which must contain the same data
+	s := newMemoryStoreMirror(t, d)
 
 	if len(d.is.BlockTimes) != 0 {
 		t.Fatal("Expecting is.BlockTimes 0, got ", len(d.is.BlockTimes))
@@ -542,7 +544,7 @@ func TestRocksDB_Index_BitcoinType(t *testing.T) {
 
 	// connect 1st block - will log warnings about missing UTXO transactions in txAddresses column
 	block1 := dbtestdata.GetTestBitcoinTypeBlock1(d.chainParser)
-	if err := d.ConnectBlock(block1); err != nil {
+	if err := s.ConnectBlock(block1); err != nil {
 		t.Fatal(err)
 	}
 	verifyAfterBitcoinTypeBlock1(t, d, false)
@@ -553,7 +555,7 @@ func TestRocksDB_Index_BitcoinType(t *testing.T) {
 
 	// connect 2nd block - use some outputs from the 1st block as the inputs and 1 input uses tx from the same block
 	block2 := dbtestdata.GetTestBitcoinTypeBlock2(d.chainParser)
-	if err := d.ConnectBlock(block2); err != nil {
+	if err := s.ConnectBlock(block2); err != nil {
 		t.Fatal(err)
 	}
 	verifyAfterBitcoinTypeBlock2(t, d)
@@ -634,7 +636,7 @@ func TestRocksDB_Index_BitcoinType(t *testing.T) {
 	// Test tx caching functionality, leave one tx in db to test cleanup in DisconnectBlock
 	testTxCache(t, d, block1, &block1.Txs[0])
 	testTxCache(t, d, block2, &block2.Txs[0])
-	if err = d.PutTx(&block2.Txs[1], block2.Height, block2.Txs[1].Blocktime); err != nil {
+	if err = s.PutTx(&block2.Txs[1], block2.Height, block2.Txs[1].Blocktime); err != nil {
 		t.Fatal(err)
 	}
 	// check that there is only the last tx in the cache
@@ -648,7 +650,7 @@ func TestRocksDB_Index_BitcoinType(t *testing.T) {
 	}
 
 	// try to disconnect both blocks, however only the last one is kept, it is not possible
-	err = d.DisconnectBlockRangeBitcoinType(225493, 225494)
+	err = s.DisconnectBlockRangeBitcoinType(225493, 225494)
 	if err == nil || err.Error() != "Cannot disconnect blocks with height 225493 and lower. It is necessary to rebuild index." {
 		t.Fatal(err)
 	}
@@ -656,7 +658,7 @@ func TestRocksDB_Index_BitcoinType(t *testing.T) {
 
 	// disconnect the 2nd block, verify that the db contains only data from the 1st block with restored unspentTxs
 	// and that the cached tx is removed
-	err = d.DisconnectBlockRangeBitcoinType(225494, 225494)
+	err = s.DisconnectBlockRangeBitcoinType(225494, 225494)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -672,7 +674,7 @@ func TestRocksDB_Index_BitcoinType(t *testing.T) {
 	}
 
 	// connect block again and verify the state of db
-	if err := d.ConnectBlock(block2); err != nil {
+	if err := s.ConnectBlock(block2); err != nil {
 		t.Fatal(err)
 	}
 	verifyAfterBitcoinTypeBlock2(t, d)
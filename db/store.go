@@ -0,0 +1,53 @@
+package db
+
+import (
+	"github.com/trezor/blockbook/bchain"
+	"github.com/trezor/blockbook/bchain/coins/eth"
+	"github.com/trezor/blockbook/common"
+)
+
+// Store defines the index operations used by the api worker and the sync worker.
+// It is implemented by RocksDB and by MemoryStore, which keeps the index in memory and is intended for tests.
+type Store interface {
+	// blocks
+	GetBestBlock() (uint32, string, error)
+	GetBlockHash(height uint32) (string, error)
+	GetBlockInfo(height uint32) (*BlockInfo, error)
+	ConnectBlock(block *bchain.Block) error
+	DisconnectBlockRangeBitcoinType(lower uint32, higher uint32) error
+	DisconnectBlockRangeEthereumType(lower uint32, higher uint32) error
+	// addresses and transactions
+	GetTransactions(address string, lower uint32, higher uint32, fn GetTransactionsCallback) error
+	GetAddrDescTransactions(addrDesc bchain.AddressDescriptor, lower uint32, higher uint32, fn GetTransactionsCallback) error
+	GetAddrDescBalance(addrDesc bchain.AddressDescriptor, detail AddressBalanceDetail) (*AddrBalance, error)
+	GetTxAddresses(txid string) (*TxAddresses, error)
+	GetTx(txid string) (*bchain.Tx, uint32, error)
+	PutTx(tx *bchain.Tx, height uint32, blockTime int64) error
+	GetBlockFilter(blockHash string) (string, error)
+	HasExtendedIndex() bool
+	Snapshot() (Store, func())
+	// EthereumType specific
+	GetAddrDescContracts(addrDesc bchain.AddressDescriptor) (*AddrContracts, error)
+	GetAddrDescApprovals(owner bchain.AddressDescriptor) ([]TokenApproval, error)
+	GetContractLogs(contract bchain.AddressDescriptor, topic0 []byte, lower, higher uint32, fn GetContractLogsCallback) error
+	GetContractInfo(contract bchain.AddressDescriptor, typeFromContext bchain.TokenTypeName) (*bchain.ContractInfo, error)
+	GetContractInfoForAddress(address string) (*bchain.ContractInfo, error)
+	StoreContractInfo(contractInfo *bchain.ContractInfo) error
+	GetEthereumInternalData(txid string) (*bchain.EthereumInternalData, error)
+	GetFourByteSignatures(fourBytes uint32) (*[]bchain.FourByteSignature, error)
+	GetEventSignatures(topic0 []byte) (*[]bchain.FourByteSignature, error)
+	GetContractABI(contract bchain.AddressDescriptor) (*eth.ContractABI, error)
+	GetAddressAlias(address string) string
+	GetBlockInternalDataErrorsEthereumType() ([]BlockInternalDataError, error)
+	UpdateBlockInternalDataErrorEthereumType(block *bchain.Block, message string, retryCount uint8) error
+	ReconnectInternalDataToBlockEthereumType(block *bchain.Block) error
+	// fiat rates
+	FiatRatesFindLastTicker(vsCurrency string, token string) (*common.CurrencyRatesTicker, error)
+	// statistics
+	DatabaseSizeOnDisk() int64
+	GetMemoryStats() string
+	GetAndResetConnectBlockStats() string
+}
+
+var _ Store = (*RocksDB)(nil)
+var _ Store = (*MemoryStore)(nil)
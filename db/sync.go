@@ -14,7 +14,7 @@ import (
 
 // SyncWorker is handle to SyncWorker
 type SyncWorker struct {
-	db                     *RocksDB
+	db                     Store
 	chain                  bchain.BlockChain
 	syncWorkers, syncChunk int
 	dryRun                 bool
@@ -26,7 +26,7 @@ type SyncWorker struct {
 }
 
 // NewSyncWorker creates new SyncWorker and returns its handle
-func NewSyncWorker(db *RocksDB, chain bchain.BlockChain, syncWorkers, syncChunk int, minStartHeight int, dryRun bool, chanOsSignal chan os.Signal, metrics *common.Metrics, is *common.InternalState) (*SyncWorker, error) {
+func NewSyncWorker(db Store, chain bchain.BlockChain, syncWorkers, syncChunk int, minStartHeight int, dryRun bool, chanOsSignal chan os.Signal, metrics *common.Metrics, is *common.InternalState) (*SyncWorker, error) {
 	if minStartHeight < 0 {
 		minStartHeight = 0
 	}
@@ -77,11 +77,12 @@ func (w *SyncWorker) updateBackendInfo() {
 
 // ResyncIndex synchronizes index to the top of the blockchain
 // onNewBlock is called when new block is connected, but not in initial parallel sync
-func (w *SyncWorker) ResyncIndex(onNewBlock bchain.OnNewBlockFunc, initialSync bool) error {
+// onDisconnectBlocks is called when blocks are disconnected because of a fork
+func (w *SyncWorker) ResyncIndex(onNewBlock bchain.OnNewBlockFunc, onDisconnectBlocks bchain.OnDisconnectBlocksFunc, initialSync bool) error {
 	start := time.Now()
 	w.is.StartedSync()
 
-	err := w.resyncIndex(onNewBlock, initialSync)
+	err := w.resyncIndex(onNewBlock, onDisconnectBlocks, initialSync)
 
 	// update backend info after each resync
 	w.updateBackendInfo()
@@ -115,7 +116,7 @@ func (w *SyncWorker) ResyncIndex(onNewBlock bchain.OnNewBlockFunc, initialSync b
 	return err
 }
 
-func (w *SyncWorker) resyncIndex(onNewBlock bchain.OnNewBlockFunc, initialSync bool) error {
+func (w *SyncWorker) resyncIndex(onNewBlock bchain.OnNewBlockFunc, onDisconnectBlocks bchain.OnDisconnectBlocksFunc, initialSync bool) error {
 	remoteBestHash, err := w.chain.GetBestBlockHash()
 	if err != nil {
 		return err
@@ -138,7 +139,7 @@ func (w *SyncWorker) resyncIndex(onNewBlock bchain.OnNewBlockFunc, initialSync b
 		if remoteHash != localBestHash {
 			// forked - the remote hash differs from the local hash at the same height
 			glog.Info("resync: local is forked at height ", localBestHeight, ", local hash ", localBestHash, ", remote hash ", remoteHash)
-			return w.handleFork(localBestHeight, localBestHash, onNewBlock, initialSync)
+			return w.handleFork(localBestHeight, localBestHash, onNewBlock, onDisconnectBlocks, initialSync)
 		}
 		glog.Info("resync: local at ", localBestHeight, " is behind")
 		w.startHeight = localBestHeight + 1
@@ -153,7 +154,8 @@ func (w *SyncWorker) resyncIndex(onNewBlock bchain.OnNewBlockFunc, initialSync b
 	// if parallel operation is enabled and the number of blocks to be connected is large,
 	// use parallel routine to load majority of blocks
 	// use parallel sync only in case of initial sync because it puts the db to inconsistent state
-	if w.syncWorkers > 1 && initialSync {
+	// the parallel sync uses BulkConnect, which is implemented only by RocksDB
+	if _, isRocksDB := w.db.(*RocksDB); isRocksDB && w.syncWorkers > 1 && initialSync {
 		remoteBestHeight, err := w.chain.GetBestBlockHeight()
 		if err != nil {
 			return err
@@ -170,17 +172,17 @@ func (w *SyncWorker) resyncIndex(onNewBlock bchain.OnNewBlockFunc, initialSync b
 			}
 			// after parallel load finish the sync using standard way,
 			// new blocks may have been created in the meantime
-			return w.resyncIndex(onNewBlock, initialSync)
+			return w.resyncIndex(onNewBlock, onDisconnectBlocks, initialSync)
 		}
 	}
 	err = w.connectBlocks(onNewBlock, initialSync)
 	if err == errFork {
-		return w.resyncIndex(onNewBlock, initialSync)
+		return w.resyncIndex(onNewBlock, onDisconnectBlocks, initialSync)
 	}
 	return err
 }
 
-func (w *SyncWorker) handleFork(localBestHeight uint32, localBestHash string, onNewBlock bchain.OnNewBlockFunc, initialSync bool) error {
+func (w *SyncWorker) handleFork(localBestHeight uint32, localBestHash string, onNewBlock bchain.OnNewBlockFunc, onDisconnectBlocks bchain.OnDisconnectBlocksFunc, initialSync bool) error {
 	// find forked blocks, disconnect them and then synchronize again
 	var height uint32
 	hashes := []string{localBestHash}
@@ -205,7 +207,10 @@ func (w *SyncWorker) handleFork(localBestHeight uint32, localBestHash string, on
 	if err := w.DisconnectBlocks(height+1, localBestHeight, hashes); err != nil {
 		return err
 	}
-	return w.resyncIndex(onNewBlock, initialSync)
+	if onDisconnectBlocks != nil {
+		onDisconnectBlocks(height+1, localBestHeight, hashes)
+	}
+	return w.resyncIndex(onNewBlock, onDisconnectBlocks, initialSync)
 }
 
 func (w *SyncWorker) connectBlocks(onNewBlock bchain.OnNewBlockFunc, initialSync bool) error {
@@ -271,8 +276,23 @@ func (w *SyncWorker) connectBlocks(onNewBlock bchain.OnNewBlockFunc, initialSync
 	return nil
 }
 
+// initBulkConnect starts the bulk connect of blocks, it is supported only if the store is RocksDB
+func (w *SyncWorker) initBulkConnect() (*BulkConnect, error) {
+	d, ok := w.db.(*RocksDB)
+	if !ok {
+		return nil, errors.New("BulkConnect is supported only by RocksDB")
+	}
+	return d.InitBulkConnect()
+}
+
 // ConnectBlocksParallel uses parallel goroutines to get data from blockchain daemon
 func (w *SyncWorker) ConnectBlocksParallel(lower, higher uint32) error {
+	if _, ok := w.db.(*RocksDB); !ok {
+		return errors.New("ConnectBlocksParallel is supported only by RocksDB")
+	}
+	if w.chain.GetChainParser().GetChainType() == bchain.ChainEthereumType {
+		return w.connectBlocksParallelEthereumType(lower, higher)
+	}
 	type hashHeight struct {
 		hash   string
 		height uint32
@@ -290,7 +310,7 @@ func (w *SyncWorker) ConnectBlocksParallel(lower, higher uint32) error {
 	terminating := make(chan struct{})
 	writeBlockWorker := func() {
 		defer close(writeBlockDone)
-		bc, err := w.db.InitBulkConnect()
+		bc, err := w.initBulkConnect()
 		if err != nil {
 			glog.Error("sync: InitBulkConnect error ", err)
 		}
@@ -446,6 +466,41 @@ func (w *SyncWorker) getBlockChain(out chan blockResult, done chan struct{}) {
 	}
 }
 
+// Rollback disconnects the blocks from the given height up to the best block of the index
+// onDisconnectBlocks is called after the blocks are disconnected
+func (w *SyncWorker) Rollback(height uint32, onDisconnectBlocks bchain.OnDisconnectBlocksFunc) error {
+	bestHeight, bestHash, err := w.db.GetBestBlock()
+	if err != nil {
+		return err
+	}
+	if height > bestHeight {
+		glog.Infof("rollback: nothing to rollback, height %d, best height %d", height, bestHeight)
+		return nil
+	}
+	hashes := []string{bestHash}
+	for h := bestHeight - 1; h >= height && h < bestHeight; h-- {
+		hash, err := w.db.GetBlockHash(h)
+		if err != nil {
+			return err
+		}
+		hashes = append(hashes, hash)
+	}
+	w.is.StartedSync()
+	if err = w.DisconnectBlocks(height, bestHeight, hashes); err != nil {
+		return err
+	}
+	if onDisconnectBlocks != nil {
+		onDisconnectBlocks(height, bestHeight, hashes)
+	}
+	// the index is not synchronized until the next resync
+	if bestHeight, _, err = w.db.GetBestBlock(); err != nil {
+		return err
+	}
+	w.is.UpdateBestHeight(bestHeight)
+	w.metrics.BlockbookBestHeight.Set(float64(bestHeight))
+	return nil
+}
+
 // DisconnectBlocks removes all data belonging to blocks in range lower-higher,
 func (w *SyncWorker) DisconnectBlocks(lower uint32, higher uint32, hashes []string) error {
 	glog.Infof("sync: disconnecting blocks %d-%d", lower, higher)
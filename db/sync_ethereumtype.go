@@ -0,0 +1,212 @@
+package db
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/trezor/blockbook/bchain"
+	"github.com/trezor/blockbook/common"
+)
+
+// number of blocks fetched by one batched request in the parallel sync of EthereumType coins
+const ethereumSyncBatchSize = 10
+
+// ethereumSyncBatch is a range of blocks passing through the stages of the parallel sync
+type ethereumSyncBatch struct {
+	index         int
+	lower, higher uint32
+	blocks        []*bchain.EthereumStagedBlock
+	// number of the logs and internal data stages not finished yet, the last one hands the batch to the writer
+	pending int32
+}
+
+// connectBlocksParallelEthereumType fetches the blocks in a pipeline of independent worker pools.
+// The first pool fetches the block headers and transactions, the logs and the internal data (traces)
+// of the fetched blocks are then fetched concurrently by two other pools. All pools use batched requests.
+// The finished batches are connected in the order of heights by BulkConnect.
+func (w *SyncWorker) connectBlocksParallelEthereumType(lower, higher uint32) error {
+	var err error
+	workers := w.syncWorkers
+	terminating := make(chan struct{})
+	// limit the number of batches in the pipeline, the batches finished out of order wait for the writer in memory
+	inFlight := make(chan struct{}, 4*workers)
+	bodyCh := make(chan *ethereumSyncBatch, workers)
+	logsCh := make(chan *ethereumSyncBatch, workers)
+	internalDataCh := make(chan *ethereumSyncBatch, workers)
+	doneCh := make(chan *ethereumSyncBatch, workers)
+	isTerminating := func() bool {
+		select {
+		case <-terminating:
+			return true
+		default:
+			return false
+		}
+	}
+	// retry calls the stage until it succeeds, returns false if the sync is terminating
+	retry := func(stage string, i int, bt *ethereumSyncBatch, f func() error) bool {
+		for {
+			err := f()
+			if err == nil {
+				return true
+			}
+			glog.Error(stage, " worker ", i, " blocks ", bt.lower, "-", bt.higher, " error ", err, ". Retrying...")
+			w.metrics.IndexResyncErrors.With(common.Labels{"error": "failure"}).Inc()
+			select {
+			case <-terminating:
+				return false
+			case <-time.After(time.Millisecond * 500):
+			}
+		}
+	}
+	send := func(ch chan *ethereumSyncBatch, bt *ethereumSyncBatch) bool {
+		select {
+		case ch <- bt:
+			return true
+		case <-terminating:
+			return false
+		}
+	}
+	var bodyWg, stagesWg sync.WaitGroup
+	bodyWorker := func(i int) {
+		defer bodyWg.Done()
+		for bt := range bodyCh {
+			if isTerminating() {
+				break
+			}
+			if !retry("getBlockBodies", i, bt, func() error {
+				var err error
+				bt.blocks, err = w.chain.EthereumTypeGetBlockBodies(bt.lower, bt.higher)
+				return err
+			}) {
+				break
+			}
+			bt.pending = 2
+			if !send(logsCh, bt) || !send(internalDataCh, bt) {
+				break
+			}
+		}
+		glog.Info("getBlockBodies worker ", i, " exiting...")
+	}
+	stageWorker := func(stage string, i int, ch chan *ethereumSyncBatch, f func([]*bchain.EthereumStagedBlock) error) {
+		defer stagesWg.Done()
+		for bt := range ch {
+			if isTerminating() {
+				break
+			}
+			if !retry(stage, i, bt, func() error { return f(bt.blocks) }) {
+				break
+			}
+			if atomic.AddInt32(&bt.pending, -1) == 0 && !send(doneCh, bt) {
+				break
+			}
+		}
+		glog.Info(stage, " worker ", i, " exiting...")
+	}
+	writeBlockDone := make(chan struct{})
+	writeBlockWorker := func() {
+		defer close(writeBlockDone)
+		bc, err := w.initBulkConnect()
+		if err != nil {
+			glog.Error("sync: InitBulkConnect error ", err)
+		}
+		keep := uint32(w.chain.GetChainParser().KeepBlockAddresses())
+		waiting := make(map[int]*ethereumSyncBatch)
+		next := 0
+		lastBlock := lower - 1
+		start := time.Now()
+		msTime := time.Now().Add(1 * time.Minute)
+	WriteBlockLoop:
+		for {
+			select {
+			case bt, ok := <-doneCh:
+				if !ok {
+					break WriteBlockLoop
+				}
+				waiting[bt.index] = bt
+				for bt = waiting[next]; bt != nil; bt = waiting[next] {
+					delete(waiting, next)
+					next++
+					for _, sb := range bt.blocks {
+						if sb.Height != lastBlock+1 {
+							glog.Fatal("writeBlockWorker skipped block, expected block ", lastBlock+1, ", new block ", sb.Height)
+						}
+						if !w.dryRun {
+							b, err := w.chain.EthereumTypeAssembleBlock(sb)
+							if err != nil {
+								glog.Fatal("writeBlockWorker ", sb.Height, " ", sb.Hash, " error ", err)
+							}
+							if err = bc.ConnectBlock(b, b.Height+keep > higher); err != nil {
+								glog.Fatal("writeBlockWorker ", b.Height, " ", b.Hash, " error ", err)
+							}
+						}
+						lastBlock = sb.Height
+						if lastBlock > 0 && lastBlock%1000 == 0 {
+							w.metrics.BlockbookBestHeight.Set(float64(lastBlock))
+							glog.Info("connected block ", lastBlock, " ", sb.Hash, ", elapsed ", time.Since(start), " ", w.db.GetAndResetConnectBlockStats())
+							start = time.Now()
+						}
+					}
+					// release the memory of the batch before taking the next one
+					bt.blocks = nil
+					<-inFlight
+				}
+				if msTime.Before(time.Now()) {
+					if glog.V(1) {
+						glog.Info(w.db.GetMemoryStats())
+					}
+					w.metrics.IndexDBSize.Set(float64(w.db.DatabaseSizeOnDisk()))
+					msTime = time.Now().Add(10 * time.Minute)
+				}
+			case <-terminating:
+				break WriteBlockLoop
+			}
+		}
+		err = bc.Close()
+		if err != nil {
+			glog.Error("sync: bulkconnect.Close error ", err)
+		}
+		glog.Info("WriteBlock exiting...")
+	}
+	for i := 0; i < workers; i++ {
+		bodyWg.Add(1)
+		go bodyWorker(i)
+		stagesWg.Add(2)
+		go stageWorker("getBlockLogs", i, logsCh, w.chain.EthereumTypeGetBlockLogs)
+		go stageWorker("getBlockInternalData", i, internalDataCh, w.chain.EthereumTypeGetBlockInternalData)
+	}
+	go writeBlockWorker()
+	index := 0
+ConnectLoop:
+	for h := lower; h <= higher; {
+		select {
+		case <-w.chanOsSignal:
+			glog.Info("connectBlocksParallel interrupted at height ", h)
+			err = ErrOperationInterrupted
+			// signal all workers to terminate their loops
+			close(terminating)
+			break ConnectLoop
+		case inFlight <- struct{}{}:
+			to := higher
+			if higher-h >= ethereumSyncBatchSize {
+				to = h + ethereumSyncBatchSize - 1
+			}
+			bodyCh <- &ethereumSyncBatch{index: index, lower: h, higher: to}
+			index++
+			if to == higher {
+				break ConnectLoop
+			}
+			h = to + 1
+		}
+	}
+	// close the channels stage by stage, the writer loop stops after the last batch is connected
+	close(bodyCh)
+	bodyWg.Wait()
+	close(logsCh)
+	close(internalDataCh)
+	stagesWg.Wait()
+	close(doneCh)
+	<-writeBlockDone
+	return err
+}
@@ -0,0 +1,152 @@
+//go:build unittest
+
+package db
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/trezor/blockbook/bchain"
+	"github.com/trezor/blockbook/common"
+)
+
+type testStagedData struct {
+	logs, internalData bool
+}
+
+// testStagesChain serves empty blocks by the stages of the parallel sync, the logs of the lower batches
+// are fetched slower so that the batches finish out of order, the internal data of one batch fail once
+type testStagesChain struct {
+	bchain.BlockChain
+	parser     bchain.BlockChainParser
+	higher     uint32
+	failHeight uint32
+	mux        sync.Mutex
+	failed     int
+	finished   []uint32
+}
+
+func testStagesBlockHash(height uint32) string {
+	return fmt.Sprintf("0x%064x", height)
+}
+
+func (c *testStagesChain) GetChainParser() bchain.BlockChainParser {
+	return c.parser
+}
+
+func (c *testStagesChain) EthereumTypeGetBlockBodies(lower, higher uint32) ([]*bchain.EthereumStagedBlock, error) {
+	blocks := make([]*bchain.EthereumStagedBlock, 0, higher-lower+1)
+	for h := lower; h <= higher; h++ {
+		blocks = append(blocks, &bchain.EthereumStagedBlock{Height: h, Hash: testStagesBlockHash(h), Data: &testStagedData{}})
+	}
+	return blocks, nil
+}
+
+// finishStage marks the stage of the batch as done and records the batch when both stages are done
+func (c *testStagesChain) finishStage(blocks []*bchain.EthereumStagedBlock, logs bool) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	var done bool
+	for _, sb := range blocks {
+		d := sb.Data.(*testStagedData)
+		if logs {
+			d.logs = true
+		} else {
+			d.internalData = true
+		}
+		done = d.logs && d.internalData
+	}
+	if done {
+		c.finished = append(c.finished, blocks[0].Height)
+	}
+}
+
+func (c *testStagesChain) EthereumTypeGetBlockLogs(blocks []*bchain.EthereumStagedBlock) error {
+	time.Sleep(time.Duration(c.higher-blocks[0].Height) * time.Millisecond)
+	c.finishStage(blocks, true)
+	return nil
+}
+
+func (c *testStagesChain) EthereumTypeGetBlockInternalData(blocks []*bchain.EthereumStagedBlock) error {
+	if blocks[0].Height <= c.failHeight && c.failHeight <= blocks[len(blocks)-1].Height {
+		c.mux.Lock()
+		c.failed++
+		failed := c.failed
+		c.mux.Unlock()
+		if failed == 1 {
+			return errors.New("internal data not available")
+		}
+	}
+	c.finishStage(blocks, false)
+	return nil
+}
+
+func (c *testStagesChain) EthereumTypeAssembleBlock(sb *bchain.EthereumStagedBlock) (*bchain.Block, error) {
+	c.mux.Lock()
+	d := *sb.Data.(*testStagedData)
+	c.mux.Unlock()
+	if !d.logs || !d.internalData {
+		return nil, errors.New("block " + strconv.Itoa(int(sb.Height)) + " not fetched by all stages")
+	}
+	return &bchain.Block{
+		BlockHeader: bchain.BlockHeader{Height: sb.Height, Hash: sb.Hash, Time: 1534858022 + int64(sb.Height)},
+	}, nil
+}
+
+func Test_connectBlocksParallelEthereumType(t *testing.T) {
+	d := setupRocksDB(t, &testEthereumParser{
+		EthereumParser: ethereumTestnetParser(),
+	})
+	defer closeAndDestroyRocksDB(t, d)
+
+	metrics, err := common.GetMetrics("coin-unittest-sync")
+	if err != nil {
+		t.Fatal(err)
+	}
+	const lower, higher = 1, 95
+	chain := &testStagesChain{parser: d.chainParser, higher: higher, failHeight: 55}
+	w, err := NewSyncWorker(d, chain, 4, 0, 0, false, make(chan os.Signal), metrics, d.is)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = w.connectBlocksParallelEthereumType(lower, higher); err != nil {
+		t.Fatal(err)
+	}
+
+	if chain.failed != 2 {
+		t.Errorf("internal data of the failing batch fetched %d times, want 2", chain.failed)
+	}
+	// the batches are finished out of order, the failed one after the higher ones
+	if len(chain.finished) != 10 {
+		t.Fatalf("finished batches %v, want 10", chain.finished)
+	}
+	if sort.SliceIsSorted(chain.finished, func(i, j int) bool { return chain.finished[i] < chain.finished[j] }) {
+		t.Errorf("finished batches %v, expected out of order", chain.finished)
+	}
+	if chain.finished[len(chain.finished)-1] != 51 {
+		t.Errorf("finished batches %v, expected the failed batch 51 last", chain.finished)
+	}
+	// the blocks are connected in the order of heights
+	height, hash, err := d.GetBestBlock()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if height != higher || hash != testStagesBlockHash(higher) {
+		t.Errorf("GetBestBlock() = %v %v, want %v %v", height, hash, higher, testStagesBlockHash(higher))
+	}
+	for h := uint32(lower); h <= higher; h++ {
+		hash, err := d.GetBlockHash(h)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if hash != testStagesBlockHash(h) {
+			t.Errorf("GetBlockHash(%d) = %v, want %v", h, hash, testStagesBlockHash(h))
+		}
+	}
+}
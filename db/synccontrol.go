@@ -0,0 +1,162 @@
+package db
+
+import (
+	"sync"
+	"time"
+
+	"github.com/juju/errors"
+)
+
+// names of the admin actions controlling the synchronization of the index
+const (
+	SyncActionPause               = "pause"
+	SyncActionResume              = "resume"
+	SyncActionRollback            = "rollback"
+	SyncActionRefetchInternalData = "refetchInternalData"
+	SyncActionComputeColumnStats  = "computeColumnStats"
+)
+
+// number of the finished actions kept in the status
+const syncControlDoneActions = 20
+
+// SyncAction is an admin action executed by the synchronization loops
+type SyncAction struct {
+	Name      string    `json:"name"`
+	Height    uint32    `json:"height,omitempty"`
+	Requested time.Time `json:"requested"`
+	Started   time.Time `json:"started"`
+	Finished  time.Time `json:"finished"`
+	Error     string    `json:"error,omitempty"`
+}
+
+// SyncControlStatus is the state of the synchronization control returned to the internal server
+type SyncControlStatus struct {
+	Paused  bool         `json:"paused"`
+	Pending []SyncAction `json:"pending"`
+	Done    []SyncAction `json:"done"`
+}
+
+// SyncControl queues the admin actions controlling the synchronization of the index.
+// The actions are executed by the synchronization loops, which are woken up by the trigger functions,
+// so that they are serialized with SyncWorker.ResyncIndex.
+type SyncControl struct {
+	mux                       sync.Mutex
+	paused                    bool
+	pending                   []*SyncAction
+	done                      []*SyncAction
+	triggerSyncIndex          func()
+	triggerStoreInternalState func()
+}
+
+// NewSyncControl creates the synchronization control,
+// triggerSyncIndex wakes up the index synchronization loop, triggerStoreInternalState the loop storing the internal state
+func NewSyncControl(triggerSyncIndex, triggerStoreInternalState func()) *SyncControl {
+	return &SyncControl{
+		triggerSyncIndex:          triggerSyncIndex,
+		triggerStoreInternalState: triggerStoreInternalState,
+	}
+}
+
+func (c *SyncControl) addDone(a *SyncAction) {
+	c.done = append(c.done, a)
+	if len(c.done) > syncControlDoneActions {
+		c.done = c.done[len(c.done)-syncControlDoneActions:]
+	}
+}
+
+// Pause stops the synchronization of the index, a running synchronization is finished first
+func (c *SyncControl) Pause() {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	now := time.Now().UTC()
+	c.paused = true
+	c.addDone(&SyncAction{Name: SyncActionPause, Requested: now, Started: now, Finished: now})
+}
+
+// Resume restarts the paused synchronization of the index
+func (c *SyncControl) Resume() {
+	c.mux.Lock()
+	now := time.Now().UTC()
+	c.paused = false
+	c.addDone(&SyncAction{Name: SyncActionResume, Requested: now, Started: now, Finished: now})
+	c.mux.Unlock()
+	c.triggerSyncIndex()
+}
+
+// IsPaused returns true if the synchronization of the index is paused
+func (c *SyncControl) IsPaused() bool {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return c.paused
+}
+
+// Request queues the action to be executed by the synchronization loop
+func (c *SyncControl) Request(name string, height uint32) error {
+	var trigger func()
+	switch name {
+	case SyncActionRollback, SyncActionRefetchInternalData:
+		trigger = c.triggerSyncIndex
+	case SyncActionComputeColumnStats:
+		trigger = c.triggerStoreInternalState
+	default:
+		return errors.Errorf("Unknown action %v", name)
+	}
+	c.mux.Lock()
+	for _, a := range c.pending {
+		if a.Name == name {
+			c.mux.Unlock()
+			return errors.Errorf("Action %v is already pending", name)
+		}
+	}
+	c.pending = append(c.pending, &SyncAction{Name: name, Height: height, Requested: time.Now().UTC()})
+	c.mux.Unlock()
+	trigger()
+	return nil
+}
+
+// Next removes from the queue the first pending action with one of the names and marks it as started,
+// it returns nil if there is no such action
+func (c *SyncControl) Next(names ...string) *SyncAction {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	for i, a := range c.pending {
+		for _, n := range names {
+			if a.Name == n {
+				c.pending = append(c.pending[:i], c.pending[i+1:]...)
+				a.Started = time.Now().UTC()
+				return a
+			}
+		}
+	}
+	return nil
+}
+
+// Finish records the result of the action returned by Next
+func (c *SyncControl) Finish(a *SyncAction, err error) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	a.Finished = time.Now().UTC()
+	if err != nil {
+		a.Error = err.Error()
+	}
+	c.addDone(a)
+}
+
+// Status returns a copy of the state of the synchronization control
+func (c *SyncControl) Status() *SyncControlStatus {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	s := &SyncControlStatus{
+		Paused:  c.paused,
+		Pending: make([]SyncAction, len(c.pending)),
+		Done:    make([]SyncAction, len(c.done)),
+	}
+	for i, a := range c.pending {
+		s.Pending[i] = *a
+	}
+	// the newest finished action first
+	for i, a := range c.done {
+		s.Done[len(c.done)-1-i] = *a
+	}
+	return s
+}
@@ -0,0 +1,74 @@
+//go:build unittest
+
+package db
+
+import (
+	"testing"
+)
+
+func TestSyncControl(t *testing.T) {
+	var syncTriggers, storeTriggers int
+	c := NewSyncControl(func() { syncTriggers++ }, func() { storeTriggers++ })
+
+	c.Pause()
+	if !c.IsPaused() {
+		t.Fatal("IsPaused() = false after Pause()")
+	}
+	if err := c.Request(SyncActionRollback, 100); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Request(SyncActionRollback, 90); err == nil {
+		t.Error("Request() expected error for already pending rollback")
+	}
+	if err := c.Request(SyncActionComputeColumnStats, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Request(SyncActionRefetchInternalData, 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Request("unknown", 0); err == nil {
+		t.Error("Request() expected error for unknown action")
+	}
+	if syncTriggers != 2 || storeTriggers != 1 {
+		t.Errorf("triggers sync %d, store %d, want 2, 1", syncTriggers, storeTriggers)
+	}
+
+	// the sync loop takes its actions in the order of the requests, the column stats are left for the store loop
+	a := c.Next(SyncActionRollback, SyncActionRefetchInternalData)
+	if a == nil || a.Name != SyncActionRollback || a.Height != 100 || a.Started.IsZero() {
+		t.Fatalf("Next() = %+v", a)
+	}
+	c.Finish(a, nil)
+	a = c.Next(SyncActionRollback, SyncActionRefetchInternalData)
+	if a == nil || a.Name != SyncActionRefetchInternalData {
+		t.Fatalf("Next() = %+v", a)
+	}
+	// an action can be requested again when the previous one is not pending anymore
+	if err := c.Request(SyncActionRollback, 90); err != nil {
+		t.Fatal(err)
+	}
+	c.Finish(a, ErrOperationInterrupted)
+
+	s := c.Status()
+	if !s.Paused || len(s.Pending) != 2 || s.Pending[0].Name != SyncActionComputeColumnStats || s.Pending[1].Height != 90 {
+		t.Errorf("Status() = %+v", s)
+	}
+	if len(s.Done) != 3 || s.Done[0].Name != SyncActionRefetchInternalData || s.Done[0].Error != ErrOperationInterrupted.Error() ||
+		s.Done[1].Name != SyncActionRollback || s.Done[1].Error != "" || s.Done[2].Name != SyncActionPause {
+		t.Errorf("Status().Done = %+v", s.Done)
+	}
+
+	c.Resume()
+	if c.IsPaused() {
+		t.Error("IsPaused() = true after Resume()")
+	}
+	if syncTriggers != 4 {
+		t.Errorf("sync triggers %d, want 4", syncTriggers)
+	}
+	for i := 0; i < 2*syncControlDoneActions; i++ {
+		c.Pause()
+	}
+	if s = c.Status(); len(s.Done) != syncControlDoneActions {
+		t.Errorf("len(Status().Done) = %d, want %d", len(s.Done), syncControlDoneActions)
+	}
+}
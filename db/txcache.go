@@ -1,6 +1,9 @@
 package db
 
 import (
+	"container/list"
+	"sync"
+
 	"github.com/golang/glog"
 	"github.com/juju/errors"
 	"github.com/trezor/blockbook/bchain"
@@ -10,19 +13,26 @@ import (
 
 // TxCache is handle to TxCacheServer
 type TxCache struct {
-	db        *RocksDB
+	db        Store
 	chain     bchain.BlockChain
 	metrics   *common.Metrics
 	is        *common.InternalState
 	enabled   bool
 	chainType bchain.ChainType
+	memory    *txMemoryCache
 }
 
 // NewTxCache creates new TxCache interface and returns its handle
-func NewTxCache(db *RocksDB, chain bchain.BlockChain, metrics *common.Metrics, is *common.InternalState, enabled bool) (*TxCache, error) {
+// memoryLimit is the size in bytes of the in-memory LRU cache in front of the db, 0 disables the in-memory cache
+func NewTxCache(db Store, chain bchain.BlockChain, metrics *common.Metrics, is *common.InternalState, enabled bool, memoryLimit int) (*TxCache, error) {
 	if !enabled {
 		glog.Info("txcache: disabled")
 	}
+	var memory *txMemoryCache
+	if enabled && memoryLimit > 0 {
+		glog.Info("txcache: in-memory cache limited to ", memoryLimit, " bytes")
+		memory = newTxMemoryCache(memoryLimit)
+	}
 	return &TxCache{
 		db:        db,
 		chain:     chain,
@@ -30,32 +40,67 @@ func NewTxCache(db *RocksDB, chain bchain.BlockChain, metrics *common.Metrics, i
 		is:        is,
 		enabled:   enabled,
 		chainType: chain.GetChainParser().GetChainType(),
+		memory:    memory,
 	}, nil
 }
 
+// OnDisconnectBlocks removes the transactions of the disconnected blocks from the in-memory cache
+func (c *TxCache) OnDisconnectBlocks(lower uint32, higher uint32, hashes []string) {
+	if c.memory != nil {
+		c.memory.removeFromHeight(lower)
+		c.metrics.TxCacheMemorySize.Set(float64(c.memory.getSize()))
+	}
+}
+
+func (c *TxCache) setConfirmations(tx *bchain.Tx, h uint32) {
+	// number of confirmations is not stored in cache, they change all the time
+	_, bestheight, _, _ := c.is.GetSyncState()
+	tx.Confirmations = bestheight - h + 1
+}
+
+func (c *TxCache) putToMemory(tx *bchain.Tx, h uint32) {
+	if c.memory != nil {
+		c.memory.put(tx, h)
+		c.metrics.TxCacheMemorySize.Set(float64(c.memory.getSize()))
+	}
+}
+
 // GetTransaction returns transaction either from RocksDB or if not present from blockchain
 // it the transaction is confirmed, it is stored in the RocksDB
 func (c *TxCache) GetTransaction(txid string) (*bchain.Tx, int, error) {
 	var tx *bchain.Tx
 	var h uint32
 	var err error
+	if c.memory != nil {
+		var found bool
+		if tx, h, found = c.memory.get(txid); found {
+			c.setConfirmations(tx, h)
+			c.metrics.TxCacheSourceEfficiency.With(common.Labels{"source": "memory", "status": "hit"}).Inc()
+			c.metrics.TxCacheEfficiency.With(common.Labels{"status": "hit"}).Inc()
+			return tx, int(h), nil
+		}
+		c.metrics.TxCacheSourceEfficiency.With(common.Labels{"source": "memory", "status": "miss"}).Inc()
+	}
 	if c.enabled {
 		tx, h, err = c.db.GetTx(txid)
 		if err != nil {
 			return nil, 0, err
 		}
 		if tx != nil {
-			// number of confirmations is not stored in cache, they change all the time
-			_, bestheight, _, _ := c.is.GetSyncState()
-			tx.Confirmations = bestheight - h + 1
+			c.putToMemory(tx, h)
+			c.setConfirmations(tx, h)
+			c.metrics.TxCacheSourceEfficiency.With(common.Labels{"source": "db", "status": "hit"}).Inc()
 			c.metrics.TxCacheEfficiency.With(common.Labels{"status": "hit"}).Inc()
 			return tx, int(h), nil
 		}
+		c.metrics.TxCacheSourceEfficiency.With(common.Labels{"source": "db", "status": "miss"}).Inc()
 	}
 	tx, err = c.chain.GetTransaction(txid)
 	if err != nil {
+		c.metrics.TxCacheSourceEfficiency.With(common.Labels{"source": "backend", "status": "miss"}).Inc()
 		return nil, 0, err
 	}
+	c.metrics.TxCacheSourceEfficiency.With(common.Labels{"source": "backend", "status": "hit"}).Inc()
 	c.metrics.TxCacheEfficiency.With(common.Labels{"status": "miss"}).Inc()
 	// cache only confirmed transactions
 	if tx.Confirmations > 0 {
@@ -94,9 +139,177 @@ func (c *TxCache) GetTransaction(txid string) (*bchain.Tx, int, error) {
 			if err != nil {
 				glog.Warning("PutTx ", tx.Txid, ",error ", err)
 			}
+			c.putToMemory(tx, h)
 		}
 	} else {
 		return tx, -1, nil
 	}
 	return tx, int(h), nil
 }
+
+// fixed overhead of the cached tx and of its inputs and outputs, used to estimate the memory consumption
+const (
+	txMemoryOverhead     = 256
+	txVinMemoryOverhead  = 128
+	txVoutMemoryOverhead = 128
+)
+
+type txMemoryCacheEntry struct {
+	txid   string
+	tx     *bchain.Tx
+	height uint32
+	size   int
+}
+
+// txMemoryCache is LRU cache of confirmed transactions limited by the estimated memory consumption
+type txMemoryCache struct {
+	mux   sync.Mutex
+	limit int
+	size  int
+	lru   *list.List
+	items map[string]*list.Element
+}
+
+func newTxMemoryCache(limit int) *txMemoryCache {
+	return &txMemoryCache{
+		limit: limit,
+		lru:   list.New(),
+		items: make(map[string]*list.Element),
+	}
+}
+
+// txMemorySize estimates the memory used by the transaction
+func txMemorySize(tx *bchain.Tx) int {
+	size := txMemoryOverhead + len(tx.Hex) + len(tx.Txid)
+	for i := range tx.Vin {
+		vin := &tx.Vin[i]
+		size += txVinMemoryOverhead + len(vin.Coinbase) + len(vin.Txid) + len(vin.ScriptSig.Hex)
+		for _, a := range vin.Addresses {
+			size += len(a)
+		}
+		for _, w := range vin.Witness {
+			size += len(w)
+		}
+	}
+	for i := range tx.Vout {
+		vout := &tx.Vout[i]
+		size += txVoutMemoryOverhead + len(vout.JsonValue) + len(vout.ScriptPubKey.Hex)
+		for _, a := range vout.ScriptPubKey.Addresses {
+			size += len(a)
+		}
+	}
+	if csd, ok := tx.CoinSpecificData.(bchain.EthereumSpecificData); ok {
+		if csd.Tx != nil {
+			size += len(csd.Tx.Payload) + 512
+		}
+		if csd.InternalData != nil {
+			size += len(csd.InternalData.Contract) + len(csd.InternalData.Error) + len(csd.InternalData.Transfers)*128
+		}
+		if csd.Receipt != nil {
+			for _, l := range csd.Receipt.Logs {
+				size += len(l.Address) + len(l.Data) + len(l.Topics)*66
+			}
+		}
+	}
+	return size
+}
+
+func copyStrings(s []string) []string {
+	if s == nil {
+		return nil
+	}
+	return append(make([]string, 0, len(s)), s...)
+}
+
+// copyTx returns a copy of the transaction with copied inputs and outputs,
+// the coin specific data are shared and must not be modified
+func copyTx(tx *bchain.Tx) *bchain.Tx {
+	t := *tx
+	if tx.Vin != nil {
+		t.Vin = make([]bchain.Vin, len(tx.Vin))
+		for i := range tx.Vin {
+			vin := &t.Vin[i]
+			*vin = tx.Vin[i]
+			vin.Addresses = copyStrings(vin.Addresses)
+			if vin.Witness != nil {
+				vin.Witness = make([][]byte, len(tx.Vin[i].Witness))
+				for j, w := range tx.Vin[i].Witness {
+					vin.Witness[j] = append([]byte(nil), w...)
+				}
+			}
+		}
+	}
+	if tx.Vout != nil {
+		t.Vout = make([]bchain.Vout, len(tx.Vout))
+		for i := range tx.Vout {
+			vout, v := &t.Vout[i], &tx.Vout[i]
+			vout.ValueSat.Set(&v.ValueSat)
+			vout.JsonValue = v.JsonValue
+			vout.N = v.N
+			vout.ScriptPubKey.Hex = v.ScriptPubKey.Hex
+			vout.ScriptPubKey.Addresses = copyStrings(v.ScriptPubKey.Addresses)
+		}
+	}
+	return &t
+}
+
+// get returns a copy of the cached transaction
+func (m *txMemoryCache) get(txid string) (*bchain.Tx, uint32, bool) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	e, found := m.items[txid]
+	if !found {
+		return nil, 0, false
+	}
+	m.lru.MoveToFront(e)
+	entry := e.Value.(*txMemoryCacheEntry)
+	return copyTx(entry.tx), entry.height, true
+}
+
+func (m *txMemoryCache) put(tx *bchain.Tx, height uint32) {
+	size := txMemorySize(tx)
+	if size > m.limit {
+		return
+	}
+	t := copyTx(tx)
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	if e, found := m.items[tx.Txid]; found {
+		m.removeElement(e)
+	}
+	m.items[tx.Txid] = m.lru.PushFront(&txMemoryCacheEntry{
+		txid:   tx.Txid,
+		tx:     t,
+		height: height,
+		size:   size,
+	})
+	m.size += size
+	for m.size > m.limit {
+		m.removeElement(m.lru.Back())
+	}
+}
+
+func (m *txMemoryCache) removeElement(e *list.Element) {
+	entry := m.lru.Remove(e).(*txMemoryCacheEntry)
+	delete(m.items, entry.txid)
+	m.size -= entry.size
+}
+
+// removeFromHeight removes the transactions in blocks with height greater or equal to the given height
+func (m *txMemoryCache) removeFromHeight(height uint32) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	for e := m.lru.Front(); e != nil; {
+		next := e.Next()
+		if e.Value.(*txMemoryCacheEntry).height >= height {
+			m.removeElement(e)
+		}
+		e = next
+	}
+}
+
+func (m *txMemoryCache) getSize() int {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	return m.size
+}
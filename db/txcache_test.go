@@ -0,0 +1,68 @@
+//go:build unittest
+
+package db
+
+import (
+	"testing"
+
+	"github.com/trezor/blockbook/bchain"
+)
+
+func Test_txMemoryCache(t *testing.T) {
+	tx := func(txid string) *bchain.Tx {
+		return &bchain.Tx{Txid: txid, Vin: []bchain.Vin{{Txid: "in"}}, Vout: []bchain.Vout{{N: 0}}}
+	}
+	size := txMemorySize(tx("tx1"))
+	m := newTxMemoryCache(3 * size)
+	m.put(tx("tx1"), 10)
+	m.put(tx("tx2"), 11)
+	m.put(tx("tx3"), 12)
+	if m.getSize() != 3*size {
+		t.Fatalf("getSize() = %v, want %v", m.getSize(), 3*size)
+	}
+	// tx1 becomes the most recently used, tx2 is evicted
+	got, height, found := m.get("tx1")
+	if !found || got.Txid != "tx1" || height != 10 {
+		t.Fatalf("get(tx1) = %v, %v, %v", got, height, found)
+	}
+	// the returned tx is a copy including the inputs and outputs
+	got.Confirmations = 100
+	got.Vin[0].Txid = "modified"
+	got.Vout[0].N = 1
+	if got, _, _ = m.get("tx1"); got.Confirmations != 0 || got.Vin[0].Txid != "in" || got.Vout[0].N != 0 {
+		t.Error("get(tx1) returned tx shared with the cache")
+	}
+	m.put(tx("tx4"), 13)
+	if _, _, found = m.get("tx2"); found {
+		t.Error("tx2 not evicted")
+	}
+	for _, txid := range []string{"tx1", "tx3", "tx4"} {
+		if _, _, found = m.get(txid); !found {
+			t.Errorf("%v not found", txid)
+		}
+	}
+	if m.getSize() != 3*size {
+		t.Fatalf("getSize() = %v, want %v", m.getSize(), 3*size)
+	}
+
+	// the transactions of the disconnected blocks are removed
+	m.removeFromHeight(12)
+	if _, _, found = m.get("tx1"); !found {
+		t.Error("tx1 removed")
+	}
+	for _, txid := range []string{"tx3", "tx4"} {
+		if _, _, found = m.get(txid); found {
+			t.Errorf("%v not removed", txid)
+		}
+	}
+	if m.getSize() != size {
+		t.Fatalf("getSize() = %v, want %v", m.getSize(), size)
+	}
+
+	// tx larger than the limit is not cached
+	big := &bchain.Tx{Txid: "big", Hex: string(make([]byte, 3*size))}
+	m.put(big, 14)
+	if _, _, found = m.get("big"); found {
+		t.Error("tx larger than the limit cached")
+	}
+}
@@ -0,0 +1,594 @@
+package db
+
+import (
+	"bytes"
+	"encoding/hex"
+	"math/big"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+	"github.com/linxGnu/grocksdb"
+	"github.com/trezor/blockbook/bchain"
+)
+
+// maximum number of mismatches kept in the verification progress
+const maxVerifyMismatches = 1000
+
+// number of addresses verified between the pauses of the throttled verification
+const verifyThrottleAddresses = 1000
+
+// number of addresses after which the snapshot of the db used by the verification is refreshed
+const verifyRefreshAddresses = 100000
+
+// VerifyMismatch describes a difference between the stored data of an address and the data recomputed from the transactions,
+// Heights are the heights of the blocks with the differing data, they are empty if the difference cannot be attributed
+// to particular blocks (the counters) or concerns all the blocks of the address (missing or invalid stored data),
+// FirstHeight and LastHeight are the range of the heights of the transactions of the address
+type VerifyMismatch struct {
+	AddrDesc    string   `json:"addrDesc"`
+	Address     string   `json:"address,omitempty"`
+	Field       string   `json:"field"`
+	Stored      string   `json:"stored"`
+	Computed    string   `json:"computed"`
+	Heights     []uint32 `json:"heights,omitempty"`
+	FirstHeight uint32   `json:"firstHeight"`
+	LastHeight  uint32   `json:"lastHeight"`
+	Repaired    bool     `json:"repaired,omitempty"`
+}
+
+// VerifyProgress contains the progress and the results of the index verification
+type VerifyProgress struct {
+	Running       bool             `json:"running"`
+	Repair        bool             `json:"repair"`
+	Started       time.Time        `json:"started"`
+	Finished      time.Time        `json:"finished"`
+	Addresses     int64            `json:"addresses"`
+	Txs           int64            `json:"txs"`
+	LastAddrDesc  string           `json:"lastAddrDesc,omitempty"`
+	MismatchCount int              `json:"mismatchCount"`
+	Repaired      int              `json:"repaired"`
+	Error         string           `json:"error,omitempty"`
+	Mismatches    []VerifyMismatch `json:"mismatches"`
+}
+
+var (
+	verifyMux      sync.Mutex
+	verifyProgress *VerifyProgress
+	verifyStop     chan os.Signal
+)
+
+// verifiedAddress contains the data of an address recomputed from the addresses and txAddresses columns
+type verifiedAddress struct {
+	addrDesc bchain.AddressDescriptor
+	txs      uint32
+	// the data of some transactions are missing, the address cannot be repaired
+	incomplete  bool
+	firstHeight uint32
+	lastHeight  uint32
+	mismatches  []VerifyMismatch
+	// BitcoinType
+	sent     big.Int
+	received big.Int
+	utxos    []Utxo
+	// EthereumType, number of txs by the type of transfer
+	nonContractTxs uint
+	internalTxs    uint
+	contractTxs    map[int]uint
+	// heights of the transactions by the contract index
+	contractHeights map[int][]uint32
+}
+
+func (va *verifiedAddress) addMismatch(field, stored, computed string, heights ...uint32) {
+	va.mismatches = append(va.mismatches, VerifyMismatch{
+		AddrDesc: hex.EncodeToString(va.addrDesc),
+		Field:    field,
+		Stored:   stored,
+		Computed: computed,
+		Heights:  heights,
+	})
+}
+
+type indexVerifier struct {
+	d        *RocksDB
+	repair   bool
+	throttle time.Duration
+	stop     chan os.Signal
+	ro       *grocksdb.ReadOptions
+	snapshot *grocksdb.Snapshot
+}
+
+// GetVerifyProgress returns the progress of the running or the last finished index verification or nil if there was none
+func (d *RocksDB) GetVerifyProgress() *VerifyProgress {
+	verifyMux.Lock()
+	defer verifyMux.Unlock()
+	if verifyProgress == nil {
+		return nil
+	}
+	p := *verifyProgress
+	p.Mismatches = append([]VerifyMismatch(nil), verifyProgress.Mismatches...)
+	return &p
+}
+
+// StartVerifyIndex starts the throttled verification of the index in background, the mismatches are only reported
+func (d *RocksDB) StartVerifyIndex(throttle time.Duration) error {
+	if err := d.beginVerify(false); err != nil {
+		return err
+	}
+	stop := make(chan os.Signal, 1)
+	verifyMux.Lock()
+	verifyStop = stop
+	verifyMux.Unlock()
+	go func() {
+		if _, err := d.verifyIndex(stop, false, throttle); err != nil && err != ErrOperationInterrupted {
+			glog.Error("VerifyIndex: ", err)
+		}
+	}()
+	return nil
+}
+
+// StopVerifyIndex stops the verification of the index started by StartVerifyIndex
+func (d *RocksDB) StopVerifyIndex() {
+	verifyMux.Lock()
+	defer verifyMux.Unlock()
+	if verifyStop != nil {
+		close(verifyStop)
+		verifyStop = nil
+	}
+}
+
+func (d *RocksDB) beginVerify(repair bool) error {
+	// the pruned history cannot be compared with the address balances
+	if d.is != nil && d.is.GetPrunedHeight() > 0 {
+		return errors.New("Verification of a pruned index is not supported")
+	}
+	verifyMux.Lock()
+	defer verifyMux.Unlock()
+	if verifyProgress != nil && verifyProgress.Running {
+		return errors.New("Verification is already running")
+	}
+	verifyProgress = &VerifyProgress{
+		Running: true,
+		Repair:  repair,
+		Started: time.Now().UTC(),
+	}
+	return nil
+}
+
+// VerifyIndex recomputes the balances of all addresses from the addresses and txAddresses columns
+// and compares them with the addressBalance column (addressContracts column for EthereumType coins).
+// If repair is set, the stored data are replaced by the recomputed ones, it must not run concurrently with the synchronization.
+// The throttle is a pause after each verifyThrottleAddresses addresses.
+func (d *RocksDB) VerifyIndex(stop chan os.Signal, repair bool, throttle time.Duration) (*VerifyProgress, error) {
+	if err := d.beginVerify(repair); err != nil {
+		return nil, err
+	}
+	return d.verifyIndex(stop, repair, throttle)
+}
+
+func (d *RocksDB) verifyIndex(stop chan os.Signal, repair bool, throttle time.Duration) (*VerifyProgress, error) {
+	glog.Info("VerifyIndex: starting, repair ", repair)
+	v := &indexVerifier{
+		d:        d,
+		repair:   repair,
+		throttle: throttle,
+		stop:     stop,
+	}
+	err := v.run()
+	verifyMux.Lock()
+	verifyProgress.Running = false
+	verifyProgress.Finished = time.Now().UTC()
+	if err != nil {
+		verifyProgress.Error = err.Error()
+	}
+	verifyMux.Unlock()
+	p := d.GetVerifyProgress()
+	glog.Info("VerifyIndex: finished, verified ", p.Addresses, " addresses, ", p.Txs, " txs, found ", p.MismatchCount, " mismatches, repaired ", p.Repaired, " addresses")
+	return p, err
+}
+
+func (v *indexVerifier) newSnapshot() {
+	v.releaseSnapshot()
+	v.snapshot = v.d.db.NewSnapshot()
+	v.ro = grocksdb.NewDefaultReadOptions()
+	// do not use cache
+	v.ro.SetFillCache(false)
+	v.ro.SetSnapshot(v.snapshot)
+}
+
+func (v *indexVerifier) releaseSnapshot() {
+	if v.snapshot != nil {
+		v.ro.Destroy()
+		v.d.db.ReleaseSnapshot(v.snapshot)
+		v.snapshot = nil
+	}
+}
+
+// run walks the addresses column, the keys of an address are not necessarily contiguous
+// as the address descriptor can be a prefix of another address descriptor,
+// therefore the addresses being processed form a stack of address descriptors, each one a prefix of the next one
+func (v *indexVerifier) run() error {
+	v.newSnapshot()
+	defer v.releaseSnapshot()
+	it := v.d.db.NewIteratorCF(v.ro, v.d.cfh[cfAddresses])
+	defer func() {
+		it.Close()
+	}()
+	var open []*verifiedAddress
+	var count int64
+	for it.SeekToFirst(); it.Valid(); it.Next() {
+		key := it.Key().Data()
+		addrDesc, height, err := unpackAddressKey(key)
+		if err != nil {
+			return errors.Annotatef(err, "key %x", key)
+		}
+		for len(open) > 0 && !bytes.HasPrefix(addrDesc, open[len(open)-1].addrDesc) {
+			if err = v.finish(open[len(open)-1]); err != nil {
+				return err
+			}
+			open = open[:len(open)-1]
+			count++
+		}
+		var va *verifiedAddress
+		if len(open) > 0 && bytes.Equal(open[len(open)-1].addrDesc, addrDesc) {
+			va = open[len(open)-1]
+		} else {
+			if len(open) == 0 && count >= verifyRefreshAddresses {
+				// refresh the snapshot between the addresses, the keys of the new address start at its address descriptor
+				count = 0
+				seek := append([]byte{}, addrDesc...)
+				it.Close()
+				v.newSnapshot()
+				it = v.d.db.NewIteratorCF(v.ro, v.d.cfh[cfAddresses])
+				it.Seek(seek)
+				if !it.Valid() {
+					break
+				}
+				key = it.Key().Data()
+				if addrDesc, height, err = unpackAddressKey(key); err != nil {
+					return errors.Annotatef(err, "key %x", key)
+				}
+			}
+			va = &verifiedAddress{
+				addrDesc:    append(bchain.AddressDescriptor{}, addrDesc...),
+				firstHeight: height,
+				lastHeight:  height,
+			}
+			open = append(open, va)
+		}
+		txis, err := v.d.unpackTxIndexes(it.Value().Data())
+		if err != nil {
+			return errors.Annotatef(err, "key %x", key)
+		}
+		if v.d.chainParser.GetChainType() == bchain.ChainEthereumType {
+			v.addTxsEthereumType(va, height, txis)
+		} else if err = v.addTxsBitcoinType(va, height, txis); err != nil {
+			return err
+		}
+	}
+	if err := it.Err(); err != nil {
+		return err
+	}
+	for i := len(open) - 1; i >= 0; i-- {
+		if err := v.finish(open[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (v *indexVerifier) addTxsBitcoinType(va *verifiedAddress, height uint32, txis []txIndexes) error {
+	// the keys of the address are ordered from the newest to the oldest height
+	va.firstHeight = height
+	for _, t := range txis {
+		va.txs++
+		ta, err := v.getTxAddresses(t.btxID)
+		if err != nil {
+			return err
+		}
+		if ta == nil {
+			va.addMismatch("txAddresses", "missing", hex.EncodeToString(t.btxID), height)
+			va.incomplete = true
+			continue
+		}
+		for _, index := range t.indexes {
+			if index < 0 {
+				i := int(^index)
+				if i >= len(ta.Inputs) || !bytes.Equal(ta.Inputs[i].AddrDesc, va.addrDesc) {
+					va.addMismatch("input", hex.EncodeToString(t.btxID)+":"+strconv.Itoa(i), "input not of the address", height)
+					va.incomplete = true
+					continue
+				}
+				va.sent.Add(&va.sent, &ta.Inputs[i].ValueSat)
+			} else {
+				i := int(index)
+				if i >= len(ta.Outputs) || !bytes.Equal(ta.Outputs[i].AddrDesc, va.addrDesc) {
+					va.addMismatch("output", hex.EncodeToString(t.btxID)+":"+strconv.Itoa(i), "output not of the address", height)
+					va.incomplete = true
+					continue
+				}
+				o := &ta.Outputs[i]
+				va.received.Add(&va.received, &o.ValueSat)
+				if !o.Spent {
+					va.utxos = append(va.utxos, Utxo{
+						BtxID:    t.btxID,
+						Vout:     index,
+						Height:   height,
+						ValueSat: o.ValueSat,
+					})
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (v *indexVerifier) addTxsEthereumType(va *verifiedAddress, height uint32, txis []txIndexes) {
+	va.firstHeight = height
+	if va.contractTxs == nil {
+		va.contractTxs = make(map[int]uint)
+		va.contractHeights = make(map[int][]uint32)
+	}
+	for _, t := range txis {
+		va.txs++
+		var nonContract, internal bool
+		contracts := make(map[int]struct{})
+		for _, index := range t.indexes {
+			if index < 0 {
+				index = ^index
+			}
+			switch {
+			case index == transferTo:
+				nonContract = true
+			case index == internalTransferTo:
+				internal = true
+			default:
+				contracts[int(index)-ContractIndexOffset] = struct{}{}
+			}
+		}
+		if nonContract {
+			va.nonContractTxs++
+		}
+		if internal {
+			va.internalTxs++
+		}
+		for c := range contracts {
+			va.contractTxs[c]++
+			va.contractHeights[c] = append(va.contractHeights[c], height)
+		}
+	}
+}
+
+func (v *indexVerifier) getTxAddresses(btxID []byte) (*TxAddresses, error) {
+	val, err := v.d.db.GetCF(v.ro, v.d.cfh[cfTxAddresses], btxID)
+	if err != nil {
+		return nil, err
+	}
+	defer val.Free()
+	buf := val.Data()
+	if len(buf) < 3 {
+		return nil, nil
+	}
+	return v.d.unpackTxAddresses(buf)
+}
+
+// finish compares the recomputed data of the address with the stored ones and records the mismatches
+func (v *indexVerifier) finish(va *verifiedAddress) error {
+	var err error
+	repaired := false
+	if v.d.chainParser.GetChainType() == bchain.ChainEthereumType {
+		repaired, err = v.compareEthereumType(va)
+	} else {
+		repaired, err = v.compareBitcoinType(va)
+	}
+	if err != nil {
+		return err
+	}
+	if len(va.mismatches) > 0 {
+		var address string
+		if a, _, err := v.d.chainParser.GetAddressesFromAddrDesc(va.addrDesc); err == nil && len(a) == 1 {
+			address = a[0]
+		}
+		for i := range va.mismatches {
+			m := &va.mismatches[i]
+			m.Address = address
+			m.Repaired = repaired
+			m.FirstHeight = va.firstHeight
+			m.LastHeight = va.lastHeight
+			glog.Warningf("VerifyIndex: address %v %v, %v stored %v, computed %v, heights %v, address heights %v-%v", address, m.AddrDesc, m.Field, m.Stored, m.Computed, m.Heights, m.FirstHeight, m.LastHeight)
+		}
+	}
+	verifyMux.Lock()
+	p := verifyProgress
+	p.Addresses++
+	p.Txs += int64(va.txs)
+	p.LastAddrDesc = hex.EncodeToString(va.addrDesc)
+	p.MismatchCount += len(va.mismatches)
+	if repaired {
+		p.Repaired++
+	}
+	for i := 0; i < len(va.mismatches) && len(p.Mismatches) < maxVerifyMismatches; i++ {
+		p.Mismatches = append(p.Mismatches, va.mismatches[i])
+	}
+	addresses := p.Addresses
+	verifyMux.Unlock()
+	if addresses%1000000 == 0 {
+		glog.Info("VerifyIndex: verified ", addresses, " addresses")
+	}
+	if addresses%verifyThrottleAddresses == 0 && v.throttle > 0 {
+		time.Sleep(v.throttle)
+	}
+	select {
+	case <-v.stop:
+		return ErrOperationInterrupted
+	default:
+	}
+	return nil
+}
+
+func utxoKey(btxID []byte, vout int32) string {
+	return string(btxID) + ":" + strconv.Itoa(int(vout))
+}
+
+func (v *indexVerifier) compareBitcoinType(va *verifiedAddress) (bool, error) {
+	var balance big.Int
+	balance.Sub(&va.received, &va.sent)
+	var stored *AddrBalance
+	val, err := v.d.db.GetCF(v.ro, v.d.cfh[cfAddressBalance], va.addrDesc)
+	if err != nil {
+		return false, err
+	}
+	if buf := val.Data(); len(buf) >= 3 {
+		stored, err = unpackAddrBalance(buf, v.d.chainParser.PackedTxidLen(), AddressBalanceDetailUTXO)
+	}
+	val.Free()
+	if err != nil {
+		va.addMismatch("addressBalance", err.Error(), "")
+	} else if stored == nil {
+		va.addMismatch("addressBalance", "missing", strconv.Itoa(int(va.txs))+" txs")
+	} else {
+		// the balance is the sum of the utxos, the heights of the differing utxos are the heights of the balance difference
+		computed := make(map[string]*Utxo, len(va.utxos))
+		for i := range va.utxos {
+			computed[utxoKey(va.utxos[i].BtxID, va.utxos[i].Vout)] = &va.utxos[i]
+		}
+		var missing, extra []uint32
+		for i := range stored.Utxos {
+			u := &stored.Utxos[i]
+			k := utxoKey(u.BtxID, u.Vout)
+			if c, found := computed[k]; found && c.ValueSat.Cmp(&u.ValueSat) == 0 && c.Height == u.Height {
+				delete(computed, k)
+			} else {
+				extra = append(extra, u.Height)
+			}
+		}
+		for _, u := range computed {
+			missing = append(missing, u.Height)
+		}
+		utxoHeights := sortedUniqueHeights(append(missing, extra...))
+		if stored.Txs != va.txs {
+			va.addMismatch("txs", strconv.Itoa(int(stored.Txs)), strconv.Itoa(int(va.txs)))
+		}
+		if stored.SentSat.Cmp(&va.sent) != 0 {
+			va.addMismatch("sentSat", stored.SentSat.String(), va.sent.String())
+		}
+		if stored.BalanceSat.Cmp(&balance) != 0 {
+			va.addMismatch("balanceSat", stored.BalanceSat.String(), balance.String(), utxoHeights...)
+		}
+		if len(missing) > 0 || len(extra) > 0 {
+			va.addMismatch("utxos", strconv.Itoa(len(extra))+" not in transactions", strconv.Itoa(len(missing))+" not stored", utxoHeights...)
+		}
+	}
+	if len(va.mismatches) == 0 || !v.repair || va.incomplete {
+		return false, nil
+	}
+	// the utxos are stored from the oldest
+	sort.SliceStable(va.utxos, func(i, j int) bool {
+		return va.utxos[i].Height < va.utxos[j].Height
+	})
+	ab := &AddrBalance{
+		Txs:        va.txs,
+		SentSat:    va.sent,
+		BalanceSat: balance,
+		Utxos:      va.utxos,
+	}
+	wb := grocksdb.NewWriteBatch()
+	defer wb.Destroy()
+	buf := packAddrBalance(ab, make([]byte, 1024), make([]byte, maxPackedBigintBytes))
+	wb.PutCF(v.d.cfh[cfAddressBalance], va.addrDesc, buf)
+	if err = v.d.WriteBatch(wb); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+func sortedUniqueHeights(heights []uint32) []uint32 {
+	sort.Slice(heights, func(i, j int) bool { return heights[i] < heights[j] })
+	r := heights[:0]
+	for i, h := range heights {
+		if i == 0 || h != heights[i-1] {
+			r = append(r, h)
+		}
+	}
+	return r
+}
+
+// compareEthereumType compares the recomputed number of transactions with the addressContracts counters,
+// the number of transactions is exact, the other counters can be only checked to be at least the number of transactions
+// of the given type as an address can have multiple transfers of the same type in one transaction
+func (v *indexVerifier) compareEthereumType(va *verifiedAddress) (bool, error) {
+	var stored *AddrContracts
+	val, err := v.d.db.GetCF(v.ro, v.d.cfh[cfAddressContracts], va.addrDesc)
+	if err != nil {
+		return false, err
+	}
+	if buf := val.Data(); len(buf) > 0 {
+		stored, err = unpackAddrContracts(buf, va.addrDesc)
+	}
+	val.Free()
+	if err != nil {
+		va.addMismatch("addressContracts", err.Error(), "")
+		return false, nil
+	}
+	if stored == nil {
+		// the zero address with only the contract transfers is not stored, its contract transfers are indexed
+		// as the non contract ones and cannot be told apart
+		if isZeroAddress(va.addrDesc) {
+			return false, nil
+		}
+		va.addMismatch("addressContracts", "missing", strconv.Itoa(int(va.txs))+" txs")
+		return false, nil
+	}
+	update := false
+	if stored.TotalTxs != uint(va.txs) {
+		va.addMismatch("totalTxs", strconv.Itoa(int(stored.TotalTxs)), strconv.Itoa(int(va.txs)))
+		stored.TotalTxs = uint(va.txs)
+		update = true
+	}
+	// the contracts of the zero address are not stored
+	if !isZeroAddress(va.addrDesc) {
+		if stored.NonContractTxs < va.nonContractTxs {
+			va.addMismatch("nonContractTxs", strconv.Itoa(int(stored.NonContractTxs)), "at least "+strconv.Itoa(int(va.nonContractTxs)))
+			stored.NonContractTxs = va.nonContractTxs
+			update = true
+		}
+		if stored.InternalTxs < va.internalTxs {
+			va.addMismatch("internalTxs", strconv.Itoa(int(stored.InternalTxs)), "at least "+strconv.Itoa(int(va.internalTxs)))
+			stored.InternalTxs = va.internalTxs
+			update = true
+		}
+		contracts := make([]int, 0, len(va.contractTxs))
+		for c := range va.contractTxs {
+			contracts = append(contracts, c)
+		}
+		sort.Ints(contracts)
+		for _, c := range contracts {
+			txs := va.contractTxs[c]
+			if c >= len(stored.Contracts) {
+				va.addMismatch("contracts", strconv.Itoa(len(stored.Contracts))+" contracts", "contract index "+strconv.Itoa(c), sortedUniqueHeights(va.contractHeights[c])...)
+				// cannot be repaired, the contract is not known
+				update = false
+				break
+			}
+			if stored.Contracts[c].Txs < txs {
+				va.addMismatch("contractTxs", strconv.Itoa(int(stored.Contracts[c].Txs)), "at least "+strconv.Itoa(int(txs)))
+				stored.Contracts[c].Txs = txs
+				update = true
+			}
+		}
+	}
+	if !update || !v.repair {
+		return false, nil
+	}
+	wb := grocksdb.NewWriteBatch()
+	defer wb.Destroy()
+	wb.PutCF(v.d.cfh[cfAddressContracts], va.addrDesc, packAddrContracts(stored))
+	if err = v.d.WriteBatch(wb); err != nil {
+		return false, err
+	}
+	return true, nil
+}
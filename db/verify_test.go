@@ -0,0 +1,205 @@
+//go:build unittest
+
+package db
+
+import (
+	"math/big"
+	"reflect"
+	"testing"
+
+	"github.com/linxGnu/grocksdb"
+	"github.com/trezor/blockbook/tests/dbtestdata"
+)
+
+func TestRocksDB_VerifyIndex_BitcoinType(t *testing.T) {
+	d := setupRocksDB(t, &testBitcoinParser{
+		BitcoinParser: bitcoinTestnetParser(),
+	})
+	defer closeAndDestroyRocksDB(t, d)
+
+	if err := d.ConnectBlock(dbtestdata.GetTestBitcoinTypeBlock1(d.chainParser)); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.ConnectBlock(dbtestdata.GetTestBitcoinTypeBlock2(d.chainParser)); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := d.VerifyIndex(nil, false, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Running || p.Addresses == 0 || p.Txs == 0 || p.MismatchCount != 0 || len(p.Mismatches) != 0 {
+		t.Fatalf("VerifyIndex() of consistent db = %+v", p)
+	}
+	addresses := p.Addresses
+
+	want, err := d.GetAddressBalance(dbtestdata.Addr5, AddressBalanceDetailUTXO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	addrDesc, err := d.chainParser.GetAddrDescFromAddress(dbtestdata.Addr5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// corrupt the balance of the address
+	wb := grocksdb.NewWriteBatch()
+	defer wb.Destroy()
+	corrupted := &AddrBalance{
+		Txs:        3,
+		SentSat:    want.SentSat,
+		BalanceSat: *big.NewInt(0),
+	}
+	wb.PutCF(d.cfh[cfAddressBalance], addrDesc, packAddrBalance(corrupted, make([]byte, 1024), make([]byte, maxPackedBigintBytes)))
+	if err = d.WriteBatch(wb); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err = d.VerifyIndex(nil, false, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := make([]string, len(p.Mismatches))
+	for i, m := range p.Mismatches {
+		if m.Address != dbtestdata.Addr5 || m.Repaired {
+			t.Errorf("VerifyIndex() mismatch %+v", m)
+		}
+		fields[i] = m.Field
+	}
+	if !reflect.DeepEqual(fields, []string{"txs", "balanceSat", "utxos"}) || p.MismatchCount != 3 || p.Repaired != 0 || p.Addresses != addresses {
+		t.Errorf("VerifyIndex() = %+v, mismatch fields %v", p, fields)
+	}
+	// the txs counter cannot be attributed to the blocks, the balance differs by the utxos
+	for i, want := range [][]uint32{nil, {225494}, {225494}} {
+		if h := p.Mismatches[i].Heights; !reflect.DeepEqual(h, want) {
+			t.Errorf("VerifyIndex() %v heights %v, want %v", fields[i], h, want)
+		}
+	}
+
+	// repair the balance
+	p, err = d.VerifyIndex(nil, true, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.MismatchCount != 3 || p.Repaired != 1 {
+		t.Errorf("VerifyIndex() with repair = %+v", p)
+	}
+	got, err := d.GetAddressBalance(dbtestdata.Addr5, AddressBalanceDetailUTXO)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("repaired balance = %+v, want %+v", got, want)
+	}
+	p, err = d.VerifyIndex(nil, false, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.MismatchCount != 0 {
+		t.Errorf("VerifyIndex() after repair = %+v", p)
+	}
+}
+
+func TestRocksDB_VerifyIndex_EthereumType(t *testing.T) {
+	d := setupRocksDB(t, &testEthereumParser{
+		EthereumParser: ethereumTestnetParser(),
+	})
+	defer closeAndDestroyRocksDB(t, d)
+
+	if err := d.ConnectBlock(dbtestdata.GetTestEthereumTypeBlock1(d.chainParser)); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.ConnectBlock(dbtestdata.GetTestEthereumTypeBlock2(d.chainParser)); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := d.VerifyIndex(nil, false, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Running || p.Addresses == 0 || p.Txs == 0 || p.MismatchCount != 0 || len(p.Mismatches) != 0 {
+		t.Fatalf("VerifyIndex() of consistent db = %+v", p)
+	}
+
+	addrDesc := hexToBytes(dbtestdata.EthAddr3e)
+	want, err := d.GetAddrDescContracts(addrDesc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want == nil || len(want.Contracts) != 1 {
+		t.Fatalf("GetAddrDescContracts() = %+v", want)
+	}
+	storeContracts := func(acs *AddrContracts) {
+		t.Helper()
+		wb := grocksdb.NewWriteBatch()
+		defer wb.Destroy()
+		wb.PutCF(d.cfh[cfAddressContracts], addrDesc, packAddrContracts(acs))
+		if err := d.WriteBatch(wb); err != nil {
+			t.Fatal(err)
+		}
+	}
+	checkMismatches := func(p *VerifyProgress, wantFields []string, wantHeights [][]uint32, wantRepaired bool) {
+		t.Helper()
+		fields := make([]string, len(p.Mismatches))
+		for i, m := range p.Mismatches {
+			if m.AddrDesc != dbtestdata.EthAddr3e || m.Repaired != wantRepaired || m.FirstHeight != 4321000 || m.LastHeight != 4321001 {
+				t.Errorf("VerifyIndex() mismatch %+v", m)
+			}
+			if !reflect.DeepEqual(m.Heights, wantHeights[i]) {
+				t.Errorf("VerifyIndex() %v heights %v, want %v", m.Field, m.Heights, wantHeights[i])
+			}
+			fields[i] = m.Field
+		}
+		if !reflect.DeepEqual(fields, wantFields) || p.MismatchCount != len(wantFields) {
+			t.Errorf("VerifyIndex() = %+v, mismatch fields %v, want %v", p, fields, wantFields)
+		}
+	}
+
+	// corrupt the counters of the address
+	corrupted := *want
+	corrupted.TotalTxs = 5
+	corrupted.Contracts = []AddrContract{want.Contracts[0]}
+	corrupted.Contracts[0].Txs = 0
+	storeContracts(&corrupted)
+	p, err = d.VerifyIndex(nil, false, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkMismatches(p, []string{"totalTxs", "contractTxs"}, [][]uint32{nil, nil}, false)
+
+	// repair the counters
+	p, err = d.VerifyIndex(nil, true, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkMismatches(p, []string{"totalTxs", "contractTxs"}, [][]uint32{nil, nil}, true)
+	if p.Repaired != 1 {
+		t.Errorf("VerifyIndex() with repair = %+v", p)
+	}
+	got, err := d.GetAddrDescContracts(addrDesc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("repaired contracts = %+v, want %+v", got, want)
+	}
+	p, err = d.VerifyIndex(nil, false, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.MismatchCount != 0 {
+		t.Errorf("VerifyIndex() after repair = %+v", p)
+	}
+
+	// the missing contract is reported at the heights of its transfers and cannot be repaired
+	corrupted = *want
+	corrupted.Contracts = nil
+	storeContracts(&corrupted)
+	p, err = d.VerifyIndex(nil, true, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkMismatches(p, []string{"contracts"}, [][]uint32{{4321001}}, false)
+	if p.Repaired != 0 {
+		t.Errorf("VerifyIndex() with repair = %+v", p)
+	}
+}
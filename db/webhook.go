@@ -0,0 +1,126 @@
+package db
+
+import (
+	"encoding/binary"
+	"encoding/json"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+)
+
+// WebhookSubscription is a registration of a URL which is notified about transactions of the watched addresses and xpubs
+type WebhookSubscription struct {
+	ID            string   `json:"id"`
+	URL           string   `json:"url"`
+	Secret        string   `json:"secret,omitempty"`
+	Descriptors   []string `json:"descriptors"`
+	Confirmations uint32   `json:"confirmations,omitempty"`
+	Created       int64    `json:"created"`
+}
+
+// WebhookDeliveryStatus is a status of a webhook delivery
+type WebhookDeliveryStatus string
+
+const (
+	// WebhookDeliveryPending is a delivery which was not yet delivered and is waiting for an attempt
+	WebhookDeliveryPending = WebhookDeliveryStatus("pending")
+	// WebhookDeliveryDelivered is a successfully delivered delivery
+	WebhookDeliveryDelivered = WebhookDeliveryStatus("delivered")
+	// WebhookDeliveryFailed is a delivery which was not delivered in the maximum number of attempts
+	WebhookDeliveryFailed = WebhookDeliveryStatus("failed")
+)
+
+// WebhookDelivery is an entry of the webhook delivery log
+type WebhookDelivery struct {
+	ID             uint64                `json:"id"`
+	SubscriptionID string                `json:"subscriptionId"`
+	Event          string                `json:"event"`
+	Txid           string                `json:"txid"`
+	Height         uint32                `json:"height,omitempty"`
+	Payload        json.RawMessage       `json:"payload"`
+	Status         WebhookDeliveryStatus `json:"status"`
+	Attempts       int                   `json:"attempts"`
+	NextAttempt    int64                 `json:"nextAttempt,omitempty"`
+	LastError      string                `json:"lastError,omitempty"`
+	Created        int64                 `json:"created"`
+	Updated        int64                 `json:"updated"`
+}
+
+func packWebhookDeliveryKey(id uint64) []byte {
+	key := make([]byte, 8)
+	binary.BigEndian.PutUint64(key, id)
+	return key
+}
+
+// StoreWebhookSubscription stores or updates the webhook subscription
+func (d *RocksDB) StoreWebhookSubscription(s *WebhookSubscription) error {
+	if s.ID == "" {
+		return errors.New("Missing webhook subscription id")
+	}
+	buf, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return d.db.PutCF(d.wo, d.cfh[cfWebhooks], []byte(s.ID), buf)
+}
+
+// DeleteWebhookSubscription removes the webhook subscription
+func (d *RocksDB) DeleteWebhookSubscription(id string) error {
+	return d.db.DeleteCF(d.wo, d.cfh[cfWebhooks], []byte(id))
+}
+
+// GetWebhookSubscriptions returns all webhook subscriptions
+func (d *RocksDB) GetWebhookSubscriptions() ([]WebhookSubscription, error) {
+	rv := []WebhookSubscription{}
+	it := d.db.NewIteratorCF(d.ro, d.cfh[cfWebhooks])
+	defer it.Close()
+	for it.SeekToFirst(); it.Valid(); it.Next() {
+		var s WebhookSubscription
+		if err := json.Unmarshal(it.Value().Data(), &s); err != nil {
+			glog.Error("GetWebhookSubscriptions key ", string(it.Key().Data()), ", unmarshal error ", err)
+			continue
+		}
+		rv = append(rv, s)
+	}
+	return rv, nil
+}
+
+// StoreWebhookDelivery stores or updates the entry of the webhook delivery log
+func (d *RocksDB) StoreWebhookDelivery(w *WebhookDelivery) error {
+	buf, err := json.Marshal(w)
+	if err != nil {
+		return err
+	}
+	return d.db.PutCF(d.wo, d.cfh[cfWebhookDeliveries], packWebhookDeliveryKey(w.ID), buf)
+}
+
+// DeleteWebhookDelivery removes the entry from the webhook delivery log
+func (d *RocksDB) DeleteWebhookDelivery(id uint64) error {
+	return d.db.DeleteCF(d.wo, d.cfh[cfWebhookDeliveries], packWebhookDeliveryKey(id))
+}
+
+// GetWebhookDeliveries iterates the webhook delivery log, from the newest entry if newestFirst is set, otherwise from the oldest one,
+// and calls the function fn for each entry until fn returns false
+func (d *RocksDB) GetWebhookDeliveries(newestFirst bool, fn func(w *WebhookDelivery) bool) error {
+	it := d.db.NewIteratorCF(d.ro, d.cfh[cfWebhookDeliveries])
+	defer it.Close()
+	if newestFirst {
+		it.SeekToLast()
+	} else {
+		it.SeekToFirst()
+	}
+	for it.Valid() {
+		var w WebhookDelivery
+		if err := json.Unmarshal(it.Value().Data(), &w); err != nil {
+			glog.Error("GetWebhookDeliveries key ", binary.BigEndian.Uint64(it.Key().Data()), ", unmarshal error ", err)
+		} else if !fn(&w) {
+			break
+		}
+		if newestFirst {
+			it.Prev()
+		} else {
+			it.Next()
+		}
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package fourbyte
 
 import (
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"io/ioutil"
@@ -20,6 +21,9 @@ type FourByteSignaturesDownloader struct {
 	url                string
 	httpTimeoutSeconds time.Duration
 	db                 *db.RocksDB
+	// events specifies that the signatures are the event signatures identified by 32 bytes of topic0
+	events bool
+	name   string
 }
 
 // NewFourByteSignaturesDownloader initializes the downloader for FourByteSignatures API.
@@ -28,6 +32,18 @@ func NewFourByteSignaturesDownloader(db *db.RocksDB, url string) (*FourByteSigna
 		url:                url,
 		httpTimeoutSeconds: 15 * time.Second,
 		db:                 db,
+		name:               "FourByteSignaturesDownloader",
+	}, nil
+}
+
+// NewEventSignaturesDownloader initializes the downloader of the event signatures from FourByteSignatures API.
+func NewEventSignaturesDownloader(db *db.RocksDB, url string) (*FourByteSignaturesDownloader, error) {
+	return &FourByteSignaturesDownloader{
+		url:                url,
+		httpTimeoutSeconds: 15 * time.Second,
+		db:                 db,
+		events:             true,
+		name:               "EventSignaturesDownloader",
 	}, nil
 }
 
@@ -80,7 +96,7 @@ func (fd *FourByteSignaturesDownloader) getPage(url string) (*signaturesPage, er
 	var data signaturesPage
 	err = json.Unmarshal(bodyBytes, &data)
 	if err != nil {
-		glog.Errorf("Error parsing 4byte signatures response from %s: %v", url, err)
+		glog.Errorf("%s error parsing signatures response from %s: %v", fd.name, url, err)
 		return nil, err
 	}
 	return &data, nil
@@ -92,13 +108,45 @@ func (fd *FourByteSignaturesDownloader) getPageWithRetry(url string) (*signature
 		if err == nil && page != nil {
 			return page, err
 		}
-		glog.Errorf("Error getting 4byte signatures from %s: %v, retry count %d", url, err, retry)
+		glog.Errorf("%s error getting signatures from %s: %v, retry count %d", fd.name, url, err, retry)
 		timer := time.NewTimer(time.Second * time.Duration(retry))
 		<-timer.C
 	}
 	return nil, errors.New("Too many retries to 4byte signatures")
 }
 
+// getSignature returns the stored signature with the hex signature and id of the record
+func (fd *FourByteSignaturesDownloader) getSignature(r *signatureData) (*bchain.FourByteSignature, error) {
+	if fd.events {
+		topic0, err := hex.DecodeString(strings.TrimPrefix(r.HexSignature, "0x"))
+		if err != nil || len(topic0) != 32 {
+			return nil, errors.New("Invalid event signature " + r.HexSignature)
+		}
+		return fd.db.GetEventSignature(topic0, uint32(r.Id))
+	}
+	fourBytes, err := strconv.ParseUint(r.HexSignature, 0, 0)
+	if err != nil {
+		return nil, err
+	}
+	return fd.db.GetFourByteSignature(uint32(fourBytes), uint32(r.Id))
+}
+
+// storeSignature stores the signature parsed from the text signature of the record
+func (fd *FourByteSignaturesDownloader) storeSignature(wb *grocksdb.WriteBatch, r *signatureData, signature *bchain.FourByteSignature) error {
+	if fd.events {
+		topic0, err := hex.DecodeString(strings.TrimPrefix(r.HexSignature, "0x"))
+		if err != nil || len(topic0) != 32 {
+			return errors.New("Invalid event signature " + r.HexSignature)
+		}
+		return fd.db.StoreEventSignature(wb, topic0, uint32(r.Id), signature)
+	}
+	fourBytes, err := strconv.ParseUint(r.HexSignature, 0, 0)
+	if err != nil {
+		return err
+	}
+	return fd.db.StoreFourByteSignature(wb, uint32(fourBytes), uint32(r.Id), signature)
+}
+
 func parseSignatureFromText(t string) *bchain.FourByteSignature {
 	s := strings.Index(t, "(")
 	e := strings.LastIndex(t, ")")
@@ -133,27 +181,22 @@ func (fd *FourByteSignaturesDownloader) downloadSignatures() {
 	timer := time.NewTimer(period)
 	url := fd.url
 	results := make([]signatureData, 0)
-	glog.Info("FourByteSignaturesDownloader starting download")
+	glog.Info(fd.name, " starting download")
 	for {
 		page, err := fd.getPageWithRetry(url)
 		if err != nil {
-			glog.Errorf("Error getting 4byte signatures from %s: %v", url, err)
+			glog.Errorf("%s error getting signatures from %s: %v", fd.name, url, err)
 			return
 		}
 		if page == nil {
-			glog.Errorf("Empty page from 4byte signatures from %s: %v", url, err)
+			glog.Errorf("%s empty page of signatures from %s: %v", fd.name, url, err)
 			return
 		}
-		glog.Infof("FourByteSignaturesDownloader downloaded %s with %d results", url, len(page.Results))
+		glog.Infof("%s downloaded %s with %d results", fd.name, url, len(page.Results))
 		if len(page.Results) > 0 {
-			fourBytes, err := strconv.ParseUint(page.Results[0].HexSignature, 0, 0)
+			sig, err := fd.getSignature(&page.Results[0])
 			if err != nil {
-				glog.Errorf("Invalid 4byte signature %+v on page %s: %v", page.Results[0], url, err)
-				return
-			}
-			sig, err := fd.db.GetFourByteSignature(uint32(fourBytes), uint32(page.Results[0].Id))
-			if err != nil {
-				glog.Errorf("db.GetFourByteSignature error %+v on page %s: %v", page.Results[0], url, err)
+				glog.Errorf("%s getSignature error %+v on page %s: %v", fd.name, page.Results[0], url, err)
 				return
 			}
 			// signature is already stored in db, break
@@ -172,29 +215,29 @@ func (fd *FourByteSignaturesDownloader) downloadSignatures() {
 		timer.Reset(period)
 	}
 	if len(results) > 0 {
-		glog.Infof("FourByteSignaturesDownloader storing %d new signatures", len(results))
+		glog.Infof("%s storing %d new signatures", fd.name, len(results))
 		wb := grocksdb.NewWriteBatch()
 		defer wb.Destroy()
 
 		for i := range results {
 			r := &results[i]
-			fourBytes, err := strconv.ParseUint(r.HexSignature, 0, 0)
-			if err != nil {
-				glog.Errorf("Invalid 4byte signature %+v: %v", r, err)
-				return
-			}
 			fbs := parseSignatureFromText(r.TextSignature)
 			if fbs != nil {
-				fd.db.StoreFourByteSignature(wb, uint32(fourBytes), uint32(r.Id), fbs)
+				if err := fd.storeSignature(wb, r, fbs); err != nil {
+					glog.Errorf("%s invalid signature %+v: %v", fd.name, r, err)
+					return
+				}
 			} else {
-				glog.Errorf("FourByteSignaturesDownloader invalid signature %s", r.TextSignature)
+				glog.Errorf("%s invalid signature %s", fd.name, r.TextSignature)
 			}
 		}
 
 		if err := fd.db.WriteBatch(wb); err != nil {
-			glog.Errorf("FourByteSignaturesDownloader failed to store signatures, %v", err)
+			glog.Errorf("%s failed to store signatures, %v", fd.name, err)
+		} else if fd.events {
+			fd.db.ClearCachedEventSignatures()
 		}
 
 	}
-	glog.Infof("FourByteSignaturesDownloader finished")
+	glog.Infof("%s finished", fd.name)
 }
@@ -2,38 +2,57 @@ package server
 
 import (
 	"context"
+	"crypto/subtle"
 	"encoding/json"
 	"fmt"
 	"html/template"
+	"io"
 	"net/http"
 	"path/filepath"
 	"sort"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/golang/glog"
+	"github.com/juju/errors"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/trezor/blockbook/api"
 	"github.com/trezor/blockbook/bchain"
 	"github.com/trezor/blockbook/common"
 	"github.com/trezor/blockbook/db"
 	"github.com/trezor/blockbook/fiat"
+	"github.com/trezor/blockbook/webhook"
 )
 
 // InternalServer is handle to internal http server
 type InternalServer struct {
 	htmlTemplates[InternalTemplateData]
-	https       *http.Server
-	certFiles   string
-	db          *db.RocksDB
-	txCache     *db.TxCache
-	chain       bchain.BlockChain
-	chainParser bchain.BlockChainParser
-	mempool     bchain.Mempool
-	is          *common.InternalState
-	api         *api.Worker
+	https         *http.Server
+	certFiles     string
+	db            db.Store
+	txCache       *db.TxCache
+	chain         bchain.BlockChain
+	chainParser   bchain.BlockChainParser
+	mempool       bchain.Mempool
+	is            *common.InternalState
+	api           *api.Worker
+	webhooks      *webhook.Dispatcher
+	syncControl   *db.SyncControl
+	adminUser     string
+	adminPassword string
+	checkpoints   string
 }
 
 // NewInternalServer creates new internal http interface to blockbook and returns its handle
-func NewInternalServer(binding, certFiles string, db *db.RocksDB, chain bchain.BlockChain, mempool bchain.Mempool, txCache *db.TxCache, metrics *common.Metrics, is *common.InternalState, fiatRates *fiat.FiatRates) (*InternalServer, error) {
+func NewInternalServer(binding, certFiles string, db db.Store, chain bchain.BlockChain, mempool bchain.Mempool, txCache *db.TxCache, metrics *common.Metrics, is *common.InternalState, fiatRates *fiat.FiatRates, webhooks *webhook.Dispatcher, syncControl *db.SyncControl, adminAuth string, replicaRelay *ReplicaRelay, checkpoints string) (*InternalServer, error) {
+	var adminUser, adminPassword string
+	if adminAuth != "" {
+		var ok bool
+		if adminUser, adminPassword, ok = strings.Cut(adminAuth, ":"); !ok || adminUser == "" || adminPassword == "" {
+			return nil, errors.New("Admin authentication must be in the format user:password")
+		}
+	}
 	api, err := api.NewWorker(db, chain, mempool, txCache, metrics, is, fiatRates)
 	if err != nil {
 		return nil, err
@@ -49,15 +68,20 @@ func NewInternalServer(binding, certFiles string, db *db.RocksDB, chain bchain.B
 		htmlTemplates: htmlTemplates[InternalTemplateData]{
 			debug: true,
 		},
-		https:       https,
-		certFiles:   certFiles,
-		db:          db,
-		txCache:     txCache,
-		chain:       chain,
-		chainParser: chain.GetChainParser(),
-		mempool:     mempool,
-		is:          is,
-		api:         api,
+		https:         https,
+		certFiles:     certFiles,
+		db:            db,
+		txCache:       txCache,
+		chain:         chain,
+		chainParser:   chain.GetChainParser(),
+		mempool:       mempool,
+		is:            is,
+		api:           api,
+		webhooks:      webhooks,
+		syncControl:   syncControl,
+		adminUser:     adminUser,
+		adminPassword: adminPassword,
+		checkpoints:   checkpoints,
 	}
 	s.htmlTemplates.newTemplateData = s.newTemplateData
 	s.htmlTemplates.newTemplateDataWithError = s.newTemplateDataWithError
@@ -70,8 +94,25 @@ func NewInternalServer(binding, certFiles string, db *db.RocksDB, chain bchain.B
 	serveMux.HandleFunc(path, s.index)
 	serveMux.HandleFunc(path+"admin", s.htmlTemplateHandler(s.adminIndex))
 	serveMux.HandleFunc(path+"admin/ws-limit-exceeding-ips", s.htmlTemplateHandler(s.wsLimitExceedingIPs))
+	serveMux.HandleFunc(path+"admin/checkpoint", s.adminAuthHandler(s.apiCheckpoint))
+	serveMux.HandleFunc(path+"admin/verifydb", s.adminAuthHandler(s.htmlTemplateHandler(s.adminVerifyDb)))
+	serveMux.HandleFunc(path+"admin/verifydb/progress", s.adminAuthHandler(s.apiVerifyDbProgress))
 	if s.chainParser.GetChainType() == bchain.ChainEthereumType {
 		serveMux.HandleFunc(path+"admin/internal-data-errors", s.htmlTemplateHandler(s.internalDataErrors))
+		serveMux.HandleFunc(path+"admin/contract-abi", s.adminAuthHandler(s.apiContractABI))
+	}
+	if webhooks != nil {
+		serveMux.HandleFunc(path+"admin/webhooks", s.adminAuthHandler(s.htmlTemplateHandler(s.adminWebhooks)))
+		serveMux.HandleFunc(path+"admin/webhooks/subscriptions", s.adminAuthHandler(s.apiWebhookSubscriptions))
+		serveMux.HandleFunc(path+"admin/webhooks/deliveries", s.adminAuthHandler(s.apiWebhookDeliveries))
+	}
+	if replicaRelay != nil {
+		serveMux.HandleFunc(path+"replica/notifications", s.adminAuthHandler(replicaRelay.ServeHTTP))
+		https.RegisterOnShutdown(replicaRelay.Close)
+	}
+	if syncControl != nil {
+		serveMux.HandleFunc(path+"admin/sync", s.adminAuthHandler(s.htmlTemplateHandler(s.adminSync)))
+		serveMux.HandleFunc(path+"admin/sync/actions", s.adminAuthHandler(s.apiSyncActions))
 	}
 	return s, nil
 }
@@ -119,6 +160,9 @@ const (
 	adminIndexTpl = iota + errorInternalTpl + 1
 	adminInternalErrorsTpl
 	adminLimitExceedingIPS
+	adminWebhooksTpl
+	adminVerifyDbTpl
+	adminSyncTpl
 
 	internalTplCount
 )
@@ -140,14 +184,24 @@ type InternalTemplateData struct {
 	RefetchingInternalData bool
 	WsGetAccountInfoLimit  int
 	WsLimitExceedingIPs    []WsLimitExceedingIP
+	WebhooksEnabled        bool
+	WebhookSubscriptions   []db.WebhookSubscription
+	WebhookDeliveries      []db.WebhookDelivery
+	NewWebhookSubscription *db.WebhookSubscription
+	VerifyProgress         *db.VerifyProgress
+	SyncControlEnabled     bool
+	SyncControlStatus      *db.SyncControlStatus
+	BestHeight             uint32
 }
 
 func (s *InternalServer) newTemplateData(r *http.Request) *InternalTemplateData {
 	t := &InternalTemplateData{
-		CoinName:     s.is.Coin,
-		CoinShortcut: s.is.CoinShortcut,
-		CoinLabel:    s.is.CoinLabel,
-		ChainType:    s.chainParser.GetChainType(),
+		CoinName:           s.is.Coin,
+		CoinShortcut:       s.is.CoinShortcut,
+		CoinLabel:          s.is.CoinLabel,
+		ChainType:          s.chainParser.GetChainType(),
+		WebhooksEnabled:    s.webhooks != nil,
+		SyncControlEnabled: s.syncControl != nil,
 	}
 	return t
 }
@@ -160,7 +214,8 @@ func (s *InternalServer) newTemplateDataWithError(error *api.APIError, r *http.R
 
 func (s *InternalServer) parseTemplates() []*template.Template {
 	templateFuncMap := template.FuncMap{
-		"formatUint32": formatUint32,
+		"formatUint32":   formatUint32,
+		"formatUnixTime": formatUnixTime,
 	}
 	createTemplate := func(filenames ...string) *template.Template {
 		if len(filenames) == 0 {
@@ -174,6 +229,9 @@ func (s *InternalServer) parseTemplates() []*template.Template {
 	t[adminIndexTpl] = createTemplate("./static/internal_templates/index.html", "./static/internal_templates/base.html")
 	t[adminInternalErrorsTpl] = createTemplate("./static/internal_templates/block_internal_data_errors.html", "./static/internal_templates/base.html")
 	t[adminLimitExceedingIPS] = createTemplate("./static/internal_templates/ws_limit_exceeding_ips.html", "./static/internal_templates/base.html")
+	t[adminWebhooksTpl] = createTemplate("./static/internal_templates/webhooks.html", "./static/internal_templates/base.html")
+	t[adminVerifyDbTpl] = createTemplate("./static/internal_templates/verify_db.html", "./static/internal_templates/base.html")
+	t[adminSyncTpl] = createTemplate("./static/internal_templates/sync.html", "./static/internal_templates/base.html")
 	return t
 }
 
@@ -215,3 +273,350 @@ func (s *InternalServer) wsLimitExceedingIPs(w http.ResponseWriter, r *http.Requ
 	data.WsGetAccountInfoLimit = s.is.WsGetAccountInfoLimit
 	return adminLimitExceedingIPS, data, nil
 }
+
+// rocksDB returns the index as RocksDB, the verification, the checkpoints and the changes of the contract ABIs are supported only by RocksDB
+func (s *InternalServer) rocksDB() (*db.RocksDB, error) {
+	d, ok := s.db.(*db.RocksDB)
+	if !ok {
+		return nil, api.NewAPIError("The operation is not supported by the index", true)
+	}
+	return d, nil
+}
+
+// default pause of the background db verification after each 1000 verified addresses
+const verifyDbDefaultThrottle = 100 * time.Millisecond
+
+// adminVerifyDb shows the progress of the db verification, POST starts the throttled verification in background or stops it
+func (s *InternalServer) adminVerifyDb(w http.ResponseWriter, r *http.Request) (tpl, *InternalTemplateData, error) {
+	d, err := s.rocksDB()
+	if err != nil {
+		return errorTpl, nil, err
+	}
+	if r.Method == http.MethodPost {
+		if r.FormValue("stop") != "" {
+			d.StopVerifyIndex()
+		} else {
+			throttle := verifyDbDefaultThrottle
+			if t := r.FormValue("throttle"); t != "" {
+				ms, err := strconv.Atoi(t)
+				if err != nil || ms < 0 {
+					return errorTpl, nil, api.NewAPIError("Parameter 'throttle' is not a valid number", true)
+				}
+				throttle = time.Duration(ms) * time.Millisecond
+			}
+			if err := d.StartVerifyIndex(throttle); err != nil {
+				return errorTpl, nil, api.NewAPIError(err.Error(), true)
+			}
+		}
+	}
+	data := s.newTemplateData(r)
+	data.VerifyProgress = d.GetVerifyProgress()
+	return adminVerifyDbTpl, data, nil
+}
+
+// apiVerifyDbProgress returns the progress of the db verification
+func (s *InternalServer) apiVerifyDbProgress(w http.ResponseWriter, r *http.Request) {
+	d, err := s.rocksDB()
+	if err != nil {
+		writeInternalJSON(w, nil, err)
+		return
+	}
+	writeInternalJSON(w, d.GetVerifyProgress(), nil)
+}
+
+// adminAuthHandler allows the request only with the basic authentication given by the -adminauth parameter,
+// without the parameter the admin actions are disabled
+func (s *InternalServer) adminAuthHandler(handler func(w http.ResponseWriter, r *http.Request)) func(w http.ResponseWriter, r *http.Request) {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if s.adminUser == "" {
+			http.Error(w, "Admin actions are disabled, run Blockbook with the -adminauth parameter", http.StatusForbidden)
+			return
+		}
+		user, password, ok := r.BasicAuth()
+		if !ok || subtle.ConstantTimeCompare([]byte(user), []byte(s.adminUser)) != 1 || subtle.ConstantTimeCompare([]byte(password), []byte(s.adminPassword)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Basic realm="blockbook admin", charset="UTF-8"`)
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+		handler(w, r)
+	}
+}
+
+// requestSyncAction validates the admin action and passes it to the synchronization control
+func (s *InternalServer) requestSyncAction(action string, height uint32) error {
+	switch action {
+	case db.SyncActionPause:
+		s.syncControl.Pause()
+		return nil
+	case db.SyncActionResume:
+		s.syncControl.Resume()
+		return nil
+	case db.SyncActionRollback:
+		bestHeight, _, err := s.db.GetBestBlock()
+		if err != nil {
+			return err
+		}
+		if height > bestHeight {
+			return api.NewAPIError(fmt.Sprintf("Height %d is above the best block height %d", height, bestHeight), true)
+		}
+	case db.SyncActionRefetchInternalData:
+		if s.chainParser.GetChainType() != bchain.ChainEthereumType {
+			return api.NewAPIError("Refetch of internal data is supported only by Ethereum type coins", true)
+		}
+	case db.SyncActionComputeColumnStats:
+	default:
+		return api.NewAPIError("Unknown action", true)
+	}
+	if err := s.syncControl.Request(action, height); err != nil {
+		return api.NewAPIError(err.Error(), true)
+	}
+	return nil
+}
+
+// adminSync shows the state of the synchronization control, POST requests an admin action
+func (s *InternalServer) adminSync(w http.ResponseWriter, r *http.Request) (tpl, *InternalTemplateData, error) {
+	if r.Method == http.MethodPost {
+		var height uint64
+		if h := r.FormValue("height"); h != "" {
+			var err error
+			if height, err = strconv.ParseUint(h, 10, 32); err != nil {
+				return errorTpl, nil, api.NewAPIError("Parameter 'height' is not a valid number", true)
+			}
+		} else if r.FormValue("action") == db.SyncActionRollback {
+			return errorTpl, nil, api.NewAPIError("Missing parameter 'height'", true)
+		}
+		if err := s.requestSyncAction(r.FormValue("action"), uint32(height)); err != nil {
+			return errorTpl, nil, err
+		}
+	}
+	data := s.newTemplateData(r)
+	bestHeight, _, err := s.db.GetBestBlock()
+	if err != nil {
+		return errorTpl, nil, err
+	}
+	data.BestHeight = bestHeight
+	data.SyncControlStatus = s.syncControl.Status()
+	return adminSyncTpl, data, nil
+}
+
+// apiSyncActions returns (GET) the state of the synchronization control or requests (POST) an admin action
+func (s *InternalServer) apiSyncActions(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeInternalJSON(w, s.syncControl.Status(), nil)
+	case http.MethodPost:
+		var req struct {
+			Action string  `json:"action"`
+			Height *uint32 `json:"height"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeInternalJSON(w, nil, api.NewAPIError("Invalid request body", true))
+			return
+		}
+		if req.Action == db.SyncActionRollback && req.Height == nil {
+			writeInternalJSON(w, nil, api.NewAPIError("Missing parameter 'height'", true))
+			return
+		}
+		var height uint32
+		if req.Height != nil {
+			height = *req.Height
+		}
+		if err := s.requestSyncAction(req.Action, height); err != nil {
+			writeInternalJSON(w, nil, err)
+			return
+		}
+		writeInternalJSON(w, s.syncControl.Status(), nil)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// number of the newest entries of the webhook delivery log shown in the admin page
+const webhookDeliveriesInAdmin = 100
+const maxWebhookDeliveries = 10000
+
+func formatUnixTime(ut int64) string {
+	if ut == 0 {
+		return ""
+	}
+	return time.Unix(ut, 0).UTC().Format("2006-01-02 15:04:05")
+}
+
+func splitWebhookDescriptors(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\n' || r == '\r' || r == '\t'
+	})
+}
+
+func (s *InternalServer) adminWebhooks(w http.ResponseWriter, r *http.Request) (tpl, *InternalTemplateData, error) {
+	data := s.newTemplateData(r)
+	if r.Method == http.MethodPost {
+		if id := r.FormValue("remove"); id != "" {
+			if err := s.webhooks.RemoveSubscription(id); err != nil {
+				return errorTpl, nil, err
+			}
+		} else {
+			var confirmations uint64
+			var err error
+			if c := r.FormValue("confirmations"); c != "" {
+				if confirmations, err = strconv.ParseUint(c, 10, 32); err != nil {
+					return errorTpl, nil, api.NewAPIError("Parameter 'confirmations' is not a valid number", true)
+				}
+			}
+			ws, err := s.webhooks.AddSubscription(strings.TrimSpace(r.FormValue("url")), strings.TrimSpace(r.FormValue("secret")), splitWebhookDescriptors(r.FormValue("descriptors")), uint32(confirmations))
+			if err != nil {
+				return errorTpl, nil, err
+			}
+			data.NewWebhookSubscription = ws
+		}
+	}
+	data.WebhookSubscriptions = s.webhooks.Subscriptions()
+	deliveries, err := s.webhooks.Deliveries(webhookDeliveriesInAdmin)
+	if err != nil {
+		return errorTpl, nil, err
+	}
+	data.WebhookDeliveries = deliveries
+	return adminWebhooksTpl, data, nil
+}
+
+func writeInternalJSON(w http.ResponseWriter, data interface{}, err error) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	if err != nil {
+		if apiErr, ok := err.(*api.APIError); ok && apiErr.Public {
+			w.WriteHeader(http.StatusBadRequest)
+		} else {
+			glog.Error(err)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+		data = struct {
+			Error string `json:"error"`
+		}{err.Error()}
+	}
+	if err := json.NewEncoder(w).Encode(data); err != nil {
+		glog.Error(err)
+	}
+}
+
+// apiWebhookSubscriptions lists (GET), adds (POST) or removes (DELETE) webhook subscriptions
+func (s *InternalServer) apiWebhookSubscriptions(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		writeInternalJSON(w, s.webhooks.Subscriptions(), nil)
+	case http.MethodPost:
+		var req struct {
+			URL           string   `json:"url"`
+			Secret        string   `json:"secret"`
+			Descriptors   []string `json:"descriptors"`
+			Confirmations uint32   `json:"confirmations"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeInternalJSON(w, nil, api.NewAPIError("Invalid request body", true))
+			return
+		}
+		ws, err := s.webhooks.AddSubscription(req.URL, req.Secret, req.Descriptors, req.Confirmations)
+		writeInternalJSON(w, ws, err)
+	case http.MethodDelete:
+		err := s.webhooks.RemoveSubscription(r.URL.Query().Get("id"))
+		writeInternalJSON(w, struct {
+			Result string `json:"result"`
+		}{"ok"}, err)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// apiWebhookDeliveries returns the newest entries of the webhook delivery log
+func (s *InternalServer) apiWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	limit := webhookDeliveriesInAdmin
+	if l, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && l > 0 {
+		limit = l
+		if limit > maxWebhookDeliveries {
+			limit = maxWebhookDeliveries
+		}
+	}
+	deliveries, err := s.webhooks.Deliveries(limit)
+	writeInternalJSON(w, deliveries, err)
+}
+
+// apiCheckpoint creates (POST) a checkpoint of the database in the subdirectory given by the name parameter
+// of the directory configured by the -checkpointsdir parameter, without the name the subdirectory is named by the current time
+func (s *InternalServer) apiCheckpoint(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	if s.checkpoints == "" {
+		writeInternalJSON(w, nil, api.NewAPIError("Checkpoints are disabled, run Blockbook with the -checkpointsdir parameter", true))
+		return
+	}
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		name = time.Now().UTC().Format("20060102-150405")
+	}
+	if name == "." || name == ".." || strings.ContainsAny(name, `/\`) {
+		writeInternalJSON(w, nil, api.NewAPIError("Parameter name must be a plain directory name", true))
+		return
+	}
+	d, err := s.rocksDB()
+	if err != nil {
+		writeInternalJSON(w, nil, err)
+		return
+	}
+	m, err := d.CreateCheckpoint(filepath.Join(s.checkpoints, name))
+	writeInternalJSON(w, m, err)
+}
+
+// maximum size of the uploaded contract ABI
+const maxContractABISize = 8 << 20
+
+// apiContractABI returns (GET), stores (POST) or removes (DELETE) the verified ABI of the contract given by the contract parameter,
+// the body of the POST request is the ABI in the JSON format
+func (s *InternalServer) apiContractABI(w http.ResponseWriter, r *http.Request) {
+	contract, err := s.chainParser.GetAddrDescFromAddress(r.URL.Query().Get("contract"))
+	if err != nil || len(contract) == 0 {
+		writeInternalJSON(w, nil, api.NewAPIError("Missing or invalid parameter 'contract'", true))
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		a, err := s.db.GetContractABI(contract)
+		if err != nil {
+			writeInternalJSON(w, nil, err)
+			return
+		}
+		if a == nil {
+			writeInternalJSON(w, nil, api.NewAPIError("Contract ABI not found", true))
+			return
+		}
+		writeInternalJSON(w, json.RawMessage(a.Data), nil)
+	case http.MethodPost:
+		data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxContractABISize))
+		if err != nil {
+			writeInternalJSON(w, nil, api.NewAPIError("Invalid request body", true))
+			return
+		}
+		d, err := s.rocksDB()
+		if err != nil {
+			writeInternalJSON(w, nil, err)
+			return
+		}
+		a, err := d.StoreContractABI(contract, data)
+		if err != nil {
+			writeInternalJSON(w, nil, api.NewAPIError(err.Error(), true))
+			return
+		}
+		writeInternalJSON(w, json.RawMessage(a.Data), nil)
+	case http.MethodDelete:
+		d, err := s.rocksDB()
+		if err != nil {
+			writeInternalJSON(w, nil, err)
+			return
+		}
+		err = d.DeleteContractABI(contract)
+		writeInternalJSON(w, struct {
+			Result string `json:"result"`
+		}{"ok"}, err)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
@@ -2,6 +2,7 @@ package server
 
 import (
 	"context"
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
 	"html/template"
@@ -49,7 +50,7 @@ type PublicServer struct {
 	socketio            *SocketIoServer
 	websocket           *WebsocketServer
 	https               *http.Server
-	db                  *db.RocksDB
+	db                  db.Store
 	txCache             *db.TxCache
 	chain               bchain.BlockChain
 	chainParser         bchain.BlockChainParser
@@ -64,7 +65,7 @@ type PublicServer struct {
 
 // NewPublicServer creates new public server http interface to blockbook and returns its handle
 // only basic functionality is mapped, to map all functions, call
-func NewPublicServer(binding string, certFiles string, db *db.RocksDB, chain bchain.BlockChain, mempool bchain.Mempool, txCache *db.TxCache, explorerURL string, metrics *common.Metrics, is *common.InternalState, fiatRates *fiat.FiatRates, debugMode bool) (*PublicServer, error) {
+func NewPublicServer(binding string, certFiles string, db db.Store, chain bchain.BlockChain, mempool bchain.Mempool, txCache *db.TxCache, explorerURL string, metrics *common.Metrics, is *common.InternalState, fiatRates *fiat.FiatRates, debugMode bool) (*PublicServer, error) {
 
 	api, err := api.NewWorker(db, chain, mempool, txCache, metrics, is, fiatRates)
 	if err != nil {
@@ -199,6 +200,7 @@ func (s *PublicServer) ConnectFullPublicInterface() {
 	serveMux.HandleFunc(path+"api/v2/block-index/", s.jsonHandler(s.apiBlockIndex, apiV2))
 	serveMux.HandleFunc(path+"api/v2/block-filters/", s.jsonHandler(s.apiBlockFilters, apiV2))
 	serveMux.HandleFunc(path+"api/v2/tx-specific/", s.jsonHandler(s.apiTxSpecific, apiV2))
+	serveMux.HandleFunc(path+"api/v2/tx-proof/", s.jsonHandler(s.apiTxProof, apiV2))
 	serveMux.HandleFunc(path+"api/v2/tx/", s.jsonHandler(s.apiTx, apiV2))
 	serveMux.HandleFunc(path+"api/v2/address/", s.jsonHandler(s.apiAddress, apiV2))
 	serveMux.HandleFunc(path+"api/v2/xpub/", s.jsonHandler(s.apiXpub, apiV2))
@@ -209,6 +211,13 @@ func (s *PublicServer) ConnectFullPublicInterface() {
 	serveMux.HandleFunc(path+"api/v2/estimatefee/", s.jsonHandler(s.apiEstimateFee, apiV2))
 	serveMux.HandleFunc(path+"api/v2/feestats/", s.jsonHandler(s.apiFeeStats, apiV2))
 	serveMux.HandleFunc(path+"api/v2/balancehistory/", s.jsonHandler(s.apiBalanceHistory, apiDefault))
+	serveMux.HandleFunc(path+"api/v2/export/", s.apiExport)
+	serveMux.HandleFunc(path+"api/v2/state/", s.jsonHandler(s.apiStateAt, apiV2))
+	serveMux.HandleFunc(path+"api/v2/approvals/", s.jsonHandler(s.apiTokenApprovals, apiV2))
+	serveMux.HandleFunc(path+"api/v2/logs", s.jsonHandler(s.apiContractLogs, apiV2))
+	serveMux.HandleFunc(path+"api/v2/decode-return", s.jsonHandler(s.apiDecodeReturnData, apiV2))
+	serveMux.HandleFunc(path+"api/v2/mempool/conflicts/", s.jsonHandler(s.apiMempoolConflicts, apiV2))
+	serveMux.HandleFunc(path+"api/v2/mempool/package/", s.jsonHandler(s.apiMempoolPackage, apiV2))
 	serveMux.HandleFunc(path+"api/v2/tickers/", s.jsonHandler(s.apiTickers, apiV2))
 	serveMux.HandleFunc(path+"api/v2/multi-tickers/", s.jsonHandler(s.apiMultiTickers, apiV2))
 	serveMux.HandleFunc(path+"api/v2/tickers-list/", s.jsonHandler(s.apiAvailableVsCurrencies, apiV2))
@@ -236,6 +245,11 @@ func (s *PublicServer) OnNewBlock(hash string, height uint32) {
 	s.websocket.OnNewBlock(hash, height)
 }
 
+// OnDisconnectBlocks notifies users subscribed to transaction status about blocks disconnected by a fork
+func (s *PublicServer) OnDisconnectBlocks(lower uint32, higher uint32, hashes []string) {
+	s.websocket.OnDisconnectBlocks(lower, higher, hashes)
+}
+
 // OnNewFiatRatesTicker notifies users subscribed to bitcoind/fiatrates about new ticker
 func (s *PublicServer) OnNewFiatRatesTicker(ticker *common.CurrencyRatesTicker) {
 	s.websocket.OnNewFiatRatesTicker(ticker)
@@ -860,7 +874,7 @@ func (s *PublicServer) explorerSpendingTx(w http.ResponseWriter, r *http.Request
 	return errorTpl, nil, err
 }
 
-func (s *PublicServer) getAddressQueryParams(r *http.Request, accountDetails api.AccountDetails, maxPageSize int) (int, int, api.AccountDetails, *api.AddressFilter, string, int) {
+func (s *PublicServer) getAddressQueryParams(r *http.Request, accountDetails api.AccountDetails, maxPageSize int) (int, int, api.AccountDetails, *api.AddressFilter, string, int, error) {
 	var voutFilter = api.AddressFilterVoutOff
 	page, ec := strconv.Atoi(r.URL.Query().Get("page"))
 	if ec != nil {
@@ -919,13 +933,20 @@ func (s *PublicServer) getAddressQueryParams(r *http.Request, accountDetails api
 		gap = 0
 	}
 	contract := r.URL.Query().Get("contract")
+	var cursor *api.TxCursor
+	if c := r.URL.Query().Get("cursor"); c != "" {
+		if cursor, ec = api.ParseTxCursor(c); ec != nil {
+			return 0, 0, 0, nil, "", 0, ec
+		}
+	}
 	return page, pageSize, accountDetails, &api.AddressFilter{
 		Vout:           voutFilter,
 		TokensToReturn: tokensToReturn,
 		FromHeight:     uint32(from),
 		ToHeight:       uint32(to),
 		Contract:       contract,
-	}, filterParam, gap
+		Cursor:         cursor,
+	}, filterParam, gap, nil
 }
 
 func (s *PublicServer) explorerAddress(w http.ResponseWriter, r *http.Request) (tpl, *TemplateData, error) {
@@ -938,7 +959,10 @@ func (s *PublicServer) explorerAddress(w http.ResponseWriter, r *http.Request) (
 		return errorTpl, nil, api.NewAPIError("Missing address", true)
 	}
 	s.metrics.ExplorerViews.With(common.Labels{"action": "address"}).Inc()
-	page, _, _, filter, filterParam, _ := s.getAddressQueryParams(r, api.AccountDetailsTxHistoryLight, txsOnPage)
+	page, _, _, filter, filterParam, _, err := s.getAddressQueryParams(r, api.AccountDetailsTxHistoryLight, txsOnPage)
+	if err != nil {
+		return errorTpl, nil, err
+	}
 	// do not allow details to be changed by query params
 	data := s.newTemplateData(r)
 	address, err := s.api.GetAddress(addressParam, page, txsOnPage, api.AccountDetailsTxHistoryLight, filter, strings.ToLower(data.SecondaryCoin))
@@ -992,7 +1016,10 @@ func (s *PublicServer) explorerXpub(w http.ResponseWriter, r *http.Request) (tpl
 	}
 	s.metrics.ExplorerViews.With(common.Labels{"action": "xpub"}).Inc()
 	// do not allow txsOnPage and details to be changed by query params
-	page, _, _, filter, filterParam, gap := s.getAddressQueryParams(r, api.AccountDetailsTxHistoryLight, txsOnPage)
+	page, _, _, filter, filterParam, gap, err := s.getAddressQueryParams(r, api.AccountDetailsTxHistoryLight, txsOnPage)
+	if err != nil {
+		return errorTpl, nil, err
+	}
 	data := s.newTemplateData(r)
 	address, err := s.api.GetXpubAddress(xpub, page, txsOnPage, api.AccountDetailsTxHistoryLight, filter, gap, strings.ToLower(data.SecondaryCoin))
 	if err != nil {
@@ -1345,6 +1372,110 @@ func (s *PublicServer) apiTx(r *http.Request, apiVersion int) (interface{}, erro
 	return tx, err
 }
 
+func (s *PublicServer) apiTokenApprovals(r *http.Request, apiVersion int) (interface{}, error) {
+	var address string
+	i := strings.LastIndexByte(r.URL.Path, '/')
+	if i > 0 {
+		address = r.URL.Path[i+1:]
+	}
+	if len(address) == 0 {
+		return nil, api.NewAPIError("Missing address", true)
+	}
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-approvals"}).Inc()
+	return s.api.GetTokenApprovals(address)
+}
+
+func (s *PublicServer) apiContractLogs(r *http.Request, apiVersion int) (interface{}, error) {
+	q := r.URL.Query()
+	contract := q.Get("contract")
+	if len(contract) == 0 {
+		return nil, api.NewAPIError("Missing contract", true)
+	}
+	topic0 := q.Get("topic0")
+	if len(topic0) == 0 {
+		return nil, api.NewAPIError("Missing topic0", true)
+	}
+	page, ec := strconv.Atoi(q.Get("page"))
+	if ec != nil {
+		page = 0
+	}
+	pageSize, ec := strconv.Atoi(q.Get("pageSize"))
+	if ec != nil || pageSize <= 0 || pageSize > txsInAPI {
+		pageSize = txsInAPI
+	}
+	from, ec := strconv.Atoi(q.Get("from"))
+	if ec != nil {
+		from = 0
+	}
+	to, ec := strconv.Atoi(q.Get("to"))
+	if ec != nil {
+		to = 0
+	}
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-logs"}).Inc()
+	return s.api.GetContractLogs(contract, topic0, from, to, page, pageSize)
+}
+
+func (s *PublicServer) apiDecodeReturnData(r *http.Request, apiVersion int) (interface{}, error) {
+	q := r.URL.Query()
+	contract := q.Get("contract")
+	if len(contract) == 0 {
+		return nil, api.NewAPIError("Missing contract", true)
+	}
+	input := q.Get("input")
+	if len(input) == 0 {
+		return nil, api.NewAPIError("Missing input", true)
+	}
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-decode-return"}).Inc()
+	return s.api.DecodeReturnData(contract, input, q.Get("output"))
+}
+
+func (s *PublicServer) apiMempoolConflicts(r *http.Request, apiVersion int) (interface{}, error) {
+	var txid string
+	i := strings.LastIndexByte(r.URL.Path, '/')
+	if i > 0 {
+		txid = r.URL.Path[i+1:]
+	}
+	if len(txid) == 0 {
+		return nil, api.NewAPIError("Missing txid", true)
+	}
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-mempool-conflicts"}).Inc()
+	return s.api.GetMempoolConflicts(txid)
+}
+
+func (s *PublicServer) apiMempoolPackage(r *http.Request, apiVersion int) (interface{}, error) {
+	var txid string
+	i := strings.LastIndexByte(r.URL.Path, '/')
+	if i > 0 {
+		txid = r.URL.Path[i+1:]
+	}
+	if len(txid) == 0 {
+		return nil, api.NewAPIError("Missing txid", true)
+	}
+	var targetFeePerKb int64
+	if t := r.URL.Query().Get("targetFeePerKb"); t != "" {
+		var err error
+		targetFeePerKb, err = strconv.ParseInt(t, 10, 64)
+		if err != nil || targetFeePerKb < 0 {
+			return nil, api.NewAPIError("Parameter 'targetFeePerKb' is not a valid number", true)
+		}
+	}
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-mempool-package"}).Inc()
+	return s.api.GetMempoolPackage(txid, targetFeePerKb)
+}
+
+func (s *PublicServer) apiTxProof(r *http.Request, apiVersion int) (interface{}, error) {
+	var txid string
+	i := strings.LastIndexByte(r.URL.Path, '/')
+	if i > 0 {
+		txid = r.URL.Path[i+1:]
+	}
+	if len(txid) == 0 {
+		return nil, api.NewAPIError("Missing txid", true)
+	}
+	s.metrics.ExplorerViews.With(common.Labels{"action": "api-tx-proof"}).Inc()
+	return s.api.GetTransactionProof(txid)
+}
+
 func (s *PublicServer) apiTxSpecific(r *http.Request, apiVersion int) (interface{}, error) {
 	var txid string
 	i := strings.LastIndexByte(r.URL.Path, '/')
@@ -1376,7 +1507,10 @@ func (s *PublicServer) apiAddress(r *http.Request, apiVersion int) (interface{},
 	var address *api.Address
 	var err error
 	s.metrics.ExplorerViews.With(common.Labels{"action": "api-address"}).Inc()
-	page, pageSize, details, filter, _, _ := s.getAddressQueryParams(r, api.AccountDetailsTxidHistory, txsInAPI)
+	page, pageSize, details, filter, _, _, err := s.getAddressQueryParams(r, api.AccountDetailsTxidHistory, txsInAPI)
+	if err != nil {
+		return nil, err
+	}
 	secondaryCoin := strings.ToLower(r.URL.Query().Get("secondary"))
 	address, err = s.api.GetAddress(addressParam, page, pageSize, details, filter, secondaryCoin)
 	if err == nil && apiVersion == apiV1 {
@@ -1397,7 +1531,10 @@ func (s *PublicServer) apiXpub(r *http.Request, apiVersion int) (interface{}, er
 	var address *api.Address
 	var err error
 	s.metrics.ExplorerViews.With(common.Labels{"action": "api-xpub"}).Inc()
-	page, pageSize, details, filter, _, gap := s.getAddressQueryParams(r, api.AccountDetailsTxidHistory, txsInAPI)
+	page, pageSize, details, filter, _, gap, err := s.getAddressQueryParams(r, api.AccountDetailsTxidHistory, txsInAPI)
+	if err != nil {
+		return nil, err
+	}
 	secondaryCoin := strings.ToLower(r.URL.Query().Get("secondary"))
 	address, err = s.api.GetXpubAddress(xpub, page, pageSize, details, filter, gap, secondaryCoin)
 	if err == nil && apiVersion == apiV1 {
@@ -1484,6 +1621,143 @@ func (s *PublicServer) apiBalanceHistory(r *http.Request, apiVersion int) (inter
 	return history, err
 }
 
+func (s *PublicServer) apiStateAt(r *http.Request, apiVersion int) (interface{}, error) {
+	var desc string
+	if i := strings.LastIndexByte(r.URL.Path, '/'); i > 0 {
+		desc = r.URL.Path[i+1:]
+	}
+	if len(desc) == 0 {
+		return nil, api.NewAPIError("Missing address or xpub", true)
+	}
+	var height uint32
+	if h := r.URL.Query().Get("height"); h != "" {
+		v, err := strconv.ParseUint(h, 10, 32)
+		if err != nil {
+			return nil, api.NewAPIError("Parameter 'height' is not a valid block height", true)
+		}
+		height = uint32(v)
+	} else if t := r.URL.Query().Get("time"); t != "" {
+		v, err := strconv.ParseInt(t, 10, 64)
+		if err != nil {
+			return nil, api.NewAPIError("Parameter 'time' is not a valid timestamp", true)
+		}
+		if height, err = s.api.GetBlockHeightAtTime(v); err != nil {
+			return nil, err
+		}
+	} else {
+		return nil, api.NewAPIError("Missing parameter 'height' or 'time'", true)
+	}
+	gap, ec := strconv.Atoi(r.URL.Query().Get("gap"))
+	if ec != nil {
+		gap = 0
+	}
+	state, err := s.api.GetXpubStateAt(desc, height, gap)
+	if err == nil {
+		s.metrics.ExplorerViews.With(common.Labels{"action": "api-xpub-state"}).Inc()
+	} else {
+		state, err = s.api.GetAddressStateAt(desc, height)
+		s.metrics.ExplorerViews.With(common.Labels{"action": "api-address-state"}).Inc()
+	}
+	return state, err
+}
+
+// apiExport streams the whole transaction history of an address or xpub in csv or ndjson format
+// it does not use jsonHandler as the response is not a single json object
+func (s *PublicServer) apiExport(w http.ResponseWriter, r *http.Request) {
+	var export *api.Export
+	var action string
+	var err error
+	s.metrics.ExplorerPendingRequests.With((common.Labels{"method": "apiExport"})).Inc()
+	defer s.metrics.ExplorerPendingRequests.With((common.Labels{"method": "apiExport"})).Dec()
+	format := strings.ToLower(r.URL.Query().Get("format"))
+	if format == "" {
+		format = "csv"
+	}
+	var desc string
+	if i := strings.LastIndex(r.URL.Path, "export/"); i > 0 {
+		desc = r.URL.Path[i+7:]
+	}
+	if len(desc) == 0 {
+		err = api.NewAPIError("Missing address or xpub", true)
+	} else if format != "csv" && format != "ndjson" {
+		err = api.NewAPIError("Unsupported format, use csv or ndjson", true)
+	} else {
+		gap, ec := strconv.Atoi(r.URL.Query().Get("gap"))
+		if ec != nil {
+			gap = 0
+		}
+		fiat := r.URL.Query().Get("fiatcurrency")
+		export, err = s.api.PrepareXpubExport(desc, gap, fiat)
+		action = "api-xpub-export"
+		if err != nil {
+			export, err = s.api.PrepareAddressExport(desc, fiat)
+			action = "api-address-export"
+		}
+	}
+	if err != nil {
+		status := http.StatusInternalServerError
+		text := "Internal server error"
+		if apiErr, ok := err.(*api.APIError); ok && apiErr.Public {
+			status = http.StatusBadRequest
+			text = apiErr.Error()
+		} else {
+			glog.Error("apiExport error: ", err)
+			if s.debug {
+				text = fmt.Sprintf("Internal server error: %v", err)
+			}
+		}
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(status)
+		if err = json.NewEncoder(w).Encode(struct {
+			Text string `json:"error"`
+		}{text}); err != nil {
+			glog.Warning("json encode ", err)
+		}
+		return
+	}
+	defer export.Close()
+	// once the streaming started, the errors can be only logged, the response is then truncated
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="export-%d.csv"`, export.Height))
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"txid", "blockHeight", "blockTime", "balanceDelta", "balance", "fees", "fiatRate", "fiatValue"})
+		err = export.Run(func(t *api.ExportTx) error {
+			var fiatRate, fiatValue string
+			if t.FiatRate != 0 {
+				fiatRate = strconv.FormatFloat(t.FiatRate, 'f', -1, 64)
+				fiatValue = strconv.FormatFloat(t.FiatValue, 'f', -1, 64)
+			}
+			return cw.Write([]string{
+				t.Txid,
+				strconv.FormatUint(uint64(t.Blockheight), 10),
+				strconv.FormatInt(t.Blocktime, 10),
+				t.BalanceDelta.String(),
+				t.Balance.String(),
+				t.FeesSat.String(),
+				fiatRate,
+				fiatValue,
+			})
+		})
+		cw.Flush()
+		if err == nil {
+			err = cw.Error()
+		}
+	} else {
+		w.Header().Set("Content-Type", "application/x-ndjson; charset=utf-8")
+		w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="export-%d.ndjson"`, export.Height))
+		enc := json.NewEncoder(w)
+		err = export.Run(func(t *api.ExportTx) error {
+			return enc.Encode(t)
+		})
+	}
+	if err != nil {
+		glog.Error("apiExport ", desc, " error: ", err)
+		return
+	}
+	s.metrics.ExplorerViews.With(common.Labels{"action": action}).Inc()
+}
+
 func (s *PublicServer) apiBlock(r *http.Request, apiVersion int) (interface{}, error) {
 	var block *api.Block
 	var err error
@@ -1639,7 +1913,8 @@ func (s *PublicServer) apiMultiTickers(r *http.Request, apiVersion int) (interfa
 }
 
 type resultEstimateFeeAsString struct {
-	Result string `json:"result"`
+	Result  string           `json:"result"`
+	Eip1559 *api.Eip1559Fees `json:"eip1559,omitempty"`
 }
 
 func (s *PublicServer) apiEstimateFee(r *http.Request, apiVersion int) (interface{}, error) {
@@ -1669,6 +1944,12 @@ func (s *PublicServer) apiEstimateFee(r *http.Request, apiVersion int) (interfac
 				}
 			}
 			res.Result = s.chainParser.AmountToDecimalString(&fee)
+			if s.chainParser.GetChainType() == bchain.ChainEthereumType {
+				res.Eip1559, err = s.api.EstimateEip1559Fees()
+				if err != nil {
+					glog.V(1).Info("apiEstimateFee: EIP-1559 fees not available: ", err)
+				}
+			}
 			return res, nil
 		}
 	}
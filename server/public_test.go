@@ -126,7 +126,7 @@ func setupPublicHTTPServer(parser bchain.BlockChainParser, chain bchain.BlockCha
 	}
 
 	// caching is switched off because test transactions do not have hex data
-	txCache, err := db.NewTxCache(d, chain, metrics, is, false)
+	txCache, err := db.NewTxCache(d, chain, metrics, is, false, 0)
 	if err != nil {
 		glog.Fatal("txCache: ", err)
 	}
@@ -146,7 +146,7 @@ func setupPublicHTTPServer(parser bchain.BlockChainParser, chain bchain.BlockCha
 
 func closeAndDestroyPublicServer(t *testing.T, s *PublicServer, dbpath string) {
 	// destroy db
-	if err := s.db.Close(); err != nil {
+	if err := s.db.(*db.RocksDB).Close(); err != nil {
 		t.Fatal(err)
 	}
 	os.RemoveAll(dbpath)
@@ -393,7 +393,7 @@ func httpTestsBitcoinType(t *testing.T, ts *httptest.Server) {
 			status:      http.StatusOK,
 			contentType: "text/html; charset=utf-8",
 			body: []string{
-				`<!doctype html><html lang="en"><head><meta charset="utf-8"><meta name="viewport" content="width=device-width,initial-scale=1.0,shrink-to-fit=no"><link rel="stylesheet" href="/static/css/bootstrap.5.2.2.min.css"><link rel="stylesheet" href="/static/css/main.min.3.css"><script>var hasSecondary=false;</script><script src="/static/js/bootstrap.bundle.5.2.2.min.js"></script><script src="/static/js/main.min.3.js"></script><meta http-equiv="X-UA-Compatible" content="IE=edge"><meta name="description" content="Trezor Fake Coin Explorer"><title>Trezor Fake Coin Explorer</title></head><body><header id="header"><nav class="navbar navbar-expand-lg"><div class="container"><a class="navbar-brand" href="/" title="Home"><span class="trezor-logo"></span><span style="padding-left: 140px;">Fake Coin Explorer</span></a><button class="navbar-toggler" type="button" data-bs-toggle="collapse" data-bs-target="#navbarSupportedContent" aria-controls="navbarSupportedContent" aria-expanded="false" aria-label="Toggle navigation"><span class="navbar-toggler-icon"></span></button><div class="collapse navbar-collapse" id="navbarSupportedContent"><ul class="navbar-nav m-md-auto"><li class="nav-item pe-xl-4"><a href="/blocks" class="nav-link">Blocks</a></li><li class="nav-item"><a href="/" class="nav-link">Status</a></li></ul><span class="navbar-form"><form class="d-flex" id="search" action="/search" method="get"><input name="q" type="text" class="form-control form-control-lg" placeholder="Search for block, transaction, address or xpub" focus="true"><button class="btn" type="submit"><span class="search-icon"></span></button></form></span></div></div></nav></header><main id="wrap"><div class="container"><div class="row"><div class="col-md-10 order-2 order-md-1"><h1>XPUB</h1><h5 class="col-12 d-flex h-data pb-2"><span class="ellipsis copyable">tr([5c9e228d/86&#39;/1&#39;/0&#39;]tpubDC88gkaZi5HvJGxGDNLADkvtdpni3mLmx6vr2KnXmWMG8zfkBRggsxHVBkUpgcwPe2KKpkyvTJCdXHb1UHEWE64vczyyPQfHr1skBcsRedN/{0,1}/*)#4rqwxvej</span></h5><h4 class="row"><div class="col-lg-6"><span class="copyable">0 FAKE</span></div></h4></div><div class="col-md-2 order-1 order-md-2 d-flex justify-content-center justify-content-md-end mb-3 mb-md-0"><div id="qrcode"></div><script type="text/javascript" src="/static/js/qrcode.min.js"></script><script type="text/javascript">new QRCode(document.getElementById("qrcode"), { text: "tr([5c9e228d\/86\u0027\/1\u0027\/0\u0027]tpubDC88gkaZi5HvJGxGDNLADkvtdpni3mLmx6vr2KnXmWMG8zfkBRggsxHVBkUpgcwPe2KKpkyvTJCdXHb1UHEWE64vczyyPQfHr1skBcsRedN\/{0,1}\/*)#4rqwxvej", width: 120, height: 120 });</script></div></div><table class="table data-table info-table"><tbody><tr><td style="white-space: nowrap;"><h5>Confirmed</h5></td><td></td></tr><tr><td style="width: 25%;">Total Received</td><td><span class="amt copyable" cc="0 FAKE"><span class="prim-amt">0 FAKE</span></span></td></tr><tr><td>Total Sent</td><td><span class="amt copyable" cc="0 FAKE"><span class="prim-amt">0 FAKE</span></span></td></tr><tr><td>Final Balance</td><td><span class="amt copyable" cc="0 FAKE"><span class="prim-amt">0 FAKE</span></span></td></tr><tr><td>No. Transactions</td><td>0</td></tr><tr><td>Used XPUB Addresses</td><td>0</td></tr></tbody></table><table class="table data-table"><tbody><tr><td style="white-space: nowrap; width: 50%;"><h5>XPUB Addresses with Balance</h5></td><td colspan="3"></td></tr><tr><td colspan="4">No addresses</td></tr></tbody></table><div class="row mb-4"><div class="col-12"><a href="?tokens=used" class="ms-3 me-3">Show used XPUB addresses</a><a href="?tokens=derived">Show all derived XPUB addresses</a></div></div></div></main><footer id="footer"><div class="container"><nav class="navbar navbar-dark"><span class="navbar-nav"><a class="nav-link" href="https://satoshilabs.com/" target="_blank" rel="noopener noreferrer">Created by SatoshiLabs</a></span><span class="navbar-nav ml-md-auto"><a class="nav-link" href="https://trezor.io/terms-of-use" target="_blank" rel="noopener noreferrer">Terms of Use</a></span><span class="navbar-nav ml-md-auto d-md-flex d-none"><a class="nav-link" href="https://trezor.io/" target="_blank" rel="noopener noreferrer">Trezor</a></span><span class="navbar-nav ml-md-auto d-md-flex d-none"><a class="nav-link" href="https://trezor.io/trezor-suite" target="_blank" rel="noopener noreferrer">Suite</a></span><span class="navbar-nav ml-md-auto d-md-flex d-none"><a class="nav-link" href="https://trezor.io/support" target="_blank" rel="noopener noreferrer">Support</a></span><span class="navbar-nav ml-md-auto"><a class="nav-link" href="/sendtx">Send Transaction</a></span><span class="navbar-nav ml-md-auto d-lg-flex d-none"><a class="nav-link" href="https://trezor.io/compare" target="_blank" rel="noopener noreferrer">Don't have a Trezor? Get one!</a></span></nav></div></footer></body></html>`,
+				`<!doctype html><html lang="en"><head><meta charset="utf-8"><meta name="viewport" content="width=device-width,initial-scale=1.0,shrink-to-fit=no"><link rel="stylesheet" href="/static/css/bootstrap.5.2.2.min.css"><link rel="stylesheet" href="/static/css/main.min.3.css"><script>var hasSecondary=false;</script><script src="/static/js/bootstrap.bundle.5.2.2.min.js"></script><script src="/static/js/main.min.3.js"></script><meta http-equiv="X-UA-Compatible" content="IE=edge"><meta name="description" content="Trezor Fake Coin Explorer"><title>Trezor Fake Coin Explorer</title></head><body><header id="header"><nav class="navbar navbar-expand-lg"><div class="container"><a class="navbar-brand" href="/" title="Home"><span class="trezor-logo"></span><span style="padding-left: 140px;">Fake Coin Explorer</span></a><button class="navbar-toggler" type="button" data-bs-toggle="collapse" data-bs-target="#navbarSupportedContent" aria-controls="navbarSupportedContent" aria-expanded="false" aria-label="Toggle navigation"><span class="navbar-toggler-icon"></span></button><div class="collapse navbar-collapse" id="navbarSupportedContent"><ul class="navbar-nav m-md-auto"><li class="nav-item pe-xl-4"><a href="/blocks" class="nav-link">Blocks</a></li><li class="nav-item"><a href="/" class="nav-link">Status</a></li></ul><span class="navbar-form"><form class="d-flex" id="search" action="/search" method="get"><input name="q" type="text" class="form-control form-control-lg" placeholder="Search for block, transaction, address or xpub" focus="true"><button class="btn" type="submit"><span class="search-icon"></span></button></form></span></div></div></nav></header><main id="wrap"><div class="container"><div class="row"><div class="col-md-10 order-2 order-md-1"><h1>XPUB</h1><h5 class="col-12 d-flex h-data pb-2"><span class="ellipsis copyable">tr([5c9e228d/86&#39;/1&#39;/0&#39;]tpubDC88gkaZi5HvJGxGDNLADkvtdpni3mLmx6vr2KnXmWMG8zfkBRggsxHVBkUpgcwPe2KKpkyvTJCdXHb1UHEWE64vczyyPQfHr1skBcsRedN/{0,1}/*)#mq9rwy77</span></h5><h4 class="row"><div class="col-lg-6"><span class="copyable">0 FAKE</span></div></h4></div><div class="col-md-2 order-1 order-md-2 d-flex justify-content-center justify-content-md-end mb-3 mb-md-0"><div id="qrcode"></div><script type="text/javascript" src="/static/js/qrcode.min.js"></script><script type="text/javascript">new QRCode(document.getElementById("qrcode"), { text: "tr([5c9e228d\/86\u0027\/1\u0027\/0\u0027]tpubDC88gkaZi5HvJGxGDNLADkvtdpni3mLmx6vr2KnXmWMG8zfkBRggsxHVBkUpgcwPe2KKpkyvTJCdXHb1UHEWE64vczyyPQfHr1skBcsRedN\/{0,1}\/*)#mq9rwy77", width: 120, height: 120 });</script></div></div><table class="table data-table info-table"><tbody><tr><td style="white-space: nowrap;"><h5>Confirmed</h5></td><td></td></tr><tr><td style="width: 25%;">Total Received</td><td><span class="amt copyable" cc="0 FAKE"><span class="prim-amt">0 FAKE</span></span></td></tr><tr><td>Total Sent</td><td><span class="amt copyable" cc="0 FAKE"><span class="prim-amt">0 FAKE</span></span></td></tr><tr><td>Final Balance</td><td><span class="amt copyable" cc="0 FAKE"><span class="prim-amt">0 FAKE</span></span></td></tr><tr><td>No. Transactions</td><td>0</td></tr><tr><td>Used XPUB Addresses</td><td>0</td></tr></tbody></table><table class="table data-table"><tbody><tr><td style="white-space: nowrap; width: 50%;"><h5>XPUB Addresses with Balance</h5></td><td colspan="3"></td></tr><tr><td colspan="4">No addresses</td></tr></tbody></table><div class="row mb-4"><div class="col-12"><a href="?tokens=used" class="ms-3 me-3">Show used XPUB addresses</a><a href="?tokens=derived">Show all derived XPUB addresses</a></div></div></div></main><footer id="footer"><div class="container"><nav class="navbar navbar-dark"><span class="navbar-nav"><a class="nav-link" href="https://satoshilabs.com/" target="_blank" rel="noopener noreferrer">Created by SatoshiLabs</a></span><span class="navbar-nav ml-md-auto"><a class="nav-link" href="https://trezor.io/terms-of-use" target="_blank" rel="noopener noreferrer">Terms of Use</a></span><span class="navbar-nav ml-md-auto d-md-flex d-none"><a class="nav-link" href="https://trezor.io/" target="_blank" rel="noopener noreferrer">Trezor</a></span><span class="navbar-nav ml-md-auto d-md-flex d-none"><a class="nav-link" href="https://trezor.io/trezor-suite" target="_blank" rel="noopener noreferrer">Suite</a></span><span class="navbar-nav ml-md-auto d-md-flex d-none"><a class="nav-link" href="https://trezor.io/support" target="_blank" rel="noopener noreferrer">Support</a></span><span class="navbar-nav ml-md-auto"><a class="nav-link" href="/sendtx">Send Transaction</a></span><span class="navbar-nav ml-md-auto d-lg-flex d-none"><a class="nav-link" href="https://trezor.io/compare" target="_blank" rel="noopener noreferrer">Don't have a Trezor? Get one!</a></span></nav></div></footer></body></html>`,
 			},
 		},
 		{
@@ -490,6 +490,15 @@ func httpTestsBitcoinType(t *testing.T, ts *httptest.Server) {
 				`{"hex":"","txid":"00b2c06055e5e90e9c82bd4181fde310104391a7fa4f289b1704e5d90caa3840","version":0,"locktime":0,"vin":[],"vout":[{"ValueSat":100000000,"value":0,"n":0,"scriptPubKey":{"hex":"76a914010d39800f86122416e28f485029acf77507169288ac","addresses":null}},{"ValueSat":12345,"value":0,"n":1,"scriptPubKey":{"hex":"76a9148bdf0aa3c567aa5975c2e61321b8bebbe7293df688ac","addresses":null}},{"ValueSat":12345,"value":0,"n":2,"scriptPubKey":{"hex":"76a9148bdf0aa3c567aa5975c2e61321b8bebbe7293df688ac","addresses":null}}],"confirmations":2,"time":1521515026,"blocktime":1521515026}`,
 			},
 		},
+		{
+			name:        "apiTxProof not found",
+			r:           newGetRequest(ts.URL + "/api/v2/tx-proof/1232e48aeabdd9b75def7b48d756ba304713c2aba7b522bf9dbc893fc4231b07"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Transaction '1232e48aeabdd9b75def7b48d756ba304713c2aba7b522bf9dbc893fc4231b07' not found"}`,
+			},
+		},
 		{
 			name:        "apiFeeStats",
 			r:           newGetRequest(ts.URL + "/api/v2/feestats/225494"),
@@ -703,7 +712,7 @@ func httpTestsBitcoinType(t *testing.T, ts *httptest.Server) {
 			status:      http.StatusOK,
 			contentType: "application/json; charset=utf-8",
 			body: []string{
-				`{"page":1,"totalPages":1,"itemsOnPage":1000,"address":"tr([5c9e228d/86'/1'/0']tpubDC88gkaZi5HvJGxGDNLADkvtdpni3mLmx6vr2KnXmWMG8zfkBRggsxHVBkUpgcwPe2KKpkyvTJCdXHb1UHEWE64vczyyPQfHr1skBcsRedN/{0,1}/*)#4rqwxvej","balance":"0","totalReceived":"0","totalSent":"0","unconfirmedBalance":"0","unconfirmedTxs":0,"txs":0,"tokens":[{"type":"XPUBAddress","name":"tb1pswrqtykue8r89t9u4rprjs0gt4qzkdfuursfnvqaa3f2yql07zmq8s8a5u","path":"m/86'/1'/0'/0/0","transfers":0,"decimals":8},{"type":"XPUBAddress","name":"tb1p8tvmvsvhsee73rhym86wt435qrqm92psfsyhy6a3n5gw455znnpqm8wald","path":"m/86'/1'/0'/0/1","transfers":0,"decimals":8},{"type":"XPUBAddress","name":"tb1p537ddhyuydg5c2v75xxmn6ac64yz4xns2x0gpdcwj5vzzzgrywlqlqwk43","path":"m/86'/1'/0'/0/2","transfers":0,"decimals":8},{"type":"XPUBAddress","name":"tb1pn2d0yjeedavnkd8z8lhm566p0f2utm3lgvxrsdehnl94y34txmts5s7t4c","path":"m/86'/1'/0'/1/0","transfers":0,"decimals":8},{"type":"XPUBAddress","name":"tb1p0pnd6ue5vryymvd28aeq3kdz6rmsdjqrq6eespgtg8wdgnxjzjksujhq4u","path":"m/86'/1'/0'/1/1","transfers":0,"decimals":8},{"type":"XPUBAddress","name":"tb1p29gpmd96hhgf7wj2vs03ca7x2xx39g8t6e0p55h2d5ssqs4fsj8qtx00wc","path":"m/86'/1'/0'/1/2","transfers":0,"decimals":8}]}`,
+				`{"page":1,"totalPages":1,"itemsOnPage":1000,"address":"tr([5c9e228d/86'/1'/0']tpubDC88gkaZi5HvJGxGDNLADkvtdpni3mLmx6vr2KnXmWMG8zfkBRggsxHVBkUpgcwPe2KKpkyvTJCdXHb1UHEWE64vczyyPQfHr1skBcsRedN/{0,1}/*)#mq9rwy77","balance":"0","totalReceived":"0","totalSent":"0","unconfirmedBalance":"0","unconfirmedTxs":0,"txs":0,"tokens":[{"type":"XPUBAddress","name":"tb1pswrqtykue8r89t9u4rprjs0gt4qzkdfuursfnvqaa3f2yql07zmq8s8a5u","path":"m/86'/1'/0'/0/0","transfers":0,"decimals":8},{"type":"XPUBAddress","name":"tb1p8tvmvsvhsee73rhym86wt435qrqm92psfsyhy6a3n5gw455znnpqm8wald","path":"m/86'/1'/0'/0/1","transfers":0,"decimals":8},{"type":"XPUBAddress","name":"tb1p537ddhyuydg5c2v75xxmn6ac64yz4xns2x0gpdcwj5vzzzgrywlqlqwk43","path":"m/86'/1'/0'/0/2","transfers":0,"decimals":8},{"type":"XPUBAddress","name":"tb1pn2d0yjeedavnkd8z8lhm566p0f2utm3lgvxrsdehnl94y34txmts5s7t4c","path":"m/86'/1'/0'/1/0","transfers":0,"decimals":8},{"type":"XPUBAddress","name":"tb1p0pnd6ue5vryymvd28aeq3kdz6rmsdjqrq6eespgtg8wdgnxjzjksujhq4u","path":"m/86'/1'/0'/1/1","transfers":0,"decimals":8},{"type":"XPUBAddress","name":"tb1p29gpmd96hhgf7wj2vs03ca7x2xx39g8t6e0p55h2d5ssqs4fsj8qtx00wc","path":"m/86'/1'/0'/1/2","transfers":0,"decimals":8}]}`,
 			},
 		},
 		{
@@ -859,6 +868,175 @@ func httpTestsBitcoinType(t *testing.T, ts *httptest.Server) {
 				`[{"time":1521594000,"txs":1,"received":"118641975500","sent":"1","sentToSelf":"118641975500","rates":{"eur":1302,"usd":2002}}]`,
 			},
 		},
+		{
+			name:        "apiExport Addr5 csv",
+			r:           newGetRequest(ts.URL + "/api/v2/export/2NEVv9LJmAnY99W1pFoc5UJjVdypBqdnvu1"),
+			status:      http.StatusOK,
+			contentType: "text/csv; charset=utf-8",
+			body: []string{
+				"txid,blockHeight,blockTime,balanceDelta,balance,fees,fiatRate,fiatValue\n" +
+					"effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75,225493,1521515026,9876,9876,0,2001,0.19761876\n" +
+					"05e2e48aeabdd9b75def7b48d756ba304713c2aba7b522bf9dbc893fc4231b07,225494,1521595678,-876,9000,876,2002,-0.01753752\n",
+			},
+		},
+		{
+			name:        "apiExport Addr5 ndjson fiatcurrency=eur",
+			r:           newGetRequest(ts.URL + "/api/v2/export/2NEVv9LJmAnY99W1pFoc5UJjVdypBqdnvu1?format=ndjson&fiatcurrency=eur"),
+			status:      http.StatusOK,
+			contentType: "application/x-ndjson; charset=utf-8",
+			body: []string{
+				`{"txid":"effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75","blockHeight":225493,"blockTime":1521515026,"balanceDelta":"9876","balance":"9876","fees":"0","fiatRate":1301,"fiatValue":0.12848676}` + "\n" +
+					`{"txid":"05e2e48aeabdd9b75def7b48d756ba304713c2aba7b522bf9dbc893fc4231b07","blockHeight":225494,"blockTime":1521595678,"balanceDelta":"-876","balance":"9000","fees":"876","fiatRate":1302,"fiatValue":-0.01140552}` + "\n",
+			},
+		},
+		{
+			name:        "apiExport xpub csv",
+			r:           newGetRequest(ts.URL + "/api/v2/export/" + dbtestdata.Xpub),
+			status:      http.StatusOK,
+			contentType: "text/csv; charset=utf-8",
+			body: []string{
+				"txid,blockHeight,blockTime,balanceDelta,balance,fees,fiatRate,fiatValue\n" +
+					"effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75,225493,1521515026,1,1,0,2001,0.00002001\n" +
+					"3d90d15ed026dc45e19ffb52875ed18fa9e8012ad123d7f7212176e2b0ebdb71,225494,1521595678,118641975499,118641975500,62,2002,2375212.34948998\n",
+			},
+		},
+		{
+			name:        "apiExport invalid format",
+			r:           newGetRequest(ts.URL + "/api/v2/export/2NEVv9LJmAnY99W1pFoc5UJjVdypBqdnvu1?format=xml"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Unsupported format, use csv or ndjson"}`,
+			},
+		},
+		{
+			name:        "apiExport invalid address",
+			r:           newGetRequest(ts.URL + "/api/v2/export/1234567890"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Invalid address`,
+			},
+		},
+		{
+			name:        "apiAddress v2 details=txids&pageSize=1",
+			r:           newGetRequest(ts.URL + "/api/v2/address/" + dbtestdata.Addr4 + "?details=txids&pageSize=1"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"page":1,"totalPages":2,"itemsOnPage":1,"nextCursor":"AANw1QAAAAA","address":"2MzmAKayJmja784jyHvRUW1bXPget1csRRG","balance":"0","totalReceived":"1","totalSent":"1","unconfirmedBalance":"0","unconfirmedTxs":0,"txs":2,"txids":["3d90d15ed026dc45e19ffb52875ed18fa9e8012ad123d7f7212176e2b0ebdb71"]}`,
+			},
+		},
+		{
+			name:        "apiAddress v2 details=txids&pageSize=1&cursor",
+			r:           newGetRequest(ts.URL + "/api/v2/address/" + dbtestdata.Addr4 + "?details=txids&pageSize=1&cursor=AANw1QAAAAA"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"itemsOnPage":1,"address":"2MzmAKayJmja784jyHvRUW1bXPget1csRRG","balance":"0","totalReceived":"1","totalSent":"1","unconfirmedBalance":"0","unconfirmedTxs":0,"txs":2,"txids":["effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75"]}`,
+			},
+		},
+		{
+			name:        "apiAddress v2 invalid cursor",
+			r:           newGetRequest(ts.URL + "/api/v2/address/" + dbtestdata.Addr4 + "?details=txids&cursor=1234"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Invalid cursor"}`,
+			},
+		},
+		{
+			name:        "apiXpub v2 details=txids&pageSize=1",
+			r:           newGetRequest(ts.URL + "/api/v2/xpub/" + dbtestdata.Xpub + "?details=txids&pageSize=1"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"page":1,"totalPages":2,"itemsOnPage":1,"nextCursor":"AANw1QAAAAA","address":"upub5E1xjDmZ7Hhej6LPpS8duATdKXnRYui7bDYj6ehfFGzWDZtmCmQkZhc3Zb7kgRLtHWd16QFxyP86JKL3ShZEBFX88aciJ3xyocuyhZZ8g6q",`,
+				`"txids":["3d90d15ed026dc45e19ffb52875ed18fa9e8012ad123d7f7212176e2b0ebdb71"]`,
+			},
+		},
+		{
+			name:        "apiXpub v2 details=txids&pageSize=1&cursor",
+			r:           newGetRequest(ts.URL + "/api/v2/xpub/" + dbtestdata.Xpub + "?details=txids&pageSize=1&cursor=AANw1QAAAAA"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"itemsOnPage":1,"address":"upub5E1xjDmZ7Hhej6LPpS8duATdKXnRYui7bDYj6ehfFGzWDZtmCmQkZhc3Zb7kgRLtHWd16QFxyP86JKL3ShZEBFX88aciJ3xyocuyhZZ8g6q",`,
+				`"txids":["effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75"]`,
+			},
+		},
+		{
+			name:        "apiStateAt Addr5 height=225493",
+			r:           newGetRequest(ts.URL + "/api/v2/state/" + dbtestdata.Addr5 + "?height=225493"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"height":225493,"hash":"0000000076fbbed90fd75b0e18856aa35baa984e9c9d444cf746ad85e94e2997","blockTime":1521515026,"address":{"address":"2NEVv9LJmAnY99W1pFoc5UJjVdypBqdnvu1","balance":"9876","totalReceived":"9876","totalSent":"0","unconfirmedBalance":"0","unconfirmedTxs":0,"txs":1},"utxos":[{"txid":"effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75","vout":2,"value":"9876","height":225493,"confirmations":1}]}`,
+			},
+		},
+		{
+			name:        "apiStateAt Addr5 time=1521595678",
+			r:           newGetRequest(ts.URL + "/api/v2/state/" + dbtestdata.Addr5 + "?time=1521595678"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"height":225494,"hash":"00000000eb0443fd7dc4a1ed5c686a8e995057805f9a161d9a5a77a95e72b7b6","blockTime":1521595678,"address":{"address":"2NEVv9LJmAnY99W1pFoc5UJjVdypBqdnvu1","balance":"9000","totalReceived":"18876","totalSent":"9876","unconfirmedBalance":"0","unconfirmedTxs":0,"txs":2},"utxos":[{"txid":"05e2e48aeabdd9b75def7b48d756ba304713c2aba7b522bf9dbc893fc4231b07","vout":0,"value":"9000","height":225494,"confirmations":1}]}`,
+			},
+		},
+		{
+			name:        "apiStateAt xpub height=225493",
+			r:           newGetRequest(ts.URL + "/api/v2/state/" + dbtestdata.Xpub + "?height=225493"),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"height":225493,"hash":"0000000076fbbed90fd75b0e18856aa35baa984e9c9d444cf746ad85e94e2997","blockTime":1521515026,"address":{"address":"upub5E1xjDmZ7Hhej6LPpS8duATdKXnRYui7bDYj6ehfFGzWDZtmCmQkZhc3Zb7kgRLtHWd16QFxyP86JKL3ShZEBFX88aciJ3xyocuyhZZ8g6q","balance":"1","totalReceived":"1","totalSent":"0","unconfirmedBalance":"0","unconfirmedTxs":0,"txs":1,"addrTxCount":1,"usedTokens":1,"tokens":[{"type":"XPUBAddress","name":"2MzmAKayJmja784jyHvRUW1bXPget1csRRG","path":"m/49'/1'/33'/0/0","transfers":1,"decimals":8,"balance":"1","totalReceived":"1","totalSent":"0"}]},"utxos":[{"txid":"effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75","vout":1,"value":"1","height":225493,"confirmations":1,"address":"2MzmAKayJmja784jyHvRUW1bXPget1csRRG","path":"m/49'/1'/33'/0/0"}]}`,
+			},
+		},
+		{
+			name:        "apiStateAt missing height",
+			r:           newGetRequest(ts.URL + "/api/v2/state/" + dbtestdata.Addr5),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Missing parameter 'height' or 'time'"}`,
+			},
+		},
+		{
+			name:        "apiStateAt height above best block",
+			r:           newGetRequest(ts.URL + "/api/v2/state/" + dbtestdata.Addr5 + "?height=225495"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Height 225495 is higher than the best block 225494"}`,
+			},
+		},
+		{
+			name:        "apiMempoolConflicts",
+			r:           newGetRequest(ts.URL + "/api/v2/mempool/conflicts/" + dbtestdata.TxidB2T3),
+			status:      http.StatusOK,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"txid":"05e2e48aeabdd9b75def7b48d756ba304713c2aba7b522bf9dbc893fc4231b07"}`,
+			},
+		},
+		{
+			name:        "apiMempoolPackage confirmed tx",
+			r:           newGetRequest(ts.URL + "/api/v2/mempool/package/" + dbtestdata.TxidB2T3),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Transaction '05e2e48aeabdd9b75def7b48d756ba304713c2aba7b522bf9dbc893fc4231b07' is not in mempool"}`,
+			},
+		},
+		{
+			name:        "apiMempoolPackage invalid target",
+			r:           newGetRequest(ts.URL + "/api/v2/mempool/package/" + dbtestdata.TxidB2T3 + "?targetFeePerKb=x"),
+			status:      http.StatusBadRequest,
+			contentType: "application/json; charset=utf-8",
+			body: []string{
+				`{"error":"Parameter 'targetFeePerKb' is not a valid number"}`,
+			},
+		},
 		{
 			name:        "apiSendTx",
 			r:           newGetRequest(ts.URL + "/api/v2/sendtx/1234567890"),
@@ -1484,6 +1662,69 @@ var websocketTestsBitcoinType = []websocketTest{
 		},
 		want: `{"id":"43","data":{"P":0,"M":1,"zeroedKey":false,"blockFilter":""}}`,
 	},
+	{
+		name: "websocket getAccountInfo address cursor",
+		req: websocketReq{
+			Method: "getAccountInfo",
+			Params: map[string]interface{}{
+				"descriptor": dbtestdata.Addr4,
+				"details":    "txids",
+				"pageSize":   1,
+				"cursor":     "AANw1QAAAAA",
+			},
+		},
+		want: `{"id":"44","data":{"itemsOnPage":1,"address":"2MzmAKayJmja784jyHvRUW1bXPget1csRRG","balance":"0","totalReceived":"1","totalSent":"1","unconfirmedBalance":"0","unconfirmedTxs":0,"txs":2,"txids":["effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75"]}}`,
+	},
+	{
+		name: "websocket getAccountStateAt",
+		req: websocketReq{
+			Method: "getAccountStateAt",
+			Params: map[string]interface{}{
+				"descriptor": dbtestdata.Addr5,
+				"height":     225493,
+			},
+		},
+		want: `{"id":"45","data":{"height":225493,"hash":"0000000076fbbed90fd75b0e18856aa35baa984e9c9d444cf746ad85e94e2997","blockTime":1521515026,"address":{"address":"2NEVv9LJmAnY99W1pFoc5UJjVdypBqdnvu1","balance":"9876","totalReceived":"9876","totalSent":"0","unconfirmedBalance":"0","unconfirmedTxs":0,"txs":1},"utxos":[{"txid":"effd9ef509383d536b1c8af5bf434c8efbf521a4f2befd4022bbd68694b4ac75","vout":2,"value":"9876","height":225493,"confirmations":1}]}}`,
+	},
+	{
+		name: "websocket subscribeTxStatus",
+		req: websocketReq{
+			Method: "subscribeTxStatus",
+			Params: map[string]interface{}{
+				"txids":         []string{dbtestdata.TxidB2T3},
+				"addresses":     []string{dbtestdata.Addr5},
+				"confirmations": 6,
+			},
+		},
+		want: `{"id":"46","data":{"subscribed":true}}`,
+	},
+	{
+		name: "websocket subscribeTxStatus missing txids and addresses",
+		req: websocketReq{
+			Method: "subscribeTxStatus",
+			Params: map[string]interface{}{
+				"confirmations": 6,
+			},
+		},
+		want: `{"id":"47","data":{"error":{"message":"Missing txids or addresses"}}}`,
+	},
+	{
+		name: "websocket unsubscribeTxStatus",
+		req: websocketReq{
+			Method: "unsubscribeTxStatus",
+		},
+		want: `{"id":"48","data":{"subscribed":false}}`,
+	},
+	{
+		name: "websocket getTransactionProof",
+		req: websocketReq{
+			Method: "getTransactionProof",
+			Params: map[string]interface{}{
+				"txid": "not a tx",
+			},
+		},
+		want: `{"id":"49","data":{"error":{"message":"Transaction 'not a tx' not found"}}}`,
+	},
 }
 
 func runWebsocketTestsBitcoinType(t *testing.T, ts *httptest.Server, tests []websocketTest) {
@@ -0,0 +1,243 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+	"github.com/trezor/blockbook/bchain"
+)
+
+// size of the queue of notifications of one replica, a replica which does not read its notifications is disconnected,
+// the queue must hold the new mempool transactions found by one resync of the mempool
+const replicaNotificationQueue = 10000
+
+// pause before the replica reconnects to the primary
+const replicaReconnectDelay = 5 * time.Second
+
+// replicaNotification is one line of the notification stream sent by the primary to the replicas,
+// it contains either the notification about new blocks or one of the mempool events
+type replicaNotification struct {
+	Notification bchain.NotificationType `json:"notification,omitempty"`
+	NewTx        *replicaMempoolTx       `json:"newTx,omitempty"`
+	NewTxAddr    *replicaTxAddr          `json:"newTxAddr,omitempty"`
+}
+
+// replicaMempoolTx is the new mempool transaction with the data which are not part of its JSON representation
+type replicaMempoolTx struct {
+	Tx                   *bchain.MempoolTx            `json:"tx"`
+	VinAddrDescs         []bchain.AddressDescriptor   `json:"vinAddrDescs,omitempty"`
+	TokenTransfers       bchain.TokenTransfers        `json:"tokenTransfers,omitempty"`
+	EthereumSpecificData *bchain.EthereumSpecificData `json:"ethereumSpecificData,omitempty"`
+}
+
+// replicaTxAddr is the new mempool transaction touching the address descriptor
+type replicaTxAddr struct {
+	Tx       *bchain.Tx               `json:"tx"`
+	AddrDesc bchain.AddressDescriptor `json:"addrDesc"`
+}
+
+func newReplicaMempoolTx(tx *bchain.MempoolTx) *replicaMempoolTx {
+	r := &replicaMempoolTx{
+		Tx:             tx,
+		VinAddrDescs:   make([]bchain.AddressDescriptor, len(tx.Vin)),
+		TokenTransfers: tx.TokenTransfers,
+	}
+	for i := range tx.Vin {
+		r.VinAddrDescs[i] = tx.Vin[i].AddrDesc
+	}
+	if csd, ok := tx.CoinSpecificData.(bchain.EthereumSpecificData); ok {
+		r.EthereumSpecificData = &csd
+	}
+	return r
+}
+
+// mempoolTx restores the mempool transaction received from the primary
+func (r *replicaMempoolTx) mempoolTx() *bchain.MempoolTx {
+	tx := r.Tx
+	for i := range tx.Vin {
+		if i < len(r.VinAddrDescs) {
+			tx.Vin[i].AddrDesc = r.VinAddrDescs[i]
+		}
+	}
+	tx.TokenTransfers = r.TokenTransfers
+	if r.EthereumSpecificData != nil {
+		tx.CoinSpecificData = *r.EthereumSpecificData
+	}
+	return tx
+}
+
+// ReplicaRelay sends the notifications about new blocks and mempool transactions to the connected replicas
+type ReplicaRelay struct {
+	mux      sync.Mutex
+	replicas map[chan *replicaNotification]struct{}
+}
+
+// NewReplicaRelay creates the relay of the notifications, it is served by the internal server
+func NewReplicaRelay() *ReplicaRelay {
+	return &ReplicaRelay{
+		replicas: make(map[chan *replicaNotification]struct{}),
+	}
+}
+
+// Notify sends the notification to all connected replicas
+func (r *ReplicaRelay) Notify(nt bchain.NotificationType) {
+	r.send(&replicaNotification{Notification: nt})
+}
+
+func (r *ReplicaRelay) send(n *replicaNotification) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	for c := range r.replicas {
+		select {
+		case c <- n:
+		default:
+			glog.Warning("replica relay: notification queue full, disconnecting replica")
+			delete(r.replicas, c)
+			close(c)
+		}
+	}
+}
+
+// OnNewBlock notifies the replicas about a block connected to the index
+func (r *ReplicaRelay) OnNewBlock(hash string, height uint32) {
+	r.Notify(bchain.NotificationNewBlock)
+}
+
+// OnDisconnectBlocks notifies the replicas about the blocks disconnected from the index
+func (r *ReplicaRelay) OnDisconnectBlocks(lower uint32, higher uint32, hashes []string) {
+	r.Notify(bchain.NotificationNewBlock)
+}
+
+// OnNewTx relays the new mempool transaction to the replicas
+func (r *ReplicaRelay) OnNewTx(tx *bchain.MempoolTx) {
+	r.send(&replicaNotification{NewTx: newReplicaMempoolTx(tx)})
+}
+
+// OnNewTxAddr relays the new mempool transaction touching the address descriptor to the replicas
+func (r *ReplicaRelay) OnNewTxAddr(tx *bchain.Tx, desc bchain.AddressDescriptor) {
+	r.send(&replicaNotification{NewTxAddr: &replicaTxAddr{Tx: tx, AddrDesc: desc}})
+}
+
+// Close disconnects all replicas, it is called on the shutdown of the internal server
+func (r *ReplicaRelay) Close() {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	for c := range r.replicas {
+		delete(r.replicas, c)
+		close(c)
+	}
+}
+
+func (r *ReplicaRelay) subscribe() chan *replicaNotification {
+	c := make(chan *replicaNotification, replicaNotificationQueue)
+	r.mux.Lock()
+	r.replicas[c] = struct{}{}
+	r.mux.Unlock()
+	return c
+}
+
+func (r *ReplicaRelay) unsubscribe(c chan *replicaNotification) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	if _, ok := r.replicas[c]; ok {
+		delete(r.replicas, c)
+		close(c)
+	}
+}
+
+// ServeHTTP streams the notifications to the connected replica, one JSON object per line
+func (r *ReplicaRelay) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	c := r.subscribe()
+	defer r.unsubscribe(c)
+	glog.Info("replica relay: replica ", req.RemoteAddr, " connected")
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	enc := json.NewEncoder(w)
+	for {
+		select {
+		case n, ok := <-c:
+			if !ok {
+				return
+			}
+			if err := enc.Encode(n); err != nil {
+				glog.Info("replica relay: replica ", req.RemoteAddr, " disconnected, ", err)
+				return
+			}
+			flusher.Flush()
+		case <-req.Context().Done():
+			glog.Info("replica relay: replica ", req.RemoteAddr, " disconnected")
+			return
+		}
+	}
+}
+
+// RunReplicaClient receives the notifications from the primary Blockbook at url authenticated by auth in the format user:password,
+// passes the notifications about new blocks to pushHandler and the new mempool transactions to onNewTxAddr and onNewTx,
+// after an error it reconnects until ctx is canceled
+func RunReplicaClient(ctx context.Context, url string, auth string, pushHandler func(bchain.NotificationType), onNewTxAddr bchain.OnNewTxAddrFunc, onNewTx bchain.OnNewTxFunc) {
+	for {
+		err := receiveReplicaNotifications(ctx, url, auth, pushHandler, onNewTxAddr, onNewTx)
+		if ctx.Err() != nil {
+			return
+		}
+		glog.Error("replica client: ", err, ", reconnecting")
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(replicaReconnectDelay):
+		}
+	}
+}
+
+func receiveReplicaNotifications(ctx context.Context, url string, auth string, pushHandler func(bchain.NotificationType), onNewTxAddr bchain.OnNewTxAddrFunc, onNewTx bchain.OnNewTxFunc) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	if user, password, ok := strings.Cut(auth, ":"); ok {
+		req.SetBasicAuth(user, password)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return errors.Errorf("status %v", resp.Status)
+	}
+	glog.Info("replica client: connected to ", url)
+	// the blocks lost during the reconnect are replaced by the catch up after the connection,
+	// the lost mempool transactions are not notified
+	pushHandler(bchain.NotificationNewBlock)
+	dec := json.NewDecoder(resp.Body)
+	for {
+		var n replicaNotification
+		if err := dec.Decode(&n); err != nil {
+			return err
+		}
+		switch {
+		case n.NewTxAddr != nil:
+			if onNewTxAddr != nil && n.NewTxAddr.Tx != nil {
+				onNewTxAddr(n.NewTxAddr.Tx, n.NewTxAddr.AddrDesc)
+			}
+		case n.NewTx != nil:
+			if onNewTx != nil && n.NewTx.Tx != nil {
+				onNewTx(n.NewTx.mempoolTx())
+			}
+		case n.Notification != bchain.NotificationUnknown:
+			pushHandler(n.Notification)
+		}
+	}
+}
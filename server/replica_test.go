@@ -0,0 +1,131 @@
+//go:build unittest
+
+package server
+
+import (
+	"context"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/trezor/blockbook/bchain"
+)
+
+func TestReplicaRelay(t *testing.T) {
+	relay := NewReplicaRelay()
+	s := &InternalServer{adminUser: "user", adminPassword: "password"}
+	ts := httptest.NewServer(http.HandlerFunc(s.adminAuthHandler(relay.ServeHTTP)))
+	defer ts.Close()
+
+	// the relay requires the admin credentials
+	if err := receiveReplicaNotifications(context.Background(), ts.URL, "user:wrong", func(nt bchain.NotificationType) {}, nil, nil); err == nil || err.Error() != "status 401 Unauthorized" {
+		t.Fatalf("receiveReplicaNotifications with wrong credentials, error %v", err)
+	}
+
+	received := make(chan bchain.NotificationType, 10)
+	receivedTxs := make(chan *bchain.MempoolTx, 10)
+	type txAddr struct {
+		tx   *bchain.Tx
+		desc bchain.AddressDescriptor
+	}
+	receivedTxAddrs := make(chan txAddr, 10)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		RunReplicaClient(ctx, ts.URL, "user:password",
+			func(nt bchain.NotificationType) { received <- nt },
+			func(tx *bchain.Tx, desc bchain.AddressDescriptor) { receivedTxAddrs <- txAddr{tx, desc} },
+			func(tx *bchain.MempoolTx) { receivedTxs <- tx })
+		close(done)
+	}()
+	receive := func() bchain.NotificationType {
+		t.Helper()
+		select {
+		case nt := <-received:
+			return nt
+		case <-time.After(5 * time.Second):
+			t.Fatal("notification not received")
+		}
+		return bchain.NotificationUnknown
+	}
+
+	// after the connection the replica catches up with the primary
+	if nt := receive(); nt != bchain.NotificationNewBlock {
+		t.Errorf("got %v, want NotificationNewBlock", nt)
+	}
+	// wait for the subscription of the replica
+	for i := 0; ; i++ {
+		relay.mux.Lock()
+		n := len(relay.replicas)
+		relay.mux.Unlock()
+		if n == 1 {
+			break
+		}
+		if i == 100 {
+			t.Fatal("replica not subscribed")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	relay.OnNewBlock("hash", 1)
+	if nt := receive(); nt != bchain.NotificationNewBlock {
+		t.Errorf("got %v, want NotificationNewBlock", nt)
+	}
+	relay.OnDisconnectBlocks(1, 1, []string{"hash"})
+	if nt := receive(); nt != bchain.NotificationNewBlock {
+		t.Errorf("got %v, want NotificationNewBlock", nt)
+	}
+
+	// the mempool transactions are relayed with the data, which are not part of their JSON
+	mempoolTx := &bchain.MempoolTx{
+		Txid: "txid",
+		Vin: []bchain.MempoolVin{
+			{Vin: bchain.Vin{Txid: "input", Vout: 1}, AddrDesc: bchain.AddressDescriptor{1, 2, 3}, ValueSat: *big.NewInt(1234)},
+		},
+		Vout: []bchain.Vout{
+			{N: 0, ValueSat: *big.NewInt(1000), JsonValue: "0.00001", ScriptPubKey: bchain.ScriptPubKey{Hex: "0014"}},
+		},
+		TokenTransfers: bchain.TokenTransfers{
+			{Type: bchain.FungibleToken, Contract: "contract", From: "from", To: "to", Value: *big.NewInt(5)},
+		},
+		CoinSpecificData: bchain.EthereumSpecificData{Tx: &bchain.RpcTransaction{Hash: "txid", Payload: "0x"}},
+	}
+	relay.OnNewTx(mempoolTx)
+	select {
+	case tx := <-receivedTxs:
+		if !reflect.DeepEqual(tx, mempoolTx) {
+			t.Errorf("OnNewTx got %+v, want %+v", tx, mempoolTx)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("new tx not received")
+	}
+	tx := &bchain.Tx{
+		Txid: "txid",
+		Vout: []bchain.Vout{{N: 0, ValueSat: *big.NewInt(1000), JsonValue: "0.00001", ScriptPubKey: bchain.ScriptPubKey{Hex: "0014"}}},
+	}
+	relay.OnNewTxAddr(tx, bchain.AddressDescriptor{4, 5, 6})
+	select {
+	case ta := <-receivedTxAddrs:
+		if !reflect.DeepEqual(ta.tx, tx) || !reflect.DeepEqual(ta.desc, bchain.AddressDescriptor{4, 5, 6}) {
+			t.Errorf("OnNewTxAddr got %+v %v, want %+v", ta.tx, ta.desc, tx)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("new tx addr not received")
+	}
+	select {
+	case nt := <-received:
+		t.Errorf("unexpected notification %v", nt)
+	default:
+	}
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("RunReplicaClient not stopped")
+	}
+	relay.Close()
+}
@@ -51,7 +51,7 @@ type websocketChannel struct {
 // WebsocketServer is a handle to websocket server
 type WebsocketServer struct {
 	upgrader                        *websocket.Upgrader
-	db                              *db.RocksDB
+	db                              db.Store
 	txCache                         *db.TxCache
 	chain                           bchain.BlockChain
 	chainParser                     bchain.BlockChainParser
@@ -70,10 +70,13 @@ type WebsocketServer struct {
 	fiatRatesSubscriptions          map[string]map[*websocketChannel]string
 	fiatRatesTokenSubscriptions     map[*websocketChannel][]string
 	fiatRatesSubscriptionsLock      sync.Mutex
+	txStatusSubscriptions           map[*websocketChannel]*txStatusSubscription
+	txStatusSubscriptionsLock       sync.Mutex
+	txStatusBlockLock               sync.Mutex
 }
 
 // NewWebsocketServer creates new websocket interface to blockbook and returns its handle
-func NewWebsocketServer(db *db.RocksDB, chain bchain.BlockChain, mempool bchain.Mempool, txCache *db.TxCache, metrics *common.Metrics, is *common.InternalState, fiatRates *fiat.FiatRates) (*WebsocketServer, error) {
+func NewWebsocketServer(db db.Store, chain bchain.BlockChain, mempool bchain.Mempool, txCache *db.TxCache, metrics *common.Metrics, is *common.InternalState, fiatRates *fiat.FiatRates) (*WebsocketServer, error) {
 	api, err := api.NewWorker(db, chain, mempool, txCache, metrics, is, fiatRates)
 	if err != nil {
 		return nil, err
@@ -104,6 +107,7 @@ func NewWebsocketServer(db *db.RocksDB, chain bchain.BlockChain, mempool bchain.
 		addressSubscriptions:        make(map[string]map[*websocketChannel]string),
 		fiatRatesSubscriptions:      make(map[string]map[*websocketChannel]string),
 		fiatRatesTokenSubscriptions: make(map[*websocketChannel][]string),
+		txStatusSubscriptions:       make(map[*websocketChannel]*txStatusSubscription),
 	}
 	return s, nil
 }
@@ -262,6 +266,7 @@ func (s *WebsocketServer) onDisconnect(c *websocketChannel) {
 	s.unsubscribeNewTransaction(c)
 	s.unsubscribeAddresses(c)
 	s.unsubscribeFiatRates(c)
+	s.unsubscribeTxStatus(c)
 	glog.Info("Client disconnected ", c.id, ", ", c.ip)
 	s.metrics.WebsocketClients.Dec()
 }
@@ -320,6 +325,14 @@ var requestHandlers = map[string]func(*WebsocketServer, *websocketChannel, *WsRe
 		}
 		return
 	},
+	"getAccountStateAt": func(s *WebsocketServer, c *websocketChannel, req *WsReq) (rv interface{}, err error) {
+		r := WsAccountStateAtReq{}
+		err = json.Unmarshal(req.Params, &r)
+		if err == nil {
+			rv, err = s.getAccountStateAt(&r)
+		}
+		return
+	},
 	"getBalanceHistory": func(s *WebsocketServer, c *websocketChannel, req *WsReq) (rv interface{}, err error) {
 		r := WsBalanceHistoryReq{}
 		err = json.Unmarshal(req.Params, &r)
@@ -356,6 +369,14 @@ var requestHandlers = map[string]func(*WebsocketServer, *websocketChannel, *WsRe
 		}
 		return
 	},
+	"getTransactionProof": func(s *WebsocketServer, c *websocketChannel, req *WsReq) (rv interface{}, err error) {
+		r := WsTransactionReq{}
+		err = json.Unmarshal(req.Params, &r)
+		if err == nil {
+			rv, err = s.api.GetTransactionProof(r.Txid)
+		}
+		return
+	},
 	"estimateFee": func(s *WebsocketServer, c *websocketChannel, req *WsReq) (rv interface{}, err error) {
 		return s.estimateFee(c, req.Params)
 	},
@@ -428,6 +449,17 @@ var requestHandlers = map[string]func(*WebsocketServer, *websocketChannel, *WsRe
 	"unsubscribeFiatRates": func(s *WebsocketServer, c *websocketChannel, req *WsReq) (rv interface{}, err error) {
 		return s.unsubscribeFiatRates(c)
 	},
+	"subscribeTxStatus": func(s *WebsocketServer, c *websocketChannel, req *WsReq) (rv interface{}, err error) {
+		var r WsSubscribeTxStatusReq
+		err = json.Unmarshal(req.Params, &r)
+		if err != nil {
+			return nil, err
+		}
+		return s.subscribeTxStatus(c, &r, req)
+	},
+	"unsubscribeTxStatus": func(s *WebsocketServer, c *websocketChannel, req *WsReq) (rv interface{}, err error) {
+		return s.unsubscribeTxStatus(c)
+	},
 	"ping": func(s *WebsocketServer, c *websocketChannel, req *WsReq) (rv interface{}, err error) {
 		r := struct{}{}
 		return r, nil
@@ -544,6 +576,11 @@ func (s *WebsocketServer) getAccountInfo(req *WsAccountInfoReq) (res *api.Addres
 		Vout:           api.AddressFilterVoutOff,
 		TokensToReturn: tokensToReturn,
 	}
+	if req.Cursor != "" {
+		if filter.Cursor, err = api.ParseTxCursor(req.Cursor); err != nil {
+			return nil, err
+		}
+	}
 	if req.PageSize == 0 {
 		req.PageSize = txsOnPage
 	}
@@ -562,6 +599,24 @@ func (s *WebsocketServer) getAccountUtxo(descriptor string) (api.Utxos, error) {
 	return utxo, nil
 }
 
+func (s *WebsocketServer) getAccountStateAt(req *WsAccountStateAtReq) (*api.AddressState, error) {
+	if req.Height < 0 {
+		return nil, api.NewAPIError("Invalid height", true)
+	}
+	height := uint32(req.Height)
+	if req.Time != 0 {
+		var err error
+		if height, err = s.api.GetBlockHeightAtTime(req.Time); err != nil {
+			return nil, err
+		}
+	}
+	a, err := s.api.GetXpubStateAt(req.Descriptor, height, req.Gap)
+	if err != nil {
+		return s.api.GetAddressStateAt(req.Descriptor, height)
+	}
+	return a, nil
+}
+
 func (s *WebsocketServer) getTransaction(txid string) (*api.Tx, error) {
 	return s.api.GetTransaction(txid, false, false)
 }
@@ -634,9 +689,15 @@ func (s *WebsocketServer) estimateFee(c *websocketChannel, params []byte) (inter
 		if err != nil {
 			return nil, err
 		}
+		// the backends without the support of EIP-1559 return only the legacy estimation
+		eip1559, err := s.api.EstimateEip1559Fees()
+		if err != nil {
+			glog.V(1).Info("estimateFee: EIP-1559 fees not available: ", err)
+		}
 		for i := range r.Blocks {
 			res[i].FeePerUnit = fee.String()
 			res[i].FeeLimit = sg
+			res[i].Eip1559 = eip1559
 			fee.Mul(&fee, new(big.Int).SetUint64(gas))
 			res[i].FeePerTx = fee.String()
 		}
@@ -925,6 +986,7 @@ func (s *WebsocketServer) onNewBlockAsync(hash string, height uint32) {
 // OnNewBlock is a callback that broadcasts info about new block to subscribed clients
 func (s *WebsocketServer) OnNewBlock(hash string, height uint32) {
 	go s.onNewBlockAsync(hash, height)
+	go s.onNewBlockTxStatus(hash, height)
 }
 
 func (s *WebsocketServer) sendOnNewTx(tx *api.Tx) {
@@ -1028,6 +1090,7 @@ func (s *WebsocketServer) onNewTxAsync(tx *bchain.MempoolTx, subscribed map[stri
 
 // OnNewTx is a callback that broadcasts info about a tx affecting subscribed address
 func (s *WebsocketServer) OnNewTx(tx *bchain.MempoolTx) {
+	s.onNewTxStatus(tx)
 	subscribed := s.getNewTxSubscriptions(tx)
 	if len(s.newTransactionSubscriptions) > 0 || len(subscribed) > 0 {
 		go s.onNewTxAsync(tx, subscribed)
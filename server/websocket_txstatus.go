@@ -0,0 +1,372 @@
+package server
+
+import (
+	"github.com/golang/glog"
+	"github.com/trezor/blockbook/api"
+	"github.com/trezor/blockbook/bchain"
+	"github.com/trezor/blockbook/common"
+)
+
+// events sent to the subscribers of the transaction status
+const (
+	txStatusConfirmed     = "confirmed"
+	txStatusConfirmations = "confirmations"
+	txStatusReplaced      = "replaced"
+	txStatusEvicted       = "evicted"
+	txStatusReorged       = "reorged"
+)
+
+// confirmed transactions are tracked for possible reorgs at least for this number of blocks
+const txStatusReorgDepth = 100
+
+// txStatusTx is a transaction tracked by the transaction status subscription
+type txStatusTx struct {
+	height    uint32 // zero if the transaction is not confirmed
+	hash      string
+	addrDescs []string          // address descriptors used to find the transaction in the index
+	outpoints []bchain.Outpoint // outputs spent by the transaction
+	missing   bool              // the unconfirmed transaction was not in the mempool at the last block
+}
+
+type txStatusSubscription struct {
+	id            string
+	confirmations uint32
+	addrDescs     map[string]struct{}
+	txs           map[string]*txStatusTx
+}
+
+func (s *WebsocketServer) newTxStatusTx(txid string) (*txStatusTx, error) {
+	tx, err := s.api.GetTransaction(txid, false, false)
+	if err != nil {
+		return nil, err
+	}
+	t := &txStatusTx{}
+	if tx.Confirmations > 0 {
+		t.height = uint32(tx.Blockheight)
+		t.hash = tx.Blockhash
+	}
+	addrDescs := make(map[string]struct{})
+	add := func(addrDesc bchain.AddressDescriptor, addresses []string) {
+		if len(addrDesc) == 0 && len(addresses) == 1 {
+			addrDesc, _ = s.chainParser.GetAddrDescFromAddress(addresses[0])
+		}
+		if len(addrDesc) > 0 {
+			if _, found := addrDescs[string(addrDesc)]; !found {
+				addrDescs[string(addrDesc)] = struct{}{}
+				t.addrDescs = append(t.addrDescs, string(addrDesc))
+			}
+		}
+	}
+	for i := range tx.Vin {
+		vin := &tx.Vin[i]
+		add(vin.AddrDesc, vin.Addresses)
+		if vin.Txid != "" {
+			t.outpoints = append(t.outpoints, bchain.Outpoint{Txid: vin.Txid, Vout: int32(vin.Vout)})
+		}
+	}
+	for i := range tx.Vout {
+		add(tx.Vout[i].AddrDesc, tx.Vout[i].Addresses)
+	}
+	return t, nil
+}
+
+// subscribeTxStatus subscribes the channel to the status of the transactions and of the transactions touching the addresses
+func (s *WebsocketServer) subscribeTxStatus(c *websocketChannel, r *WsSubscribeTxStatusReq, req *WsReq) (res interface{}, err error) {
+	if len(r.Txids) == 0 && len(r.Addresses) == 0 {
+		return nil, api.NewAPIError("Missing txids or addresses", true)
+	}
+	if r.Confirmations < 0 {
+		return nil, api.NewAPIError("Invalid confirmations", true)
+	}
+	sub := &txStatusSubscription{
+		id:            req.ID,
+		confirmations: uint32(r.Confirmations),
+		addrDescs:     make(map[string]struct{}),
+		txs:           make(map[string]*txStatusTx),
+	}
+	if sub.confirmations == 0 {
+		sub.confirmations = 1
+	}
+	for _, a := range r.Addresses {
+		addrDesc, err := s.chainParser.GetAddrDescFromAddress(a)
+		if err != nil {
+			return nil, api.NewAPIError("Invalid address "+a, true)
+		}
+		sub.addrDescs[string(addrDesc)] = struct{}{}
+	}
+	for _, txid := range r.Txids {
+		t, err := s.newTxStatusTx(txid)
+		if err != nil {
+			return nil, err
+		}
+		sub.txs[txid] = t
+	}
+	s.txStatusSubscriptionsLock.Lock()
+	defer s.txStatusSubscriptionsLock.Unlock()
+	s.txStatusSubscriptions[c] = sub
+	s.metrics.WebsocketSubscribes.With((common.Labels{"method": "subscribeTxStatus"})).Set(float64(len(s.txStatusSubscriptions)))
+	return &subscriptionResponse{true}, nil
+}
+
+// unsubscribeTxStatus unsubscribes the transaction status subscription of this channel
+func (s *WebsocketServer) unsubscribeTxStatus(c *websocketChannel) (res interface{}, err error) {
+	s.txStatusSubscriptionsLock.Lock()
+	defer s.txStatusSubscriptionsLock.Unlock()
+	delete(s.txStatusSubscriptions, c)
+	s.metrics.WebsocketSubscribes.With((common.Labels{"method": "subscribeTxStatus"})).Set(float64(len(s.txStatusSubscriptions)))
+	return &subscriptionResponse{false}, nil
+}
+
+func (s *WebsocketServer) sendTxStatus(c *websocketChannel, sub *txStatusSubscription, txid string, t *txStatusTx, event string, confirmations uint32, replacedBy string) {
+	c.DataOut(&WsRes{
+		ID: sub.id,
+		Data: &WsTxStatus{
+			Event:         event,
+			Txid:          txid,
+			BlockHeight:   t.height,
+			BlockHash:     t.hash,
+			Confirmations: confirmations,
+			ReplacedBy:    replacedBy,
+		},
+	})
+}
+
+// spentByConfirmedTx checks if any of the outpoints was spent by a transaction in the index
+func (s *WebsocketServer) spentByConfirmedTx(outpoints []bchain.Outpoint) bool {
+	if s.chainParser.GetChainType() != bchain.ChainBitcoinType {
+		return false
+	}
+	for _, o := range outpoints {
+		ta, err := s.db.GetTxAddresses(o.Txid)
+		if err != nil {
+			glog.Error("GetTxAddresses error ", err, " for ", o.Txid)
+			continue
+		}
+		if ta != nil && int(o.Vout) < len(ta.Outputs) && ta.Outputs[o.Vout].Spent {
+			return true
+		}
+	}
+	return false
+}
+
+// onNewTxStatus starts tracking of a new mempool transaction touching the subscribed addresses
+// and notifies about the tracked transactions replaced by it
+func (s *WebsocketServer) onNewTxStatus(tx *bchain.MempoolTx) {
+	s.txStatusSubscriptionsLock.Lock()
+	defer s.txStatusSubscriptionsLock.Unlock()
+	if len(s.txStatusSubscriptions) == 0 {
+		return
+	}
+	addrDescs := make([]string, 0, len(tx.Vin)+len(tx.Vout))
+	outpoints := make([]bchain.Outpoint, 0, len(tx.Vin))
+	spent := make(map[bchain.Outpoint]struct{}, len(tx.Vin))
+	for i := range tx.Vin {
+		vin := &tx.Vin[i]
+		if len(vin.AddrDesc) > 0 {
+			addrDescs = append(addrDescs, string(vin.AddrDesc))
+		}
+		if vin.Txid != "" {
+			o := bchain.Outpoint{Txid: vin.Txid, Vout: int32(vin.Vout)}
+			outpoints = append(outpoints, o)
+			spent[o] = struct{}{}
+		}
+	}
+	for i := range tx.Vout {
+		addrDesc, err := s.chainParser.GetAddrDescFromVout(&tx.Vout[i])
+		if err == nil && len(addrDesc) > 0 {
+			addrDescs = append(addrDescs, string(addrDesc))
+		}
+	}
+	for c, sub := range s.txStatusSubscriptions {
+		for txid, t := range sub.txs {
+			if t.height != 0 || txid == tx.Txid {
+				continue
+			}
+			for _, o := range t.outpoints {
+				if _, found := spent[o]; found {
+					s.sendTxStatus(c, sub, txid, t, txStatusReplaced, 0, tx.Txid)
+					delete(sub.txs, txid)
+					break
+				}
+			}
+		}
+		if _, found := sub.txs[tx.Txid]; found {
+			continue
+		}
+		for _, ad := range addrDescs {
+			if _, found := sub.addrDescs[ad]; found {
+				sub.txs[tx.Txid] = &txStatusTx{addrDescs: addrDescs, outpoints: outpoints}
+				break
+			}
+		}
+	}
+}
+
+// txStatusSnapshot is a copy of a subscription taken for the processing of a new block outside of the lock
+type txStatusSnapshot struct {
+	c   *websocketChannel
+	sub *txStatusSubscription
+	// unconfirmed transactions of the subscription and their state at the time of the copy
+	unconfirmed map[string]txStatusTx
+}
+
+// txStatusRemoval is the event of an unconfirmed transaction which left the mempool
+type txStatusRemoval struct {
+	event      string
+	replacedBy string
+}
+
+// onNewBlockTxStatus notifies about the tracked transactions confirmed in the block,
+// about the confirmations of already confirmed transactions and about transactions which left the mempool;
+// the database and the mempool are queried without holding the subscriptions lock, it is expected to be run in a goroutine
+func (s *WebsocketServer) onNewBlockTxStatus(hash string, height uint32) {
+	s.txStatusBlockLock.Lock()
+	defer s.txStatusBlockLock.Unlock()
+	s.txStatusSubscriptionsLock.Lock()
+	snapshots := make([]txStatusSnapshot, 0, len(s.txStatusSubscriptions))
+	for c, sub := range s.txStatusSubscriptions {
+		ss := txStatusSnapshot{c: c, sub: sub, unconfirmed: make(map[string]txStatusTx)}
+		for txid, t := range sub.txs {
+			if t.height == 0 {
+				ss.unconfirmed[txid] = *t
+			}
+		}
+		snapshots = append(snapshots, ss)
+	}
+	s.txStatusSubscriptionsLock.Unlock()
+	if len(snapshots) == 0 {
+		return
+	}
+	// transactions of the address descriptors in the block, shared by all subscriptions
+	blockTxids := make(map[string]map[string]struct{})
+	getBlockTxids := func(ad string) {
+		if _, found := blockTxids[ad]; found {
+			return
+		}
+		txids := make(map[string]struct{})
+		err := s.db.GetAddrDescTransactions(bchain.AddressDescriptor(ad), height, height, func(txid string, height uint32, indexes []int32) error {
+			txids[txid] = struct{}{}
+			return nil
+		})
+		if err != nil {
+			glog.Error("GetAddrDescTransactions error ", err, " for block ", height)
+		}
+		blockTxids[ad] = txids
+	}
+	// the replica does not synchronize its own mempool, the transactions which left the mempool cannot be detected
+	_, lastMempoolSync, _ := s.is.GetMempoolSyncState()
+	inMempool := make(map[string]bool)
+	removals := make(map[string]txStatusRemoval)
+	for i := range snapshots {
+		ss := &snapshots[i]
+		// the address descriptors of the subscription are not modified after the subscription is created
+		for ad := range ss.sub.addrDescs {
+			getBlockTxids(ad)
+		}
+		for txid, t := range ss.unconfirmed {
+			for _, ad := range t.addrDescs {
+				getBlockTxids(ad)
+			}
+			if _, found := inMempool[txid]; found || lastMempoolSync.IsZero() {
+				continue
+			}
+			inMempool[txid] = s.mempool.GetTransactionTime(txid) != 0
+			// the event of the transaction which is going to be removed
+			if !inMempool[txid] && t.missing {
+				r := txStatusRemoval{event: txStatusEvicted}
+				if conflicts, err := s.mempool.GetTxConflicts(txid); err == nil && conflicts.ReplacedBy != "" {
+					r = txStatusRemoval{event: txStatusReplaced, replacedBy: conflicts.ReplacedBy}
+				} else if s.spentByConfirmedTx(t.outpoints) {
+					r.event = txStatusReplaced
+				}
+				removals[txid] = r
+			}
+		}
+	}
+	s.txStatusSubscriptionsLock.Lock()
+	defer s.txStatusSubscriptionsLock.Unlock()
+	for i := range snapshots {
+		c, sub := snapshots[i].c, snapshots[i].sub
+		// skip the subscriptions removed or replaced in the meantime
+		if s.txStatusSubscriptions[c] != sub {
+			continue
+		}
+		for ad := range sub.addrDescs {
+			for txid := range blockTxids[ad] {
+				t, found := sub.txs[txid]
+				if !found {
+					t = &txStatusTx{addrDescs: []string{ad}}
+					sub.txs[txid] = t
+				}
+				if t.height == 0 {
+					t.height = height
+					t.hash = hash
+					s.sendTxStatus(c, sub, txid, t, txStatusConfirmed, 1, "")
+				}
+			}
+		}
+		for txid, t := range sub.txs {
+			if t.height != 0 {
+				continue
+			}
+			for _, ad := range t.addrDescs {
+				if _, found := blockTxids[ad][txid]; found {
+					t.height = height
+					t.hash = hash
+					s.sendTxStatus(c, sub, txid, t, txStatusConfirmed, 1, "")
+					break
+				}
+			}
+		}
+		for txid, t := range sub.txs {
+			if t.height == 0 {
+				// the transactions added after the copy are checked at the next block
+				in, checked := inMempool[txid]
+				if !checked {
+					continue
+				}
+				if in {
+					t.missing = false
+					continue
+				}
+				// the mempool is resynchronized independently of the blocks,
+				// the transaction must be missing at two consecutive blocks to be considered removed
+				if !t.missing {
+					t.missing = true
+					continue
+				}
+				r, found := removals[txid]
+				if !found {
+					continue
+				}
+				s.sendTxStatus(c, sub, txid, t, r.event, 0, r.replacedBy)
+				delete(sub.txs, txid)
+				continue
+			}
+			confirmations := height - t.height + 1
+			if confirmations > 1 && confirmations <= sub.confirmations {
+				s.sendTxStatus(c, sub, txid, t, txStatusConfirmations, confirmations, "")
+			}
+			if confirmations >= sub.confirmations && confirmations >= txStatusReorgDepth {
+				delete(sub.txs, txid)
+			}
+		}
+	}
+}
+
+// OnDisconnectBlocks is a callback that notifies subscribed clients about transactions removed from the blockchain by a fork
+func (s *WebsocketServer) OnDisconnectBlocks(lower uint32, higher uint32, hashes []string) {
+	s.txStatusSubscriptionsLock.Lock()
+	defer s.txStatusSubscriptionsLock.Unlock()
+	for c, sub := range s.txStatusSubscriptions {
+		for txid, t := range sub.txs {
+			if t.height >= lower {
+				s.sendTxStatus(c, sub, txid, t, txStatusReorged, 0, "")
+				t.height = 0
+				t.hash = ""
+				t.missing = false
+			}
+		}
+	}
+	glog.Info("disconnected blocks ", lower, "-", higher, ", notified ", len(s.txStatusSubscriptions), " tx status subscriptions")
+}
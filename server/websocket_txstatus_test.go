@@ -0,0 +1,208 @@
+//go:build unittest
+
+package server
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/trezor/blockbook/bchain"
+	"github.com/trezor/blockbook/bchain/coins/btc"
+	"github.com/trezor/blockbook/common"
+	"github.com/trezor/blockbook/db"
+	"github.com/trezor/blockbook/tests/dbtestdata"
+)
+
+// txStatusMempool is a mempool with the transaction times and conflicts set by the test
+type txStatusMempool struct {
+	bchain.Mempool
+	times      map[string]uint32
+	replacedBy map[string]string
+}
+
+func (m *txStatusMempool) GetTransactionTime(txid string) uint32 {
+	return m.times[txid]
+}
+
+func (m *txStatusMempool) GetTxConflicts(txid string) (bchain.MempoolTxConflicts, error) {
+	return bchain.MempoolTxConflicts{ReplacedBy: m.replacedBy[txid]}, nil
+}
+
+func receivedTxStatuses(t *testing.T, c *websocketChannel) []WsTxStatus {
+	t.Helper()
+	rv := make([]WsTxStatus, 0)
+	for {
+		select {
+		case r := <-c.out:
+			if r.ID != "1" {
+				t.Errorf("response id %v, want 1", r.ID)
+			}
+			rv = append(rv, *r.Data.(*WsTxStatus))
+		default:
+			sort.Slice(rv, func(i, j int) bool { return rv[i].Txid < rv[j].Txid })
+			return rv
+		}
+	}
+}
+
+func checkTxStatuses(t *testing.T, name string, got []WsTxStatus, want []WsTxStatus) {
+	t.Helper()
+	sort.Slice(want, func(i, j int) bool { return want[i].Txid < want[j].Txid })
+	if len(got) != len(want) {
+		t.Fatalf("%s: got %+v, want %+v", name, got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("%s: got %+v, want %+v", name, got[i], want[i])
+		}
+	}
+}
+
+func Test_WebsocketServer_txStatus(t *testing.T) {
+	parser := btc.NewBitcoinParser(btc.GetChainParams("test"), &btc.Configuration{BlockAddressesToKeep: 100})
+	m := db.NewMemoryStore(parser, false)
+	block1 := dbtestdata.GetTestBitcoinTypeBlock1(parser)
+	block2 := dbtestdata.GetTestBitcoinTypeBlock2(parser)
+	if err := m.ConnectBlock(block1); err != nil {
+		t.Fatal(err)
+	}
+	mempool := &txStatusMempool{times: make(map[string]uint32), replacedBy: make(map[string]string)}
+	is := &common.InternalState{}
+	is.FinishedMempoolSync(0)
+	s := &WebsocketServer{
+		db:                    m,
+		chainParser:           parser,
+		mempool:               mempool,
+		is:                    is,
+		txStatusSubscriptions: make(map[*websocketChannel]*txStatusSubscription),
+	}
+	c := &websocketChannel{out: make(chan *WsRes, outChannelSize), alive: true}
+	addrDesc6, err := parser.GetAddrDescFromAddress(dbtestdata.Addr6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.txStatusSubscriptions[c] = &txStatusSubscription{
+		id:            "1",
+		confirmations: 3,
+		addrDescs:     map[string]struct{}{string(addrDesc6): {}},
+		txs:           make(map[string]*txStatusTx),
+	}
+	toAddr6 := []bchain.Vout{{N: 0, ScriptPubKey: bchain.ScriptPubKey{Hex: dbtestdata.AddressToPubKeyHex(dbtestdata.Addr6, parser)}}}
+	toAddr1 := []bchain.Vout{{N: 0, ScriptPubKey: bchain.ScriptPubKey{Hex: dbtestdata.AddressToPubKeyHex(dbtestdata.Addr1, parser)}}}
+	spending := func(txid string, vout uint32) []bchain.MempoolVin {
+		return []bchain.MempoolVin{{Vin: bchain.Vin{Txid: txid, Vout: vout}}}
+	}
+	txEvicted := strings.Repeat("e1", 32)
+	txReturned := strings.Repeat("e2", 32)
+	txReplacedInMempool := strings.Repeat("e3", 32)
+	txReplacedInBlock := strings.Repeat("e4", 32)
+	txReplacedByConflict := strings.Repeat("e5", 32)
+	txReplacement := strings.Repeat("f1", 32)
+	txConflict := strings.Repeat("f2", 32)
+	txUnknown := strings.Repeat("f3", 32)
+	mempoolTxs := []bchain.MempoolTx{
+		{Txid: dbtestdata.TxidB2T1, Vin: spending(dbtestdata.TxidB1T2, 0), Vout: toAddr6},
+		{Txid: txEvicted, Vin: spending(txUnknown, 0), Vout: toAddr6},
+		{Txid: txReturned, Vin: spending(txUnknown, 1), Vout: toAddr6},
+		{Txid: txReplacedInMempool, Vin: spending(txUnknown, 2), Vout: toAddr6},
+		// the output of addr4 is spent by TxidB2T2 in the block 2
+		{Txid: txReplacedInBlock, Vin: spending(dbtestdata.TxidB1T2, 1), Vout: toAddr6},
+		{Txid: txReplacedByConflict, Vin: spending(txUnknown, 3), Vout: toAddr6},
+		// not touching the subscribed address, it is not tracked
+		{Txid: strings.Repeat("f4", 32), Vin: spending(txUnknown, 4), Vout: toAddr1},
+	}
+	for i := range mempoolTxs {
+		mempool.times[mempoolTxs[i].Txid] = 1
+		s.onNewTxStatus(&mempoolTxs[i])
+	}
+	if txs := s.txStatusSubscriptions[c].txs; len(txs) != 6 {
+		t.Fatalf("tracked %d txs, want 6", len(txs))
+	}
+	checkTxStatuses(t, "mempool", receivedTxStatuses(t, c), []WsTxStatus{})
+
+	// the replacement of a tracked transaction in the mempool
+	s.onNewTxStatus(&bchain.MempoolTx{Txid: txReplacement, Vin: spending(txUnknown, 2), Vout: toAddr1})
+	mempool.times[txReplacement] = 1
+	delete(mempool.times, txReplacedInMempool)
+	checkTxStatuses(t, "replaced in mempool", receivedTxStatuses(t, c), []WsTxStatus{
+		{Event: txStatusReplaced, Txid: txReplacedInMempool, ReplacedBy: txReplacement},
+	})
+
+	// block 2 confirms TxidB2T1 and TxidB2T2 of addr6, the transactions missing in the mempool are removed only at the next block
+	if err := m.ConnectBlock(block2); err != nil {
+		t.Fatal(err)
+	}
+	delete(mempool.times, dbtestdata.TxidB2T1)
+	for _, txid := range []string{txEvicted, txReturned, txReplacedInBlock, txReplacedByConflict} {
+		delete(mempool.times, txid)
+	}
+	mempool.replacedBy[txReplacedByConflict] = txConflict
+	s.onNewBlockTxStatus(block2.Hash, block2.Height)
+	checkTxStatuses(t, "block 2", receivedTxStatuses(t, c), []WsTxStatus{
+		{Event: txStatusConfirmed, Txid: dbtestdata.TxidB2T1, BlockHeight: block2.Height, BlockHash: block2.Hash, Confirmations: 1},
+		{Event: txStatusConfirmed, Txid: dbtestdata.TxidB2T2, BlockHeight: block2.Height, BlockHash: block2.Hash, Confirmations: 1},
+	})
+
+	// block 3, the transactions missing at two consecutive blocks are removed, the transaction back in the mempool is kept
+	block3 := &bchain.Block{
+		BlockHeader: bchain.BlockHeader{Height: block2.Height + 1, Hash: strings.Repeat("b3", 32)},
+		Txs:         []bchain.Tx{{Txid: strings.Repeat("c3", 32), Vin: []bchain.Vin{{Coinbase: "00"}}, Vout: toAddr1}},
+	}
+	if err := m.ConnectBlock(block3); err != nil {
+		t.Fatal(err)
+	}
+	mempool.times[txReturned] = 1
+	s.onNewBlockTxStatus(block3.Hash, block3.Height)
+	checkTxStatuses(t, "block 3", receivedTxStatuses(t, c), []WsTxStatus{
+		{Event: txStatusConfirmations, Txid: dbtestdata.TxidB2T1, BlockHeight: block2.Height, BlockHash: block2.Hash, Confirmations: 2},
+		{Event: txStatusConfirmations, Txid: dbtestdata.TxidB2T2, BlockHeight: block2.Height, BlockHash: block2.Hash, Confirmations: 2},
+		{Event: txStatusEvicted, Txid: txEvicted},
+		{Event: txStatusReplaced, Txid: txReplacedInBlock},
+		{Event: txStatusReplaced, Txid: txReplacedByConflict, ReplacedBy: txConflict},
+	})
+	if _, found := s.txStatusSubscriptions[c].txs[txReturned]; !found {
+		t.Fatal("the transaction back in the mempool is not tracked")
+	}
+
+	// block 4, the transaction missing once again is not removed yet, the requested number of confirmations is reached
+	block4 := &bchain.Block{
+		BlockHeader: bchain.BlockHeader{Height: block3.Height + 1, Hash: strings.Repeat("b4", 32)},
+		Txs:         []bchain.Tx{{Txid: strings.Repeat("c4", 32), Vin: []bchain.Vin{{Coinbase: "00"}}, Vout: toAddr1}},
+	}
+	if err := m.ConnectBlock(block4); err != nil {
+		t.Fatal(err)
+	}
+	delete(mempool.times, txReturned)
+	s.onNewBlockTxStatus(block4.Hash, block4.Height)
+	checkTxStatuses(t, "block 4", receivedTxStatuses(t, c), []WsTxStatus{
+		{Event: txStatusConfirmations, Txid: dbtestdata.TxidB2T1, BlockHeight: block2.Height, BlockHash: block2.Hash, Confirmations: 3},
+		{Event: txStatusConfirmations, Txid: dbtestdata.TxidB2T2, BlockHeight: block2.Height, BlockHash: block2.Hash, Confirmations: 3},
+	})
+
+	// the disconnected blocks 2-4 return the confirmed transactions to the unconfirmed state
+	if err := m.DisconnectBlockRangeBitcoinType(block2.Height, block4.Height); err != nil {
+		t.Fatal(err)
+	}
+	s.OnDisconnectBlocks(block2.Height, block4.Height, []string{block2.Hash, block3.Hash, block4.Hash})
+	checkTxStatuses(t, "disconnect", receivedTxStatuses(t, c), []WsTxStatus{
+		{Event: txStatusReorged, Txid: dbtestdata.TxidB2T1, BlockHeight: block2.Height, BlockHash: block2.Hash},
+		{Event: txStatusReorged, Txid: dbtestdata.TxidB2T2, BlockHeight: block2.Height, BlockHash: block2.Hash},
+	})
+	for _, txid := range []string{dbtestdata.TxidB2T1, dbtestdata.TxidB2T2} {
+		if tx := s.txStatusSubscriptions[c].txs[txid]; tx == nil || tx.height != 0 || tx.hash != "" {
+			t.Errorf("reorged tx %v = %+v", txid, tx)
+		}
+	}
+
+	// the block 2 connected again confirms the transactions again
+	if err := m.ConnectBlock(block2); err != nil {
+		t.Fatal(err)
+	}
+	mempool.times[txReturned] = 1
+	s.onNewBlockTxStatus(block2.Hash, block2.Height)
+	checkTxStatuses(t, "block 2 again", receivedTxStatuses(t, c), []WsTxStatus{
+		{Event: txStatusConfirmed, Txid: dbtestdata.TxidB2T1, BlockHeight: block2.Height, BlockHash: block2.Hash, Confirmations: 1},
+		{Event: txStatusConfirmed, Txid: dbtestdata.TxidB2T2, BlockHeight: block2.Height, BlockHash: block2.Hash, Confirmations: 1},
+	})
+}
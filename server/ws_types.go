@@ -1,6 +1,10 @@
 package server
 
-import "encoding/json"
+import (
+	"encoding/json"
+
+	"github.com/trezor/blockbook/api"
+)
 
 type WsReq struct {
 	ID     string          `json:"id"`
@@ -24,6 +28,7 @@ type WsAccountInfoReq struct {
 	ContractFilter    string `json:"contractFilter,omitempty"`
 	SecondaryCurrency string `json:"secondaryCurrency,omitempty"`
 	Gap               int    `json:"gap,omitempty"`
+	Cursor            string `json:"cursor,omitempty"`
 }
 
 type WsBackendInfo struct {
@@ -63,6 +68,13 @@ type WsAccountUtxoReq struct {
 	Descriptor string `json:"descriptor"`
 }
 
+type WsAccountStateAtReq struct {
+	Descriptor string `json:"descriptor"`
+	Height     int    `json:"height,omitempty"`
+	Time       int64  `json:"time,omitempty"`
+	Gap        int    `json:"gap,omitempty"`
+}
+
 type WsBalanceHistoryReq struct {
 	Descriptor string   `json:"descriptor"`
 	From       int64    `json:"from,omitempty"`
@@ -105,9 +117,10 @@ type WsEstimateFeeReq struct {
 }
 
 type WsEstimateFeeRes struct {
-	FeePerTx   string `json:"feePerTx,omitempty"`
-	FeePerUnit string `json:"feePerUnit,omitempty"`
-	FeeLimit   string `json:"feeLimit,omitempty"`
+	FeePerTx   string           `json:"feePerTx,omitempty"`
+	FeePerUnit string           `json:"feePerUnit,omitempty"`
+	FeeLimit   string           `json:"feeLimit,omitempty"`
+	Eip1559    *api.Eip1559Fees `json:"eip1559,omitempty"`
 }
 
 type WsSendTransactionReq struct {
@@ -122,6 +135,21 @@ type WsSubscribeFiatRatesReq struct {
 	Tokens   []string `json:"tokens,omitempty"`
 }
 
+type WsSubscribeTxStatusReq struct {
+	Txids         []string `json:"txids,omitempty"`
+	Addresses     []string `json:"addresses,omitempty"`
+	Confirmations int      `json:"confirmations,omitempty"`
+}
+
+type WsTxStatus struct {
+	Event         string `json:"event" ts_type:"'confirmed' | 'confirmations' | 'replaced' | 'evicted' | 'reorged'"`
+	Txid          string `json:"txid"`
+	BlockHeight   uint32 `json:"blockHeight,omitempty"`
+	BlockHash     string `json:"blockHash,omitempty"`
+	Confirmations uint32 `json:"confirmations,omitempty"`
+	ReplacedBy    string `json:"replacedBy,omitempty"`
+}
+
 type WsCurrentFiatRatesReq struct {
 	Currencies []string `json:"currencies,omitempty"`
 	Token      string   `json:"token,omitempty"`
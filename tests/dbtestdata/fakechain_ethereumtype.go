@@ -134,6 +134,11 @@ func (c *fakeBlockChainEthereumType) EthereumTypeGetErc20ContractBalance(addrDes
 	return big.NewInt(1000000000 + int64(addrDesc[0])*1000 + int64(contractDesc[0])), nil
 }
 
+// EthereumTypeGetErc20ContractAllowance returns simulated allowance
+func (c *fakeBlockChainEthereumType) EthereumTypeGetErc20ContractAllowance(ownerDesc, spenderDesc, contractDesc bchain.AddressDescriptor) (*big.Int, error) {
+	return big.NewInt(int64(ownerDesc[0])*1000 + int64(spenderDesc[0])), nil
+}
+
 // GetTokenURI returns URI derived from the input contractDesc
 func (c *fakeBlockChainEthereumType) GetTokenURI(contractDesc bchain.AddressDescriptor, tokenID *big.Int) (string, error) {
 	return "https://ipfs.io/ipfs/" + contractDesc.String()[3:] + ".json", nil
@@ -0,0 +1,695 @@
+package webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/juju/errors"
+	"github.com/trezor/blockbook/api"
+	"github.com/trezor/blockbook/bchain"
+	"github.com/trezor/blockbook/common"
+	"github.com/trezor/blockbook/db"
+	"github.com/trezor/blockbook/fiat"
+)
+
+// Webhook events
+const (
+	// EventMempool is sent when a transaction of a watched address enters the mempool
+	EventMempool = "mempool"
+	// EventConfirmed is sent when a transaction of a watched address is included in a block
+	EventConfirmed = "confirmed"
+	// EventConfirmations is sent when a transaction reaches the number of confirmations requested by the subscription
+	EventConfirmations = "confirmations"
+	// EventRemoved is sent when a confirmed transaction is removed from the blockchain by a reorg
+	EventRemoved = "removed"
+)
+
+const maxAttempts = 12
+const retryBaseDelay = 10 * time.Second
+const retryMaxDelay = time.Hour
+const deliveryTimeout = 15 * time.Second
+const deliveryWorkers = 8
+
+// delivered and failed entries are removed from the delivery log after this period
+const deliveryLogRetention = 7 * 24 * time.Hour
+
+// confirmed transactions are tracked for possible reorgs at least for this number of blocks
+const reorgTrackingDepth = 100
+const maxConfirmations = 1000
+
+const mempoolTxsExpiration = 72 * time.Hour
+
+const maxDescriptorsInSubscription = 1000
+
+type trackedTx struct {
+	height            uint32
+	hash              string
+	addresses         []string
+	confirmationsSent bool
+}
+
+type subscription struct {
+	db.WebhookSubscription
+	addrDescs map[string]struct{}
+	// gapAddrDescs are the derived unused addresses of the xpubs, new addresses are derived when one of them is used
+	gapAddrDescs map[string]struct{}
+	mempoolTxs   map[string]time.Time
+	confirmedTxs map[string]*trackedTx
+}
+
+type payload struct {
+	ID            uint64   `json:"id"`
+	Subscription  string   `json:"subscription"`
+	Event         string   `json:"event"`
+	Txid          string   `json:"txid"`
+	Addresses     []string `json:"addresses,omitempty"`
+	BlockHeight   uint32   `json:"blockHeight,omitempty"`
+	BlockHash     string   `json:"blockHash,omitempty"`
+	Confirmations uint32   `json:"confirmations,omitempty"`
+	Time          int64    `json:"time"`
+}
+
+type blockNotification struct {
+	hash   string
+	height uint32
+}
+
+// Dispatcher sends signed notifications about transactions of the subscribed addresses and xpubs to the configured URLs
+type Dispatcher struct {
+	db             *db.RocksDB
+	api            *api.Worker
+	chainParser    bchain.BlockChainParser
+	metrics        *common.Metrics
+	client         *http.Client
+	mux            sync.Mutex
+	subscriptions  map[string]*subscription
+	lastDeliveryID uint64
+	pending        map[uint64]*db.WebhookDelivery
+	inFlight       map[uint64]struct{}
+	chanBlocks     chan blockNotification
+	chanWake       chan struct{}
+}
+
+// NewDispatcher loads the webhook subscriptions and the pending deliveries and returns a new Dispatcher
+func NewDispatcher(d *db.RocksDB, chain bchain.BlockChain, mempool bchain.Mempool, txCache *db.TxCache, metrics *common.Metrics, is *common.InternalState, fiatRates *fiat.FiatRates) (*Dispatcher, error) {
+	w, err := api.NewWorker(d, chain, mempool, txCache, metrics, is, fiatRates)
+	if err != nil {
+		return nil, err
+	}
+	wd := &Dispatcher{
+		db:            d,
+		api:           w,
+		chainParser:   chain.GetChainParser(),
+		metrics:       metrics,
+		client:        &http.Client{Timeout: deliveryTimeout},
+		subscriptions: make(map[string]*subscription),
+		pending:       make(map[uint64]*db.WebhookDelivery),
+		inFlight:      make(map[uint64]struct{}),
+		chanBlocks:    make(chan blockNotification, 1000),
+		chanWake:      make(chan struct{}, 1),
+	}
+	subs, err := d.GetWebhookSubscriptions()
+	if err != nil {
+		return nil, err
+	}
+	for i := range subs {
+		s := newSubscription(&subs[i])
+		if s.addrDescs, s.gapAddrDescs, err = wd.resolve(s.Descriptors); err != nil {
+			glog.Error("webhook subscription ", s.ID, " error ", err)
+		}
+		wd.subscriptions[s.ID] = s
+	}
+	if err = wd.loadDeliveryLog(); err != nil {
+		return nil, err
+	}
+	glog.Info("webhooks: loaded ", len(wd.subscriptions), " subscriptions, ", len(wd.pending), " pending deliveries")
+	return wd, nil
+}
+
+func newSubscription(ws *db.WebhookSubscription) *subscription {
+	return &subscription{
+		WebhookSubscription: *ws,
+		addrDescs:           make(map[string]struct{}),
+		gapAddrDescs:        make(map[string]struct{}),
+		mempoolTxs:          make(map[string]time.Time),
+		confirmedTxs:        make(map[string]*trackedTx),
+	}
+}
+
+// loadDeliveryLog restores the pending deliveries and the confirmed transactions tracked for reorgs
+func (wd *Dispatcher) loadDeliveryLog() error {
+	bestHeight, _, err := wd.db.GetBestBlock()
+	if err != nil {
+		return err
+	}
+	return wd.db.GetWebhookDeliveries(false, func(w *db.WebhookDelivery) bool {
+		if w.ID > wd.lastDeliveryID {
+			wd.lastDeliveryID = w.ID
+		}
+		if w.Status == db.WebhookDeliveryPending {
+			wd.pending[w.ID] = w
+		}
+		s := wd.subscriptions[w.SubscriptionID]
+		if s == nil || w.Height+reorgTrackingDepth+s.Confirmations < bestHeight {
+			return true
+		}
+		switch w.Event {
+		case EventConfirmed:
+			var p payload
+			if err := json.Unmarshal(w.Payload, &p); err == nil {
+				s.confirmedTxs[w.Txid] = &trackedTx{height: p.BlockHeight, hash: p.BlockHash, addresses: p.Addresses}
+			}
+		case EventConfirmations:
+			if t := s.confirmedTxs[w.Txid]; t != nil {
+				t.confirmationsSent = true
+			}
+		case EventRemoved:
+			delete(s.confirmedTxs, w.Txid)
+		}
+		return true
+	})
+}
+
+// resolve returns the address descriptors of the addresses and xpubs and the address descriptors of the unused derived addresses of the xpubs,
+// it derives the xpub addresses using the database and must not be called with the lock held
+func (wd *Dispatcher) resolve(descriptors []string) (map[string]struct{}, map[string]struct{}, error) {
+	addrDescs := make(map[string]struct{})
+	gapAddrDescs := make(map[string]struct{})
+	for _, desc := range descriptors {
+		if _, err := wd.chainParser.ParseXpub(desc); err == nil {
+			a, err := wd.api.GetXpubAddress(desc, 0, 1, api.AccountDetailsTokens, &api.AddressFilter{
+				Vout:           api.AddressFilterVoutOff,
+				TokensToReturn: api.TokensToReturnDerived,
+			}, 0, "")
+			if err != nil {
+				return nil, nil, errors.Annotatef(err, "xpub %s", desc)
+			}
+			for _, t := range a.Tokens {
+				addrDesc, err := wd.chainParser.GetAddrDescFromAddress(t.Name)
+				if err != nil {
+					return nil, nil, errors.Annotatef(err, "xpub %s address %s", desc, t.Name)
+				}
+				addrDescs[string(addrDesc)] = struct{}{}
+				if t.Transfers == 0 {
+					gapAddrDescs[string(addrDesc)] = struct{}{}
+				}
+			}
+		} else {
+			addrDesc, err := wd.chainParser.GetAddrDescFromAddress(desc)
+			if err != nil {
+				return nil, nil, api.NewAPIError("Invalid address or xpub "+desc, true)
+			}
+			addrDescs[string(addrDesc)] = struct{}{}
+		}
+	}
+	return addrDescs, gapAddrDescs, nil
+}
+
+func (wd *Dispatcher) addresses(addrDesc bchain.AddressDescriptor) []string {
+	addresses, _, err := wd.chainParser.GetAddressesFromAddrDesc(addrDesc)
+	if err != nil {
+		glog.V(1).Info("webhooks: GetAddressesFromAddrDesc error ", err)
+	}
+	return addresses
+}
+
+func appendUnique(a []string, s []string) []string {
+	for _, v := range s {
+		found := false
+		for _, e := range a {
+			if e == v {
+				found = true
+				break
+			}
+		}
+		if !found {
+			a = append(a, v)
+		}
+	}
+	return a
+}
+
+// enqueue stores a new delivery to the delivery log and wakes up the delivery loop, it must be called with the lock held
+func (wd *Dispatcher) enqueue(s *subscription, event string, txid string, height uint32, hash string, confirmations uint32, addresses []string) {
+	now := time.Now()
+	id := uint64(now.UnixNano())
+	if id <= wd.lastDeliveryID {
+		id = wd.lastDeliveryID + 1
+	}
+	wd.lastDeliveryID = id
+	buf, err := json.Marshal(&payload{
+		ID:            id,
+		Subscription:  s.ID,
+		Event:         event,
+		Txid:          txid,
+		Addresses:     addresses,
+		BlockHeight:   height,
+		BlockHash:     hash,
+		Confirmations: confirmations,
+		Time:          now.Unix(),
+	})
+	if err != nil {
+		glog.Error("webhooks: marshal payload error ", err)
+		return
+	}
+	w := &db.WebhookDelivery{
+		ID:             id,
+		SubscriptionID: s.ID,
+		Event:          event,
+		Txid:           txid,
+		Height:         height,
+		Payload:        buf,
+		Status:         db.WebhookDeliveryPending,
+		NextAttempt:    now.Unix(),
+		Created:        now.Unix(),
+		Updated:        now.Unix(),
+	}
+	if err := wd.db.StoreWebhookDelivery(w); err != nil {
+		glog.Error("webhooks: StoreWebhookDelivery error ", err)
+	}
+	wd.pending[id] = w
+	select {
+	case wd.chanWake <- struct{}{}:
+	default:
+	}
+}
+
+// OnNewTxAddr enqueues the mempool event for the subscriptions watching the address
+func (wd *Dispatcher) OnNewTxAddr(tx *bchain.Tx, desc bchain.AddressDescriptor) {
+	wd.mux.Lock()
+	defer wd.mux.Unlock()
+	for _, s := range wd.subscriptions {
+		if _, found := s.addrDescs[string(desc)]; !found {
+			continue
+		}
+		if _, found := s.mempoolTxs[tx.Txid]; found {
+			continue
+		}
+		s.mempoolTxs[tx.Txid] = time.Now()
+		addresses := wd.addresses(desc)
+		// add other watched addresses of the transaction, if they are known at this moment
+		for i := range tx.Vout {
+			addrDesc, err := wd.chainParser.GetAddrDescFromVout(&tx.Vout[i])
+			if err == nil {
+				if _, found := s.addrDescs[string(addrDesc)]; found {
+					addresses = appendUnique(addresses, wd.addresses(addrDesc))
+				}
+			}
+		}
+		wd.enqueue(s, EventMempool, tx.Txid, 0, "", 0, addresses)
+	}
+}
+
+// OnNewBlock passes the new block to the block processing loop
+func (wd *Dispatcher) OnNewBlock(hash string, height uint32) {
+	wd.chanBlocks <- blockNotification{hash: hash, height: height}
+}
+
+// Run processes the new blocks and delivers the notifications, it is expected to be run in a goroutine
+func (wd *Dispatcher) Run() {
+	go func() {
+		for b := range wd.chanBlocks {
+			wd.processBlock(b.hash, b.height)
+		}
+	}()
+	wd.deliveryLoop()
+}
+
+// getBlockTxs returns transactions of the subscription in the block, the watched addresses which they touch
+// and if any of the unused derived addresses of the xpubs was used
+func (wd *Dispatcher) getBlockTxs(s *subscription, height uint32) ([]string, map[string][]string, bool, error) {
+	txids := make([]string, 0)
+	addresses := make(map[string][]string)
+	gapUsed := false
+	for ad := range s.addrDescs {
+		addrDesc := bchain.AddressDescriptor(ad)
+		_, inGap := s.gapAddrDescs[ad]
+		err := wd.db.GetAddrDescTransactions(addrDesc, height, height, func(txid string, height uint32, indexes []int32) error {
+			a, found := addresses[txid]
+			if !found {
+				txids = append(txids, txid)
+			}
+			addresses[txid] = appendUnique(a, wd.addresses(addrDesc))
+			gapUsed = gapUsed || inGap
+			return nil
+		})
+		if err != nil {
+			return nil, nil, false, err
+		}
+	}
+	return txids, addresses, gapUsed, nil
+}
+
+// confirmBlockTxs enqueues the confirmed event for the new transactions of the subscription in the block,
+// it returns true if new addresses of the xpubs must be derived, it must be called with the lock held
+func (wd *Dispatcher) confirmBlockTxs(s *subscription, hash string, height uint32) bool {
+	txids, addresses, gapUsed, err := wd.getBlockTxs(s, height)
+	if err != nil {
+		glog.Error("webhook subscription ", s.ID, " block ", height, " error ", err)
+		return false
+	}
+	for _, txid := range txids {
+		if _, found := s.confirmedTxs[txid]; found {
+			continue
+		}
+		delete(s.mempoolTxs, txid)
+		s.confirmedTxs[txid] = &trackedTx{height: height, hash: hash, addresses: addresses[txid]}
+		wd.enqueue(s, EventConfirmed, txid, height, hash, 1, addresses[txid])
+	}
+	return gapUsed
+}
+
+func (wd *Dispatcher) processBlock(hash string, height uint32) {
+	// skip the block if it was already disconnected by a fork
+	h, err := wd.db.GetBlockHash(height)
+	if err != nil || h != hash {
+		return
+	}
+	wd.mux.Lock()
+	resolve := make([]*subscription, 0)
+	for _, s := range wd.subscriptions {
+		// transactions confirmed in the disconnected blocks at the same or higher height were removed by a reorg
+		removed := make([]string, 0)
+		for txid, t := range s.confirmedTxs {
+			if t.height > height || (t.height == height && t.hash != hash) {
+				removed = append(removed, txid)
+			}
+		}
+		sort.Strings(removed)
+		for _, txid := range removed {
+			t := s.confirmedTxs[txid]
+			delete(s.confirmedTxs, txid)
+			wd.enqueue(s, EventRemoved, txid, t.height, t.hash, 0, t.addresses)
+		}
+		if wd.confirmBlockTxs(s, hash, height) {
+			resolve = append(resolve, s)
+		}
+		for txid, t := range s.confirmedTxs {
+			confirmations := height - t.height + 1
+			// blocks may be skipped, the event is sent once the confirmations reach at least the requested number
+			if s.Confirmations > 1 && confirmations >= s.Confirmations && !t.confirmationsSent {
+				t.confirmationsSent = true
+				wd.enqueue(s, EventConfirmations, txid, t.height, t.hash, confirmations, t.addresses)
+			}
+			if confirmations >= reorgTrackingDepth && confirmations >= s.Confirmations {
+				delete(s.confirmedTxs, txid)
+			}
+		}
+		expired := time.Now().Add(-mempoolTxsExpiration)
+		for txid, t := range s.mempoolTxs {
+			if t.Before(expired) {
+				delete(s.mempoolTxs, txid)
+			}
+		}
+	}
+	wd.mux.Unlock()
+	// an unused address of an xpub was used, new addresses are derived outside of the lock
+	// and the block is searched also for the transactions of the newly derived addresses
+	for i := 0; i < len(resolve); i++ {
+		s := resolve[i]
+		addrDescs, gapAddrDescs, err := wd.resolve(s.Descriptors)
+		if err != nil {
+			glog.Error("webhook subscription ", s.ID, " error ", err)
+			continue
+		}
+		wd.mux.Lock()
+		if wd.subscriptions[s.ID] == s {
+			derived := len(addrDescs) > len(s.addrDescs)
+			s.addrDescs, s.gapAddrDescs = addrDescs, gapAddrDescs
+			if wd.confirmBlockTxs(s, hash, height) && derived {
+				resolve = append(resolve, s)
+			}
+		}
+		wd.mux.Unlock()
+	}
+}
+
+// retryDelay returns the delay before the next attempt, exponentially growing with the number of attempts
+func retryDelay(attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+	d := retryBaseDelay
+	for i := 1; i < attempts; i++ {
+		d *= 2
+		if d >= retryMaxDelay {
+			return retryMaxDelay
+		}
+	}
+	return d
+}
+
+// sign returns hex encoded HMAC-SHA256 of the body using the secret of the subscription
+func sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (wd *Dispatcher) post(url, secret string, w *db.WebhookDelivery) error {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(w.Payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Blockbook/"+common.GetVersionInfo().Version)
+	req.Header.Set("X-Blockbook-Event", w.Event)
+	req.Header.Set("X-Blockbook-Delivery", strconv.FormatUint(w.ID, 10))
+	req.Header.Set("X-Blockbook-Signature", "sha256="+sign(secret, w.Payload))
+	resp, err := wd.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return errors.Errorf("Invalid response status %v", resp.Status)
+	}
+	return nil
+}
+
+func (wd *Dispatcher) attempt(w *db.WebhookDelivery) {
+	wd.mux.Lock()
+	s := wd.subscriptions[w.SubscriptionID]
+	var url, secret string
+	if s != nil {
+		url, secret = s.URL, s.Secret
+	}
+	wd.mux.Unlock()
+	var err error
+	if s == nil {
+		err = errors.New("Subscription removed")
+	} else {
+		err = wd.post(url, secret, w)
+	}
+	wd.mux.Lock()
+	defer wd.mux.Unlock()
+	delete(wd.inFlight, w.ID)
+	now := time.Now()
+	w.Attempts++
+	w.Updated = now.Unix()
+	if err == nil {
+		w.Status = db.WebhookDeliveryDelivered
+		w.NextAttempt = 0
+		w.LastError = ""
+		delete(wd.pending, w.ID)
+		wd.metrics.WebhookDeliveries.With(common.Labels{"event": w.Event, "status": "success"}).Inc()
+	} else {
+		w.LastError = err.Error()
+		if s == nil || w.Attempts >= maxAttempts {
+			w.Status = db.WebhookDeliveryFailed
+			w.NextAttempt = 0
+			delete(wd.pending, w.ID)
+			glog.Warning("webhooks: delivery ", w.ID, " of subscription ", w.SubscriptionID, " failed after ", w.Attempts, " attempts, error ", err)
+		} else {
+			w.NextAttempt = now.Add(retryDelay(w.Attempts)).Unix()
+		}
+		wd.metrics.WebhookDeliveries.With(common.Labels{"event": w.Event, "status": "failure"}).Inc()
+	}
+	if err := wd.db.StoreWebhookDelivery(w); err != nil {
+		glog.Error("webhooks: StoreWebhookDelivery error ", err)
+	}
+}
+
+// dueDeliveries returns pending deliveries, which are due for an attempt, and marks them as in flight
+func (wd *Dispatcher) dueDeliveries() []*db.WebhookDelivery {
+	wd.mux.Lock()
+	defer wd.mux.Unlock()
+	now := time.Now().Unix()
+	due := make([]*db.WebhookDelivery, 0)
+	for id, w := range wd.pending {
+		if _, found := wd.inFlight[id]; !found && w.NextAttempt <= now {
+			due = append(due, w)
+			wd.inFlight[id] = struct{}{}
+		}
+	}
+	sort.Slice(due, func(i, j int) bool { return due[i].ID < due[j].ID })
+	return due
+}
+
+func (wd *Dispatcher) deliveryLoop() {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	workers := make(chan struct{}, deliveryWorkers)
+	var lastPrune time.Time
+	for {
+		select {
+		case <-wd.chanWake:
+		case <-ticker.C:
+		}
+		if common.IsInShutdown() {
+			return
+		}
+		for _, w := range wd.dueDeliveries() {
+			workers <- struct{}{}
+			go func(w *db.WebhookDelivery) {
+				defer func() { <-workers }()
+				wd.attempt(w)
+			}(w)
+		}
+		if time.Since(lastPrune) > time.Hour {
+			wd.pruneDeliveryLog()
+			lastPrune = time.Now()
+		}
+	}
+}
+
+// pruneDeliveryLog removes old delivered and failed entries from the delivery log
+func (wd *Dispatcher) pruneDeliveryLog() {
+	threshold := time.Now().Add(-deliveryLogRetention).Unix()
+	count := 0
+	err := wd.db.GetWebhookDeliveries(false, func(w *db.WebhookDelivery) bool {
+		if w.Created >= threshold {
+			return false
+		}
+		if w.Status != db.WebhookDeliveryPending && w.Updated < threshold {
+			if err := wd.db.DeleteWebhookDelivery(w.ID); err != nil {
+				glog.Error("webhooks: DeleteWebhookDelivery error ", err)
+				return false
+			}
+			count++
+		}
+		return true
+	})
+	if err != nil {
+		glog.Error("webhooks: pruneDeliveryLog error ", err)
+	}
+	if count > 0 {
+		glog.Info("webhooks: removed ", count, " old entries from the delivery log")
+	}
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// Subscriptions returns the webhook subscriptions ordered by the time of creation, the secrets are redacted
+func (wd *Dispatcher) Subscriptions() []db.WebhookSubscription {
+	wd.mux.Lock()
+	defer wd.mux.Unlock()
+	subs := make([]db.WebhookSubscription, 0, len(wd.subscriptions))
+	for _, s := range wd.subscriptions {
+		ws := s.WebhookSubscription
+		ws.Secret = ""
+		subs = append(subs, ws)
+	}
+	sort.Slice(subs, func(i, j int) bool {
+		if subs[i].Created == subs[j].Created {
+			return subs[i].ID < subs[j].ID
+		}
+		return subs[i].Created < subs[j].Created
+	})
+	return subs
+}
+
+// AddSubscription registers a new webhook subscription, if the secret is not specified, a random one is generated;
+// the secret is returned only if it was generated, otherwise it is redacted
+func (wd *Dispatcher) AddSubscription(hookURL string, secret string, descriptors []string, confirmations uint32) (*db.WebhookSubscription, error) {
+	u, err := url.Parse(hookURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, api.NewAPIError("Invalid webhook URL", true)
+	}
+	if len(descriptors) == 0 {
+		return nil, api.NewAPIError("Missing addresses or xpubs", true)
+	}
+	if len(descriptors) > maxDescriptorsInSubscription {
+		return nil, api.NewAPIError("Too many addresses or xpubs", true)
+	}
+	if confirmations > maxConfirmations {
+		return nil, api.NewAPIError("Too many confirmations, maximum is "+strconv.Itoa(maxConfirmations), true)
+	}
+	id, err := randomHex(16)
+	if err != nil {
+		return nil, err
+	}
+	generated := secret == ""
+	if generated {
+		if secret, err = randomHex(32); err != nil {
+			return nil, err
+		}
+	}
+	s := newSubscription(&db.WebhookSubscription{
+		ID:            id,
+		URL:           hookURL,
+		Secret:        secret,
+		Descriptors:   descriptors,
+		Confirmations: confirmations,
+		Created:       time.Now().Unix(),
+	})
+	if s.addrDescs, s.gapAddrDescs, err = wd.resolve(descriptors); err != nil {
+		return nil, err
+	}
+	wd.mux.Lock()
+	defer wd.mux.Unlock()
+	if err = wd.db.StoreWebhookSubscription(&s.WebhookSubscription); err != nil {
+		return nil, err
+	}
+	wd.subscriptions[s.ID] = s
+	glog.Info("webhooks: added subscription ", s.ID, " ", s.URL, ", ", len(s.addrDescs), " addresses")
+	ws := s.WebhookSubscription
+	if !generated {
+		ws.Secret = ""
+	}
+	return &ws, nil
+}
+
+// RemoveSubscription removes the webhook subscription, its pending deliveries are failed at their next attempt
+func (wd *Dispatcher) RemoveSubscription(id string) error {
+	wd.mux.Lock()
+	defer wd.mux.Unlock()
+	if _, found := wd.subscriptions[id]; !found {
+		return api.NewAPIError("Webhook subscription not found", true)
+	}
+	if err := wd.db.DeleteWebhookSubscription(id); err != nil {
+		return err
+	}
+	delete(wd.subscriptions, id)
+	glog.Info("webhooks: removed subscription ", id)
+	return nil
+}
+
+// Deliveries returns up to limit newest entries of the delivery log
+func (wd *Dispatcher) Deliveries(limit int) ([]db.WebhookDelivery, error) {
+	rv := make([]db.WebhookDelivery, 0)
+	err := wd.db.GetWebhookDeliveries(true, func(w *db.WebhookDelivery) bool {
+		rv = append(rv, *w)
+		return len(rv) < limit
+	})
+	return rv, err
+}
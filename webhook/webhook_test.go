@@ -0,0 +1,403 @@
+//go:build unittest
+
+package webhook
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/trezor/blockbook/bchain"
+	"github.com/trezor/blockbook/bchain/coins/btc"
+	"github.com/trezor/blockbook/common"
+	"github.com/trezor/blockbook/db"
+	"github.com/trezor/blockbook/tests/dbtestdata"
+)
+
+func Test_sign(t *testing.T) {
+	tests := []struct {
+		name   string
+		secret string
+		body   string
+		want   string
+	}{
+		{
+			name:   "known vector",
+			secret: "key",
+			body:   "The quick brown fox jumps over the lazy dog",
+			want:   "f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8",
+		},
+		{
+			name:   "empty",
+			secret: "",
+			body:   "",
+			want:   "b613679a0814d9ec772f95d778c35fc5ff1697c493715653c6c712144292c5ad",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sign(tt.secret, []byte(tt.body)); got != tt.want {
+				t.Errorf("sign() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_retryDelay(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{0, 10 * time.Second},
+		{1, 10 * time.Second},
+		{2, 20 * time.Second},
+		{3, 40 * time.Second},
+		{9, 2560 * time.Second},
+		{10, time.Hour},
+		{maxAttempts, time.Hour},
+	}
+	for _, tt := range tests {
+		if got := retryDelay(tt.attempts); got != tt.want {
+			t.Errorf("retryDelay(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+type receivedRequest struct {
+	header http.Header
+	body   []byte
+}
+
+// testReceiver is a webhook receiver, which records the requests and fails the requests while failures is positive
+type testReceiver struct {
+	mux      sync.Mutex
+	requests []receivedRequest
+	failures int
+}
+
+func (r *testReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	r.requests = append(r.requests, receivedRequest{header: req.Header, body: body})
+	if r.failures > 0 {
+		r.failures--
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (r *testReceiver) take() []receivedRequest {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	rv := r.requests
+	r.requests = nil
+	return rv
+}
+
+func setupDispatcher(t *testing.T) (*Dispatcher, *db.RocksDB, bchain.BlockChain, func()) {
+	parser := btc.NewBitcoinParser(
+		btc.GetChainParams("test"),
+		&btc.Configuration{
+			BlockAddressesToKeep:  100,
+			XPubMagic:             70617039,
+			XPubMagicSegwitP2sh:   71979618,
+			XPubMagicSegwitNative: 73342198,
+			Slip44:                1,
+		})
+	chain, err := dbtestdata.NewFakeBlockChain(parser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmp, err := os.MkdirTemp("", "testdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := db.NewRocksDB(tmp, 100000, -1, parser, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	is, err := d.LoadInternalState(&common.Config{CoinName: "Fakecoin"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.SetInternalState(is)
+	block1 := dbtestdata.GetTestBitcoinTypeBlock1(parser)
+	for i := uint32(0); i < block1.Height; i++ {
+		is.BlockTimes = append(is.BlockTimes, 0)
+	}
+	if err := d.ConnectBlock(block1); err != nil {
+		t.Fatal(err)
+	}
+	is.FinishedSync(block1.Height)
+	metrics, err := common.GetMetrics("Fakecoin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	mempool, err := chain.CreateMempool(chain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	txCache, err := db.NewTxCache(d, chain, metrics, is, false, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wd, err := NewDispatcher(d, chain, mempool, txCache, metrics, is, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return wd, d, chain, func() {
+		d.Close()
+		os.RemoveAll(tmp)
+	}
+}
+
+// coinbaseBlock returns a block containing only a coinbase transaction paying to an address, which is not watched
+func coinbaseBlock(parser bchain.BlockChainParser, height uint32, hash string) *bchain.Block {
+	return &bchain.Block{
+		BlockHeader: bchain.BlockHeader{
+			Height: height,
+			Hash:   hash,
+			Time:   1521595700,
+		},
+		Txs: []bchain.Tx{
+			{
+				Txid: strings.Repeat(hash[len(hash)-2:], 32),
+				Vin:  []bchain.Vin{{Coinbase: "03bf1e15"}},
+				Vout: []bchain.Vout{
+					{
+						N:            0,
+						ScriptPubKey: bchain.ScriptPubKey{Hex: dbtestdata.AddressToPubKeyHex(dbtestdata.Addr1, parser)},
+						ValueSat:     *dbtestdata.SatB1T1A1,
+					},
+				},
+			},
+		},
+	}
+}
+
+// deliver makes one delivery attempt of all due deliveries
+func deliver(wd *Dispatcher) {
+	for _, w := range wd.dueDeliveries() {
+		wd.attempt(w)
+	}
+}
+
+func checkRequests(t *testing.T, requests []receivedRequest, secret string, want []payload) {
+	t.Helper()
+	if len(requests) != len(want) {
+		t.Fatalf("received %d requests, want %d", len(requests), len(want))
+	}
+	got := make([]payload, len(requests))
+	for i, r := range requests {
+		if err := json.Unmarshal(r.body, &got[i]); err != nil {
+			t.Fatal(err)
+		}
+		if s := r.header.Get("X-Blockbook-Signature"); s != "sha256="+sign(secret, r.body) {
+			t.Errorf("request %d: X-Blockbook-Signature = %v", i, s)
+		}
+		if e := r.header.Get("X-Blockbook-Event"); e != got[i].Event {
+			t.Errorf("request %d: X-Blockbook-Event = %v, want %v", i, e, got[i].Event)
+		}
+	}
+	sort.Slice(got, func(i, j int) bool { return got[i].Txid < got[j].Txid })
+	sort.Slice(want, func(i, j int) bool { return want[i].Txid < want[j].Txid })
+	for i := range want {
+		g := got[i]
+		if g.ID == 0 || g.Time == 0 {
+			t.Errorf("payload %d: missing id or time %+v", i, g)
+		}
+		g.ID, g.Time = 0, 0
+		w := want[i]
+		if g.Subscription != w.Subscription || g.Event != w.Event || g.Txid != w.Txid || g.BlockHeight != w.BlockHeight ||
+			g.BlockHash != w.BlockHash || g.Confirmations != w.Confirmations || strings.Join(g.Addresses, ",") != strings.Join(w.Addresses, ",") {
+			t.Errorf("payload %d = %+v, want %+v", i, g, w)
+		}
+	}
+}
+
+func TestDispatcher(t *testing.T) {
+	wd, d, chain, cleanup := setupDispatcher(t)
+	defer cleanup()
+	parser := chain.GetChainParser()
+	receiver := &testReceiver{}
+	server := httptest.NewServer(receiver)
+	defer server.Close()
+
+	// subscription registration
+	for _, tt := range []struct {
+		name        string
+		url         string
+		descriptors []string
+		want        string
+	}{
+		{name: "invalid scheme", url: "ftp://example.com/hook", descriptors: []string{dbtestdata.Addr6}, want: "Invalid webhook URL"},
+		{name: "missing host", url: "http:///hook", descriptors: []string{dbtestdata.Addr6}, want: "Invalid webhook URL"},
+		{name: "missing descriptors", url: server.URL, want: "Missing addresses or xpubs"},
+		{name: "invalid address", url: server.URL, descriptors: []string{"invalid"}, want: "Invalid address or xpub invalid"},
+	} {
+		if _, err := wd.AddSubscription(tt.url, "", tt.descriptors, 0); err == nil || err.Error() != tt.want {
+			t.Errorf("AddSubscription %s: error %v, want %v", tt.name, err, tt.want)
+		}
+	}
+	ws, err := wd.AddSubscription(server.URL, "", []string{dbtestdata.Addr6}, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ws.Secret) != 64 {
+		t.Fatalf("AddSubscription generated secret %q", ws.Secret)
+	}
+	secret := ws.Secret
+	if subs := wd.Subscriptions(); len(subs) != 1 || subs[0].ID != ws.ID || subs[0].Secret != "" || subs[0].Confirmations != 2 {
+		t.Fatalf("Subscriptions() = %+v", subs)
+	}
+	stored, err := d.GetWebhookSubscriptions()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stored) != 1 || stored[0].ID != ws.ID || stored[0].Secret != secret || stored[0].URL != server.URL {
+		t.Fatalf("GetWebhookSubscriptions() = %+v", stored)
+	}
+
+	// mempool event, the first attempt fails with 5xx and is retried with a backoff
+	block2 := dbtestdata.GetTestBitcoinTypeBlock2(parser)
+	addrDesc6, err := parser.GetAddrDescFromAddress(dbtestdata.Addr6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wd.OnNewTxAddr(&block2.Txs[0], addrDesc6)
+	// the repeated notification of the same transaction does not create a new delivery
+	wd.OnNewTxAddr(&block2.Txs[0], addrDesc6)
+	receiver.failures = 1
+	start := time.Now().Unix()
+	deliver(wd)
+	requests := receiver.take()
+	checkRequests(t, requests, secret, []payload{
+		{Subscription: ws.ID, Event: EventMempool, Txid: dbtestdata.TxidB2T1, Addresses: []string{dbtestdata.Addr6}},
+	})
+	deliveries, err := wd.Deliveries(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 1 {
+		t.Fatalf("Deliveries() = %+v", deliveries)
+	}
+	w := deliveries[0]
+	if w.Status != db.WebhookDeliveryPending || w.Attempts != 1 || w.LastError != "Invalid response status 500 Internal Server Error" ||
+		w.NextAttempt < start+int64(retryBaseDelay/time.Second) || w.NextAttempt > time.Now().Unix()+int64(retryBaseDelay/time.Second) {
+		t.Fatalf("failed delivery = %+v", w)
+	}
+	// the delivery is not retried before its next attempt
+	deliver(wd)
+	if requests := receiver.take(); len(requests) != 0 {
+		t.Fatalf("delivery retried before the backoff, %d requests", len(requests))
+	}
+	// the pending delivery is restored from the delivery log
+	wd2, err := NewDispatcher(d, chain, nil, nil, wd.metrics, d.GetInternalState(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p, found := wd2.pending[w.ID]; len(wd2.pending) != 1 || !found || p.Attempts != 1 || p.NextAttempt != w.NextAttempt {
+		t.Fatalf("restored pending deliveries %+v", wd2.pending)
+	}
+	if len(wd2.subscriptions) != 1 || wd2.subscriptions[ws.ID] == nil {
+		t.Fatalf("restored subscriptions %+v", wd2.subscriptions)
+	}
+	wd.pending[w.ID].NextAttempt = 0
+	deliver(wd)
+	if requests := receiver.take(); len(requests) != 1 || string(requests[0].body) != string(w.Payload) {
+		t.Fatalf("retried delivery, %d requests", len(requests))
+	}
+	if deliveries, err = wd.Deliveries(10); err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 1 || deliveries[0].Status != db.WebhookDeliveryDelivered || deliveries[0].Attempts != 2 || deliveries[0].LastError != "" {
+		t.Fatalf("retried delivery = %+v", deliveries)
+	}
+
+	// first confirmation, Addr6 receives in TxidB2T1 and spends in TxidB2T2
+	if err := d.ConnectBlock(block2); err != nil {
+		t.Fatal(err)
+	}
+	wd.processBlock(block2.Hash, block2.Height)
+	deliver(wd)
+	confirmed := []payload{
+		{Subscription: ws.ID, Event: EventConfirmed, Txid: dbtestdata.TxidB2T1, Addresses: []string{dbtestdata.Addr6}, BlockHeight: block2.Height, BlockHash: block2.Hash, Confirmations: 1},
+		{Subscription: ws.ID, Event: EventConfirmed, Txid: dbtestdata.TxidB2T2, Addresses: []string{dbtestdata.Addr6}, BlockHeight: block2.Height, BlockHash: block2.Hash, Confirmations: 1},
+	}
+	checkRequests(t, receiver.take(), secret, confirmed)
+	// the block processed again does not produce new events
+	wd.processBlock(block2.Hash, block2.Height)
+	deliver(wd)
+	if requests := receiver.take(); len(requests) != 0 {
+		t.Fatalf("block processed again, %d requests", len(requests))
+	}
+
+	// the requested number of confirmations
+	block3 := coinbaseBlock(parser, block2.Height+1, "00000000c0a1d2b3c4d5e6f708192a3b4c5d6e7f8091a2b3c4d5e6f708192a03")
+	if err := d.ConnectBlock(block3); err != nil {
+		t.Fatal(err)
+	}
+	wd.processBlock(block3.Hash, block3.Height)
+	deliver(wd)
+	checkRequests(t, receiver.take(), secret, []payload{
+		{Subscription: ws.ID, Event: EventConfirmations, Txid: dbtestdata.TxidB2T1, Addresses: []string{dbtestdata.Addr6}, BlockHeight: block2.Height, BlockHash: block2.Hash, Confirmations: 2},
+		{Subscription: ws.ID, Event: EventConfirmations, Txid: dbtestdata.TxidB2T2, Addresses: []string{dbtestdata.Addr6}, BlockHeight: block2.Height, BlockHash: block2.Hash, Confirmations: 2},
+	})
+
+	// reorg replaces the block2 by a block without the watched transactions
+	if err := d.DisconnectBlockRangeBitcoinType(block2.Height, block3.Height); err != nil {
+		t.Fatal(err)
+	}
+	// the disconnected block is skipped
+	wd.processBlock(block3.Hash, block3.Height)
+	block2a := coinbaseBlock(parser, block2.Height, "00000000d0a1d2b3c4d5e6f708192a3b4c5d6e7f8091a2b3c4d5e6f708192a02")
+	if err := d.ConnectBlock(block2a); err != nil {
+		t.Fatal(err)
+	}
+	wd.processBlock(block2a.Hash, block2a.Height)
+	deliver(wd)
+	checkRequests(t, receiver.take(), secret, []payload{
+		{Subscription: ws.ID, Event: EventRemoved, Txid: dbtestdata.TxidB2T1, Addresses: []string{dbtestdata.Addr6}, BlockHeight: block2.Height, BlockHash: block2.Hash},
+		{Subscription: ws.ID, Event: EventRemoved, Txid: dbtestdata.TxidB2T2, Addresses: []string{dbtestdata.Addr6}, BlockHeight: block2.Height, BlockHash: block2.Hash},
+	})
+
+	// the delivery log contains all deliveries, the newest first
+	if deliveries, err = wd.Deliveries(100); err != nil {
+		t.Fatal(err)
+	}
+	wantEvents := []string{EventRemoved, EventRemoved, EventConfirmations, EventConfirmations, EventConfirmed, EventConfirmed, EventMempool}
+	if len(deliveries) != len(wantEvents) {
+		t.Fatalf("Deliveries() returned %d entries, want %d", len(deliveries), len(wantEvents))
+	}
+	for i, w := range deliveries {
+		if w.Event != wantEvents[i] || w.Status != db.WebhookDeliveryDelivered || w.SubscriptionID != ws.ID {
+			t.Errorf("Deliveries()[%d] = %+v, want event %v", i, w, wantEvents[i])
+		}
+	}
+	if deliveries, err = wd.Deliveries(2); err != nil || len(deliveries) != 2 {
+		t.Fatalf("Deliveries(2) returned %d entries, error %v", len(deliveries), err)
+	}
+	if len(wd.pending) != 0 {
+		t.Errorf("pending deliveries %+v", wd.pending)
+	}
+
+	// the removed subscription is deleted from the database
+	if err := wd.RemoveSubscription(ws.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := wd.RemoveSubscription(ws.ID); err == nil {
+		t.Error("RemoveSubscription of the removed subscription succeeded")
+	}
+	if stored, err = d.GetWebhookSubscriptions(); err != nil || len(stored) != 0 {
+		t.Fatalf("GetWebhookSubscriptions() = %+v, error %v", stored, err)
+	}
+}